@@ -1,13 +1,15 @@
 package alias
 
 import (
-	"errors"
 	"strings"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/fspath"
+	"github.com/ncw/rclone/lib/encoder"
+	"github.com/pkg/errors"
 )
 
 // Register with Fs
@@ -20,6 +22,55 @@ func init() {
 			Name:     "remote",
 			Help:     "Remote or path to alias.\nCan be \"myremote:path/to/dir\", \"myremote:bucket\", \"myremote:\" or \"/local/path\".",
 			Required: true,
+		}, {
+			Name: "options",
+			Help: `Comma separated list of backend options for the aliased remote.
+
+These are in the form name=value, eg "chunk_size=64M,upload_cutoff=200M"
+using the config file names of the options of the aliased remote's
+backend.  They override the values set in the config file for the
+aliased remote, so the alias can be used as a differently configured
+view of it.`,
+			Advanced: true,
+		}, {
+			Name: "read_only",
+			Help: `Make the alias read only.
+
+If set then any attempt to modify the aliased remote through the
+alias, eg uploading, deleting or setting the modification time of
+files, will fail.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "bwlimit",
+			Help: `Bandwidth limit for transfers to and from the aliased remote.
+
+This is in the same format as --bwlimit, eg "1M" or a timetable like
+"08:00,512 19:00,10M".  It applies to all transfers to and from the
+aliased remote in this run of rclone, not just those made through the
+alias.  A --bwlimit-remote flag for the aliased remote takes precedence.`,
+			Advanced: true,
+		}, {
+			Name: "encoding",
+			Help: `Encode the file names stored on the aliased remote.
+
+This is a comma separated list of the encodings to apply, eg
+"Slash,Win,InvalidUtf8", which replace the characters the aliased
+remote can't store with similar looking unicode characters.  See
+"rclone help encoding" for the encodings and the characters they
+replace.`,
+			Advanced: true,
+		}, {
+			Name: "encoding_substitutions",
+			Help: `Substitute extra characters in the file names stored on the aliased remote.
+
+This is for characters the aliased remote can't store which none of
+the encodings replace, eg "," or "&".  It is written as pairs of
+characters, the character to substitute followed by its replacement,
+eg ",，&＆" to replace "," with "，" (FULLWIDTH COMMA) and "&" with
+"＆" (FULLWIDTH AMPERSAND).  The replacements must not be ASCII or
+already used by the encodings.`,
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -27,7 +78,29 @@ func init() {
 
 // Options defines the configuration for this backend
 type Options struct {
-	Remote string `config:"remote"`
+	Remote   string `config:"remote"`
+	Options  string `config:"options"`
+	ReadOnly bool   `config:"read_only"`
+	BwLimit  string `config:"bwlimit"`
+	Encoding string `config:"encoding"`
+	Subs     string `config:"encoding_substitutions"`
+}
+
+// parseOptions parses a comma separated list of name=value pairs
+func parseOptions(options string) (configmap.Simple, error) {
+	out := configmap.Simple{}
+	for _, option := range strings.Split(options, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		equals := strings.IndexRune(option, '=')
+		if equals <= 0 {
+			return nil, errors.New("alias options must be in the form name=value - check the value of the options setting")
+		}
+		out[strings.TrimSpace(option[:equals])] = strings.TrimSpace(option[equals+1:])
+	}
+	return out, nil
 }
 
 // NewFs constructs an Fs from the path.
@@ -50,5 +123,37 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if err != nil {
 		return nil, err
 	}
-	return fsInfo.NewFs(configName, fspath.JoinRootPath(fsPath, root), config)
+	overrides, err := parseOptions(opt.Options)
+	if err != nil {
+		return nil, err
+	}
+	var wrappedConfig configmap.Mapper = config
+	if len(overrides) > 0 {
+		wrappedConfig = configmap.New().AddGetters(overrides, config).AddSetter(config)
+	}
+	var timetable fs.BwTimetable
+	if opt.BwLimit != "" {
+		err = timetable.Set(opt.BwLimit)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad bwlimit")
+		}
+	}
+	enc, err := newEncoder(opt.Encoding, opt.Subs)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fsInfo.NewFs(configName, fspath.JoinRootPath(fsPath, enc.FromStandardPath(root)), wrappedConfig)
+	if f == nil {
+		return f, err
+	}
+	if opt.BwLimit != "" {
+		accounting.SetRemoteLimit(f.Name(), timetable)
+	}
+	if enc != encoder.Identity() {
+		f = newEncodingFs(f, enc)
+	}
+	if opt.ReadOnly {
+		f = newReadOnlyFs(f)
+	}
+	return f, err
 }
@@ -1,15 +1,23 @@
 package alias
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
 	"sort"
 	"testing"
+	"time"
 
 	_ "github.com/ncw/rclone/backend/local" // pull in test backend
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/lib/encoder"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -102,3 +110,162 @@ func TestNewFSInvalidRemote(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, f)
 }
+
+func TestNewFSReadOnly(t *testing.T) {
+	remoteRoot, err := filepath.Abs(filepath.FromSlash("test/files"))
+	require.NoError(t, err)
+	prepare(t, remoteRoot)
+	config.FileSet(remoteName, "read_only", "true")
+	defer config.FileDeleteKey(remoteName, "read_only")
+
+	f, err := fs.NewFs(fmt.Sprintf("%s:", remoteName))
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrorReadOnly, f.Mkdir("newdir"))
+	assert.Equal(t, ErrorReadOnly, f.Rmdir("four"))
+	assert.Nil(t, f.Features().Purge)
+	assert.Nil(t, f.Features().Move)
+	assert.Nil(t, f.Features().HardLink)
+	assert.Nil(t, f.Features().DirSetModTime)
+	assert.Nil(t, f.Features().Command)
+	assert.NotNil(t, f.Features().UnWrap)
+
+	o, err := f.NewObject("two.html")
+	require.NoError(t, err)
+	assert.Equal(t, ErrorReadOnly, o.Remove())
+	assert.Equal(t, ErrorReadOnly, o.SetModTime(o.ModTime()))
+
+	entries, err := f.List("")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			assert.Equal(t, ErrorReadOnly, o.Remove())
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    configmap.Simple
+		wantErr bool
+	}{
+		{"", configmap.Simple{}, false},
+		{"a=b", configmap.Simple{"a": "b"}, false},
+		{" chunk_size=64M , upload_cutoff = 200M,", configmap.Simple{"chunk_size": "64M", "upload_cutoff": "200M"}, false},
+		{"a=", configmap.Simple{"a": ""}, false},
+		{"a", nil, true},
+		{"=b", nil, true},
+	} {
+		got, err := parseOptions(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+			assert.Equal(t, test.want, got, test.in)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    encoder.MultiEncoder
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"Slash", encoder.MultiEncoder(encoder.EncodeSlash), false},
+		{" win , invalidutf8,", encoder.MultiEncoder(encoder.EncodeWin | encoder.EncodeInvalidUtf8), false},
+		{"potato", 0, true},
+	} {
+		got, err := parseEncoding(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			assert.NoError(t, err, test.in)
+			assert.Equal(t, test.want, got, test.in)
+		}
+	}
+}
+
+func TestNewFSEncoding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-alias-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	prepare(t, dir)
+	config.FileSet(remoteName, "encoding", "Win")
+	defer config.FileDeleteKey(remoteName, "encoding")
+
+	f, err := fs.NewFs(fmt.Sprintf("%s:", remoteName))
+	require.NoError(t, err)
+
+	// Check the encoding is reported
+	var replaced []string
+	for _, pair := range fs.Encoding(f).Mapping() {
+		replaced = append(replaced, string(pair.From))
+	}
+	assert.Subset(t, replaced, []string{":", "?", "*"})
+
+	// Check the names are encoded on the aliased remote
+	require.NoError(t, f.Mkdir("dir?"))
+	contents := []byte("potato")
+	src := object.NewStaticObjectInfo("dir?/a:b.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(bytes.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, "dir?/a:b.txt", o.Remote())
+	_, err = os.Stat(filepath.Join(dir, "dir？", "a：b.txt"))
+	require.NoError(t, err)
+
+	// Check they are decoded when read through the alias
+	entries, err := f.List("")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dir?", entries[0].Remote())
+	entries, err = f.List("dir?")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "dir?/a:b.txt", entries[0].Remote())
+	o, err = f.NewObject("dir?/a:b.txt")
+	require.NoError(t, err)
+	moved, err := f.Features().Move(o, "dir?/c*d.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "dir?/c*d.txt", moved.Remote())
+	_, err = os.Stat(filepath.Join(dir, "dir？", "c＊d.txt"))
+	require.NoError(t, err)
+
+	config.FileSet(remoteName, "encoding", "potato")
+	_, err = fs.NewFs(fmt.Sprintf("%s:", remoteName))
+	assert.Error(t, err)
+}
+
+func TestNewFSEncodingSubstitutions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-alias-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	prepare(t, dir)
+	config.FileSet(remoteName, "encoding_substitutions", ",，&＆")
+	defer config.FileDeleteKey(remoteName, "encoding_substitutions")
+
+	f, err := fs.NewFs(fmt.Sprintf("%s:", remoteName))
+	require.NoError(t, err)
+
+	// Check the substituted names are stored on the aliased remote
+	contents := []byte("potato")
+	src := object.NewStaticObjectInfo("a,b&c.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(bytes.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, "a,b&c.txt", o.Remote())
+	_, err = os.Stat(filepath.Join(dir, "a，b＆c.txt"))
+	require.NoError(t, err)
+	o, err = f.NewObject("a,b&c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "a,b&c.txt", o.Remote())
+
+	config.FileSet(remoteName, "encoding_substitutions", ",")
+	_, err = fs.NewFs(fmt.Sprintf("%s:", remoteName))
+	assert.Error(t, err)
+}
@@ -0,0 +1,365 @@
+package alias
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/lib/encoder"
+	"github.com/pkg/errors"
+)
+
+// parseEncoding parses a comma separated list of encoder flag names,
+// eg "Slash,Win,InvalidUtf8"
+func parseEncoding(s string) (encoder.MultiEncoder, error) {
+	var enc encoder.MultiEncoder
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, flag := range encoder.Flags {
+			if strings.EqualFold(name, flag.Name) {
+				enc |= encoder.MultiEncoder(flag.Flag)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, errors.Errorf("unknown encoding %q - see rclone help encoding", name)
+		}
+	}
+	return enc, nil
+}
+
+// newEncoder makes the encoder from the encoding and
+// encoding_substitutions options, returning encoder.Identity() if
+// the names shouldn't be encoded.
+func newEncoder(encoding, substitutions string) (encoder.Encoder, error) {
+	mask, err := parseEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	if substitutions == "" {
+		if mask == 0 {
+			return encoder.Identity(), nil
+		}
+		return mask, nil
+	}
+	subs, err := encoder.ParseSubstitutions(substitutions)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad encoding_substitutions")
+	}
+	enc, err := encoder.NewSubstitutionEncoder(mask, subs)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad encoding_substitutions")
+	}
+	return enc, nil
+}
+
+// encodingFs wraps an fs.Fs encoding the names of the files and
+// directories with an encoder before passing them to it
+type encodingFs struct {
+	fs.Fs
+	enc         encoder.Encoder
+	features    *fs.Features // optional features
+	changedOnce sync.Once    // for logging the first name changed
+	lossyOnce   sync.Once    // for logging the first name which can't be written back
+}
+
+// newEncodingFs returns f wrapped so the names are encoded with enc
+func newEncodingFs(f fs.Fs, enc encoder.Encoder) *encodingFs {
+	e := &encodingFs{
+		Fs:  f,
+		enc: enc,
+	}
+	e.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		PartialUploads:          true,
+		SlowHash:                true,
+	}).Fill(e).Mask(f).WrapsFs(e, f)
+	return e
+}
+
+// fromStandardPath encodes the / separated path s for the aliased
+// remote, logging the first name the encoding changes.
+func (e *encodingFs) fromStandardPath(s string) string {
+	parts := strings.Split(s, "/")
+	for i, name := range parts {
+		encoded, changed := e.enc.EncodeWithInfo(encoder.Standard.Decode(name))
+		if changed {
+			e.changedOnce.Do(func() {
+				fs.Logf(e, "File names are changed on the aliased remote by the encoding, eg %q is stored as %q", name, encoded)
+			})
+		}
+		parts[i] = encoded
+	}
+	return strings.Join(parts, "/")
+}
+
+// toStandardPath decodes the / separated path s read from the aliased
+// remote, logging the first name which won't encode back to s so
+// can't be found or written again through the alias.
+func (e *encodingFs) toStandardPath(s string) string {
+	parts := strings.Split(s, "/")
+	for i, name := range parts {
+		decoded, changed := e.enc.DecodeWithInfo(name)
+		if changed && e.enc.Encode(decoded) != name {
+			e.lossyOnce.Do(func() {
+				fs.Logf(e, "File name %q on the aliased remote is read as %q which the encoding doesn't store with the same name", name, decoded)
+			})
+		}
+		parts[i] = encoder.Standard.Encode(decoded)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Features returns the optional features of this Fs
+func (e *encodingFs) Features() *fs.Features {
+	return e.features
+}
+
+// newObject wraps o so its name is decoded
+func (e *encodingFs) newObject(o fs.Object) *encodingObject {
+	return &encodingObject{Object: o, f: e}
+}
+
+// wrapEntries decodes the names of the entries
+func (e *encodingFs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	for i, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			entries[i] = e.newObject(x)
+		case fs.Directory:
+			entries[i] = fs.NewDirCopy(x).SetRemote(e.toStandardPath(x.Remote()))
+		}
+	}
+	return entries
+}
+
+// List the objects and directories in dir into entries.
+func (e *encodingFs) List(dir string) (entries fs.DirEntries, err error) {
+	entries, err = e.Fs.List(e.fromStandardPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	return e.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (e *encodingFs) ListR(dir string, callback fs.ListRCallback) (err error) {
+	return e.Fs.Features().ListR(e.fromStandardPath(dir), func(entries fs.DirEntries) error {
+		return callback(e.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.
+func (e *encodingFs) NewObject(remote string) (fs.Object, error) {
+	o, err := e.Fs.NewObject(e.fromStandardPath(remote))
+	if err != nil {
+		return nil, err
+	}
+	return e.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (e *encodingFs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := e.Fs.Put(in, e.newObjectInfo(src), options...)
+	if err != nil {
+		return nil, err
+	}
+	return e.newObject(o), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (e *encodingFs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := e.Fs.Features().PutStream(in, e.newObjectInfo(src), options...)
+	if err != nil {
+		return nil, err
+	}
+	return e.newObject(o), nil
+}
+
+// Mkdir makes the directory (container, bucket)
+func (e *encodingFs) Mkdir(dir string) error {
+	return e.Fs.Mkdir(e.fromStandardPath(dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+func (e *encodingFs) Rmdir(dir string) error {
+	return e.Fs.Rmdir(e.fromStandardPath(dir))
+}
+
+// Purge all files in the root and the root directory
+func (e *encodingFs) Purge() error {
+	return e.Fs.Features().Purge()
+}
+
+// Copy src to this remote using server side copy operations.
+func (e *encodingFs) Copy(src fs.Object, remote string) (fs.Object, error) {
+	o, ok := src.(*encodingObject)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	dst, err := e.Fs.Features().Copy(o.Object, e.fromStandardPath(remote))
+	if err != nil {
+		return nil, err
+	}
+	return e.newObject(dst), nil
+}
+
+// Move src to this remote using server side move operations.
+func (e *encodingFs) Move(src fs.Object, remote string) (fs.Object, error) {
+	o, ok := src.(*encodingObject)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	dst, err := e.Fs.Features().Move(o.Object, e.fromStandardPath(remote))
+	if err != nil {
+		return nil, err
+	}
+	return e.newObject(dst), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server side move operations.
+func (e *encodingFs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*encodingFs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	return e.Fs.Features().DirMove(srcFs.Fs, srcFs.fromStandardPath(srcRemote), e.fromStandardPath(dstRemote))
+}
+
+// CleanUp the trash in the Fs
+func (e *encodingFs) CleanUp() error {
+	return e.Fs.Features().CleanUp()
+}
+
+// About gets quota information from the Fs
+func (e *encodingFs) About() (*fs.Usage, error) {
+	return e.Fs.Features().About()
+}
+
+// PartialNaming returns the partial naming of the wrapped remote with
+// the leaf length limited so the encoded names fit.
+//
+// Each byte may be encoded as up to 5 bytes - an invalid UTF-8 byte
+// is replaced by a 3 byte quote character and 2 hex digits.
+func (e *encodingFs) PartialNaming() fs.PartialNaming {
+	naming := fs.DefaultPartialNaming
+	if do := e.Fs.Features().PartialNaming; do != nil {
+		naming = do()
+	}
+	if naming.MaxLeafLength > 0 {
+		naming.MaxLeafLength /= 5
+		if naming.MaxLeafLength < 1 {
+			naming.MaxLeafLength = 1
+		}
+	}
+	return naming
+}
+
+// Encoding returns the Encoder used to translate the names of the
+// files and directories stored on the wrapped remote
+func (e *encodingFs) Encoding() encoder.Encoder {
+	return e.enc
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (e *encodingFs) UnWrap() fs.Fs {
+	return e.Fs
+}
+
+// encodingObject wraps an fs.Object decoding its name
+type encodingObject struct {
+	fs.Object
+	f *encodingFs
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *encodingObject) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the decoded remote path
+func (o *encodingObject) Remote() string {
+	return o.f.toStandardPath(o.Object.Remote())
+}
+
+// String returns a description of the Object
+func (o *encodingObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *encodingObject) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.Object.Update(in, o.f.newObjectInfo(src), options...)
+}
+
+// MimeType returns the content type of the wrapped Object if known
+func (o *encodingObject) MimeType() string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType()
+	}
+	return ""
+}
+
+// UnWrap returns the wrapped Object
+func (o *encodingObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// encodingObjectInfo wraps an fs.ObjectInfo encoding its name
+type encodingObjectInfo struct {
+	fs.ObjectInfo
+	f *encodingFs
+}
+
+// newObjectInfo wraps src so its name is encoded
+func (e *encodingFs) newObjectInfo(src fs.ObjectInfo) *encodingObjectInfo {
+	return &encodingObjectInfo{ObjectInfo: src, f: e}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *encodingObjectInfo) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the encoded remote path
+func (o *encodingObjectInfo) Remote() string {
+	return o.f.fromStandardPath(o.ObjectInfo.Remote())
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*encodingFs)(nil)
+	_ fs.Purger          = (*encodingFs)(nil)
+	_ fs.PutStreamer     = (*encodingFs)(nil)
+	_ fs.Copier          = (*encodingFs)(nil)
+	_ fs.Mover           = (*encodingFs)(nil)
+	_ fs.DirMover        = (*encodingFs)(nil)
+	_ fs.CleanUpper      = (*encodingFs)(nil)
+	_ fs.Abouter         = (*encodingFs)(nil)
+	_ fs.PartialNamer    = (*encodingFs)(nil)
+	_ fs.NameEncoder     = (*encodingFs)(nil)
+	_ fs.UnWrapper       = (*encodingFs)(nil)
+	_ fs.ListRer         = (*encodingFs)(nil)
+	_ fs.Object          = (*encodingObject)(nil)
+	_ fs.MimeTyper       = (*encodingObject)(nil)
+	_ fs.ObjectUnWrapper = (*encodingObject)(nil)
+	_ fs.ObjectInfo      = (*encodingObjectInfo)(nil)
+)
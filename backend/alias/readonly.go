@@ -0,0 +1,147 @@
+package alias
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// ErrorReadOnly is returned when trying to modify a read only alias
+var ErrorReadOnly = errors.New("can't modify a read only alias")
+
+// readOnlyFs wraps an fs.Fs refusing all operations which modify it
+type readOnlyFs struct {
+	fs.Fs
+	features *fs.Features // optional features
+}
+
+// newReadOnlyFs returns f wrapped so it can't be modified
+func newReadOnlyFs(f fs.Fs) *readOnlyFs {
+	r := &readOnlyFs{Fs: f}
+	// Only the features which can't modify the remote are passed
+	// through so any added in the future are refused by default
+	wrapped := f.Features()
+	r.features = &fs.Features{
+		CaseInsensitive:         wrapped.CaseInsensitive,
+		DuplicateFiles:          wrapped.DuplicateFiles,
+		ReadMimeType:            wrapped.ReadMimeType,
+		CanHaveEmptyDirectories: wrapped.CanHaveEmptyDirectories,
+		BucketBased:             wrapped.BucketBased,
+		GetTier:                 wrapped.GetTier,
+		ChangeNotify:            wrapped.ChangeNotify,
+		DirCacheFlush:           wrapped.DirCacheFlush,
+		About:                   wrapped.About,
+		UnWrap:                  r.UnWrap,
+	}
+	if wrapped.ListR != nil {
+		r.features.ListR = r.ListR
+	}
+	return r
+}
+
+// Features returns the optional features of this Fs
+func (r *readOnlyFs) Features() *fs.Features {
+	return r.features
+}
+
+// wrapEntries wraps the objects in entries so they can't be modified
+func (r *readOnlyFs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = &readOnlyObject{Object: o}
+		}
+	}
+	return entries
+}
+
+// List the objects and directories in dir into entries.
+func (r *readOnlyFs) List(dir string) (entries fs.DirEntries, err error) {
+	entries, err = r.Fs.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	return r.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (r *readOnlyFs) ListR(dir string, callback fs.ListRCallback) (err error) {
+	return r.Fs.Features().ListR(dir, func(entries fs.DirEntries) error {
+		return callback(r.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.
+func (r *readOnlyFs) NewObject(remote string) (fs.Object, error) {
+	o, err := r.Fs.NewObject(remote)
+	if err != nil {
+		return nil, err
+	}
+	return &readOnlyObject{Object: o}, nil
+}
+
+// Put refuses to upload as the alias is read only
+func (r *readOnlyFs) Put(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, ErrorReadOnly
+}
+
+// Mkdir refuses to make directories as the alias is read only
+func (r *readOnlyFs) Mkdir(dir string) error {
+	return ErrorReadOnly
+}
+
+// Rmdir refuses to remove directories as the alias is read only
+func (r *readOnlyFs) Rmdir(dir string) error {
+	return ErrorReadOnly
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (r *readOnlyFs) UnWrap() fs.Fs {
+	return r.Fs
+}
+
+// readOnlyObject wraps an fs.Object refusing all operations which
+// modify it
+type readOnlyObject struct {
+	fs.Object
+}
+
+// SetModTime refuses to set the modification time as the alias is read only
+func (o *readOnlyObject) SetModTime(time.Time) error {
+	return ErrorReadOnly
+}
+
+// Update refuses to update the object as the alias is read only
+func (o *readOnlyObject) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return ErrorReadOnly
+}
+
+// Remove refuses to remove the object as the alias is read only
+func (o *readOnlyObject) Remove() error {
+	return ErrorReadOnly
+}
+
+// MimeType returns the content type of the wrapped Object if known
+func (o *readOnlyObject) MimeType() string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType()
+	}
+	return ""
+}
+
+// UnWrap returns the wrapped Object
+func (o *readOnlyObject) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*readOnlyFs)(nil)
+	_ fs.UnWrapper       = (*readOnlyFs)(nil)
+	_ fs.ListRer         = (*readOnlyFs)(nil)
+	_ fs.Object          = (*readOnlyObject)(nil)
+	_ fs.MimeTyper       = (*readOnlyObject)(nil)
+	_ fs.ObjectUnWrapper = (*readOnlyObject)(nil)
+)
@@ -0,0 +1,315 @@
+// Azure AD authentication - managed identities and service principals
+
+// +build !plan9,!solaris,go1.8
+
+package azureblob
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// resource the Azure AD tokens are asked for
+	storageResource = "https://storage.azure.com/"
+	// refresh tokens this long before they expire
+	tokenRefreshMargin = 5 * time.Minute
+	// if a refresh fails try again after this long
+	tokenRetryInterval = 30 * time.Second
+)
+
+var (
+	// URL of the instance metadata service token endpoint used for
+	// managed identities - vars so the tests can change them
+	msiTokenURL = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// URL of Azure AD to get service principal tokens from
+	aadAuthorityURL = "https://login.microsoftonline.com"
+)
+
+// aadToken is the reply from the Azure AD and instance metadata
+// token endpoints
+type aadToken struct {
+	AccessToken string   `json:"access_token"`
+	ExpiresIn   jsonUint `json:"expires_in"`
+}
+
+// jsonUint is an unsigned integer which may be sent as a JSON number
+// or a JSON string as the token endpoints do
+type jsonUint uint64
+
+// UnmarshalJSON parses a number which may be quoted
+func (i *jsonUint) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseUint(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonUint(n)
+	return nil
+}
+
+// tokenSource fetches Azure AD access tokens for storage
+type tokenSource struct {
+	client      *http.Client
+	fetch       func() (*http.Request, error) // makes the request for a token
+	mu          sync.Mutex
+	haveInitial bool // set if the first token hasn't been handed out yet
+	expiry      time.Time
+	token       string
+}
+
+// newTokenSource makes a tokenSource for the Azure AD auth set in
+// opt or returns nil if none is set.
+func newTokenSource(client *http.Client, opt *Options) (*tokenSource, error) {
+	ts := &tokenSource{client: client}
+	switch {
+	case opt.UseMSI:
+		ts.fetch = opt.msiRequest
+	case opt.ClientID != "" && opt.ClientSecret != "":
+		if opt.Tenant == "" {
+			return nil, errors.New("tenant must be set to use client_secret")
+		}
+		ts.fetch = func() (*http.Request, error) {
+			return opt.aadRequest(url.Values{
+				"client_secret": {opt.ClientSecret},
+			})
+		}
+	case opt.ClientID != "" && opt.ClientCertificatePath != "":
+		if opt.Tenant == "" {
+			return nil, errors.New("tenant must be set to use client_certificate_path")
+		}
+		cert, key, err := readCertificate(os.ExpandEnv(opt.ClientCertificatePath))
+		if err != nil {
+			return nil, err
+		}
+		ts.fetch = func() (*http.Request, error) {
+			assertion, err := clientAssertion(cert, key, opt.ClientID, opt.tokenURL())
+			if err != nil {
+				return nil, err
+			}
+			return opt.aadRequest(url.Values{
+				"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+				"client_assertion":      {assertion},
+			})
+		}
+	default:
+		return nil, nil
+	}
+	err := ts.refresh()
+	if err != nil {
+		return nil, err
+	}
+	ts.haveInitial = true
+	return ts, nil
+}
+
+// msiRequest makes the request for a token for a managed identity
+func (opt *Options) msiRequest() (*http.Request, error) {
+	params := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {storageResource},
+	}
+	// select a user assigned identity if set
+	if opt.MSIClientID != "" {
+		params.Set("client_id", opt.MSIClientID)
+	}
+	if opt.MSIObjectID != "" {
+		params.Set("object_id", opt.MSIObjectID)
+	}
+	if opt.MSIResourceID != "" {
+		params.Set("mi_res_id", opt.MSIResourceID)
+	}
+	req, err := http.NewRequest("GET", msiTokenURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	return req, nil
+}
+
+// tokenURL is the URL of the Azure AD token endpoint for the tenant
+func (opt *Options) tokenURL() string {
+	return aadAuthorityURL + "/" + url.PathEscape(opt.Tenant) + "/oauth2/token"
+}
+
+// aadRequest makes the request for a service principal token with
+// the credentials in params
+func (opt *Options) aadRequest(params url.Values) (*http.Request, error) {
+	params.Set("grant_type", "client_credentials")
+	params.Set("client_id", opt.ClientID)
+	params.Set("resource", storageResource)
+	req, err := http.NewRequest("POST", opt.tokenURL(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// refresh fetches a new token
+func (ts *tokenSource) refresh() (err error) {
+	req, err := ts.fetch()
+	if err != nil {
+		return errors.Wrap(err, "failed to make token request")
+	}
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch token")
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read token")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to fetch token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token aadToken
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode token")
+	}
+	if token.AccessToken == "" {
+		return errors.New("no access token returned")
+	}
+	ts.mu.Lock()
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	ts.mu.Unlock()
+	return nil
+}
+
+// credential returns an azblob.TokenCredential which keeps itself
+// up to date with the tokens from ts
+func (ts *tokenSource) credential() azblob.TokenCredential {
+	ts.mu.Lock()
+	token := ts.token
+	ts.mu.Unlock()
+	return azblob.NewTokenCredential(token, ts.refresher)
+}
+
+// refresher is the azblob.TokenRefresher for the credential - it
+// returns how long to wait before it is called again
+func (ts *tokenSource) refresher(credential azblob.TokenCredential) time.Duration {
+	ts.mu.Lock()
+	initial := ts.haveInitial
+	ts.haveInitial = false
+	ts.mu.Unlock()
+	if !initial {
+		err := ts.refresh()
+		if err != nil {
+			fs.Errorf(nil, "azure: failed to refresh token: %v", err)
+			return tokenRetryInterval
+		}
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	credential.SetToken(ts.token)
+	d := time.Until(ts.expiry) - tokenRefreshMargin
+	if d < tokenRetryInterval {
+		d = tokenRetryInterval
+	}
+	return d
+}
+
+// readCertificate reads the certificate and the RSA private key from
+// the PEM file at path
+func readCertificate(path string) (cert *x509.Certificate, key *rsa.PrivateKey, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read client certificate")
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			if cert == nil {
+				cert, err = x509.ParseCertificate(block.Bytes)
+			}
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			var parsed interface{}
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err == nil {
+				var ok bool
+				key, ok = parsed.(*rsa.PrivateKey)
+				if !ok {
+					err = errors.New("private key isn't an RSA key")
+				}
+			}
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to parse %s in client certificate", strings.ToLower(block.Type))
+		}
+	}
+	if cert == nil {
+		return nil, nil, errors.New("no certificate found in client certificate file")
+	}
+	if key == nil {
+		return nil, nil, errors.New("no private key found in client certificate file")
+	}
+	return cert, key, nil
+}
+
+// clientAssertion makes the signed JWT which proves the service
+// principal clientID holds the private key of cert
+func clientAssertion(cert *x509.Certificate, key *rsa.PrivateKey, clientID, audience string) (string, error) {
+	thumbprint := sha1.Sum(cert.Raw)
+	jti := make([]byte, 16)
+	_, err := rand.Read(jti)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.StdEncoding.EncodeToString(thumbprint[:]),
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"iss": clientID,
+		"sub": clientID,
+		"jti": hex.EncodeToString(jti),
+		"nbf": now.Unix(),
+		"exp": now.Add(10 * time.Minute).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	unsigned := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign client assertion")
+	}
+	return unsigned + "." + encode(signature), nil
+}
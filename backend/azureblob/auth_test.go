@@ -0,0 +1,131 @@
+// +build !plan9,!solaris,go1.8
+
+package azureblob
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenServer starts a server which hands out tokens recording the
+// requests it gets in requests
+func tokenServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		*requests = append(*requests, r)
+		_, _ = w.Write([]byte(`{"access_token":"token","expires_in":"3599","token_type":"Bearer"}`))
+	}))
+}
+
+func TestTokenSourceNone(t *testing.T) {
+	ts, err := newTokenSource(http.DefaultClient, &Options{})
+	require.NoError(t, err)
+	assert.Nil(t, ts)
+}
+
+func TestTokenSourceMSI(t *testing.T) {
+	var requests []*http.Request
+	server := tokenServer(t, &requests)
+	defer server.Close()
+	oldURL := msiTokenURL
+	msiTokenURL = server.URL + "/metadata"
+	defer func() { msiTokenURL = oldURL }()
+
+	ts, err := newTokenSource(http.DefaultClient, &Options{UseMSI: true, MSIClientID: "id"})
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	require.Len(t, requests, 1)
+	r := requests[0]
+	assert.Equal(t, "GET", r.Method)
+	assert.Equal(t, "true", r.Header.Get("Metadata"))
+	assert.Equal(t, storageResource, r.Form.Get("resource"))
+	assert.Equal(t, "id", r.Form.Get("client_id"))
+	assert.Equal(t, "token", ts.token)
+	assert.WithinDuration(t, time.Now().Add(3599*time.Second), ts.expiry, time.Minute)
+
+	// the first refresh uses the token already fetched
+	credential := ts.credential()
+	assert.Equal(t, "token", credential.Token())
+	assert.Len(t, requests, 1)
+	d := ts.refresher(credential)
+	assert.Len(t, requests, 2)
+	assert.True(t, d > time.Hour-tokenRefreshMargin-time.Minute, d)
+}
+
+func TestTokenSourceClientSecret(t *testing.T) {
+	var requests []*http.Request
+	server := tokenServer(t, &requests)
+	defer server.Close()
+	oldURL := aadAuthorityURL
+	aadAuthorityURL = server.URL
+	defer func() { aadAuthorityURL = oldURL }()
+
+	_, err := newTokenSource(http.DefaultClient, &Options{ClientID: "app", ClientSecret: "secret"})
+	assert.Error(t, err)
+
+	ts, err := newTokenSource(http.DefaultClient, &Options{Tenant: "tenant", ClientID: "app", ClientSecret: "secret"})
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	require.Len(t, requests, 1)
+	r := requests[0]
+	assert.Equal(t, "POST", r.Method)
+	assert.Equal(t, "/tenant/oauth2/token", r.URL.Path)
+	assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+	assert.Equal(t, "app", r.Form.Get("client_id"))
+	assert.Equal(t, "secret", r.Form.Get("client_secret"))
+	assert.Equal(t, storageResource, r.Form.Get("resource"))
+}
+
+func TestTokenSourceClientCertificate(t *testing.T) {
+	var requests []*http.Request
+	server := tokenServer(t, &requests)
+	defer server.Close()
+	oldURL := aadAuthorityURL
+	aadAuthorityURL = server.URL
+	defer func() { aadAuthorityURL = oldURL }()
+
+	// make a self signed certificate
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rclone"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	dir, err := ioutil.TempDir("", "rclone-azureblob")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	certPath := filepath.Join(dir, "cert.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})...)
+	require.NoError(t, ioutil.WriteFile(certPath, data, 0600))
+
+	ts, err := newTokenSource(http.DefaultClient, &Options{Tenant: "tenant", ClientID: "app", ClientCertificatePath: certPath})
+	require.NoError(t, err)
+	require.NotNil(t, ts)
+	require.Len(t, requests, 1)
+	r := requests[0]
+	assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", r.Form.Get("client_assertion_type"))
+	assert.Equal(t, 3, len(strings.Split(r.Form.Get("client_assertion"), ".")))
+
+	_, _, err = readCertificate(filepath.Join(dir, "notfound.pem"))
+	assert.Error(t, err)
+}
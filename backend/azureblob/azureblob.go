@@ -61,15 +61,49 @@ func init() {
 		Name:        "azureblob",
 		Description: "Microsoft Azure Blob Storage",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name: "account",
 			Help: "Storage Account Name (leave blank to use connection string or SAS URL)",
 		}, {
 			Name: "key",
-			Help: "Storage Account Key (leave blank to use connection string or SAS URL)",
+			Help: "Storage Account Key (leave blank to use connection string, SAS URL or Azure AD auth)",
 		}, {
 			Name: "sas_url",
 			Help: "SAS URL for container level access only\n(leave blank if using account/key or connection string)",
+		}, {
+			Name: "use_msi",
+			Help: `Use a managed service identity to authenticate (only works in Azure)
+
+When true, use a managed identity of the VM or AKS pod rclone is
+running on to get an Azure AD token for the storage account.  If the
+VM has more than one user assigned identity then select the one to
+use with msi_client_id, msi_object_id or msi_mi_res_id.`,
+			Default: false,
+		}, {
+			Name:     "msi_client_id",
+			Help:     "Client ID of the user assigned managed identity to use, if any.\nLeave blank normally.",
+			Advanced: true,
+		}, {
+			Name:     "msi_object_id",
+			Help:     "Object ID of the user assigned managed identity to use, if any.\nLeave blank normally.",
+			Advanced: true,
+		}, {
+			Name:     "msi_mi_res_id",
+			Help:     "Azure resource ID of the user assigned managed identity to use, if any.\nLeave blank normally.",
+			Advanced: true,
+		}, {
+			Name: "tenant",
+			Help: "ID of the Azure AD tenant of the service principal\nLeave blank unless using client_id.",
+		}, {
+			Name: "client_id",
+			Help: "Application (client) ID of the service principal to authenticate as\nLeave blank unless using a service principal.",
+		}, {
+			Name: "client_secret",
+			Help: "Client secret of the service principal\nLeave blank to use client_certificate_path.",
+		}, {
+			Name: "client_certificate_path",
+			Help: "Path to a PEM file with the certificate and unencrypted RSA private key of the service principal\nLeave blank to use client_secret.",
 		}, {
 			Name:     "endpoint",
 			Help:     "Endpoint for the service\nLeave blank normally.",
@@ -121,14 +155,22 @@ tiering blob to "Hot" or "Cool".`,
 
 // Options defines the configuration for this backend
 type Options struct {
-	Account       string        `config:"account"`
-	Key           string        `config:"key"`
-	Endpoint      string        `config:"endpoint"`
-	SASURL        string        `config:"sas_url"`
-	UploadCutoff  fs.SizeSuffix `config:"upload_cutoff"`
-	ChunkSize     fs.SizeSuffix `config:"chunk_size"`
-	ListChunkSize uint          `config:"list_chunk"`
-	AccessTier    string        `config:"access_tier"`
+	Account               string        `config:"account"`
+	Key                   string        `config:"key"`
+	Endpoint              string        `config:"endpoint"`
+	SASURL                string        `config:"sas_url"`
+	UseMSI                bool          `config:"use_msi"`
+	MSIClientID           string        `config:"msi_client_id"`
+	MSIObjectID           string        `config:"msi_object_id"`
+	MSIResourceID         string        `config:"msi_mi_res_id"`
+	Tenant                string        `config:"tenant"`
+	ClientID              string        `config:"client_id"`
+	ClientSecret          string        `config:"client_secret"`
+	ClientCertificatePath string        `config:"client_certificate_path"`
+	UploadCutoff          fs.SizeSuffix `config:"upload_cutoff"`
+	ChunkSize             fs.SizeSuffix `config:"chunk_size"`
+	ListChunkSize         uint          `config:"list_chunk"`
+	AccessTier            string        `config:"access_tier"`
 }
 
 // Fs represents a remote azure server
@@ -364,7 +406,22 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		serviceURL   azblob.ServiceURL
 		containerURL azblob.ContainerURL
 	)
+	tokens, err := newTokenSource(f.client, opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get Azure AD token")
+	}
 	switch {
+	case tokens != nil:
+		if opt.Account == "" {
+			return nil, errors.New("account must be set to use Azure AD auth")
+		}
+		u, err = url.Parse(fmt.Sprintf("https://%s.%s", opt.Account, opt.Endpoint))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to make azure storage url from account and endpoint")
+		}
+		pipeline := f.newPipeline(tokens.credential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{TryTimeout: maxTryTimeout}})
+		serviceURL = azblob.NewServiceURL(*u, pipeline)
+		containerURL = serviceURL.NewContainerURL(container)
 	case opt.Account != "" && opt.Key != "":
 		credential, err := azblob.NewSharedKeyCredential(opt.Account, opt.Key)
 		if err != nil {
@@ -398,7 +455,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 			containerURL = serviceURL.NewContainerURL(container)
 		}
 	default:
-		return nil, errors.New("Need account+key or connectionString or sasURL")
+		return nil, errors.New("Need account+key, sasURL, use_msi or a service principal")
 	}
 	f.svcURL = &serviceURL
 	f.cntURL = &containerURL
@@ -910,6 +967,112 @@ func (f *Fs) Purge() error {
 	return f.deleteContainer()
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "lifecycle",
+	Short: "Read or set the soft delete retention of the storage account.",
+	Long: `Azure Blob Storage can keep deleted and overwritten blobs for a number
+of days, after which they are removed permanently.  This shows the
+soft delete retention policy, or sets it if the delete-retention
+option is supplied.
+
+    rclone backend lifecycle azureblob:
+
+shows something like
+
+    {
+        "enabled": true,
+        "days": 7
+    }
+
+To keep deleted blobs for 30 days
+
+    rclone backend lifecycle azureblob: -o delete-retention=30
+
+To turn soft delete off
+
+    rclone backend lifecycle azureblob: -o delete-retention=0
+
+Note that this applies to the whole storage account, not just the
+container.  Lifecycle management rules can only be set with the Azure
+management API so aren't supported.  Use --dry-run to see the policy
+which would be set.
+`,
+	Opts: map[string]string{
+		"delete-retention": "Keep deleted blobs for this many days, 0 to turn soft delete off",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "lifecycle":
+		return f.lifecycle(opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// retentionPolicy is the soft delete retention returned by lifecycle
+type retentionPolicy struct {
+	Enabled bool   `json:"enabled"`
+	Days    *int32 `json:"days,omitempty"`
+}
+
+// lifecycle reads the soft delete retention policy of the account,
+// setting it first if the delete-retention option is set
+func (f *Fs) lifecycle(opt map[string]string) (policy *retentionPolicy, err error) {
+	ctx := context.Background()
+	if value, ok := opt["delete-retention"]; ok {
+		days, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || days < 0 || days > 365 {
+			return nil, errors.Errorf("bad delete-retention %q: must be a number of days from 0 to 365", value)
+		}
+		newPolicy := &azblob.RetentionPolicy{}
+		if days > 0 {
+			newPolicy.Enabled = true
+			newPolicy.Days = new(int32)
+			*newPolicy.Days = int32(days)
+		}
+		if fs.Config.DryRun {
+			fs.Logf(f, "Not setting soft delete retention as --dry-run")
+			return &retentionPolicy{Enabled: newPolicy.Enabled, Days: newPolicy.Days}, nil
+		}
+		err = f.pacer.Call(func() (bool, error) {
+			_, err := f.svcURL.SetProperties(ctx, azblob.StorageServiceProperties{
+				DeleteRetentionPolicy: newPolicy,
+			})
+			return f.shouldRetry(err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set soft delete retention")
+		}
+		fs.Infof(f, "Set soft delete retention")
+	}
+	var props *azblob.StorageServiceProperties
+	err = f.pacer.Call(func() (bool, error) {
+		var err error
+		props, err = f.svcURL.GetProperties(ctx)
+		return f.shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read soft delete retention")
+	}
+	policy = &retentionPolicy{}
+	if props.DeleteRetentionPolicy != nil {
+		policy.Enabled = props.DeleteRetentionPolicy.Enabled
+		policy.Days = props.DeleteRetentionPolicy.Days
+	}
+	return policy, nil
+}
+
 // Copy src to this remote using server side copy operations.
 //
 // This is stored with the remote path given
@@ -1384,7 +1547,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 	blob := o.getBlobReference()
 	httpHeaders := azblob.BlobHTTPHeaders{}
-	httpHeaders.ContentType = fs.MimeType(o)
+	httpHeaders.ContentType = fs.UploadMimeType(o)
 	// Multipart upload doesn't support MD5 checksums at put block calls, hence calculate
 	// MD5 only for PutBlob requests
 	if size < int64(o.fs.opt.UploadCutoff) {
@@ -1509,6 +1672,7 @@ var (
 	_ fs.Copier    = &Fs{}
 	_ fs.Purger    = &Fs{}
 	_ fs.ListRer   = &Fs{}
+	_ fs.Commander = &Fs{}
 	_ fs.Object    = &Object{}
 	_ fs.MimeTyper = &Object{}
 )
@@ -22,6 +22,11 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
 }
 
+// StatusCode returns the HTTP status code of the error
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
 // Fatal satisfies the Fatal interface
 //
 // It indicates which errors should be treated as fatal
@@ -33,10 +38,19 @@ var _ fserrors.Fataler = (*Error)(nil)
 
 // Bucket describes a B2 bucket
 type Bucket struct {
-	ID        string `json:"bucketId"`
-	AccountID string `json:"accountId"`
-	Name      string `json:"bucketName"`
-	Type      string `json:"bucketType"`
+	ID             string          `json:"bucketId"`
+	AccountID      string          `json:"accountId"`
+	Name           string          `json:"bucketName"`
+	Type           string          `json:"bucketType"`
+	LifecycleRules []LifecycleRule `json:"lifecycleRules,omitempty"`
+}
+
+// LifecycleRule is a single lifecycle rule for a bucket - see
+// https://www.backblaze.com/b2/docs/lifecycle_rules.html
+type LifecycleRule struct {
+	DaysFromHidingToDeleting  *int   `json:"daysFromHidingToDeleting"`  // delete hidden files after this many days, nil for never
+	DaysFromUploadingToHiding *int   `json:"daysFromUploadingToHiding"` // hide files after this many days, nil for never
+	FileNamePrefix            string `json:"fileNamePrefix"`            // the files the rule applies to
 }
 
 // Timestamp is a UTC time when this file was uploaded. It is a base
@@ -210,6 +224,13 @@ type CreateBucketRequest struct {
 	Type      string `json:"bucketType"`
 }
 
+// UpdateBucketRequest is used to change the settings of a bucket
+type UpdateBucketRequest struct {
+	ID             string          `json:"bucketId"`
+	AccountID      string          `json:"accountId"`
+	LifecycleRules []LifecycleRule `json:"lifecycleRules"`
+}
+
 // DeleteBucketRequest is used to create a bucket
 type DeleteBucketRequest struct {
 	ID        string `json:"bucketId"`
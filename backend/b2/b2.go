@@ -24,6 +24,7 @@ import (
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
@@ -134,6 +135,7 @@ free egress for data downloaded through the Cloudflare network.
 Leave blank if you want to use the endpoint provided by Backblaze.`,
 			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
@@ -1065,6 +1067,220 @@ func (f *Fs) CleanUp() error {
 	return f.purge(true)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "undelete",
+	Short: "Restore deleted files by removing their hide markers.",
+	Long: `When rclone deletes a file on B2 it hides it rather than removing it,
+so the previous version can be restored, for example to recover from
+an accidental "rclone sync".  This removes the hide markers to restore
+the most recent version of each deleted file.
+
+    rclone backend undelete b2:bucket/path/to/dir
+
+The filter flags can be used to choose which files are restored, and
+--max-age and --min-age apply to the time the file was deleted, so
+this restores all the files deleted in the last hour:
+
+    rclone backend undelete b2:bucket --max-age 1h
+
+Note that hidden files are deleted permanently by "rclone cleanup" so
+can't be restored after that.  Use --dry-run to see what would be
+restored.
+
+The paths of the files restored are returned.
+`,
+}, {
+	Name:  "lifecycle",
+	Short: "Read or set the lifecycle rule for the bucket.",
+	Long: `This shows the lifecycle rules of the bucket, or sets the rule for the
+path given if any options are supplied, so old versions can be
+expired without using the B2 web interface.
+
+    rclone backend lifecycle b2:bucket
+
+shows something like this
+
+    [
+        {
+            "daysFromHidingToDeleting": 30,
+            "daysFromUploadingToHiding": null,
+            "fileNamePrefix": ""
+        }
+    ]
+
+To delete hidden files (old versions) after 30 days
+
+    rclone backend lifecycle b2:bucket -o daysFromHidingToDeleting=30
+
+The rule applies to the files within the path given, so this only
+hides the files in the logs directory after 7 days and deletes them a
+day later
+
+    rclone backend lifecycle b2:bucket/logs -o daysFromUploadingToHiding=7 -o daysFromHidingToDeleting=1
+
+Setting both options to 0 removes the rule for the path.  Rules for
+other paths are left alone.  Use --dry-run to see the rules which
+would be set.
+`,
+	Opts: map[string]string{
+		"daysFromHidingToDeleting":  "Delete files this many days after they are hidden, 0 for never",
+		"daysFromUploadingToHiding": "Hide files this many days after they are uploaded, 0 for never",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "undelete":
+		return f.undelete()
+	case "lifecycle":
+		return f.lifecycle(opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// undelete restores the hidden files which match the filters by
+// deleting the hide markers which are their current versions
+func (f *Fs) undelete() (restored []string, err error) {
+	last := ""
+	var hidden *api.File // hide marker of the file being listed, if any
+	err = f.list("", true, "", 0, true, func(remote string, object *api.File, isDirectory bool) error {
+		if isDirectory {
+			return nil
+		}
+		if remote != last {
+			// This is the current version
+			last = remote
+			hidden = nil
+			if object.Action == "hide" {
+				hidden = object
+			}
+			return nil
+		}
+		// This is an old version - only look at the one before
+		// the hide marker
+		marker := hidden
+		hidden = nil
+		if marker == nil || object.Action != "upload" {
+			return nil
+		}
+		if !filter.GetActive().Include(remote, object.Size, time.Time(marker.UploadTimestamp)) {
+			return nil
+		}
+		if fs.Config.DryRun {
+			fs.Logf(remote, "Not restoring as --dry-run")
+		} else {
+			err := f.deleteByID(marker.ID, marker.Name)
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(remote, "Failed to restore: %v", err)
+				return nil
+			}
+			fs.Infof(remote, "Restored")
+		}
+		restored = append(restored, remote)
+		return nil
+	})
+	return restored, err
+}
+
+// parseLifecycleDays parses the option called name from opt
+//
+// It returns nil if it isn't set or is 0 as that means never.
+func parseLifecycleDays(opt map[string]string, name string) (days *int, set bool, err error) {
+	value, set := opt[name]
+	if !set {
+		return nil, false, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, false, errors.Errorf("bad %s %q: must be a number of days", name, value)
+	}
+	if n == 0 {
+		return nil, true, nil
+	}
+	return &n, true, nil
+}
+
+// lifecycle reads the lifecycle rules of the bucket, setting the rule
+// for the root first if any options are set
+func (f *Fs) lifecycle(opt map[string]string) (rules []api.LifecycleRule, err error) {
+	if f.bucket == "" {
+		return nil, errors.New("can't read lifecycle rules without a bucket")
+	}
+	newRule := api.LifecycleRule{
+		FileNamePrefix: f.root,
+	}
+	var setHiding, setUploading bool
+	newRule.DaysFromHidingToDeleting, setHiding, err = parseLifecycleDays(opt, "daysFromHidingToDeleting")
+	if err != nil {
+		return nil, err
+	}
+	newRule.DaysFromUploadingToHiding, setUploading, err = parseLifecycleDays(opt, "daysFromUploadingToHiding")
+	if err != nil {
+		return nil, err
+	}
+	var bucket *api.Bucket
+	err = f.listBucketsToFn(func(b *api.Bucket) error {
+		if b.Name == f.bucket {
+			bucket = b
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if bucket == nil {
+		return nil, fs.ErrorDirNotFound
+	}
+	if !setHiding && !setUploading {
+		return bucket.LifecycleRules, nil
+	}
+
+	// Replace the rule for the root keeping any others
+	rules = []api.LifecycleRule{}
+	for _, rule := range bucket.LifecycleRules {
+		if rule.FileNamePrefix != newRule.FileNamePrefix {
+			rules = append(rules, rule)
+		}
+	}
+	if newRule.DaysFromHidingToDeleting != nil || newRule.DaysFromUploadingToHiding != nil {
+		rules = append(rules, newRule)
+	}
+	if fs.Config.DryRun {
+		fs.Logf(f, "Not setting lifecycle rules as --dry-run")
+		return rules, nil
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/b2_update_bucket",
+	}
+	var request = api.UpdateBucketRequest{
+		ID:             bucket.ID,
+		AccountID:      f.info.AccountID,
+		LifecycleRules: rules,
+	}
+	var response api.Bucket
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, &request, &response)
+		return f.shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set lifecycle rules")
+	}
+	fs.Infof(f, "Set lifecycle rules")
+	return response.LifecycleRules, nil
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.SHA1)
@@ -1517,7 +1733,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		ExtraHeaders: map[string]string{
 			"Authorization":  upload.AuthorizationToken,
 			"X-Bz-File-Name": urlEncode(o.fs.root + o.remote),
-			"Content-Type":   fs.MimeType(src),
+			"Content-Type":   fs.UploadMimeType(src),
 			sha1Header:       calculatedSha1,
 			timeHeader:       timeString(modTime),
 		},
@@ -1569,6 +1785,7 @@ var (
 	_ fs.PutStreamer = &Fs{}
 	_ fs.CleanUpper  = &Fs{}
 	_ fs.ListRer     = &Fs{}
+	_ fs.Commander   = &Fs{}
 	_ fs.Object      = &Object{}
 	_ fs.MimeTyper   = &Object{}
 	_ fs.IDer        = &Object{}
@@ -110,7 +110,7 @@ func (f *Fs) newLargeUpload(o *Object, in io.Reader, src fs.ObjectInfo) (up *lar
 	var request = api.StartLargeFileRequest{
 		BucketID:    bucketID,
 		Name:        o.fs.root + remote,
-		ContentType: fs.MimeType(src),
+		ContentType: fs.UploadMimeType(src),
 		Info: map[string]string{
 			timeKey: timeString(modTime),
 		},
@@ -57,6 +57,11 @@ func (e *Error) Error() string {
 	return out
 }
 
+// StatusCode returns the HTTP status code of the error
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
 // Check Error satisfies the error interface
 var _ error = (*Error)(nil)
 
@@ -152,11 +157,17 @@ type CopyFile struct {
 // CreateSharedLink is the request for Public Link
 type CreateSharedLink struct {
 	SharedLink struct {
-		URL    string `json:"url,omitempty"`
-		Access string `json:"access,omitempty"`
+		URL        string `json:"url,omitempty"`
+		Access     string `json:"access,omitempty"`
+		UnsharedAt *Time  `json:"unshared_at,omitempty"`
 	} `json:"shared_link"`
 }
 
+// RemoveSharedLink is the request to remove a Public Link
+type RemoveSharedLink struct {
+	SharedLink *struct{} `json:"shared_link"` // always nil
+}
+
 // UploadSessionRequest is uses in Create Upload Session
 type UploadSessionRequest struct {
 	FolderID string `json:"folder_id,omitempty"` // don't pass for update
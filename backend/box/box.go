@@ -28,6 +28,7 @@ import (
 	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/lib/dircache"
 	"github.com/ncw/rclone/lib/oauthutil"
 	"github.com/ncw/rclone/lib/pacer"
@@ -94,6 +95,7 @@ func init() {
 			Default:  100,
 			Advanced: true,
 		}},
+		CommandHelp: operations.CopyIDHelp("box"),
 	})
 }
 
@@ -266,6 +268,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	f.features = (&fs.Features{
 		CaseInsensitive:         true,
 		CanHaveEmptyDirectories: true,
+		ServerSideAcrossConfigs: true,
 	}).Fill(f)
 	f.srv.SetErrorHandler(errorHandler)
 
@@ -851,8 +854,51 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	return nil
 }
 
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "copyid", "moveid":
+		return nil, operations.CopyOrMoveIDs(f, arg, name == "moveid", f.findID)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// findID finds the file with ID for copyid and moveid
+func (f *Fs) findID(ID string) (o fs.Object, move func(dest string) (fs.Object, error), err error) {
+	var info *api.Item
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       "/files/" + ID,
+		Parameters: fieldsValue(),
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, nil, &info)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't find ID %q", ID)
+	}
+	o, err = f.newObjectWithInfo(restoreReservedChars(info.Name), info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o, nil, nil
+}
+
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
-func (f *Fs) PublicLink(remote string) (string, error) {
+//
+// If expire is set the link will expire after that long and if
+// unlink is set the shared link is removed instead.
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (string, error) {
 	id, err := f.dirCache.FindDir(remote, false)
 	var opts rest.Opts
 	if err == nil {
@@ -870,7 +916,7 @@ func (f *Fs) PublicLink(remote string) (string, error) {
 			return "", err
 		}
 
-		if o.(*Object).publicLink != "" {
+		if o.(*Object).publicLink != "" && !expire.IsSet() && !unlink {
 			return o.(*Object).publicLink, nil
 		}
 
@@ -881,13 +927,26 @@ func (f *Fs) PublicLink(remote string) (string, error) {
 		}
 	}
 
-	shareLink := api.CreateSharedLink{}
+	var request interface{}
+	if unlink {
+		request = &api.RemoveSharedLink{}
+	} else {
+		shareLink := api.CreateSharedLink{}
+		if expire.IsSet() {
+			unsharedAt := api.Time(time.Now().Add(time.Duration(expire)))
+			shareLink.SharedLink.UnsharedAt = &unsharedAt
+		}
+		request = &shareLink
+	}
 	var info api.Item
 	var resp *http.Response
 	err = f.pacer.Call(func() (bool, error) {
-		resp, err = f.srv.CallJSON(&opts, &shareLink, &info)
+		resp, err = f.srv.CallJSON(&opts, request, &info)
 		return shouldRetry(resp, err)
 	})
+	if unlink {
+		return "", err
+	}
 	return info.SharedLink.URL, err
 }
 
@@ -1136,6 +1195,7 @@ var (
 	_ fs.DirMover        = (*Fs)(nil)
 	_ fs.DirCacheFlusher = (*Fs)(nil)
 	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
 )
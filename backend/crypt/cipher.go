@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"crypto/aes"
 	gocipher "crypto/cipher"
+	"crypto/md5"
 	"crypto/rand"
 	"encoding/base32"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"strconv"
 	"strings"
@@ -26,12 +29,15 @@ import (
 const (
 	nameCipherBlockSize = aes.BlockSize
 	fileMagic           = "RCLONE\x00\x00"
+	fileMagicHash       = "RCLONE\x00\x01" // magic for files with a plaintext hash trailer
+	fileMagicScrypt     = "RCL\x02"        // prefix of the magic for files encrypted with non default scrypt parameters
 	fileMagicSize       = len(fileMagic)
 	fileNonceSize       = 24
 	fileHeaderSize      = fileMagicSize + fileNonceSize
 	blockHeaderSize     = secretbox.Overhead
 	blockDataSize       = 64 * 1024
 	blockSize           = blockHeaderSize + blockDataSize
+	fileTrailerSize     = blockHeaderSize + md5.Size // encrypted MD5 of the plaintext
 	encryptedSuffix     = ".bin" // when file name encryption is off we add this suffix to make sure the cloud provider doesn't process the file
 )
 
@@ -45,20 +51,62 @@ var (
 	ErrorEncryptedFileTooShort   = errors.New("file is too short to be encrypted")
 	ErrorEncryptedFileBadHeader  = errors.New("file has truncated block header")
 	ErrorEncryptedBadMagic       = errors.New("not an encrypted file - bad magic string")
+	ErrorEncryptedNoHash         = errors.New("encrypted file has no plaintext hash - was it uploaded without plaintext_hash?")
+	ErrorEncryptedHasHash        = errors.New("encrypted file has a plaintext hash - set plaintext_hash to read it")
+	ErrorEncryptedBadTrailer     = errors.New("failed to authenticate plaintext hash - bad password?")
 	ErrorEncryptedBadBlock       = errors.New("failed to authenticate decrypted block - bad password?")
 	ErrorBadBase32Encoding       = errors.New("bad base32 filename encoding")
 	ErrorFileClosed              = errors.New("file already closed")
 	ErrorNotAnEncryptedFile      = errors.New("not an encrypted file - no \"" + encryptedSuffix + "\" suffix")
 	ErrorBadSeek                 = errors.New("Seek beyond end of file")
+	ErrorBadScryptParams         = errors.New("bad scrypt parameters - N must be a power of 2 greater than 1 and r, p must be between 1 and 255")
+	ErrorEncryptedBadScrypt      = errors.New("encrypted file has different scrypt parameters - set scrypt_n, scrypt_r and scrypt_p to match")
 	defaultSalt                  = []byte{0xA8, 0x0D, 0xF4, 0x3A, 0x8F, 0xBD, 0x03, 0x08, 0xA7, 0xCA, 0xB8, 0x3E, 0x58, 0x1F, 0x86, 0xB1}
 	obfuscQuoteRune              = '!'
 )
 
 // Global variables
 var (
-	fileMagicBytes = []byte(fileMagic)
+	fileMagicBytes     = []byte(fileMagic)
+	fileMagicHashBytes = []byte(fileMagicHash)
 )
 
+// Offsets of the fields in the magic of files encrypted with non
+// default scrypt parameters.  This is the same size as fileMagic so
+// the encrypted sizes don't change.
+const (
+	scryptMagicFlags = len(fileMagicScrypt) // bit 0 set if the file has a plaintext hash trailer
+	scryptMagicLogN  = scryptMagicFlags + 1 // log2 of N
+	scryptMagicR     = scryptMagicLogN + 1  // r
+	scryptMagicP     = scryptMagicR + 1     // p
+)
+
+// ScryptParams are the parameters used with scrypt to derive the
+// keys from the password
+type ScryptParams struct {
+	N int // CPU/memory cost parameter - must be a power of 2 greater than 1
+	R int // block size parameter
+	P int // parallelisation parameter
+}
+
+// DefaultScryptParams are the scrypt parameters rclone has always
+// used.  Changing these on an existing remote will make it
+// unreadable.
+var DefaultScryptParams = ScryptParams{N: 16384, R: 8, P: 1}
+
+// check returns an error if the parameters aren't valid
+func (p ScryptParams) check() error {
+	if p.N <= 1 || p.N&(p.N-1) != 0 || p.R <= 0 || p.R > 255 || p.P <= 0 || p.P > 255 {
+		return ErrorBadScryptParams
+	}
+	return nil
+}
+
+// String returns the parameters as a string
+func (p ScryptParams) String() string {
+	return fmt.Sprintf("N=%d, r=%d, p=%d", p.N, p.R, p.P)
+}
+
 // ReadSeekCloser is the interface of the read handles
 type ReadSeekCloser interface {
 	io.Reader
@@ -144,19 +192,32 @@ type cipher struct {
 	buffers        sync.Pool // encrypt/decrypt buffers
 	cryptoRand     io.Reader // read crypto random numbers from here
 	dirNameEncrypt bool
+	scrypt         ScryptParams // parameters for the key derivation
+	plaintextHash  bool         // if set files have an MD5 of the plaintext in a trailer
 }
 
 // newCipher initialises the cipher.  If salt is "" then it uses a built in salt val
 func newCipher(mode NameEncryptionMode, password, salt string, dirNameEncrypt bool) (*cipher, error) {
+	return newCipherScrypt(mode, password, salt, dirNameEncrypt, DefaultScryptParams)
+}
+
+// newCipherScrypt initialises the cipher using the scrypt parameters
+// passed in to derive the keys.
+func newCipherScrypt(mode NameEncryptionMode, password, salt string, dirNameEncrypt bool, params ScryptParams) (*cipher, error) {
+	err := params.check()
+	if err != nil {
+		return nil, err
+	}
 	c := &cipher{
 		mode:           mode,
 		cryptoRand:     rand.Reader,
 		dirNameEncrypt: dirNameEncrypt,
+		scrypt:         params,
 	}
 	c.buffers.New = func() interface{} {
 		return make([]byte, blockSize)
 	}
-	err := c.Key(password, salt)
+	err = c.Key(password, salt)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +242,7 @@ func (c *cipher) Key(password, salt string) (err error) {
 	if password == "" {
 		key = make([]byte, keySize)
 	} else {
-		key, err = scrypt.Key([]byte(password), saltBytes, 16384, 8, 1, keySize)
+		key, err = scrypt.Key([]byte(password), saltBytes, c.scrypt.N, c.scrypt.R, c.scrypt.P, keySize)
 		if err != nil {
 			return err
 		}
@@ -508,6 +569,33 @@ func (c *cipher) EncryptDirName(in string) string {
 	return c.encryptFileName(in)
 }
 
+// maxDecryptedLeafLength returns the length of the longest leaf name
+// which is at most n bytes long when encrypted with mode, or 0 for no
+// limit if n is 0
+func maxDecryptedLeafLength(mode NameEncryptionMode, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	var max int
+	switch mode {
+	case NameEncryptionStandard:
+		// base32 makes 8 characters from every 5 bytes of the
+		// padded name which always has at least 1 byte of padding
+		padded := (5 * n / 8) / nameCipherBlockSize * nameCipherBlockSize
+		max = padded - 1
+	case NameEncryptionObfuscated:
+		// allow for the "NNN." prefix and each character being
+		// quoted
+		max = (n - 4) / 2
+	default:
+		max = n - len(encryptedSuffix)
+	}
+	if max < 1 {
+		max = 1
+	}
+	return max
+}
+
 // decryptFileName decrypts a file path
 func (c *cipher) decryptFileName(in string) (string, error) {
 	segments := strings.Split(in, "/")
@@ -615,6 +703,63 @@ func (n *nonce) add(x uint64) {
 	}
 }
 
+// magic returns the magic string the files should start with
+//
+// Files encrypted with the default scrypt parameters have the
+// original magic so older versions of rclone can read them.  Otherwise
+// the magic records the scrypt parameters so the file can be
+// identified if it is read with the wrong ones.
+func (c *cipher) magic() []byte {
+	if c.scrypt == DefaultScryptParams {
+		if c.plaintextHash {
+			return fileMagicHashBytes
+		}
+		return fileMagicBytes
+	}
+	magic := make([]byte, fileMagicSize)
+	copy(magic, fileMagicScrypt)
+	if c.plaintextHash {
+		magic[scryptMagicFlags] = 1
+	}
+	for n := c.scrypt.N; n > 1; n >>= 1 {
+		magic[scryptMagicLogN]++
+	}
+	magic[scryptMagicR] = byte(c.scrypt.R)
+	magic[scryptMagicP] = byte(c.scrypt.P)
+	return magic
+}
+
+// parseMagic returns whether the file has a plaintext hash trailer
+// and the scrypt parameters it was encrypted with from its magic.
+//
+// It returns ErrorEncryptedBadMagic if the magic isn't recognised.
+func parseMagic(magic []byte) (plaintextHash bool, params ScryptParams, err error) {
+	switch {
+	case bytes.Equal(magic, fileMagicBytes):
+		return false, DefaultScryptParams, nil
+	case bytes.Equal(magic, fileMagicHashBytes):
+		return true, DefaultScryptParams, nil
+	case bytes.HasPrefix(magic, []byte(fileMagicScrypt)) && magic[scryptMagicFlags] <= 1 && magic[scryptMagicLogN] < 63:
+		params = ScryptParams{
+			N: 1 << magic[scryptMagicLogN],
+			R: int(magic[scryptMagicR]),
+			P: int(magic[scryptMagicP]),
+		}
+		if params.check() == nil {
+			return magic[scryptMagicFlags] == 1, params, nil
+		}
+	}
+	return false, params, ErrorEncryptedBadMagic
+}
+
+// trailerSize returns the size of the trailer on the files
+func (c *cipher) trailerSize() int64 {
+	if c.plaintextHash {
+		return fileTrailerSize
+	}
+	return 0
+}
+
 // encrypter encrypts an io.Reader on the fly
 type encrypter struct {
 	mu       sync.Mutex
@@ -626,6 +771,7 @@ type encrypter struct {
 	bufIndex int
 	bufSize  int
 	err      error
+	hasher   hash.Hash // MD5 of the plaintext if plaintextHash is set
 }
 
 // newEncrypter creates a new file handle encrypting on the fly
@@ -646,8 +792,11 @@ func (c *cipher) newEncrypter(in io.Reader, nonce *nonce) (*encrypter, error) {
 			return nil, err
 		}
 	}
+	if c.plaintextHash {
+		fh.hasher = md5.New()
+	}
 	// Copy magic into buffer
-	copy(fh.buf, fileMagicBytes)
+	copy(fh.buf, c.magic())
 	// Copy nonce into buffer
 	copy(fh.buf[fileMagicSize:], fh.nonce[:])
 	return fh, nil
@@ -669,8 +818,19 @@ func (fh *encrypter) Read(p []byte) (n int, err error) {
 		if n == 0 {
 			// err can't be nil since:
 			// n == len(buf) if and only if err == nil.
+			if err == io.EOF && fh.hasher != nil {
+				// Write the encrypted hash of the
+				// plaintext as the trailer
+				fh.sealTrailer()
+				n = copy(p, fh.buf[fh.bufIndex:fh.bufSize])
+				fh.bufIndex += n
+				return n, nil
+			}
 			return fh.finish(err)
 		}
+		if fh.hasher != nil {
+			_, _ = fh.hasher.Write(readBuf[:n])
+		}
 		// possibly err != nil here, but we will process the
 		// data and the next call to ReadFull will return 0, err
 		// Write nonce to start of block
@@ -687,6 +847,17 @@ func (fh *encrypter) Read(p []byte) (n int, err error) {
 	return n, nil
 }
 
+// sealTrailer puts the encrypted MD5 of the plaintext into the
+// buffer using the next nonce - call with fh.mu held
+func (fh *encrypter) sealTrailer() {
+	sum := fh.hasher.Sum(nil)
+	fh.hasher = nil
+	secretbox.Seal(fh.buf[:0], sum, fh.nonce.pointer(), &fh.c.dataKey)
+	fh.bufIndex = 0
+	fh.bufSize = fileTrailerSize
+	fh.nonce.increment()
+}
+
 // finish sets the final error and tidies up
 func (fh *encrypter) finish(err error) (int, error) {
 	if fh.err != nil {
@@ -745,8 +916,16 @@ func (c *cipher) newDecrypter(rc io.ReadCloser) (*decrypter, error) {
 		return nil, fh.finishAndClose(err)
 	}
 	// check the magic
-	if !bytes.Equal(readBuf[:fileMagicSize], fileMagicBytes) {
-		return nil, fh.finishAndClose(ErrorEncryptedBadMagic)
+	plaintextHash, params, err := parseMagic(readBuf[:fileMagicSize])
+	switch {
+	case err != nil:
+		return nil, fh.finishAndClose(err)
+	case params != c.scrypt:
+		return nil, fh.finishAndClose(errors.Wrapf(ErrorEncryptedBadScrypt, "file has %v, remote has %v", params, c.scrypt))
+	case c.plaintextHash && !plaintextHash:
+		return nil, fh.finishAndClose(ErrorEncryptedNoHash)
+	case !c.plaintextHash && plaintextHash:
+		return nil, fh.finishAndClose(ErrorEncryptedHasHash)
 	}
 	// retrieve the nonce
 	fh.nonce.fromBuf(readBuf[fileMagicSize:])
@@ -1030,6 +1209,8 @@ func (fh *decrypter) finishAndClose(err error) error {
 }
 
 // DecryptData decrypts the data stream
+//
+// If plaintextHash is set then rc must not return the trailer.
 func (c *cipher) DecryptData(rc io.ReadCloser) (io.ReadCloser, error) {
 	out, err := c.newDecrypter(rc)
 	if err != nil {
@@ -1043,6 +1224,9 @@ func (c *cipher) DecryptData(rc io.ReadCloser) (io.ReadCloser, error) {
 // The open function must return a ReadCloser opened to the offset supplied
 //
 // You must use this form of DecryptData if you might want to Seek the file handle
+//
+// If plaintextHash is set then the open function must not return the
+// trailer.
 func (c *cipher) DecryptDataSeek(open OpenRangeSeek, offset, limit int64) (ReadSeekCloser, error) {
 	out, err := c.newDecrypterSeek(open, offset, limit)
 	if err != nil {
@@ -1058,12 +1242,12 @@ func (c *cipher) EncryptedSize(size int64) int64 {
 	if residue != 0 {
 		encryptedSize += blockHeaderSize + residue
 	}
-	return encryptedSize
+	return encryptedSize + c.trailerSize()
 }
 
 // DecryptedSize calculates the size of the data when decrypted
 func (c *cipher) DecryptedSize(size int64) (int64, error) {
-	size -= int64(fileHeaderSize)
+	size -= int64(fileHeaderSize) + c.trailerSize()
 	if size < 0 {
 		return 0, ErrorEncryptedFileTooShort
 	}
@@ -1079,6 +1263,24 @@ func (c *cipher) DecryptedSize(size int64) (int64, error) {
 	return decryptedSize, nil
 }
 
+// decryptTrailer decrypts the trailer of a file with dataSize bytes
+// of plaintext whose header had initialNonce, returning the MD5 of
+// the plaintext as a hex string.
+func (c *cipher) decryptTrailer(initialNonce nonce, dataSize int64, trailer []byte) (string, error) {
+	if len(trailer) != fileTrailerSize {
+		return "", ErrorEncryptedBadTrailer
+	}
+	// The trailer uses the nonce after the last block
+	blocks := (dataSize + blockDataSize - 1) / blockDataSize
+	n := initialNonce
+	n.add(uint64(blocks))
+	sum, ok := secretbox.Open(nil, trailer, n.pointer(), &c.dataKey)
+	if !ok {
+		return "", ErrorEncryptedBadTrailer
+	}
+	return hex.EncodeToString(sum), nil
+}
+
 // check interfaces
 var (
 	_ Cipher         = (*cipher)(nil)
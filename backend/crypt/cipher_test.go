@@ -2,6 +2,7 @@ package crypt
 
 import (
 	"bytes"
+	"crypto/md5"
 	"encoding/base32"
 	"fmt"
 	"io"
@@ -243,6 +244,24 @@ func TestEncryptFileName(t *testing.T) {
 	assert.Equal(t, "160.\u03c2", c.EncryptFileName("\u03a0"))
 }
 
+func TestMaxDecryptedLeafLength(t *testing.T) {
+	assert.Equal(t, 0, maxDecryptedLeafLength(NameEncryptionStandard, 0))
+	assert.Equal(t, 143, maxDecryptedLeafLength(NameEncryptionStandard, 255))
+	assert.Equal(t, 125, maxDecryptedLeafLength(NameEncryptionObfuscated, 255))
+	assert.Equal(t, 251, maxDecryptedLeafLength(NameEncryptionOff, 255))
+	assert.Equal(t, 1, maxDecryptedLeafLength(NameEncryptionStandard, 10))
+	for _, mode := range []NameEncryptionMode{NameEncryptionStandard, NameEncryptionObfuscated, NameEncryptionOff} {
+		c, _ := newCipher(mode, "", "", true)
+		max := maxDecryptedLeafLength(mode, 255)
+		// the worst case for obfuscation is quoting every character
+		assert.True(t, len(c.EncryptFileName(strings.Repeat("!", max))) <= 255, mode.String())
+		assert.True(t, len(c.EncryptFileName(strings.Repeat("a", max))) <= 255, mode.String())
+	}
+	// the next longest name doesn't fit
+	c, _ := newCipher(NameEncryptionStandard, "", "", true)
+	assert.True(t, len(c.EncryptFileName(strings.Repeat("a", 144))) > 255)
+}
+
 func TestDecryptFileName(t *testing.T) {
 	for _, test := range []struct {
 		mode           NameEncryptionMode
@@ -822,6 +841,58 @@ func TestEncryptData(t *testing.T) {
 	}
 }
 
+func TestEncryptDataPlaintextHash(t *testing.T) {
+	for _, size := range []int{0, 1, 16, blockDataSize, blockDataSize + 1, 3 * blockDataSize} {
+		c, err := newCipher(NameEncryptionStandard, "", "", true)
+		require.NoError(t, err)
+		c.plaintextHash = true
+		c.cryptoRand = newRandomSource(1E8) // nodge the crypto rand generator
+		in := make([]byte, size)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		what := fmt.Sprintf("size %d", size)
+
+		encrypted, err := c.EncryptData(bytes.NewBuffer(in))
+		require.NoError(t, err, what)
+		out, err := ioutil.ReadAll(encrypted)
+		require.NoError(t, err, what)
+		assert.Equal(t, c.EncryptedSize(int64(size)), int64(len(out)), what)
+		assert.Equal(t, fileMagicHash, string(out[:fileMagicSize]), what)
+		dataSize, err := c.DecryptedSize(int64(len(out)))
+		require.NoError(t, err, what)
+		assert.Equal(t, int64(size), dataSize, what)
+
+		// Check the trailer has the MD5 of the plaintext
+		var initialNonce nonce
+		initialNonce.fromBuf(out[fileMagicSize:fileHeaderSize])
+		sum, err := c.decryptTrailer(initialNonce, dataSize, out[len(out)-fileTrailerSize:])
+		require.NoError(t, err, what)
+		assert.Equal(t, fmt.Sprintf("%x", md5.Sum(in)), sum, what)
+		_, err = c.decryptTrailer(initialNonce, dataSize+blockDataSize, out[len(out)-fileTrailerSize:])
+		assert.Equal(t, ErrorEncryptedBadTrailer, err, what)
+
+		// Check the data decrypts without the trailer
+		decrypted, err := c.DecryptData(ioutil.NopCloser(bytes.NewBuffer(out[:len(out)-fileTrailerSize])))
+		require.NoError(t, err, what)
+		plain, err := ioutil.ReadAll(decrypted)
+		require.NoError(t, err, what)
+		assert.Equal(t, in, plain, what)
+
+		// Check a cipher without plaintextHash refuses the file
+		c.plaintextHash = false
+		_, err = c.DecryptData(ioutil.NopCloser(bytes.NewBuffer(out)))
+		assert.Equal(t, ErrorEncryptedHasHash, err, what)
+	}
+
+	// Check a cipher with plaintextHash refuses an old file
+	c, err := newCipher(NameEncryptionStandard, "", "", true)
+	require.NoError(t, err)
+	c.plaintextHash = true
+	_, err = c.DecryptData(ioutil.NopCloser(bytes.NewBuffer(file1)))
+	assert.Equal(t, ErrorEncryptedNoHash, err)
+}
+
 func TestNewEncrypter(t *testing.T) {
 	c, err := newCipher(NameEncryptionStandard, "", "", true)
 	assert.NoError(t, err)
@@ -1293,3 +1364,108 @@ func TestKey(t *testing.T) {
 	assert.Equal(t, [32]byte{}, c.nameKey)
 	assert.Equal(t, [16]byte{}, c.nameTweak)
 }
+
+func TestKeyScrypt(t *testing.T) {
+	for _, params := range []ScryptParams{
+		{N: 0, R: 8, P: 1},
+		{N: 1, R: 8, P: 1},
+		{N: 1000, R: 8, P: 1},
+		{N: 1024, R: 0, P: 1},
+		{N: 1024, R: 8, P: 0},
+		{N: 1024, R: 256, P: 1},
+		{N: 1024, R: 8, P: 256},
+	} {
+		_, err := newCipherScrypt(NameEncryptionStandard, "potato", "", true, params)
+		assert.Equal(t, ErrorBadScryptParams, err, fmt.Sprintf("%+v", params))
+	}
+
+	c, err := newCipherScrypt(NameEncryptionStandard, "potato", "", true, DefaultScryptParams)
+	require.NoError(t, err)
+	c2, err := newCipher(NameEncryptionStandard, "potato", "", true)
+	require.NoError(t, err)
+	assert.Equal(t, c2.dataKey, c.dataKey)
+
+	c3, err := newCipherScrypt(NameEncryptionStandard, "potato", "", true, ScryptParams{N: 1024, R: 8, P: 1})
+	require.NoError(t, err)
+	assert.NotEqual(t, c.dataKey, c3.dataKey)
+	assert.NotEqual(t, c.nameKey, c3.nameKey)
+}
+
+func TestEncryptDataScrypt(t *testing.T) {
+	params := ScryptParams{N: 1024, R: 4, P: 2}
+	c, err := newCipherScrypt(NameEncryptionStandard, "potato", "", true, params)
+	require.NoError(t, err)
+	in := []byte("hello")
+	for _, plaintextHash := range []bool{false, true} {
+		what := fmt.Sprintf("plaintextHash=%v", plaintextHash)
+		c.plaintextHash = plaintextHash
+		encrypted, err := c.EncryptData(bytes.NewBuffer(in))
+		require.NoError(t, err, what)
+		out, err := ioutil.ReadAll(encrypted)
+		require.NoError(t, err, what)
+		assert.Equal(t, c.EncryptedSize(int64(len(in))), int64(len(out)), what)
+
+		// Check the header records the parameters
+		gotHash, gotParams, err := parseMagic(out[:fileMagicSize])
+		require.NoError(t, err, what)
+		assert.Equal(t, plaintextHash, gotHash, what)
+		assert.Equal(t, params, gotParams, what)
+
+		// Check it decrypts
+		if plaintextHash {
+			out = out[:len(out)-fileTrailerSize]
+		}
+		decrypted, err := c.DecryptData(ioutil.NopCloser(bytes.NewBuffer(out)))
+		require.NoError(t, err, what)
+		plain, err := ioutil.ReadAll(decrypted)
+		require.NoError(t, err, what)
+		assert.Equal(t, in, plain, what)
+
+		// Check a cipher with the default parameters identifies it
+		c2, err := newCipher(NameEncryptionStandard, "potato", "", true)
+		require.NoError(t, err)
+		c2.plaintextHash = plaintextHash
+		_, err = c2.DecryptData(ioutil.NopCloser(bytes.NewBuffer(out)))
+		assert.Equal(t, ErrorEncryptedBadScrypt, errors.Cause(err), what)
+		assert.Contains(t, err.Error(), "file has N=1024, r=4, p=2", what)
+	}
+
+	// Check files with the default parameters have the old magic
+	// and are identified by a cipher with different ones
+	c2, err := newCipher(NameEncryptionStandard, "potato", "", true)
+	require.NoError(t, err)
+	encrypted, err := c2.EncryptData(bytes.NewBuffer(in))
+	require.NoError(t, err)
+	out, err := ioutil.ReadAll(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, fileMagic, string(out[:fileMagicSize]))
+	c.plaintextHash = false
+	_, err = c.DecryptData(ioutil.NopCloser(bytes.NewBuffer(out)))
+	assert.Equal(t, ErrorEncryptedBadScrypt, errors.Cause(err))
+}
+
+func TestParseMagic(t *testing.T) {
+	for _, test := range []struct {
+		in            string
+		plaintextHash bool
+		params        ScryptParams
+		err           error
+	}{
+		{fileMagic, false, DefaultScryptParams, nil},
+		{fileMagicHash, true, DefaultScryptParams, nil},
+		{"RCL\x02\x00\x0a\x08\x01", false, ScryptParams{N: 1024, R: 8, P: 1}, nil},
+		{"RCL\x02\x01\x14\x10\x02", true, ScryptParams{N: 1 << 20, R: 16, P: 2}, nil},
+		{"RCL\x02\x02\x0a\x08\x01", false, ScryptParams{}, ErrorEncryptedBadMagic},
+		{"RCL\x02\x00\x00\x08\x01", false, ScryptParams{}, ErrorEncryptedBadMagic},
+		{"RCL\x02\x00\x0a\x00\x01", false, ScryptParams{}, ErrorEncryptedBadMagic},
+		{"RCLONE\x00\x02", false, ScryptParams{}, ErrorEncryptedBadMagic},
+		{"potatoes", false, ScryptParams{}, ErrorEncryptedBadMagic},
+	} {
+		plaintextHash, params, err := parseMagic([]byte(test.in))
+		assert.Equal(t, test.err, err, test.in)
+		if test.err == nil {
+			assert.Equal(t, test.plaintextHash, plaintextHash, test.in)
+			assert.Equal(t, test.params, params, test.in)
+		}
+	}
+}
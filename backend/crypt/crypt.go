@@ -4,6 +4,7 @@ package crypt
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/fspath"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/readers"
 	"github.com/pkg/errors"
 )
 
@@ -66,6 +68,53 @@ func init() {
 			Name:       "password2",
 			Help:       "Password or pass phrase for salt. Optional but recommended.\nShould be different to the previous password.",
 			IsPassword: true,
+		}, {
+			Name: "key_file",
+			Help: `Path to a file containing the key material.
+
+If this is set then the contents of the file are used to derive the
+keys instead of the password, so the password should be left blank.
+The file may contain binary data and should be kept as safe as the
+encrypted data.`,
+			Advanced: true,
+		}, {
+			Name: "scrypt_n",
+			Help: `The scrypt CPU/memory cost parameter N used to derive the keys.
+
+This must be a power of 2.  Increasing it makes the key derivation
+slower and uses more memory which increases the cost of brute forcing
+the password.
+
+Note that changing any of the scrypt parameters on an existing remote
+will make the existing data unreadable.`,
+			Default:  DefaultScryptParams.N,
+			Advanced: true,
+		}, {
+			Name:     "scrypt_r",
+			Help:     "The scrypt block size parameter r used to derive the keys.",
+			Default:  DefaultScryptParams.R,
+			Advanced: true,
+		}, {
+			Name:     "scrypt_p",
+			Help:     "The scrypt parallelisation parameter p used to derive the keys.",
+			Default:  DefaultScryptParams.P,
+			Advanced: true,
+		}, {
+			Name: "plaintext_hash",
+			Help: `Store an MD5 hash of the plaintext in each file.
+
+If this is set then an encrypted MD5 hash of the plaintext is stored
+at the end of each file uploaded.  This gives the remote MD5 hashes,
+so "rclone check" and the hash checks after each transfer can verify
+the contents without downloading them - only the header and the
+trailer of each file are read.
+
+The files are written in a different format which older versions of
+rclone can't read, and its size is different.  Set this when making
+a new remote as the files must all be written with the same setting -
+files written with the other setting can't be read.`,
+			Default:  false,
+			Advanced: true,
 		}, {
 			Name: "show_mapping",
 			Help: `For all files listed show how the names encrypt.
@@ -81,6 +130,7 @@ names, or for debugging purposes.`,
 			Hide:     fs.OptionHideConfigurator,
 			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
@@ -90,13 +140,27 @@ func newCipherForConfig(opt *Options) (Cipher, error) {
 	if err != nil {
 		return nil, err
 	}
-	if opt.Password == "" {
+	var password string
+	switch {
+	case opt.KeyFile != "" && opt.Password != "":
+		return nil, errors.New("only one of password and key_file may be set in the config file")
+	case opt.KeyFile != "":
+		key, err := ioutil.ReadFile(opt.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read key_file")
+		}
+		if len(key) == 0 {
+			return nil, errors.New("key_file is empty")
+		}
+		password = string(key)
+	case opt.Password != "":
+		password, err = obscure.Reveal(opt.Password)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt password")
+		}
+	default:
 		return nil, errors.New("password not set in config file")
 	}
-	password, err := obscure.Reveal(opt.Password)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to decrypt password")
-	}
 	var salt string
 	if opt.Password2 != "" {
 		salt, err = obscure.Reveal(opt.Password2)
@@ -104,10 +168,12 @@ func newCipherForConfig(opt *Options) (Cipher, error) {
 			return nil, errors.Wrap(err, "failed to decrypt password2")
 		}
 	}
-	cipher, err := newCipher(mode, password, salt, opt.DirectoryNameEncryption)
+	params := ScryptParams{N: opt.ScryptN, R: opt.ScryptR, P: opt.ScryptP}
+	cipher, err := newCipherScrypt(mode, password, salt, opt.DirectoryNameEncryption, params)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to make cipher")
 	}
+	cipher.plaintextHash = opt.PlaintextHash
 	return cipher, nil
 }
 
@@ -158,6 +224,7 @@ func NewFs(name, rpath string, m configmap.Mapper) (fs.Fs, error) {
 		name:   name,
 		root:   rpath,
 		opt:    *opt,
+		m:      m,
 		cipher: cipher,
 	}
 	// the features here are ones we could support, and they are
@@ -169,6 +236,7 @@ func NewFs(name, rpath string, m configmap.Mapper) (fs.Fs, error) {
 		WriteMimeType:           false,
 		BucketBased:             true,
 		CanHaveEmptyDirectories: true,
+		PartialUploads:          true,
 	}).Fill(f).Mask(wrappedFs).WrapsFs(f, wrappedFs)
 
 	doChangeNotify := wrappedFs.Features().ChangeNotify
@@ -196,6 +264,11 @@ type Options struct {
 	DirectoryNameEncryption bool   `config:"directory_name_encryption"`
 	Password                string `config:"password"`
 	Password2               string `config:"password2"`
+	KeyFile                 string `config:"key_file"`
+	ScryptN                 int    `config:"scrypt_n"`
+	ScryptR                 int    `config:"scrypt_r"`
+	ScryptP                 int    `config:"scrypt_p"`
+	PlaintextHash           bool   `config:"plaintext_hash"`
 	ShowMapping             bool   `config:"show_mapping"`
 }
 
@@ -205,7 +278,8 @@ type Fs struct {
 	name     string
 	root     string
 	opt      Options
-	features *fs.Features // optional features
+	m        configmap.Mapper // config the Fs was made with
+	features *fs.Features     // optional features
 	cipher   Cipher
 }
 
@@ -396,6 +470,9 @@ func (f *Fs) PutStream(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption
 
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
+	if f.opt.PlaintextHash {
+		return hash.Set(hash.MD5)
+	}
 	return hash.Set(hash.None)
 }
 
@@ -539,6 +616,17 @@ func (f *Fs) About() (*fs.Usage, error) {
 	return do()
 }
 
+// PartialNaming returns the partial naming of the wrapped remote with
+// the leaf length limited so the encrypted names fit
+func (f *Fs) PartialNaming() fs.PartialNaming {
+	naming := fs.DefaultPartialNaming
+	if do := f.Fs.Features().PartialNaming; do != nil {
+		naming = do()
+	}
+	naming.MaxLeafLength = maxDecryptedLeafLength(f.cipher.NameEncryptionMode(), naming.MaxLeafLength)
+	return naming
+}
+
 // UnWrap returns the Fs that this Fs is wrapping
 func (f *Fs) UnWrap() fs.Fs {
 	return f.Fs
@@ -666,8 +754,42 @@ func (o *Object) Size() int64 {
 
 // Hash returns the selected checksum of the file
 // If no checksum is available it returns ""
+//
+// With plaintext_hash the MD5 is read from the trailer of the file
+// which needs the header and the trailer to be downloaded.
 func (o *Object) Hash(ht hash.Type) (string, error) {
-	return "", hash.ErrUnsupported
+	if ht != hash.MD5 || !o.f.opt.PlaintextHash {
+		return "", hash.ErrUnsupported
+	}
+	c := o.f.cipher.(*cipher)
+	size := o.Object.Size()
+	dataSize, err := c.DecryptedSize(size)
+	if err != nil {
+		return "", err
+	}
+	// Read the header to check the magic and read the nonce
+	in, err := o.Object.Open(&fs.RangeOption{Start: 0, End: int64(fileHeaderSize) - 1})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open object to read nonce")
+	}
+	d, err := c.newDecrypter(in)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read nonce")
+	}
+	initialNonce := d.initialNonce
+	_ = d.Close()
+	// Read the trailer
+	in, err = o.Object.Open(&fs.RangeOption{Start: size - fileTrailerSize, End: size - 1})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open object to read plaintext hash")
+	}
+	trailer := make([]byte, fileTrailerSize)
+	_, err = io.ReadFull(in, trailer)
+	_ = in.Close()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read plaintext hash")
+	}
+	return c.decryptTrailer(initialNonce, dataSize, trailer)
 }
 
 // UnWrap returns the wrapped Object
@@ -690,8 +812,14 @@ func (o *Object) Open(options ...fs.OpenOption) (rc io.ReadCloser, err error) {
 			openOptions = append(openOptions, option)
 		}
 	}
+	// If there is a plaintext hash trailer then don't read it
+	trailerSize := int64(0)
+	if o.f.opt.PlaintextHash {
+		trailerSize = fileTrailerSize
+	}
+	dataEnd := o.Object.Size() - trailerSize // end of the encrypted data
 	rc, err = o.f.cipher.DecryptDataSeek(func(underlyingOffset, underlyingLimit int64) (io.ReadCloser, error) {
-		if underlyingOffset == 0 && underlyingLimit < 0 {
+		if underlyingOffset == 0 && underlyingLimit < 0 && trailerSize == 0 {
 			// Open with no seek
 			return o.Object.Open(openOptions...)
 		}
@@ -699,12 +827,20 @@ func (o *Object) Open(options ...fs.OpenOption) (rc io.ReadCloser, err error) {
 		end := int64(-1)
 		if underlyingLimit >= 0 {
 			end = underlyingOffset + underlyingLimit - 1
-			if end >= o.Object.Size() {
-				end = -1
+		}
+		if end < 0 || end >= dataEnd {
+			end = -1
+			if trailerSize != 0 {
+				end = dataEnd - 1
 			}
 		}
 		newOpenOptions := append(openOptions, &fs.RangeOption{Start: underlyingOffset, End: end})
-		return o.Object.Open(newOpenOptions...)
+		in, err := o.Object.Open(newOpenOptions...)
+		if err != nil || trailerSize == 0 {
+			return in, err
+		}
+		// Make sure the trailer isn't read if the range is ignored
+		return readers.NewLimitedReadCloser(in, dataEnd-underlyingOffset), nil
 	}, offset, limit)
 	if err != nil {
 		return nil, err
@@ -787,6 +923,8 @@ var (
 	_ fs.UnWrapper       = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.PartialNamer    = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.ObjectInfo      = (*ObjectInfo)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.ObjectUnWrapper = (*Object)(nil)
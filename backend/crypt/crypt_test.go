@@ -83,3 +83,23 @@ func TestObfuscate(t *testing.T) {
 		SkipBadWindowsCharacters: true,
 	})
 }
+
+// TestPlaintextHash runs integration tests against the remote
+func TestPlaintextHash(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	tempdir := filepath.Join(os.TempDir(), "rclone-crypt-test-plaintext-hash")
+	name := "TestCrypt4"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*crypt.Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "crypt"},
+			{Name: name, Key: "remote", Value: tempdir},
+			{Name: name, Key: "password", Value: obscure.MustObscure("potato2")},
+			{Name: name, Key: "filename_encryption", Value: "standard"},
+			{Name: name, Key: "plaintext_hash", Value: "true"},
+		},
+	})
+}
@@ -0,0 +1,385 @@
+package crypt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "rekey",
+	Short: "Re-encrypt the remote with a new password or key file.",
+	Long: `This re-encrypts all the file names and file contents of the crypt
+remote with a new password (and optionally a new salt) or key file, for
+example to follow a credential rotation policy.
+
+    rclone backend rekey crypt: -o password=newpassword -o password2=newsalt
+    rclone backend rekey crypt: -o key_file=/path/to/new.key -o progress=/path/to/rekey.progress
+
+It can also be used to migrate the remote to new scrypt parameters.
+These default to the current ones if not supplied.
+
+    rclone backend rekey crypt: -o password=newpassword -o scrypt_n=1048576
+
+There is no way of re-encrypting data server side so each file is
+downloaded, decrypted with the old key, encrypted with the new key and
+uploaded again.  Once a file has been uploaded with the new key the
+old version is deleted.
+
+If the rekey is interrupted it can be run again with the same options.
+Files whose names were already encrypted with the new key can't be
+decrypted with the old key so they are skipped.  If the file names
+aren't encrypted (filename_encryption = off) then use the "progress"
+option to record which files have been done so they can be skipped.
+
+Use --dry-run to see what would be done.
+
+When the rekey completes without errors the config file is updated
+with the new password or key file.  If there were errors the config
+file is left alone and the rekey should be run again.
+`,
+	Opts: map[string]string{
+		"password":  "The new password or pass phrase (in plain text).",
+		"password2": "The new password or pass phrase for the salt (in plain text).",
+		"key_file":  "Path to a file containing the new key material.",
+		"scrypt_n":  "The new scrypt N parameter.",
+		"scrypt_r":  "The new scrypt r parameter.",
+		"scrypt_p":  "The new scrypt p parameter.",
+		"progress":  "Path to a file to record completed files in so the rekey can be resumed.",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "rekey":
+		return f.rekey(opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// rekeyStats is returned from the rekey command
+type rekeyStats struct {
+	Rekeyed int64 `json:"rekeyed"`
+	Skipped int64 `json:"skipped"`
+	Errors  int64 `json:"errors"`
+}
+
+// newRekeyFs makes a copy of f which encrypts with the password,
+// salt and key file in opt.
+func (f *Fs) newRekeyFs(opt map[string]string) (*Fs, error) {
+	newOpt := f.opt
+	newOpt.Password = ""
+	newOpt.Password2 = ""
+	newOpt.KeyFile = opt["key_file"]
+	if password, ok := opt["password"]; ok {
+		newOpt.Password = obscure.MustObscure(password)
+	}
+	if password2, ok := opt["password2"]; ok && password2 != "" {
+		newOpt.Password2 = obscure.MustObscure(password2)
+	}
+	if newOpt.Password == "" && newOpt.KeyFile == "" {
+		return nil, errors.New("need a new password or key_file to rekey with")
+	}
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"scrypt_n", &newOpt.ScryptN},
+		{"scrypt_r", &newOpt.ScryptR},
+		{"scrypt_p", &newOpt.ScryptP},
+	} {
+		if value, ok := opt[param.name]; ok {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "bad %s", param.name)
+			}
+			*param.value = i
+		}
+	}
+	cipher, err := newCipherForConfig(&newOpt)
+	if err != nil {
+		return nil, err
+	}
+	newF := *f
+	newF.opt = newOpt
+	newF.cipher = cipher
+	return &newF, nil
+}
+
+// readRekeyProgress reads the set of completed files from the
+// progress file, if any
+func readRekeyProgress(progressPath string) (done map[string]struct{}, err error) {
+	done = make(map[string]struct{})
+	if progressPath == "" {
+		return done, nil
+	}
+	in, err := os.Open(progressPath)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open progress file")
+	}
+	defer fs.CheckClose(in, &err)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		done[scanner.Text()] = struct{}{}
+	}
+	return done, scanner.Err()
+}
+
+// rekeyObject re-encrypts o from f into newF
+func (f *Fs) rekeyObject(newF *Fs, o *Object) (err error) {
+	remote := o.Remote()
+	accounting.Stats.Transferring(remote)
+	defer func() {
+		accounting.Stats.DoneTransferring(remote, err == nil)
+	}()
+	if newF.cipher.EncryptFileName(remote) == o.Object.Remote() {
+		return f.rekeyInPlace(newF, o)
+	}
+	in0, err := o.Open()
+	if err != nil {
+		return errors.Wrap(err, "failed to open")
+	}
+	in := accounting.NewAccount(in0, o).WithBuffer()
+	_, err = newF.Put(in, o)
+	closeErr := in.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to upload")
+	}
+	if closeErr != nil {
+		return errors.Wrap(closeErr, "failed to read")
+	}
+	err = o.Object.Remove()
+	if err != nil {
+		return errors.Wrap(err, "failed to remove old version")
+	}
+	return nil
+}
+
+// rekeyTempSuffix is added to the names of objects being rekeyed in
+// place while they are uploaded
+const rekeyTempSuffix = ".rekey"
+
+// rekeyTempInfo is an ObjectInfo with rekeyTempSuffix added to the
+// name
+type rekeyTempInfo struct {
+	fs.ObjectInfo
+}
+
+// Remote returns the temporary name
+func (o rekeyTempInfo) Remote() string {
+	return o.ObjectInfo.Remote() + rekeyTempSuffix
+}
+
+// rekeyInPlace re-encrypts o from f into newF where the encrypted
+// name doesn't change, eg when file name encryption is off.
+//
+// o can't be overwritten while it is being read, so if the
+// underlying remote can move objects the new version is uploaded to
+// a temporary name and moved over o, otherwise o is downloaded to a
+// local temporary file first.
+//
+// The old version is only removed once the new one is in place, and
+// only on remotes which allow duplicate files as the move replaces it
+// on the others.
+func (f *Fs) rekeyInPlace(newF *Fs, o *Object) (err error) {
+	doMove := f.Fs.Features().Move
+	if doMove == nil {
+		return f.rekeyViaTempFile(newF, o)
+	}
+	in0, err := o.Open()
+	if err != nil {
+		return errors.Wrap(err, "failed to open")
+	}
+	in := accounting.NewAccount(in0, o).WithBuffer()
+	tmp, err := newF.Put(in, rekeyTempInfo{o})
+	closeErr := in.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to upload")
+	}
+	if closeErr != nil {
+		if removeErr := tmp.Remove(); removeErr != nil {
+			fs.Errorf(tmp, "Failed to remove temporary upload: %v", removeErr)
+		}
+		return errors.Wrap(closeErr, "failed to read")
+	}
+	_, err = doMove(tmp.(*Object).Object, o.Object.Remote())
+	if err != nil {
+		if removeErr := tmp.Remove(); removeErr != nil {
+			fs.Errorf(tmp, "Failed to remove temporary upload: %v", removeErr)
+		}
+		return errors.Wrap(err, "failed to move new version into place")
+	}
+	if f.Fs.Features().DuplicateFiles {
+		err = o.Object.Remove()
+		if err != nil {
+			return errors.Wrap(err, "failed to remove old version")
+		}
+	}
+	return nil
+}
+
+// rekeyViaTempFile re-encrypts o from f into newF by downloading it
+// to a local temporary file and updating it from there.
+func (f *Fs) rekeyViaTempFile(newF *Fs, o *Object) (err error) {
+	in0, err := o.Open()
+	if err != nil {
+		return errors.Wrap(err, "failed to open")
+	}
+	in := accounting.NewAccount(in0, o).WithBuffer()
+	tmpFile, err := ioutil.TempFile("", "rclone-rekey")
+	if err != nil {
+		_ = in.Close()
+		return errors.Wrap(err, "failed to make temporary file")
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+	_, err = io.Copy(tmpFile, in)
+	closeErr := in.Close()
+	if err != nil {
+		return errors.Wrap(err, "failed to download")
+	}
+	if closeErr != nil {
+		return errors.Wrap(closeErr, "failed to read")
+	}
+	_, err = tmpFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "failed to rewind temporary file")
+	}
+	err = newF.newObject(o.Object).Update(tmpFile, o)
+	if err != nil {
+		return errors.Wrap(err, "failed to upload")
+	}
+	return nil
+}
+
+// rekey re-encrypts the whole remote with the new key in opt
+func (f *Fs) rekey(opt map[string]string) (out interface{}, err error) {
+	newF, err := f.newRekeyFs(opt)
+	if err != nil {
+		return nil, err
+	}
+	progressPath := opt["progress"]
+	done, err := readRekeyProgress(progressPath)
+	if err != nil {
+		return nil, err
+	}
+	var progress *os.File
+	if progressPath != "" && !fs.Config.DryRun {
+		progress, err = os.OpenFile(progressPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open progress file")
+		}
+		defer fs.CheckClose(progress, &err)
+	}
+
+	// Read everything first so we don't see the files we upload
+	var (
+		objects []*Object
+		dirs    []string
+	)
+	err = walk.Walk(f, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			switch x := entry.(type) {
+			case *Object:
+				objects = append(objects, x)
+			case fs.Directory:
+				dirs = append(dirs, x.Remote())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list remote")
+	}
+
+	var stats rekeyStats
+	for _, o := range objects {
+		remote := o.Remote()
+		if _, found := done[remote]; found {
+			stats.Skipped++
+			continue
+		}
+		if fs.Config.DryRun {
+			fs.Logf(o, "Not rekeying as --dry-run")
+			continue
+		}
+		err = f.rekeyObject(newF, o)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(o, "Failed to rekey: %v", err)
+			stats.Errors++
+			continue
+		}
+		fs.Infof(o, "Rekeyed")
+		stats.Rekeyed++
+		if progress != nil {
+			_, err = fmt.Fprintln(progress, remote)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to write progress file")
+			}
+		}
+	}
+
+	// Recreate the directories with the new names deepest first,
+	// removing the old ones if they are now empty
+	if !fs.Config.DryRun && f.cipher.NameEncryptionMode() != NameEncryptionOff && f.opt.DirectoryNameEncryption {
+		sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+		for _, dir := range dirs {
+			err = newF.Mkdir(dir)
+			if err != nil {
+				fs.Errorf(dir, "Failed to make rekeyed directory: %v", err)
+				stats.Errors++
+			}
+			err = f.Rmdir(dir)
+			if err != nil {
+				fs.Debugf(dir, "Failed to remove old directory: %v", err)
+			}
+		}
+	}
+
+	if stats.Errors == 0 && !fs.Config.DryRun {
+		f.saveRekeyConfig(newF)
+	}
+	return &stats, nil
+}
+
+// saveRekeyConfig writes the new keys into the config file
+func (f *Fs) saveRekeyConfig(newF *Fs) {
+	f.m.Set("password", newF.opt.Password)
+	f.m.Set("password2", newF.opt.Password2)
+	f.m.Set("key_file", newF.opt.KeyFile)
+	f.m.Set("scrypt_n", strconv.Itoa(newF.opt.ScryptN))
+	f.m.Set("scrypt_r", strconv.Itoa(newF.opt.ScryptR))
+	f.m.Set("scrypt_p", strconv.Itoa(newF.opt.ScryptP))
+	config.SaveConfig()
+	fs.Logf(f, "Rekey complete - updated password, password2, key_file and scrypt parameters in the config file")
+}
@@ -0,0 +1,144 @@
+package crypt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRekeyFs(t *testing.T) {
+	f := &Fs{
+		opt: Options{
+			FilenameEncryption:      "standard",
+			DirectoryNameEncryption: true,
+			Password:                obscure.MustObscure("potato"),
+			ScryptN:                 DefaultScryptParams.N,
+			ScryptR:                 DefaultScryptParams.R,
+			ScryptP:                 DefaultScryptParams.P,
+		},
+	}
+	var err error
+	f.cipher, err = newCipherForConfig(&f.opt)
+	require.NoError(t, err)
+
+	_, err = f.newRekeyFs(map[string]string{})
+	assert.Error(t, err)
+
+	newF, err := f.newRekeyFs(map[string]string{"password": "sausage"})
+	require.NoError(t, err)
+	assert.Equal(t, "sausage", obscure.MustReveal(newF.opt.Password))
+	assert.Equal(t, "", newF.opt.Password2)
+	assert.NotEqual(t, f.cipher.EncryptFileName("file.txt"), newF.cipher.EncryptFileName("file.txt"))
+	assert.Equal(t, "potato", obscure.MustReveal(f.opt.Password), "original must be unchanged")
+	assert.Equal(t, DefaultScryptParams.N, newF.opt.ScryptN)
+
+	newF, err = f.newRekeyFs(map[string]string{"password": "potato", "scrypt_n": "1024"})
+	require.NoError(t, err)
+	assert.Equal(t, 1024, newF.opt.ScryptN)
+	assert.Equal(t, DefaultScryptParams.R, newF.opt.ScryptR)
+	assert.NotEqual(t, f.cipher.EncryptFileName("file.txt"), newF.cipher.EncryptFileName("file.txt"))
+
+	_, err = f.newRekeyFs(map[string]string{"password": "potato", "scrypt_r": "potato"})
+	assert.Error(t, err)
+	_, err = f.newRekeyFs(map[string]string{"password": "potato", "scrypt_n": "1000"})
+	assert.Error(t, err)
+}
+
+func TestReadRekeyProgress(t *testing.T) {
+	done, err := readRekeyProgress("")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(done))
+
+	dir, err := ioutil.TempDir("", "rclone-rekey-test")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	progressPath := filepath.Join(dir, "progress")
+
+	done, err = readRekeyProgress(progressPath)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(done))
+
+	require.NoError(t, ioutil.WriteFile(progressPath, []byte("one\ndir/two\n"), 0600))
+	done, err = readRekeyProgress(progressPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"one": {}, "dir/two": {}}, done)
+}
+
+// Test rekeying files whose names don't change on the local backend,
+// both with the server side move and with a local temporary file
+func TestRekeyObjectSameName(t *testing.T) {
+	for _, disableMove := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "rclone-rekey-test")
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, os.RemoveAll(dir))
+		}()
+
+		fIface, err := NewFs("TestRekey", "", configmap.Simple{
+			"remote":              dir,
+			"filename_encryption": "off",
+			"password":            obscure.MustObscure("potato"),
+			"scrypt_n":            strconv.Itoa(DefaultScryptParams.N),
+			"scrypt_r":            strconv.Itoa(DefaultScryptParams.R),
+			"scrypt_p":            strconv.Itoa(DefaultScryptParams.P),
+		})
+		require.NoError(t, err)
+		f := fIface.(*Fs)
+		if disableMove {
+			f.Fs.Features().Disable("Move")
+		}
+
+		// Make the contents bigger than the buffers so the file
+		// would be truncated while it was being read if it was
+		// updated in place
+		contents := bytes.Repeat([]byte("potato"), 1024*1024)
+		src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(contents)), true, nil, nil)
+		o, err := f.Put(bytes.NewReader(contents), src)
+		require.NoError(t, err)
+
+		newF, err := f.newRekeyFs(map[string]string{"password": "sausage"})
+		require.NoError(t, err)
+		require.NoError(t, f.rekeyObject(newF, o.(*Object)))
+
+		// Check there is only the rekeyed file in the directory
+		entries, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		assert.Equal(t, "file.txt.bin", entries[0].Name())
+
+		// Check it decrypts with the new key only
+		newO, err := newF.NewObject("file.txt")
+		require.NoError(t, err)
+		in, err := newO.Open()
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		assert.Equal(t, contents, got, "disableMove=%v", disableMove)
+		oldO, err := f.NewObject("file.txt")
+		require.NoError(t, err)
+		in, err = oldO.Open()
+		if err == nil {
+			_, err = ioutil.ReadAll(in)
+			_ = in.Close()
+		}
+		assert.Error(t, err, "disableMove=%v", disableMove)
+	}
+}
+
+// Check the interfaces are satisfied
+var _ fs.ObjectInfo = rekeyTempInfo{}
@@ -33,9 +33,11 @@ import (
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/lib/dircache"
 	"github.com/ncw/rclone/lib/oauthutil"
@@ -370,6 +372,7 @@ will download it anyway.`,
 			Help:     "Number of API calls to allow without sleeping.",
 			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 
 	// register duplicate MIME types first
@@ -616,6 +619,10 @@ func (f *Fs) list(dirIDs []string, title string, directoriesOnly, filesOnly, inc
 		fields += ",owners"
 	}
 
+	if f.opt.TrashedOnly {
+		fields += ",trashedTime"
+	}
+
 	fields = fmt.Sprintf("files(%s),nextPageToken", fields)
 
 OUTER:
@@ -902,6 +909,7 @@ func NewFs(name, path string, m configmap.Mapper) (fs.Fs, error) {
 		ReadMimeType:            true,
 		WriteMimeType:           true,
 		CanHaveEmptyDirectories: true,
+		ServerSideAcrossConfigs: true,
 	}).Fill(f)
 
 	// Create a new authorized Drive client.
@@ -1626,6 +1634,10 @@ func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOpt
 	size := src.Size()
 	modTime := src.ModTime()
 	srcMimeType := fs.MimeTypeFromName(remote)
+	forceMimeType := fs.Config.MimeType != ""
+	if forceMimeType {
+		srcMimeType = fs.UploadMimeType(src)
+	}
 	srcExt := path.Ext(remote)
 	exportExt := ""
 	importMimeType := ""
@@ -1652,6 +1664,8 @@ func (f *Fs) PutUnchecked(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOpt
 	}
 	if importMimeType != "" {
 		createInfo.MimeType = importMimeType
+	} else if forceMimeType {
+		createInfo.MimeType = srcMimeType
 	}
 
 	var info *drive.File
@@ -1907,6 +1921,182 @@ func (f *Fs) CleanUp() error {
 	return nil
 }
 
+var commandHelp = append([]fs.CommandHelp{{
+	Name:  "undelete",
+	Short: "Restore files from the trash.",
+	Long: `This restores files in the trash to their original locations, for
+example to recover from an accidental "rclone sync".
+
+    rclone backend undelete drive:path/to/dir
+
+Only files which were in the directory given are restored.  The
+filter flags can be used to choose which files are restored, and
+--max-age and --min-age apply to the time the file was put in the
+trash, so this restores all the files deleted in the last hour:
+
+    rclone backend undelete drive: --max-age 1h
+
+Any trashed directories the files are in are restored too.  Use
+--dry-run to see what would be restored.
+
+The paths of the files restored are returned.
+`,
+}}, operations.CopyIDHelp("drive")...)
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "undelete":
+		return f.undelete()
+	case "copyid", "moveid":
+		return nil, operations.CopyOrMoveIDs(f, arg, name == "moveid", f.findID)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// findID finds the file with ID for copyid and moveid
+func (f *Fs) findID(ID string) (o fs.Object, move func(dest string) (fs.Object, error), err error) {
+	var info *drive.File
+	err = f.pacer.Call(func() (bool, error) {
+		var err error
+		info, err = f.svc.Files.Get(ID).
+			Fields(partialFields).
+			SupportsTeamDrives(f.isTeamDrive).
+			Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't find ID %q", ID)
+	}
+	if info.MimeType == driveFolderType {
+		return nil, nil, errors.Errorf("ID %q is a directory", ID)
+	}
+	o, err = f.newObjectWithInfo(info.Name, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o == nil {
+		return nil, nil, errors.Errorf("ID %q can't be copied", ID)
+	}
+	// The file may not be below the root so move it by its parents
+	move = func(dest string) (fs.Object, error) {
+		return f.moveFile(ID, strings.Join(info.Parents, ","), dest, o.ModTime())
+	}
+	return o, move, nil
+}
+
+// untrash restores the item with ID from the trash
+func (f *Fs) untrash(ID string) error {
+	info := drive.File{
+		Trashed:         false,
+		ForceSendFields: []string{"Trashed"},
+	}
+	return f.pacer.Call(func() (bool, error) {
+		_, err := f.svc.Files.Update(ID, &info).
+			Fields("").
+			SupportsTeamDrives(f.isTeamDrive).
+			Do()
+		return shouldRetry(err)
+	})
+}
+
+// undelete restores the trashed files in the Fs which match the
+// filters, along with any trashed directories they are in
+func (f *Fs) undelete() (restored []string, err error) {
+	// make a copy of f which lists the trashed files
+	trashed := *f
+	trashed.opt.TrashedOnly = true
+
+	type dirInfo struct {
+		ID      string
+		trashed bool
+	}
+	dirs := map[string]dirInfo{"": {ID: f.dirCache.RootID()}}
+	restoredDirs := map[string]bool{}
+
+	// restore the trashed directories dir is in, parents first
+	var restoreDir func(dir string) error
+	restoreDir = func(dir string) error {
+		if dir == "" || restoredDirs[dir] {
+			return nil
+		}
+		parent := path.Dir(dir)
+		if parent == "." {
+			parent = ""
+		}
+		err := restoreDir(parent)
+		if err != nil {
+			return err
+		}
+		restoredDirs[dir] = true
+		if !dirs[dir].trashed {
+			return nil
+		}
+		if fs.Config.DryRun {
+			fs.Logf(dir, "Not restoring directory as --dry-run")
+			return nil
+		}
+		fs.Infof(dir, "Restoring directory")
+		return f.untrash(dirs[dir].ID)
+	}
+
+	todo := []string{""}
+	for len(todo) > 0 {
+		dir := todo[0]
+		todo = todo[1:]
+		var listErr error
+		_, err = trashed.list([]string{dirs[dir].ID}, "", false, false, false, func(item *drive.File) bool {
+			remote := path.Join(dir, item.Name)
+			if item.MimeType == driveFolderType {
+				dirs[remote] = dirInfo{ID: item.Id, trashed: item.Trashed}
+				todo = append(todo, remote)
+				return false
+			}
+			if !item.Trashed {
+				return false
+			}
+			when, _ := time.Parse(timeFormatIn, item.TrashedTime)
+			if !filter.GetActive().Include(remote, item.Size, when) {
+				return false
+			}
+			listErr = restoreDir(dir)
+			if listErr != nil {
+				return true
+			}
+			if fs.Config.DryRun {
+				fs.Logf(remote, "Not restoring as --dry-run")
+			} else {
+				listErr = f.untrash(item.Id)
+				if listErr != nil {
+					fs.CountError(listErr)
+					fs.Errorf(remote, "Failed to restore: %v", listErr)
+					listErr = nil
+					return false
+				}
+				fs.Infof(remote, "Restored")
+			}
+			restored = append(restored, remote)
+			return false
+		})
+		if err == nil {
+			err = listErr
+		}
+		if err != nil {
+			return restored, err
+		}
+	}
+	return restored, nil
+}
+
 // About gets quota information
 func (f *Fs) About() (*fs.Usage, error) {
 	if f.isTeamDrive {
@@ -1972,8 +2162,14 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 		return nil, err
 	}
 
+	return f.moveFile(srcObj.id, srcParentID, remote, src.ModTime())
+}
+
+// moveFile moves the file with ID from the directories srcParentIDs
+// (comma separated) to remote setting its modification time
+func (f *Fs) moveFile(ID, srcParentIDs, remote string, modTime time.Time) (fs.Object, error) {
 	// Temporary Object under construction
-	dstInfo, err := f.createFileInfo(remote, src.ModTime())
+	dstInfo, err := f.createFileInfo(remote, modTime)
 	if err != nil {
 		return nil, err
 	}
@@ -1983,8 +2179,8 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 	// Do the move
 	var info *drive.File
 	err = f.pacer.Call(func() (bool, error) {
-		info, err = f.svc.Files.Update(srcObj.id, dstInfo).
-			RemoveParents(srcParentID).
+		info, err = f.svc.Files.Update(ID, dstInfo).
+			RemoveParents(srcParentIDs).
 			AddParents(dstParents).
 			Fields(partialFields).
 			SupportsTeamDrives(f.isTeamDrive).
@@ -1999,7 +2195,12 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 }
 
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
-func (f *Fs) PublicLink(remote string) (link string, err error) {
+//
+// If unlink is set then the "anyone" permissions are removed instead.
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if expire.IsSet() {
+		return "", fs.ErrorCantExpireLink
+	}
 	id, err := f.dirCache.FindDir(remote, false)
 	if err == nil {
 		fs.Debugf(f, "attempting to share directory '%s'", remote)
@@ -2012,6 +2213,10 @@ func (f *Fs) PublicLink(remote string) (link string, err error) {
 		id = o.(fs.IDer).ID()
 	}
 
+	if unlink {
+		return "", f.removePublicLink(id)
+	}
+
 	permission := &drive.Permission{
 		AllowFileDiscovery: false,
 		Role:               "reader",
@@ -2033,6 +2238,38 @@ func (f *Fs) PublicLink(remote string) (link string, err error) {
 	return fmt.Sprintf("https://drive.google.com/open?id=%s", id), nil
 }
 
+// removePublicLink removes the "anyone" permissions from the item with id
+func (f *Fs) removePublicLink(id string) error {
+	var list *drive.PermissionList
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		list, err = f.svc.Permissions.List(id).
+			Fields("permissions(id,type)").
+			SupportsTeamDrives(f.isTeamDrive).
+			Do()
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't list permissions")
+	}
+	for _, permission := range list.Permissions {
+		if permission.Type != "anyone" {
+			continue
+		}
+		permissionID := permission.Id
+		err = f.pacer.Call(func() (bool, error) {
+			err := f.svc.Permissions.Delete(id, permissionID).
+				SupportsTeamDrives(f.isTeamDrive).
+				Do()
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "couldn't remove public link")
+		}
+	}
+	return nil
+}
+
 // DirMove moves src, srcRemote to this remote at dstRemote
 // using server side move operations.
 //
@@ -2623,7 +2860,7 @@ func (o *baseObject) update(updateInfo *drive.File, uploadMimeType string, in io
 //
 // The new object may have been created if an error is returned
 func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
-	srcMimeType := fs.MimeType(src)
+	srcMimeType := fs.UploadMimeType(src)
 	updateInfo := &drive.File{
 		MimeType:     srcMimeType,
 		ModifiedTime: src.ModTime().Format(timeFormatOut),
@@ -2646,7 +2883,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	return nil
 }
 func (o *documentObject) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
-	srcMimeType := fs.MimeType(src)
+	srcMimeType := fs.UploadMimeType(src)
 	importMimeType := ""
 	updateInfo := &drive.File{
 		MimeType:     srcMimeType,
@@ -2780,6 +3017,7 @@ var (
 	_ fs.ListRer         = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = (*Object)(nil)
 	_ fs.IDer            = (*Object)(nil)
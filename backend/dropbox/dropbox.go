@@ -745,12 +745,26 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 }
 
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
-func (f *Fs) PublicLink(remote string) (link string, err error) {
+//
+// If expire is set the link will expire after that long and if
+// unlink is set the existing links are revoked instead.
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (link string, err error) {
 	absPath := "/" + path.Join(f.Root(), remote)
+	if unlink {
+		fs.Debugf(f, "attempting to unshare '%s' (absolute path: %s)", remote, absPath)
+		return "", f.revokeSharedLinks(absPath)
+	}
 	fs.Debugf(f, "attempting to share '%s' (absolute path: %s)", remote, absPath)
 	createArg := sharing.CreateSharedLinkWithSettingsArg{
 		Path: absPath,
 	}
+	var settings *sharing.SharedLinkSettings
+	if expire.IsSet() {
+		settings = &sharing.SharedLinkSettings{
+			Expires: time.Now().Add(time.Duration(expire)).UTC().Round(time.Second),
+		}
+		createArg.Settings = settings
+	}
 	var linkRes sharing.IsSharedLinkMetadata
 	err = f.pacer.Call(func() (bool, error) {
 		linkRes, err = f.sharing.CreateSharedLinkWithSettings(&createArg)
@@ -759,37 +773,87 @@ func (f *Fs) PublicLink(remote string) (link string, err error) {
 
 	if err != nil && strings.Contains(err.Error(), sharing.CreateSharedLinkWithSettingsErrorSharedLinkAlreadyExists) {
 		fs.Debugf(absPath, "has a public link already, attempting to retrieve it")
-		listArg := sharing.ListSharedLinksArg{
-			Path:       absPath,
-			DirectOnly: true,
-		}
-		var listRes *sharing.ListSharedLinksResult
-		err = f.pacer.Call(func() (bool, error) {
-			listRes, err = f.sharing.ListSharedLinks(&listArg)
-			return shouldRetry(err)
-		})
+		var links []sharing.IsSharedLinkMetadata
+		links, err = f.listSharedLinks(absPath)
 		if err != nil {
 			return
 		}
-		if len(listRes.Links) == 0 {
+		if len(links) == 0 {
 			err = errors.New("Dropbox says the sharing link already exists, but list came back empty")
 			return
 		}
-		linkRes = listRes.Links[0]
+		linkRes = links[0]
+		if settings != nil {
+			fs.Debugf(absPath, "setting expiry on existing public link")
+			modifyArg := sharing.ModifySharedLinkSettingsArgs{
+				Url:      sharedLinkURL(linkRes),
+				Settings: settings,
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				linkRes, err = f.sharing.ModifySharedLinkSettings(&modifyArg)
+				return shouldRetry(err)
+			})
+		}
 	}
 	if err == nil {
-		switch res := linkRes.(type) {
-		case *sharing.FileLinkMetadata:
-			link = res.Url
-		case *sharing.FolderLinkMetadata:
-			link = res.Url
-		default:
-			err = fmt.Errorf("Don't know how to extract link, response has unknown format: %T", res)
+		link = sharedLinkURL(linkRes)
+		if link == "" {
+			err = fmt.Errorf("Don't know how to extract link, response has unknown format: %T", linkRes)
 		}
 	}
 	return
 }
 
+// sharedLinkURL returns the URL of the shared link or "" if unknown
+func sharedLinkURL(linkRes sharing.IsSharedLinkMetadata) string {
+	switch res := linkRes.(type) {
+	case *sharing.FileLinkMetadata:
+		return res.Url
+	case *sharing.FolderLinkMetadata:
+		return res.Url
+	}
+	return ""
+}
+
+// listSharedLinks lists the shared links which point directly at absPath
+func (f *Fs) listSharedLinks(absPath string) (links []sharing.IsSharedLinkMetadata, err error) {
+	listArg := sharing.ListSharedLinksArg{
+		Path:       absPath,
+		DirectOnly: true,
+	}
+	var listRes *sharing.ListSharedLinksResult
+	err = f.pacer.Call(func() (bool, error) {
+		listRes, err = f.sharing.ListSharedLinks(&listArg)
+		return shouldRetry(err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listRes.Links, nil
+}
+
+// revokeSharedLinks revokes all the shared links which point directly
+// at absPath
+func (f *Fs) revokeSharedLinks(absPath string) error {
+	links, err := f.listSharedLinks(absPath)
+	if err != nil {
+		return errors.Wrap(err, "couldn't list public links")
+	}
+	for _, linkRes := range links {
+		revokeArg := sharing.RevokeSharedLinkArg{
+			Url: sharedLinkURL(linkRes),
+		}
+		err = f.pacer.Call(func() (bool, error) {
+			err = f.sharing.RevokeSharedLink(&revokeArg)
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "couldn't remove public link")
+		}
+	}
+	return nil
+}
+
 // DirMove moves src, srcRemote to this remote at dstRemote
 // using server side move operations.
 //
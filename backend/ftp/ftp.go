@@ -70,6 +70,7 @@ type Fs struct {
 	dialAddr string
 	poolMu   sync.Mutex
 	pool     []*ftp.ServerConn
+	drain    *time.Timer // used to drain the pool when we stop using the connections
 }
 
 // Object describes an FTP file
@@ -133,6 +134,11 @@ func (f *Fs) getFtpConnection() (c *ftp.ServerConn, err error) {
 		c = f.pool[0]
 		f.pool = f.pool[1:]
 	}
+	if f.drain != nil {
+		// stop draining while a connection is in use - it is
+		// restarted when the connection is returned
+		f.drain.Stop()
+	}
 	f.poolMu.Unlock()
 	if c != nil {
 		return c, nil
@@ -163,9 +169,31 @@ func (f *Fs) putFtpConnection(pc **ftp.ServerConn, err error) {
 	}
 	f.poolMu.Lock()
 	f.pool = append(f.pool, c)
+	if idleTimeout := fs.Config.IdleTimeout; idleTimeout > 0 {
+		if f.drain == nil {
+			f.drain = time.AfterFunc(idleTimeout, f.drainPool)
+		} else {
+			f.drain.Reset(idleTimeout)
+		}
+	}
 	f.poolMu.Unlock()
 }
 
+// drainPool closes the connections in the pool when they haven't
+// been used for --idle-timeout
+func (f *Fs) drainPool() {
+	f.poolMu.Lock()
+	pool := f.pool
+	f.pool = nil
+	f.poolMu.Unlock()
+	if len(pool) > 0 {
+		fs.Debugf(f, "Closing %d unused connections", len(pool))
+	}
+	for _, c := range pool {
+		_ = c.Quit()
+	}
+}
+
 // NewFs constructs an Fs from the path, container:path
 func NewFs(name, root string, m configmap.Mapper) (ff fs.Fs, err error) {
 	// defer fs.Trace(nil, "name=%q, root=%q", name, root)("fs=%v, err=%v", &ff, &err)
@@ -201,6 +229,7 @@ func NewFs(name, root string, m configmap.Mapper) (ff fs.Fs, err error) {
 	}
 	f.features = (&fs.Features{
 		CanHaveEmptyDirectories: true,
+		PartialUploads:          true,
 	}).Fill(f)
 	// Make a connection and pool it to return errors early
 	c, err := f.getFtpConnection()
@@ -374,6 +403,17 @@ func (f *Fs) List(dir string) (entries fs.DirEntries, err error) {
 	return entries, nil
 }
 
+// PartialNaming returns how partial uploads are named.
+//
+// They aren't hidden with a leading . as some FTP servers refuse
+// those names.
+func (f *Fs) PartialNaming() fs.PartialNaming {
+	return fs.PartialNaming{
+		Suffix:        ".partial",
+		MaxLeafLength: 255,
+	}
+}
+
 // Hashes are not supported
 func (f *Fs) Hashes() hash.Set {
 	return 0
@@ -772,9 +812,10 @@ func (o *Object) Remove() (err error) {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Mover        = &Fs{}
+	_ fs.DirMover     = &Fs{}
+	_ fs.PutStreamer  = &Fs{}
+	_ fs.PartialNamer = &Fs{}
+	_ fs.Object       = &Object{}
 )
@@ -79,7 +79,8 @@ func init() {
 		Config: func(name string, m configmap.Mapper) {
 			saFile, _ := m.Get("service_account_file")
 			saCreds, _ := m.Get("service_account_credentials")
-			if saFile != "" || saCreds != "" {
+			envAuth, _ := m.Get("env_auth")
+			if saFile != "" || saCreds != "" || envAuth == "true" {
 				return
 			}
 			err := oauthutil.Config("google cloud storage", name, m, storageConfig)
@@ -103,6 +104,21 @@ func init() {
 			Name: "service_account_credentials",
 			Help: "Service Account Credentials JSON blob\nLeave blank normally.\nNeeded only if you want use SA instead of interactive login.",
 			Hide: fs.OptionHideBoth,
+		}, {
+			Name:    "env_auth",
+			Help:    "Get GCP credentials from runtime (environment variables or instance meta data if no env vars).\nOnly applies if service_account_file and service_account_credentials is blank.",
+			Default: false,
+			Examples: []fs.OptionExample{{
+				Value: "false",
+				Help:  "Enter credentials in the next step",
+			}, {
+				Value: "true",
+				Help:  "Get GCP IAM credentials from the environment (env vars, GKE workload identity or GCE metadata)",
+			}},
+		}, {
+			Name:     "impersonate",
+			Help:     "Email of a service account to impersonate.\nLeave blank normally.\nThe credentials in use must be allowed to make tokens for it (roles/iam.serviceAccountTokenCreator).",
+			Advanced: true,
 		}, {
 			Name: "object_acl",
 			Help: "Access Control List for new objects.",
@@ -239,6 +255,8 @@ type Options struct {
 	ProjectNumber             string `config:"project_number"`
 	ServiceAccountFile        string `config:"service_account_file"`
 	ServiceAccountCredentials string `config:"service_account_credentials"`
+	EnvAuth                   bool   `config:"env_auth"`
+	Impersonate               string `config:"impersonate"`
 	ObjectACL                 string `config:"object_acl"`
 	BucketACL                 string `config:"bucket_acl"`
 	Location                  string `config:"location"`
@@ -339,8 +357,8 @@ func parsePath(path string) (bucket, directory string, err error) {
 	return
 }
 
-func getServiceAccountClient(credentialsData []byte) (*http.Client, error) {
-	conf, err := google.JWTConfigFromJSON(credentialsData, storageConfig.Scopes...)
+func getServiceAccountClient(credentialsData []byte, scopes []string) (*http.Client, error) {
+	conf, err := google.JWTConfigFromJSON(credentialsData, scopes...)
 	if err != nil {
 		return nil, errors.Wrap(err, "error processing credentials")
 	}
@@ -348,6 +366,18 @@ func getServiceAccountClient(credentialsData []byte) (*http.Client, error) {
 	return oauth2.NewClient(ctxWithSpecialClient, conf.TokenSource(ctxWithSpecialClient)), nil
 }
 
+// getEnvAuthClient returns a client using the Application Default
+// Credentials - GOOGLE_APPLICATION_CREDENTIALS, the gcloud credentials
+// or the metadata server on GCE and GKE (including workload identity).
+func getEnvAuthClient(scopes []string) (*http.Client, error) {
+	ctxWithSpecialClient := oauthutil.Context(fshttp.NewClient(fs.Config))
+	ts, err := google.DefaultTokenSource(ctxWithSpecialClient, scopes...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding default credentials")
+	}
+	return oauth2.NewClient(ctxWithSpecialClient, ts), nil
+}
+
 // NewFs constructs an Fs from the path, bucket:path
 func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	var oAuthClient *http.Client
@@ -373,17 +403,33 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		}
 		opt.ServiceAccountCredentials = string(loadedCreds)
 	}
+	// impersonating needs the base credentials to be able to call
+	// the IAM credentials API
+	scopes := storageConfig.Scopes
+	if opt.Impersonate != "" {
+		scopes = []string{cloudPlatformScope}
+	}
 	if opt.ServiceAccountCredentials != "" {
-		oAuthClient, err = getServiceAccountClient([]byte(opt.ServiceAccountCredentials))
+		oAuthClient, err = getServiceAccountClient([]byte(opt.ServiceAccountCredentials), scopes)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed configuring Google Cloud Storage Service Account")
 		}
+	} else if opt.EnvAuth {
+		oAuthClient, err = getEnvAuthClient(scopes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed configuring Google Cloud Storage from the environment")
+		}
+	} else if opt.Impersonate != "" {
+		return nil, errors.New("impersonate needs service_account_file, service_account_credentials or env_auth to be set")
 	} else {
 		oAuthClient, _, err = oauthutil.NewClient(name, m, storageConfig)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to configure Google Cloud Storage")
 		}
 	}
+	if opt.Impersonate != "" {
+		oAuthClient = newImpersonateClient(oAuthClient, opt.Impersonate)
+	}
 
 	bucket, directory, err := parsePath(root)
 	if err != nil {
@@ -398,9 +444,10 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		pacer:  pacer.New().SetMinSleep(minSleep).SetPacer(pacer.GoogleDrivePacer),
 	}
 	f.features = (&fs.Features{
-		ReadMimeType:  true,
-		WriteMimeType: true,
-		BucketBased:   true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		BucketBased:             true,
+		ServerSideAcrossConfigs: true,
 	}).Fill(f)
 
 	// Create a new authorized Drive client.
@@ -970,7 +1017,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	object := storage.Object{
 		Bucket:      o.fs.bucket,
 		Name:        o.fs.root + o.remote,
-		ContentType: fs.MimeType(src),
+		ContentType: fs.UploadMimeType(src),
 		Updated:     modTime.Format(timeFormatOut), // Doesn't get set
 		Metadata:    metadataFromModTime(modTime),
 	}
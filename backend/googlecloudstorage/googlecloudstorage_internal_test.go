@@ -0,0 +1,55 @@
+// +build go1.9
+
+package googlecloudstorage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonateTokenSource(t *testing.T) {
+	var gotPath string
+	var gotRequest generateAccessTokenRequest
+	expiry := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotRequest))
+		if r.URL.Path != "/sa@example.iam.gserviceaccount.com:generateAccessToken" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(generateAccessTokenResponse{
+			AccessToken: "token",
+			ExpireTime:  expiry,
+		})
+	}))
+	defer server.Close()
+	oldURL := generateAccessTokenURL
+	generateAccessTokenURL = server.URL + "/%s:generateAccessToken"
+	defer func() { generateAccessTokenURL = oldURL }()
+
+	ts := &impersonateTokenSource{
+		client: server.Client(),
+		target: "sa@example.iam.gserviceaccount.com",
+		scopes: storageConfig.Scopes,
+	}
+	token, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "/sa@example.iam.gserviceaccount.com:generateAccessToken", gotPath)
+	assert.Equal(t, storageConfig.Scopes, gotRequest.Scope)
+	assert.Equal(t, "3600s", gotRequest.Lifetime)
+	assert.Equal(t, "token", token.AccessToken)
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.True(t, token.Expiry.Equal(expiry))
+
+	ts.target = "other@example.iam.gserviceaccount.com"
+	_, err = ts.Token()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
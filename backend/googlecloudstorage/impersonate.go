@@ -0,0 +1,92 @@
+// +build go1.9
+
+package googlecloudstorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fshttp"
+	"github.com/ncw/rclone/lib/oauthutil"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// scope the base credentials need to call the IAM credentials API
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	// lifetime of the access tokens asked for when impersonating
+	impersonateLifetime = time.Hour
+)
+
+// URL of the IAM credentials API call which makes access tokens for
+// a service account - a var so the tests can change it
+var generateAccessTokenURL = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+
+// impersonateTokenSource makes access tokens for a target service
+// account by calling the IAM credentials API with the credentials of
+// client.
+type impersonateTokenSource struct {
+	client *http.Client // client authorized with the base credentials
+	target string       // email of the service account to impersonate
+	scopes []string     // scopes the access tokens should have
+}
+
+// generateAccessTokenRequest is the body of a generateAccessToken call
+type generateAccessTokenRequest struct {
+	Scope    []string `json:"scope"`
+	Lifetime string   `json:"lifetime"`
+}
+
+// generateAccessTokenResponse is the reply to a generateAccessToken call
+type generateAccessTokenResponse struct {
+	AccessToken string    `json:"accessToken"`
+	ExpireTime  time.Time `json:"expireTime"`
+}
+
+// Token makes a new access token for the target service account
+func (ts *impersonateTokenSource) Token() (token *oauth2.Token, err error) {
+	body, err := json.Marshal(generateAccessTokenRequest{
+		Scope:    ts.scopes,
+		Lifetime: fmt.Sprintf("%ds", int64(impersonateLifetime/time.Second)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	callURL := fmt.Sprintf(generateAccessTokenURL, url.PathEscape(ts.target))
+	resp, err := ts.client.Post(callURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to impersonate %q", ts.target)
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to impersonate %q: %s", ts.target, resp.Status)
+	}
+	var result generateAccessTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode token impersonating %q", ts.target)
+	}
+	return &oauth2.Token{
+		AccessToken: result.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      result.ExpireTime,
+	}, nil
+}
+
+// newImpersonateClient returns a client which authorizes its requests
+// as the service account target using the credentials of base.
+func newImpersonateClient(base *http.Client, target string) *http.Client {
+	ts := oauth2.ReuseTokenSource(nil, &impersonateTokenSource{
+		client: base,
+		target: target,
+		scopes: storageConfig.Scopes,
+	})
+	ctx := oauthutil.Context(fshttp.NewClient(fs.Config))
+	return oauth2.NewClient(ctx, ts)
+}
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -136,6 +137,10 @@ type Object struct {
 	size           int64  // file metadata - always present
 	mode           os.FileMode
 	modTime        time.Time
+	uid, gid       uint32               // owner of the file if hasOwner is set
+	hasOwner       bool                 // set if uid and gid could be read
+	linkID         string               // ID shared by the hard links to the file if known
+	links          int                  // number of hard links to the file if known
 	hashes         map[hash.Type]string // Hashes
 	translatedLink bool                 // Is this object a translated link
 }
@@ -172,6 +177,8 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	f.features = (&fs.Features{
 		CaseInsensitive:         f.caseInsensitive(),
 		CanHaveEmptyDirectories: true,
+		PartialUploads:          true,
+		SlowHash:                true,
 	}).Fill(f)
 	if opt.FollowSymlinks {
 		f.lstat = os.Stat
@@ -489,6 +496,16 @@ func (f *Fs) Rmdir(dir string) error {
 	return os.Remove(root)
 }
 
+// DirSetModTime sets the modification time of the directory dir
+func (f *Fs) DirSetModTime(dir string, modTime time.Time) error {
+	root := f.cleanPath(filepath.Join(f.root, dir))
+	err := os.Chtimes(root, modTime, modTime)
+	if os.IsNotExist(err) {
+		return fs.ErrorDirNotFound
+	}
+	return err
+}
+
 // Precision of the file system
 func (f *Fs) Precision() (precision time.Duration) {
 	f.precisionOk.Do(func() {
@@ -625,6 +642,67 @@ func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 	return dstObj, nil
 }
 
+// HardLink makes remote a hard link to the existing object src,
+// replacing remote if it exists.
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantHardLink
+func (f *Fs) HardLink(src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't hard link - not same remote type")
+		return nil, fs.ErrorCantHardLink
+	}
+
+	// Temporary Object under construction
+	dstObj := f.newObject(remote, "")
+
+	// Check it is a file if it exists
+	err := dstObj.lstat()
+	if os.IsNotExist(err) {
+		// OK
+	} else if err != nil {
+		return nil, err
+	} else if !dstObj.fs.isRegular(dstObj.mode) {
+		// It isn't a file
+		return nil, errors.New("can't hard link onto non-file")
+	}
+
+	// Create destination
+	err = dstObj.mkdirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make the link next to the destination then rename it into
+	// place so an existing file is replaced atomically
+	tmpPath := dstObj.path + ".rclone-link"
+	_ = os.Remove(tmpPath)
+	err = os.Link(srcObj.path, tmpPath)
+	if err != nil {
+		// probably trying to link across file system boundaries
+		// or the file system doesn't support hard links.
+		fs.Debugf(src, "Can't hard link: %v", err)
+		return nil, fs.ErrorCantHardLink
+	}
+	err = os.Rename(tmpPath, dstObj.path)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	// Update the info
+	err = dstObj.lstat()
+	if err != nil {
+		return nil, err
+	}
+
+	return dstObj, nil
+}
+
 // DirMove moves src, srcRemote to this remote at dstRemote
 // using server side move operations.
 //
@@ -672,6 +750,16 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	return nil
 }
 
+// PartialNaming returns how partial uploads are named.
+//
+// 255 bytes is the longest leaf name most file systems allow.
+func (f *Fs) PartialNaming() fs.PartialNaming {
+	return fs.PartialNaming{
+		Suffix:        ".partial",
+		MaxLeafLength: 255,
+	}
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Supported
@@ -1011,6 +1099,30 @@ func (o *Object) setMetadata(info os.FileInfo) {
 	if o.mode != info.Mode() {
 		o.mode = info.Mode()
 	}
+	if uid, gid, ok := readOwner(info); ok && (!o.hasOwner || o.uid != uid || o.gid != gid) {
+		o.uid, o.gid, o.hasOwner = uid, gid, true
+	}
+	if linkID, links := readHardLink(info); o.linkID != linkID || o.links != links {
+		o.linkID, o.links = linkID, links
+	}
+}
+
+// HardLinkID returns an ID shared by all the hard links to the file
+// and the number of links to it
+func (o *Object) HardLinkID() (id string, links int) {
+	return o.linkID, o.links
+}
+
+// Metadata returns the permissions and owner of the file
+func (o *Object) Metadata() (fs.Metadata, error) {
+	m := fs.Metadata{
+		"mode": strconv.FormatUint(uint64(o.mode.Perm()), 8),
+	}
+	if o.hasOwner {
+		m["uid"] = strconv.FormatUint(uint64(o.uid), 10)
+		m["gid"] = strconv.FormatUint(uint64(o.gid), 10)
+	}
+	return m, nil
 }
 
 // Stat a Object into info
@@ -1139,10 +1251,15 @@ func cleanWindowsName(f *Fs, name string) string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Purger      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.Purger         = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.HardLinker     = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.DirSetModTimer = &Fs{}
+	_ fs.PartialNamer   = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.Metadataer     = &Object{}
+	_ fs.HardLinkIDer   = &Object{}
 )
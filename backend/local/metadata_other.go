@@ -0,0 +1,20 @@
+// Owner reading functions
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package local
+
+import "os"
+
+// readOwner reads the numeric user and group IDs of the owner from a
+// valid os.FileInfo, returning ok false if it fails.
+func readOwner(fi os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+// readHardLink reads an ID for the file shared by all its hard links
+// and the number of links to it from a valid os.FileInfo, returning
+// "", 0 if it fails.
+func readHardLink(fi os.FileInfo) (id string, links int) {
+	return "", 0
+}
@@ -0,0 +1,32 @@
+// Owner reading functions
+
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package local
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// readOwner reads the numeric user and group IDs of the owner from a
+// valid os.FileInfo, returning ok false if it fails.
+func readOwner(fi os.FileInfo) (uid, gid uint32, ok bool) {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint32(statT.Uid), uint32(statT.Gid), true // nolint: unconvert
+}
+
+// readHardLink reads an ID for the file shared by all its hard links
+// and the number of links to it from a valid os.FileInfo, returning
+// "", 0 if it fails.
+func readHardLink(fi os.FileInfo) (id string, links int) {
+	statT, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", 0
+	}
+	return fmt.Sprintf("%d:%d", uint64(statT.Dev), uint64(statT.Ino)), int(statT.Nlink) // nolint: unconvert
+}
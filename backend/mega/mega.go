@@ -795,7 +795,13 @@ func (f *Fs) Hashes() hash.Set {
 }
 
 // PublicLink generates a public link to the remote path (usually readable by anyone)
-func (f *Fs) PublicLink(remote string) (link string, err error) {
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if expire.IsSet() {
+		return "", fs.ErrorCantExpireLink
+	}
+	if unlink {
+		return "", fs.ErrorCantUnlink
+	}
 	root, err := f.findRoot(false)
 	if err != nil {
 		return "", errors.Wrap(err, "PublicLink failed to find root node")
@@ -253,8 +253,9 @@ type MoveItemRequest struct {
 //CreateShareLinkRequest is the request to create a sharing link
 //Always Type:view and Scope:anonymous for public sharing
 type CreateShareLinkRequest struct {
-	Type  string `json:"type"`            //Link type in View, Edit or Embed
-	Scope string `json:"scope,omitempty"` //Optional. Scope in anonymousi, organization
+	Type   string     `json:"type"`                         //Link type in View, Edit or Embed
+	Scope  string     `json:"scope,omitempty"`              //Optional. Scope in anonymousi, organization
+	Expiry *Timestamp `json:"expirationDateTime,omitempty"` //Optional. When the link expires
 }
 
 //CreateShareLinkResponse is the response from CreateShareLinkRequest
@@ -272,6 +273,12 @@ type CreateShareLinkResponse struct {
 	} `json:"link"`
 }
 
+// PermissionsResponse is the response to listing the permissions of
+// an item - the sharing links are permissions with a Link
+type PermissionsResponse struct {
+	Value []CreateShareLinkResponse `json:"value"`
+}
+
 // AsyncOperationStatus provides information on the status of a asynchronous job progress.
 //
 // The following API calls return AsyncOperationStatus resources:
@@ -22,6 +22,7 @@ import (
 	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/lib/dircache"
 	"github.com/ncw/rclone/lib/oauthutil"
 	"github.com/ncw/rclone/lib/pacer"
@@ -242,6 +243,7 @@ listing, set this option.`,
 			Default:  false,
 			Advanced: true,
 		}},
+		CommandHelp: operations.CopyIDHelp("onedrive"),
 	})
 }
 
@@ -1180,18 +1182,64 @@ func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.QuickXorHash)
 }
 
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "copyid", "moveid":
+		return nil, operations.CopyOrMoveIDs(f, arg, name == "moveid", f.findID)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// findID finds the file with ID for copyid and moveid
+func (f *Fs) findID(ID string) (o fs.Object, move func(dest string) (fs.Object, error), err error) {
+	var info *api.Item
+	opts := newOptsCall(ID, "GET", "")
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(&opts, nil, &info)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't find ID %q", ID)
+	}
+	o, err = f.newObjectWithInfo(restoreReservedChars(info.GetName()), info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o, nil, nil
+}
+
 // PublicLink returns a link for downloading without accout.
-func (f *Fs) PublicLink(remote string) (link string, err error) {
+//
+// If expire is set the link will expire after that long and if
+// unlink is set the anonymous links are removed instead.
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (link string, err error) {
 	info, _, err := f.readMetaDataForPath(f.srvPath(remote))
 	if err != nil {
 		return "", err
 	}
+	if unlink {
+		return "", f.removePublicLinks(info.GetID())
+	}
 	opts := newOptsCall(info.GetID(), "POST", "/createLink")
 
 	share := api.CreateShareLinkRequest{
 		Type:  "view",
 		Scope: "anonymous",
 	}
+	if expire.IsSet() {
+		expiry := api.Timestamp(time.Now().Add(time.Duration(expire)))
+		share.Expiry = &expiry
+	}
 
 	var resp *http.Response
 	var result api.CreateShareLinkResponse
@@ -1206,6 +1254,36 @@ func (f *Fs) PublicLink(remote string) (link string, err error) {
 	return result.Link.WebURL, nil
 }
 
+// removePublicLinks removes the anonymous sharing links from the item with id
+func (f *Fs) removePublicLinks(id string) error {
+	opts := newOptsCall(id, "GET", "/permissions")
+	var resp *http.Response
+	var result api.PermissionsResponse
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.CallJSON(&opts, nil, &result)
+		return shouldRetry(resp, err)
+	})
+	if err != nil {
+		return errors.Wrap(err, "couldn't list public links")
+	}
+	for _, permission := range result.Value {
+		if permission.Link.Scope != "anonymous" {
+			continue
+		}
+		opts := newOptsCall(id, "DELETE", "/permissions/"+permission.ID)
+		opts.NoResponse = true
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.Call(&opts)
+			return shouldRetry(resp, err)
+		})
+		if err != nil {
+			return errors.Wrap(err, "couldn't remove public link")
+		}
+	}
+	return nil
+}
+
 // ------------------------------------------------------------
 
 // Fs returns the parent Fs
@@ -1678,6 +1756,7 @@ var (
 	_ fs.DirCacheFlusher = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
 	_ fs.Object          = (*Object)(nil)
 	_ fs.MimeTyper       = &Object{}
 	_ fs.IDer            = &Object{}
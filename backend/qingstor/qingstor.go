@@ -991,7 +991,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 	key := o.fs.root + o.remote
 	// Guess the content type
-	mimeType := fs.MimeType(src)
+	mimeType := fs.UploadMimeType(src)
 
 	req := uploadInput{
 		body:        in,
@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	gohash "hash"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+// quirks are the ways an S3 provider differs from AWS S3
+type quirks struct {
+	listVersion      int  // version of ListObjects to use, 1 or 2
+	virtualHostStyle bool // set if the provider needs virtual hosted style access
+	useMultipartEtag bool // set if the ETag of a multipart upload can be used to check it
+	maxUploadParts   int  // maximum number of parts in a multipart upload
+}
+
+// providerQuirks returns the quirks of provider.
+//
+// Providers not known are treated conservatively.
+func providerQuirks(provider string) quirks {
+	q := quirks{
+		listVersion:      2,
+		useMultipartEtag: true,
+		maxUploadParts:   s3manager.MaxUploadParts,
+	}
+	switch provider {
+	case "AWS", "DigitalOcean", "Dreamhost", "Minio":
+		// no quirks
+	case "Alibaba":
+		q.virtualHostStyle = true
+		q.useMultipartEtag = false
+	case "Ceph":
+		q.listVersion = 1
+	case "Cloudflare":
+		// multipart ETags aren't made from the MD5s of the parts
+		q.useMultipartEtag = false
+	case "IBMCOS":
+		q.listVersion = 1
+		q.useMultipartEtag = false
+	case "Netease":
+		q.listVersion = 1
+		q.virtualHostStyle = true
+		q.useMultipartEtag = false
+	case "Wasabi":
+		q.useMultipartEtag = false
+	default:
+		q.listVersion = 1
+		q.useMultipartEtag = false
+	}
+	return q
+}
+
+// setQuirks returns the quirks of the provider in opt with any
+// overrides set by the user and adjusts opt to match.
+func setQuirks(opt *Options) (q quirks, err error) {
+	q = providerQuirks(opt.Provider)
+	switch opt.ListVersion {
+	case 0:
+	case 1, 2:
+		q.listVersion = opt.ListVersion
+	default:
+		return q, errors.Errorf("list_version must be 0, 1 or 2 not %d", opt.ListVersion)
+	}
+	switch strings.ToLower(opt.UseMultipartEtag) {
+	case "":
+	case "true":
+		q.useMultipartEtag = true
+	case "false":
+		q.useMultipartEtag = false
+	default:
+		return q, errors.Errorf("use_multipart_etag must be true, false or empty not %q", opt.UseMultipartEtag)
+	}
+	if opt.MaxUploadParts > 0 && opt.MaxUploadParts < q.maxUploadParts {
+		q.maxUploadParts = opt.MaxUploadParts
+	}
+	if q.virtualHostStyle {
+		opt.ForcePathStyle = false
+	}
+	return q, nil
+}
+
+// multipartHasher works out the ETag S3 gives an object uploaded in
+// parts of partSize bytes from the data written to it.
+//
+// The ETag is the MD5 of the MD5s of the parts followed by "-" and
+// the number of parts, or the MD5 of the data if it was uploaded in
+// one part.
+type multipartHasher struct {
+	partSize int64
+	n        int64       // bytes written to the current part
+	whole    gohash.Hash // MD5 of all the data
+	part     gohash.Hash // MD5 of the current part
+	sums     []byte      // MD5s of the finished parts
+	parts    int         // number of finished parts
+}
+
+// newMultipartHasher makes a multipartHasher for parts of partSize
+func newMultipartHasher(partSize int64) *multipartHasher {
+	return &multipartHasher{
+		partSize: partSize,
+		whole:    md5.New(),
+		part:     md5.New(),
+	}
+}
+
+// Write the data to the hashes - never returns an error
+func (h *multipartHasher) Write(p []byte) (int, error) {
+	total := len(p)
+	_, _ = h.whole.Write(p)
+	for len(p) > 0 {
+		n := int64(len(p))
+		if left := h.partSize - h.n; n > left {
+			n = left
+		}
+		_, _ = h.part.Write(p[:n])
+		h.n += n
+		p = p[n:]
+		if h.n == h.partSize {
+			h.endPart()
+		}
+	}
+	return total, nil
+}
+
+// endPart finishes the current part
+func (h *multipartHasher) endPart() {
+	h.sums = h.part.Sum(h.sums)
+	h.parts++
+	h.part.Reset()
+	h.n = 0
+}
+
+// Check returns an error if etag doesn't match the data written
+func (h *multipartHasher) Check(etag string) error {
+	etag = strings.Trim(strings.ToLower(etag), `"`)
+	var want string
+	if strings.Contains(etag, "-") {
+		if h.n > 0 {
+			h.endPart()
+		}
+		sum := md5.Sum(h.sums)
+		want = fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), h.parts)
+	} else {
+		want = hex.EncodeToString(h.whole.Sum(nil))
+	}
+	if etag != want {
+		return errors.Errorf("multipart upload corrupted: ETag is %q but the data uploaded gives %q", etag, want)
+	}
+	return nil
+}
+
+// etagPartSizes returns the part sizes an object of size bytes might
+// have been uploaded in to give the number of parts in its ETag.
+//
+// These are chunkSize, the sizes the common uploaders use and the
+// smallest size giving parts rounded up to a MB as rclone and the AWS
+// tools do for big files, as it isn't recorded which was used.
+func etagPartSizes(size int64, parts int, chunkSize int64) (partSizes []int64) {
+	if size <= 0 || parts < 1 {
+		return nil
+	}
+	const mb = 1 << 20
+	exact := (size + int64(parts) - 1) / int64(parts)
+	candidates := []int64{chunkSize, 5 * mb, 8 * mb, 15 * mb, 16 * mb, 32 * mb, 64 * mb, 100 * mb, 128 * mb, 256 * mb, 512 * mb, ((exact + mb - 1) / mb) * mb, exact}
+	seen := make(map[int64]bool, len(candidates))
+	for _, partSize := range candidates {
+		if partSize <= 0 || seen[partSize] {
+			continue
+		}
+		seen[partSize] = true
+		if (size+partSize-1)/partSize == int64(parts) {
+			partSizes = append(partSizes, partSize)
+		}
+	}
+	return partSizes
+}
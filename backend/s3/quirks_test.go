@@ -0,0 +1,126 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetQuirks(t *testing.T) {
+	opt := &Options{Provider: "AWS", ForcePathStyle: true}
+	q, err := setQuirks(opt)
+	require.NoError(t, err)
+	assert.Equal(t, quirks{listVersion: 2, useMultipartEtag: true, maxUploadParts: 10000}, q)
+	assert.True(t, opt.ForcePathStyle)
+
+	opt = &Options{Provider: "Alibaba", ForcePathStyle: true}
+	q, err = setQuirks(opt)
+	require.NoError(t, err)
+	assert.Equal(t, quirks{listVersion: 2, virtualHostStyle: true, maxUploadParts: 10000}, q)
+	assert.False(t, opt.ForcePathStyle)
+
+	// unknown providers are treated conservatively
+	opt = &Options{Provider: "Potato"}
+	q, err = setQuirks(opt)
+	require.NoError(t, err)
+	assert.Equal(t, quirks{listVersion: 1, maxUploadParts: 10000}, q)
+
+	// overrides
+	opt = &Options{Provider: "Ceph", ListVersion: 2, UseMultipartEtag: "false", MaxUploadParts: 1000}
+	q, err = setQuirks(opt)
+	require.NoError(t, err)
+	assert.Equal(t, quirks{listVersion: 2, maxUploadParts: 1000}, q)
+
+	_, err = setQuirks(&Options{ListVersion: 3})
+	assert.Error(t, err)
+	_, err = setQuirks(&Options{UseMultipartEtag: "potato"})
+	assert.Error(t, err)
+}
+
+func TestMultipartHasher(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25)
+	md5hex := func(b []byte) string {
+		sum := md5.Sum(b)
+		return hex.EncodeToString(sum[:])
+	}
+
+	// uploaded in one part
+	h := newMultipartHasher(100)
+	_, _ = h.Write(data)
+	assert.NoError(t, h.Check(`"`+md5hex(data)+`"`))
+	assert.Error(t, h.Check(md5hex(data[1:])))
+
+	// uploaded in parts of 100, 100 and 50 written in odd sizes
+	h = newMultipartHasher(100)
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		_, _ = h.Write(data[i:end])
+	}
+	var sums []byte
+	for _, part := range [][]byte{data[:100], data[100:200], data[200:]} {
+		sum := md5.Sum(part)
+		sums = append(sums, sum[:]...)
+	}
+	etag := fmt.Sprintf("%s-3", md5hex(sums))
+	assert.NoError(t, h.Check(`"`+etag+`"`))
+	assert.Error(t, h.Check(fmt.Sprintf("%s-3", md5hex(data))))
+}
+
+func TestEtagPartSizes(t *testing.T) {
+	const mb = 1 << 20
+	assert.Nil(t, etagPartSizes(0, 1, 5*mb))
+	assert.Nil(t, etagPartSizes(100, 0, 5*mb))
+	assert.Equal(t, []int64{5 * mb}, etagPartSizes(40*mb, 8, 5*mb))
+	assert.Equal(t, []int64{8 * mb}, etagPartSizes(40*mb, 5, 5*mb))
+	assert.Equal(t, []int64{100, 84}, etagPartSizes(250, 3, 100))
+	// the exact size is tried if nothing else fits
+	assert.Equal(t, []int64{84}, etagPartSizes(250, 3, 5*mb))
+}
+
+func TestMatchETag(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 25)
+	md5hex := func(b []byte) string {
+		sum := md5.Sum(b)
+		return hex.EncodeToString(sum[:])
+	}
+	var sums []byte
+	for _, part := range [][]byte{data[:84], data[84:168], data[168:]} {
+		sum := md5.Sum(part)
+		sums = append(sums, sum[:]...)
+	}
+	f := &Fs{quirks: quirks{useMultipartEtag: true}}
+	f.opt.ChunkSize = 100
+	for _, test := range []struct {
+		etag    string
+		want    bool
+		wantErr error
+	}{
+		{"", false, hash.ErrUnsupported},
+		{`"` + md5hex(data) + `"`, true, nil},
+		{md5hex(data[1:]), false, nil},
+		{md5hex(sums) + "-3", true, nil},
+		{md5hex(data) + "-3", false, nil},
+		{md5hex(sums) + "-potato", false, hash.ErrUnsupported},
+		{"potato", false, hash.ErrUnsupported},
+	} {
+		o := &Object{fs: f, etag: test.etag, bytes: int64(len(data))}
+		got, err := o.MatchETag(bytes.NewReader(data))
+		assert.Equal(t, test.wantErr, err, test.etag)
+		assert.Equal(t, test.want, got, test.etag)
+	}
+
+	// providers which make multipart ETags differently
+	f.quirks.useMultipartEtag = false
+	o := &Object{fs: f, etag: md5hex(sums) + "-3", bytes: int64(len(data))}
+	_, err := o.MatchETag(bytes.NewReader(data))
+	assert.Equal(t, hash.ErrUnsupported, err)
+}
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -39,6 +40,7 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/config/configmap"
 	"github.com/ncw/rclone/fs/config/configstruct"
+	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fshttp"
 	"github.com/ncw/rclone/fs/hash"
@@ -53,7 +55,7 @@ import (
 func init() {
 	fs.Register(&fs.RegInfo{
 		Name:        "s3",
-		Description: "Amazon S3 Compliant Storage Provider (AWS, Alibaba, Ceph, Digital Ocean, Dreamhost, IBM COS, Minio, etc)",
+		Description: "Amazon S3 Compliant Storage Provider (AWS, Alibaba, Ceph, Cloudflare, Digital Ocean, Dreamhost, IBM COS, Minio, etc)",
 		NewFs:       NewFs,
 		Options: []fs.Option{{
 			Name: fs.ConfigProvider,
@@ -67,6 +69,9 @@ func init() {
 			}, {
 				Value: "Ceph",
 				Help:  "Ceph Object Storage",
+			}, {
+				Value: "Cloudflare",
+				Help:  "Cloudflare R2 Storage",
 			}, {
 				Value: "DigitalOcean",
 				Help:  "Digital Ocean Spaces",
@@ -729,7 +734,45 @@ If it is set then rclone will use v2 authentication.
 Use this only if v4 signatures don't work, eg pre Jewel/v10 CEPH.`,
 			Default:  false,
 			Advanced: true,
+		}, {
+			Name: "list_version",
+			Help: `Version of ListObjects to use: 1, 2 or 0 for auto.
+
+When S3 first launched it only provided the ListObjects call to
+enumerate objects in a bucket.  ListObjectsV2 was added later and is
+more efficient, however not all providers support it.
+
+If this is 0 (the default) rclone chooses the version according to
+the provider set.`,
+			Default:  0,
+			Advanced: true,
+		}, {
+			Name: "use_multipart_etag",
+			Help: `Whether to check multipart uploads with their ETag: true, false or unset for auto.
+
+AWS S3 makes the ETag of an object uploaded in parts from the MD5s
+of the parts which rclone uses to check the upload wasn't corrupted.
+Some providers make the ETag differently so the check must be turned
+off for them.
+
+If this is unset (the default) rclone chooses according to the
+provider set.  The check is never done with server side encryption
+using KMS.`,
+			Default:  "",
+			Advanced: true,
+		}, {
+			Name: "max_upload_parts",
+			Help: `Maximum number of parts in a multipart upload.
+
+This is the maximum number of chunks a file is uploaded in.  It is
+10,000 for AWS S3 and most providers but some have a lower limit.
+
+Rclone increases the chunk size to keep the number of chunks under
+this when uploading a large file of known size.`,
+			Default:  s3manager.MaxUploadParts,
+			Advanced: true,
 		}},
+		CommandHelp: commandHelp,
 	})
 }
 
@@ -768,6 +811,9 @@ type Options struct {
 	UploadConcurrency    int           `config:"upload_concurrency"`
 	ForcePathStyle       bool          `config:"force_path_style"`
 	V2Auth               bool          `config:"v2_auth"`
+	ListVersion          int           `config:"list_version"`
+	UseMultipartEtag     string        `config:"use_multipart_etag"`
+	MaxUploadParts       int           `config:"max_upload_parts"`
 }
 
 // Fs represents a remote s3 server
@@ -775,6 +821,7 @@ type Fs struct {
 	name          string           // the name of the remote
 	root          string           // root of the bucket - ignore all objects above this
 	opt           Options          // parsed options
+	quirks        quirks           // how the provider differs from AWS S3
 	features      *fs.Features     // optional features
 	c             *s3.S3           // the connection to the s3 server
 	ses           *session.Session // the s3 session
@@ -941,9 +988,6 @@ func s3Connection(opt *Options) (*s3.S3, *session.Session, error) {
 	if opt.Region == "" {
 		opt.Region = "us-east-1"
 	}
-	if opt.Provider == "Alibaba" || opt.Provider == "Netease" {
-		opt.ForcePathStyle = false
-	}
 	awsConfig := aws.NewConfig().
 		WithMaxRetries(maxRetries).
 		WithCredentials(cred).
@@ -1044,6 +1088,10 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	if opt.BucketACL == "" {
 		opt.BucketACL = opt.ACL
 	}
+	q, err := setQuirks(opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "s3")
+	}
 	c, ses, err := s3Connection(opt)
 	if err != nil {
 		return nil, err
@@ -1052,6 +1100,7 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		name:   name,
 		root:   directory,
 		opt:    *opt,
+		quirks: q,
 		c:      c,
 		bucket: bucket,
 		ses:    ses,
@@ -1059,9 +1108,10 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		srv:    fshttp.NewClient(fs.Config),
 	}
 	f.features = (&fs.Features{
-		ReadMimeType:  true,
-		WriteMimeType: true,
-		BucketBased:   true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		BucketBased:             true,
+		ServerSideAcrossConfigs: true,
 	}).Fill(f)
 	if f.root != "" {
 		f.root += "/"
@@ -1186,21 +1236,46 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 		delimiter = "/"
 	}
 	var marker *string
+	var continuationToken *string // used instead of marker with ListObjectsV2
 	for {
 		// FIXME need to implement ALL loop
-		req := s3.ListObjectsInput{
-			Bucket:    &f.bucket,
-			Delimiter: &delimiter,
-			Prefix:    &root,
-			MaxKeys:   &maxKeys,
-			Marker:    marker,
-		}
 		var resp *s3.ListObjectsOutput
 		var err error
-		err = f.pacer.Call(func() (bool, error) {
-			resp, err = f.c.ListObjects(&req)
-			return f.shouldRetry(err)
-		})
+		if f.quirks.listVersion == 2 {
+			req := s3.ListObjectsV2Input{
+				Bucket:            &f.bucket,
+				Delimiter:         &delimiter,
+				Prefix:            &root,
+				MaxKeys:           &maxKeys,
+				ContinuationToken: continuationToken,
+			}
+			var respv2 *s3.ListObjectsV2Output
+			err = f.pacer.Call(func() (bool, error) {
+				respv2, err = f.c.ListObjectsV2(&req)
+				return f.shouldRetry(err)
+			})
+			if err == nil {
+				// convert the response to a V1 one to share the code below
+				resp = &s3.ListObjectsOutput{
+					CommonPrefixes: respv2.CommonPrefixes,
+					Contents:       respv2.Contents,
+					IsTruncated:    respv2.IsTruncated,
+				}
+				continuationToken = respv2.NextContinuationToken
+			}
+		} else {
+			req := s3.ListObjectsInput{
+				Bucket:    &f.bucket,
+				Delimiter: &delimiter,
+				Prefix:    &root,
+				MaxKeys:   &maxKeys,
+				Marker:    marker,
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				resp, err = f.c.ListObjects(&req)
+				return f.shouldRetry(err)
+			})
+		}
 		if err != nil {
 			if awsErr, ok := err.(awserr.RequestFailure); ok {
 				if awsErr.StatusCode() == http.StatusNotFound {
@@ -1258,6 +1333,12 @@ func (f *Fs) list(dir string, recurse bool, fn listFn) error {
 		if !aws.BoolValue(resp.IsTruncated) {
 			break
 		}
+		if f.quirks.listVersion == 2 {
+			if aws.StringValue(continuationToken) == "" {
+				return errors.New("s3 protocol error: received listing v2 with IsTruncated set and no NextContinuationToken")
+			}
+			continue
+		}
 		// Use NextMarker if set, otherwise use last Key
 		if resp.NextMarker == nil || *resp.NextMarker == "" {
 			if len(resp.Contents) == 0 {
@@ -1554,6 +1635,387 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 	return f.NewObject(remote)
 }
 
+var commandHelp = []fs.CommandHelp{{
+	Name:  "undelete",
+	Short: "Restore deleted files in a versioned bucket.",
+	Long: `When a file is deleted from a bucket with versioning enabled S3 adds a
+delete marker rather than removing it, so the previous version can be
+restored, for example to recover from an accidental "rclone sync".
+This removes the delete markers to restore the most recent version of
+each deleted file.
+
+    rclone backend undelete s3:bucket/path/to/dir
+
+The filter flags can be used to choose which files are restored, and
+--max-age and --min-age apply to the time the file was deleted, so
+this restores all the files deleted in the last hour:
+
+    rclone backend undelete s3:bucket --max-age 1h
+
+This does nothing on buckets without versioning.  Use --dry-run to
+see what would be restored.
+
+The paths of the files restored are returned.
+`,
+}, {
+	Name:  "lifecycle",
+	Short: "Read or set the lifecycle rule for the bucket.",
+	Long: `This shows the lifecycle rules of the bucket, or sets the rule for the
+path given if any options are supplied, so the expiry of backups can
+be configured with the same tool which uploads them.
+
+    rclone backend lifecycle s3:bucket
+
+To delete objects 90 days after they were uploaded
+
+    rclone backend lifecycle s3:bucket -o expire=90
+
+The rule applies to the objects within the path given and is given
+the ID "rclone:" followed by the path.  So this deletes old versions
+of the objects in the logs directory 7 days after they were replaced
+and removes incomplete multipart uploads after a day
+
+    rclone backend lifecycle s3:bucket/logs/ -o noncurrent-expire=7 -o abort-multipart=1
+
+Setting all the options to 0 removes the rule for the path.  Rules
+made by other tools are left alone.  Use --dry-run to see the rules
+which would be set.
+`,
+	Opts: map[string]string{
+		"expire":            "Delete objects this many days after they are uploaded, 0 for never",
+		"noncurrent-expire": "Delete old versions this many days after they are replaced, 0 for never",
+		"abort-multipart":   "Remove incomplete multipart uploads after this many days, 0 for never",
+	},
+}, {
+	Name:  "presign",
+	Short: "Make presigned URLs for downloading or uploading objects.",
+	Long: `This makes URLs which can be used to download or upload an object
+without the credentials for the bucket, so transfers can be handed
+over to clients which shouldn't have them.
+
+    rclone backend presign s3:bucket/path/to/file -o expire=24h
+
+To make a URL to upload to
+
+    rclone backend presign s3:bucket/path/to/file -o method=PUT
+
+The object doesn't have to exist to make a PUT URL.  Paths given as
+arguments are relative to the remote, and the URLs for them are
+returned keyed by path if there is more than one.
+
+    rclone backend presign s3:bucket/dir file1 file2
+
+The URLs can't be made to last longer than a week and can't be
+revoked before they expire.
+`,
+	Opts: map[string]string{
+		"expire": "How long the URL is valid for - default 1h, max 1w",
+		"method": "GET to make a download URL (the default) or PUT for an upload URL",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "undelete":
+		return f.undelete()
+	case "lifecycle":
+		return f.lifecycle(opt)
+	case "presign":
+		return f.presign(arg, opt)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// presign returns presigned URLs for the paths in arg, or the path
+// the remote points to if there aren't any
+func (f *Fs) presign(arg []string, opt map[string]string) (out interface{}, err error) {
+	expiry := time.Hour
+	if value, ok := opt["expire"]; ok {
+		d, err := fs.ParseDuration(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad expire")
+		}
+		expiry = d
+	}
+	method := "GET"
+	if value, ok := opt["method"]; ok {
+		method = strings.ToUpper(value)
+	}
+	if len(arg) == 0 {
+		// remote:path doesn't exist yet so use it as the
+		// object name, eg for a PUT
+		if f.root == "" {
+			return nil, errors.New("need a path to presign")
+		}
+		return f.presignURL(method, strings.TrimSuffix(f.root, "/"), expiry)
+	}
+	links := make(map[string]string, len(arg))
+	for _, remote := range arg {
+		links[remote], err = f.presignURL(method, f.root+remote, expiry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(links) == 1 {
+		return links[arg[0]], nil
+	}
+	return links, nil
+}
+
+// undelete restores the deleted files in a versioned bucket which
+// match the filters by removing the delete markers which are their
+// current versions
+func (f *Fs) undelete() (restored []string, err error) {
+	if f.bucket == "" {
+		return nil, errors.New("can't undelete without a bucket")
+	}
+	var (
+		markers []*s3.DeleteMarkerEntry // current delete markers
+		sizes   = map[string]int64{}    // size of the newest version of each key
+	)
+	req := s3.ListObjectVersionsInput{
+		Bucket: &f.bucket,
+		Prefix: &f.root,
+	}
+	for {
+		var resp *s3.ListObjectVersionsOutput
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.c.ListObjectVersions(&req)
+			return f.shouldRetry(err)
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list versions")
+		}
+		for _, marker := range resp.DeleteMarkers {
+			if aws.BoolValue(marker.IsLatest) {
+				markers = append(markers, marker)
+			}
+		}
+		// Versions of each key are listed newest first
+		for _, version := range resp.Versions {
+			key := aws.StringValue(version.Key)
+			if _, found := sizes[key]; !found {
+				sizes[key] = aws.Int64Value(version.Size)
+			}
+		}
+		if !aws.BoolValue(resp.IsTruncated) {
+			break
+		}
+		req.KeyMarker = resp.NextKeyMarker
+		req.VersionIdMarker = resp.NextVersionIdMarker
+	}
+
+	for _, marker := range markers {
+		key := aws.StringValue(marker.Key)
+		size, found := sizes[key]
+		if !found || !strings.HasPrefix(key, f.root) {
+			continue
+		}
+		remote := key[len(f.root):]
+		if !filter.GetActive().Include(remote, size, aws.TimeValue(marker.LastModified)) {
+			continue
+		}
+		if fs.Config.DryRun {
+			fs.Logf(remote, "Not restoring as --dry-run")
+		} else {
+			req := s3.DeleteObjectInput{
+				Bucket:    &f.bucket,
+				Key:       marker.Key,
+				VersionId: marker.VersionId,
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				_, err := f.c.DeleteObject(&req)
+				return f.shouldRetry(err)
+			})
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(remote, "Failed to restore: %v", err)
+				continue
+			}
+			fs.Infof(remote, "Restored")
+		}
+		restored = append(restored, remote)
+	}
+	return restored, nil
+}
+
+// parseLifecycleDays parses the option called name from opt
+//
+// It returns nil if it isn't set or is 0 as that means never.
+func parseLifecycleDays(opt map[string]string, name string) (days *int64, set bool, err error) {
+	value, set := opt[name]
+	if !set {
+		return nil, false, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return nil, false, errors.Errorf("bad %s %q: must be a number of days", name, value)
+	}
+	if n == 0 {
+		return nil, true, nil
+	}
+	return &n, true, nil
+}
+
+// lifecycle reads the lifecycle rules of the bucket, setting the rule
+// for the root first if any options are set
+func (f *Fs) lifecycle(opt map[string]string) (rules []*s3.LifecycleRule, err error) {
+	if f.bucket == "" {
+		return nil, errors.New("can't read lifecycle rules without a bucket")
+	}
+	var expire, noncurrentExpire, abortMultipart *int64
+	var setExpire, setNoncurrentExpire, setAbortMultipart bool
+	if expire, setExpire, err = parseLifecycleDays(opt, "expire"); err != nil {
+		return nil, err
+	}
+	if noncurrentExpire, setNoncurrentExpire, err = parseLifecycleDays(opt, "noncurrent-expire"); err != nil {
+		return nil, err
+	}
+	if abortMultipart, setAbortMultipart, err = parseLifecycleDays(opt, "abort-multipart"); err != nil {
+		return nil, err
+	}
+
+	getReq := s3.GetBucketLifecycleConfigurationInput{
+		Bucket: &f.bucket,
+	}
+	var resp *s3.GetBucketLifecycleConfigurationOutput
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.c.GetBucketLifecycleConfiguration(&getReq)
+		return f.shouldRetry(err)
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "NoSuchLifecycleConfiguration" {
+			return nil, errors.Wrap(err, "failed to read lifecycle rules")
+		}
+		resp = &s3.GetBucketLifecycleConfigurationOutput{}
+	}
+	rules = resp.Rules
+	if rules == nil {
+		rules = []*s3.LifecycleRule{}
+	}
+	if !setExpire && !setNoncurrentExpire && !setAbortMultipart {
+		return rules, nil
+	}
+
+	// Replace the rule for the root keeping any others
+	ID := "rclone:" + f.root
+	newRules := []*s3.LifecycleRule{}
+	for _, rule := range rules {
+		if aws.StringValue(rule.ID) != ID {
+			newRules = append(newRules, rule)
+		}
+	}
+	if expire != nil || noncurrentExpire != nil || abortMultipart != nil {
+		rule := &s3.LifecycleRule{
+			ID:     aws.String(ID),
+			Status: aws.String(s3.ExpirationStatusEnabled),
+			Filter: &s3.LifecycleRuleFilter{
+				Prefix: aws.String(f.root),
+			},
+		}
+		if expire != nil {
+			rule.Expiration = &s3.LifecycleExpiration{Days: expire}
+		}
+		if noncurrentExpire != nil {
+			rule.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{NoncurrentDays: noncurrentExpire}
+		}
+		if abortMultipart != nil {
+			rule.AbortIncompleteMultipartUpload = &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: abortMultipart}
+		}
+		newRules = append(newRules, rule)
+	}
+	if fs.Config.DryRun {
+		fs.Logf(f, "Not setting lifecycle rules as --dry-run")
+		return newRules, nil
+	}
+	err = f.pacer.Call(func() (bool, error) {
+		if len(newRules) == 0 {
+			// S3 doesn't allow an empty configuration
+			_, err = f.c.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{
+				Bucket: &f.bucket,
+			})
+		} else {
+			_, err = f.c.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+				Bucket: &f.bucket,
+				LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+					Rules: newRules,
+				},
+			})
+		}
+		return f.shouldRetry(err)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set lifecycle rules")
+	}
+	fs.Infof(f, "Set lifecycle rules")
+	return newRules, nil
+}
+
+// maxPresignExpiry is the longest a presigned URL can be valid for
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// PublicLink generates a presigned URL to download the object at
+// remote which is valid for expire or a week if it isn't set.
+//
+// S3 links can't be removed except by waiting for them to expire.
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if unlink {
+		return "", fs.ErrorCantUnlink
+	}
+	// Check the object exists - directories can't be shared
+	_, err = f.NewObject(remote)
+	if err == fs.ErrorObjectNotFound {
+		if _, listErr := f.List(remote); listErr == nil {
+			return "", fs.ErrorCantShareDirectories
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	expiry := maxPresignExpiry
+	if expire.IsSet() {
+		expiry = time.Duration(expire)
+	}
+	return f.presignURL("GET", f.root+remote, expiry)
+}
+
+// presignURL returns a URL for method on the object with key which
+// is valid for expiry
+func (f *Fs) presignURL(method, key string, expiry time.Duration) (string, error) {
+	if expiry > maxPresignExpiry {
+		return "", errors.Errorf("expiry %v is longer than the maximum of %v", expiry, maxPresignExpiry)
+	}
+	if f.bucket == "" {
+		return "", errors.New("can't presign without a bucket")
+	}
+	var req *request.Request
+	switch method {
+	case "GET":
+		req, _ = f.c.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: &f.bucket,
+			Key:    &key,
+		})
+	case "PUT":
+		req, _ = f.c.PutObjectRequest(&s3.PutObjectInput{
+			Bucket: &f.bucket,
+			Key:    &key,
+		})
+	default:
+		return "", errors.Errorf("unknown method %q - must be GET or PUT", method)
+	}
+	return req.Presign(expiry)
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.MD5)
@@ -1607,6 +2069,55 @@ func (o *Object) Hash(t hash.Type) (string, error) {
 	return hash, nil
 }
 
+// ETag returns the ETag of the object
+func (o *Object) ETag() string {
+	return strings.Trim(o.etag, `"`)
+}
+
+// MatchETag reads the data of the object from in and returns whether
+// it gives the ETag of the object.
+//
+// The ETag of an object uploaded in parts depends on the size of the
+// parts, so each size it might have been uploaded with is tried.
+func (o *Object) MatchETag(in io.Reader) (bool, error) {
+	etag := strings.ToLower(o.ETag())
+	if etag == "" || o.fs.opt.ServerSideEncryption == "aws:kms" || o.fs.opt.SSEKMSKeyID != "" {
+		return false, hash.ErrUnsupported
+	}
+	var hashers []*multipartHasher
+	if dash := strings.LastIndex(etag, "-"); dash >= 0 {
+		if !o.fs.quirks.useMultipartEtag {
+			return false, hash.ErrUnsupported
+		}
+		parts, err := strconv.Atoi(etag[dash+1:])
+		if err != nil {
+			return false, hash.ErrUnsupported
+		}
+		for _, partSize := range etagPartSizes(o.bytes, parts, int64(o.fs.opt.ChunkSize)) {
+			hashers = append(hashers, newMultipartHasher(partSize))
+		}
+	} else if matchMd5.MatchString(etag) {
+		hashers = append(hashers, newMultipartHasher(o.bytes+1))
+	}
+	if len(hashers) == 0 {
+		return false, hash.ErrUnsupported
+	}
+	out := make([]io.Writer, len(hashers))
+	for i := range hashers {
+		out[i] = hashers[i]
+	}
+	_, err := io.Copy(io.MultiWriter(out...), in)
+	if err != nil {
+		return false, err
+	}
+	for _, h := range hashers {
+		if h.Check(etag) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Size returns the size of an object in bytes
 func (o *Object) Size() int64 {
 	return o.bytes
@@ -1780,23 +2291,29 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 	multipart := size < 0 || size >= int64(o.fs.opt.UploadCutoff)
 	var uploader *s3manager.Uploader
+	var etagHasher *multipartHasher
 	if multipart {
 		uploader = s3manager.NewUploader(o.fs.ses, func(u *s3manager.Uploader) {
 			u.Concurrency = o.fs.opt.UploadConcurrency
 			u.LeavePartsOnError = false
 			u.S3 = o.fs.c
 			u.PartSize = int64(o.fs.opt.ChunkSize)
+			u.MaxUploadParts = o.fs.quirks.maxUploadParts
+			maxParts := int64(u.MaxUploadParts)
 
 			if size == -1 {
 				// Make parts as small as possible while still being able to upload to the
 				// S3 file size limit. Rounded up to nearest MB.
-				u.PartSize = (((maxFileSize / s3manager.MaxUploadParts) >> 20) + 1) << 20
-				return
-			}
-			// Adjust PartSize until the number of parts is small enough.
-			if size/u.PartSize >= s3manager.MaxUploadParts {
+				u.PartSize = (((maxFileSize / maxParts) >> 20) + 1) << 20
+			} else if size/u.PartSize >= maxParts {
+				// Adjust PartSize until the number of parts is small enough.
 				// Calculate partition size rounded up to the nearest MB
-				u.PartSize = (((size / s3manager.MaxUploadParts) >> 20) + 1) << 20
+				u.PartSize = (((size / maxParts) >> 20) + 1) << 20
+			}
+			if o.fs.quirks.useMultipartEtag && o.fs.opt.ServerSideEncryption != "aws:kms" && o.fs.opt.SSEKMSKeyID == "" {
+				// check the upload with the ETag when it is done
+				etagHasher = newMultipartHasher(u.PartSize)
+				in = io.TeeReader(in, etagHasher)
 			}
 		})
 	}
@@ -1823,7 +2340,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	}
 
 	// Guess the content type
-	mimeType := fs.MimeType(src)
+	mimeType := fs.UploadMimeType(src)
 
 	key := o.fs.root + o.remote
 	if multipart {
@@ -1923,7 +2440,13 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	// Read the metadata from the newly created object
 	o.meta = nil // wipe old metadata
 	err = o.readMetaData()
-	return err
+	if err != nil {
+		return err
+	}
+	if etagHasher != nil {
+		return etagHasher.Check(o.etag)
+	}
+	return nil
 }
 
 // Remove an object
@@ -1952,10 +2475,13 @@ func (o *Object) MimeType() string {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.Copier      = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.ListRer     = &Fs{}
-	_ fs.Object      = &Object{}
-	_ fs.MimeTyper   = &Object{}
+	_ fs.Fs           = &Fs{}
+	_ fs.Copier       = &Fs{}
+	_ fs.PutStreamer  = &Fs{}
+	_ fs.ListRer      = &Fs{}
+	_ fs.Commander    = &Fs{}
+	_ fs.PublicLinker = &Fs{}
+	_ fs.Object       = &Object{}
+	_ fs.MimeTyper    = &Object{}
+	_ fs.ETager       = &Object{}
 )
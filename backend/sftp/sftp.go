@@ -10,10 +10,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/user"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +32,7 @@ import (
 	"github.com/pkg/sftp"
 	sshagent "github.com/xanzy/ssh-agent"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/time/rate"
 )
 
@@ -82,6 +85,35 @@ When key-file is also set, the ".pub" file of the specified key-file is read and
 requested from the ssh-agent. This allows to avoid ` + "`Too many authentication failures for *username*`" + ` errors
 when the ssh-agent contains many keys.`,
 			Default: false,
+		}, {
+			Name: "pubkey_file",
+			Help: `Optional path to an OpenSSH certificate to authenticate with.
+
+Set this if your key has been signed by a certificate authority the
+server trusts, eg "~/.ssh/id_rsa-cert.pub".  The certificate is used
+with the key from key_file or, if that isn't set, the matching key in
+the ssh-agent.`,
+		}, {
+			Name: "known_hosts_file",
+			Help: `Optional path to a known_hosts file to verify the host keys of the servers.
+
+Leave blank to accept any host key, which isn't secure.  The file is
+in the OpenSSH format, eg "~/.ssh/known_hosts".  The host keys of the
+proxy_jump hosts are checked too.`,
+			Examples: []fs.OptionExample{{
+				Value: "~/.ssh/known_hosts",
+				Help:  "Use OpenSSH's known_hosts file",
+			}},
+		}, {
+			Name: "proxy_jump",
+			Help: `Optional jump hosts to connect through, eg a bastion.
+
+This is a comma separated list of [user@]host[:port] like the
+OpenSSH ProxyJump option.  The connection is made to the first
+host, through it to the next and so on, then to the SFTP server.
+The jump hosts are logged in to with the same credentials as the
+server.`,
+			Advanced: true,
 		}, {
 			Name:    "use_insecure_cipher",
 			Help:    "Enable the use of the aes128-cbc cipher. This cipher is insecure and may allow plaintext data to be recovered by an attacker.",
@@ -139,6 +171,9 @@ type Options struct {
 	KeyFile           string `config:"key_file"`
 	KeyFilePass       string `config:"key_file_pass"`
 	KeyUseAgent       bool   `config:"key_use_agent"`
+	PubKeyFile        string `config:"pubkey_file"`
+	KnownHostsFile    string `config:"known_hosts_file"`
+	ProxyJump         string `config:"proxy_jump"`
 	UseInsecureCipher bool   `config:"use_insecure_cipher"`
 	DisableHashCheck  bool   `config:"disable_hashcheck"`
 	AskPassword       bool   `config:"ask_password"`
@@ -158,18 +193,21 @@ type Fs struct {
 	cachedHashes *hash.Set
 	poolMu       sync.Mutex
 	pool         []*conn
+	drain        *time.Timer   // used to drain the pool when we stop using the connections
 	connLimit    *rate.Limiter // for limiting number of connections per second
 }
 
 // Object is a remote SFTP file that has been stat'd (so it exists, but is not necessarily open for reading)
 type Object struct {
-	fs      *Fs
-	remote  string
-	size    int64       // size of the object
-	modTime time.Time   // modification time of the object
-	mode    os.FileMode // mode bits from the file
-	md5sum  *string     // Cached MD5 checksum
-	sha1sum *string     // Cached SHA1 checksum
+	fs       *Fs
+	remote   string
+	size     int64       // size of the object
+	modTime  time.Time   // modification time of the object
+	mode     os.FileMode // mode bits from the file
+	uid, gid uint32      // owner of the file if hasOwner is set
+	hasOwner bool        // set if the server returned the owner
+	md5sum   *string     // Cached MD5 checksum
+	sha1sum  *string     // Cached SHA1 checksum
 }
 
 // readCurrentUser finds the current user name or "" if not found
@@ -202,10 +240,77 @@ func Dial(network, addr string, sshConfig *ssh.ClientConfig) (*ssh.Client, error
 	return ssh.NewClient(c, chans, reqs), nil
 }
 
+// dialVia starts a client connection to the SSH server at addr
+// through the SSH connection via, or directly if via is nil.
+func dialVia(via *ssh.Client, addr string, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return Dial("tcp", addr, sshConfig)
+	}
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// parseJumpHost parses a proxy_jump host of the form
+// [user@]host[:port] returning the user, or defaultUser if not set,
+// and the address to dial.
+func parseJumpHost(spec, defaultUser string) (user, addr string) {
+	spec = strings.TrimSpace(spec)
+	user = defaultUser
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		user, spec = spec[:i], spec[i+1:]
+	}
+	if _, _, err := net.SplitHostPort(spec); err != nil {
+		spec = net.JoinHostPort(strings.Trim(spec, "[]"), "22")
+	}
+	return user, spec
+}
+
+// dial connects to the SSH server through the proxy_jump hosts if
+// set, returning the client for the server and the clients for the
+// jump hosts which must be closed after it.
+func (f *Fs) dial() (sshClient *ssh.Client, jumps []*ssh.Client, err error) {
+	var via *ssh.Client
+	if f.opt.ProxyJump != "" {
+		for _, spec := range strings.Split(f.opt.ProxyJump, ",") {
+			user, addr := parseJumpHost(spec, f.opt.User)
+			jumpConfig := *f.config
+			jumpConfig.User = user
+			via, err = dialVia(via, addr, &jumpConfig)
+			if err != nil {
+				closeClients(jumps)
+				return nil, nil, errors.Wrapf(err, "couldn't connect to jump host %q", addr)
+			}
+			jumps = append(jumps, via)
+		}
+	}
+	sshClient, err = dialVia(via, f.opt.Host+":"+f.opt.Port, f.config)
+	if err != nil {
+		closeClients(jumps)
+		return nil, nil, err
+	}
+	return sshClient, jumps, nil
+}
+
+// closeClients closes the jump host clients, last first
+func closeClients(clients []*ssh.Client) {
+	for i := len(clients) - 1; i >= 0; i-- {
+		_ = clients[i].Close()
+	}
+}
+
 // conn encapsulates an ssh client and corresponding sftp client
 type conn struct {
 	sshClient  *ssh.Client
 	sftpClient *sftp.Client
+	jumps      []*ssh.Client // clients for the proxy_jump hosts, if any
 	err        chan error
 }
 
@@ -218,6 +323,7 @@ func (c *conn) wait() {
 func (c *conn) close() error {
 	sftpErr := c.sftpClient.Close()
 	sshErr := c.sshClient.Close()
+	closeClients(c.jumps)
 	if sftpErr != nil {
 		return sftpErr
 	}
@@ -244,13 +350,14 @@ func (f *Fs) sftpConnection() (c *conn, err error) {
 	c = &conn{
 		err: make(chan error, 1),
 	}
-	c.sshClient, err = Dial("tcp", f.opt.Host+":"+f.opt.Port, f.config)
+	c.sshClient, c.jumps, err = f.dial()
 	if err != nil {
 		return nil, errors.Wrap(err, "couldn't connect SSH")
 	}
 	c.sftpClient, err = sftp.NewClient(c.sshClient)
 	if err != nil {
 		_ = c.sshClient.Close()
+		closeClients(c.jumps)
 		return nil, errors.Wrap(err, "couldn't initialise SFTP")
 	}
 	go c.wait()
@@ -270,6 +377,11 @@ func (f *Fs) getSftpConnection() (c *conn, err error) {
 		fs.Errorf(f, "Discarding closed SSH connection: %v", err)
 		c = nil
 	}
+	if f.drain != nil {
+		// stop draining while a connection is in use - it is
+		// restarted when the connection is returned
+		f.drain.Stop()
+	}
 	f.poolMu.Unlock()
 	if c != nil {
 		return c, nil
@@ -312,9 +424,33 @@ func (f *Fs) putSftpConnection(pc **conn, err error) {
 	}
 	f.poolMu.Lock()
 	f.pool = append(f.pool, c)
+	if idleTimeout := fs.Config.IdleTimeout; idleTimeout > 0 {
+		if f.drain == nil {
+			f.drain = time.AfterFunc(idleTimeout, f.drainPool)
+		} else {
+			f.drain.Reset(idleTimeout)
+		}
+	}
 	f.poolMu.Unlock()
 }
 
+// drainPool closes the connections in the pool when they haven't
+// been used for --idle-timeout
+func (f *Fs) drainPool() {
+	f.poolMu.Lock()
+	pool := f.pool
+	f.pool = nil
+	f.poolMu.Unlock()
+	if len(pool) > 0 {
+		fs.Debugf(f, "Closing %d unused connections", len(pool))
+	}
+	for _, c := range pool {
+		if err := c.close(); err != nil {
+			fs.Debugf(f, "Error closing unused connection: %v", err)
+		}
+	}
+}
+
 // shellExpand replaces a leading "~" with "${HOME}" and expands all environment
 // variables afterwards.
 func shellExpand(s string) string {
@@ -327,6 +463,23 @@ func shellExpand(s string) string {
 	return s
 }
 
+// readCertificate reads the OpenSSH certificate in the file at path
+func readCertificate(path string) (*ssh.Certificate, error) {
+	certBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read certificate file")
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate file")
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.Errorf("%q is a public key not a certificate", path)
+	}
+	return cert, nil
+}
+
 // NewFs creates a new Fs object from the name and root. It connects to
 // the host specified in the config file.
 func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
@@ -349,12 +502,28 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		Timeout:         fs.Config.ConnectTimeout,
 	}
 
+	if opt.KnownHostsFile != "" {
+		hostKeyCallback, err := knownhosts.New(shellExpand(opt.KnownHostsFile))
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't parse known_hosts_file")
+		}
+		sshConfig.HostKeyCallback = hostKeyCallback
+	}
+
 	if opt.UseInsecureCipher {
 		sshConfig.Config.SetDefaults()
 		sshConfig.Config.Ciphers = append(sshConfig.Config.Ciphers, "aes128-cbc")
 	}
 
 	keyFile := shellExpand(opt.KeyFile)
+	pubkeyFile := shellExpand(opt.PubKeyFile)
+	var cert *ssh.Certificate
+	if pubkeyFile != "" {
+		cert, err = readCertificate(pubkeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 	// Add ssh agent-auth if no password or file specified
 	if (opt.Pass == "" && keyFile == "") || opt.KeyUseAgent {
 		sshAgentClient, _, err := sshagent.New()
@@ -365,19 +534,30 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "couldn't read ssh agent signers")
 		}
-		if keyFile != "" {
-			pubBytes, err := ioutil.ReadFile(keyFile + ".pub")
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to read public key file")
-			}
-			pub, _, _, _, err := ssh.ParseAuthorizedKey(pubBytes)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to parse public key file")
+		if keyFile != "" || cert != nil {
+			var pub ssh.PublicKey
+			if cert != nil {
+				pub = cert.Key
+			} else {
+				pubBytes, err := ioutil.ReadFile(keyFile + ".pub")
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to read public key file")
+				}
+				pub, _, _, _, err = ssh.ParseAuthorizedKey(pubBytes)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to parse public key file")
+				}
 			}
 			pubM := pub.Marshal()
 			found := false
 			for _, s := range signers {
 				if bytes.Equal(pubM, s.PublicKey().Marshal()) {
+					if cert != nil {
+						s, err = ssh.NewCertSigner(cert, s)
+						if err != nil {
+							return nil, errors.Wrap(err, "failed to use certificate with the ssh-agent key")
+						}
+					}
 					sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(s))
 					found = true
 					break
@@ -408,6 +588,12 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to parse private key file")
 		}
+		if cert != nil {
+			signer, err = ssh.NewCertSigner(cert, signer)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to use certificate with the private key file")
+			}
+		}
 		sshConfig.Auth = append(sshConfig.Auth, ssh.PublicKeys(signer))
 	}
 
@@ -438,7 +624,11 @@ func NewFs(name, root string, m configmap.Mapper) (fs.Fs, error) {
 	}
 	f.features = (&fs.Features{
 		CanHaveEmptyDirectories: true,
+		PartialUploads:          true,
 	}).Fill(f)
+	if !opt.SetModTime {
+		f.features.DirSetModTime = nil
+	}
 	// Make a connection and pool it to return errors early
 	c, err := f.getSftpConnection()
 	if err != nil {
@@ -678,6 +868,21 @@ func (f *Fs) Rmdir(dir string) error {
 	return err
 }
 
+// DirSetModTime sets the modification time of the directory dir
+func (f *Fs) DirSetModTime(dir string, modTime time.Time) error {
+	root := path.Join(f.root, dir)
+	c, err := f.getSftpConnection()
+	if err != nil {
+		return errors.Wrap(err, "DirSetModTime")
+	}
+	err = c.sftpClient.Chtimes(root, modTime, modTime)
+	f.putSftpConnection(&c, err)
+	if os.IsNotExist(err) {
+		return fs.ErrorDirNotFound
+	}
+	return err
+}
+
 // Move renames a remote sftp file object
 func (f *Fs) Move(src fs.Object, remote string) (fs.Object, error) {
 	srcObj, ok := src.(*Object)
@@ -756,6 +961,18 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 	return nil
 }
 
+// PartialNaming returns how partial uploads are named.
+//
+// SFTP servers are nearly always unix-like so the leading . hides the
+// partial uploads.
+func (f *Fs) PartialNaming() fs.PartialNaming {
+	return fs.PartialNaming{
+		Prefix:        ".",
+		Suffix:        ".partial",
+		MaxLeafLength: 255,
+	}
+}
+
 // Hashes returns the supported hash types of the filesystem
 func (f *Fs) Hashes() hash.Set {
 	if f.cachedHashes != nil {
@@ -917,6 +1134,21 @@ func (o *Object) setMetadata(info os.FileInfo) {
 	o.modTime = info.ModTime()
 	o.size = info.Size()
 	o.mode = info.Mode()
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		o.uid, o.gid, o.hasOwner = stat.UID, stat.GID, true
+	}
+}
+
+// Metadata returns the permissions and owner of the file
+func (o *Object) Metadata() (fs.Metadata, error) {
+	m := fs.Metadata{
+		"mode": strconv.FormatUint(uint64(o.mode.Perm()), 8),
+	}
+	if o.hasOwner {
+		m["uid"] = strconv.FormatUint(uint64(o.uid), 10)
+		m["gid"] = strconv.FormatUint(uint64(o.gid), 10)
+	}
+	return m, nil
 }
 
 // statRemote stats the file or directory at the remote given
@@ -1113,9 +1345,12 @@ func (o *Object) Remove() error {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = &Fs{}
-	_ fs.PutStreamer = &Fs{}
-	_ fs.Mover       = &Fs{}
-	_ fs.DirMover    = &Fs{}
-	_ fs.Object      = &Object{}
+	_ fs.Fs             = &Fs{}
+	_ fs.PutStreamer    = &Fs{}
+	_ fs.Mover          = &Fs{}
+	_ fs.DirMover       = &Fs{}
+	_ fs.DirSetModTimer = &Fs{}
+	_ fs.PartialNamer   = &Fs{}
+	_ fs.Object         = &Object{}
+	_ fs.Metadataer     = &Object{}
 )
@@ -35,3 +35,20 @@ func TestParseHash(t *testing.T) {
 		assert.Equal(t, test.checksum, got, fmt.Sprintf("Test %d sshOutput = %q", i, test.sshOutput))
 	}
 }
+
+func TestParseJumpHost(t *testing.T) {
+	for i, test := range []struct {
+		spec, user, addr string
+	}{
+		{"bastion", "me", "bastion:22"},
+		{"bastion:2222", "me", "bastion:2222"},
+		{"jump@bastion", "jump", "bastion:22"},
+		{" jump@bastion:2222 ", "jump", "bastion:2222"},
+		{"[::1]", "me", "[::1]:22"},
+		{"jump@[::1]:2222", "jump", "[::1]:2222"},
+	} {
+		user, addr := parseJumpHost(test.spec, "me")
+		assert.Equal(t, test.user, user, fmt.Sprintf("Test %d spec = %q", i, test.spec))
+		assert.Equal(t, test.addr, addr, fmt.Sprintf("Test %d spec = %q", i, test.spec))
+	}
+}
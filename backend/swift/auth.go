@@ -1,17 +1,23 @@
 package swift
 
 import (
+	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/ncw/swift"
 )
 
 // auth is an authenticator for swift.  It overrides the StorageUrl
-// and AuthToken with fixed values.
+// and AuthToken with fixed values if set and remembers when the
+// token expires.
 type auth struct {
 	parentAuth swift.Authenticator
 	storageURL string
 	authToken  string
+	mu         sync.Mutex
+	expires    time.Time // when the token expires or zero if unknown
 }
 
 // newAuth creates a swift authenticator wrapper to override the
@@ -23,6 +29,7 @@ func newAuth(parentAuth swift.Authenticator, storageURL string, authToken string
 		parentAuth: parentAuth,
 		storageURL: storageURL,
 		authToken:  authToken,
+		expires:    tokenExpiry(parentAuth),
 	}
 }
 
@@ -39,7 +46,11 @@ func (a *auth) Response(resp *http.Response) error {
 	if a.parentAuth == nil {
 		return nil
 	}
-	return a.parentAuth.Response(resp)
+	err := a.parentAuth.Response(resp)
+	a.mu.Lock()
+	a.expires = tokenExpiry(a.parentAuth)
+	a.mu.Unlock()
+	return err
 }
 
 // The public storage URL - set Internal to true to read
@@ -54,6 +65,18 @@ func (a *auth) StorageUrl(Internal bool) string { // nolint
 	return a.parentAuth.StorageUrl(Internal)
 }
 
+// StorageUrlForEndpoint returns the storage URL for the endpoint type
+// so wrapping the parent doesn't lose the endpoint_type setting
+func (a *auth) StorageUrlForEndpoint(endpointType swift.EndpointType) string { // nolint
+	if a.storageURL != "" {
+		return a.storageURL
+	}
+	if customAuth, ok := a.parentAuth.(swift.CustomEndpointAuthenticator); ok {
+		return customAuth.StorageUrlForEndpoint(endpointType)
+	}
+	return a.StorageUrl(endpointType == swift.EndpointTypeInternal)
+}
+
 // The access token
 func (a *auth) Token() string {
 	if a.authToken != "" {
@@ -73,5 +96,51 @@ func (a *auth) CdnUrl() string { // nolint
 	return a.parentAuth.CdnUrl()
 }
 
+// expiry returns when the token expires or the zero time if unknown
+func (a *auth) expiry() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.expires
+}
+
+// tokenExpiry returns when the token in the v2 or v3 auth response
+// held by parentAuth expires or the zero time if it isn't known.
+//
+// The swift authenticators aren't exported but the responses they
+// hold are, so this reads them via JSON.
+func tokenExpiry(parentAuth swift.Authenticator) (expires time.Time) {
+	if parentAuth == nil {
+		return expires
+	}
+	data, err := json.Marshal(parentAuth)
+	if err != nil {
+		return expires
+	}
+	var response struct {
+		Auth struct {
+			Token struct {
+				ExpiresAt string `json:"Expires_At"` // v3
+			}
+			Access struct {
+				Token struct {
+					Expires string // v2
+				}
+			}
+		}
+	}
+	if json.Unmarshal(data, &response) != nil {
+		return expires
+	}
+	for _, value := range []string{response.Auth.Token.ExpiresAt, response.Auth.Access.Token.Expires} {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return t
+		}
+	}
+	return expires
+}
+
 // Check the interfaces are satisfied
-var _ swift.Authenticator = (*auth)(nil)
+var (
+	_ swift.Authenticator               = (*auth)(nil)
+	_ swift.CustomEndpointAuthenticator = (*auth)(nil)
+)
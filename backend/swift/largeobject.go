@@ -0,0 +1,201 @@
+package swift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/swift"
+)
+
+// segmentsRoot returns the path in the segments container under which
+// the segments of remote are stored.
+//
+// If the segments container was set by the user it may be shared
+// between containers so the container name is included.
+func (f *Fs) segmentsRoot(remote string) string {
+	if f.opt.SegmentsContainer != "" {
+		return f.container + "/" + f.root + remote + "/"
+	}
+	return f.root + remote + "/"
+}
+
+// newSegmentsPath returns a new unique path to upload the segments of
+// an object of size bytes at remote to
+func (f *Fs) newSegmentsPath(remote string, size int64) string {
+	return fmt.Sprintf("%s%s/%d", f.segmentsRoot(remote), swift.TimeToFloatString(time.Now()), size)
+}
+
+// makeSegmentsContainer creates the segments container if it doesn't
+// exist
+func (f *Fs) makeSegmentsContainer() error {
+	var err error
+	err = f.pacer.Call(func() (bool, error) {
+		_, _, err = f.c.Container(f.segmentsContainer)
+		return shouldRetry(err)
+	})
+	if err == swift.ContainerNotFound {
+		headers := swift.Headers{}
+		if f.opt.StoragePolicy != "" {
+			headers["X-Storage-Policy"] = f.opt.StoragePolicy
+		}
+		err = f.pacer.Call(func() (bool, error) {
+			err = f.c.ContainerCreate(f.segmentsContainer, headers)
+			return shouldRetry(err)
+		})
+	}
+	return err
+}
+
+// sloSegment is an entry in the manifest of a static large object
+type sloSegment struct {
+	Path      string `json:"path"`
+	Etag      string `json:"etag"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// putManifest uploads the manifest of the large object manifestName
+// made of segments stored in f.segmentsContainer under segmentsPath.
+//
+// If slo is set a static large object manifest is made, otherwise a
+// dynamic large object manifest.
+func (f *Fs) putManifest(manifestName string, slo bool, segmentsPath string, segments []swift.Object, contentType string, headers swift.Headers) error {
+	var err error
+	if !slo {
+		headers["X-Object-Manifest"] = urlEncode(fmt.Sprintf("%s/%s/", f.segmentsContainer, segmentsPath))
+		headers["Content-Length"] = "0" // set Content-Length as we know it
+		emptyReader := bytes.NewReader(nil)
+		return f.pacer.Call(func() (bool, error) {
+			_, err = f.c.ObjectPut(f.container, manifestName, emptyReader, true, "", contentType, headers)
+			return shouldRetry(err)
+		})
+	}
+	manifest := make([]sloSegment, len(segments))
+	for i, segment := range segments {
+		manifest[i] = sloSegment{
+			Path:      f.segmentsContainer + "/" + segment.Name,
+			Etag:      segment.Hash,
+			SizeBytes: segment.Bytes,
+		}
+	}
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	delete(headers, "X-Object-Manifest")
+	headers["Content-Length"] = strconv.Itoa(len(body))
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return f.pacer.Call(func() (bool, error) {
+		_, _, err = f.c.Call(f.c.StorageUrl, swift.RequestOpts{
+			Container:  f.container,
+			ObjectName: manifestName,
+			Operation:  "PUT",
+			Parameters: url.Values{"multipart-manifest": {"put"}},
+			Headers:    headers,
+			Body:       bytes.NewReader(body),
+			NoResponse: true,
+			OnReAuth: func() (string, error) {
+				return f.c.StorageUrl, nil
+			},
+		})
+		return shouldRetry(err)
+	})
+}
+
+// largeObjectSegments returns the container holding the segments of
+// o and the segments if o is a large object, and whether it is a
+// static large object.  If o isn't a large object or doesn't exist
+// then no segments are returned.
+func (o *Object) largeObjectSegments() (container string, segments []swift.Object, slo bool, err error) {
+	err = o.readMetaData()
+	if err == fs.ErrorObjectNotFound {
+		return "", nil, false, nil
+	} else if err != nil {
+		return "", nil, false, err
+	}
+	if manifest, ok := o.headers["X-Object-Manifest"]; ok {
+		if unescaped, err := url.PathUnescape(manifest); err == nil {
+			manifest = unescaped
+		}
+		container, prefix := manifest, ""
+		if i := strings.IndexRune(manifest, '/'); i >= 0 {
+			container, prefix = manifest[:i], manifest[i+1:]
+		}
+		err = o.fs.pacer.Call(func() (bool, error) {
+			segments, err = o.fs.c.ObjectsAll(container, &swift.ObjectsOpts{Prefix: prefix})
+			return shouldRetry(err)
+		})
+		return container, segments, false, err
+	}
+	if _, ok := o.headers["X-Static-Large-Object"]; ok {
+		err = o.fs.pacer.Call(func() (bool, error) {
+			container, segments, err = o.fs.c.LargeObjectGetSegments(o.fs.container, o.fs.root+o.remote)
+			return shouldRetry(err)
+		})
+		return container, segments, true, err
+	}
+	return "", nil, false, nil
+}
+
+// removeSegments removes segments from container, then container
+// itself if it is now empty
+func (f *Fs) removeSegments(container string, segments []swift.Object) error {
+	for _, segment := range segments {
+		segmentPath := segment.Name
+		fs.Debugf(f, "Removing segment file %q in container %q", segmentPath, container)
+		err := f.pacer.Call(func() (bool, error) {
+			err := f.c.ObjectDelete(container, segmentPath)
+			return shouldRetry(err)
+		})
+		if err != nil && err != swift.ObjectNotFound {
+			return err
+		}
+	}
+	// remove the segments container if empty, ignore errors
+	err := f.pacer.Call(func() (bool, error) {
+		err := f.c.ContainerDelete(container)
+		return shouldRetry(err)
+	})
+	if err == nil {
+		fs.Debugf(f, "Removed empty container %q", container)
+	}
+	return nil
+}
+
+// copyLargeObject copies the large object srcObj to remote by copying
+// each of its segments server side then making a new manifest of the
+// same type pointing to the copies, so that the source and the copy
+// don't share segments.
+func (f *Fs) copyLargeObject(srcObj *Object, remote string, srcContainer string, segments []swift.Object, slo bool) error {
+	err := f.makeSegmentsContainer()
+	if err != nil {
+		return err
+	}
+	segmentsPath := f.newSegmentsPath(remote, srcObj.size)
+	copies := make([]swift.Object, len(segments))
+	for i, segment := range segments {
+		segmentPath := fmt.Sprintf("%s/%08d", segmentsPath, i)
+		fs.Debugf(srcObj, "Copying segment file %q into %q", segmentPath, f.segmentsContainer)
+		err = f.pacer.Call(func() (bool, error) {
+			_, err = f.c.ObjectCopy(srcContainer, segment.Name, f.segmentsContainer, segmentPath, nil)
+			return shouldRetry(err)
+		})
+		if err != nil {
+			return err
+		}
+		copies[i] = swift.Object{
+			Name:  segmentPath,
+			Bytes: segment.Bytes,
+			Hash:  segment.Hash,
+		}
+	}
+	headers := srcObj.headers.ObjectMetadata().ObjectHeaders()
+	return f.putManifest(f.root+remote, slo, segmentsPath, copies, srcObj.contentType, headers)
+}
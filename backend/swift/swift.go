@@ -22,6 +22,7 @@ import (
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/walk"
 	"github.com/ncw/rclone/lib/pacer"
+	"github.com/ncw/rclone/lib/readers"
 	"github.com/ncw/swift"
 	"github.com/pkg/errors"
 )
@@ -32,6 +33,7 @@ const (
 	listChunks                 = 1000                    // chunk size to read directory listings
 	defaultChunkSize           = 5 * fs.GibiByte
 	minSleep                   = 10 * time.Millisecond // In case of error, start at 10ms sleep.
+	tokenExpiryMargin          = 10 * time.Minute      // re-authenticate before uploads if the token expires within this
 )
 
 // SharedOptions are shared between swift and hubic
@@ -57,6 +59,25 @@ Rclone will still chunk files bigger than chunk_size when doing normal
 copy operations.`,
 	Default:  false,
 	Advanced: true,
+}, {
+	Name: "segments_container",
+	Help: `Container to store the segments of large objects in.
+
+Leave blank to use the name of the container with "_segments" on the
+end.  If set, the segments of objects in different containers are
+stored under the name of their container.`,
+	Advanced: true,
+}, {
+	Name: "use_slo",
+	Help: `Upload large objects as static large objects (SLO).
+
+By default large objects are uploaded as dynamic large objects (DLO)
+which find their segments by listing the segments container.  Static
+large objects list their segments in the manifest so they are
+consistent as soon as they are uploaded, but the server must support
+them.`,
+	Default:  false,
+	Advanced: true,
 }}
 
 // Register with Fs
@@ -112,6 +133,9 @@ func init() {
 		}, {
 			Name: "domain",
 			Help: "User domain - optional (v3 auth) (OS_USER_DOMAIN_NAME)",
+		}, {
+			Name: "domain_id",
+			Help: "User domain ID - optional (v3 auth) (OS_USER_DOMAIN_ID)",
 		}, {
 			Name: "tenant",
 			Help: "Tenant name - optional for v1 auth, this or tenant_id required otherwise (OS_TENANT_NAME or OS_PROJECT_NAME)",
@@ -121,6 +145,9 @@ func init() {
 		}, {
 			Name: "tenant_domain",
 			Help: "Tenant domain - optional (v3 auth) (OS_PROJECT_DOMAIN_NAME)",
+		}, {
+			Name: "tenant_domain_id",
+			Help: "Tenant domain ID - optional (v3 auth) (OS_PROJECT_DOMAIN_ID)",
 		}, {
 			Name: "region",
 			Help: "Region name - optional (OS_REGION_NAME)",
@@ -188,9 +215,11 @@ type Options struct {
 	Auth                        string        `config:"auth"`
 	UserID                      string        `config:"user_id"`
 	Domain                      string        `config:"domain"`
+	DomainID                    string        `config:"domain_id"`
 	Tenant                      string        `config:"tenant"`
 	TenantID                    string        `config:"tenant_id"`
 	TenantDomain                string        `config:"tenant_domain"`
+	TenantDomainID              string        `config:"tenant_domain_id"`
 	Region                      string        `config:"region"`
 	StorageURL                  string        `config:"storage_url"`
 	AuthToken                   string        `config:"auth_token"`
@@ -202,6 +231,8 @@ type Options struct {
 	EndpointType                string        `config:"endpoint_type"`
 	ChunkSize                   fs.SizeSuffix `config:"chunk_size"`
 	NoChunk                     bool          `config:"no_chunk"`
+	SegmentsContainer           string        `config:"segments_container"`
+	UseSLO                      bool          `config:"use_slo"`
 }
 
 // Fs represents a remote swift server
@@ -286,6 +317,24 @@ func shouldRetry(err error) (bool, error) {
 	return fserrors.ShouldRetry(err), err
 }
 
+// checkToken makes the connection re-authenticate if the token is
+// about to expire.
+//
+// Uploads can't be retried if the token expires part way through so
+// call this before starting each one.
+func (f *Fs) checkToken() {
+	a, ok := f.c.Auth.(*auth)
+	if !ok {
+		return
+	}
+	expires := a.expiry()
+	if expires.IsZero() || time.Until(expires) > tokenExpiryMargin {
+		return
+	}
+	fs.Debugf(f, "Token expires at %v - re-authenticating", expires)
+	f.c.UnAuthenticate()
+}
+
 // Pattern to match a swift path
 var matcher = regexp.MustCompile(`^/*([^/]*)(.*)$`)
 
@@ -310,9 +359,11 @@ func swiftConnection(opt *Options, name string) (*swift.Connection, error) {
 		AuthUrl:                     opt.Auth,
 		UserId:                      opt.UserID,
 		Domain:                      opt.Domain,
+		DomainId:                    opt.DomainID,
 		Tenant:                      opt.Tenant,
 		TenantId:                    opt.TenantID,
 		TenantDomain:                opt.TenantDomain,
+		TenantDomainId:              opt.TenantDomainID,
 		Region:                      opt.Region,
 		StorageUrl:                  opt.StorageURL,
 		AuthToken:                   opt.AuthToken,
@@ -333,13 +384,21 @@ func swiftConnection(opt *Options, name string) (*swift.Connection, error) {
 	}
 	StorageUrl, AuthToken := c.StorageUrl, c.AuthToken // nolint
 	if !c.Authenticated() {
-		if (c.ApplicationCredentialId != "" || c.ApplicationCredentialName != "") && c.ApplicationCredentialSecret == "" {
+		if c.ApplicationCredentialSecret == "" {
 			if c.UserName == "" && c.UserId == "" {
 				return nil, errors.New("user name or user id not found for authentication (and no storage_url+auth_token is provided)")
 			}
 			if c.ApiKey == "" {
 				return nil, errors.New("key not found")
 			}
+		} else if c.ApplicationCredentialId == "" {
+			// application credentials are looked up by name in the user
+			if c.ApplicationCredentialName == "" {
+				return nil, errors.New("application_credential_id or application_credential_name needed with application_credential_secret")
+			}
+			if c.UserName == "" && c.UserId == "" {
+				return nil, errors.New("user name or user id needed with application_credential_name")
+			}
 		}
 		if c.AuthUrl == "" {
 			return nil, errors.New("auth not found")
@@ -351,19 +410,19 @@ func swiftConnection(opt *Options, name string) (*swift.Connection, error) {
 	}
 	// Make sure we re-auth with the AuthToken and StorageUrl
 	// provided by wrapping the existing auth, so we can just
-	// override one or the other or both.
-	if StorageUrl != "" || AuthToken != "" {
-		// Re-write StorageURL and AuthToken if they are being
-		// overridden as c.Authenticate above will have
-		// overwritten them.
-		if StorageUrl != "" {
-			c.StorageUrl = StorageUrl
-		}
-		if AuthToken != "" {
-			c.AuthToken = AuthToken
-		}
-		c.Auth = newAuth(c.Auth, StorageUrl, AuthToken)
-	}
+	// override one or the other or both.  The wrapper also
+	// notes when the token expires.
+	//
+	// Re-write StorageURL and AuthToken if they are being
+	// overridden as c.Authenticate above will have overwritten
+	// them.
+	if StorageUrl != "" {
+		c.StorageUrl = StorageUrl
+	}
+	if AuthToken != "" {
+		c.AuthToken = AuthToken
+	}
+	c.Auth = newAuth(c.Auth, StorageUrl, AuthToken)
 	return c, nil
 }
 
@@ -393,12 +452,16 @@ func NewFsWithConnection(opt *Options, name, root string, c *swift.Connection, n
 	if err != nil {
 		return nil, err
 	}
+	segmentsContainer := opt.SegmentsContainer
+	if segmentsContainer == "" {
+		segmentsContainer = container + "_segments"
+	}
 	f := &Fs{
 		name:              name,
 		opt:               *opt,
 		c:                 c,
 		container:         container,
-		segmentsContainer: container + "_segments",
+		segmentsContainer: segmentsContainer,
 		root:              directory,
 		noCheckContainer:  noCheckContainer,
 		pacer:             pacer.New().SetMinSleep(minSleep).SetPacer(pacer.S3Pacer),
@@ -815,6 +878,19 @@ func (f *Fs) Copy(src fs.Object, remote string) (fs.Object, error) {
 		return nil, fs.ErrorCantCopy
 	}
 	srcFs := srcObj.fs
+	// Copying a large object would copy the joined up segments
+	// which fails if it is too big, so copy the segments instead
+	segmentsContainer, segments, slo, err := srcObj.largeObjectSegments()
+	if err != nil {
+		return nil, err
+	}
+	if segmentsContainer != "" {
+		err = f.copyLargeObject(srcObj, remote, segmentsContainer, segments, slo)
+		if err != nil {
+			return nil, err
+		}
+		return f.NewObject(remote)
+	}
 	err = f.pacer.Call(func() (bool, error) {
 		_, err = f.c.ObjectCopy(srcFs.container, srcFs.root+srcObj.remote, f.container, f.root+remote, nil)
 		return shouldRetry(err)
@@ -1016,41 +1092,6 @@ func min(x, y int64) int64 {
 	return y
 }
 
-// removeSegments removes any old segments from o
-//
-// if except is passed in then segments with that prefix won't be deleted
-func (o *Object) removeSegments(except string) error {
-	segmentsRoot := o.fs.root + o.remote + "/"
-	err := o.fs.listContainerRoot(o.fs.segmentsContainer, segmentsRoot, "", true, func(remote string, object *swift.Object, isDirectory bool) error {
-		if isDirectory {
-			return nil
-		}
-		if except != "" && strings.HasPrefix(remote, except) {
-			// fs.Debugf(o, "Ignoring current segment file %q in container %q", segmentsRoot+remote, o.fs.segmentsContainer)
-			return nil
-		}
-		segmentPath := segmentsRoot + remote
-		fs.Debugf(o, "Removing segment file %q in container %q", segmentPath, o.fs.segmentsContainer)
-		var err error
-		return o.fs.pacer.Call(func() (bool, error) {
-			err = o.fs.c.ObjectDelete(o.fs.segmentsContainer, segmentPath)
-			return shouldRetry(err)
-		})
-	})
-	if err != nil {
-		return err
-	}
-	// remove the segments container if empty, ignore errors
-	err = o.fs.pacer.Call(func() (bool, error) {
-		err = o.fs.c.ContainerDelete(o.fs.segmentsContainer)
-		return shouldRetry(err)
-	})
-	if err == nil {
-		fs.Debugf(o, "Removed empty container %q", o.fs.segmentsContainer)
-	}
-	return nil
-}
-
 // urlEncode encodes a string so that it is a valid URL
 //
 // We don't use any of Go's standard methods as we need `/` not
@@ -1069,38 +1110,24 @@ func urlEncode(str string) string {
 }
 
 // updateChunks updates the existing object using chunks to a separate
-// container.  It returns a string which prefixes current segments.
-func (o *Object) updateChunks(in0 io.Reader, headers swift.Headers, size int64, contentType string) (string, error) {
+// container.
+func (o *Object) updateChunks(in0 io.Reader, headers swift.Headers, size int64, contentType string) error {
 	// Create the segmentsContainer if it doesn't exist
-	var err error
-	err = o.fs.pacer.Call(func() (bool, error) {
-		_, _, err = o.fs.c.Container(o.fs.segmentsContainer)
-		return shouldRetry(err)
-	})
-	if err == swift.ContainerNotFound {
-		headers := swift.Headers{}
-		if o.fs.opt.StoragePolicy != "" {
-			headers["X-Storage-Policy"] = o.fs.opt.StoragePolicy
-		}
-		err = o.fs.pacer.Call(func() (bool, error) {
-			err = o.fs.c.ContainerCreate(o.fs.segmentsContainer, headers)
-			return shouldRetry(err)
-		})
-	}
+	err := o.fs.makeSegmentsContainer()
 	if err != nil {
-		return "", err
+		return err
 	}
 	// Upload the chunks
 	left := size
 	i := 0
-	uniquePrefix := fmt.Sprintf("%s/%d", swift.TimeToFloatString(time.Now()), size)
-	segmentsPath := fmt.Sprintf("%s%s/%s", o.fs.root, o.remote, uniquePrefix)
+	segmentsPath := o.fs.newSegmentsPath(o.remote, size)
+	var segments []swift.Object
 	in := bufio.NewReader(in0)
 	for {
 		// can we read at least one byte?
 		if _, err := in.Peek(1); err != nil {
 			if left > 0 {
-				return "", err // read less than expected
+				return err // read less than expected
 			}
 			fs.Debugf(o, "Uploading segments into %q seems done (%v)", o.fs.segmentsContainer, err)
 			break
@@ -1111,28 +1138,28 @@ func (o *Object) updateChunks(in0 io.Reader, headers swift.Headers, size int64,
 			headers["Content-Length"] = strconv.FormatInt(n, 10) // set Content-Length as we know it
 			left -= n
 		}
-		segmentReader := io.LimitReader(in, n)
+		segmentReader := readers.NewCountingReader(io.LimitReader(in, n))
+		o.fs.checkToken()
 		segmentPath := fmt.Sprintf("%s/%08d", segmentsPath, i)
 		fs.Debugf(o, "Uploading segment file %q into %q", segmentPath, o.fs.segmentsContainer)
+		var rxHeaders swift.Headers
 		err = o.fs.pacer.CallNoRetry(func() (bool, error) {
-			_, err = o.fs.c.ObjectPut(o.fs.segmentsContainer, segmentPath, segmentReader, true, "", "", headers)
+			rxHeaders, err = o.fs.c.ObjectPut(o.fs.segmentsContainer, segmentPath, segmentReader, true, "", "", headers)
 			return shouldRetry(err)
 		})
 		if err != nil {
-			return "", err
+			return err
 		}
+		segments = append(segments, swift.Object{
+			Name:  segmentPath,
+			Bytes: int64(segmentReader.BytesRead()),
+			Hash:  rxHeaders["Etag"],
+		})
 		i++
 	}
 	// Upload the manifest
-	headers["X-Object-Manifest"] = urlEncode(fmt.Sprintf("%s/%s", o.fs.segmentsContainer, segmentsPath))
-	headers["Content-Length"] = "0" // set Content-Length as we know it
-	emptyReader := bytes.NewReader(nil)
-	manifestName := o.fs.root + o.remote
-	err = o.fs.pacer.Call(func() (bool, error) {
-		_, err = o.fs.c.ObjectPut(o.fs.container, manifestName, emptyReader, true, "", contentType, headers)
-		return shouldRetry(err)
-	})
-	return uniquePrefix + "/", err
+	delete(headers, "Content-Length")
+	return o.fs.putManifest(o.fs.root+o.remote, o.fs.opt.UseSLO, segmentsPath, segments, contentType, headers)
 }
 
 // Update the object with the contents of the io.Reader, modTime and size
@@ -1148,9 +1175,11 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	}
 	size := src.Size()
 	modTime := src.ModTime()
+	o.fs.checkToken()
 
-	// Note whether this is a dynamic large object before starting
-	isDynamicLargeObject, err := o.isDynamicLargeObject()
+	// Note the segments of the object if it is a large object
+	// before starting so they can be removed afterwards
+	oldSegmentsContainer, oldSegments, _, err := o.largeObjectSegments()
 	if err != nil {
 		return err
 	}
@@ -1158,11 +1187,10 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	// Set the mtime
 	m := swift.Metadata{}
 	m.SetModTime(modTime)
-	contentType := fs.MimeType(src)
+	contentType := fs.UploadMimeType(src)
 	headers := m.ObjectHeaders()
-	uniquePrefix := ""
 	if size > int64(o.fs.opt.ChunkSize) || (size == -1 && !o.fs.opt.NoChunk) {
-		uniquePrefix, err = o.updateChunks(in, headers, size, contentType)
+		err = o.updateChunks(in, headers, size, contentType)
 		if err != nil {
 			return err
 		}
@@ -1188,9 +1216,9 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 		o.headers = headers
 	}
 
-	// If file was a dynamic large object then remove old/all segments
-	if isDynamicLargeObject {
-		err = o.removeSegments(uniquePrefix)
+	// If file was a large object then remove its old segments
+	if len(oldSegments) > 0 {
+		err = o.fs.removeSegments(oldSegmentsContainer, oldSegments)
 		if err != nil {
 			fs.Logf(o, "Failed to remove old segments - carrying on with upload: %v", err)
 		}
@@ -1202,7 +1230,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 
 // Remove an object
 func (o *Object) Remove() error {
-	isDynamicLargeObject, err := o.isDynamicLargeObject()
+	segmentsContainer, segments, _, err := o.largeObjectSegments()
 	if err != nil {
 		return err
 	}
@@ -1215,8 +1243,8 @@ func (o *Object) Remove() error {
 		return err
 	}
 	// ...then segments if required
-	if isDynamicLargeObject {
-		err = o.removeSegments("")
+	if len(segments) > 0 {
+		err = o.fs.removeSegments(segmentsContainer, segments)
 		if err != nil {
 			return err
 		}
@@ -1,6 +1,12 @@
 package swift
 
-import "testing"
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ncw/swift"
+)
 
 func TestInternalUrlEncode(t *testing.T) {
 	for _, test := range []struct {
@@ -23,3 +29,48 @@ func TestInternalUrlEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestInternalSegmentsRoot(t *testing.T) {
+	f := &Fs{container: "container", root: "root/"}
+	got := f.segmentsRoot("file.txt")
+	if got != "root/file.txt/" {
+		t.Errorf("want %q got %q", "root/file.txt/", got)
+	}
+	f.opt.SegmentsContainer = "segments"
+	got = f.segmentsRoot("file.txt")
+	if got != "container/root/file.txt/" {
+		t.Errorf("want %q got %q", "container/root/file.txt/", got)
+	}
+}
+
+// testAuth is a swift.Authenticator holding an auth response like
+// the ones in the swift library
+type testAuth struct {
+	Auth interface{}
+}
+
+func (a *testAuth) Request(*swift.Connection) (*http.Request, error) { return nil, nil }
+func (a *testAuth) Response(resp *http.Response) error               { return nil }
+func (a *testAuth) StorageUrl(Internal bool) string                  { return "" } // nolint
+func (a *testAuth) Token() string                                    { return "" }
+func (a *testAuth) CdnUrl() string                                   { return "" } // nolint
+
+func TestInternalTokenExpiry(t *testing.T) {
+	want := time.Date(2019, 3, 1, 12, 30, 0, 123000000, time.UTC)
+	v3 := map[string]interface{}{"Token": map[string]string{"Expires_At": "2019-03-01T12:30:00.123000Z"}}
+	v2 := map[string]interface{}{"Access": map[string]interface{}{"Token": map[string]string{"Expires": "2019-03-01T12:30:00.123Z"}}}
+	for _, test := range []struct {
+		auth swift.Authenticator
+		want time.Time
+	}{
+		{nil, time.Time{}},
+		{&testAuth{}, time.Time{}},
+		{&testAuth{Auth: v3}, want},
+		{&testAuth{Auth: v2}, want},
+	} {
+		got := tokenExpiry(test.auth)
+		if !got.Equal(test.want) {
+			t.Errorf("%#v: want %v got %v", test.auth, test.want, got)
+		}
+	}
+}
@@ -783,9 +783,13 @@ func (f *Fs) DirMove(src fs.Fs, srcRemote, dstRemote string) error {
 }
 
 // PublicLink generates a public link to the remote path (usually readable by anyone)
-func (f *Fs) PublicLink(remote string) (link string, err error) {
+func (f *Fs) PublicLink(remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if expire.IsSet() {
+		return "", fs.ErrorCantExpireLink
+	}
+	unlink = unlink || f.opt.Unlink
 	var path string
-	if f.opt.Unlink {
+	if unlink {
 		path = "/resources/unpublish"
 	} else {
 		path = "/resources/publish"
@@ -812,11 +816,14 @@ func (f *Fs) PublicLink(remote string) (link string, err error) {
 		}
 	}
 	if err != nil {
-		if f.opt.Unlink {
+		if unlink {
 			return "", errors.Wrap(err, "couldn't remove public link")
 		}
 		return "", errors.Wrap(err, "couldn't create public link")
 	}
+	if unlink {
+		return "", nil
+	}
 
 	info, err := f.readMetaDataForPath(f.filePath(remote), &api.ResourceInfoRequestOptions{})
 	if err != nil {
@@ -1102,7 +1109,7 @@ func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOptio
 	}
 
 	//upload file
-	err = o.upload(in1, true, fs.MimeType(src))
+	err = o.upload(in1, true, fs.UploadMimeType(src))
 	if err != nil {
 		return err
 	}
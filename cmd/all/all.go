@@ -6,6 +6,7 @@ import (
 	_ "github.com/ncw/rclone/cmd"
 	_ "github.com/ncw/rclone/cmd/about"
 	_ "github.com/ncw/rclone/cmd/authorize"
+	_ "github.com/ncw/rclone/cmd/backend"
 	_ "github.com/ncw/rclone/cmd/cachestats"
 	_ "github.com/ncw/rclone/cmd/cat"
 	_ "github.com/ncw/rclone/cmd/check"
@@ -24,7 +25,6 @@ import (
 	_ "github.com/ncw/rclone/cmd/genautocomplete"
 	_ "github.com/ncw/rclone/cmd/gendocs"
 	_ "github.com/ncw/rclone/cmd/hashsum"
-	_ "github.com/ncw/rclone/cmd/info"
 	_ "github.com/ncw/rclone/cmd/link"
 	_ "github.com/ncw/rclone/cmd/listremotes"
 	_ "github.com/ncw/rclone/cmd/ls"
@@ -44,6 +44,7 @@ import (
 	_ "github.com/ncw/rclone/cmd/rc"
 	_ "github.com/ncw/rclone/cmd/rcat"
 	_ "github.com/ncw/rclone/cmd/rcd"
+	_ "github.com/ncw/rclone/cmd/rename"
 	_ "github.com/ncw/rclone/cmd/reveal"
 	_ "github.com/ncw/rclone/cmd/rmdir"
 	_ "github.com/ncw/rclone/cmd/rmdirs"
@@ -52,6 +53,10 @@ import (
 	_ "github.com/ncw/rclone/cmd/sha1sum"
 	_ "github.com/ncw/rclone/cmd/size"
 	_ "github.com/ncw/rclone/cmd/sync"
+	_ "github.com/ncw/rclone/cmd/test"
+	_ "github.com/ncw/rclone/cmd/test/connectivity"
+	_ "github.com/ncw/rclone/cmd/test/info"
+	_ "github.com/ncw/rclone/cmd/test/makefiles"
 	_ "github.com/ncw/rclone/cmd/touch"
 	_ "github.com/ncw/rclone/cmd/tree"
 	_ "github.com/ncw/rclone/cmd/version"
@@ -0,0 +1,218 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	options    []string
+	jsonOutput bool
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	commandDefinition.Flags().StringArrayVarP(&options, "option", "o", options, "Option in the form name=value or name.")
+	commandDefinition.Flags().BoolVarP(&jsonOutput, "json", "", jsonOutput, "Always output in JSON format.")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "backend <command> remote:path [opts] <args>",
+	Short: `Run a backend specific command.`,
+	Long: `
+This runs a backend specific command. The commands themselves (except
+for "help" and "features") are defined by the backends and you should see the backend
+docs for definitions.
+
+You can discover what commands a backend implements by using
+
+    rclone backend help remote:
+    rclone backend help <backendname>
+
+You can discover what optional features a remote supports, eg whether
+it can do server side copies and moves, which hashes it supports and
+the precision of its modification times by using
+
+    rclone backend features remote:
+
+This is the same as the [operations/fsinfo](/rc/#operations/fsinfo)
+rc call.
+
+Options can be passed to the command with the -o flag, eg
+
+    rclone backend rekey crypt: -o password=newpassword
+
+Options without a value are set to "true".
+
+If remote:path points to a file and no arguments are given then the
+name of the file is passed to the command as its only argument.
+
+The command may output a string, a list of strings or some other data
+structure.  Use --json to always get JSON output.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 1e6, command, args)
+		name, remote := args[0], args[1]
+		cmd.Run(false, false, command, func() error {
+			if name == "help" {
+				return showHelp(remote)
+			}
+			fsInfo, _, _, _, err := fs.ConfigFs(remote)
+			if err != nil {
+				return err
+			}
+			f, fileName := cmd.NewFsSrcFile(args[1:2])
+			if name == "features" {
+				return printFeatures(f)
+			}
+			doCommand := f.Features().Command
+			if doCommand == nil {
+				return errors.Errorf("%v: doesn't support backend commands", f)
+			}
+			arg := args[2:]
+			if fileName != "" && len(arg) == 0 {
+				// remote:path pointed to a file so pass it on
+				arg = []string{fileName}
+			}
+			opt := parseOptions(options)
+			out, err := doCommand(name, arg, opt)
+			if err == fs.ErrorCommandNotFound {
+				return errors.Errorf("%q is not a backend command for %q - see \"rclone backend help %s\"", name, fsInfo.Name, fsInfo.Name)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "command %q failed", name)
+			}
+			return printOutput(out)
+		})
+	},
+}
+
+// parseOptions turns a list of name=value or name strings into a map
+func parseOptions(options []string) map[string]string {
+	opt := make(map[string]string, len(options))
+	for _, option := range options {
+		equals := strings.IndexRune(option, '=')
+		name, value := option, "true"
+		if equals >= 0 {
+			name, value = option[:equals], option[equals+1:]
+		}
+		opt[name] = value
+	}
+	return opt
+}
+
+// printOutput shows the output of a command to the user
+func printOutput(out interface{}) error {
+	if out == nil {
+		return nil
+	}
+	if !jsonOutput {
+		switch x := out.(type) {
+		case string:
+			fmt.Println(x)
+			return nil
+		case []string:
+			for _, line := range x {
+				fmt.Println(line)
+			}
+			return nil
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(out)
+}
+
+// printFeatures shows the optional features of f
+func printFeatures(f fs.Fs) error {
+	info := operations.GetFsInfo(f)
+	if jsonOutput {
+		return printOutput(info)
+	}
+	fmt.Printf("Name:      %s\n", info.Name)
+	fmt.Printf("Root:      %s\n", info.Root)
+	fmt.Printf("String:    %s\n", info.String)
+	if info.Precision == fs.ModTimeNotSupported {
+		fmt.Printf("Precision: modification times not supported\n")
+	} else {
+		fmt.Printf("Precision: %v\n", info.Precision)
+	}
+	fmt.Printf("Hashes:    %s\n", strings.Join(info.Hashes, ", "))
+	if len(info.Encoding) == 0 {
+		fmt.Printf("Encoding:  none\n")
+	} else {
+		fmt.Printf("Encoding:  %s\n", strings.Join(info.Encoding, ", "))
+	}
+	fmt.Printf("Features:\n")
+	names := make([]string, 0, len(info.Features))
+	for name := range info.Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-24s %v\n", name, info.Features[name])
+	}
+	return nil
+}
+
+// showHelp shows the backend commands for the remote or backend name
+func showHelp(remote string) error {
+	name := strings.TrimRight(remote, ":")
+	fsInfo, err := fs.Find(name)
+	if err != nil {
+		fsInfo, _, _, _, err = fs.ConfigFs(remote)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("### Backend commands\n\n")
+	if len(fsInfo.CommandHelp) == 0 {
+		fmt.Printf("The %q backend has no backend specific commands.\n", fsInfo.Name)
+		return nil
+	}
+	fmt.Printf(`Here are the commands specific to the %s backend.
+
+Run them with
+
+    rclone backend COMMAND remote:
+
+The help below will explain what arguments each command takes.
+
+See [the "rclone backend" command](/commands/rclone_backend/) for more
+info on how to pass options and arguments.
+
+These can be run on a running backend using the rc command
+[backend/command](/rc/#backend/command).
+
+`, fsInfo.Name)
+	for _, cmdHelp := range fsInfo.CommandHelp {
+		fmt.Printf("#### %s\n\n", cmdHelp.Name)
+		fmt.Printf("%s\n\n", cmdHelp.Short)
+		fmt.Printf("    rclone backend %s remote: [options] [<arguments>+]\n\n", cmdHelp.Name)
+		if cmdHelp.Long != "" {
+			fmt.Printf("%s\n\n", strings.TrimSpace(cmdHelp.Long))
+		}
+		if len(cmdHelp.Opts) != 0 {
+			fmt.Printf("Options:\n\n")
+			keys := make([]string, 0, len(cmdHelp.Opts))
+			for key := range cmdHelp.Opts {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("- %q: %s\n", key, cmdHelp.Opts[key])
+			}
+			fmt.Printf("\n")
+		}
+	}
+	return nil
+}
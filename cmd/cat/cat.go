@@ -13,11 +13,12 @@ import (
 
 // Globals
 var (
-	head    = int64(0)
-	tail    = int64(0)
-	offset  = int64(0)
-	count   = int64(-1)
-	discard = false
+	head      = int64(0)
+	tail      = int64(0)
+	offset    = int64(0)
+	count     = int64(-1)
+	discard   = false
+	separator = ""
 )
 
 func init() {
@@ -27,6 +28,7 @@ func init() {
 	commandDefintion.Flags().Int64VarP(&offset, "offset", "", offset, "Start printing at offset N (or from end if -ve).")
 	commandDefintion.Flags().Int64VarP(&count, "count", "", count, "Only print N characters.")
 	commandDefintion.Flags().BoolVarP(&discard, "discard", "", discard, "Discard the output instead of printing.")
+	commandDefintion.Flags().StringVarP(&separator, "separator", "", separator, "Separator to print between files.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -51,6 +53,13 @@ Use the --head flag to print characters only at the start, --tail for
 the end and --offset and --count to print a section in the middle.
 Note that if offset is negative it will count from the end, so
 --offset -1 --count 1 is equivalent to --tail 1.
+
+When printing more than one file use the --separator flag to print a
+string between the contents of each file.  Special characters need to
+be escaped by the shell, so to print a newline between files in bash
+use
+
+    rclone --include "*.txt" cat --separator $'\n' remote:path/to/dir
 `,
 	Run: func(command *cobra.Command, args []string) {
 		usedOffset := offset != 0 || count >= 0
@@ -74,7 +83,7 @@ Note that if offset is negative it will count from the end, so
 			w = ioutil.Discard
 		}
 		cmd.Run(false, false, command, func() error {
-			return operations.Cat(fsrc, w, offset, count)
+			return operations.Cat(fsrc, w, offset, count, []byte(separator))
 		})
 	},
 }
@@ -8,13 +8,16 @@ import (
 
 // Globals
 var (
-	download = false
-	oneway   = false
+	download    = false
+	oneway      = false
+	downloadOpt operations.CheckDownloadOpt
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&download, "download", "", download, "Check by downloading rather than with hash.")
+	commandDefintion.Flags().IntVarP(&downloadOpt.Checkers, "download-checkers", "", 0, "Number of pairs of files to download at once with --download (default --checkers).")
+	commandDefintion.Flags().VarP(&downloadOpt.BufferSize, "download-buffer", "", "Memory to buffer each pair of files in with --download (default 2*--buffer-size).")
 	commandDefintion.Flags().BoolVarP(&oneway, "one-way", "", oneway, "Check one way only, source files must exist on remote")
 }
 
@@ -34,6 +37,14 @@ both remotes and check them against each other on the fly.  This can
 be useful for remotes that don't support hashes or if you really want
 to check all the data.
 
+With --download the files are downloaded and checked in parallel,
+--download-checkers pairs of files at once (default the value of
+--checkers).  Each pair is compared as it streams in, so only
+--download-buffer of memory (default twice --buffer-size) is used to
+buffer each pair however big the files are.  Reduce these to use less
+memory, or increase --download-checkers to check large numbers of
+small files faster.
+
 If you supply the --one-way flag, it will only check that files in source
 match the files in destination, not the other way around. Meaning extra files in
 destination that are not in the source will not trigger an error.
@@ -43,7 +54,7 @@ destination that are not in the source will not trigger an error.
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(false, false, command, func() error {
 			if download {
-				return operations.CheckDownload(fdst, fsrc, oneway)
+				return operations.CheckDownloadWithOpt(fdst, fsrc, oneway, downloadOpt)
 			}
 			return operations.Check(fdst, fsrc, oneway)
 		})
@@ -29,6 +29,7 @@ import (
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/fspath"
 	fslog "github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fs/rc/rcflags"
 	"github.com/ncw/rclone/fs/rc/rcserver"
 	"github.com/ncw/rclone/lib/atexit"
@@ -64,6 +65,8 @@ const (
 	exitCodeNoRetryError
 	exitCodeFatalError
 	exitCodeTransferExceeded
+	exitCodeSignal
+	exitCodeCopiedToFailover
 )
 
 // ShowVersion prints the version to stdout
@@ -127,6 +130,14 @@ func NewFsSrc(args []string) fs.Fs {
 	return fsrc
 }
 
+// NewFsSrcFile creates a new src fs from the arguments.
+//
+// The source can be a file or a directory - if a file then it will
+// limit the Fs to a single file and return its name.
+func NewFsSrcFile(args []string) (fsrc fs.Fs, srcFileName string) {
+	return newFsFileAddFilter(args[0])
+}
+
 // newFsDir creates an Fs from a name
 //
 // This must point to a directory
@@ -228,8 +239,20 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 		stopStats = StartStats()
 	}
 	SigInfoHandler()
+	if Retry {
+		atexit.OnSignal(func(sig os.Signal) {
+			fs.Logf(nil, "Signal received: %s - shutting down with --cutoff-mode %s, send it again to exit now", sig, fs.Config.CutoffMode)
+			accounting.Shutdown(fs.Config.CutoffMode == "hard")
+		}, exitCodeSignal)
+	} else {
+		atexit.OnSignal(nil, exitCodeSignal)
+	}
 	for try := 1; try <= *retries; try++ {
 		err = f()
+		if accounting.ShuttingDown() {
+			err = accounting.ErrorShuttingDown
+			break
+		}
 		if !Retry || (err == nil && !accounting.Stats.Errored()) {
 			if try > 1 {
 				fs.Errorf(nil, "Attempt %d/%d succeeded", try, *retries)
@@ -251,12 +274,19 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 		}
 		if try < *retries {
 			accounting.Stats.ResetErrors()
+			accounting.ErrorReportNextAttempt()
 		}
 		if *retriesInterval > 0 {
 			time.Sleep(*retriesInterval)
 		}
 	}
 	stopStats()
+	if reportErr := accounting.WriteErrorReport(); reportErr != nil {
+		fs.Errorf(nil, "%v", reportErr)
+	}
+	if showStats && err == accounting.ErrorShuttingDown {
+		accounting.Stats.Log()
+	}
 	if err != nil {
 		log.Printf("Failed to %s: %v", cmd.Name(), err)
 		resolveExitCode(err)
@@ -264,6 +294,7 @@ func Run(Retry bool, showStats bool, cmd *cobra.Command, f func() error) {
 	if showStats && (accounting.Stats.Errored() || *statsInterval > 0) {
 		accounting.Stats.Log()
 	}
+	accounting.LogUsage()
 	fs.Debugf(nil, "%d go routines active\n", runtime.NumGoroutine())
 
 	// dump all running go-routines
@@ -421,6 +452,10 @@ func resolveExitCode(err error) {
 		os.Exit(exitCodeUncategorizedError)
 	case unwrapped == accounting.ErrorMaxTransferLimitReached:
 		os.Exit(exitCodeTransferExceeded)
+	case unwrapped == accounting.ErrorShuttingDown:
+		os.Exit(exitCodeSignal)
+	case unwrapped == operations.ErrorCopiedToFailover:
+		os.Exit(exitCodeCopiedToFailover)
 	case fserrors.ShouldRetry(err):
 		os.Exit(exitCodeRetryError)
 	case fserrors.IsNoRetryError(err):
@@ -477,6 +512,13 @@ func AddBackendFlags() {
 func Main() {
 	setupRootCommand(Root)
 	AddBackendFlags()
+	if isMountHelper() {
+		args, err := convertMountHelperArgs(os.Args[1:])
+		if err != nil {
+			log.Fatalf("Fatal error: %v", err)
+		}
+		Root.SetArgs(args)
+	}
 	if err := Root.Execute(); err != nil {
 		log.Fatalf("Fatal error: %v", err)
 	}
@@ -95,7 +95,10 @@ func (fsys *FS) closeHandle(fh uint64) (errc int) {
 
 // lookup a Node given a path
 func (fsys *FS) lookupNode(path string) (node vfs.Node, errc int) {
-	node, err := fsys.VFS.Stat(path)
+	err := mountlib.Retry(func() (err error) {
+		node, err = fsys.VFS.Stat(path)
+		return err
+	})
 	return node, translateError(err)
 }
 
@@ -159,11 +162,10 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 		Mode |= fuse.S_IFREG
 	}
 	//stat.Dev = 1
-	stat.Ino = node.Inode() // FIXME do we need to set the inode number?
+	stat.Ino = node.Inode() // only used if mounted with -o use_ino
 	stat.Mode = uint32(Mode)
-	stat.Nlink = 1
-	stat.Uid = fsys.VFS.Opt.UID
-	stat.Gid = fsys.VFS.Opt.GID
+	stat.Nlink = node.Nlink()
+	stat.Uid, stat.Gid = node.Owner()
 	//stat.Rdev
 	stat.Size = int64(Size)
 	t := fuse.NewTimespec(modTime)
@@ -203,7 +205,11 @@ func (fsys *FS) Getattr(path string, stat *fuse.Stat_t, fh uint64) (errc int) {
 // Opendir opens path as a directory
 func (fsys *FS) Opendir(path string) (errc int, fh uint64) {
 	defer log.Trace(path, "")("errc=%d, fh=0x%X", &errc, &fh)
-	handle, err := fsys.VFS.OpenFile(path, os.O_RDONLY, 0777)
+	var handle vfs.Handle
+	err := mountlib.Retry(func() (err error) {
+		handle, err = fsys.VFS.OpenFile(path, os.O_RDONLY, 0777)
+		return err
+	})
 	if err != nil {
 		return translateError(err), fhUnset
 	}
@@ -223,7 +229,11 @@ func (fsys *FS) Readdir(dirPath string,
 		return errc
 	}
 
-	items, err := node.Readdir(-1)
+	var items []os.FileInfo
+	err := mountlib.Retry(func() (err error) {
+		items, err = node.Readdir(-1)
+		return err
+	})
 	if err != nil {
 		return translateError(err)
 	}
@@ -294,7 +304,11 @@ func (fsys *FS) Open(path string, flags int) (errc int, fh uint64) {
 
 	// translate the fuse flags to os flags
 	flags = translateOpenFlags(flags)
-	handle, err := fsys.VFS.OpenFile(path, flags, 0777)
+	var handle vfs.Handle
+	err := mountlib.RetryOpen(flags, func() (err error) {
+		handle, err = fsys.VFS.OpenFile(path, flags, 0777)
+		return err
+	})
 	if err != nil {
 		return translateError(err), fhUnset
 	}
@@ -348,7 +362,10 @@ func (fsys *FS) Read(path string, buff []byte, ofst int64, fh uint64) (n int) {
 	if errc != 0 {
 		return errc
 	}
-	n, err := handle.ReadAt(buff, ofst)
+	err := mountlib.Retry(func() (err error) {
+		n, err = handle.ReadAt(buff, ofst)
+		return err
+	})
 	if err == io.EOF {
 	} else if err != nil {
 		return translateError(err)
@@ -528,6 +545,7 @@ func translateError(err error) (errc int) {
 	if err == nil {
 		return 0
 	}
+	err = mountlib.MapError(err)
 	switch errors.Cause(err) {
 	case vfs.OK:
 		return 0
@@ -551,6 +569,13 @@ func translateError(err error) (errc int) {
 		return -fuse.ENOSYS
 	case vfs.EINVAL:
 		return -fuse.EINVAL
+	case vfs.EDQUOT:
+		// cgofuse doesn't have EDQUOT as Windows doesn't
+		return -fuse.ENOSPC
+	case vfs.EACCES:
+		return -fuse.EACCES
+	case vfs.EBUSY:
+		return -fuse.EBUSY
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return -fuse.EIO
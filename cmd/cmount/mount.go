@@ -89,12 +89,19 @@ func mountOptions(device string, mountpoint string) (options []string) {
 	if vfsflags.Opt.ReadOnly {
 		options = append(options, "-o", "ro")
 	}
+	if vfsflags.Opt.PersistentInodes && runtime.GOOS != "windows" {
+		// use the inode numbers from the VFS
+		options = append(options, "-o", "use_ino")
+	}
 	if mountlib.WritebackCache {
 		// FIXME? options = append(options, "-o", WritebackCache())
 	}
 	if mountlib.DaemonTimeout != 0 {
 		options = append(options, "-o", fmt.Sprintf("daemon_timeout=%d", int(mountlib.DaemonTimeout.Seconds())))
 	}
+	if mountlib.AsyncRead && runtime.GOOS == "linux" {
+		options = append(options, "-o", "async_read")
+	}
 	for _, option := range mountlib.ExtraOptions {
 		options = append(options, "-o", option)
 	}
@@ -20,6 +20,7 @@ func init() {
 	configCommand.AddCommand(configUpdateCommand)
 	configCommand.AddCommand(configDeleteCommand)
 	configCommand.AddCommand(configPasswordCommand)
+	configCommand.AddCommand(configKeyringCommand)
 }
 
 var configCommand = &cobra.Command{
@@ -184,6 +185,36 @@ For example to set password of a remote of name myremote you would do:
 	},
 }
 
+var configKeyringCommand = &cobra.Command{
+	Use:   "keyring <name> <key>+",
+	Short: `Move secrets from the config file into the system keyring.`,
+	Long: `
+Move the values of the keys given in an existing remote from the
+config file into the system keyring.  The value in the config file is
+replaced with ` + "`" + config.KeyringValue + "`" + ` and rclone reads the real value
+from the keyring when it is needed.
+
+For example to keep the secret access key of an s3 remote called
+myremote in the keyring
+
+    rclone config keyring myremote secret_access_key
+
+On Linux and other Unixes this uses ` + "`secret-tool`" + ` from libsecret so
+needs a running Secret Service such as GNOME Keyring or KWallet.  On
+macOS it uses ` + "`security`" + ` and the login keychain.  The keyring isn't
+supported on Windows - use config encryption instead.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(2, 256, command, args)
+		err := config.KeyringRemote(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+		config.ShowRemote(args[0])
+		return nil
+	},
+}
+
 // This takes a list of arguments in key value key value form and
 // converts it into a map
 func argsToMap(args []string) (out rc.Params, err error) {
@@ -47,6 +47,9 @@ the files in remote:path.
 
 After it has run it will log the status of the encryptedremote:.
 
+Up to --checkers files are checked at once.  Reduce this if checking
+against a remote which needs downloading uses too much memory.
+
 If you supply the --one-way flag, it will only check that files in source
 match the files in destination, not the other way around. Meaning extra files in
 destination that are not in the source will not trigger an error.
@@ -67,12 +70,14 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 	if !ok {
 		return errors.Errorf("%s:%s is not a crypt remote", fdst.Name(), fdst.Root())
 	}
-	// Find a hash to use
+	// Find a hash to use - the wrapped hashes of the crypt are the
+	// hashes of the encrypted data stored in the underlying remote
 	funderlying := fcrypt.UnWrap()
-	hashType := funderlying.Hashes().GetOne()
-	if hashType == hash.None {
+	wrappedHashType := funderlying.Hashes().Wrapped().GetOne()
+	if wrappedHashType == hash.None {
 		return errors.Errorf("%s:%s does not support any hashes", funderlying.Name(), funderlying.Root())
 	}
+	hashType := wrappedHashType.Unwrap()
 	fs.Infof(nil, "Using %v for hash comparisons", hashType)
 
 	// checkIdentical checks to see if dst and src are identical
@@ -81,11 +86,10 @@ func cryptCheck(fdst, fsrc fs.Fs) error {
 	// it also returns whether it couldn't be hashed
 	checkIdentical := func(dst, src fs.Object) (differ bool, noHash bool) {
 		cryptDst := dst.(*crypt.Object)
-		underlyingDst := cryptDst.UnWrap()
-		underlyingHash, err := underlyingDst.Hash(hashType)
+		underlyingHash, err := fs.WrappedHash(cryptDst, wrappedHashType)
 		if err != nil {
 			fs.CountError(err)
-			fs.Errorf(dst, "Error reading hash from underlying %v: %v", underlyingDst, err)
+			fs.Errorf(dst, "Error reading hash from underlying %v: %v", cryptDst.UnWrap(), err)
 			return true, false
 		}
 		if underlyingHash == "" {
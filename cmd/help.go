@@ -12,6 +12,7 @@ import (
 	"github.com/ncw/rclone/fs/filter/filterflags"
 	"github.com/ncw/rclone/fs/rc/rcflags"
 	"github.com/ncw/rclone/lib/atexit"
+	"github.com/ncw/rclone/lib/encoder"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -127,6 +128,35 @@ var helpBackend = &cobra.Command{
 	},
 }
 
+// Show the encoding of a remote
+var helpEncoding = &cobra.Command{
+	Use:   "encoding [remote:]",
+	Short: "Show how file names are encoded on a remote",
+	Long: `
+Show the characters which are replaced in the names of the files and
+directories stored on remote:, and the characters which replace them,
+so you can see how your file names will be transformed before
+uploading them.
+
+The characters are replaced wherever they are in the name unless they
+are shown as leading or trailing.  Names which contain the
+replacements already have them quoted with "` + string(encoder.QuoteRune) + `" so they are
+restored correctly.
+
+Without a remote this shows the characters replaced by each of the
+encoding flags used by the backends.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		CheckArgs(0, 1, command, args)
+		if len(args) == 0 {
+			showEncodings()
+			return
+		}
+		f := NewFsSrc(args)
+		showEncoding(f)
+	},
+}
+
 // runRoot implements the main rclone command with no subcommands
 func runRoot(cmd *cobra.Command, args []string) {
 	if version {
@@ -186,6 +216,7 @@ func setupRootCommand(rootCmd *cobra.Command) {
 	helpCommand.AddCommand(helpFlags)
 	helpCommand.AddCommand(helpBackends)
 	helpCommand.AddCommand(helpBackend)
+	helpCommand.AddCommand(helpEncoding)
 
 	cobra.OnInitialize(initConfig)
 
@@ -231,6 +262,48 @@ func showBackends() {
 	fmt.Printf("  rclone help backend <name>\n")
 }
 
+// show the encoding of f
+func showEncoding(f fs.Fs) {
+	pairs := fs.Encoding(f).Mapping()
+	if len(pairs) == 0 {
+		fmt.Printf("%v: file names are stored unchanged\n", f)
+		return
+	}
+	fmt.Printf("%v: file names are stored with these characters replaced\n\n", f)
+	if err := encoder.WriteTable(os.Stdout, pairs); err != nil {
+		log.Fatalf("Failed to write encoding: %v", err)
+	}
+}
+
+// show the characters replaced by each encoder flag
+func showEncodings() {
+	for _, flag := range encoder.Flags {
+		fmt.Printf("### %s ###\n\n", flag.Name)
+		var pairs []encoder.Pair
+		base := encoder.MultiEncoder(0).Mapping()
+		for _, pair := range encoder.MultiEncoder(flag.Flag).Mapping() {
+			if flag.Flag == encoder.EncodeZero || !containsPair(base, pair) {
+				pairs = append(pairs, pair)
+			}
+		}
+		if err := encoder.WriteTable(os.Stdout, pairs); err != nil {
+			log.Fatalf("Failed to write encoding: %v", err)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("NUL is always replaced whichever flags are used.\n")
+}
+
+// containsPair returns true if pairs contains pair
+func containsPair(pairs []encoder.Pair, pair encoder.Pair) bool {
+	for _, p := range pairs {
+		if p == pair {
+			return true
+		}
+	}
+	return false
+}
+
 func quoteString(v interface{}) string {
 	switch v.(type) {
 	case string:
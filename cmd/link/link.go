@@ -4,12 +4,22 @@ import (
 	"fmt"
 
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/spf13/cobra"
 )
 
+var (
+	expire = fs.DurationOff
+	unlink = false
+)
+
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	cmdFlags := commandDefintion.Flags()
+	flags.FVarP(cmdFlags, &expire, "expire", "", "The amount of time that the link will be valid")
+	flags.BoolVarP(cmdFlags, &unlink, "unlink", "", unlink, "Remove existing public link to file/folder")
 }
 
 var commandDefintion = &cobra.Command{
@@ -20,17 +30,31 @@ rclone link will create or retrieve a public link to the given file or folder.
 
     rclone link remote:path/to/file
     rclone link remote:path/to/folder/
+    rclone link --unlink remote:path/to/folder/
+    rclone link --expire 1d remote:path/to/file
+
+Use the --expire flag to make a link which stops working after the
+time given, eg "--expire 1d".  Otherwise the link won't expire unless
+the backend has a maximum lifetime for links (eg S3 presigned URLs
+last at most a week).
+
+Use the --unlink flag to remove existing public links to the file or
+folder.
+
+Not all backends support --expire and --unlink - those that don't
+will return an error rather than make a link which doesn't do what
+was asked.
 
 If successful, the last line of the output will contain the link. Exact
-capabilities depend on the remote, but the link will always be created with
-the least constraints – e.g. no expiry, no password protection, accessible
-without account.
+capabilities depend on the remote, but the link will always be created
+with the least constraints – e.g. no expiry (unless --expire is used),
+no password protection, accessible without account.
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		fsrc, remote := cmd.NewFsFile(args[0])
 		cmd.Run(false, false, command, func() error {
-			link, err := operations.PublicLink(fsrc, remote)
+			link, err := operations.PublicLink(fsrc, remote, expire, unlink)
 			if err != nil {
 				return err
 			}
@@ -69,6 +69,8 @@ output:
     h - hash
     i - ID of object if known
     m - MimeType of object if known
+    u - path of the underlying object for overlay remotes like crypt
+    U - size of the underlying object for overlay remotes like crypt
 
 So if you wanted the path, size and modification time, you would use
 --format "pst", or maybe --format "tsp" to put the path last.
@@ -104,6 +106,15 @@ Eg
 
 (Though "rclone md5sum ." is an easier way of typing this.)
 
+The "u" and "U" parameters show the path and size of the object as it
+is stored on the wrapped remote for remotes like crypt and cache.
+They will be empty for directories and if the remote isn't wrapping
+another.  Eg to see the encrypted names and sizes of a crypt remote
+
+    $ rclone lsf --format "psuU" secret:
+    file.txt;6;v0qpsdq8anpci8n929v3uu9338;54
+
+
 By default the separator is ";" this can be changed with the
 --separator flag.  Note that separators aren't escaped in the path so
 putting it last is a good strategy.
@@ -176,6 +187,10 @@ func Lsf(fsrc fs.Fs, out io.Writer) error {
 			list.AddID()
 		case 'm':
 			list.AddMimeType()
+		case 'u':
+			list.AddUnderlyingPath()
+		case 'U':
+			list.AddUnderlyingSize()
 		default:
 			return errors.Errorf("Unknown format character %q", char)
 		}
@@ -23,6 +23,7 @@ func init() {
 	commandDefintion.Flags().BoolVarP(&opt.NoModTime, "no-modtime", "", false, "Don't read the modification time (can speed things up).")
 	commandDefintion.Flags().BoolVarP(&opt.ShowEncrypted, "encrypted", "M", false, "Show the encrypted names.")
 	commandDefintion.Flags().BoolVarP(&opt.ShowOrigIDs, "original", "", false, "Show the ID of the underlying Object.")
+	commandDefintion.Flags().BoolVarP(&opt.ShowUnderlying, "underlying", "", false, "Show the path and size of the underlying Object for overlay remotes like crypt.")
 }
 
 var commandDefintion = &cobra.Command{
@@ -55,6 +56,14 @@ If --no-modtime is specified then ModTime will be blank.
 
 If --encrypted is not specified the Encrypted won't be emitted.
 
+If --underlying is specified then for remotes which wrap another
+remote, like crypt and cache, UnderlyingPath and UnderlyingSize will
+be emitted with the path and size of the object as it is stored on
+the wrapped remote.  This is useful to reconcile the listing with the
+raw remote, eg its web interface or bill, as crypt names and sizes
+differ from the decrypted ones.  For crypt, UnderlyingPath is emitted
+for directories too if --encrypted is also specified.
+
 The Path field will only show folders below the remote path being listed.
 If "remote:path" contains the file "subfolder/file.txt", the Path for "file.txt"
 will be "subfolder/file.txt", not "remote:path/subfolder/file.txt".
@@ -0,0 +1,134 @@
+// +build linux darwin freebsd
+
+package mount
+
+import (
+	"sync"
+	"time"
+
+	"bazil.org/fuse"
+	fusefs "bazil.org/fuse/fs"
+	"github.com/ncw/rclone/cmd/mountlib"
+	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/vfs"
+	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.8
+)
+
+// ControlFile is the --control-file in the root of the mount.
+//
+// Commands written to it are run when the file is closed and the
+// output of the last command can be read back from it.
+type ControlFile struct {
+	vfs     *vfs.VFS
+	mu      sync.Mutex
+	result  []byte    // output of the last command
+	modTime time.Time // when the last command was run
+}
+
+// newControlFile makes the control file for the mount
+func newControlFile(VFS *vfs.VFS) *ControlFile {
+	return &ControlFile{
+		vfs:     VFS,
+		modTime: time.Now(),
+	}
+}
+
+// String returns a description of the control file for logging
+func (c *ControlFile) String() string {
+	return mountlib.ControlFile
+}
+
+// Check interface satisfied
+var _ fusefs.Node = (*ControlFile)(nil)
+
+// Attr fills out the attributes for the control file
+func (c *ControlFile) Attr(ctx context.Context, a *fuse.Attr) (err error) {
+	defer log.Trace(c, "")("a=%+v, err=%v", a, &err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	a.Gid = c.vfs.Opt.GID
+	a.Uid = c.vfs.Opt.UID
+	a.Mode = c.vfs.Opt.FilePerms
+	a.Size = uint64(len(c.result))
+	a.Atime = c.modTime
+	a.Mtime = c.modTime
+	a.Ctime = c.modTime
+	a.Crtime = c.modTime
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.NodeSetattrer = (*ControlFile)(nil)
+
+// Setattr ignores attribute changes, eg the truncate from "echo cmd > file"
+func (c *ControlFile) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) (err error) {
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.NodeOpener = (*ControlFile)(nil)
+
+// Open the control file for reading or writing commands
+func (c *ControlFile) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fh fusefs.Handle, err error) {
+	defer log.Trace(c, "flags=%v", req.Flags)("fh=%v, err=%v", &fh, &err)
+	// Bypass the page cache as the size changes with each command
+	resp.Flags |= fuse.OpenDirectIO
+	return &controlHandle{c: c}, nil
+}
+
+// controlHandle is an open handle on the ControlFile
+type controlHandle struct {
+	c       *ControlFile
+	mu      sync.Mutex
+	command []byte // the command written so far
+}
+
+// Check interface satisfied
+var _ fusefs.HandleReader = (*controlHandle)(nil)
+
+// Read the output of the last command
+func (h *controlHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) (err error) {
+	h.c.mu.Lock()
+	defer h.c.mu.Unlock()
+	if req.Offset >= int64(len(h.c.result)) {
+		return nil
+	}
+	end := req.Offset + int64(req.Size)
+	if end > int64(len(h.c.result)) {
+		end = int64(len(h.c.result))
+	}
+	resp.Data = append([]byte(nil), h.c.result[req.Offset:end]...)
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.HandleWriter = (*controlHandle)(nil)
+
+// Write part of a command
+func (h *controlHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) (err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.command = append(h.command, req.Data...)
+	resp.Size = len(req.Data)
+	return nil
+}
+
+// Check interface satisfied
+var _ fusefs.HandleFlusher = (*controlHandle)(nil)
+
+// Flush runs the command written to the handle, if any
+func (h *controlHandle) Flush(ctx context.Context, req *fuse.FlushRequest) (err error) {
+	h.mu.Lock()
+	command := string(h.command)
+	h.command = nil
+	h.mu.Unlock()
+	if command == "" {
+		return nil
+	}
+	result := mountlib.RunControlCommand(command)
+	h.c.mu.Lock()
+	h.c.result = result
+	h.c.modTime = time.Now()
+	h.c.mu.Unlock()
+	return nil
+}
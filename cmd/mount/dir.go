@@ -18,6 +18,7 @@ import (
 // Dir represents a directory entry
 type Dir struct {
 	*vfs.Dir
+	control *ControlFile // set on the root if --control-file is in use
 }
 
 // Check interface satsified
@@ -30,6 +31,8 @@ func (d *Dir) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	a.Gid = d.VFS().Opt.GID
 	a.Uid = d.VFS().Opt.UID
 	a.Mode = os.ModeDir | d.VFS().Opt.DirPerms
+	a.Inode = d.Inode()
+	a.Nlink = d.Nlink()
 	modTime := d.ModTime()
 	a.Atime = modTime
 	a.Mtime = modTime
@@ -70,7 +73,15 @@ var _ fusefs.NodeRequestLookuper = (*Dir)(nil)
 // Lookup need not to handle the names "." and "..".
 func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (node fusefs.Node, err error) {
 	defer log.Trace(d, "name=%q", req.Name)("node=%+v, err=%v", &node, &err)
-	mnode, err := d.Dir.Stat(req.Name)
+	if d.control != nil && req.Name == mountlib.ControlFile {
+		resp.EntryValid = 0
+		return d.control, nil
+	}
+	var mnode vfs.Node
+	err = mountlib.Retry(func() (err error) {
+		mnode, err = d.Dir.Stat(req.Name)
+		return err
+	})
 	if err != nil {
 		return nil, translateError(err)
 	}
@@ -79,7 +90,7 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 	case *vfs.File:
 		return &File{x}, nil
 	case *vfs.Dir:
-		return &Dir{x}, nil
+		return &Dir{Dir: x}, nil
 	}
 	panic("bad type")
 }
@@ -91,15 +102,19 @@ var _ fusefs.HandleReadDirAller = (*Dir)(nil)
 func (d *Dir) ReadDirAll(ctx context.Context) (dirents []fuse.Dirent, err error) {
 	itemsRead := -1
 	defer log.Trace(d, "")("item=%d, err=%v", &itemsRead, &err)
-	items, err := d.Dir.ReadDirAll()
+	var items vfs.Nodes
+	err = mountlib.Retry(func() (err error) {
+		items, err = d.Dir.ReadDirAll()
+		return err
+	})
 	if err != nil {
 		return nil, translateError(err)
 	}
 	for _, node := range items {
 		var dirent = fuse.Dirent{
-			// Inode FIXME ???
-			Type: fuse.DT_File,
-			Name: node.Name(),
+			Inode: node.Inode(),
+			Type:  fuse.DT_File,
+			Name:  node.Name(),
 		}
 		if node.IsDir() {
 			dirent.Type = fuse.DT_Dir
@@ -135,7 +150,7 @@ func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (node fusefs.No
 	if err != nil {
 		return nil, translateError(err)
 	}
-	return &Dir{dir}, nil
+	return &Dir{Dir: dir}, nil
 }
 
 var _ fusefs.NodeRemover = (*Dir)(nil)
@@ -29,9 +29,10 @@ func (f *File) Attr(ctx context.Context, a *fuse.Attr) (err error) {
 	modTime := f.File.ModTime()
 	Size := uint64(f.File.Size())
 	Blocks := (Size + 511) / 512
-	a.Gid = f.VFS().Opt.GID
-	a.Uid = f.VFS().Opt.UID
-	a.Mode = f.VFS().Opt.FilePerms
+	a.Uid, a.Gid = f.File.Owner()
+	a.Mode = f.File.Mode()
+	a.Inode = f.File.Inode()
+	a.Nlink = f.File.Nlink()
 	a.Size = Size
 	a.Atime = modTime
 	a.Mtime = modTime
@@ -69,7 +70,11 @@ func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 
 	// fuse flags are based off syscall flags as are os flags, so
 	// should be compatible
-	handle, err := f.File.Open(int(req.Flags))
+	var handle vfs.Handle
+	err = mountlib.RetryOpen(int(req.Flags), func() (err error) {
+		handle, err = f.File.Open(int(req.Flags))
+		return err
+	})
 	if err != nil {
 		return nil, translateError(err)
 	}
@@ -21,7 +21,8 @@ import (
 // FS represents the top level filing system
 type FS struct {
 	*vfs.VFS
-	f fs.Fs
+	f       fs.Fs
+	control *ControlFile // the --control-file or nil if not in use
 }
 
 // Check interface satistfied
@@ -33,6 +34,9 @@ func NewFS(f fs.Fs) *FS {
 		VFS: vfs.New(f, &vfsflags.Opt),
 		f:   f,
 	}
+	if mountlib.ControlFile != "" {
+		fsys.control = newControlFile(fsys.VFS)
+	}
 	return fsys
 }
 
@@ -43,7 +47,7 @@ func (f *FS) Root() (node fusefs.Node, err error) {
 	if err != nil {
 		return nil, translateError(err)
 	}
-	return &Dir{root}, nil
+	return &Dir{Dir: root, control: f.control}, nil
 }
 
 // Check interface satsified
@@ -84,6 +88,7 @@ func translateError(err error) error {
 	if err == nil {
 		return nil
 	}
+	err = mountlib.MapError(err)
 	switch errors.Cause(err) {
 	case vfs.OK:
 		return nil
@@ -107,6 +112,12 @@ func translateError(err error) error {
 		return fuse.ENOSYS
 	case vfs.EINVAL:
 		return fuse.Errno(syscall.EINVAL)
+	case vfs.EDQUOT:
+		return fuse.Errno(syscall.EDQUOT)
+	case vfs.EACCES:
+		return fuse.Errno(syscall.EACCES)
+	case vfs.EBUSY:
+		return fuse.Errno(syscall.EBUSY)
 	}
 	return err
 }
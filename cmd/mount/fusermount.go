@@ -0,0 +1,53 @@
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// useFuse3 is set if fusermount3 is being used for mounting
+var useFuse3 = false
+
+// setupFusermount makes bazil.org/fuse, which runs "fusermount",
+// work on systems which only have libfuse3 installed by putting a
+// "fusermount" which points to "fusermount3" at the start of the
+// PATH.
+//
+// It returns a function to tidy up afterwards.
+func setupFusermount() (cleanup func(), err error) {
+	cleanup = func() {}
+	if _, err := exec.LookPath("fusermount"); err == nil {
+		return cleanup, nil
+	}
+	fusermount3, err := exec.LookPath("fusermount3")
+	if err != nil {
+		// Let the mount report that fusermount is missing
+		return cleanup, nil
+	}
+	dir, err := ioutil.TempDir("", "rclone-fusermount")
+	if err != nil {
+		return cleanup, err
+	}
+	cleanup = func() {
+		_ = os.RemoveAll(dir)
+	}
+	err = os.Symlink(fusermount3, filepath.Join(dir, "fusermount"))
+	if err != nil {
+		cleanup()
+		return func() {}, err
+	}
+	err = os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if err != nil {
+		cleanup()
+		return func() {}, err
+	}
+	fs.Debugf(nil, "Using %q to mount", fusermount3)
+	useFuse3 = true
+	return cleanup, nil
+}
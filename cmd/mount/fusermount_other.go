@@ -0,0 +1,11 @@
+// +build darwin freebsd
+
+package mount
+
+// useFuse3 is set if fusermount3 is being used for mounting
+const useFuse3 = false
+
+// setupFusermount does nothing as fusermount isn't used on this OS
+func setupFusermount() (cleanup func(), err error) {
+	return func() {}, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"bazil.org/fuse"
 	fusefs "bazil.org/fuse/fs"
+	"github.com/ncw/rclone/cmd/mountlib"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
 	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.8
@@ -25,7 +26,10 @@ func (fh *FileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fus
 	var n int
 	defer log.Trace(fh, "len=%d, offset=%d", req.Size, req.Offset)("read=%d, err=%v", &n, &err)
 	data := make([]byte, req.Size)
-	n, err = fh.Handle.ReadAt(data, req.Offset)
+	err = mountlib.Retry(func() (err error) {
+		n, err = fh.Handle.ReadAt(data, req.Offset)
+		return err
+	})
 	if err == io.EOF {
 		err = nil
 	} else if err != nil {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"bazil.org/fuse"
@@ -46,7 +47,7 @@ func mountOptions(device string) (options []fuse.MountOption) {
 	if mountlib.NoAppleXattr {
 		options = append(options, fuse.NoAppleXattr())
 	}
-	if mountlib.AllowNonEmpty {
+	if mountlib.AllowNonEmpty && !useFuse3 {
 		options = append(options, fuse.AllowNonEmptyMount())
 	}
 	if mountlib.AllowOther {
@@ -67,15 +68,76 @@ func mountOptions(device string) (options []fuse.MountOption) {
 	if mountlib.DaemonTimeout != 0 {
 		options = append(options, fuse.DaemonTimeout(fmt.Sprint(int(mountlib.DaemonTimeout.Seconds()))))
 	}
-	if len(mountlib.ExtraOptions) > 0 {
-		fs.Errorf(nil, "-o/--option not supported with this FUSE backend")
+	if mountlib.AsyncRead {
+		options = append(options, fuse.AsyncRead())
 	}
-	if len(mountlib.ExtraOptions) > 0 {
+	if len(mountlib.ExtraFlags) > 0 {
 		fs.Errorf(nil, "--fuse-flag not supported with this FUSE backend")
 	}
 	return options
 }
 
+// parseExtraOptions converts the -o options into fuse.MountOptions
+//
+// bazil.org/fuse doesn't allow arbitrary options to be set so only
+// the ones it has a MountOption for are supported.
+func parseExtraOptions(extraOptions []string) (options []fuse.MountOption, err error) {
+	for _, option := range extraOptions {
+		for _, opt := range strings.Split(option, ",") {
+			name, value := opt, ""
+			if equals := strings.IndexRune(opt, '='); equals >= 0 {
+				name, value = opt[:equals], opt[equals+1:]
+			}
+			switch name {
+			case "allow_other":
+				options = append(options, fuse.AllowOther())
+			case "allow_root":
+				options = append(options, fuse.AllowRoot())
+			case "default_permissions":
+				options = append(options, fuse.DefaultPermissions())
+			case "ro":
+				options = append(options, fuse.ReadOnly())
+			case "rw", "sync_read":
+				// the defaults
+			case "nonempty":
+				if !useFuse3 {
+					options = append(options, fuse.AllowNonEmptyMount())
+				}
+			case "dev":
+				options = append(options, fuse.AllowDev())
+			case "suid":
+				options = append(options, fuse.AllowSUID())
+			case "async_read":
+				options = append(options, fuse.AsyncRead())
+			case "fsname":
+				options = append(options, fuse.FSName(value))
+			case "subtype":
+				options = append(options, fuse.Subtype(value))
+			case "volname":
+				options = append(options, fuse.VolumeName(value))
+			case "noappledouble":
+				options = append(options, fuse.NoAppleDouble())
+			case "noapplexattr":
+				options = append(options, fuse.NoAppleXattr())
+			case "local":
+				options = append(options, fuse.LocalVolume())
+			case "daemon_timeout":
+				options = append(options, fuse.DaemonTimeout(value))
+			case "max_readahead":
+				var size fs.SizeSuffix
+				err = size.Set(value)
+				if err != nil {
+					return nil, errors.Wrapf(err, "bad max_readahead %q", value)
+				}
+				options = append(options, fuse.MaxReadahead(uint32(size)))
+			default:
+				return nil, errors.Errorf("option %q not supported by rclone mount - try rclone cmount", opt)
+			}
+		}
+	}
+	return options, nil
+}
+
 // mount the file system
 //
 // The mount point will be ready when this returns.
@@ -84,7 +146,12 @@ func mountOptions(device string) (options []fuse.MountOption) {
 // report an error when fusermount is called.
 func mount(f fs.Fs, mountpoint string) (*vfs.VFS, <-chan error, func() error, error) {
 	fs.Debugf(f, "Mounting on %q", mountpoint)
-	c, err := fuse.Mount(mountpoint, mountOptions(f.Name()+":"+f.Root())...)
+	extraOptions, err := parseExtraOptions(mountlib.ExtraOptions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	options := append(mountOptions(f.Name()+":"+f.Root()), extraOptions...)
+	c, err := fuse.Mount(mountpoint, options...)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -128,6 +195,13 @@ func Mount(f fs.Fs, mountpoint string) error {
 		}
 	}
 
+	// Use fusermount3 if it is the only one installed
+	cleanup, err := setupFusermount()
+	if err != nil {
+		return errors.Wrap(err, "failed to set up fusermount")
+	}
+	defer cleanup()
+
 	// Mount it
 	FS, errChan, unmount, err := mount(f, mountpoint)
 	if err != nil {
@@ -6,8 +6,26 @@ import (
 	"testing"
 
 	"github.com/ncw/rclone/cmd/mountlib/mounttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMount(t *testing.T) {
 	mounttest.RunTests(t, mount)
 }
+
+func TestParseExtraOptions(t *testing.T) {
+	options, err := parseExtraOptions(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(options))
+
+	options, err = parseExtraOptions([]string{"allow_other,ro", "fsname=backup", "max_readahead=1M"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(options))
+
+	_, err = parseExtraOptions([]string{"max_readahead=potato"})
+	assert.Error(t, err)
+
+	_, err = parseExtraOptions([]string{"ro", "potato"})
+	assert.Error(t, err)
+}
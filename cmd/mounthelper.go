@@ -0,0 +1,118 @@
+// Support for running rclone as a mount helper, eg /sbin/mount.rclone
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// mountHelperName is the name rclone should be linked as to run as a
+// mount helper for "mount -t rclone"
+const mountHelperName = "mount.rclone"
+
+// isMountHelper returns true if rclone was run as a mount helper
+func isMountHelper() bool {
+	return filepath.Base(os.Args[0]) == mountHelperName
+}
+
+// mountHelperIgnore are mount options which are for mount(8),
+// /etc/fstab or systemd rather than rclone
+var mountHelperIgnore = map[string]bool{
+	"defaults": true,
+	"auto":     true,
+	"noauto":   true,
+	"user":     true,
+	"nouser":   true,
+	"users":    true,
+	"rw":       true,
+	"_netdev":  true,
+	"nofail":   true,
+	"exec":     true,
+	"noexec":   true,
+	"suid":     true,
+	"nosuid":   true,
+	"dev":      true,
+	"nodev":    true,
+	"async":    true,
+	"atime":    true,
+	"noatime":  true,
+	"relatime": true,
+}
+
+// convertMountHelperArgs converts the arguments rclone is given when
+// run as a mount helper, ie
+//
+//     mount.rclone remote:path /mountpoint [-sfnv] [-o options] [-t type]
+//
+// into the arguments for "rclone mount".
+//
+// Options which are the names of flags (with "_" for "-") are passed
+// as flags, eg "vfs_cache_mode=writes" becomes "--vfs-cache-mode=writes",
+// and other options are passed to FUSE with -o.  The mount is always
+// run in the background as mount(8) waits for the helper to exit.
+func convertMountHelperArgs(args []string) (newArgs []string, err error) {
+	mountCmd, _, err := Root.Find([]string{"mount"})
+	if err != nil || mountCmd == Root {
+		return nil, errors.New("rclone was built without mount support")
+	}
+	isFlag := func(name string) bool {
+		return mountCmd.Flags().Lookup(name) != nil || Root.PersistentFlags().Lookup(name) != nil
+	}
+	var positional, options []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-o":
+			if i+1 >= len(args) {
+				return nil, errors.New("missing argument to -o")
+			}
+			i++
+			options = append(options, strings.Split(args[i], ",")...)
+		case strings.HasPrefix(arg, "-o"):
+			options = append(options, strings.Split(arg[2:], ",")...)
+		case arg == "-t" || arg == "-N":
+			// ignore the type and namespace
+			i++
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, c := range arg[1:] {
+				switch c {
+				case 'v':
+					newArgs = append(newArgs, "-v")
+				case 's', 'n':
+					// sloppy and no mtab are ignored
+				case 'f':
+					return nil, errors.New("fake mount (-f) not supported")
+				default:
+					return nil, errors.Errorf("unknown mount helper flag -%c", c)
+				}
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) != 2 {
+		return nil, errors.Errorf("need remote:path and mountpoint but got %d arguments", len(positional))
+	}
+	newArgs = append([]string{"mount", positional[0], positional[1], "--daemon"}, newArgs...)
+	for _, option := range options {
+		name := option
+		if i := strings.IndexRune(option, '='); i >= 0 {
+			name = option[:i]
+		}
+		switch {
+		case option == "" || mountHelperIgnore[name] || strings.HasPrefix(name, "x-") || name == "comment":
+			continue
+		case option == "ro":
+			newArgs = append(newArgs, "--read-only")
+		case isFlag(strings.Replace(name, "_", "-", -1)):
+			newArgs = append(newArgs, "--"+strings.Replace(name, "_", "-", -1)+option[len(name):])
+		default:
+			newArgs = append(newArgs, "-o", option)
+		}
+	}
+	return newArgs, nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMountHelperArgs(t *testing.T) {
+	// no mount command
+	_, err := convertMountHelperArgs([]string{"remote:path", "/mnt"})
+	assert.EqualError(t, err, "rclone was built without mount support")
+
+	// add a mount command with some flags to convert options to
+	mountCmd := &cobra.Command{Use: "mount"}
+	mountCmd.Flags().String("vfs-cache-mode", "", "")
+	mountCmd.Flags().Bool("allow-other", false, "")
+	mountCmd.Flags().Bool("read-only", false, "")
+	Root.AddCommand(mountCmd)
+	defer Root.RemoveCommand(mountCmd)
+
+	for _, test := range []struct {
+		in      []string
+		want    []string
+		wantErr string
+	}{
+		{
+			in:   []string{"remote:path", "/mnt"},
+			want: []string{"mount", "remote:path", "/mnt", "--daemon"},
+		},
+		{
+			in:   []string{"remote:path", "/mnt", "-t", "rclone", "-sn"},
+			want: []string{"mount", "remote:path", "/mnt", "--daemon"},
+		},
+		{
+			in:   []string{"-v", "remote:path", "/mnt"},
+			want: []string{"mount", "remote:path", "/mnt", "--daemon", "-v"},
+		},
+		{
+			in:   []string{"remote:path", "/mnt", "-o", "rw,noauto,_netdev,x-systemd.automount,comment=foo"},
+			want: []string{"mount", "remote:path", "/mnt", "--daemon"},
+		},
+		{
+			in:   []string{"remote:path", "/mnt", "-o", "ro,vfs_cache_mode=writes,allow_other"},
+			want: []string{"mount", "remote:path", "/mnt", "--daemon", "--read-only", "--vfs-cache-mode=writes", "--allow-other"},
+		},
+		{
+			in:   []string{"remote:path", "/mnt", "-ouid=1000,gid=1000", "-o", "allow-other"},
+			want: []string{"mount", "remote:path", "/mnt", "--daemon", "-o", "uid=1000", "-o", "gid=1000", "--allow-other"},
+		},
+		{
+			in:      []string{"remote:path", "/mnt", "-o"},
+			wantErr: "missing argument to -o",
+		},
+		{
+			in:      []string{"remote:path", "/mnt", "-f"},
+			wantErr: "fake mount (-f) not supported",
+		},
+		{
+			in:      []string{"remote:path", "/mnt", "-x"},
+			wantErr: "unknown mount helper flag -x",
+		},
+		{
+			in:      []string{"remote:path"},
+			wantErr: "need remote:path and mountpoint but got 1 arguments",
+		},
+	} {
+		got, err := convertMountHelperArgs(test.in)
+		if test.wantErr != "" {
+			assert.EqualError(t, err, test.wantErr, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
@@ -0,0 +1,114 @@
+package mountlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+// ControlFile is the name of the control file in the root of the
+// mount, or "" if it is disabled
+var ControlFile = ""
+
+// controlAllowed are the rc calls which may be run from the control
+// file.  Entries ending in "/" allow every call starting with them.
+//
+// Anyone who can write to the mount can write to the control file so
+// only calls which act on the mount itself or report on its progress
+// are allowed.
+var controlAllowed = []string{
+	"vfs/",
+	"core/stats",
+	"core/transferred",
+	"rc/noop",
+}
+
+// controlIsAllowed returns true if the rc call at path may be run
+// from the control file
+func controlIsAllowed(path string) bool {
+	for _, allowed := range controlAllowed {
+		if path == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(path, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseControlCommand parses a command written to the control file.
+//
+// This is an rc command name followed by either key=value parameters
+// or a JSON object of parameters, eg
+//
+//     vfs/refresh dir=home/junk recursive=true
+//     vfs/forget {"file": "hello"}
+func parseControlCommand(command string) (path string, in rc.Params, err error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return "", nil, errors.New("empty command")
+	}
+	fields := strings.SplitN(command, " ", 2)
+	path = strings.Trim(fields[0], "/")
+	in = rc.Params{}
+	if len(fields) < 2 {
+		return path, in, nil
+	}
+	rest := strings.TrimSpace(fields[1])
+	if strings.HasPrefix(rest, "{") {
+		err = json.Unmarshal([]byte(rest), &in)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "bad JSON parameters")
+		}
+		return path, in, nil
+	}
+	for _, param := range strings.Fields(rest) {
+		equals := strings.IndexRune(param, '=')
+		if equals < 0 {
+			return "", nil, errors.Errorf("bad parameter %q: must be key=value", param)
+		}
+		in[param[:equals]] = param[equals+1:]
+	}
+	return path, in, nil
+}
+
+// RunControlCommand runs a command written to the control file
+// returning the JSON output of the rc call or a JSON error.
+//
+// Only the rc calls in controlAllowed may be run so that users of
+// the mount can't reach beyond it.
+func RunControlCommand(command string) []byte {
+	out, err := runControlCommand(command)
+	if err != nil {
+		fs.Errorf(nil, "control file: %q failed: %v", strings.TrimSpace(command), err)
+		out = rc.Params{"error": err.Error()}
+	}
+	if out == nil {
+		out = rc.Params{}
+	}
+	var buf bytes.Buffer
+	err = rc.WriteJSON(&buf, out)
+	if err != nil {
+		return []byte(`{"error": "failed to encode output"}` + "\n")
+	}
+	return buf.Bytes()
+}
+
+// runControlCommand does the work for RunControlCommand
+func runControlCommand(command string) (out rc.Params, err error) {
+	path, in, err := parseControlCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if !controlIsAllowed(path) {
+		return nil, errors.Errorf("method %q can't be run from the control file", path)
+	}
+	call := rc.Calls.Get(path)
+	if call == nil {
+		return nil, errors.Errorf("couldn't find method %q", path)
+	}
+	fs.Debugf(nil, "control file: running %q with %v", path, in)
+	return call.Fn(in)
+}
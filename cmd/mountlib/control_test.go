@@ -0,0 +1,73 @@
+package mountlib
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseControlCommand(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		path    string
+		params  rc.Params
+		wantErr bool
+	}{
+		{"", "", nil, true},
+		{"core/stats\n", "core/stats", rc.Params{}, false},
+		{"/vfs/refresh/ dir=a/b recursive=true\n", "vfs/refresh", rc.Params{"dir": "a/b", "recursive": "true"}, false},
+		{`vfs/forget {"file": "hello"}`, "vfs/forget", rc.Params{"file": "hello"}, false},
+		{`vfs/forget {"file": `, "", nil, true},
+		{"vfs/forget potato", "", nil, true},
+	} {
+		path, params, err := parseControlCommand(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.path, path, test.in)
+		assert.Equal(t, test.params, params, test.in)
+	}
+}
+
+func TestRunControlCommand(t *testing.T) {
+	var out rc.Params
+
+	require.NoError(t, json.Unmarshal(RunControlCommand("rc/noop a=1"), &out))
+	assert.Equal(t, rc.Params{"a": "1"}, out)
+
+	out = nil
+	require.NoError(t, json.Unmarshal(RunControlCommand("rc/noopauth a=1"), &out))
+	assert.Contains(t, out["error"], "can't be run from the control file")
+
+	out = nil
+	require.NoError(t, json.Unmarshal(RunControlCommand("core/bwlimit rate=off"), &out))
+	assert.Contains(t, out["error"], "can't be run from the control file")
+
+	out = nil
+	require.NoError(t, json.Unmarshal(RunControlCommand("vfs/potato"), &out))
+	assert.Contains(t, out["error"], "couldn't find method")
+}
+
+func TestControlIsAllowed(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want bool
+	}{
+		{"vfs/refresh", true},
+		{"vfs/forget", true},
+		{"core/stats", true},
+		{"core/stats-delete", false},
+		{"core/bwlimit", false},
+		{"rc/noop", true},
+		{"rc/noopauth", false},
+		{"config/dump", false},
+		{"vfs", false},
+	} {
+		assert.Equal(t, test.want, controlIsAllowed(test.path), test.path)
+	}
+}
@@ -0,0 +1,187 @@
+package mountlib
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// errorClass is a class of backend error translated by --errno-mapping
+type errorClass int
+
+// Classes of backend errors
+const (
+	classOther       errorClass = iota // not in any class so translated to EIO
+	classNotFound                      // the file or directory doesn't exist
+	classPermission                    // access is forbidden
+	classQuota                         // the storage is full
+	classRateLimited                   // too many requests - retried before giving up
+)
+
+// errnos are the vfs errors the classes are translated to
+var errnos = map[errorClass]error{
+	classNotFound:    vfs.ENOENT,
+	classPermission:  vfs.EACCES,
+	classQuota:       vfs.EDQUOT,
+	classRateLimited: vfs.EBUSY,
+}
+
+// statusCodes are the classes of the HTTP status codes of backend
+// errors
+var statusCodes = map[int]errorClass{
+	http.StatusUnauthorized:        classPermission,
+	http.StatusForbidden:           classPermission,
+	http.StatusNotFound:            classNotFound,
+	http.StatusTooManyRequests:     classRateLimited,
+	http.StatusServiceUnavailable:  classRateLimited,
+	http.StatusInsufficientStorage: classQuota,
+}
+
+// statusCoder is implemented by backend errors which know the HTTP
+// status code of the response, eg those from the AWS SDK
+type statusCoder interface {
+	StatusCode() int
+}
+
+// Phrases found in rate limited and quota exceeded errors, in lower
+// case
+var (
+	rateLimitedPhrases = []string{"rate limit", "ratelimit", "too many requests", "throttl", "slow down", "slowdown"}
+	quotaPhrases       = []string{"quota", "insufficient storage", "insufficientstorage", "no space left", "storage limit", "storage full"}
+)
+
+// Phrases found in the backend errors of each class, in lower case,
+// for errors without a type or status code rclone knows.  The rate
+// limited phrases are checked first as some providers describe rate
+// limits as exceeding a quota of requests, eg with a 403 status.
+var classErrorStrings = []struct {
+	class   errorClass
+	phrases []string
+}{
+	{classRateLimited, rateLimitedPhrases},
+	{classQuota, quotaPhrases},
+	{classPermission, []string{"permission denied", "access denied", "accessdenied", "forbidden", "insufficient permissions", "insufficientpermissions"}},
+	{classNotFound, []string{"not found", "notfound", "no such file"}},
+}
+
+// errnoRetries is the number of times read only operations are
+// retried after a transient error and errnoRetrySleep is the time to
+// sleep before the first retry, which is doubled for each retry.
+var (
+	errnoRetries    = 3
+	errnoRetrySleep = 200 * time.Millisecond
+)
+
+// classify returns the class of the backend error err
+func classify(err error) errorClass {
+	if err == nil {
+		return classOther
+	}
+	cause := errors.Cause(err)
+	switch {
+	case cause == fs.ErrorObjectNotFound || cause == fs.ErrorDirNotFound || os.IsNotExist(cause):
+		return classNotFound
+	case cause == fs.ErrorPermissionDenied || os.IsPermission(cause):
+		return classPermission
+	}
+	errString := strings.ToLower(err.Error())
+	// Rate limits reported as a 403 need the message to tell them
+	// from permission errors so look at that first
+	if hasPhrase(errString, rateLimitedPhrases) {
+		return classRateLimited
+	}
+	statusCode := 0
+	switch x := cause.(type) {
+	case statusCoder:
+		statusCode = x.StatusCode()
+	case *googleapi.Error:
+		statusCode = x.Code
+	}
+	if class, ok := statusCodes[statusCode]; ok {
+		if class == classPermission && hasPhrase(errString, quotaPhrases) {
+			return classQuota
+		}
+		return class
+	}
+	for _, c := range classErrorStrings {
+		for _, phrase := range c.phrases {
+			if strings.Contains(errString, phrase) {
+				return c.class
+			}
+		}
+	}
+	return classOther
+}
+
+// hasPhrase returns true if errString contains any of phrases
+func hasPhrase(errString string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(errString, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// MapError translates err returned by a backend into the vfs error
+// for its class if --errno-mapping is set, so it can be returned to
+// the application as a useful errno instead of EIO.
+//
+// The vfs errors and the errors which aren't in a class are returned
+// unchanged.
+func MapError(err error) error {
+	if !ErrnoMapping || err == nil {
+		return err
+	}
+	switch cause := errors.Cause(err); cause {
+	case vfs.ENOENT, vfs.EEXIST, vfs.EPERM, vfs.EINVAL, vfs.ECLOSED:
+		return err
+	default:
+		if _, ok := cause.(vfs.Error); ok {
+			return err
+		}
+	}
+	errno, ok := errnos[classify(err)]
+	if !ok {
+		return err
+	}
+	fs.Debugf(nil, "Translating error to %q: %v", errno, err)
+	return errno
+}
+
+// Retry calls fn, and if --errno-mapping is set calls it again while
+// it returns a transient error, such as the backend rate limiting the
+// requests, a few times before giving up.
+//
+// Only use this for operations which don't change anything, eg
+// lookups, directory listings and reads, as an operation which
+// failed may have been done anyway.
+func Retry(fn func() error) (err error) {
+	err = fn()
+	if !ErrnoMapping {
+		return err
+	}
+	sleep := errnoRetrySleep
+	for try := 1; try <= errnoRetries && classify(err) == classRateLimited; try++ {
+		fs.Debugf(nil, "Retrying after transient error (%d/%d): %v", try, errnoRetries, err)
+		time.Sleep(sleep)
+		sleep *= 2
+		err = fn()
+	}
+	return err
+}
+
+// RetryOpen calls fn to open a file with flags using Retry if the
+// file is opened read only, or just once otherwise.
+func RetryOpen(flags int, fn func() error) error {
+	if flags&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return fn()
+	}
+	return Retry(fn)
+}
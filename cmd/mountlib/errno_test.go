@@ -0,0 +1,113 @@
+package mountlib
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+func TestMapError(t *testing.T) {
+	oldErrnoMapping := ErrnoMapping
+	defer func() {
+		ErrnoMapping = oldErrnoMapping
+	}()
+	other := errors.New("potato")
+	wrapped := errors.Wrap(vfs.EEXIST, "wrapped")
+	for _, test := range []struct {
+		in   error
+		want error
+	}{
+		{nil, nil},
+		{other, other},
+		{vfs.EPERM, vfs.EPERM},
+		{vfs.EROFS, vfs.EROFS},
+		{wrapped, wrapped},
+		{fs.ErrorObjectNotFound, vfs.ENOENT},
+		{errors.Wrap(fs.ErrorDirNotFound, "wrapped"), vfs.ENOENT},
+		{&os.PathError{Op: "open", Path: "/potato", Err: os.ErrNotExist}, vfs.ENOENT},
+		{fs.ErrorPermissionDenied, vfs.EACCES},
+		{errors.New("googleapi: Error 403: Forbidden"), vfs.EACCES},
+		{errors.New("googleapi: Error 403: The user's Drive storage quota has been exceeded., storageQuotaExceeded"), vfs.EDQUOT},
+		{errors.New("write /mnt/potato: no space left on device"), vfs.EDQUOT},
+		{errors.New("googleapi: Error 403: User Rate Limit Exceeded., userRateLimitExceeded"), vfs.EBUSY},
+		{errors.New("HTTP error 429 (429 Too Many Requests)"), vfs.EBUSY},
+		{errors.New("SlowDown: Please reduce your request rate"), vfs.EBUSY},
+		{&googleapi.Error{Code: 404, Message: "File not found"}, vfs.ENOENT},
+		{&googleapi.Error{Code: 403, Message: "The user does not have sufficient permissions for this file"}, vfs.EACCES},
+		{&googleapi.Error{Code: 403, Message: "The user's Drive storage quota has been exceeded."}, vfs.EDQUOT},
+		{&googleapi.Error{Code: 403, Message: "User Rate Limit Exceeded"}, vfs.EBUSY},
+		{errors.Wrap(statusError(429), "wrapped"), vfs.EBUSY},
+		{statusError(507), vfs.EDQUOT},
+		{statusError(401), vfs.EACCES},
+		{statusError(500), statusError(500)},
+	} {
+		ErrnoMapping = false
+		assert.Equal(t, test.in, MapError(test.in), test.in)
+		ErrnoMapping = true
+		assert.Equal(t, test.want, MapError(test.in), test.in)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	oldErrnoMapping, oldRetries, oldSleep := ErrnoMapping, errnoRetries, errnoRetrySleep
+	defer func() {
+		ErrnoMapping, errnoRetries, errnoRetrySleep = oldErrnoMapping, oldRetries, oldSleep
+	}()
+	errnoRetries = 2
+	errnoRetrySleep = time.Millisecond
+	rateLimited := errors.New("rate limit exceeded")
+	other := errors.New("potato")
+
+	// call returns a function which returns the errors passed in
+	// in turn, counting the calls
+	calls := 0
+	call := func(errs ...error) func() error {
+		calls = 0
+		return func() error {
+			err := errs[calls]
+			calls++
+			return err
+		}
+	}
+
+	ErrnoMapping = false
+	assert.Equal(t, rateLimited, Retry(call(rateLimited)))
+	assert.Equal(t, 1, calls)
+
+	ErrnoMapping = true
+	assert.NoError(t, Retry(call(nil)))
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, other, Retry(call(other)))
+	assert.Equal(t, 1, calls)
+
+	assert.NoError(t, Retry(call(rateLimited, nil)))
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, other, Retry(call(rateLimited, other)))
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, rateLimited, Retry(call(rateLimited, rateLimited, rateLimited)))
+	assert.Equal(t, 3, calls)
+
+	// Only files opened read only are retried
+	assert.NoError(t, RetryOpen(os.O_RDONLY, call(rateLimited, nil)))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, rateLimited, RetryOpen(os.O_WRONLY|os.O_CREATE, call(rateLimited, nil)))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, rateLimited, RetryOpen(os.O_RDWR, call(rateLimited, nil)))
+	assert.Equal(t, 1, calls)
+}
+
+// statusError is an error with an HTTP status code
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("HTTP error %d", int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
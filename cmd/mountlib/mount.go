@@ -27,6 +27,7 @@ var (
 	AllowOther                       = false
 	DefaultPermissions               = false
 	WritebackCache                   = false
+	AsyncRead                        = false
 	Daemon                           = false
 	MaxReadAhead       fs.SizeSuffix = 128 * 1024
 	ExtraOptions       []string
@@ -36,6 +37,7 @@ var (
 	NoAppleDouble      = true        // use noappledouble by default
 	NoAppleXattr       = false       // do not use noapplexattr by default
 	DaemonTimeout      time.Duration // OSXFUSE only
+	ErrnoMapping       = false       // translate backend errors into errnos
 )
 
 // Check is folder is empty
@@ -204,6 +206,67 @@ then there is no chance of corruption.
 
 This is the same as setting the attr_timeout option in mount.fuse.
 
+### FUSE options
+
+Options can be passed to FUSE with -o, eg "-o allow_other" or
+"-o fsname=backup", as they would be with mount.fuse.  Use
+--fuse-flag to pass flags or arguments directly to libfuse/WinFsp.
+
+` + "`rclone cmount`" + ` passes -o options straight to libfuse/WinFsp.  ` + "`rclone mount`" + `
+doesn't use libfuse so it only understands these options:
+allow_other, allow_root, default_permissions, ro, rw, nonempty, dev,
+suid, async_read, sync_read, fsname=NAME, subtype=NAME, volname=NAME,
+max_readahead=BYTES and daemon_timeout=SECONDS, along with
+noappledouble, noapplexattr and local on OS X.  Other options give an
+error rather than being silently ignored.
+
+On Linux ` + "`rclone mount`" + ` uses the fusermount program to mount.  If
+only fusermount3 from libfuse3 is installed then rclone will use that
+instead.  libfuse3 allows mounting over non-empty directories so the
+nonempty option isn't passed to it.
+
+### Asynchronous reads
+
+By default ` + "`rclone mount`" + ` asks the kernel to send it read requests
+for a file one at a time, in order, which the VFS layer reads most
+efficiently.  Use --async-read to let the kernel send reads in
+parallel, which may be needed by some kernels or when the mount is
+re-exported over NFS, at the cost of extra seeks on the remote when
+reads arrive out of order.
+
+` + "`rclone cmount`" + ` uses the default of libfuse which is to send reads in
+parallel.  Use ` + "`-o sync_read`" + ` to send them one at a time instead.
+
+### File permissions
+
+By default all files have the permissions set by --file-perms and all
+directories those set by --dir-perms, both masked by --umask, and are
+owned by --uid and --gid.
+
+If --vfs-metadata-perms is set then files on backends which store
+POSIX permissions, such as local and sftp, are shown with their own
+permissions masked by --umask, and their own owner.  Files on other
+backends, directories and files which are still being written use the
+defaults above.
+
+### Inode numbers and link counts
+
+By default inode numbers are handed out as files and directories are
+seen so they change each time rclone ` + commandName + ` is run.  This can confuse
+programs such as backup tools which use them to spot files which
+haven't changed.
+
+If --vfs-persistent-inodes is set then the inode number of each path
+is stored in the cache directory (see --cache-dir) so it is the same
+across remounts and is kept when the file or directory is renamed.
+With cmount this also passes the use_ino option to FUSE.
+
+Files report a link count of 1, or the number of hard links for
+backends which know it, such as local.  Directories report 2 plus the
+number of subdirectories once they have been listed, and 1 before
+then, which tells programs such as find not to assume the count is
+exact.
+
 ### Filters
 
 Note that all the rclone filters can be used to select a subset of the
@@ -235,6 +298,79 @@ When --vfs-read-chunk-size-limit 500M is specified, the result would be
 
 Chunked reading will only work with --vfs-cache-mode < full, as the file will always
 be copied to the vfs cache before opening with --vfs-cache-mode full.
+
+### Control file ###
+
+If --control-file is set, eg to ` + "`.rclone`" + `, then a hidden file of
+that name appears in the root of the mount which can be used to
+control rclone from inside the mount.  This is useful when the remote
+control port isn't reachable, eg from within a container.
+
+Write an rc command followed by its parameters as key=value pairs or
+a JSON object to the file, then read the file to get the result as
+JSON, eg
+
+    echo "vfs/refresh dir=home/junk recursive=true" > /mnt/.rclone
+    cat /mnt/.rclone
+
+    echo "core/stats" > /mnt/.rclone
+    cat /mnt/.rclone
+
+Anyone who can write to files in the mount can write to the control
+file - that is the user running rclone, and other users too if
+--allow-other is set and --file-perms lets them.  For that reason only
+the ` + "`vfs/*`" + ` commands, ` + "`core/stats`" + `, ` + "`core/transferred`" + ` and ` + "`rc/noop`" + `
+may be run this way, whatever the --rc authorisation settings.  The
+result of the last command is shared between all users of the mount.
+
+Control files are only supported by ` + "`rclone mount`" + ` at the moment.
+
+### Error translation ###
+
+By default any error from the remote which rclone doesn't know how to
+translate is returned to the application as an I/O error (EIO).  If
+--errno-mapping is set then the errors are classified and translated
+into errors applications can act on
+
+  * quota exceeded or storage full - EDQUOT (ENOSPC on Windows)
+  * permission denied or forbidden - EACCES
+  * rate limited - EBUSY
+  * not found - ENOENT
+
+The errors are classified by the HTTP status codes returned by the
+remote where rclone can read them, and otherwise by looking at the
+error messages, so some errors may not be recognised.
+
+When the remote is rate limiting the requests, operations which don't
+change anything, such as looking up files, listing directories,
+opening files for reading and reading them, are retried a few times,
+waiting a little longer before each try, before EBUSY is returned.
+Other operations return EBUSY straight away as they may have been
+done anyway.
+
+### Mounting on demand with systemd and /etc/fstab
+
+If rclone is linked or copied to ` + "`/sbin/mount.rclone`" + ` then it can be
+used as a mount helper by ` + "`mount -t rclone`" + ` and in ` + "`/etc/fstab`" + `.
+Options given with ` + "`-o`" + ` which are the names of rclone flags (with
+` + "`_`" + ` instead of ` + "`-`" + `) are passed to rclone as flags, the fstab and
+systemd options are ignored and anything else is passed to FUSE.  The
+mount always runs in the background as if ` + "`--daemon`" + ` was given.
+
+    sudo ln -s /usr/bin/rclone /sbin/mount.rclone
+
+Combined with systemd's automount support this means the mount isn't
+started, and no connections or tokens are held for the remote, until
+something first accesses the mountpoint.  With an idle timeout the
+mount is stopped again when it hasn't been used for a while, so many
+remotes can be configured without them all being active at boot.
+For example in ` + "`/etc/fstab`" + `
+
+    remote:path /mnt/remote rclone rw,noauto,nofail,_netdev,x-systemd.automount,x-systemd.idle-timeout=10min,allow_other,vfs_cache_mode=writes,config=/etc/rclone.conf,cache_dir=/var/cache/rclone 0 0
+
+Then run ` + "`systemctl daemon-reload`" + ` and ` + "`systemctl start mnt-remote.automount`" + `.
+Note that systemd runs the mount as root so give the path to the
+config file with ` + "`config`" + `.
 ` + vfs.Help,
 		Run: func(command *cobra.Command, args []string) {
 			cmd.CheckArgs(2, 2, command, args)
@@ -304,11 +440,14 @@ be copied to the vfs cache before opening with --vfs-cache-mode full.
 	flags.BoolVarP(flagSet, &WritebackCache, "write-back-cache", "", WritebackCache, "Makes kernel buffer writes before sending them to rclone. Without this, writethrough caching is used.")
 	flags.FVarP(flagSet, &MaxReadAhead, "max-read-ahead", "", "The number of bytes that can be prefetched for sequential reads.")
 	flags.DurationVarP(flagSet, &AttrTimeout, "attr-timeout", "", AttrTimeout, "Time for which file/directory attributes are cached.")
+	flags.BoolVarP(flagSet, &AsyncRead, "async-read", "", AsyncRead, "Allow the kernel to send read requests in parallel.")
 	flags.StringArrayVarP(flagSet, &ExtraOptions, "option", "o", []string{}, "Option for libfuse/WinFsp. Repeat if required.")
 	flags.StringArrayVarP(flagSet, &ExtraFlags, "fuse-flag", "", []string{}, "Flags or arguments to be passed direct to libfuse/WinFsp. Repeat if required.")
 	flags.BoolVarP(flagSet, &Daemon, "daemon", "", Daemon, "Run mount as a daemon (background mode).")
 	flags.StringVarP(flagSet, &VolumeName, "volname", "", VolumeName, "Set the volume name (not supported by all OSes).")
+	flags.StringVarP(flagSet, &ControlFile, "control-file", "", ControlFile, "Name of a file in the root of the mount to control rclone with, eg .rclone.")
 	flags.DurationVarP(flagSet, &DaemonTimeout, "daemon-timeout", "", DaemonTimeout, "Time limit for rclone to respond to kernel (not supported by all OSes).")
+	flags.BoolVarP(flagSet, &ErrnoMapping, "errno-mapping", "", ErrnoMapping, "Translate remote errors such as quota exceeded into errnos instead of EIO.")
 
 	if runtime.GOOS == "darwin" {
 		flags.BoolVarP(flagSet, &NoAppleDouble, "noappledouble", "", NoAppleDouble, "Sets the OSXFUSE option noappledouble.")
@@ -15,6 +15,12 @@ func init() {
 var commandDefintion = &cobra.Command{
 	Use:   "obscure password",
 	Short: `Obscure password for use in the rclone.conf`,
+	Long: `Obscure a password so it can be put in the config file.
+
+Obscuring is not encryption - it only stops passwords being read at a
+glance and can be undone with ` + "`rclone reveal`" + `.  To protect the
+passwords use config encryption or ` + "`rclone config keyring`" + `.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		cmd.Run(false, false, command, func() error {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/fs/rc/rcflags"
+	"github.com/ncw/rclone/fs/rc/rcschedule"
 	"github.com/ncw/rclone/fs/rc/rcserver"
 	"github.com/spf13/cobra"
 )
@@ -25,6 +26,10 @@ If you pass in a path to a directory, rclone will serve that directory
 for GET requests on the URL passed in.  It will also open the URL in
 the browser when rclone is run.
 
+If --rc-schedule-file is set then rclone will run the rc commands in
+it on a cron like schedule, for example to do a nightly sync.  See the
+[rc documentation](/rc/#scheduling-commands) for the format of the file.
+
 See the [rc documentation](/rc/) for more info on the rc flags.
 `,
 	Run: func(command *cobra.Command, args []string) {
@@ -44,6 +49,12 @@ See the [rc documentation](/rc/) for more info on the rc flags.
 		if s == nil {
 			log.Fatal("rc server not configured")
 		}
+		if rcflags.Opt.ScheduleFile != "" {
+			err = rcschedule.Load(rcflags.Opt.ScheduleFile)
+			if err != nil {
+				log.Fatalf("Failed to load schedules: %v", err)
+			}
+		}
 		s.Wait()
 	},
 }
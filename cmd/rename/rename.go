@@ -0,0 +1,80 @@
+package rename
+
+import (
+	"log"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	regex    = ""
+	fullPath = false
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().StringVarP(&regex, "regex", "", regex, "Substitution to make the new names, eg s/old/new/")
+	commandDefintion.Flags().BoolVarP(&fullPath, "full-path", "", fullPath, "Apply --regex to the path of each file rather than its name.")
+}
+
+var commandDefintion = &cobra.Command{
+	Use:   "rename remote:path --regex s/regexp/replacement/",
+	Short: `Rename the files in remote:path with a regular expression.`,
+	Long: `
+Renames each file in remote:path whose name matches a regular
+expression, moving it server side if the remote supports it so the
+files don't need to be downloaded and uploaded again.
+
+The --regex flag is a substitution in the style of sed
+
+    s/regexp/replacement/flags
+
+The regexp is in [Go syntax](https://golang.org/pkg/regexp/syntax/) and
+the replacement may refer to the groups matched as ` + "`$1`" + ` or ` + "`\\1`" + `.
+Any character may be used instead of ` + "`/`" + `, which is useful if it
+appears in the regexp.  The flags may be
+
+  * ` + "`g`" + ` - replace every match, not just the first
+  * ` + "`i`" + ` - match case insensitively
+
+By default the substitution is applied to the name of each file
+leaving the directory it is in alone.  Use --full-path to apply it to
+the path of each file relative to remote:path instead, which can move
+files between directories.
+
+Use the filters to select which files are renamed and --max-depth to
+stop it recursing.  Directories aren't renamed.
+
+A file isn't renamed if a file with the new name exists already or if
+more than one file would be given the same name.  These are reported
+as errors.
+
+**Important**: Since this can rename a lot of files, test first with
+the --dry-run flag which shows what would be renamed, or use
+--interactive to confirm each rename.
+
+For example to change the extension of all the .jpeg files to .jpg
+
+    rclone rename --dry-run --regex 's/\.jpeg$/.jpg/i' remote:photos
+
+Or to put the date taken first in names like "holiday 2019-01-02.jpg"
+
+    rclone rename --regex 's/^(.*) ([0-9-]+)\.jpg$/$2 $1.jpg/' remote:photos
+
+If the remote can't move files server side they are copied then the
+originals are deleted.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		r, err := operations.NewRenameRegex(regex, fullPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fdst := cmd.NewFsSrc(args)
+		cmd.Run(false, true, command, func() error {
+			return operations.Rename(fdst, r)
+		})
+	},
+}
@@ -15,6 +15,15 @@ func init() {
 var commandDefintion = &cobra.Command{
 	Use:   "reveal password",
 	Short: `Reveal obscured password from rclone.conf`,
+	Long: `Reveal a password obscured with ` + "`rclone obscure`" + ` or as stored in
+the config file.
+
+Obscuring is not encryption - it only stops passwords being read at a
+glance.  Anyone with access to the config file can reveal them with
+this command, so protect the config file with config encryption (see
+` + "`rclone config`" + `) or keep secrets in the system keyring with
+` + "`rclone config keyring`" + `.
+`,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		cmd.Run(false, false, command, func() error {
@@ -26,5 +35,4 @@ var commandDefintion = &cobra.Command{
 			return nil
 		})
 	},
-	Hidden: true,
 }
@@ -25,7 +25,8 @@ it has nothing in.
 If you supply the --leave-root flag, it will not remove the root directory.
 
 This is useful for tidying up remotes that rclone has left a lot of
-empty directories in.
+empty directories in.  To tidy up the source of a move in the same
+pass use ` + "`rclone move --delete-empty-src-dirs`" + ` instead.
 
 `,
 	Run: func(command *cobra.Command, args []string) {
@@ -10,6 +10,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"time"
 
 	"github.com/anacrolix/dms/dlna"
 	"github.com/anacrolix/dms/upnp"
@@ -56,6 +57,11 @@ func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fi
 		Res:    make([]upnpav.Resource, 0, 1),
 	}
 
+	var info mediaInfo
+	if file, ok := fileInfo.(*vfs.File); ok && cds.mediaInfo != nil {
+		info, _ = cds.mediaInfo.get(file)
+	}
+
 	item.Res = append(item.Res, upnpav.Resource{
 		URL: (&url.URL{
 			Scheme: "http",
@@ -69,10 +75,10 @@ func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fi
 		ProtocolInfo: fmt.Sprintf("http-get:*:video/x-matroska:%s", dlna.ContentFeatures{
 			SupportRange: true,
 		}.String()),
-		Bitrate:    0,
-		Duration:   "",
+		Bitrate:    info.bitrate(fileInfo.Size()),
+		Duration:   formatDuration(info.duration),
 		Size:       uint64(fileInfo.Size()),
-		Resolution: "",
+		Resolution: info.resolution(),
 	})
 
 	ret = item
@@ -98,7 +104,9 @@ func (cds *contentDirectoryService) readContainer(o object, host string) (ret []
 		return
 	}
 
-	sort.Sort(dirEntries)
+	sort.SliceStable(dirEntries, func(i, j int) bool {
+		return cds.less(dirEntries[i], dirEntries[j])
+	})
 
 	for _, de := range dirEntries {
 		child := object{
@@ -119,6 +127,43 @@ func (cds *contentDirectoryService) readContainer(o object, host string) (ret []
 	return
 }
 
+// nodeLess returns the function to order directory listings by for
+// --sort and --sort-reverse.
+func nodeLess(order string, reverse bool) (less func(a, b vfs.Node) bool, err error) {
+	switch order {
+	case "", "name":
+		less = func(a, b vfs.Node) bool {
+			return a.Path() < b.Path()
+		}
+	case "date":
+		less = func(a, b vfs.Node) bool {
+			aTime, bTime := a.ModTime(), b.ModTime()
+			if aTime.Equal(bTime) {
+				return a.Path() < b.Path()
+			}
+			return aTime.Before(bTime)
+		}
+	default:
+		return nil, errors.Errorf("unknown --sort %q - must be name or date", order)
+	}
+	if reverse {
+		forward := less
+		less = func(a, b vfs.Node) bool {
+			return forward(b, a)
+		}
+	}
+	return less, nil
+}
+
+// formatDuration returns d in the format DLNA wants or "" if it is
+// unknown
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return dlna.FormatNPTTime(d)
+}
+
 type browse struct {
 	ObjectID       string
 	BrowseFlag     string
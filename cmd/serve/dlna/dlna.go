@@ -19,9 +19,11 @@ import (
 	"github.com/anacrolix/dms/upnp"
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/cmd/serve/dlna/dlnaflags"
+	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -49,7 +51,10 @@ players might show files that they are not able to play back correctly.
 		f := cmd.NewFsSrc(args)
 
 		cmd.Run(false, false, command, func() error {
-			s := newServer(f, &dlnaflags.Opt)
+			s, err := newServer(f, &dlnaflags.Opt)
+			if err != nil {
+				return err
+			}
 			if err := s.Serve(); err != nil {
 				log.Fatal(err)
 			}
@@ -122,9 +127,20 @@ type server struct {
 
 	f   fs.Fs
 	vfs *vfs.VFS
+
+	// How to order directory listings
+	less func(a, b vfs.Node) bool
+
+	// Media info of the files if --media-info is set, nil otherwise
+	mediaInfo *mediaInfoCache
 }
 
-func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
+func newServer(f fs.Fs, opt *dlnaflags.Options) (*server, error) {
+	less, err := nodeLess(opt.Sort, opt.SortReverse)
+	if err != nil {
+		return nil, err
+	}
+
 	hostName, err := os.Hostname()
 	if err != nil {
 		hostName = ""
@@ -140,6 +156,11 @@ func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
 
 		f:   f,
 		vfs: vfs.New(f, &vfsflags.Opt),
+
+		less: less,
+	}
+	if opt.MediaInfo {
+		s.mediaInfo = newMediaInfoCache()
 	}
 
 	s.initServicesMap()
@@ -172,7 +193,7 @@ func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
 	s.rootDescXML = append([]byte(`<?xml version="1.0"?>`), s.rootDescXML...)
 	s.initMux(s.httpServeMux)
 
-	return s
+	return s, nil
 }
 
 // UPnPService is the interface for the SOAP service.
@@ -311,10 +332,15 @@ func (s *server) soapActionResponse(sa upnp.SoapAction, actionRequestXML []byte,
 // use s.Wait() to block on the listener indefinitely.
 func (s *server) Serve() (err error) {
 	if s.HTTPConn == nil {
-		s.HTTPConn, err = net.Listen("tcp", s.httpListenAddr)
+		s.HTTPConn, err = httplib.Listen(s.httpListenAddr)
 		if err != nil {
 			return
 		}
+		if _, ok := s.HTTPConn.Addr().(*net.TCPAddr); !ok {
+			_ = s.HTTPConn.Close()
+			s.HTTPConn = nil
+			return errors.Errorf("DLNA needs a TCP socket but %q isn't one", s.httpListenAddr)
+		}
 	}
 
 	go func() {
@@ -12,18 +12,40 @@ var Help = `
 
 Use --addr to specify which IP address and port the server should
 listen on, eg --addr 1.2.3.4:8000 or --addr :8080 to listen to all
-IPs.
+IPs.  Use --addr systemd to use a TCP socket passed by systemd socket
+activation.
+
+### Media info
+
+If --media-info is set then rclone reads the headers of MP4, MOV,
+Matroska (MKV and WebM) and AVI files to find their duration and
+resolution and passes these on to the player.  This needs a small
+amount of each file to be read the first time its directory is
+browsed so it is off by default, but it helps players such as smart
+TVs which won't otherwise show the length of the video or allow
+seeking.  The results are remembered while the server is running.
+
+### Sort order
+
+Use --sort to set the order directories are shown in, either "name"
+(the default) or "date" which shows the oldest first.  Add
+--sort-reverse to reverse the order, so "--sort date --sort-reverse"
+shows the most recently modified first.
 
 `
 
 // Options is the type for DLNA serving options.
 type Options struct {
-	ListenAddr string
+	ListenAddr  string
+	MediaInfo   bool   // read the duration and resolution of media files
+	Sort        string // order of directory listings, "name" or "date"
+	SortReverse bool   // reverse the order of directory listings
 }
 
 // DefaultOpt contains the defaults options for DLNA serving.
 var DefaultOpt = Options{
 	ListenAddr: ":7879",
+	Sort:       "name",
 }
 
 // Opt contains the options for DLNA serving.
@@ -34,6 +56,9 @@ var (
 func addFlagsPrefix(flagSet *pflag.FlagSet, prefix string, Opt *Options) {
 	rc.AddOption("dlna", &Opt)
 	flags.StringVarP(flagSet, &Opt.ListenAddr, prefix+"addr", "", Opt.ListenAddr, "ip:port or :port to bind the DLNA http server to.")
+	flags.BoolVarP(flagSet, &Opt.MediaInfo, prefix+"media-info", "", Opt.MediaInfo, "Read the duration and resolution of media files.")
+	flags.StringVarP(flagSet, &Opt.Sort, prefix+"sort", "", Opt.Sort, "Order to list directories in: name or date.")
+	flags.BoolVarP(flagSet, &Opt.SortReverse, prefix+"sort-reverse", "", Opt.SortReverse, "Reverse the order directories are listed in.")
 }
 
 // AddFlags add the command line flags for DLNA serving.
@@ -0,0 +1,462 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+)
+
+// mediaInfo is what is found out about a media file by probing it
+type mediaInfo struct {
+	duration time.Duration // length of the media, 0 if unknown
+	width    int           // width of the video in pixels, 0 if unknown
+	height   int           // height of the video in pixels, 0 if unknown
+}
+
+// resolution returns the resolution in DLNA format or "" if unknown
+func (info mediaInfo) resolution() string {
+	if info.width <= 0 || info.height <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", info.width, info.height)
+}
+
+// bitrate returns the average bitrate in bytes per second of a file
+// of size bytes, as DLNA wants it, or 0 if unknown
+func (info mediaInfo) bitrate(size int64) uint {
+	if info.duration <= 0 || size <= 0 {
+		return 0
+	}
+	return uint(float64(size) / info.duration.Seconds())
+}
+
+// mediaProber reads the mediaInfo of a file of size bytes from r
+type mediaProber func(r io.ReaderAt, size int64) (mediaInfo, error)
+
+// mediaProbers are the probers to use for each file extension
+var mediaProbers = map[string]mediaProber{
+	".mp4":  probeMP4,
+	".m4v":  probeMP4,
+	".m4a":  probeMP4,
+	".mov":  probeMP4,
+	".3gp":  probeMP4,
+	".mkv":  probeMatroska,
+	".mka":  probeMatroska,
+	".webm": probeMatroska,
+	".avi":  probeAVI,
+}
+
+// maxProbeElements is the most boxes or elements read from a file
+// before giving up
+const maxProbeElements = 1000
+
+// readAtFull reads n bytes at off from r
+func readAtFull(r io.ReaderAt, off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := r.ReadAt(buf, off)
+	if read == n {
+		return buf, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+// mediaInfoCacheEntry is a remembered result of probing a file
+type mediaInfoCacheEntry struct {
+	size    int64
+	modTime time.Time
+	info    mediaInfo
+	ok      bool
+}
+
+// mediaInfoCache remembers the results of probing files so each is
+// only read once while it is unchanged
+type mediaInfoCache struct {
+	mu      sync.Mutex
+	entries map[string]mediaInfoCacheEntry
+}
+
+// newMediaInfoCache makes a new empty mediaInfoCache
+func newMediaInfoCache() *mediaInfoCache {
+	return &mediaInfoCache{
+		entries: make(map[string]mediaInfoCacheEntry),
+	}
+}
+
+// get returns the media info for the file, probing it if it hasn't
+// been probed since it last changed.  It returns ok false if the file
+// isn't a media file rclone can probe.
+func (c *mediaInfoCache) get(file *vfs.File) (info mediaInfo, ok bool) {
+	prober := mediaProbers[strings.ToLower(path.Ext(file.Name()))]
+	if prober == nil {
+		return info, false
+	}
+	remote := file.Path()
+	size, modTime := file.Size(), file.ModTime()
+	c.mu.Lock()
+	entry, found := c.entries[remote]
+	c.mu.Unlock()
+	if found && entry.size == size && entry.modTime.Equal(modTime) {
+		return entry.info, entry.ok
+	}
+	info, err := probeFile(file, prober)
+	if err != nil {
+		fs.Debugf(remote, "Failed to read media info: %v", err)
+	}
+	ok = err == nil
+	c.mu.Lock()
+	c.entries[remote] = mediaInfoCacheEntry{
+		size:    size,
+		modTime: modTime,
+		info:    info,
+		ok:      ok,
+	}
+	c.mu.Unlock()
+	return info, ok
+}
+
+// probeFile opens file and reads its media info with prober
+func probeFile(file *vfs.File, prober mediaProber) (info mediaInfo, err error) {
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		return info, err
+	}
+	defer fs.CheckClose(in, &err)
+	return prober(in, file.Size())
+}
+
+// probeMP4 reads the duration and video size from the moov box of an
+// MP4 or QuickTime file, skipping over the media data.
+func probeMP4(r io.ReaderAt, size int64) (info mediaInfo, err error) {
+	found := false
+	elements := 0
+	var walk func(start, end int64) error
+	walk = func(start, end int64) error {
+		for off := start; off+8 <= end; {
+			elements++
+			if elements > maxProbeElements {
+				return errors.New("too many boxes")
+			}
+			header, err := readAtFull(r, off, 8)
+			if err != nil {
+				return err
+			}
+			boxSize := int64(binary.BigEndian.Uint32(header))
+			boxType := string(header[4:8])
+			headerSize := int64(8)
+			switch boxSize {
+			case 0:
+				boxSize = end - off
+			case 1:
+				large, err := readAtFull(r, off+8, 8)
+				if err != nil {
+					return err
+				}
+				boxSize = int64(binary.BigEndian.Uint64(large))
+				headerSize = 16
+			}
+			if boxSize < headerSize || off+boxSize > end {
+				return errors.Errorf("bad %q box size %d", boxType, boxSize)
+			}
+			content, contentEnd := off+headerSize, off+boxSize
+			switch boxType {
+			case "moov", "trak":
+				if err := walk(content, contentEnd); err != nil {
+					return err
+				}
+			case "mvhd":
+				if err := readMVHD(r, content, contentEnd, &info); err != nil {
+					return err
+				}
+				found = true
+			case "tkhd":
+				if err := readTKHD(r, content, contentEnd, &info); err != nil {
+					return err
+				}
+			}
+			off = contentEnd
+		}
+		return nil
+	}
+	if err = walk(0, size); err != nil {
+		return info, err
+	}
+	if !found {
+		return info, errors.New("no movie header found")
+	}
+	return info, nil
+}
+
+// readMVHD reads the duration from the movie header box at
+// [start,end)
+func readMVHD(r io.ReaderAt, start, end int64, info *mediaInfo) error {
+	buf, err := readAtFull(r, start, int(min64(end-start, 32)))
+	if err != nil {
+		return err
+	}
+	var timescale, duration uint64
+	switch {
+	case len(buf) >= 32 && buf[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(buf[20:]))
+		duration = binary.BigEndian.Uint64(buf[24:])
+	case len(buf) >= 20 && buf[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(buf[12:]))
+		duration = uint64(binary.BigEndian.Uint32(buf[16:]))
+	default:
+		return errors.New("bad movie header")
+	}
+	if timescale != 0 && duration != math.MaxUint32 && duration != math.MaxUint64 {
+		info.duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+	return nil
+}
+
+// readTKHD reads the video size from the track header box at
+// [start,end) if the track has one and it hasn't been found already
+func readTKHD(r io.ReaderAt, start, end int64, info *mediaInfo) error {
+	if info.width != 0 {
+		return nil
+	}
+	buf, err := readAtFull(r, start, int(min64(end-start, 96)))
+	if err != nil {
+		return err
+	}
+	widthOff := 76
+	if len(buf) > 0 && buf[0] == 1 {
+		widthOff = 88
+	}
+	if len(buf) < widthOff+8 {
+		return errors.New("bad track header")
+	}
+	// the sizes are 16.16 fixed point
+	info.width = int(binary.BigEndian.Uint32(buf[widthOff:]) >> 16)
+	info.height = int(binary.BigEndian.Uint32(buf[widthOff+4:]) >> 16)
+	return nil
+}
+
+// Matroska element IDs
+const (
+	mkvEBML          = 0x1A45DFA3
+	mkvSegment       = 0x18538067
+	mkvInfo          = 0x1549A966
+	mkvTimecodeScale = 0x2AD7B1
+	mkvDuration      = 0x4489
+	mkvTracks        = 0x1654AE6B
+	mkvTrackEntry    = 0xAE
+	mkvVideo         = 0xE0
+	mkvPixelWidth    = 0xB0
+	mkvPixelHeight   = 0xBA
+	mkvCluster       = 0x1F43B675
+)
+
+// errMkvDone is returned internally to stop parsing a Matroska file
+var errMkvDone = errors.New("done")
+
+// readVint reads a Matroska variable length integer at off returning
+// it and its length.  If raw is set the length marker is kept as is
+// done for element IDs.  Sizes with all bits set are unknown and
+// returned as -1.
+func readVint(r io.ReaderAt, off int64, raw bool) (value int64, length int, err error) {
+	first, err := readAtFull(r, off, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	length = 1
+	for mask := byte(0x80); first[0]&mask == 0; mask >>= 1 {
+		length++
+		if length > 8 {
+			return 0, 0, errors.New("bad variable length integer")
+		}
+	}
+	buf, err := readAtFull(r, off, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !raw {
+		buf[0] &^= 0x80 >> uint(length-1)
+	}
+	var u uint64
+	for _, b := range buf {
+		u = u<<8 | uint64(b)
+	}
+	if !raw && u == 1<<(7*uint(length))-1 {
+		return -1, length, nil
+	}
+	return int64(u), length, nil
+}
+
+// readUint reads an unsigned Matroska element of length n at off
+func readUint(r io.ReaderAt, off int64, n int64) (uint64, error) {
+	if n > 8 {
+		return 0, errors.New("integer too long")
+	}
+	buf, err := readAtFull(r, off, int(n))
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, b := range buf {
+		u = u<<8 | uint64(b)
+	}
+	return u, nil
+}
+
+// readFloat reads a Matroska float element of length n at off
+func readFloat(r io.ReaderAt, off int64, n int64) (float64, error) {
+	buf, err := readAtFull(r, off, int(n))
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(buf))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+	}
+	return 0, errors.Errorf("bad float length %d", n)
+}
+
+// probeMatroska reads the duration and video size from the Info and
+// Tracks elements of a Matroska or WebM file, stopping at the first
+// Cluster as they come before the media data in practice.
+func probeMatroska(r io.ReaderAt, size int64) (info mediaInfo, err error) {
+	id, _, err := readVint(r, 0, true)
+	if err != nil {
+		return info, err
+	}
+	if id != mkvEBML {
+		return info, errors.New("not a Matroska file")
+	}
+	var (
+		timecodeScale = uint64(1000000)
+		duration      float64
+		foundInfo     bool
+		foundTracks   bool
+		elements      int
+	)
+	var walk func(start, end int64) error
+	walk = func(start, end int64) error {
+		for off := start; off < end; {
+			elements++
+			if elements > maxProbeElements {
+				return errors.New("too many elements")
+			}
+			id, idLength, err := readVint(r, off, true)
+			if err != nil {
+				return err
+			}
+			elementSize, sizeLength, err := readVint(r, off+int64(idLength), false)
+			if err != nil {
+				return err
+			}
+			content := off + int64(idLength+sizeLength)
+			contentEnd := end
+			if elementSize >= 0 && content+elementSize < end {
+				contentEnd = content + elementSize
+			}
+			switch id {
+			case mkvSegment, mkvTrackEntry, mkvVideo:
+				if err := walk(content, contentEnd); err != nil {
+					return err
+				}
+			case mkvInfo:
+				foundInfo = true
+				if err := walk(content, contentEnd); err != nil {
+					return err
+				}
+			case mkvTracks:
+				foundTracks = true
+				if err := walk(content, contentEnd); err != nil {
+					return err
+				}
+			case mkvTimecodeScale:
+				if timecodeScale, err = readUint(r, content, contentEnd-content); err != nil {
+					return err
+				}
+			case mkvDuration:
+				if duration, err = readFloat(r, content, contentEnd-content); err != nil {
+					return err
+				}
+			case mkvPixelWidth:
+				if info.width == 0 {
+					width, err := readUint(r, content, contentEnd-content)
+					if err != nil {
+						return err
+					}
+					info.width = int(width)
+				}
+			case mkvPixelHeight:
+				if info.height == 0 {
+					height, err := readUint(r, content, contentEnd-content)
+					if err != nil {
+						return err
+					}
+					info.height = int(height)
+				}
+			case mkvCluster:
+				return errMkvDone
+			}
+			if (id == mkvInfo || id == mkvTracks) && foundInfo && foundTracks {
+				return errMkvDone
+			}
+			if elementSize < 0 {
+				// an unknown size element runs to the end of its
+				// parent so it has been read already
+				return nil
+			}
+			off = contentEnd
+		}
+		return nil
+	}
+	err = walk(0, size)
+	if err != nil && err != errMkvDone {
+		return info, err
+	}
+	if !foundInfo {
+		return info, errors.New("no segment info found")
+	}
+	info.duration = time.Duration(duration * float64(timecodeScale))
+	return info, nil
+}
+
+// probeAVI reads the duration and video size from the main header of
+// an AVI file which is at a fixed place at the start.
+func probeAVI(r io.ReaderAt, size int64) (info mediaInfo, err error) {
+	buf, err := readAtFull(r, 0, 72)
+	if err != nil {
+		return info, err
+	}
+	if !bytes.Equal(buf[0:4], []byte("RIFF")) || !bytes.Equal(buf[8:12], []byte("AVI ")) ||
+		!bytes.Equal(buf[12:16], []byte("LIST")) || !bytes.Equal(buf[20:24], []byte("hdrl")) ||
+		!bytes.Equal(buf[24:28], []byte("avih")) {
+		return info, errors.New("not an AVI file")
+	}
+	avih := buf[32:]
+	microSecPerFrame := binary.LittleEndian.Uint32(avih[0:])
+	totalFrames := binary.LittleEndian.Uint32(avih[16:])
+	info.duration = time.Duration(microSecPerFrame) * time.Duration(totalFrames) * time.Microsecond
+	info.width = int(binary.LittleEndian.Uint32(avih[32:]))
+	info.height = int(binary.LittleEndian.Uint32(avih[36:]))
+	return info, nil
+}
+
+// min64 returns the smaller of a and b
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
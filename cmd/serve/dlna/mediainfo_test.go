@@ -0,0 +1,124 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// box makes an MP4 box
+func box(boxType string, contents ...[]byte) []byte {
+	body := bytes.Join(contents, nil)
+	buf := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(8+len(body)))
+	copy(buf[4:], boxType)
+	return append(buf, body...)
+}
+
+// be32 encodes the 32 bit big endian numbers in xs
+func be32(xs ...uint32) []byte {
+	buf := make([]byte, 4*len(xs))
+	for i, x := range xs {
+		binary.BigEndian.PutUint32(buf[4*i:], x)
+	}
+	return buf
+}
+
+func TestProbeMP4(t *testing.T) {
+	// version 0 movie header with timescale 1000 and duration 90.5s
+	mvhd := box("mvhd", be32(0, 0, 0, 1000, 90500), make([]byte, 80))
+	// an audio track with no size then a 1920x1080 video track
+	tkhd := func(width, height uint32) []byte {
+		return box("tkhd", make([]byte, 76), be32(width<<16, height<<16))
+	}
+	file := bytes.Join([][]byte{
+		box("ftyp", []byte("isom")),
+		box("mdat", make([]byte, 1000)),
+		box("moov", mvhd, box("trak", tkhd(0, 0)), box("trak", tkhd(1920, 1080))),
+	}, nil)
+
+	info, err := probeMP4(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	assert.Equal(t, 90500*time.Millisecond, info.duration)
+	assert.Equal(t, "1920x1080", info.resolution())
+	assert.Equal(t, uint(len(file)*1000/90500), info.bitrate(int64(len(file))))
+
+	_, err = probeMP4(bytes.NewReader(file[:20]), 20)
+	assert.Error(t, err)
+	_, err = probeMP4(bytes.NewReader(file[:1020]), 1020)
+	assert.Error(t, err)
+}
+
+// element makes a Matroska element with a one byte size
+func element(id uint32, contents ...[]byte) []byte {
+	body := bytes.Join(contents, nil)
+	var buf []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> uint(shift)); b != 0 || len(buf) > 0 {
+			buf = append(buf, b)
+		}
+	}
+	buf = append(buf, 0x80|byte(len(body)))
+	return append(buf, body...)
+}
+
+func TestProbeMatroska(t *testing.T) {
+	var duration [8]byte
+	binary.BigEndian.PutUint64(duration[:], math.Float64bits(12345))
+	file := bytes.Join([][]byte{
+		element(mkvEBML, element(0x4282, []byte("webm"))),
+		// a Segment of unknown size
+		{0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+		element(mkvInfo, element(mkvTimecodeScale, []byte{0x0F, 0x42, 0x40}), element(mkvDuration, duration[:])),
+		element(mkvTracks, element(mkvTrackEntry, element(mkvVideo, element(mkvPixelWidth, []byte{0x05, 0x00}), element(mkvPixelHeight, []byte{0x02, 0xD0})))),
+		element(mkvCluster, make([]byte, 100)),
+	}, nil)
+
+	info, err := probeMatroska(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	assert.Equal(t, 12345*time.Millisecond, info.duration)
+	assert.Equal(t, "1280x720", info.resolution())
+
+	_, err = probeMatroska(bytes.NewReader(file[1:]), int64(len(file)-1))
+	assert.Error(t, err)
+}
+
+func TestProbeAVI(t *testing.T) {
+	le32 := func(xs ...uint32) []byte {
+		buf := make([]byte, 4*len(xs))
+		for i, x := range xs {
+			binary.LittleEndian.PutUint32(buf[4*i:], x)
+		}
+		return buf
+	}
+	file := bytes.Join([][]byte{
+		[]byte("RIFF"), le32(1000), []byte("AVI "),
+		[]byte("LIST"), le32(200), []byte("hdrl"),
+		[]byte("avih"), le32(56),
+		// 40ms per frame, 250 frames, 640x480
+		le32(40000, 0, 0, 0, 250, 0, 1, 0, 640, 480, 0, 0, 0, 0),
+	}, nil)
+
+	info, err := probeAVI(bytes.NewReader(file), int64(len(file)))
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, info.duration)
+	assert.Equal(t, "640x480", info.resolution())
+
+	_, err = probeAVI(bytes.NewReader(file[:40]), 40)
+	assert.Error(t, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "", formatDuration(0))
+	assert.Equal(t, "01:02:03.456", formatDuration(time.Hour+2*time.Minute+3456*time.Millisecond))
+}
+
+func TestNodeLess(t *testing.T) {
+	_, err := nodeLess("size", false)
+	assert.Error(t, err)
+}
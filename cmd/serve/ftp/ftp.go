@@ -18,6 +18,8 @@ import (
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/cmd/serve/ftp/ftpflags"
 	"github.com/ncw/rclone/cmd/serve/ftp/ftpopt"
+	"github.com/ncw/rclone/cmd/serve/httplib"
+	"github.com/ncw/rclone/cmd/serve/userdb"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/log"
@@ -39,12 +41,18 @@ var Command = &cobra.Command{
 rclone serve ftp implements a basic ftp server to serve the
 remote over FTP protocol. This can be viewed with a ftp client
 or you can make a remote of type ftp to read and write it.
-` + ftpopt.Help + vfs.Help,
+` + ftpopt.Help + userdb.Help + `
+--user-file can't be used with --user.
+` + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		f := cmd.NewFsSrc(args)
 		cmd.Run(false, false, command, func() error {
-			s, err := newServer(f, &ftpflags.Opt)
+			// --user defaults to anonymous so check it was set
+			if ftpflags.Opt.UserFile != "" && command.Flags().Changed("user") {
+				return errors.New("can't use --user-file with --user")
+			}
+			s, err := newServer(f, args[0], &ftpflags.Opt)
 			if err != nil {
 				return err
 			}
@@ -55,52 +63,87 @@ or you can make a remote of type ftp to read and write it.
 
 // server contains everything to run the server
 type server struct {
-	f   fs.Fs
-	srv *ftp.Server
+	f        fs.Fs
+	srv      *ftp.Server
+	addr     string       // the address being served
+	listener net.Listener // the unix or systemd socket to serve, nil for TCP
 }
 
-// Make a new FTP to serve the remote
-func newServer(f fs.Fs, opt *ftpopt.Options) (*server, error) {
-	host, port, err := net.SplitHostPort(opt.ListenAddr)
-	if err != nil {
-		return nil, errors.New("Failed to parse host:port")
+// Make a new FTP to serve the remote f made from remote
+func newServer(f fs.Fs, remote string, opt *ftpopt.Options) (*server, error) {
+	var (
+		host     string
+		portNum  int
+		listener net.Listener
+	)
+	if httplib.IsTCP(opt.ListenAddr) {
+		var port string
+		var err error
+		host, port, err = net.SplitHostPort(opt.ListenAddr)
+		if err != nil {
+			return nil, errors.New("Failed to parse host:port")
+		}
+		portNum, err = strconv.Atoi(port)
+		if err != nil {
+			return nil, errors.New("Failed to parse host:port")
+		}
 	}
-	portNum, err := strconv.Atoi(port)
-	if err != nil {
-		return nil, errors.New("Failed to parse host:port")
+
+	factory := &DriverFactory{}
+	auth := &Auth{
+		BasicUser: opt.BasicUser,
+		BasicPass: opt.BasicPass,
+	}
+	if opt.UserFile != "" {
+		users, err := userdb.New(opt.UserFile, remote, f, &vfsflags.Opt)
+		if err != nil {
+			return nil, err
+		}
+		factory.users = users
+		auth.users = users
+	} else {
+		factory.vfs = vfs.New(f, &vfsflags.Opt)
 	}
 
 	ftpopt := &ftp.ServerOpts{
 		Name:           "Rclone FTP Server",
 		WelcomeMessage: "Welcome on Rclone FTP Server",
-		Factory: &DriverFactory{
-			vfs: vfs.New(f, &vfsflags.Opt),
-		},
-		Hostname:     host,
-		Port:         portNum,
-		PassivePorts: opt.PassivePorts,
-		Auth: &Auth{
-			BasicUser: opt.BasicUser,
-			BasicPass: opt.BasicPass,
-		},
-		Logger: &Logger{},
+		Factory:        factory,
+		Hostname:       host,
+		Port:           portNum,
+		PassivePorts:   opt.PassivePorts,
+		Auth:           auth,
+		Logger:         &Logger{},
 		//TODO implement a maximum of https://godoc.org/github.com/goftp/server#ServerOpts
 	}
+	if !httplib.IsTCP(opt.ListenAddr) {
+		var err error
+		listener, err = httplib.Listen(opt.ListenAddr)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &server{
-		f:   f,
-		srv: ftp.NewServer(ftpopt),
+		f:        f,
+		srv:      ftp.NewServer(ftpopt),
+		addr:     opt.ListenAddr,
+		listener: listener,
 	}, nil
 }
 
 // serve runs the ftp server
 func (s *server) serve() error {
-	fs.Logf(s.f, "Serving FTP on %s", s.srv.Hostname+":"+strconv.Itoa(s.srv.Port))
+	fs.Logf(s.f, "Serving FTP on %s", s.addr)
+	if s.listener != nil {
+		return s.srv.Serve(s.listener)
+	}
+	// Use ListenAndServe for TCP as only it sets up the FEAT reply
 	return s.srv.ListenAndServe()
 }
 
 // serve runs the ftp server
 func (s *server) close() error {
-	fs.Logf(s.f, "Stopping FTP on %s", s.srv.Hostname+":"+strconv.Itoa(s.srv.Port))
+	fs.Logf(s.f, "Stopping FTP on %s", s.addr)
 	return s.srv.Shutdown()
 }
 
@@ -135,45 +178,67 @@ func (l *Logger) PrintResponse(sessionID string, code int, message string) {
 type Auth struct {
 	BasicUser string
 	BasicPass string
+	users     *userdb.DB // set if using --user-file
 }
 
 //CheckPasswd handle auth based on configuration
 func (a *Auth) CheckPasswd(user, pass string) (bool, error) {
+	if a.users != nil {
+		return a.users.Check(user, pass) == nil, nil
+	}
 	return a.BasicUser == user && (a.BasicPass == "" || a.BasicPass == pass), nil
 }
 
 //DriverFactory factory of ftp driver for each session
 type DriverFactory struct {
-	vfs *vfs.VFS
+	vfs   *vfs.VFS
+	users *userdb.DB // set if using --user-file
 }
 
 //NewDriver start a new session
 func (f *DriverFactory) NewDriver() (ftp.Driver, error) {
 	log.Trace("", "Init driver")("")
 	return &Driver{
-		vfs: f.vfs,
+		vfs:   f.vfs,
+		users: f.users,
 	}, nil
 }
 
 //Driver impletation of ftp server
 type Driver struct {
-	vfs  *vfs.VFS
-	lock sync.Mutex
+	vfs   *vfs.VFS
+	users *userdb.DB // set if using --user-file
+	conn  *ftp.Conn
+	lock  sync.Mutex
 }
 
 //Init a connection
-func (d *Driver) Init(*ftp.Conn) {
+func (d *Driver) Init(conn *ftp.Conn) {
 	defer log.Trace("", "Init session")("")
+	d.conn = conn
+}
+
+// getVFS returns the VFS for the session, finding the one for the
+// logged in user if using --user-file
+func (d *Driver) getVFS() (*vfs.VFS, error) {
+	if d.users == nil {
+		return d.vfs, nil
+	}
+	return d.users.VFS(d.conn.LoginUser())
 }
 
 //Stat get information on file or folder
 func (d *Driver) Stat(path string) (fi ftp.FileInfo, err error) {
 	defer log.Trace(path, "")("fi=%+v, err = %v", &fi, &err)
-	n, err := d.vfs.Stat(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return nil, err
+	}
+	n, err := VFS.Stat(path)
 	if err != nil {
 		return nil, err
 	}
-	return &FileInfo{n, n.Mode(), d.vfs.Opt.UID, d.vfs.Opt.GID}, err
+	return &FileInfo{n, n.Mode(), VFS.Opt.UID, VFS.Opt.GID}, err
 }
 
 //ChangeDir move current folder
@@ -181,7 +246,11 @@ func (d *Driver) ChangeDir(path string) (err error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "")("err = %v", &err)
-	n, err := d.vfs.Stat(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return err
+	}
+	n, err := VFS.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -196,7 +265,11 @@ func (d *Driver) ListDir(path string, callback func(ftp.FileInfo) error) (err er
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "")("err = %v", &err)
-	node, err := d.vfs.Stat(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return err
+	}
+	node, err := VFS.Stat(path)
 	if err == vfs.ENOENT {
 		return errors.New("Directory not found")
 	} else if err != nil {
@@ -217,7 +290,7 @@ func (d *Driver) ListDir(path string, callback func(ftp.FileInfo) error) (err er
 	defer accounting.Stats.DoneTransferring(path, true)
 
 	for _, file := range dirEntries {
-		err = callback(&FileInfo{file, file.Mode(), d.vfs.Opt.UID, d.vfs.Opt.GID})
+		err = callback(&FileInfo{file, file.Mode(), VFS.Opt.UID, VFS.Opt.GID})
 		if err != nil {
 			return err
 		}
@@ -230,7 +303,11 @@ func (d *Driver) DeleteDir(path string) (err error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "")("err = %v", &err)
-	node, err := d.vfs.Stat(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return err
+	}
+	node, err := VFS.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -249,7 +326,11 @@ func (d *Driver) DeleteFile(path string) (err error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "")("err = %v", &err)
-	node, err := d.vfs.Stat(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return err
+	}
+	node, err := VFS.Stat(path)
 	if err != nil {
 		return err
 	}
@@ -268,7 +349,11 @@ func (d *Driver) Rename(oldName, newName string) (err error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(oldName, "newName=%q", newName)("err = %v", &err)
-	return d.vfs.Rename(oldName, newName)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return err
+	}
+	return VFS.Rename(oldName, newName)
 }
 
 //MakeDir create a folder
@@ -276,7 +361,11 @@ func (d *Driver) MakeDir(path string) (err error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "")("err = %v", &err)
-	dir, leaf, err := d.vfs.StatParent(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return err
+	}
+	dir, leaf, err := VFS.StatParent(path)
 	if err != nil {
 		return err
 	}
@@ -289,7 +378,11 @@ func (d *Driver) GetFile(path string, offset int64) (size int64, fr io.ReadClose
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "offset=%v", offset)("err = %v", &err)
-	node, err := d.vfs.Stat(path)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return 0, nil, err
+	}
+	node, err := VFS.Stat(path)
 	if err == vfs.ENOENT {
 		fs.Infof(path, "File not found")
 		return 0, nil, errors.New("File not found")
@@ -321,8 +414,12 @@ func (d *Driver) PutFile(path string, data io.Reader, appendData bool) (n int64,
 	d.lock.Lock()
 	defer d.lock.Unlock()
 	defer log.Trace(path, "append=%v", appendData)("err = %v", &err)
+	VFS, err := d.getVFS()
+	if err != nil {
+		return 0, err
+	}
 	var isExist bool
-	node, err := d.vfs.Stat(path)
+	node, err := VFS.Stat(path)
 	if err == nil {
 		isExist = true
 		if node.IsDir() {
@@ -347,7 +444,7 @@ func (d *Driver) PutFile(path string, data io.Reader, appendData bool) (n int64,
 				return 0, err
 			}
 		}
-		f, err := d.vfs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0660)
+		f, err := VFS.OpenFile(path, os.O_RDWR|os.O_CREATE, 0660)
 		if err != nil {
 			return 0, err
 		}
@@ -359,7 +456,7 @@ func (d *Driver) PutFile(path string, data io.Reader, appendData bool) (n int64,
 		return bytes, nil
 	}
 
-	of, err := d.vfs.OpenFile(path, os.O_APPEND|os.O_RDWR, 0660)
+	of, err := VFS.OpenFile(path, os.O_APPEND|os.O_RDWR, 0660)
 	if err != nil {
 		return 0, err
 	}
@@ -8,16 +8,23 @@
 package ftp
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	ftp "github.com/goftp/server"
 	_ "github.com/ncw/rclone/backend/local"
 	"github.com/ncw/rclone/cmd/serve/ftp/ftpopt"
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -37,7 +44,7 @@ func TestFTP(t *testing.T) {
 
 	fstest.Initialise()
 
-	fremote, _, clean, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
+	fremote, remoteName, clean, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
 	assert.NoError(t, err)
 	defer clean()
 
@@ -45,7 +52,7 @@ func TestFTP(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Start the server
-	w, err := newServer(fremote, &opt)
+	w, err := newServer(fremote, remoteName, &opt)
 	assert.NoError(t, err)
 
 	go func() {
@@ -87,3 +94,34 @@ func TestFTP(t *testing.T) {
 	}
 	assert.NoError(t, err, "Running ftp integration tests")
 }
+
+// TestFTPUnix checks the server can listen on a unix socket
+func TestFTPUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-serve-ftp")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "socket")
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	opt := ftpopt.DefaultOpt
+	opt.ListenAddr = "unix:" + path
+	w, err := newServer(f, dir, &opt)
+	require.NoError(t, err)
+	go func() {
+		err := w.serve()
+		if err != ftp.ErrServerClosed {
+			assert.NoError(t, err)
+		}
+	}()
+	defer func() {
+		assert.NoError(t, w.close())
+	}()
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(reply, "220 "), reply)
+}
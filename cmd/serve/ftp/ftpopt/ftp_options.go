@@ -13,11 +13,19 @@ IPs.  By default it only listens on localhost.  You can use port
 If you set --addr to listen on a public or LAN accessible IP address
 then using Authentication is advised - see the next section for info.
 
+--addr unix:/path/to/socket listens on a unix domain socket and --addr
+systemd or systemd:NAME uses a socket passed by systemd socket
+activation, as for "rclone serve http".  Only active mode (PORT) data
+connections can be used with a unix domain socket.
+
 #### Authentication
 
 By default this will serve files without needing a login.
 
 You can set a single username and password with the --user and --pass flags.
+
+Use --user-file to serve many users each with their own password and
+root directory - see below.
 `
 
 // Options contains options for the http Server
@@ -27,6 +35,7 @@ type Options struct {
 	PassivePorts string // Passive ports range
 	BasicUser    string // single username for basic auth if not using Htpasswd
 	BasicPass    string // password for BasicUser
+	UserFile     string // file of users, passwords and roots if not using BasicUser
 }
 
 // DefaultOpt is the default values used for Options
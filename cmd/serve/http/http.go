@@ -36,6 +36,11 @@ is served.
 
 The server will log errors.  Use -v to see access logs.
 
+Add ?download=zip to the URL of a directory to download it and
+everything in it as a zip archive.  The archive is made on the fly so
+the files are stored uncompressed.  Each directory listing has a link
+to do this.
+
 --bwlimit will be respected for file transfers.  Use --stats to
 control the stats printing.
 ` + httplib.Help + vfs.Help,
@@ -119,6 +124,10 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 		return
 	}
 	dir := node.(*vfs.Dir)
+	if serve.IsZipRequest(r) {
+		serve.ZipDir(w, r, dir)
+		return
+	}
 	dirEntries, err := dir.ReadDirAll()
 	if err != nil {
 		serve.Error(dirRemote, w, "Failed to list directory", err)
@@ -127,6 +136,7 @@ func (s *server) serveDir(w http.ResponseWriter, r *http.Request, dirRemote stri
 
 	// Make the entries for display
 	directory := serve.NewDirectory(dirRemote, s.HTMLTemplate)
+	directory.ZipURL = "?" + serve.ZipQuery
 	for _, node := range dirEntries {
 		directory.AddEntry(node.Path(), node.IsDir())
 	}
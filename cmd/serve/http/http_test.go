@@ -3,6 +3,8 @@
 package http
 
 import (
+	"archive/zip"
+	"bytes"
 	"flag"
 	"io/ioutil"
 	"net"
@@ -201,6 +203,48 @@ func TestGET(t *testing.T) {
 	}
 }
 
+func TestGETZip(t *testing.T) {
+	resp, err := http.Get(testURL + "three/?download=zip")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="three.zip"`, resp.Header.Get("Content-Disposition"))
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+		if file.Name != "three/a.txt" {
+			continue
+		}
+		in, err := file.Open()
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		want, err := ioutil.ReadFile("testdata/files/three/a.txt")
+		require.NoError(t, err)
+		assert.Equal(t, want, contents)
+	}
+	assert.Equal(t, []string{"three/", "three/a.txt", "three/b.txt"}, names)
+
+	// Check the root excludes the hidden files
+	resp, err = http.Get(testURL + "?download=zip")
+	require.NoError(t, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	zr, err = zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	for _, file := range zr.File {
+		assert.NotContains(t, file.Name, "hidden")
+	}
+}
+
 func TestFinalise(t *testing.T) {
 	httpServer.Close()
 	httpServer.Wait()
@@ -36,6 +36,17 @@ IPs.  By default it only listens on localhost.  You can use port
 If you set --addr to listen on a public or LAN accessible IP address
 then using Authentication is advised - see the next section for info.
 
+To listen on a unix domain socket use --addr unix:/path/to/socket, eg
+so a reverse proxy on the same machine can connect to rclone without
+using a TCP port.  Any socket left at the path by a previous run is
+removed first, unless another server is still listening on it.
+
+To use a socket passed by systemd socket activation use --addr
+systemd, or --addr systemd:NAME to choose the socket set with
+FileDescriptorName=NAME in the socket unit when there is more than
+one.  This lets systemd start rclone when the first connection
+arrives.
+
 --server-read-timeout and --server-write-timeout can be used to
 control the timeouts on the server.  Note that this is the total time
 for a transfer.
@@ -43,6 +54,18 @@ for a transfer.
 --max-header-bytes controls the maximum number of bytes the server will
 accept in the HTTP header.
 
+#### CORS and custom headers
+
+Use --allow-origin to allow browser based apps served from other
+sites to use the server, eg --allow-origin "https://app.example.com"
+or --allow-origin "*" for any site.  This sets the CORS headers on
+responses and answers CORS preflight requests, including before
+authentication.
+
+Use --server-header to add a header to every response, eg
+--server-header "Cache-Control: no-cache".  This can be repeated to
+add more than one header.
+
 #### Authentication
 
 By default this will serve files without needing a login.
@@ -90,6 +113,8 @@ type Options struct {
 	Realm              string        // realm for authentication
 	BasicUser          string        // single username for basic auth if not using Htpasswd
 	BasicPass          string        // password for BasicUser
+	AllowOrigin        string        // origin allowed to make CORS requests, "" for none
+	ServerHeaders      []string      // extra "Name: Value" headers to add to each response
 }
 
 // DefaultOpt is the default values used for Options
@@ -136,6 +161,16 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 		s.Opt = DefaultOpt
 	}
 
+	// Parse the extra headers
+	headers := make(http.Header)
+	for _, header := range s.Opt.ServerHeaders {
+		colon := strings.IndexRune(header, ':')
+		if colon <= 0 {
+			log.Fatalf("Bad --server-header %q: must be \"Name: Value\"", header)
+		}
+		headers.Add(strings.TrimSpace(header[:colon]), strings.TrimSpace(header[colon+1:]))
+	}
+
 	// Use htpasswd if required on everything
 	if s.Opt.HtPasswd != "" || s.Opt.BasicUser != "" {
 		var secretProvider auth.SecretProvider
@@ -173,6 +208,13 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 		s.usingAuth = true
 	}
 
+	// Add the CORS and extra headers outside the authentication so
+	// they are on every response and preflight requests don't need
+	// credentials
+	if s.Opt.AllowOrigin != "" || len(headers) > 0 {
+		handler = headersHandler(handler, s.Opt.AllowOrigin, headers)
+	}
+
 	s.useSSL = s.Opt.SslKey != ""
 	if (s.Opt.SslCert != "") != s.useSSL {
 		log.Fatalf("Need both -cert and -key to use SSL")
@@ -217,11 +259,49 @@ func NewServer(handler http.Handler, opt *Options) *Server {
 	return s
 }
 
+// corsAllowMethods are the methods a CORS preflight is told are allowed
+const corsAllowMethods = "GET, HEAD, PUT, POST, DELETE, OPTIONS, PROPFIND, PROPPATCH, MKCOL, COPY, MOVE, LOCK, UNLOCK"
+
+// corsExposeHeaders are the response headers browser apps may read
+const corsExposeHeaders = "Content-Length, Content-Range, Content-Type, Accept-Ranges, ETag, Last-Modified, DAV"
+
+// headersHandler wraps handler to add the CORS headers for
+// allowOrigin (if set) and headers to each response and answer CORS
+// preflight requests itself.
+func headersHandler(handler http.Handler, allowOrigin string, headers http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range headers {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+		if allowOrigin != "" && r.Header.Get("Origin") != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+			if allowOrigin != "*" {
+				w.Header().Add("Vary", "Origin")
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			// Answer preflight requests here
+			if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+				if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+					w.Header().Set("Access-Control-Allow-Headers", reqHeaders)
+				}
+				w.Header().Set("Access-Control-Max-Age", "3600")
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // Serve runs the server - returns an error only if
 // the listener was not started; does not block, so
 // use s.Wait() to block on the listener indefinitely.
 func (s *Server) Serve() error {
-	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	ln, err := Listen(s.httpServer.Addr)
 	if err != nil {
 		return errors.Wrapf(err, "start server failed")
 	}
@@ -286,6 +366,10 @@ func (s *Server) URL() string {
 		// prefer actual listener address; required if using 0-port
 		// (i.e. port assigned by operating system)
 		addr = s.listener.Addr().String()
+		if s.listener.Addr().Network() == "unix" {
+			// in the format nginx uses for proxy_pass
+			return fmt.Sprintf("%s://unix:%s:/", proto, addr)
+		}
 	}
 	return fmt.Sprintf("%s://%s/", proto, addr)
 }
@@ -0,0 +1,145 @@
+package httplib
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadersHandler(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusTeapot)
+	})
+	headers := http.Header{"Cache-Control": []string{"no-cache"}}
+	handler := headersHandler(next, "https://example.com", headers)
+
+	// Normal request without an Origin gets the extra headers only
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.True(t, called)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Cross origin request gets the CORS headers
+	called = false
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+	handler.ServeHTTP(w, r)
+	assert.True(t, called)
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Preflight is answered without calling the handler
+	called = false
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("OPTIONS", "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", "PUT")
+	r.Header.Set("Access-Control-Request-Headers", "Authorization, Range")
+	handler.ServeHTTP(w, r)
+	assert.False(t, called)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, corsAllowMethods, w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Range", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+}
+
+func TestListenUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets not supported")
+	}
+	dir, err := ioutil.TempDir("", "rclone-httplib")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "socket")
+
+	_, err = Listen("unix:")
+	assert.Error(t, err)
+
+	// make a stale socket which should be removed
+	old, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	old.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, old.Close())
+
+	opt := DefaultOpt
+	opt.ListenAddr = "unix:" + path
+	s := NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}), &opt)
+	require.NoError(t, s.Serve())
+	defer s.Close()
+	assert.Equal(t, "http://unix:"+path+":/", s.URL())
+
+	client := http.Client{
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return net.Dial("unix", path)
+			},
+		},
+	}
+	resp, err := client.Get("http://localhost/")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "hello", string(body))
+
+	// a socket in use isn't removed
+	_, err = Listen("unix:" + path)
+	assert.Error(t, err)
+	_, err = os.Lstat(path)
+	assert.NoError(t, err)
+}
+
+func TestIsTCP(t *testing.T) {
+	assert.True(t, IsTCP("localhost:8080"))
+	assert.True(t, IsTCP(":8080"))
+	assert.False(t, IsTCP("unix:/tmp/socket"))
+	assert.False(t, IsTCP("systemd"))
+	assert.False(t, IsTCP("systemd:name"))
+}
+
+func TestListenSystemd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("systemd not supported")
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	// the file is closed when the sockets passed by systemd are read
+	file, err := ln.(*net.TCPListener).File()
+	require.NoError(t, err)
+
+	oldStart := listenFdsStart
+	listenFdsStart = int(file.Fd())
+	defer func() { listenFdsStart = oldStart }()
+	require.NoError(t, os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid())))
+	require.NoError(t, os.Setenv("LISTEN_FDS", "1"))
+	require.NoError(t, os.Setenv("LISTEN_FDNAMES", "web"))
+
+	_, err = Listen("systemd:potato")
+	assert.EqualError(t, err, `no unused socket called "potato" was passed by systemd`)
+	assert.Equal(t, "", os.Getenv("LISTEN_FDS"))
+
+	got, err := Listen("systemd:web")
+	require.NoError(t, err)
+	defer func() { _ = got.Close() }()
+	assert.Equal(t, ln.Addr().String(), got.Addr().String())
+
+	_, err = Listen("systemd")
+	assert.EqualError(t, err, "all the sockets passed by systemd are in use")
+}
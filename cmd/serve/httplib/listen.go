@@ -0,0 +1,141 @@
+package httplib
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Prefixes of the listen addresses which aren't TCP
+const (
+	unixPrefix    = "unix:"
+	systemdPrefix = "systemd"
+)
+
+// Listen makes a listener for addr which may be
+//
+//   - "IPaddress:Port" or ":Port" for a TCP socket
+//   - "unix:/path/to/socket" for a unix domain socket
+//   - "systemd" for the next socket passed by systemd socket activation
+//   - "systemd:name" for the socket systemd passed with FileDescriptorName=name
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, unixPrefix):
+		return listenUnix(addr[len(unixPrefix):])
+	case addr == systemdPrefix:
+		return systemdListener("")
+	case strings.HasPrefix(addr, systemdPrefix+":"):
+		return systemdListener(addr[len(systemdPrefix)+1:])
+	}
+	return net.Listen("tcp", addr)
+}
+
+// IsTCP returns true if addr is a TCP address, rather than one of
+// the other kinds of address read by Listen.
+func IsTCP(addr string) bool {
+	return !strings.HasPrefix(addr, unixPrefix) && addr != systemdPrefix && !strings.HasPrefix(addr, systemdPrefix+":")
+}
+
+// listenUnix listens on the unix domain socket at path, removing a
+// socket left there by a previous run first.
+//
+// If something is still accepting connections on the socket it is
+// left alone and an error is returned.
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("need a path to listen on a unix socket, eg unix:/path/to/socket")
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			_ = conn.Close()
+			return nil, errors.Errorf("unix socket %q is in use by another server", path)
+		}
+		fs.Debugf(nil, "Removing old unix socket %q", path)
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "failed to remove old unix socket")
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// listenFdsStart is the first file descriptor passed by systemd
+var listenFdsStart = 3
+
+// systemd holds the sockets passed by systemd socket activation
+var systemd struct {
+	once      sync.Once
+	mu        sync.Mutex
+	listeners []net.Listener // the sockets not used yet, nil when used
+	names     []string       // the FileDescriptorName of each socket
+	err       error          // error reading the sockets if any
+}
+
+// systemdListeners reads the sockets passed by systemd in the
+// environment variables of the socket activation protocol, unsetting
+// them so they aren't passed on to any child processes.
+func systemdListeners() (listeners []net.Listener, names []string, err error) {
+	defer func() {
+		_ = os.Unsetenv("LISTEN_PID")
+		_ = os.Unsetenv("LISTEN_FDS")
+		_ = os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil, nil
+	}
+	names = strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		file := os.NewFile(uintptr(listenFdsStart+i), name)
+		ln, err := net.FileListener(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to use socket %d passed by systemd", i)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, names, nil
+}
+
+// systemdListener returns the socket passed by systemd called name,
+// or the next one if name is empty.  Each socket can only be used
+// once.
+func systemdListener(name string) (net.Listener, error) {
+	systemd.once.Do(func() {
+		systemd.listeners, systemd.names, systemd.err = systemdListeners()
+	})
+	if systemd.err != nil {
+		return nil, systemd.err
+	}
+	if len(systemd.listeners) == 0 {
+		return nil, errors.New("no sockets were passed by systemd socket activation")
+	}
+	systemd.mu.Lock()
+	defer systemd.mu.Unlock()
+	for i, ln := range systemd.listeners {
+		if ln == nil {
+			continue
+		}
+		if name == "" || (i < len(systemd.names) && systemd.names[i] == name) {
+			systemd.listeners[i] = nil
+			return ln, nil
+		}
+	}
+	if name == "" {
+		return nil, errors.New("all the sockets passed by systemd are in use")
+	}
+	return nil, errors.Errorf("no unused socket called %q was passed by systemd", name)
+}
@@ -26,9 +26,9 @@ var Assets = func() http.FileSystem {
 		"/index.html": &vfsgen۰CompressedFileInfo{
 			name:             "index.html",
 			modTime:          time.Date(2018, 12, 16, 6, 54, 42, 790442328, time.UTC),
-			uncompressedSize: 226,
+			uncompressedSize: 307,
 
-			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5c\x8f\x31\xcf\x83\x20\x10\x86\x77\x7e\xc5\x7d\xc4\xf5\x93\xb8\x35\x0d\xb0\xb4\x6e\x26\x6d\x1a\x3b\x74\x3c\xeb\x29\x24\x4a\x13\xa4\x43\x43\xf8\xef\x0d\xea\xd4\x09\xee\x79\xef\x9e\xcb\xc9\xbf\xf3\xe5\xd4\x3e\xae\x35\x98\x30\x4f\x9a\xc9\xfc\xc0\x84\x6e\x54\x9c\x1c\xcf\x80\xb0\xd7\x4c\xce\x14\x10\x9e\x06\xfd\x42\x41\xf1\x77\x18\xfe\x0f\x39\x0d\x36\x4c\xa4\x63\x84\xb2\xcd\x3f\x48\x49\x8a\x8d\x31\x29\xf6\xd1\xee\xd5\x7f\xb2\xa8\xfa\xe9\x33\x95\x66\x31\x82\x47\x37\x12\x14\x16\x8e\x0a\xca\xda\x05\x6f\x69\xc9\x39\x82\xf1\x34\x28\x1e\x23\x14\xb6\xbc\xdf\x1a\x48\x89\xeb\xad\x6a\x08\x87\xd5\x81\x5a\x76\x1e\xc4\x2a\x22\xd7\xaf\x6c\xdf\x27\xb6\x8b\xbe\x01\x00\x00\xff\xff\x92\x2e\x35\x75\xe2\x00\x00\x00"),
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x65\x90\xb1\x0e\x82\x30\x10\x86\x77\x9e\xe2\x6c\x58\xa5\x71\x33\xa6\xb0\x08\x1b\x89\xc6\xe0\xa0\xdb\x21\x87\x34\x81\x42\x4a\x8d\x51\xc2\xbb\xdb\x0a\x3a\xe8\xd2\xbb\x7e\xfd\xfb\xe5\x5a\xb1\x88\x77\xdb\xec\xb4\x4f\xa0\x32\x4d\x1d\x79\xc2\x15\xa8\x51\x5d\x43\x46\x8a\x39\x40\x58\xd8\xd2\x90\x41\xb8\x54\xa8\x7b\x32\x21\xbb\x99\x72\xb9\x76\xa7\x46\x9a\x9a\xa2\x61\x80\x20\x73\x1d\x8c\xa3\xe0\x13\xf3\x04\x9f\xaf\xe6\x6d\xf1\x70\xa2\xd5\x4f\xce\x02\xcf\x12\x59\x42\x70\x96\xdd\xf1\x90\x3a\x8a\x50\x69\x2a\x43\xe6\xa2\x5f\xca\xa2\xb8\xbd\xab\xba\xc5\x02\xb0\x87\xa7\xec\x04\xc7\x48\xe4\x1a\xf8\xbc\x3a\x0f\xa9\xc2\x46\x6d\xa3\xed\xf4\x04\xbe\x84\x4d\x08\x41\xa2\x8c\x96\xd4\xff\xa8\x7d\x19\x7c\xcc\xd3\x2e\x25\x2c\xdf\x43\xe1\x9f\x51\xf0\xf9\x01\x7c\xfa\xa2\x17\x71\x18\xc3\xf4\x33\x01\x00\x00"),
 		},
 	}
 	fs["/"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
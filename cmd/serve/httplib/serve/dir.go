@@ -25,6 +25,7 @@ type Directory struct {
 	Title        string
 	Entries      []DirEntry
 	Query        string
+	ZipURL       string // if set a link to download the directory as a zip archive
 	HTMLTemplate *template.Template
 }
 
@@ -0,0 +1,144 @@
+package serve
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+)
+
+// ZipQuery is the query string to add to the URL of a directory to
+// download it as a zip archive
+const ZipQuery = "download=zip"
+
+// IsZipRequest returns true if r is asking for a directory as a zip
+// archive
+func IsZipRequest(r *http.Request) bool {
+	return (r.Method == "GET" || r.Method == "HEAD") && r.URL.Query().Get("download") == "zip"
+}
+
+// ZipHandler serves the requests asking for a directory of VFS as a
+// zip archive and passes all the others on to next.
+func ZipHandler(VFS *vfs.VFS, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsZipRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		dirRemote := strings.Trim(r.URL.Path, "/")
+		node, err := VFS.Stat(dirRemote)
+		if err == vfs.ENOENT {
+			http.Error(w, "Directory not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			Error(dirRemote, w, "Failed to find directory", err)
+			return
+		}
+		if !node.IsDir() {
+			http.Error(w, "Not a directory", http.StatusNotFound)
+			return
+		}
+		ZipDir(w, r, node.(*vfs.Dir))
+	})
+}
+
+// ZipDir streams the contents of dir and its subdirectories as a zip
+// archive.
+//
+// The archive is made on the fly as the files are read so the files
+// are stored without compression to keep up with the remote.
+func ZipDir(w http.ResponseWriter, r *http.Request, dir *vfs.Dir) {
+	dirRemote := dir.Path()
+	name := path.Base(dirRemote)
+	if dirRemote == "" {
+		name = "root"
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".zip"))
+	if r.Method == "HEAD" {
+		return
+	}
+
+	fs.Infof(dirRemote, "%s: Serving directory as zip", r.RemoteAddr)
+	zw := zip.NewWriter(w)
+	err := zipAddDir(zw, dir, name+"/")
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		// The headers have been sent so all we can do is stop
+		// which leaves the client with a truncated archive
+		fs.CountError(err)
+		fs.Errorf(dirRemote, "%s: Failed to serve directory as zip: %v", r.RemoteAddr, err)
+	}
+}
+
+// zipAddDir adds the contents of dir to zw with names starting prefix
+func zipAddDir(zw *zip.Writer, dir *vfs.Dir, prefix string) error {
+	nodes, err := dir.ReadDirAll()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list %q", dir.Path())
+	}
+	header := &zip.FileHeader{
+		Name: prefix,
+	}
+	header.SetModTime(dir.ModTime())
+	header.SetMode(dir.Mode())
+	_, err = zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		switch x := node.(type) {
+		case *vfs.Dir:
+			err = zipAddDir(zw, x, prefix+x.Name()+"/")
+		case *vfs.File:
+			err = zipAddFile(zw, x, prefix+x.Name())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zipAddFile adds the contents of file to zw as name
+func zipAddFile(zw *zip.Writer, file *vfs.File, name string) (err error) {
+	remote := file.Path()
+	in, err := file.Open(os.O_RDONLY)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %q", remote)
+	}
+	acc := accounting.NewAccountSizeName(in, file.Size(), remote) // account the transfer
+	accounting.Stats.Transferring(remote)
+	defer func() {
+		closeErr := acc.Close()
+		if err == nil && closeErr != nil {
+			err = errors.Wrapf(closeErr, "failed to close %q", remote)
+		}
+		accounting.Stats.DoneTransferring(remote, err == nil)
+	}()
+	header := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Store,
+	}
+	header.SetModTime(file.ModTime())
+	header.SetMode(file.Mode())
+	out, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, acc)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %q", remote)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+// Package userdb implements the --user-file for the serve commands
+// which maps users to passwords and root directories so one server
+// can serve many isolated users.
+package userdb
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fspath"
+	"github.com/ncw/rclone/vfs"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Help contains text describing the user file to add to the command
+// help.
+var Help = `
+#### User file
+
+Use --user-file /path/to/users to serve many users from one rclone,
+each with their own password and root directory.  Users can't see
+outside their root directory.
+
+The file has one user per line in the format
+
+    user:password-hash:root
+
+password-hash is in the same format as an htpasswd file and may be
+MD5, SHA1 or BCrypt.  Bcrypt is recommended.  root is the directory
+the user is confined to, relative to the remote:path being served.
+If it is missing the user's name is used and if it is "/" the user
+can see the whole remote.  The root directory is created when the
+user first logs in if it doesn't exist.
+
+An htpasswd file can be used as a user file - each user will be
+confined to a directory of their own name.  To make one:
+
+    touch users
+    htpasswd -B users user
+    htpasswd -B users anotherUser
+
+Blank lines and lines starting with # are ignored.  The user file can
+be updated while rclone is running.
+`
+
+// ErrBadLogin is returned when the user doesn't exist or the
+// password is wrong
+var ErrBadLogin = errors.New("bad user name or password")
+
+// user is an entry in the user file
+type user struct {
+	name string
+	hash string // htpasswd style password hash
+	root string // root directory, relative to the served remote
+}
+
+// DB is a user database read from a user file
+type DB struct {
+	path    string
+	remote  string // the remote:path f was made from
+	f       fs.Fs
+	opt     *vfs.Options
+	mu      sync.Mutex
+	modTime time.Time           // modification time of the file when read
+	users   map[string]*user    // users by name
+	vfses   map[string]*vfs.VFS // VFS for each root in use
+}
+
+// New reads the user file at path for serving f made from remote
+// with VFS options opt
+func New(path string, remote string, f fs.Fs, opt *vfs.Options) (*DB, error) {
+	db := &DB{
+		path:   path,
+		remote: remote,
+		f:      f,
+		opt:    opt,
+		vfses:  make(map[string]*vfs.VFS),
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read user file")
+	}
+	err = db.load(fi.ModTime())
+	if err != nil {
+		return nil, err
+	}
+	fs.Infof(nil, "Using %q as user file with %d users", path, len(db.users))
+	return db, nil
+}
+
+// load reads the users from the file
+//
+// Call with mu held or before the DB is in use
+func (db *DB) load(modTime time.Time) error {
+	data, err := ioutil.ReadFile(db.path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read user file")
+	}
+	users, err := parse(data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse user file %q", db.path)
+	}
+	db.users = users
+	db.modTime = modTime
+	return nil
+}
+
+// reloadIfNeeded re-reads the user file if it has changed, keeping
+// the old users if it can't be read.
+//
+// Call with mu held
+func (db *DB) reloadIfNeeded() {
+	fi, err := os.Stat(db.path)
+	if err != nil {
+		fs.Errorf(nil, "Failed to check user file: %v", err)
+		return
+	}
+	if fi.ModTime().Equal(db.modTime) {
+		return
+	}
+	err = db.load(fi.ModTime())
+	if err != nil {
+		fs.Errorf(nil, "Keeping old users: %v", err)
+		return
+	}
+	fs.Infof(nil, "Reloaded %q with %d users", db.path, len(db.users))
+}
+
+// parse the contents of a user file
+func parse(data []byte) (map[string]*user, error) {
+	users := make(map[string]*user)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			return nil, errors.Errorf("line %d: must be user:password-hash:root", lineNumber)
+		}
+		u := &user{
+			name: fields[0],
+			hash: fields[1],
+		}
+		if !supportedHash(u.hash) {
+			return nil, errors.Errorf("line %d: unsupported password hash for user %q", lineNumber, u.name)
+		}
+		root := u.name
+		if len(fields) == 3 && fields[2] != "" {
+			root = fields[2]
+		}
+		u.root = cleanRoot(root)
+		if _, found := users[u.name]; found {
+			return nil, errors.Errorf("line %d: duplicate user %q", lineNumber, u.name)
+		}
+		users[u.name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// cleanRoot makes root relative and removes any ".." so the user
+// can't escape from the served remote
+func cleanRoot(root string) string {
+	root = path.Clean("/" + root)
+	return strings.TrimPrefix(root, "/")
+}
+
+// supportedHash returns true if hash is in a format checkPassword
+// understands
+func supportedHash(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2x$", "$2y$", "{SHA}", "$apr1$", "$1$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPassword returns true if password matches the htpasswd style
+// hash
+func checkPassword(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		encoded := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(hash[5:]), []byte(encoded)) == 1
+	case strings.HasPrefix(hash, "$apr1$"), strings.HasPrefix(hash, "$1$"):
+		parts := strings.SplitN(hash, "$", 4)
+		if len(parts) != 4 {
+			return false
+		}
+		magic := "$" + parts[1] + "$"
+		crypted := auth.MD5Crypt([]byte(password), []byte(parts[2]), []byte(magic))
+		return subtle.ConstantTimeCompare([]byte(hash), crypted) == 1
+	}
+	return false
+}
+
+// Check returns nil if the user exists and the password is correct,
+// or ErrBadLogin.
+func (db *DB) Check(name, password string) error {
+	db.mu.Lock()
+	db.reloadIfNeeded()
+	u := db.users[name]
+	db.mu.Unlock()
+	if u == nil || !checkPassword(u.hash, password) {
+		return ErrBadLogin
+	}
+	return nil
+}
+
+// Login checks the user's password and returns the VFS for their
+// root directory.
+func (db *DB) Login(name, password string) (*vfs.VFS, error) {
+	err := db.Check(name, password)
+	if err != nil {
+		return nil, err
+	}
+	return db.VFS(name)
+}
+
+// VFS returns the VFS serving the root directory of the user called
+// name, making it if necessary.
+//
+// Users with the same root directory share a VFS.
+func (db *DB) VFS(name string) (*vfs.VFS, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	u := db.users[name]
+	if u == nil {
+		return nil, ErrBadLogin
+	}
+	if VFS := db.vfses[u.root]; VFS != nil {
+		return VFS, nil
+	}
+	f, err := subFs(db.f, db.remote, u.root)
+	if err != nil {
+		return nil, err
+	}
+	VFS := vfs.New(f, db.opt)
+	db.vfses[u.root] = VFS
+	return VFS, nil
+}
+
+// subFs returns an Fs for dir within f made from remote, creating it
+// if necessary.
+//
+// The new Fs is made from remote rather than the name of f so it has
+// the same config, eg for on the fly remotes.
+func subFs(f fs.Fs, remote string, dir string) (fs.Fs, error) {
+	if dir == "" {
+		return f, nil
+	}
+	configName, fsPath := fspath.Parse(remote)
+	root := path.Join(fsPath, dir)
+	if configName != "" {
+		root = configName + ":" + root
+	}
+	newF, err := fs.NewFs(root)
+	if err == fs.ErrorIsFile {
+		return nil, errors.Errorf("user root %q is a file", dir)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make user root %q", dir)
+	}
+	err = newF.Mkdir("")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create user root %q", dir)
+	}
+	return newF, nil
+}
@@ -0,0 +1,137 @@
+package userdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	auth "github.com/abbot/go-http-auth"
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("potato"), bcrypt.MinCost)
+	require.NoError(t, err)
+	md5Hash := string(auth.MD5Crypt([]byte("potato"), []byte("dlPL2MqE"), []byte("$apr1$")))
+	for _, test := range []struct {
+		hash string
+		want bool
+	}{
+		{string(bcryptHash), true},
+		{md5Hash, true},
+		{"{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=", true}, // sha1 of potato
+		{"{SHA}AAAAAa2XDq36fhfq9z2pcCSqU1k=", false},
+		{"$apr1$broken", false},
+		{"potato", false},
+	} {
+		assert.Equal(t, test.want, checkPassword(test.hash, "potato"), test.hash)
+		assert.False(t, checkPassword(test.hash, "carrot"), test.hash)
+	}
+}
+
+func TestParse(t *testing.T) {
+	users, err := parse([]byte(`
+# comment
+one:{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=
+two:{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=:shared/dir
+three:{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=:../../etc
+four:{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=:/
+`))
+	require.NoError(t, err)
+	require.Len(t, users, 4)
+	assert.Equal(t, "one", users["one"].root)
+	assert.Equal(t, "shared/dir", users["two"].root)
+	assert.Equal(t, "etc", users["three"].root)
+	assert.Equal(t, "", users["four"].root)
+
+	for _, bad := range []string{
+		"one",
+		"one:",
+		":{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=",
+		"one:plaintext",
+		"one:{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=\none:{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=",
+	} {
+		_, err := parse([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-userdb")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	served := filepath.Join(dir, "served")
+	require.NoError(t, os.Mkdir(served, 0777))
+	f, err := fs.NewFs(served)
+	require.NoError(t, err)
+
+	userFile := filepath.Join(dir, "users")
+	const sha = "{SHA}Pi6V9a2XDq36fhfq9z2pcCSqU1k=" // potato
+	require.NoError(t, ioutil.WriteFile(userFile, []byte("one:"+sha+"\ntwo:"+sha+":one\n"), 0600))
+
+	db, err := New(userFile, served, f, &vfs.DefaultOpt)
+	require.NoError(t, err)
+
+	_, err = db.Login("one", "carrot")
+	assert.Equal(t, ErrBadLogin, err)
+	_, err = db.Login("potato", "potato")
+	assert.Equal(t, ErrBadLogin, err)
+
+	one, err := db.Login("one", "potato")
+	require.NoError(t, err)
+	fi, err := os.Stat(filepath.Join(served, "one"))
+	require.NoError(t, err, "root directory should be created")
+	assert.True(t, fi.IsDir())
+
+	two, err := db.Login("two", "potato")
+	require.NoError(t, err)
+	assert.True(t, one == two, "same root should share a VFS")
+
+	// Change the file and check it is reloaded
+	require.NoError(t, ioutil.WriteFile(userFile, []byte("three:"+sha+"\n"), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(userFile, future, future))
+	_, err = db.Login("one", "potato")
+	assert.Equal(t, ErrBadLogin, err)
+	three, err := db.Login("three", "potato")
+	require.NoError(t, err)
+	assert.False(t, one == three)
+
+	// A broken file keeps the old users
+	require.NoError(t, ioutil.WriteFile(userFile, []byte("broken\n"), 0600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(userFile, future, future))
+	assert.NoError(t, db.Check("three", "potato"))
+}
+
+func TestSubFs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-userdb")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	// The user root is made from the on the fly remote
+	remote := ":local:" + filepath.ToSlash(dir)
+	f, err := fs.NewFs(remote)
+	require.NoError(t, err)
+	subF, err := subFs(f, remote, "one")
+	require.NoError(t, err)
+	assert.Equal(t, ":local", subF.Name())
+	fi, err := os.Stat(filepath.Join(dir, "one"))
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	same, err := subFs(f, remote, "")
+	require.NoError(t, err)
+	assert.True(t, f == same)
+}
@@ -5,15 +5,19 @@ package webdav
 import (
 	"net/http"
 	"os"
+	"sync"
 
 	"github.com/ncw/rclone/cmd"
 	"github.com/ncw/rclone/cmd/serve/httplib"
 	"github.com/ncw/rclone/cmd/serve/httplib/httpflags"
+	"github.com/ncw/rclone/cmd/serve/httplib/serve"
+	"github.com/ncw/rclone/cmd/serve/userdb"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/log"
 	"github.com/ncw/rclone/vfs"
 	"github.com/ncw/rclone/vfs/vfsflags"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context" // switch to "context" when we stop supporting go1.8
 	"golang.org/x/net/webdav"
@@ -22,12 +26,14 @@ import (
 var (
 	hashName string
 	hashType = hash.None
+	userFile string
 )
 
 func init() {
 	httpflags.AddFlags(Command.Flags())
 	vfsflags.AddFlags(Command.Flags())
 	Command.Flags().StringVar(&hashName, "etag-hash", "", "Which hash to use for the ETag, or auto or blank for off")
+	Command.Flags().StringVar(&userFile, "user-file", "", "File of users with their password hashes and root directories.")
 }
 
 // Command definition for cobra
@@ -53,7 +59,17 @@ supported hash on the backend or you can use a named hash such as
 
 Use "rclone hashsum" to see the full list.
 
-` + httplib.Help + vfs.Help,
+### Downloading directories as zip
+
+A GET request for a directory with ?download=zip added to the URL,
+eg http://localhost:8080/path/to/dir/?download=zip, downloads the
+directory and everything in it as a zip archive.  This is useful for
+fetching whole directories with a web browser.  The archive is made
+on the fly so the files are stored uncompressed.
+
+` + httplib.Help + userdb.Help + `
+--user-file can't be used with --htpasswd or --user.
+` + vfs.Help,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(1, 1, command, args)
 		f := cmd.NewFsSrc(args)
@@ -70,8 +86,11 @@ Use "rclone hashsum" to see the full list.
 			fs.Debugf(f, "Using hash %v for ETag", hashType)
 		}
 		cmd.Run(false, false, command, func() error {
-			s := newWebDAV(f, &httpflags.Opt)
-			err := s.serve()
+			s, err := newWebDAV(f, args[0], &httpflags.Opt, userFile)
+			if err != nil {
+				return err
+			}
+			err = s.serve()
 			if err != nil {
 				return err
 			}
@@ -98,26 +117,92 @@ type WebDAV struct {
 	*httplib.Server
 	f   fs.Fs
 	vfs *vfs.VFS
+
+	// for --user-file
+	users    *userdb.DB
+	realm    string
+	mu       sync.Mutex
+	handlers map[*vfs.VFS]*webdav.Handler // handler for each user VFS
 }
 
 // check interface
 var _ webdav.FileSystem = (*WebDAV)(nil)
 
-// Make a new WebDAV to serve the remote
-func newWebDAV(f fs.Fs, opt *httplib.Options) *WebDAV {
+// Make a new WebDAV to serve the remote f made from remote
+//
+// If userFile is set then each user is served their own root
+// directory from it.
+func newWebDAV(f fs.Fs, remote string, opt *httplib.Options, userFile string) (*WebDAV, error) {
 	w := &WebDAV{
-		f:   f,
-		vfs: vfs.New(f, &vfsflags.Opt),
+		f: f,
 	}
 
-	handler := &webdav.Handler{
-		FileSystem: w,
+	var handler http.Handler
+	if userFile != "" {
+		if opt.HtPasswd != "" || opt.BasicUser != "" {
+			return nil, errors.New("can't use --user-file with --htpasswd or --user")
+		}
+		users, err := userdb.New(userFile, remote, f, &vfsflags.Opt)
+		if err != nil {
+			return nil, err
+		}
+		w.users = users
+		w.realm = opt.Realm
+		w.handlers = make(map[*vfs.VFS]*webdav.Handler)
+		handler = http.HandlerFunc(w.serveUser)
+	} else {
+		w.vfs = vfs.New(f, &vfsflags.Opt)
+		handler = serve.ZipHandler(w.vfs, w.newHandler(w))
+	}
+
+	w.Server = httplib.NewServer(handler, opt)
+	return w, nil
+}
+
+// newHandler makes a webdav handler serving fileSystem
+func (w *WebDAV) newHandler(fileSystem webdav.FileSystem) *webdav.Handler {
+	return &webdav.Handler{
+		FileSystem: fileSystem,
 		LockSystem: webdav.NewMemLS(),
 		Logger:     w.logRequest, // FIXME
 	}
+}
 
-	w.Server = httplib.NewServer(handler, opt)
-	return w
+// userHandler returns the webdav handler for the VFS of a user,
+// making it if necessary
+func (w *WebDAV) userHandler(VFS *vfs.VFS) *webdav.Handler {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	handler := w.handlers[VFS]
+	if handler == nil {
+		handler = w.newHandler(&WebDAV{f: w.f, vfs: VFS})
+		w.handlers[VFS] = handler
+	}
+	return handler
+}
+
+// serveUser authenticates the request against the --user-file and
+// serves it from the user's root directory
+func (w *WebDAV) serveUser(rw http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		fs.Infof(r.URL.Path, "%s: Basic auth challenge sent", r.RemoteAddr)
+		w.requireAuth(rw)
+		return
+	}
+	VFS, err := w.users.Login(user, pass)
+	if err != nil {
+		fs.Infof(r.URL.Path, "%s: Unauthorized request from %s: %v", r.RemoteAddr, user, err)
+		w.requireAuth(rw)
+		return
+	}
+	serve.ZipHandler(VFS, w.userHandler(VFS)).ServeHTTP(rw, r)
+}
+
+// requireAuth sends a basic auth challenge
+func (w *WebDAV) requireAuth(rw http.ResponseWriter) {
+	rw.Header().Set("WWW-Authenticate", `Basic realm="`+w.realm+`"`)
+	http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 }
 
 // serve runs the http server in the background.
@@ -39,7 +39,7 @@ func TestWebDav(t *testing.T) {
 
 	fstest.Initialise()
 
-	fremote, _, clean, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
+	fremote, remoteName, clean, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
 	assert.NoError(t, err)
 	defer clean()
 
@@ -47,7 +47,8 @@ func TestWebDav(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Start the server
-	w := newWebDAV(fremote, &opt)
+	w, err := newWebDAV(fremote, remoteName, &opt, "")
+	assert.NoError(t, err)
 	assert.NoError(t, w.serve())
 	defer func() {
 		w.Close()
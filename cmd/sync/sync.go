@@ -6,8 +6,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Globals
+var (
+	createEmptySrcDirs = false
+)
+
 func init() {
 	cmd.Root.AddCommand(commandDefintion)
+	commandDefintion.Flags().BoolVarP(&createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after sync")
 }
 
 var commandDefintion = &cobra.Command{
@@ -33,13 +39,16 @@ extended explanation in the ` + "`" + `copy` + "`" + ` command above if unsure.
 If dest:path doesn't exist, it is created and the source:path contents
 go there.
 
+If you want empty source directories to be created on the destination,
+use the --create-empty-src-dirs flag.
+
 **Note**: Use the ` + "`-P`" + `/` + "`--progress`" + ` flag to view real-time transfer statistics
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, fdst := cmd.NewFsSrcDst(args)
 		cmd.Run(true, true, command, func() error {
-			return sync.Sync(fdst, fsrc)
+			return sync.Sync(fdst, fsrc, createEmptySrcDirs)
 		})
 	},
 }
@@ -0,0 +1,232 @@
+// Package connectivity provides the "rclone test connectivity"
+// command which checks a remote is working end to end.
+package connectivity
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/test"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/object"
+	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags
+	fileSize = fs.SizeSuffix(1024)
+	output   = ""
+)
+
+func init() {
+	test.Command.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.FVarP(cmdFlags, &fileSize, "size", "", "Size of the test file to write")
+	flags.StringVarP(cmdFlags, &output, "output", "o", output, "Write the JSON result to this file instead of stdout")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "connectivity remote:path",
+	Short: `Check a remote works by writing, reading and deleting a file.`,
+	Long: `rclone test connectivity checks that remote:path is working end to end
+so that problems can be found before a backup using it fails.
+
+It runs these steps in order, stopping at the first one which fails.
+
+  * auth - make the remote, which logs in to it if needed
+  * list - list remote:path
+  * write - upload a small file of random data to remote:path
+  * read - download the file and check it is the same
+  * hash - check the hashes the remote has for the file are correct
+  * delete - delete the file
+
+The file is deleted even if the read or hash steps fail.  Use --size
+to set how big it is (default 1k).
+
+The result is written as JSON to stdout (or to the file given with
+--output) for monitoring systems to read, eg
+
+    {
+        "remote": "remote:path",
+        "ok": true,
+        "time": "2019-03-01T12:00:00.123456789Z",
+        "duration": 1.234,
+        "steps": [
+            {"name": "auth", "ok": true, "duration": 0.012},
+            {"name": "list", "ok": true, "duration": 0.345},
+            ...
+        ]
+    }
+
+Durations are in seconds.  A step which wasn't run has "skipped" set
+and one which failed has "error" set.  If any of the steps failed
+then "ok" is false and rclone exits with a non zero exit code.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		cmd.Run(false, false, command, func() error {
+			r := checkConnectivity(args[0], int64(fileSize))
+			err := writeResult(r)
+			if err != nil {
+				return err
+			}
+			if !r.OK {
+				return errors.Errorf("connectivity test of %q failed", args[0])
+			}
+			return nil
+		})
+	},
+}
+
+// step is the result of one step of the test
+type step struct {
+	Name     string  `json:"name"`
+	OK       bool    `json:"ok"`
+	Skipped  bool    `json:"skipped,omitempty"`
+	Duration float64 `json:"duration"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// result is the result of the test
+type result struct {
+	Remote   string    `json:"remote"`
+	OK       bool      `json:"ok"`
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration"`
+	Steps    []step    `json:"steps"`
+	failed   bool      // set once a step has failed
+}
+
+// run runs the step called name with fn unless a previous step has
+// failed.  If always is set then it is run anyway.
+func (r *result) run(name string, always bool, fn func() error) bool {
+	s := step{Name: name}
+	if r.failed && !always {
+		s.Skipped = true
+		r.Steps = append(r.Steps, s)
+		return false
+	}
+	start := time.Now()
+	err := fn()
+	s.Duration = time.Since(start).Seconds()
+	if err != nil {
+		s.Error = err.Error()
+		r.failed = true
+		fs.Errorf(r.Remote, "connectivity: %s failed: %v", name, err)
+	} else {
+		s.OK = true
+		fs.Infof(r.Remote, "connectivity: %s OK in %.3fs", name, s.Duration)
+	}
+	r.Steps = append(r.Steps, s)
+	return s.OK
+}
+
+// checkConnectivity runs the steps of the test on remote writing a
+// file of size bytes
+func checkConnectivity(remote string, size int64) *result {
+	r := &result{
+		Remote: remote,
+		Time:   time.Now(),
+	}
+	var (
+		f    fs.Fs
+		o    fs.Object
+		data = make([]byte, size)
+		name = "rclone-connectivity-" + fstest.RandomString(16)
+	)
+	_, _ = rand.Read(data)
+	r.run("auth", false, func() (err error) {
+		f, err = fs.NewFs(remote)
+		if err == fs.ErrorIsFile {
+			return errors.New("remote:path is a file, not a directory")
+		}
+		return err
+	})
+	r.run("list", false, func() error {
+		_, err := f.List("")
+		if err == fs.ErrorDirNotFound {
+			// the write will create it
+			return nil
+		}
+		return err
+	})
+	r.run("write", false, func() (err error) {
+		src := object.NewStaticObjectInfo(name, time.Now(), size, true, nil, f)
+		o, err = f.Put(bytes.NewReader(data), src)
+		if err != nil {
+			return err
+		}
+		if o.Size() != size {
+			return errors.Errorf("wrote %d bytes but object is %d bytes", size, o.Size())
+		}
+		return nil
+	})
+	r.run("read", false, func() error {
+		in, err := o.Open()
+		if err != nil {
+			return err
+		}
+		got, err := ioutil.ReadAll(in)
+		closeErr := in.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+		if !bytes.Equal(got, data) {
+			return errors.Errorf("read back %d bytes which don't match the %d written", len(got), len(data))
+		}
+		return nil
+	})
+	r.run("hash", false, func() error {
+		hasher, err := hash.NewMultiHasherTypes(f.Hashes())
+		if err != nil {
+			return err
+		}
+		_, _ = hasher.Write(data)
+		for ht, want := range hasher.Sums() {
+			got, err := o.Hash(ht)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read %v", ht)
+			}
+			if got != "" && got != want {
+				return errors.Errorf("%v is %q but should be %q", ht, got, want)
+			}
+		}
+		return nil
+	})
+	r.run("delete", o != nil, func() error {
+		return o.Remove()
+	})
+	r.OK = !r.failed
+	r.Duration = time.Since(r.Time).Seconds()
+	return r
+}
+
+// writeResult writes r as JSON to stdout or --output
+func writeResult(r *result) error {
+	out, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return errors.Wrap(err, "failed to make JSON")
+	}
+	out = append(out, '\n')
+	if output != "" {
+		err = ioutil.WriteFile(output, out, 0666)
+		if err != nil {
+			return errors.Wrap(err, "failed to write JSON")
+		}
+		return nil
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,96 @@
+package connectivity
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stepNames returns the names of the steps in r which are ok, skipped
+// or failed
+func stepNames(r *result) (ok, skipped, failed []string) {
+	for _, s := range r.Steps {
+		switch {
+		case s.OK:
+			ok = append(ok, s.Name)
+		case s.Skipped:
+			skipped = append(skipped, s.Name)
+		default:
+			failed = append(failed, s.Name)
+		}
+	}
+	return ok, skipped, failed
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-connectivity")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	r := checkConnectivity(dir, 1024)
+	assert.True(t, r.OK)
+	assert.Equal(t, dir, r.Remote)
+	ok, skipped, failed := stepNames(r)
+	assert.Equal(t, []string{"auth", "list", "write", "read", "hash", "delete"}, ok)
+	assert.Nil(t, skipped)
+	assert.Nil(t, failed)
+
+	// the test file should have been deleted
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	// a directory which doesn't exist yet is created by the write
+	r = checkConnectivity(filepath.Join(dir, "sub"), 0)
+	assert.True(t, r.OK)
+}
+
+func TestCheckConnectivityFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-connectivity")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, []byte("hello"), 0666))
+
+	r := checkConnectivity(file, 1024)
+	assert.False(t, r.OK)
+	ok, skipped, failed := stepNames(r)
+	assert.Nil(t, ok)
+	assert.Equal(t, []string{"list", "write", "read", "hash", "delete"}, skipped)
+	assert.Equal(t, []string{"auth"}, failed)
+	assert.Equal(t, "remote:path is a file, not a directory", r.Steps[0].Error)
+}
+
+func TestWriteResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-connectivity")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	oldOutput := output
+	output = filepath.Join(dir, "result.json")
+	defer func() { output = oldOutput }()
+
+	r := checkConnectivity(dir, 16)
+	require.NoError(t, writeResult(r))
+
+	data, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, dir, got["remote"])
+	assert.Equal(t, true, got["ok"])
+	steps, ok := got["steps"].([]interface{})
+	require.True(t, ok)
+	assert.Len(t, steps, 6)
+}
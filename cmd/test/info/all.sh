@@ -1,5 +1,5 @@
 #!/usr/bin/env bash
-exec rclone --check-normalization=true --check-control=true --check-length=true info \
+exec rclone --check-normalization=true --check-control=true --check-length=true test info \
 	/tmp/testInfo \
 	TestAmazonCloudDrive:testInfo \
 	TestB2:testInfo \
@@ -5,14 +5,17 @@ package info
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/test"
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/object"
@@ -36,26 +39,39 @@ var (
 	checkControl       bool
 	checkLength        bool
 	checkStreaming     bool
+	checkCase          bool
+	checkModTime       bool
+	writeJSON          string
 	positionList       = []position{positionMiddle, positionLeft, positionRight}
 )
 
 func init() {
-	cmd.Root.AddCommand(commandDefintion)
+	test.Command.AddCommand(commandDefintion)
 	commandDefintion.Flags().BoolVarP(&checkNormalization, "check-normalization", "", true, "Check UTF-8 Normalization.")
 	commandDefintion.Flags().BoolVarP(&checkControl, "check-control", "", true, "Check control characters.")
 	commandDefintion.Flags().BoolVarP(&checkLength, "check-length", "", true, "Check max filename length.")
 	commandDefintion.Flags().BoolVarP(&checkStreaming, "check-streaming", "", true, "Check uploads with indeterminate file size.")
+	commandDefintion.Flags().BoolVarP(&checkCase, "check-case", "", true, "Check whether file names are case sensitive.")
+	commandDefintion.Flags().BoolVarP(&checkModTime, "check-modtime", "", true, "Check the precision of modification times.")
+	commandDefintion.Flags().StringVarP(&writeJSON, "write-json", "", "", "Write the results as JSON to this file.")
 }
 
 var commandDefintion = &cobra.Command{
 	Use:   "info [remote:path]+",
 	Short: `Discovers file name or other limitations for paths.`,
-	Long: `rclone info discovers what filenames and upload methods are possible
+	Long: `rclone test info discovers what filenames and upload methods are possible
 to write to the paths passed in and how long they can be.  It can take some
 time.  It will write test files into the remote:path passed in.  It outputs
 a bit of go code for each one.
+
+It also checks whether file names are case sensitive and how precise
+the modification times are, and compares these with what the backend
+claims.
+
+From the characters which couldn't be written it suggests the
+encoder flags the backend needs.  Use --write-json to save the
+results as JSON for processing by other tools.
 `,
-	Hidden: true,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1E6, command, args)
 		for i := range args {
@@ -76,6 +92,9 @@ type results struct {
 	canReadUnnormalized  bool
 	canReadRenormalized  bool
 	canStream            bool
+	caseInsensitive      bool
+	modTimePrecision     time.Duration
+	modTimeSupported     bool
 }
 
 func newResults(f fs.Fs) *results {
@@ -111,6 +130,125 @@ func (r *results) Print() {
 	if checkStreaming {
 		fmt.Printf("canStream = %v\n", r.canStream)
 	}
+	if checkCase {
+		fmt.Printf("caseInsensitive = %v // backend says %v\n", r.caseInsensitive, r.f.Features().CaseInsensitive)
+	}
+	if checkModTime {
+		if r.modTimeSupported {
+			fmt.Printf("modTimePrecision = %v // backend says %v\n", r.modTimePrecision, r.f.Precision())
+		} else {
+			fmt.Printf("modTimePrecision = fs.ModTimeNotSupported // backend says %v\n", r.f.Precision())
+		}
+	}
+	if checkControl {
+		flags, unhandled := r.suggestEncoder()
+		fmt.Printf("encoder = %s\n", strings.Join(flags, " |\n\t"))
+		if len(unhandled) > 0 {
+			fmt.Printf("// no encoder flag for %s\n", strings.Join(unhandled, ", "))
+		}
+	}
+}
+
+// jsonResults is the format of the results written by --write-json
+type jsonResults struct {
+	Remote               string            `json:"remote"`
+	StringNeedsEscaping  map[string]string `json:"stringNeedsEscaping,omitempty"`
+	MaxFileLength        int               `json:"maxFileLength,omitempty"`
+	CanWriteUnnormalized bool              `json:"canWriteUnnormalized"`
+	CanReadUnnormalized  bool              `json:"canReadUnnormalized"`
+	CanReadRenormalized  bool              `json:"canReadRenormalized"`
+	CanStream            bool              `json:"canStream"`
+	CaseInsensitive      bool              `json:"caseInsensitive"`
+	ModTimeSupported     bool              `json:"modTimeSupported"`
+	ModTimePrecision     string            `json:"modTimePrecision,omitempty"`
+	Encoder              []string          `json:"encoder,omitempty"`
+	Unhandled            []string          `json:"unhandled,omitempty"`
+}
+
+// WriteJSON writes the results as JSON to path
+func (r *results) WriteJSON(path string) error {
+	out := jsonResults{
+		Remote:               r.f.Name() + ":" + r.f.Root(),
+		StringNeedsEscaping:  make(map[string]string),
+		MaxFileLength:        r.maxFileLength,
+		CanWriteUnnormalized: r.canWriteUnnormalized,
+		CanReadUnnormalized:  r.canReadUnnormalized,
+		CanReadRenormalized:  r.canReadRenormalized,
+		CanStream:            r.canStream,
+		CaseInsensitive:      r.caseInsensitive,
+		ModTimeSupported:     r.modTimeSupported,
+	}
+	for s, pos := range r.stringNeedsEscaping {
+		if pos != positionNone {
+			out.StringNeedsEscaping[fmt.Sprintf("0x%02X", s)] = pos.String()
+		}
+	}
+	if r.modTimeSupported {
+		out.ModTimePrecision = r.modTimePrecision.String()
+	}
+	if checkControl {
+		out.Encoder, out.Unhandled = r.suggestEncoder()
+	}
+	data, err := json.MarshalIndent(out, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0666)
+}
+
+// encoderFlags maps the characters checked to the encoder flags
+// which escape them in the given positions.
+//
+// NUL isn't here as it is always encoded - encoder.EncodeZero is 0.
+var encoderFlags = []struct {
+	chars    string
+	position position
+	flag     string
+}{
+	{"/", positionAll, "encoder.EncodeSlash"},
+	{":?\"*<>|", positionAll, "encoder.EncodeWin"},
+	{"\\", positionAll, "encoder.EncodeBackSlash"},
+	{"#%", positionAll, "encoder.EncodeHashPercent"},
+	{"\x7F", positionAll, "encoder.EncodeDel"},
+	{"\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0A\x0B\x0C\x0D\x0E\x0F\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19\x1A\x1B\x1C\x1D\x1E\x1F", positionAll, "encoder.EncodeCtl"},
+	{" ", positionLeft, "encoder.EncodeLeftSpace"},
+	{"~", positionLeft, "encoder.EncodeLeftTilde"},
+	{" ", positionRight, "encoder.EncodeRightSpace"},
+	{".", positionRight, "encoder.EncodeRightPeriod"},
+	{"\xBF\xFE", positionAll, "encoder.EncodeInvalidUtf8"},
+}
+
+// suggestEncoder returns the encoder flags needed to escape the
+// characters which couldn't be written and the characters (in the
+// positions) which none of the flags escape.
+func (r *results) suggestEncoder() (flags []string, unhandled []string) {
+	for s, pos := range r.stringNeedsEscaping {
+		if s == "\x00" {
+			// NUL is always encoded so needs no flag
+			continue
+		}
+		for _, enc := range encoderFlags {
+			if pos == positionNone {
+				break
+			}
+			if strings.Contains(enc.chars, s) {
+				pos &^= enc.position
+			}
+		}
+		if pos != positionNone {
+			unhandled = append(unhandled, fmt.Sprintf("0x%02X (%v)", s, pos))
+		}
+	}
+	for _, enc := range encoderFlags {
+		for s, pos := range r.stringNeedsEscaping {
+			if pos&enc.position != positionNone && strings.Contains(enc.chars, s) {
+				flags = append(flags, enc.flag)
+				break
+			}
+		}
+	}
+	sort.Strings(unhandled)
+	return flags, unhandled
 }
 
 // writeFile writes a file with some random contents
@@ -292,6 +430,49 @@ func (r *results) checkStreaming() {
 	r.canStream = true
 }
 
+// check whether file names differing only in case are the same file
+func (r *results) checkCaseSensitivity() {
+	_, err := r.writeFile("case-Sensitive")
+	if err != nil {
+		fs.Errorf(r.f, "Couldn't write file to check case sensitivity: %v", err)
+		return
+	}
+	_, err = r.f.NewObject("CASE-sENSITIVE")
+	r.caseInsensitive = err == nil
+	fs.Infof(r.f, "Case insensitive is %v", r.caseInsensitive)
+}
+
+// check how precisely modification times are stored by writing a
+// file with a modification time with all the digits set
+func (r *results) checkModTimePrecision() {
+	modTime := time.Date(2009, 11, 10, 23, 0, 1, 123456789, time.UTC)
+	contents := fstest.RandomString(50)
+	src := object.NewStaticObjectInfo("modtime-precision", modTime, int64(len(contents)), true, nil, r.f)
+	_, err := r.f.Put(bytes.NewBufferString(contents), src)
+	if err != nil {
+		fs.Errorf(r.f, "Couldn't write file to check modification time precision: %v", err)
+		return
+	}
+	// Read the object back as some backends don't update it
+	o, err := r.f.NewObject("modtime-precision")
+	if err != nil {
+		fs.Errorf(r.f, "Couldn't read file to check modification time precision: %v", err)
+		return
+	}
+	dt := o.ModTime().Sub(modTime)
+	if dt < 0 {
+		dt = -dt
+	}
+	for _, precision := range []time.Duration{time.Nanosecond, time.Microsecond, time.Millisecond, time.Second, 2 * time.Second} {
+		if dt < precision || (precision == time.Nanosecond && dt == 0) {
+			r.modTimeSupported = true
+			r.modTimePrecision = precision
+			break
+		}
+	}
+	fs.Infof(r.f, "Modification time read back differs by %v", dt)
+}
+
 func readInfo(f fs.Fs) error {
 	err := f.Mkdir("")
 	if err != nil {
@@ -310,7 +491,19 @@ func readInfo(f fs.Fs) error {
 	if checkStreaming {
 		r.checkStreaming()
 	}
+	if checkCase {
+		r.checkCaseSensitivity()
+	}
+	if checkModTime {
+		r.checkModTimePrecision()
+	}
 	r.Print()
+	if writeJSON != "" {
+		err = r.WriteJSON(writeJSON)
+		if err != nil {
+			return errors.Wrap(err, "failed to write results")
+		}
+	}
 	return nil
 }
 
@@ -1,3 +1,3 @@
 rclone.exe purge    info
-rclone.exe info -vv info > info-LocalWindows.log  2>&1
+rclone.exe test info -vv info > info-LocalWindows.log  2>&1
 rclone.exe ls   -vv info > info-LocalWindows.list 2>&1
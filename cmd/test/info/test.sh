@@ -1,8 +1,8 @@
 #!/usr/bin/env zsh
 #
 # example usage: 
-# $GOPATH/src/github.com/ncw/rclone/cmd/info/test.sh --list | \
-#   parallel -P20 $GOPATH/src/github.com/ncw/rclone/cmd/info/test.sh
+# $GOPATH/src/github.com/ncw/rclone/cmd/test/info/test.sh --list | \
+#   parallel -P20 $GOPATH/src/github.com/ncw/rclone/cmd/test/info/test.sh
 
 export PATH=$GOPATH/src/github.com/ncw/rclone:$PATH
 
@@ -38,6 +38,6 @@ for remote; do
     dir=infotest
   fi
   rclone purge    $dir || :
-  rclone info -vv $dir ${=allRemotes[$remote]} &> info-$remote.log
+  rclone test info -vv $dir ${=allRemotes[$remote]} &> info-$remote.log
   rclone ls   -vv $dir &> info-$remote.list
 done
\ No newline at end of file
@@ -0,0 +1,174 @@
+// Package makefiles builds a directory structure with the required
+// number of files in of the required size.
+package makefiles
+
+import (
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/ncw/rclone/cmd"
+	"github.com/ncw/rclone/cmd/test"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Flags
+	numberOfFiles            = 1000
+	averageFilesPerDirectory = 10
+	maxDepth                 = 10
+	minFileSize              = fs.SizeSuffix(0)
+	maxFileSize              = fs.SizeSuffix(100)
+	minFileNameLength        = 4
+	maxFileNameLength        = 12
+	seed                     = int64(1)
+
+	// Globals
+	randSource          *rand.Rand
+	directoriesToCreate int
+	totalDirectories    int
+	fileNames           = map[string]struct{}{} // keep a note of which file name we've used already
+)
+
+func init() {
+	test.Command.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.IntVarP(cmdFlags, &numberOfFiles, "files", "", numberOfFiles, "Number of files to create")
+	flags.IntVarP(cmdFlags, &averageFilesPerDirectory, "files-per-directory", "", averageFilesPerDirectory, "Average number of files per directory")
+	flags.IntVarP(cmdFlags, &maxDepth, "max-depth", "", maxDepth, "Maximum depth of directory hierarchy")
+	flags.FVarP(cmdFlags, &minFileSize, "min-file-size", "", "Minimum size of file to create")
+	flags.FVarP(cmdFlags, &maxFileSize, "max-file-size", "", "Maximum size of files to create")
+	flags.IntVarP(cmdFlags, &minFileNameLength, "min-name-length", "", minFileNameLength, "Minimum size of file names")
+	flags.IntVarP(cmdFlags, &maxFileNameLength, "max-name-length", "", maxFileNameLength, "Maximum size of file names")
+	cmdFlags.Int64VarP(&seed, "seed", "", seed, "Seed for the random number generator (0 for random)")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "makefiles <dir>",
+	Short: `Make a random file hierarchy in <dir>`,
+	Long: `Make a random file hierarchy in the local directory <dir> for
+benchmarking and testing.
+
+The tree has --files files of random sizes and names in directories
+with on average --files-per-directory files each.  With the same
+--seed the same tree is made every time.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		if seed == 0 {
+			seed = rand.Int63()
+			fs.Logf(nil, "Using random seed = %d", seed)
+		}
+		randSource = rand.New(rand.NewSource(seed))
+		if averageFilesPerDirectory < 1 {
+			log.Fatalf("--files-per-directory must be at least 1")
+		}
+		if minFileNameLength < 1 || maxFileNameLength < minFileNameLength {
+			log.Fatalf("--min-name-length must be at least 1 and not more than --max-name-length")
+		}
+		if minFileSize > maxFileSize {
+			log.Fatalf("--min-file-size must not be more than --max-file-size")
+		}
+		outputDirectory := args[0]
+		directoriesToCreate = numberOfFiles / averageFilesPerDirectory
+		if directoriesToCreate < 1 {
+			directoriesToCreate = 1
+		}
+		fs.Logf(nil, "Creating %d files of average size %v in %d directories in %q.", numberOfFiles, (minFileSize+maxFileSize)/2, directoriesToCreate, outputDirectory)
+		root := &dir{name: outputDirectory, depth: 1}
+		for totalDirectories < directoriesToCreate {
+			root.createDirectories()
+		}
+		dirs := root.list("", []string{})
+		for i := 0; i < numberOfFiles; i++ {
+			dir := dirs[randSource.Intn(len(dirs))]
+			writeFile(dir, fileName())
+		}
+		fs.Logf(nil, "Done.")
+	},
+}
+
+// fileName creates a unique random file or directory name
+func fileName() (name string) {
+	for {
+		length := randSource.Intn(maxFileNameLength-minFileNameLength+1) + minFileNameLength
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = 'a' + byte(randSource.Intn(26))
+		}
+		name = string(b)
+		if _, found := fileNames[name]; !found {
+			break
+		}
+	}
+	fileNames[name] = struct{}{}
+	return name
+}
+
+// dir is a directory in the directory hierarchy being built up
+type dir struct {
+	name     string
+	depth    int
+	children []*dir
+	parent   *dir
+}
+
+// Create a random directory hierarchy under d
+func (d *dir) createDirectories() {
+	for totalDirectories < directoriesToCreate {
+		newDir := &dir{
+			name:   fileName(),
+			depth:  d.depth + 1,
+			parent: d,
+		}
+		d.children = append(d.children, newDir)
+		totalDirectories++
+		switch randSource.Intn(4) {
+		case 0:
+			if d.depth < maxDepth {
+				newDir.createDirectories()
+			}
+		case 1:
+			return
+		}
+	}
+}
+
+// list the directory hierarchy
+func (d *dir) list(path string, output []string) []string {
+	dirPath := filepath.Join(path, d.name)
+	output = append(output, dirPath)
+	for _, subDir := range d.children {
+		output = subDir.list(dirPath, output)
+	}
+	return output
+}
+
+// writeFile writes a random file at dir/name
+func writeFile(dir, name string) {
+	err := os.MkdirAll(dir, 0777)
+	if err != nil {
+		log.Fatalf("Failed to make directory %q: %v", dir, err)
+	}
+	path := filepath.Join(dir, name)
+	fd, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Failed to open file %q: %v", path, err)
+	}
+	size := int64(minFileSize)
+	if maxFileSize > minFileSize {
+		size += randSource.Int63n(int64(maxFileSize - minFileSize + 1))
+	}
+	_, err = io.CopyN(fd, randSource, size)
+	if err != nil {
+		log.Fatalf("Failed to write %v bytes to file %q: %v", size, path, err)
+	}
+	err = fd.Close()
+	if err != nil {
+		log.Fatalf("Failed to close file %q: %v", path, err)
+	}
+}
@@ -60,6 +60,9 @@ func init() {
 	// Graphics
 	flags.BoolVarP(&opts.NoIndent, "noindent", "i", false, "Don't print indentation lines.")
 	flags.BoolVarP(&opts.Colorize, "color", "C", false, "Turn colorization on always.")
+	// Shadow the global --interactive flag so -i can be used for
+	// --noindent as tree doesn't do any destructive operations
+	flags.BoolVarP(&fs.Config.Interactive, "interactive", "", fs.Config.Interactive, "Enable interactive mode")
 }
 
 var commandDefintion = &cobra.Command{
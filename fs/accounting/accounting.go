@@ -2,9 +2,11 @@
 package accounting
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -31,16 +33,21 @@ type Account struct {
 	close   io.Closer
 	size    int64
 	name    string
-	statmu  sync.Mutex    // Separate mutex for stat values.
-	bytes   int64         // Total number of bytes read
-	max     int64         // if >=0 the max number of bytes to transfer
-	start   time.Time     // Start time of first read
-	lpTime  time.Time     // Time of last average measurement
-	lpBytes int           // Number of bytes read since last measurement
-	avg     float64       // Moving average of last few measurements in bytes/s
-	closed  bool          // set if the file is closed
-	exit    chan struct{} // channel that will be closed when transfer is finished
-	withBuf bool          // is using a buffered in
+	statmu  sync.Mutex      // Separate mutex for stat values.
+	bytes   int64           // Total number of bytes read
+	max     int64           // if >=0 the max number of bytes to transfer
+	start   time.Time       // Start time of first read
+	lpTime  time.Time       // Time of last average measurement
+	lpBytes int             // Number of bytes read since last measurement
+	avg     float64         // Moving average of last few measurements in bytes/s
+	closed  bool            // set if the file is closed
+	exit    chan struct{}   // channel that will be closed when transfer is finished
+	withBuf bool            // is using a buffered in
+	bufSize int64           // size of the buffer if set with WithBufferSize
+	group   *StatsInfo      // if set, the stats group to account to as well as Stats
+	remotes []*remoteBucket // limits from --bwlimit-remote for the remotes involved
+	from    *RemoteUsage    // if set, the usage of the remote being downloaded from
+	to      *RemoteUsage    // if set, the usage of the remote being uploaded to
 }
 
 const averagePeriod = 16 // period to do exponentially weighted averages over
@@ -65,16 +72,27 @@ func NewAccountSizeName(in io.ReadCloser, size int64, name string) *Account {
 }
 
 // NewAccount makes a Account reader for an object
+//
+// The transfer is limited by the --bwlimit-remote of the remote obj
+// is on as well as --bwlimit, and is counted as a download from it.
 func NewAccount(in io.ReadCloser, obj fs.Object) *Account {
-	return NewAccountSizeName(in, obj.Size(), obj.Remote())
+	return NewAccountSizeName(in, obj.Size(), obj.Remote()).WithRemoteLimit(obj.Fs()).WithDownload(obj.Fs())
 }
 
 // WithBuffer - If the file is above a certain size it adds an Async reader
 func (acc *Account) WithBuffer() *Account {
+	return acc.WithBufferSize(int64(fs.Config.BufferSize))
+}
+
+// WithBufferSize adds an Async reader using at most bufSize bytes of
+// memory rather than --buffer-size.  No buffer is added if bufSize is
+// smaller than a single async buffer.
+func (acc *Account) WithBufferSize(bufSize int64) *Account {
 	acc.withBuf = true
+	acc.bufSize = bufSize
 	var buffers int
-	if acc.size >= int64(fs.Config.BufferSize) || acc.size == -1 {
-		buffers = int(int64(fs.Config.BufferSize) / asyncreader.BufferSize)
+	if acc.size >= bufSize || acc.size == -1 {
+		buffers = int(bufSize / asyncreader.BufferSize)
 	} else {
 		buffers = int(acc.size / asyncreader.BufferSize)
 	}
@@ -91,6 +109,66 @@ func (acc *Account) WithBuffer() *Account {
 	return acc
 }
 
+// WithGroup sets the stats group which this transfer should be
+// accounted to as well as the global Stats. A nil group is ignored.
+func (acc *Account) WithGroup(group *StatsInfo) *Account {
+	if group == nil || group == Stats {
+		return acc
+	}
+	acc.mu.Lock()
+	acc.group = group
+	acc.mu.Unlock()
+	group.inProgress.set(acc.name, acc)
+	return acc
+}
+
+// WithRemoteLimit limits the transfer by the --bwlimit-remote of f,
+// usually the remote being transferred to, as well as any limits
+// already set.  A nil f is ignored.
+func (acc *Account) WithRemoteLimit(f fs.Info) *Account {
+	b := getRemoteBucket(f)
+	if b == nil {
+		return acc
+	}
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	for _, remote := range acc.remotes {
+		if remote == b {
+			return acc
+		}
+	}
+	acc.remotes = append(acc.remotes, b)
+	return acc
+}
+
+// WithDownload counts the transfer as a download from f in the usage
+// of f, along with a request to open it.  A nil f is ignored.
+func (acc *Account) WithDownload(f fs.Info) *Account {
+	u := getUsage(f)
+	if u == nil {
+		return acc
+	}
+	atomic.AddInt64(&u.Requests, 1)
+	acc.mu.Lock()
+	acc.from = u
+	acc.mu.Unlock()
+	return acc
+}
+
+// WithUpload counts the transfer as an upload to f in the usage of
+// f, along with a request to upload it.  A nil f is ignored.
+func (acc *Account) WithUpload(f fs.Info) *Account {
+	u := getUsage(f)
+	if u == nil {
+		return acc
+	}
+	atomic.AddInt64(&u.Requests, 1)
+	acc.mu.Lock()
+	acc.to = u
+	acc.mu.Unlock()
+	return acc
+}
+
 // GetReader returns the underlying io.ReadCloser under any Buffer
 func (acc *Account) GetReader() io.ReadCloser {
 	acc.mu.Lock()
@@ -123,7 +201,11 @@ func (acc *Account) UpdateReader(in io.ReadCloser) {
 	acc.in = in
 	acc.close = in
 	acc.origIn = in
-	acc.WithBuffer()
+	if acc.bufSize > 0 {
+		acc.WithBufferSize(acc.bufSize)
+	} else {
+		acc.WithBuffer()
+	}
 	acc.mu.Unlock()
 }
 
@@ -167,6 +249,10 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 	}
 	acc.statmu.Unlock()
 
+	_ = globalPauser.wait(context.Background(), true)
+	if abortTransfers() {
+		return 0, ErrorShuttingDown
+	}
 	n, err = in.Read(p)
 
 	// Update Stats
@@ -176,8 +262,20 @@ func (acc *Account) read(in io.Reader, p []byte) (n int, err error) {
 	acc.statmu.Unlock()
 
 	Stats.Bytes(int64(n))
+	if acc.group != nil {
+		acc.group.Bytes(int64(n))
+	}
+	if acc.from != nil {
+		atomic.AddInt64(&acc.from.Downloaded, int64(n))
+	}
+	if acc.to != nil {
+		atomic.AddInt64(&acc.to.Uploaded, int64(n))
+	}
 
 	limitBandwidth(n)
+	for _, remote := range acc.remotes {
+		remote.wait(n)
+	}
 	return
 }
 
@@ -198,6 +296,9 @@ func (acc *Account) Close() error {
 	acc.closed = true
 	close(acc.exit)
 	Stats.inProgress.clear(acc.name)
+	if acc.group != nil {
+		acc.group.inProgress.clear(acc.name)
+	}
 	return acc.close.Close()
 }
 
@@ -253,3 +253,60 @@ func TestShortenName(t *testing.T) {
 		})
 	}
 }
+
+// limitedFs is an fs.Info with just a name for the remote limits
+type limitedFs struct {
+	fs.Info
+	name string
+}
+
+func (f limitedFs) Name() string { return f.name }
+
+func TestAccountWithRemoteLimit(t *testing.T) {
+	oldLimits := fs.Config.BwLimitRemote
+	defer func() { fs.Config.BwLimitRemote = oldLimits }()
+	fs.Config.BwLimitRemote = nil
+	require.NoError(t, fs.Config.BwLimitRemote.Set("limited=100M"))
+
+	in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+	acc := NewAccountSizeName(in, 1, "test")
+	acc.WithRemoteLimit(nil)
+	acc.WithRemoteLimit(limitedFs{name: "unlimited"})
+	assert.Len(t, acc.remotes, 0)
+	acc.WithRemoteLimit(limitedFs{name: "limited"})
+	acc.WithRemoteLimit(limitedFs{name: "limited"})
+	require.Len(t, acc.remotes, 1)
+	assert.Equal(t, "limited", acc.remotes[0].name)
+
+	var buf = make([]byte, 10)
+	n, err := acc.Read(buf)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, err)
+	assert.Equal(t, fs.SizeSuffix(100*1024*1024), acc.remotes[0].bandwidth)
+	require.NoError(t, acc.Close())
+}
+
+func TestSetRemoteLimit(t *testing.T) {
+	oldLimits := fs.Config.BwLimitRemote
+	defer func() {
+		fs.Config.BwLimitRemote = oldLimits
+		remoteBucketsMu.Lock()
+		delete(remoteBuckets, "configured")
+		delete(remoteBuckets, "flagged")
+		remoteBucketsMu.Unlock()
+	}()
+	fs.Config.BwLimitRemote = nil
+	require.NoError(t, fs.Config.BwLimitRemote.Set("flagged=100M"))
+
+	var timetable fs.BwTimetable
+	require.NoError(t, timetable.Set("1M"))
+	SetRemoteLimit("configured", timetable)
+	SetRemoteLimit("flagged", timetable)
+
+	b := getRemoteBucket(limitedFs{name: "configured"})
+	require.NotNil(t, b)
+	assert.Equal(t, timetable, b.timetable)
+	b = getRemoteBucket(limitedFs{name: "flagged"})
+	require.NotNil(t, b)
+	assert.Equal(t, fs.Config.BwLimitRemote["flagged"], b.timetable, "flag should take precedence")
+}
@@ -0,0 +1,177 @@
+package accounting
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+)
+
+// ErrorReportItem is an object which failed as recorded in the
+// --error-report
+type ErrorReportItem struct {
+	Path      string // path of the object relative to the root of Fs
+	Fs        string // the remote the object is on
+	Operation string // what failed, eg copy, move or delete
+	Error     string // the last error
+	Class     string // fatal, no_retry, retry or error
+	Retries   int    // number of times the operation was retried
+	Failover  string // the remote the object was copied to instead, if any
+}
+
+// errorReport collects the objects which failed for --error-report
+type errorReport struct {
+	mu       sync.Mutex
+	items    map[string]*ErrorReportItem // failures in this attempt
+	previous map[string]*ErrorReportItem // failures in the previous attempt
+}
+
+var globalErrorReport = &errorReport{
+	items: make(map[string]*ErrorReportItem),
+}
+
+// errorClass returns the class of err for the error report
+func errorClass(err error) string {
+	switch {
+	case fserrors.IsFatalError(err):
+		return "fatal"
+	case fserrors.IsNoRetryError(err):
+		return "no_retry"
+	case fserrors.IsRetryError(err) || fserrors.ShouldRetry(err):
+		return "retry"
+	}
+	return "error"
+}
+
+// RecordError records that operation failed on o with err after
+// retries low level retries for the --error-report.
+func RecordError(o fs.ObjectInfo, operation string, retries int, err error) {
+	RecordFailover(o, operation, retries, err, "")
+}
+
+// RecordFailover records that operation failed on o with err after
+// retries low level retries for the --error-report, but that o was
+// copied to the remote failover instead.
+func RecordFailover(o fs.ObjectInfo, operation string, retries int, err error, failover string) {
+	if fs.Config.ErrorReport == "" || err == nil {
+		return
+	}
+	item := &ErrorReportItem{
+		Path:      o.Remote(),
+		Operation: operation,
+		Error:     err.Error(),
+		Class:     errorClass(err),
+		Retries:   retries,
+		Failover:  failover,
+	}
+	if f := o.Fs(); f != nil {
+		item.Fs = f.Name() + ":" + f.Root()
+	}
+	r := globalErrorReport
+	key := item.Fs + "\x00" + item.Operation + "\x00" + item.Path
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.items[key]; ok {
+		item.Retries += old.Retries + 1
+	} else if old, ok := r.previous[key]; ok {
+		// failed in the previous attempt too
+		item.Retries += old.Retries + 1
+	}
+	r.items[key] = item
+}
+
+// ErrorReportNextAttempt starts recording the failures of the next
+// attempt of a command being retried.  Only the failures of the last
+// attempt are written to the report, with the retries of the earlier
+// attempts added on.
+func ErrorReportNextAttempt() {
+	r := globalErrorReport
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.previous = r.items
+	r.items = make(map[string]*ErrorReportItem)
+}
+
+// ErrorReportItems returns the failures recorded sorted by path
+func ErrorReportItems() []ErrorReportItem {
+	r := globalErrorReport
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	items := make([]ErrorReportItem, 0, len(r.items))
+	for _, item := range r.items {
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Path != items[j].Path {
+			return items[i].Path < items[j].Path
+		}
+		return items[i].Operation < items[j].Operation
+	})
+	return items
+}
+
+// WriteErrorReport writes the failures recorded to the file set
+// with --error-report, if any.
+//
+// The report is written as CSV if the file name ends in .csv, as a
+// list of paths suitable for --files-from if it ends in .txt and as
+// JSON lines otherwise.
+func WriteErrorReport() (err error) {
+	if fs.Config.ErrorReport == "" {
+		return nil
+	}
+	items := ErrorReportItems()
+	out, err := os.Create(fs.Config.ErrorReport)
+	if err != nil {
+		return errors.Wrap(err, "failed to create --error-report file")
+	}
+	defer fs.CheckClose(out, &err)
+	buf := bufio.NewWriter(out)
+	switch strings.ToLower(filepath.Ext(fs.Config.ErrorReport)) {
+	case ".csv":
+		w := csv.NewWriter(buf)
+		_ = w.Write([]string{"Path", "Fs", "Operation", "Error", "Class", "Retries", "Failover"})
+		for _, item := range items {
+			_ = w.Write([]string{item.Path, item.Fs, item.Operation, item.Error, item.Class, strconv.Itoa(item.Retries), item.Failover})
+		}
+		w.Flush()
+		err = w.Error()
+	case ".txt":
+		seen := make(map[string]struct{}, len(items))
+		for _, item := range items {
+			if _, found := seen[item.Path]; found {
+				continue
+			}
+			seen[item.Path] = struct{}{}
+			_, err = buf.WriteString(item.Path + "\n")
+			if err != nil {
+				break
+			}
+		}
+	default:
+		enc := json.NewEncoder(buf)
+		for i := range items {
+			err = enc.Encode(&items[i])
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write --error-report file")
+	}
+	fs.Infof(nil, "Wrote %d failures to --error-report %q", len(items), fs.Config.ErrorReport)
+	return nil
+}
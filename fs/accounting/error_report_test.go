@@ -0,0 +1,72 @@
+package accounting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-error-report")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	oldErrorReport, oldReport := fs.Config.ErrorReport, globalErrorReport
+	defer func() {
+		fs.Config.ErrorReport, globalErrorReport = oldErrorReport, oldReport
+	}()
+	globalErrorReport = &errorReport{items: make(map[string]*ErrorReportItem)}
+
+	// nothing recorded if --error-report isn't set
+	fs.Config.ErrorReport = ""
+	RecordError(mockobject.Object("a"), "copy", 0, errors.New("potato"))
+	assert.Len(t, ErrorReportItems(), 0)
+
+	fs.Config.ErrorReport = filepath.Join(dir, "report.json")
+	RecordError(mockobject.Object("b"), "copy", 2, fserrors.RetryErrorf("retry me"))
+	RecordError(mockobject.Object("a"), "delete", 0, fserrors.NoRetryError(errors.New("no retry")))
+	RecordError(mockobject.Object("c"), "copy", 0, errors.New("fixed next time"))
+
+	// the next attempt adds the retries of the previous one
+	ErrorReportNextAttempt()
+	RecordError(mockobject.Object("b"), "copy", 3, fserrors.FatalError(errors.New("fatal")))
+	RecordError(mockobject.Object("a"), "delete", 0, errors.New("potato"))
+	RecordFailover(mockobject.Object("d"), "copy", 0, errors.New("failed over"), "backup:dir")
+
+	assert.Equal(t, []ErrorReportItem{
+		{Path: "a", Operation: "delete", Error: "potato", Class: "error", Retries: 1},
+		{Path: "b", Operation: "copy", Error: "fatal", Class: "fatal", Retries: 6},
+		{Path: "d", Operation: "copy", Error: "failed over", Class: "error", Failover: "backup:dir"},
+	}, ErrorReportItems())
+
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{"report.json", `{"Path":"a","Fs":"","Operation":"delete","Error":"potato","Class":"error","Retries":1,"Failover":""}
+{"Path":"b","Fs":"","Operation":"copy","Error":"fatal","Class":"fatal","Retries":6,"Failover":""}
+{"Path":"d","Fs":"","Operation":"copy","Error":"failed over","Class":"error","Retries":0,"Failover":"backup:dir"}
+`},
+		{"report.csv", `Path,Fs,Operation,Error,Class,Retries,Failover
+a,,delete,potato,error,1,
+b,,copy,fatal,fatal,6,
+d,,copy,failed over,error,0,backup:dir
+`},
+		{"report.txt", "a\nb\nd\n"},
+	} {
+		fs.Config.ErrorReport = filepath.Join(dir, test.name)
+		require.NoError(t, WriteErrorReport())
+		got, err := ioutil.ReadFile(fs.Config.ErrorReport)
+		require.NoError(t, err)
+		assert.Equal(t, test.want, string(got), test.name)
+	}
+}
@@ -0,0 +1,191 @@
+package accounting
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+)
+
+// pauser stops transfers being started, and optionally suspends the
+// ones in progress, until resumed
+type pauser struct {
+	mu        sync.Mutex
+	paused    bool          // set if paused
+	suspend   bool          // set if transfers in progress are suspended too
+	scheduled bool          // set if suspended by the --bwlimit timetable
+	changed   chan struct{} // closed when the state changes
+}
+
+var globalPauser pauser
+
+// notify wakes up the transfers waiting for the state to change - call
+// with mu held
+func (p *pauser) notify() {
+	if p.changed != nil {
+		close(p.changed)
+		p.changed = nil
+	}
+}
+
+// Pause stops new transfers starting until Resume is called.  If
+// suspend is set then the transfers in progress are suspended at the
+// end of the block they are reading too.
+func Pause(suspend bool) {
+	p := &globalPauser
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+	p.suspend = suspend
+	p.notify()
+	fs.Logf(nil, "Transfers paused (suspend transfers in progress: %v)", suspend)
+}
+
+// Resume restarts the transfers stopped by Pause.  It doesn't resume
+// transfers suspended by the --bwlimit timetable.
+func Resume() {
+	p := &globalPauser
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return
+	}
+	p.paused = false
+	p.suspend = false
+	p.notify()
+	fs.Logf(nil, "Transfers resumed")
+}
+
+// Paused returns whether transfers are paused and whether the
+// transfers in progress are suspended
+func Paused() (paused, suspend bool) {
+	p := &globalPauser
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused, p.suspend
+}
+
+// schedulePause suspends all the transfers if paused is set, or
+// resumes them if not, for a pause in the --bwlimit timetable
+func schedulePause(paused bool) {
+	p := &globalPauser
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.scheduled == paused {
+		return
+	}
+	p.scheduled = paused
+	p.notify()
+}
+
+// pausedBySchedule returns whether the transfers are suspended by the
+// --bwlimit timetable
+func pausedBySchedule() bool {
+	p := &globalPauser
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.scheduled
+}
+
+// wait blocks while paused, unless shutting down or ctx is
+// cancelled, in which case it returns the error of ctx.  Transfers in
+// progress set inFlight and only block if they were suspended.
+func (p *pauser) wait(ctx context.Context, inFlight bool) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p.mu.Lock()
+		if ShuttingDown() || (!p.scheduled && (!p.paused || (inFlight && !p.suspend))) {
+			p.mu.Unlock()
+			return nil
+		}
+		if p.changed == nil {
+			p.changed = make(chan struct{})
+		}
+		changed := p.changed
+		p.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		case <-shutdown.ctx.Done():
+		}
+	}
+}
+
+// WaitIfPaused blocks while the transfers are paused.  It should be
+// called before starting a transfer with the context of the job
+// doing it.
+//
+// It returns ErrorShuttingDown if rclone is shutting down or the
+// error of ctx if it is cancelled, in which case the transfer
+// shouldn't be started.
+func WaitIfPaused(ctx context.Context) error {
+	err := globalPauser.wait(ctx, false)
+	if ShuttingDown() {
+		return ErrorShuttingDown
+	}
+	return err
+}
+
+// pauseStatus returns the state of the pauser for the rc
+func pauseStatus() rc.Params {
+	paused, suspend := Paused()
+	return rc.Params{
+		"paused":  paused,
+		"suspend": suspend,
+	}
+}
+
+// Remote control for the pauser
+func init() {
+	rc.Add(rc.Call{
+		Path: "core/pause",
+		Fn: func(in rc.Params) (out rc.Params, err error) {
+			suspend, err := in.GetBool("suspend")
+			if rc.NotErrParamNotFound(err) {
+				return nil, err
+			}
+			Pause(suspend)
+			return pauseStatus(), nil
+		},
+		Title: "Pause the transfers.",
+		Help: `
+This stops rclone starting any new transfers until core/resume is
+called, freeing up the bandwidth without stopping the jobs.
+
+Parameters
+- suspend - if true also suspend the transfers in progress (optional)
+
+The transfers in progress carry on unless suspend is set, in which
+case they stop at the end of the block they are reading.  Note that
+some remotes will time out a suspended transfer if it is paused for a
+long time, in which case it will be retried when resumed.
+
+Eg
+
+    rclone rc core/pause suspend=true
+
+Returns
+- paused - true
+- suspend - whether the transfers in progress are suspended
+`,
+	})
+	rc.Add(rc.Call{
+		Path: "core/resume",
+		Fn: func(in rc.Params) (out rc.Params, err error) {
+			Resume()
+			return pauseStatus(), nil
+		},
+		Title: "Resume the transfers paused by core/pause.",
+		Help: `
+This restarts the transfers paused by core/pause.  It does nothing if
+the transfers aren't paused.
+
+Returns
+- paused - false
+- suspend - false
+`,
+	})
+}
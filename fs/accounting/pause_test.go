@@ -0,0 +1,144 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// returns a channel which is closed when fn returns
+func runAsync(fn func()) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		fn()
+		close(done)
+	}()
+	return done
+}
+
+// waitIfPaused calls WaitIfPaused with a background context
+func waitIfPaused() {
+	_ = WaitIfPaused(context.Background())
+}
+
+// waitInFlight waits as a transfer in progress does
+func waitInFlight() {
+	_ = globalPauser.wait(context.Background(), true)
+}
+
+func isDone(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(50 * time.Millisecond):
+		return false
+	}
+}
+
+func TestPause(t *testing.T) {
+	defer Resume()
+
+	// not paused
+	assert.True(t, isDone(runAsync(waitIfPaused)))
+	assert.True(t, isDone(runAsync(waitInFlight)))
+
+	// paused but not suspended
+	Pause(false)
+	paused, suspend := Paused()
+	assert.True(t, paused)
+	assert.False(t, suspend)
+	newTransfer := runAsync(waitIfPaused)
+	assert.False(t, isDone(newTransfer))
+	assert.True(t, isDone(runAsync(waitInFlight)))
+
+	// suspend the transfers in progress too
+	Pause(true)
+	acc := NewAccountSizeName(ioutil.NopCloser(bytes.NewBufferString("potato")), 6, "test")
+	defer func() { _ = acc.Close() }()
+	inFlight := runAsync(func() {
+		_, _ = ioutil.ReadAll(acc)
+	})
+	assert.False(t, isDone(inFlight))
+	assert.False(t, isDone(newTransfer))
+
+	Resume()
+	assert.True(t, isDone(newTransfer))
+	assert.True(t, isDone(inFlight))
+	paused, suspend = Paused()
+	assert.False(t, paused)
+	assert.False(t, suspend)
+
+	// resuming again does nothing
+	Resume()
+}
+
+func TestSchedulePause(t *testing.T) {
+	defer schedulePause(false)
+	defer Resume()
+
+	schedulePause(true)
+	assert.True(t, pausedBySchedule())
+	newTransfer := runAsync(waitIfPaused)
+	inFlight := runAsync(waitInFlight)
+	assert.False(t, isDone(newTransfer))
+	assert.False(t, isDone(inFlight))
+
+	// core/resume doesn't override the timetable
+	Pause(false)
+	Resume()
+	assert.False(t, isDone(newTransfer))
+	assert.False(t, isDone(inFlight))
+
+	// paused with core/pause when the timetable resumes
+	Pause(false)
+	schedulePause(false)
+	assert.False(t, pausedBySchedule())
+	assert.True(t, isDone(inFlight))
+	assert.False(t, isDone(newTransfer))
+
+	Resume()
+	assert.True(t, isDone(newTransfer))
+}
+
+func TestPauseContext(t *testing.T) {
+	defer Resume()
+
+	assert.NoError(t, WaitIfPaused(context.Background()))
+
+	// a cancelled job stops waiting with its error
+	Pause(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	var err error
+	newTransfer := runAsync(func() {
+		err = WaitIfPaused(ctx)
+	})
+	assert.False(t, isDone(newTransfer))
+	cancel()
+	assert.True(t, isDone(newTransfer))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, WaitIfPaused(ctx))
+}
+
+func TestPauseRc(t *testing.T) {
+	defer Resume()
+	call := rc.Calls.Get("core/pause")
+	require.NotNil(t, call)
+	out, err := call.Fn(rc.Params{"suspend": true})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"paused": true, "suspend": true}, out)
+
+	_, err = call.Fn(rc.Params{"suspend": "potato"})
+	assert.Error(t, err)
+
+	call = rc.Calls.Get("core/resume")
+	require.NotNil(t, call)
+	out, err = call.Fn(rc.Params{})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"paused": false, "suspend": false}, out)
+}
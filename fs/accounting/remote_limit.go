@@ -0,0 +1,112 @@
+package accounting
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"golang.org/x/time/rate"
+)
+
+// remoteBucket limits the bandwidth of the transfers to and from a
+// single remote as set with --bwlimit-remote
+type remoteBucket struct {
+	name      string         // name of the remote
+	timetable fs.BwTimetable // limits for the remote
+	mu        sync.Mutex     // protect the below
+	bandwidth fs.SizeSuffix  // the limit in use now, <= 0 for unlimited
+	limiter   *rate.Limiter  // the token bucket, nil if unlimited
+	paused    bool           // set if the timetable has paused the transfers
+}
+
+var (
+	remoteBucketsMu sync.Mutex
+	remoteBuckets   = make(map[string]*remoteBucket) // token buckets by remote name
+)
+
+// getRemoteBucket returns the token bucket for the remote f or nil if
+// it doesn't have a limit set with --bwlimit-remote or SetRemoteLimit
+func getRemoteBucket(f fs.Info) *remoteBucket {
+	if f == nil {
+		return nil
+	}
+	name := f.Name()
+	remoteBucketsMu.Lock()
+	defer remoteBucketsMu.Unlock()
+	b := remoteBuckets[name]
+	if b == nil {
+		timetable, ok := fs.Config.BwLimitRemote[name]
+		if !ok {
+			return nil
+		}
+		b = &remoteBucket{
+			name:      name,
+			timetable: timetable,
+		}
+		remoteBuckets[name] = b
+	}
+	return b
+}
+
+// SetRemoteLimit sets the bandwidth timetable for the transfers to
+// and from the remote called name, eg from the config of a backend
+// wrapping it.  A limit set with --bwlimit-remote takes precedence.
+func SetRemoteLimit(name string, timetable fs.BwTimetable) {
+	if _, ok := fs.Config.BwLimitRemote[name]; ok {
+		return
+	}
+	remoteBucketsMu.Lock()
+	defer remoteBucketsMu.Unlock()
+	remoteBuckets[name] = &remoteBucket{
+		name:      name,
+		timetable: timetable,
+	}
+}
+
+// wait sleeps for the correct amount of time for the passage of n
+// bytes according to the remote's limit now, or until the end of the
+// time slot if the timetable pauses the transfers now
+func (b *remoteBucket) wait(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		now := time.Now()
+		if !b.timetable.LimitAt(now).Pause || ShuttingDown() {
+			break
+		}
+		if !b.paused {
+			b.paused = true
+			fs.Logf(nil, "Transfers to and from remote %q paused by the bandwidth timetable", b.name)
+		}
+		// the time slots start on the minute
+		b.mu.Unlock()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-timer.C:
+		case <-ShutdownContext().Done():
+			timer.Stop()
+		}
+		b.mu.Lock()
+	}
+	if b.paused {
+		b.paused = false
+		fs.Logf(nil, "Transfers to and from remote %q resumed", b.name)
+	}
+	if limitNow := b.timetable.LimitAt(time.Now()).Bandwidth; limitNow != b.bandwidth {
+		b.bandwidth = limitNow
+		if limitNow > 0 {
+			b.limiter = newTokenBucket(limitNow)
+			fs.Infof(nil, "Bandwidth limit for remote %q set to %vBytes/s", b.name, &limitNow)
+		} else {
+			b.limiter = nil
+			fs.Infof(nil, "Bandwidth limit for remote %q disabled", b.name)
+		}
+	}
+	if b.limiter != nil {
+		err := b.limiter.WaitN(context.Background(), n)
+		if err != nil {
+			fs.Errorf(nil, "Token bucket error: %v", err)
+		}
+	}
+}
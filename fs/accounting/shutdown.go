@@ -0,0 +1,62 @@
+package accounting
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+)
+
+// ErrorShuttingDown is returned for the transfers which are stopped,
+// or not started, because rclone is shutting down
+var ErrorShuttingDown = fserrors.FatalError(errors.New("shutting down"))
+
+// shutdown coordinates a graceful shutdown
+var shutdown struct {
+	ctx    context.Context // cancelled when the shutdown starts
+	cancel func()
+	abort  int32 // set to 1 if the transfers in progress should be stopped
+}
+
+func init() {
+	shutdown.ctx, shutdown.cancel = context.WithCancel(context.Background())
+}
+
+// Shutdown starts a graceful shutdown.  No new transfers are started
+// after it is called.  If abort is set then the transfers in progress
+// are stopped at the end of the block they are reading with
+// ErrorShuttingDown, otherwise they are left to finish.
+//
+// Transfers suspended by Pause or the --bwlimit timetable are resumed
+// so they can finish or be stopped.
+func Shutdown(abort bool) {
+	if abort {
+		atomic.StoreInt32(&shutdown.abort, 1)
+	}
+	shutdown.cancel()
+	p := &globalPauser
+	p.mu.Lock()
+	p.notify()
+	p.mu.Unlock()
+	fs.Debugf(nil, "Shutting down (stop transfers in progress: %v)", abort)
+}
+
+// ShuttingDown returns whether Shutdown has been called
+func ShuttingDown() bool {
+	return shutdown.ctx.Err() != nil
+}
+
+// ShutdownContext returns a context which is cancelled when Shutdown
+// is called.  Use it as the parent of the contexts which control the
+// scheduling of work so it stops when rclone shuts down.
+func ShutdownContext() context.Context {
+	return shutdown.ctx
+}
+
+// abortTransfers returns whether the transfers in progress should be
+// stopped
+func abortTransfers() bool {
+	return atomic.LoadInt32(&shutdown.abort) != 0
+}
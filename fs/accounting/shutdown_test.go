@@ -0,0 +1,61 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resetShutdown undoes Shutdown for the tests
+func resetShutdown() {
+	shutdown.ctx, shutdown.cancel = context.WithCancel(context.Background())
+	atomic.StoreInt32(&shutdown.abort, 0)
+}
+
+func TestShutdownSoft(t *testing.T) {
+	defer resetShutdown()
+	defer Resume()
+
+	acc := NewAccountSizeName(ioutil.NopCloser(bytes.NewBufferString("potato")), 6, "test")
+	defer func() { _ = acc.Close() }()
+
+	// suspended transfers are woken up by the shutdown
+	Pause(true)
+	var err error
+	newTransfer := runAsync(func() {
+		err = WaitIfPaused(context.Background())
+	})
+	assert.False(t, isDone(newTransfer))
+	assert.False(t, ShuttingDown())
+
+	Shutdown(false)
+	assert.True(t, ShuttingDown())
+	assert.True(t, isDone(newTransfer))
+	assert.Equal(t, ErrorShuttingDown, err)
+	select {
+	case <-ShutdownContext().Done():
+	default:
+		t.Error("context not cancelled")
+	}
+
+	// the transfers in progress finish
+	data, err := ioutil.ReadAll(acc)
+	assert.NoError(t, err)
+	assert.Equal(t, "potato", string(data))
+}
+
+func TestShutdownHard(t *testing.T) {
+	defer resetShutdown()
+
+	acc := NewAccountSizeName(ioutil.NopCloser(bytes.NewBufferString("potato")), 6, "test")
+	defer func() { _ = acc.Close() }()
+
+	Shutdown(true)
+	assert.True(t, ShuttingDown())
+	_, err := ioutil.ReadAll(acc)
+	assert.Equal(t, ErrorShuttingDown, err)
+}
@@ -2,9 +2,11 @@ package accounting
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/ncw/rclone/fs"
@@ -15,26 +17,45 @@ import (
 var (
 	// Stats is global statistics counter
 	Stats = NewStats()
+
+	// errTransferFailed is recorded for transfers which failed
+	// without an error being given
+	errTransferFailed = errors.New("transfer failed")
 )
 
+// maximum number of completed transfers to remember for core/transferred
+const maxCompletedTransfers = 100
+
 func init() {
 	// Set the function pointer up in fs
 	fs.CountError = Stats.Error
 
 	rc.Add(rc.Call{
 		Path:  "core/stats",
-		Fn:    Stats.RemoteStats,
+		Fn:    rcStats,
 		Title: "Returns stats about current transfers.",
 		Help: `
 This returns all available stats
 
 	rclone rc core/stats
 
+If group is not provided then the global stats for the whole rclone
+process are returned.  These count every transfer, whichever group it
+is in.  Otherwise the stats for the group given are returned, eg
+
+	rclone rc core/stats group=job/1
+
+Parameters
+
+- group - name of the stats group (string)
+
 Returns the following values:
 
 ` + "```" + `
 {
 	"speed": average speed in bytes/sec since start of the process,
+	"speedAvg": speed in bytes/sec as an exponentially weighted moving average,
+	"eta": estimated time in seconds until all the transfers and checks are complete,
 	"bytes": total transferred bytes since the start of the process,
 	"errors": number of errors,
 	"fatalError": whether there has been at least one FatalError,
@@ -42,8 +63,15 @@ Returns the following values:
 	"checks": number of checked files,
 	"transfers": number of transferred files,
 	"deletes" : number of deleted files,
+	"serverSideCopies": number of server side copies done,
+	"serverSideCopyBytes": number bytes server side copied,
+	"serverSideMoves": number of server side moves done,
+	"serverSideMoveBytes": number bytes server side moved,
+	"verified": number of transfers whose hash was checked after transfer,
 	"elapsedTime": time in seconds since the start of the process,
 	"lastError": last occurred error,
+	"paused": whether the transfers are paused with core/pause,
+	"pausedBySchedule": whether the transfers are paused by the --bwlimit timetable,
 	"transferring": an array of currently active file transfers:
 		[
 			{
@@ -57,51 +85,94 @@ Returns the following values:
 			}
 		],
 	"checking": an array of names of currently active file checks
-		[]
+		[],
+	"remotes": the usage of each remote keyed by remote name:
+		{
+			"remote": {
+				"uploaded": bytes uploaded to the remote,
+				"downloaded": bytes downloaded from the remote,
+				"requests": number of requests made to the remote,
+				"cost": estimated cost from --cost if set
+			}
+		}
 }
 ` + "```" + `
 Values for "transferring", "checking" and "lastError" are only assigned if data is available.
+The "remotes" are only returned when no group is given.
 The value for "eta" is null if an eta cannot be determined.
+
+The top level "eta" is worked out from "speedAvg" and the number of
+bytes still to transfer, and from the rate files are being checked
+and the number of checks still to do, so it gives a realistic
+estimate early on in a large sync.
+
+Note that server side copies and moves are not included in "bytes" or
+"speed" as no data passes through rclone for them.
 `,
 	})
 }
 
 // StatsInfo accounts all transfers
 type StatsInfo struct {
-	mu                sync.RWMutex
-	bytes             int64
-	errors            int64
-	lastError         error
-	fatalError        bool
-	retryError        bool
-	checks            int64
-	checking          *stringSet
-	checkQueue        int
-	checkQueueSize    int64
-	transfers         int64
-	transferring      *stringSet
-	transferQueue     int
-	transferQueueSize int64
-	renameQueue       int
-	renameQueueSize   int64
-	deletes           int64
-	start             time.Time
-	inProgress        *inProgress
+	mu                  sync.RWMutex
+	bytes               int64
+	errors              int64
+	lastError           error
+	fatalError          bool
+	retryError          bool
+	checks              int64
+	checking            *stringSet
+	checkQueue          int
+	checkQueueSize      int64
+	transfers           int64
+	transferring        *stringSet
+	transferQueue       int
+	transferQueueSize   int64
+	renameQueue         int
+	renameQueueSize     int64
+	deletes             int64
+	deletesSize         int64
+	serverSideCopies    int64
+	serverSideCopyBytes int64
+	serverSideMoves     int64
+	serverSideMoveBytes int64
+	verified            int64
+	start               time.Time
+	inProgress          *inProgress
+	startedTransfers    map[string]time.Time // start time of transfers in progress
+	transferred         []transferredItem    // the most recently completed transfers
+	avgSpeed            float64              // moving average of the speed in bytes/s
+	avgPeriod           float64              // number of samples in avgSpeed up to averagePeriod
+	avgBytes            int64                // bytes when avgSpeed was last updated
+	avgTime             time.Time            // time avgSpeed was last updated
+}
+
+// transferredItem describes a completed transfer for core/transferred
+type transferredItem struct {
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	Error       string    `json:"error"`
+	StartedAt   time.Time `json:"startedAt"`
+	CompletedAt time.Time `json:"completedAt"`
 }
 
 // NewStats cretates an initialised StatsInfo
 func NewStats() *StatsInfo {
 	return &StatsInfo{
-		checking:     newStringSet(fs.Config.Checkers, "checking"),
-		transferring: newStringSet(fs.Config.Transfers, "transferring"),
-		start:        time.Now(),
-		inProgress:   newInProgress(),
+		checking:         newStringSet(fs.Config.Checkers, "checking"),
+		transferring:     newStringSet(fs.Config.Transfers, "transferring"),
+		start:            time.Now(),
+		inProgress:       newInProgress(),
+		startedTransfers: make(map[string]time.Time),
 	}
 }
 
 // RemoteStats returns stats for rc
 func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 	out = make(rc.Params)
+	transferring, checking := s.transferring.count(), s.checking.count()
+	transferringBytesDone, transferringBytesTotal := s.transferring.progress()
+	s.updateAverage()
 	s.mu.RLock()
 	dt := time.Now().Sub(s.start)
 	dtSeconds := dt.Seconds()
@@ -110,6 +181,13 @@ func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 		speed = float64(s.bytes) / dtSeconds
 	}
 	out["speed"] = speed
+	out["speedAvg"] = s.avgSpeed
+	totalChecks, _, totalSize := s._totals(transferring, checking, transferringBytesDone, transferringBytesTotal)
+	if d, ok := s._eta(totalChecks, totalSize, dt); ok {
+		out["eta"] = d.Seconds()
+	} else {
+		out["eta"] = nil
+	}
 	out["bytes"] = s.bytes
 	out["errors"] = s.errors
 	out["fatalError"] = s.fatalError
@@ -117,8 +195,15 @@ func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 	out["checks"] = s.checks
 	out["transfers"] = s.transfers
 	out["deletes"] = s.deletes
+	out["serverSideCopies"] = s.serverSideCopies
+	out["serverSideCopyBytes"] = s.serverSideCopyBytes
+	out["serverSideMoves"] = s.serverSideMoves
+	out["serverSideMoveBytes"] = s.serverSideMoveBytes
+	out["verified"] = s.verified
 	out["elapsedTime"] = dtSeconds
 	s.mu.RUnlock()
+	out["paused"], _ = Paused()
+	out["pausedBySchedule"] = pausedBySchedule()
 	if !s.checking.empty() {
 		var c []string
 		s.checking.mu.RLock()
@@ -144,6 +229,9 @@ func (s *StatsInfo) RemoteStats(in rc.Params) (out rc.Params, err error) {
 	if s.errors > 0 {
 		out["lastError"] = s.lastError
 	}
+	if s == Stats {
+		out["remotes"] = rcUsage()
+	}
 	return out, nil
 }
 
@@ -167,12 +255,77 @@ func eta(size, total int64, rate float64) (eta time.Duration, ok bool) {
 // If the ETA cannot be determined it returns "-"
 func etaString(done, total int64, rate float64) string {
 	d, ok := eta(done, total, rate)
+	return durationString(d, ok)
+}
+
+// durationString returns d as a string or "-" if it isn't ok
+func durationString(d time.Duration, ok bool) string {
 	if !ok {
 		return "-"
 	}
 	return d.String()
 }
 
+// updateAverage updates the moving average of the speed if at least
+// a second has passed since it was last updated.
+func (s *StatsInfo) updateAverage() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.avgTime.IsZero() {
+		s.avgTime = s.start
+	}
+	elapsed := now.Sub(s.avgTime).Seconds()
+	if elapsed < 1 {
+		return
+	}
+	speed := float64(s.bytes-s.avgBytes) / elapsed
+	// Soft start the moving average
+	if s.avgPeriod < averagePeriod {
+		s.avgPeriod++
+	}
+	s.avgSpeed = (speed + (s.avgPeriod-1)*s.avgSpeed) / s.avgPeriod
+	s.avgBytes = s.bytes
+	s.avgTime = now
+}
+
+// _totals returns the total number of checks and transfers and the
+// total size of the transfers, including the queued and in progress
+// ones.
+//
+// Call with mu held
+func (s *StatsInfo) _totals(transferring, checking int, transferringBytesDone, transferringBytesTotal int64) (totalChecks, totalTransfer, totalSize int64) {
+	totalChecks = int64(s.checkQueue) + s.checks + int64(checking)
+	totalTransfer = int64(s.transferQueue) + s.transfers + int64(transferring)
+	// note that s.bytes already includes transferringBytesDone so
+	// we take it off here to avoid double counting
+	totalSize = s.transferQueueSize + s.bytes + transferringBytesTotal - transferringBytesDone
+	return totalChecks, totalTransfer, totalSize
+}
+
+// _eta returns the estimated time until the operation completes.
+//
+// This is the longer of the time to transfer the remaining bytes at
+// the moving average speed and the time to do the remaining checks at
+// the rate they have been done so far.  If the ETA can't be
+// determined 'ok' returns false.
+//
+// Call with mu held
+func (s *StatsInfo) _eta(totalChecks, totalSize int64, dt time.Duration) (d time.Duration, ok bool) {
+	speed := s.avgSpeed
+	if speed <= 0 && dt > 0 {
+		speed = float64(s.bytes) / dt.Seconds()
+	}
+	d, ok = eta(s.bytes, totalSize, speed)
+	if s.checkQueue > 0 && s.checks > 0 && dt > 0 {
+		checkRate := float64(s.checks) / dt.Seconds()
+		if dChecks, okChecks := eta(s.checks, totalChecks, checkRate); okChecks && (!ok || dChecks > d) {
+			return dChecks, true
+		}
+	}
+	return d, ok
+}
+
 // percent returns a/b as a percentage rounded to the nearest integer
 // as a string
 //
@@ -184,6 +337,62 @@ func percent(a int64, b int64) string {
 	return fmt.Sprintf("%d%%", int(float64(a)*100/float64(b)+0.5))
 }
 
+// StatsLine is the data the --stats-one-line-template is executed
+// with.
+type StatsLine struct {
+	Date           time.Time     // when the stats were made
+	Bytes          fs.SizeSuffix // bytes transferred
+	TotalBytes     fs.SizeSuffix // total bytes to transfer
+	Percent        string        // percentage of the bytes transferred, eg "50%"
+	Speed          fs.SizeSuffix // average speed in --stats-unit per second
+	ETA            string        // estimated time left, eg "1m2s" or "-"
+	Errors         int64         // number of errors
+	Checks         int64         // number of files checked
+	TotalChecks    int64         // total number of files to check
+	Transfers      int64         // number of files transferred
+	TotalTransfers int64         // total number of files to transfer
+	Elapsed        time.Duration // time since the stats were started
+}
+
+var (
+	statsTemplateMu     sync.Mutex
+	statsTemplateText   string             // text of statsTemplate
+	statsTemplate       *template.Template // parsed --stats-one-line-template
+	statsTemplateLogged bool               // set if an error executing it was logged
+)
+
+// ParseStatsTemplate parses text as a --stats-one-line-template
+func ParseStatsTemplate(text string) (*template.Template, error) {
+	return template.New("stats-one-line-template").Parse(text)
+}
+
+// writeStatsTemplate writes line to buf with the
+// --stats-one-line-template returning false if it couldn't be used.
+func writeStatsTemplate(buf *bytes.Buffer, line *StatsLine) bool {
+	statsTemplateMu.Lock()
+	defer statsTemplateMu.Unlock()
+	text := fs.Config.StatsOneLineTemplate
+	if statsTemplate == nil || text != statsTemplateText {
+		t, err := ParseStatsTemplate(text)
+		if err != nil {
+			fs.Errorf(nil, "Failed to parse --stats-one-line-template: %v", err)
+			return false
+		}
+		statsTemplate, statsTemplateText, statsTemplateLogged = t, text, false
+	}
+	var out bytes.Buffer
+	err := statsTemplate.Execute(&out, line)
+	if err != nil {
+		if !statsTemplateLogged {
+			fs.Errorf(nil, "Failed to execute --stats-one-line-template: %v", err)
+			statsTemplateLogged = true
+		}
+		return false
+	}
+	_, _ = buf.Write(out.Bytes())
+	return true
+}
+
 // String convert the StatsInfo to a string for printing
 func (s *StatsInfo) String() string {
 	// checking and transferring have their own locking so read
@@ -191,6 +400,7 @@ func (s *StatsInfo) String() string {
 	transferring, checking := s.transferring.count(), s.checking.count()
 	transferringBytesDone, transferringBytesTotal := s.transferring.progress()
 
+	s.updateAverage()
 	s.mu.RLock()
 
 	dt := time.Now().Sub(s.start)
@@ -205,15 +415,11 @@ func (s *StatsInfo) String() string {
 		speed = speed * 8
 	}
 
+	totalChecks, totalTransfer, totalSize := s._totals(transferring, checking, transferringBytesDone, transferringBytesTotal)
 	var (
-		totalChecks   = int64(s.checkQueue) + s.checks + int64(checking)
-		totalTransfer = int64(s.transferQueue) + s.transfers + int64(transferring)
-		// note that s.bytes already includes transferringBytesDone so
-		// we take it off here to avoid double counting
-		totalSize    = s.transferQueueSize + s.bytes + transferringBytesTotal - transferringBytesDone
-		currentSize  = s.bytes
 		buf          = &bytes.Buffer{}
 		xfrchkString = ""
+		dateString   = ""
 	)
 
 	if !fs.Config.StatsOneLine {
@@ -229,16 +435,39 @@ func (s *StatsInfo) String() string {
 		if len(xfrchk) > 0 {
 			xfrchkString = fmt.Sprintf(" (%s)", strings.Join(xfrchk, ", "))
 		}
+		if fs.Config.StatsOneLineDate {
+			dateString = time.Now().Format(fs.Config.StatsOneLineDateFormat)
+		}
 	}
 
-	_, _ = fmt.Fprintf(buf, "%10s / %s, %s, %s, ETA %s%s",
-		fs.SizeSuffix(s.bytes),
-		fs.SizeSuffix(totalSize).Unit("Bytes"),
-		percent(s.bytes, totalSize),
-		fs.SizeSuffix(speed).Unit(strings.Title(fs.Config.DataRateUnit)+"/s"),
-		etaString(currentSize, totalSize, speed),
-		xfrchkString,
-	)
+	useTemplate := fs.Config.StatsOneLine && fs.Config.StatsOneLineTemplate != ""
+	if useTemplate {
+		useTemplate = writeStatsTemplate(buf, &StatsLine{
+			Date:           time.Now(),
+			Bytes:          fs.SizeSuffix(s.bytes),
+			TotalBytes:     fs.SizeSuffix(totalSize),
+			Percent:        percent(s.bytes, totalSize),
+			Speed:          fs.SizeSuffix(speed),
+			ETA:            durationString(s._eta(totalChecks, totalSize, dt)),
+			Errors:         s.errors,
+			Checks:         s.checks,
+			TotalChecks:    totalChecks,
+			Transfers:      s.transfers,
+			TotalTransfers: totalTransfer,
+			Elapsed:        dtRounded,
+		})
+	}
+	if !useTemplate {
+		_, _ = fmt.Fprintf(buf, "%s%10s / %s, %s, %s, ETA %s%s",
+			dateString,
+			fs.SizeSuffix(s.bytes),
+			fs.SizeSuffix(totalSize).Unit("Bytes"),
+			percent(s.bytes, totalSize),
+			fs.SizeSuffix(speed).Unit(strings.Title(fs.Config.DataRateUnit)+"/s"),
+			durationString(s._eta(totalChecks, totalSize, dt)),
+			xfrchkString,
+		)
+	}
 
 	if !fs.Config.StatsOneLine {
 		errorDetails := ""
@@ -261,6 +490,15 @@ Elapsed time:  %10v
 			s.checks, totalChecks, percent(s.checks, totalChecks),
 			s.transfers, totalTransfer, percent(s.transfers, totalTransfer),
 			dtRounded)
+		if s.serverSideCopies != 0 || s.serverSideMoves != 0 {
+			_, _ = fmt.Fprintf(buf, "Server Side Copies:%6d @ %s\nServer Side Moves: %6d @ %s\n",
+				s.serverSideCopies, fs.SizeSuffix(s.serverSideCopyBytes).Unit("Bytes"),
+				s.serverSideMoves, fs.SizeSuffix(s.serverSideMoveBytes).Unit("Bytes"))
+		}
+		if s.verified != 0 {
+			_, _ = fmt.Fprintf(buf, "Verified:      %10d / %d, %s\n",
+				s.verified, s.transfers, percent(s.verified, s.transfers))
+		}
 	}
 
 	// checking and transferring have their own locking so unlock
@@ -363,7 +601,71 @@ func (s *StatsInfo) Deletes(deletes int64) int64 {
 	return s.deletes
 }
 
-// ResetCounters sets the counters (bytes, checks, errors, transfers, deletes) to 0 and resets lastError, fatalError and retryError
+// DeletesSize updates the stats for the size of the files deleted
+// and returns the total size deleted so far
+func (s *StatsInfo) DeletesSize(size int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletesSize += size
+	return s.deletesSize
+}
+
+// ServerSideCopy records a server side copy of size bytes
+//
+// These aren't counted in bytes as no data passes through rclone
+func (s *StatsInfo) ServerSideCopy(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverSideCopies++
+	if size > 0 {
+		s.serverSideCopyBytes += size
+	}
+}
+
+// ServerSideMove records a server side move of size bytes
+//
+// These aren't counted in bytes as no data passes through rclone
+func (s *StatsInfo) ServerSideMove(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverSideMoves++
+	if size > 0 {
+		s.serverSideMoveBytes += size
+	}
+}
+
+// GetServerSideCopies returns the number and total size of server
+// side copies
+func (s *StatsInfo) GetServerSideCopies() (copies, bytes int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.serverSideCopies, s.serverSideCopyBytes
+}
+
+// GetServerSideMoves returns the number and total size of server
+// side moves
+func (s *StatsInfo) GetServerSideMoves() (moves, bytes int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.serverSideMoves, s.serverSideMoveBytes
+}
+
+// Verified records that a transfer had its hash checked after transfer
+func (s *StatsInfo) Verified() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.verified++
+}
+
+// GetVerified returns the number of transfers whose hash was checked
+// after transfer
+func (s *StatsInfo) GetVerified() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.verified
+}
+
+// ResetCounters sets the counters (bytes, checks, errors, transfers, deletes, server side copies/moves, verified) to 0, forgets the completed transfers and resets lastError, fatalError and retryError
 func (s *StatsInfo) ResetCounters() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -375,6 +677,17 @@ func (s *StatsInfo) ResetCounters() {
 	s.checks = 0
 	s.transfers = 0
 	s.deletes = 0
+	s.deletesSize = 0
+	s.serverSideCopies = 0
+	s.serverSideCopyBytes = 0
+	s.serverSideMoves = 0
+	s.serverSideMoveBytes = 0
+	s.verified = 0
+	s.transferred = nil
+	s.avgSpeed = 0
+	s.avgPeriod = 0
+	s.avgBytes = 0
+	s.avgTime = time.Now()
 }
 
 // ResetErrors sets the errors count to 0 and resets lastError, fatalError and retryError
@@ -431,18 +744,56 @@ func (s *StatsInfo) GetTransfers() int64 {
 // Transferring adds a transfer into the stats
 func (s *StatsInfo) Transferring(remote string) {
 	s.transferring.add(remote)
+	s.mu.Lock()
+	s.startedTransfers[remote] = time.Now()
+	s.mu.Unlock()
 }
 
 // DoneTransferring removes a transfer from the stats
 //
 // if ok is true then it increments the transfers count
 func (s *StatsInfo) DoneTransferring(remote string, ok bool) {
+	var err error
+	if !ok {
+		err = errTransferFailed
+	}
+	s.DoneTransferringError(remote, -1, err)
+}
+
+// DoneTransferringError removes a transfer of size bytes (-1 if
+// unknown) from the stats, recording it in the completed transfers
+// with err.
+//
+// if err is nil then it increments the transfers count
+func (s *StatsInfo) DoneTransferringError(remote string, size int64, err error) {
 	s.transferring.del(remote)
-	if ok {
-		s.mu.Lock()
+	item := transferredItem{
+		Name:        remote,
+		Size:        size,
+		CompletedAt: time.Now(),
+	}
+	if err != nil {
+		item.Error = err.Error()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
 		s.transfers++
-		s.mu.Unlock()
 	}
+	item.StartedAt = s.startedTransfers[remote]
+	delete(s.startedTransfers, remote)
+	if len(s.transferred) >= maxCompletedTransfers {
+		s.transferred = s.transferred[1:]
+	}
+	s.transferred = append(s.transferred, item)
+}
+
+// Transferred returns a copy of the most recently completed
+// transfers, oldest first
+func (s *StatsInfo) Transferred() []transferredItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]transferredItem(nil), s.transferred...)
 }
 
 // SetCheckQueue sets the number of queued checks
@@ -0,0 +1,197 @@
+package accounting
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+// statsGroups holds the named stats groups - the global Stats is the
+// group with the empty name
+var statsGroups = struct {
+	mu     sync.Mutex
+	groups map[string]*StatsInfo
+}{
+	groups: make(map[string]*StatsInfo),
+}
+
+// StatsGroup returns the stats group called name, creating it if it
+// doesn't exist. The empty name returns the global Stats.
+func StatsGroup(name string) *StatsInfo {
+	if name == "" {
+		return Stats
+	}
+	statsGroups.mu.Lock()
+	defer statsGroups.mu.Unlock()
+	s := statsGroups.groups[name]
+	if s == nil {
+		s = NewStats()
+		statsGroups.groups[name] = s
+	}
+	return s
+}
+
+// getStatsGroup returns the stats group called name or nil if it
+// doesn't exist. The empty name returns the global Stats.
+func getStatsGroup(name string) *StatsInfo {
+	if name == "" {
+		return Stats
+	}
+	statsGroups.mu.Lock()
+	defer statsGroups.mu.Unlock()
+	return statsGroups.groups[name]
+}
+
+// ListStatsGroups returns the names of the stats groups in sorted order
+func ListStatsGroups() []string {
+	statsGroups.mu.Lock()
+	defer statsGroups.mu.Unlock()
+	names := make([]string, 0, len(statsGroups.groups))
+	for name := range statsGroups.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeleteStatsGroup removes the stats group called name
+func DeleteStatsGroup(name string) {
+	statsGroups.mu.Lock()
+	defer statsGroups.mu.Unlock()
+	delete(statsGroups.groups, name)
+}
+
+// groupFromParams reads the optional "group" parameter from in and
+// returns the stats group it names
+func groupFromParams(in rc.Params) (*StatsInfo, error) {
+	name, err := in.GetString("group")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	s := getStatsGroup(name)
+	if s == nil {
+		return nil, errors.Errorf("stats group %q not found", name)
+	}
+	return s, nil
+}
+
+func init() {
+	// Delete the stats groups of the rc jobs when they expire
+	rc.DeleteJobGroup = DeleteStatsGroup
+
+	rc.Add(rc.Call{
+		Path:  "core/transferred",
+		Fn:    rcTransferred,
+		Title: "Returns stats about completed transfers.",
+		Help: `
+This returns stats about completed transfers:
+
+	rclone rc core/transferred
+
+If group is not provided then the global list of completed transfers
+for the whole rclone process is returned, which includes those of
+every group, otherwise only those for the group given, eg
+
+	rclone rc core/transferred group=job/1
+
+Note only the last 100 completed transfers are returned.
+
+Parameters
+
+- group - name of the stats group (string)
+
+Returns the following values:
+` + "```" + `
+{
+	"transferred":  an array of completed transfers (including failed ones):
+		[
+			{
+				"name": name of the file,
+				"size": size of the file in bytes or -1 if unknown,
+				"error": string description of the error (empty if successful),
+				"startedAt": time the transfer was started at,
+				"completedAt": time the transfer was completed at
+			}
+		]
+}
+` + "```" + `
+`,
+	})
+
+	rc.Add(rc.Call{
+		Path:  "core/group-list",
+		Fn:    rcGroupList,
+		Title: "Returns list of stats groups.",
+		Help: `
+This returns the names of the stats groups currently in use, eg those
+created for each job started with _async=true.
+
+Returns the following values:
+` + "```" + `
+{
+	"groups":  an array of group names:
+		[
+			"job/1",
+			"job/2",
+		]
+}
+` + "```" + `
+`,
+	})
+
+	rc.Add(rc.Call{
+		Path:  "core/stats-delete",
+		Fn:    rcStatsDelete,
+		Title: "Delete stats group.",
+		Help: `
+This deletes the stats group given, eg when a job has finished and its
+stats are no longer needed.
+
+The stats group made for a job started with _async=true is deleted
+when the job expires, a minute after it finishes.
+
+Parameters
+
+- group - name of the stats group (string)
+`,
+	})
+}
+
+// rcStats returns the stats for the group given in the params
+func rcStats(in rc.Params) (rc.Params, error) {
+	s, err := groupFromParams(in)
+	if err != nil {
+		return nil, err
+	}
+	return s.RemoteStats(in)
+}
+
+// rcTransferred returns the completed transfers for the group given in the params
+func rcTransferred(in rc.Params) (out rc.Params, err error) {
+	s, err := groupFromParams(in)
+	if err != nil {
+		return nil, err
+	}
+	out = make(rc.Params)
+	out["transferred"] = s.Transferred()
+	return out, nil
+}
+
+// rcGroupList returns the names of the stats groups
+func rcGroupList(in rc.Params) (out rc.Params, err error) {
+	out = make(rc.Params)
+	out["groups"] = ListStatsGroups()
+	return out, nil
+}
+
+// rcStatsDelete deletes the stats group given in the params
+func rcStatsDelete(in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("group")
+	if err != nil {
+		return nil, err
+	}
+	DeleteStatsGroup(name)
+	return nil, nil
+}
@@ -1,11 +1,16 @@
 package accounting
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestETA(t *testing.T) {
@@ -36,6 +41,39 @@ func TestETA(t *testing.T) {
 	}
 }
 
+func TestStatsETA(t *testing.T) {
+	s := NewStats()
+	s.start = time.Now().Add(-10 * time.Second)
+
+	// Nothing done yet so no ETA
+	_, ok := s._eta(0, 0, 10*time.Second)
+	assert.False(t, ok)
+
+	// Moving average of 100 bytes/s with 1000 bytes to go
+	s.bytes = 1000
+	s.updateAverage()
+	assert.InDelta(t, 100.0, s.avgSpeed, 0.1)
+	s.avgSpeed = 100
+	d, ok := s._eta(0, 2000, 10*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+
+	// Checks still to do take longer than the transfers
+	s.checks = 10
+	s.checkQueue = 90
+	d, ok = s._eta(100, 2000, 10*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, d)
+
+	out, err := s.RemoteStats(nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 100.0, out["speedAvg"], 0.1)
+	assert.NotNil(t, out["eta"])
+
+	s.ResetCounters()
+	assert.Equal(t, 0.0, s.avgSpeed)
+}
+
 func TestPercentage(t *testing.T) {
 	assert.Equal(t, percent(0, 1000), "0%")
 	assert.Equal(t, percent(1, 1000), "0%")
@@ -49,3 +87,128 @@ func TestPercentage(t *testing.T) {
 	assert.Equal(t, percent(-100, 100), "-")
 	assert.Equal(t, percent(-100, -100), "-")
 }
+
+func TestStatsServerSide(t *testing.T) {
+	s := NewStats()
+	s.ServerSideCopy(100)
+	s.ServerSideCopy(-1)
+	s.ServerSideMove(50)
+
+	copies, copyBytes := s.GetServerSideCopies()
+	assert.Equal(t, int64(2), copies)
+	assert.Equal(t, int64(100), copyBytes)
+	moves, moveBytes := s.GetServerSideMoves()
+	assert.Equal(t, int64(1), moves)
+	assert.Equal(t, int64(50), moveBytes)
+	assert.Equal(t, int64(0), s.GetBytes())
+
+	out, err := s.RemoteStats(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), out["serverSideCopies"])
+	assert.Equal(t, int64(50), out["serverSideMoveBytes"])
+
+	s.ResetCounters()
+	copies, copyBytes = s.GetServerSideCopies()
+	assert.Equal(t, int64(0), copies)
+	assert.Equal(t, int64(0), copyBytes)
+}
+
+func TestStatsOneLineDate(t *testing.T) {
+	oldOneLine, oldDate, oldFormat := fs.Config.StatsOneLine, fs.Config.StatsOneLineDate, fs.Config.StatsOneLineDateFormat
+	defer func() {
+		fs.Config.StatsOneLine, fs.Config.StatsOneLineDate, fs.Config.StatsOneLineDateFormat = oldOneLine, oldDate, oldFormat
+	}()
+	fs.Config.StatsOneLine = true
+	fs.Config.StatsOneLineDate = true
+	fs.Config.StatsOneLineDateFormat = "[potato] "
+
+	s := NewStats()
+	out := s.String()
+	assert.True(t, strings.HasPrefix(out, "[potato] "), out)
+	assert.NotContains(t, out, "\n")
+
+	fs.Config.StatsOneLineDate = false
+	out = s.String()
+	assert.False(t, strings.HasPrefix(out, "[potato] "), out)
+}
+
+func TestStatsTransferred(t *testing.T) {
+	s := NewStats()
+	s.Transferring("a")
+	s.DoneTransferringError("a", 10, nil)
+	s.Transferring("b")
+	s.DoneTransferring("b", false)
+	s.DoneTransferringError("c", 5, errors.New("potato"))
+
+	assert.Equal(t, int64(1), s.GetTransfers())
+	transferred := s.Transferred()
+	require.Len(t, transferred, 3)
+	assert.Equal(t, "a", transferred[0].Name)
+	assert.Equal(t, int64(10), transferred[0].Size)
+	assert.Equal(t, "", transferred[0].Error)
+	assert.False(t, transferred[0].StartedAt.IsZero())
+	assert.Equal(t, "b", transferred[1].Name)
+	assert.Equal(t, int64(-1), transferred[1].Size)
+	assert.Equal(t, errTransferFailed.Error(), transferred[1].Error)
+	assert.Equal(t, "potato", transferred[2].Error)
+	assert.True(t, transferred[2].StartedAt.IsZero())
+
+	for i := 0; i < 2*maxCompletedTransfers; i++ {
+		s.DoneTransferringError(fmt.Sprint(i), 1, nil)
+	}
+	transferred = s.Transferred()
+	require.Len(t, transferred, maxCompletedTransfers)
+	assert.Equal(t, fmt.Sprint(2*maxCompletedTransfers-1), transferred[maxCompletedTransfers-1].Name)
+
+	s.ResetCounters()
+	assert.Len(t, s.Transferred(), 0)
+}
+
+func TestStatsGroups(t *testing.T) {
+	assert.Equal(t, Stats, StatsGroup(""))
+	group := StatsGroup("job/1")
+	assert.Equal(t, group, StatsGroup("job/1"))
+	assert.NotEqual(t, Stats, group)
+	assert.Contains(t, ListStatsGroups(), "job/1")
+
+	group.Bytes(42)
+	out, err := rcStats(rc.Params{"group": "job/1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), out["bytes"])
+
+	group.DoneTransferringError("file", 42, nil)
+	out, err = rcTransferred(rc.Params{"group": "job/1"})
+	require.NoError(t, err)
+	assert.Len(t, out["transferred"], 1)
+
+	_, err = rcStatsDelete(rc.Params{"group": "job/1"})
+	require.NoError(t, err)
+	assert.NotContains(t, ListStatsGroups(), "job/1")
+	_, err = rcStats(rc.Params{"group": "job/1"})
+	assert.Error(t, err)
+}
+
+func TestStatsOneLineTemplate(t *testing.T) {
+	oldOneLine, oldTemplate := fs.Config.StatsOneLine, fs.Config.StatsOneLineTemplate
+	defer func() {
+		fs.Config.StatsOneLine, fs.Config.StatsOneLineTemplate = oldOneLine, oldTemplate
+	}()
+	fs.Config.StatsOneLine = true
+	fs.Config.StatsOneLineTemplate = `{{.Transfers}}/{{.TotalTransfers}} files {{.Bytes}} ({{.Percent}}) errors {{.Errors}}`
+
+	s := NewStats()
+	s.Bytes(1024)
+	s.Transferring("a")
+	s.DoneTransferring("a", true)
+	s.Error(errors.New("potato"))
+	assert.Equal(t, "1/1 files 1k (100%) errors 1", s.String())
+
+	// Templates which fail fall back to the default format
+	fs.Config.StatsOneLineTemplate = `{{.Potato}}`
+	out := s.String()
+	assert.Contains(t, out, "ETA")
+	assert.NotContains(t, out, "\n")
+
+	_, err := ParseStatsTemplate(`{{.Bytes`)
+	assert.Error(t, err)
+}
@@ -40,7 +40,10 @@ func StartTokenBucket() {
 	currLimit := fs.Config.BwLimit.LimitAt(time.Now())
 	currLimitMu.Unlock()
 
-	if currLimit.Bandwidth > 0 {
+	if currLimit.Pause {
+		schedulePause(true)
+		fs.Infof(nil, "Starting with transfers paused by the bandwidth timetable")
+	} else if currLimit.Bandwidth > 0 {
 		tokenBucket = newTokenBucket(currLimit.Bandwidth)
 		fs.Infof(nil, "Starting bandwidth limiter at %vBytes/s", &currLimit.Bandwidth)
 
@@ -64,7 +67,15 @@ func StartTokenTicker() {
 			limitNow := fs.Config.BwLimit.LimitAt(time.Now())
 			currLimitMu.Lock()
 
-			if currLimit.Bandwidth != limitNow.Bandwidth {
+			if currLimit.Pause != limitNow.Pause {
+				schedulePause(limitNow.Pause)
+				if limitNow.Pause {
+					fs.Logf(nil, "Scheduled bandwidth change. Transfers paused")
+				} else {
+					fs.Logf(nil, "Scheduled bandwidth change. Transfers resumed")
+				}
+			}
+			if currLimit.Bandwidth != limitNow.Bandwidth && !limitNow.Pause {
 				tokenBucketMu.Lock()
 
 				// If bwlimit is toggled off, the change should only
@@ -91,9 +102,9 @@ func StartTokenTicker() {
 					fs.Logf(nil, "Scheduled bandwidth change. Bandwidth limits disabled")
 				}
 
-				currLimit = limitNow
 				tokenBucketMu.Unlock()
 			}
+			currLimit = limitNow
 			currLimitMu.Unlock()
 		}
 	}()
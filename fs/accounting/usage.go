@@ -0,0 +1,198 @@
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// RemoteUsage is the data transferred to and from a remote and the
+// number of requests made to it.
+//
+// The requests are the listings, opens, uploads, deletes and server
+// side copies and moves rclone makes, which may be fewer than the
+// HTTP requests the backend needs to do them.
+type RemoteUsage struct {
+	Uploaded   int64 // bytes uploaded to the remote
+	Downloaded int64 // bytes downloaded from the remote
+	Requests   int64 // requests made to the remote
+}
+
+// CostModel estimates the cost of using a remote
+type CostModel interface {
+	// Cost returns the estimated cost of usage
+	Cost(usage RemoteUsage) float64
+}
+
+// linearCost is the CostModel for the prices set with --cost
+type linearCost fs.Cost
+
+// Cost returns the estimated cost of usage
+func (c linearCost) Cost(usage RemoteUsage) float64 {
+	const GiB = 1 << 30
+	return float64(usage.Downloaded)/GiB*c.Download +
+		float64(usage.Uploaded)/GiB*c.Upload +
+		float64(usage.Requests)/1000*c.Requests
+}
+
+var (
+	usageMu    sync.Mutex
+	usage      = make(map[string]*RemoteUsage) // usage by remote name
+	costModels = make(map[string]CostModel)    // models set with SetCostModel by remote name
+)
+
+// SetCostModel sets the model used to estimate the cost of using the
+// remote called name in place of any prices set with --cost.  A nil
+// model removes it.
+func SetCostModel(name string, model CostModel) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	if model == nil {
+		delete(costModels, name)
+	} else {
+		costModels[name] = model
+	}
+}
+
+// costModel returns the CostModel for the remote called name or nil
+// if there isn't one - call with usageMu held
+func costModel(name string) CostModel {
+	if model, ok := costModels[name]; ok {
+		return model
+	}
+	if cost, ok := fs.Config.Costs[name]; ok {
+		return linearCost(cost)
+	}
+	return nil
+}
+
+// getUsage returns the usage for the remote f or nil if f is nil
+func getUsage(f fs.Info) *RemoteUsage {
+	if f == nil {
+		return nil
+	}
+	name := f.Name()
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	u := usage[name]
+	if u == nil {
+		u = new(RemoteUsage)
+		usage[name] = u
+	}
+	return u
+}
+
+// CountRequest records a request made to the remote f.  A nil f is
+// ignored.
+func CountRequest(f fs.Info) {
+	if u := getUsage(f); u != nil {
+		atomic.AddInt64(&u.Requests, 1)
+	}
+}
+
+// Usage returns a copy of the usage of each remote keyed by remote
+// name.
+func Usage() map[string]RemoteUsage {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	out := make(map[string]RemoteUsage, len(usage))
+	for name, u := range usage {
+		out[name] = RemoteUsage{
+			Uploaded:   atomic.LoadInt64(&u.Uploaded),
+			Downloaded: atomic.LoadInt64(&u.Downloaded),
+			Requests:   atomic.LoadInt64(&u.Requests),
+		}
+	}
+	return out
+}
+
+// ResetUsage forgets the usage of all the remotes
+func ResetUsage() {
+	usageMu.Lock()
+	usage = make(map[string]*RemoteUsage)
+	usageMu.Unlock()
+}
+
+// Costs returns the estimated cost of using each remote which has a
+// cost model keyed by remote name.
+func Costs() map[string]float64 {
+	all := Usage()
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	out := make(map[string]float64)
+	for name, u := range all {
+		if model := costModel(name); model != nil {
+			out[name] = model.Cost(u)
+		}
+	}
+	return out
+}
+
+// UsageString returns a summary of the usage of each remote with the
+// estimated costs, or "" if no remotes have been used.
+func UsageString() string {
+	all := Usage()
+	if len(all) == 0 {
+		return ""
+	}
+	costs := Costs()
+	var names []string
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	_, _ = fmt.Fprintf(buf, "\nUsage by remote:\n")
+	total := 0.0
+	for _, name := range names {
+		u := all[name]
+		_, _ = fmt.Fprintf(buf, " * %s: uploaded %s, downloaded %s, %d requests",
+			name,
+			fs.SizeSuffix(u.Uploaded).Unit("Bytes"),
+			fs.SizeSuffix(u.Downloaded).Unit("Bytes"),
+			u.Requests,
+		)
+		if cost, ok := costs[name]; ok {
+			_, _ = fmt.Fprintf(buf, ", estimated cost %.2f", cost)
+			total += cost
+		}
+		_, _ = fmt.Fprintf(buf, "\n")
+	}
+	if len(costs) > 0 {
+		_, _ = fmt.Fprintf(buf, "Estimated total cost: %.2f\n", total)
+	}
+	return buf.String()
+}
+
+// LogUsage logs the summary of the usage of each remote if
+// --stats-remotes or --cost is in use.
+func LogUsage() {
+	if !fs.Config.StatsRemotes && len(fs.Config.Costs) == 0 {
+		return
+	}
+	if s := UsageString(); s != "" {
+		fs.LogLevelPrintf(fs.Config.StatsLogLevel, nil, "%s", s)
+	}
+}
+
+// rcUsage returns the usage of each remote for core/stats
+func rcUsage() map[string]interface{} {
+	costs := Costs()
+	out := make(map[string]interface{})
+	for name, u := range Usage() {
+		remote := map[string]interface{}{
+			"uploaded":   u.Uploaded,
+			"downloaded": u.Downloaded,
+			"requests":   u.Requests,
+		}
+		if cost, ok := costs[name]; ok {
+			remote["cost"] = cost
+		}
+		out[name] = remote
+	}
+	return out
+}
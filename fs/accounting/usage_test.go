@@ -0,0 +1,61 @@
+package accounting
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedCost is a CostModel which costs the same whatever the usage
+type fixedCost float64
+
+func (c fixedCost) Cost(usage RemoteUsage) float64 { return float64(c) }
+
+func TestUsage(t *testing.T) {
+	oldCosts := fs.Config.Costs
+	defer func() {
+		fs.Config.Costs = oldCosts
+		SetCostModel("model", nil)
+		ResetUsage()
+	}()
+	fs.Config.Costs = nil
+	require.NoError(t, fs.Config.Costs.Set("dst=upload:2,requests:1000"))
+	SetCostModel("model", fixedCost(1.5))
+	ResetUsage()
+
+	src, dst, model := limitedFs{name: "src"}, limitedFs{name: "dst"}, limitedFs{name: "model"}
+	in := ioutil.NopCloser(bytes.NewBuffer(make([]byte, 100)))
+	acc := NewAccountSizeName(in, 100, "test").WithDownload(src).WithUpload(dst).WithDownload(nil).WithUpload(nil)
+	_, err := ioutil.ReadAll(acc)
+	require.NoError(t, err)
+	require.NoError(t, acc.Close())
+	CountRequest(dst)
+	CountRequest(model)
+	CountRequest(nil)
+
+	assert.Equal(t, map[string]RemoteUsage{
+		"src":   {Downloaded: 100, Requests: 1},
+		"dst":   {Uploaded: 100, Requests: 2},
+		"model": {Requests: 1},
+	}, Usage())
+
+	costs := Costs()
+	assert.Len(t, costs, 2)
+	assert.InDelta(t, 2*100.0/(1<<30)+2, costs["dst"], 1e-9)
+	assert.Equal(t, 1.5, costs["model"])
+
+	assert.Equal(t, `
+Usage by remote:
+ * dst: uploaded 100 Bytes, downloaded 0 Bytes, 2 requests, estimated cost 2.00
+ * model: uploaded 0 Bytes, downloaded 0 Bytes, 1 requests, estimated cost 1.50
+ * src: uploaded 0 Bytes, downloaded 100 Bytes, 1 requests
+Estimated total cost: 3.50
+`, UsageString())
+
+	ResetUsage()
+	assert.Equal(t, "", UsageString())
+}
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +15,29 @@ type BwTimeSlot struct {
 	DayOfTheWeek int
 	HHMM         int
 	Bandwidth    SizeSuffix
+	Pause        bool // set if transfers are suspended in this slot
+}
+
+// setBandwidth sets the bandwidth of the time slot in a timetable
+// from s.  A bandwidth of "pause" or 0 suspends the transfers.
+func (ts *BwTimeSlot) setBandwidth(s string) error {
+	if strings.ToLower(s) == "pause" {
+		ts.Bandwidth, ts.Pause = 0, true
+		return nil
+	}
+	if err := ts.Bandwidth.Set(s); err != nil {
+		return err
+	}
+	ts.Pause = ts.Bandwidth == 0
+	return nil
+}
+
+// bandwidthString returns the bandwidth of the time slot as a string
+func (ts BwTimeSlot) bandwidthString() string {
+	if ts.Pause {
+		return "pause"
+	}
+	return ts.Bandwidth.String()
 }
 
 // BwTimetable contains all configured time slots.
@@ -23,7 +47,7 @@ type BwTimetable []BwTimeSlot
 func (x BwTimetable) String() string {
 	ret := []string{}
 	for _, ts := range x {
-		ret = append(ret, fmt.Sprintf("%s-%04.4d,%s", time.Weekday(ts.DayOfTheWeek), ts.HHMM, ts.Bandwidth.String()))
+		ret = append(ret, fmt.Sprintf("%s-%04.4d,%s", time.Weekday(ts.DayOfTheWeek), ts.HHMM, ts.bandwidthString()))
 	}
 	return strings.Join(ret, " ")
 }
@@ -82,12 +106,16 @@ func (x *BwTimetable) Set(s string) error {
 	// The timetable is formatted as:
 	// "dayOfWeek-hh:mm,bandwidth dayOfWeek-hh:mm,banwidth..." ex: "Mon-10:00,10G Mon-11:30,1G Tue-18:00,off"
 	// If only a single bandwidth identifier is provided, we assume constant bandwidth.
+	// A bandwidth of "pause" or 0 in a timetable suspends transfers until the next time slot.
 
 	if len(s) == 0 {
 		return errors.New("empty string")
 	}
 	// Single value without time specification.
 	if !strings.Contains(s, " ") && !strings.Contains(s, ",") {
+		if strings.ToLower(s) == "pause" {
+			return errors.New("can't pause transfers without a timetable")
+		}
 		ts := BwTimeSlot{}
 		if err := ts.Bandwidth.Set(s); err != nil {
 			return err
@@ -98,6 +126,7 @@ func (x *BwTimetable) Set(s string) error {
 		return nil
 	}
 
+	start := len(*x)
 	for _, tok := range strings.Split(s, " ") {
 		tv := strings.Split(tok, ",")
 
@@ -120,7 +149,7 @@ func (x *BwTimetable) Set(s string) error {
 					DayOfTheWeek: i,
 					HHMM:         (hh * 100) + mm,
 				}
-				if err := ts.Bandwidth.Set(tv[1]); err != nil {
+				if err := ts.setBandwidth(tv[1]); err != nil {
 					return err
 				}
 				*x = append(*x, ts)
@@ -147,13 +176,19 @@ func (x *BwTimetable) Set(s string) error {
 				HHMM:         (hh * 100) + mm,
 			}
 			// Bandwidth limit for this time slot.
-			if err := ts.Bandwidth.Set(tv[1]); err != nil {
+			if err := ts.setBandwidth(tv[1]); err != nil {
 				return err
 			}
 			*x = append(*x, ts)
 		}
 	}
-	return nil
+	for _, ts := range (*x)[start:] {
+		if !ts.Pause {
+			return nil
+		}
+	}
+	*x = (*x)[:start]
+	return errors.New("timetable would pause transfers for ever")
 }
 
 //	Difference in minutes between lateDayOfWeekHHMM and earlyDayOfWeekHHMM
@@ -212,3 +247,43 @@ func (x BwTimetable) LimitAt(tt time.Time) BwTimeSlot {
 func (x BwTimetable) Type() string {
 	return "BwTimetable"
 }
+
+// BwRemoteLimits contains the bandwidth timetables set for individual
+// remotes with --bwlimit-remote keyed by remote name.
+type BwRemoteLimits map[string]BwTimetable
+
+// String returns a printable representation of BwRemoteLimits.
+func (x BwRemoteLimits) String() string {
+	var ret []string
+	for name, timetable := range x {
+		ret = append(ret, name+"="+timetable.String())
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, "; ")
+}
+
+// Set adds a remote's bandwidth timetable from "remote=timetable".
+func (x *BwRemoteLimits) Set(s string) error {
+	i := strings.IndexRune(s, '=')
+	if i < 0 {
+		return errors.Errorf("need remote=bandwidth, got %q", s)
+	}
+	name := strings.TrimSuffix(strings.TrimSpace(s[:i]), ":")
+	if name == "" {
+		return errors.Errorf("empty remote name in %q", s)
+	}
+	var timetable BwTimetable
+	if err := timetable.Set(s[i+1:]); err != nil {
+		return errors.Wrapf(err, "bad bandwidth for remote %q", name)
+	}
+	if *x == nil {
+		*x = make(BwRemoteLimits)
+	}
+	(*x)[name] = timetable
+	return nil
+}
+
+// Type of the value
+func (x BwRemoteLimits) Type() string {
+	return "RemoteBwTimetable"
+}
@@ -117,6 +117,19 @@ func TestBwTimetableSet(t *testing.T) {
 			},
 			false,
 		},
+		{"pause", BwTimetable{}, true},
+		{"Mon-10:00,pause", BwTimetable{}, true},
+		{"09:00,0 17:00,pause", BwTimetable{}, true},
+		{
+			"Mon-09:00,pause Mon-17:00,off Tue-09:00,0 Tue-17:00,1M",
+			BwTimetable{
+				BwTimeSlot{DayOfTheWeek: 1, HHMM: 900, Bandwidth: 0, Pause: true},
+				BwTimeSlot{DayOfTheWeek: 1, HHMM: 1700, Bandwidth: -1},
+				BwTimeSlot{DayOfTheWeek: 2, HHMM: 900, Bandwidth: 0, Pause: true},
+				BwTimeSlot{DayOfTheWeek: 2, HHMM: 1700, Bandwidth: 1024 * 1024},
+			},
+			false,
+		},
 	} {
 		tt := BwTimetable{}
 		err := tt.Set(test.in)
@@ -129,6 +142,12 @@ func TestBwTimetableSet(t *testing.T) {
 	}
 }
 
+func TestBwTimetableString(t *testing.T) {
+	tt := BwTimetable{}
+	require.NoError(t, tt.Set("Mon-09:00,pause Mon-17:00,1M"))
+	assert.Equal(t, "Monday-0900,pause Monday-1700,1M", tt.String())
+}
+
 func TestBwTimetableLimitAt(t *testing.T) {
 	for _, test := range []struct {
 		tt   BwTimetable
@@ -327,3 +346,24 @@ func TestBwTimetableLimitAt(t *testing.T) {
 		assert.Equal(t, test.want, slot)
 	}
 }
+
+// Check it satisfies the interface
+var _ pflag.Value = (*BwRemoteLimits)(nil)
+
+func TestBwRemoteLimitsSet(t *testing.T) {
+	var limits BwRemoteLimits
+	assert.Error(t, limits.Set("10M"))
+	assert.Error(t, limits.Set("=10M"))
+	assert.Error(t, limits.Set("remote=bad"))
+	require.NoError(t, limits.Set("remote=10M"))
+	require.NoError(t, limits.Set("other:=Mon-10:00,1M"))
+	assert.Equal(t, BwRemoteLimits{
+		"remote": BwTimetable{
+			BwTimeSlot{DayOfTheWeek: 0, HHMM: 0, Bandwidth: 10 * 1024 * 1024},
+		},
+		"other": BwTimetable{
+			BwTimeSlot{DayOfTheWeek: 1, HHMM: 1000, Bandwidth: 1024 * 1024},
+		},
+	}, limits)
+	assert.Equal(t, "other=Monday-1000,1M; remote=Sunday-0000,10M", limits.String())
+}
@@ -38,58 +38,105 @@ var (
 
 // ConfigInfo is filesystem config options
 type ConfigInfo struct {
-	LogLevel              LogLevel
-	StatsLogLevel         LogLevel
-	DryRun                bool
-	CheckSum              bool
-	SizeOnly              bool
-	IgnoreTimes           bool
-	IgnoreExisting        bool
-	IgnoreErrors          bool
-	ModifyWindow          time.Duration
-	Checkers              int
-	Transfers             int
-	ConnectTimeout        time.Duration // Connect timeout
-	Timeout               time.Duration // Data channel timeout
-	Dump                  DumpFlags
-	InsecureSkipVerify    bool // Skip server certificate verification
-	DeleteMode            DeleteMode
-	MaxDelete             int64
-	TrackRenames          bool // Track file renames.
-	LowLevelRetries       int
-	UpdateOlder           bool // Skip files that are newer on the destination
-	NoGzip                bool // Disable compression
-	MaxDepth              int
-	IgnoreSize            bool
-	IgnoreChecksum        bool
-	NoTraverse            bool
-	NoUpdateModTime       bool
-	DataRateUnit          string
-	BackupDir             string
-	Suffix                string
-	UseListR              bool
-	BufferSize            SizeSuffix
-	BwLimit               BwTimetable
-	TPSLimit              float64
-	TPSLimitBurst         int
-	BindAddr              net.IP
-	DisableFeatures       []string
-	UserAgent             string
-	Immutable             bool
-	AutoConfirm           bool
-	StreamingUploadCutoff SizeSuffix
-	StatsFileNameLength   int
-	AskPassword           bool
-	UseServerModTime      bool
-	MaxTransfer           SizeSuffix
-	MaxBacklog            int
-	StatsOneLine          bool
-	Progress              bool
-	Cookie                bool
-	UseMmap               bool
-	CaCert                string // Client Side CA
-	ClientCert            string // Client Side Cert
-	ClientKey             string // Client Side Key
+	LogLevel                LogLevel
+	StatsLogLevel           LogLevel
+	DryRun                  bool
+	Interactive             bool
+	DryRunPlan              string
+	CheckSum                bool
+	Compare                 string // how to compare hashes: "", "etag", "provider-hash" or "wrapped-hash"
+	SizeOnly                bool
+	IgnoreTimes             bool
+	IgnoreExisting          bool
+	IgnoreErrors            bool
+	IgnoreCaseSync          bool
+	FixCase                 bool
+	Force                   bool
+	RenameCaseCollisions    bool
+	Duplicates              string
+	ModifyWindow            time.Duration
+	Checkers                int
+	Transfers               int
+	AutoTune                bool
+	AutoTuneMaxCheckers     int
+	AutoTuneMaxTransfers    int
+	ConnectTimeout          time.Duration // Connect timeout
+	Timeout                 time.Duration // Data channel timeout
+	IdleTimeout             time.Duration // Close pooled connections unused for this long
+	Dump                    DumpFlags
+	InsecureSkipVerify      bool // Skip server certificate verification
+	DeleteMode              DeleteMode
+	MaxDelete               int64
+	MaxDeleteSize           SizeSuffix // Limit the total size of the files deleted
+	MaxDeletePercent        int        // Don't delete if more than this percentage of the destination would be deleted
+	RefuseEmptySource       bool       // Don't delete if the source is empty
+	TrackRenames            bool       // Track file renames.
+	ServerSideAcrossConfigs bool       // Allow server side copies and moves between remotes of the same type
+	HardLinks               bool       // Recreate hard links on the destination
+	HardLinksManifest       string     // File to record or read hard link groups
+	LowLevelRetries         int
+	UpdateOlder             bool   // Skip files that are newer on the destination
+	ConflictResolve         string // How to decide ambiguous transfers: newer, larger, source or dest
+	NoGzip                  bool   // Disable compression
+	MaxDepth                int
+	IgnoreSize              bool
+	IgnoreChecksum          bool
+	NoTraverse              bool
+	NoUpdateModTime         bool
+	RefreshTimes            bool
+	VerifyTransfers         bool
+	CheckFirst              bool
+	OrderBy                 string
+	DataRateUnit            string
+	BackupDir               string
+	Suffix                  string
+	UseListR                bool
+	FastListAuto            bool
+	BufferSize              SizeSuffix
+	BwLimit                 BwTimetable
+	BwLimitRemote           BwRemoteLimits // Bandwidth limits for individual remotes
+	TPSLimit                float64
+	TPSLimitBurst           int
+	TPSLimitList            float64 // Directory listings per second for each remote
+	BindAddr                net.IP
+	DisableFeatures         []string
+	UserAgent               string
+	MimeType                string   // Force the mime type of uploaded files
+	MimeTypeMap             []string // Extra ext=type mappings used to guess mime types
+	NoMimeDetection         bool     // Don't guess mime types from file names
+	Immutable               bool
+	AutoConfirm             bool
+	StreamingUploadCutoff   SizeSuffix
+	Partial                 bool   // Upload to a partial name and rename it when complete
+	PartialDir              string // Directory relative to the file's to make partial uploads in
+	PartialPrefix           string // Prefix for the names of partial uploads
+	PartialSuffix           string // Suffix for the names of partial uploads
+	StatsFileNameLength     int
+	AskPassword             bool
+	UseServerModTime        bool
+	MaxTransfer             SizeSuffix
+	CutoffMode              string // what to do with the transfers in progress on SIGINT or SIGTERM: "hard" or "soft"
+	MinFreeSpace            SizeSuffix
+	Manifest                string
+	ErrorReport             string
+	FailoverDest            string
+	UploadCache             string
+	UploadCacheTTL          time.Duration
+	UploadCacheVerify       bool
+	UseListCache            time.Duration
+	MaxBacklog              int
+	StatsOneLine            bool
+	StatsOneLineDate        bool        // If we want a date prefix at all
+	StatsOneLineDateFormat  string      // If we want to customize the prefix
+	StatsOneLineTemplate    string      // text/template for the one line stats if set
+	StatsRemotes            bool        // Show the usage of each remote at the end
+	Costs                   RemoteCosts // Prices of individual remotes to estimate the cost of the run
+	Progress                bool
+	Cookie                  bool
+	UseMmap                 bool
+	CaCert                  string // Client Side CA
+	ClientCert              string // Client Side Cert
+	ClientKey               string // Client Side Key
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -104,10 +151,14 @@ func NewConfig() *ConfigInfo {
 	c.ModifyWindow = time.Nanosecond
 	c.Checkers = 8
 	c.Transfers = 4
+	c.AutoTuneMaxCheckers = 64
+	c.AutoTuneMaxTransfers = 32
 	c.ConnectTimeout = 60 * time.Second
 	c.Timeout = 5 * 60 * time.Second
 	c.DeleteMode = DeleteModeDefault
 	c.MaxDelete = -1
+	c.MaxDeleteSize = -1
+	c.MaxDeletePercent = -1
 	c.LowLevelRetries = 10
 	c.MaxDepth = -1
 	c.DataRateUnit = "bytes"
@@ -115,10 +166,14 @@ func NewConfig() *ConfigInfo {
 	c.UserAgent = "rclone/" + Version
 	c.StreamingUploadCutoff = SizeSuffix(100 * 1024)
 	c.StatsFileNameLength = 45
+	c.StatsOneLineDateFormat = "2006/01/02 15:04:05 - "
 	c.AskPassword = true
 	c.TPSLimitBurst = 1
 	c.MaxTransfer = -1
+	c.CutoffMode = "hard"
+	c.MinFreeSpace = -1
 	c.MaxBacklog = 10000
+	c.Duplicates = "refuse"
 
 	return c
 }
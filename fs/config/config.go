@@ -35,6 +35,7 @@ import (
 	"github.com/ncw/rclone/fs/fspath"
 	"github.com/ncw/rclone/fs/rc"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 	"golang.org/x/crypto/nacl/secretbox"
 	"golang.org/x/text/unicode/norm"
 )
@@ -207,6 +208,12 @@ func LoadConfig() {
 		fs.Debugf(nil, "Using config file from %q", ConfigPath)
 	}
 
+	// Apply any presets before the flags are used below
+	err = applyPresets(pflag.CommandLine)
+	if err != nil {
+		log.Fatalf("Failed to apply --preset: %v", err)
+	}
+
 	// Start the token bucket limiter
 	accounting.StartTokenBucket()
 
@@ -589,7 +596,7 @@ func FileGetFresh(section, key string) (value string, err error) {
 
 // ShowRemotes shows an overview of the config file
 func ShowRemotes() {
-	remotes := getConfigData().GetSectionList()
+	remotes := remoteSections()
 	if len(remotes) == 0 {
 		return
 	}
@@ -597,13 +604,13 @@ func ShowRemotes() {
 	fmt.Printf("%-20s %s\n", "Name", "Type")
 	fmt.Printf("%-20s %s\n", "====", "====")
 	for _, remote := range remotes {
-		fmt.Printf("%-20s %s\n", remote, FileGet(remote, "type"))
+		fmt.Printf("%-20s %s\n", remote, RemoteType(remote))
 	}
 }
 
 // ChooseRemote chooses a remote name
 func ChooseRemote() string {
-	remotes := getConfigData().GetSectionList()
+	remotes := remoteSections()
 	sort.Strings(remotes)
 	return Choose("remote", remotes, nil, false)
 }
@@ -791,10 +798,17 @@ func OkRemote(name string) bool {
 	return false
 }
 
+// RemoteType returns the type of the remote name, reading it from
+// its template if the remote doesn't set it, or "" if not found.
+func RemoteType(name string) string {
+	fsType, _ := fs.ConfigMap(nil, name).Get("type")
+	return fsType
+}
+
 // MustFindByName finds the RegInfo for the remote name passed in or
 // exits with a fatal error.
 func MustFindByName(name string) *fs.RegInfo {
-	fsType := FileGet(name, "type")
+	fsType := RemoteType(name)
 	if fsType == "" {
 		log.Fatalf("Couldn't find type of fs for %q", name)
 	}
@@ -1164,7 +1178,7 @@ func ShowConfig() {
 // EditConfig edits the config file interactively
 func EditConfig() {
 	for {
-		haveRemotes := len(getConfigData().GetSectionList()) != 0
+		haveRemotes := len(remoteSections()) != 0
 		what := []string{"eEdit existing remote", "nNew remote", "dDelete remote", "rRename remote", "cCopy remote", "sSet configuration password", "qQuit config"}
 		if haveRemotes {
 			fmt.Printf("Current remotes:\n\n")
@@ -1274,7 +1288,10 @@ func Authorize(args []string) {
 // the value and true if found and or ("", false) otherwise
 func FileGetFlag(section, key string) (string, bool) {
 	newValue, err := getConfigData().GetValue(section, key)
-	return newValue, err == nil
+	if err != nil {
+		return "", false
+	}
+	return fileGetKeyring(section, key, newValue)
 }
 
 // FileGet gets the config key under section returning the
@@ -1292,7 +1309,13 @@ func FileGet(section, key string, defaultVal ...string) string {
 
 // FileSet sets the key in section to value.  It doesn't save
 // the config file.
+//
+// If the key is kept in the system keyring then value is stored
+// there instead.
 func FileSet(section, key, value string) {
+	if fileSetKeyring(section, key, value) {
+		return
+	}
 	if value != "" {
 		getConfigData().SetValue(section, key, value)
 	} else {
@@ -1312,7 +1335,7 @@ var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
 // FileSections returns the sections in the config file
 // including any defined by environment variables.
 func FileSections() []string {
-	sections := getConfigData().GetSectionList()
+	sections := remoteSections()
 	for _, item := range os.Environ() {
 		matches := matchEnv.FindStringSubmatch(item)
 		if len(matches) == 2 {
@@ -227,3 +227,29 @@ func TestMatchProvider(t *testing.T) {
 		assert.Equal(t, test.want, got, what)
 	}
 }
+
+func TestRemoteType(t *testing.T) {
+	configKey = nil // reset password
+	tempFile, err := ioutil.TempFile("", "template.conf")
+	require.NoError(t, err)
+	path := tempFile.Name()
+	defer func() {
+		_ = os.Remove(path)
+	}()
+	_, err = tempFile.WriteString("[base]\ntype = local\n\n[child]\ntemplate = base\n\n[other]\nkey = value\n")
+	require.NoError(t, err)
+	require.NoError(t, tempFile.Close())
+	oldConfigPath, oldConfigFile := ConfigPath, configFile
+	ConfigPath = path
+	configFile = nil
+	defer func() {
+		ConfigPath, configFile = oldConfigPath, oldConfigFile
+	}()
+	LoadConfig()
+
+	assert.Equal(t, "local", RemoteType("base"))
+	assert.Equal(t, "local", RemoteType("child"))
+	assert.Equal(t, "", RemoteType("other"))
+	assert.Equal(t, "", RemoteType("missing"))
+	assert.Equal(t, "local", MustFindByName("child").Name)
+}
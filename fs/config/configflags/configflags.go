@@ -6,10 +6,13 @@ package configflags
 import (
 	"log"
 	"net"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/config"
 	"github.com/ncw/rclone/fs/config/flags"
 	"github.com/ncw/rclone/fs/rc"
@@ -27,6 +30,7 @@ var (
 	deleteAfter     bool
 	bindAddr        string
 	disableFeatures string
+	fastList        = "false"
 )
 
 // AddFlags adds the non filing system specific flags to the command
@@ -38,16 +42,29 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.DurationVarP(flagSet, &fs.Config.ModifyWindow, "modify-window", "", fs.Config.ModifyWindow, "Max time diff to be considered the same")
 	flags.IntVarP(flagSet, &fs.Config.Checkers, "checkers", "", fs.Config.Checkers, "Number of checkers to run in parallel.")
 	flags.IntVarP(flagSet, &fs.Config.Transfers, "transfers", "", fs.Config.Transfers, "Number of file transfers to run in parallel.")
+	flags.BoolVarP(flagSet, &fs.Config.AutoTune, "auto-tune", "", fs.Config.AutoTune, "Adjust the number of checkers and transfers while running.")
+	flags.IntVarP(flagSet, &fs.Config.AutoTuneMaxCheckers, "auto-tune-max-checkers", "", fs.Config.AutoTuneMaxCheckers, "Maximum number of checkers to run with --auto-tune.")
+	flags.IntVarP(flagSet, &fs.Config.AutoTuneMaxTransfers, "auto-tune-max-transfers", "", fs.Config.AutoTuneMaxTransfers, "Maximum number of transfers to run with --auto-tune.")
 	flags.StringVarP(flagSet, &config.ConfigPath, "config", "", config.ConfigPath, "Config file.")
+	flags.StringVarP(flagSet, &config.Preset, "preset", "", config.Preset, "Comma separated list of presets from the config file to set flags from.")
 	flags.StringVarP(flagSet, &config.CacheDir, "cache-dir", "", config.CacheDir, "Directory rclone will use for caching.")
 	flags.BoolVarP(flagSet, &fs.Config.CheckSum, "checksum", "c", fs.Config.CheckSum, "Skip based on checksum (if available) & size, not mod-time & size")
+	flags.StringVarP(flagSet, &fs.Config.Compare, "compare", "", fs.Config.Compare, "Compare checksums using the etag, provider-hash or wrapped-hash of the destination. Implies --checksum. Crypt uploads never match with wrapped-hash.")
 	flags.BoolVarP(flagSet, &fs.Config.SizeOnly, "size-only", "", fs.Config.SizeOnly, "Skip based on size only, not mod-time or checksum")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreTimes, "ignore-times", "I", fs.Config.IgnoreTimes, "Don't skip files that match size and time - transfer all files")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreExisting, "ignore-existing", "", fs.Config.IgnoreExisting, "Skip all files that exist on destination")
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreErrors, "ignore-errors", "", fs.Config.IgnoreErrors, "delete even if there are I/O errors")
+	flags.BoolVarP(flagSet, &fs.Config.IgnoreCaseSync, "ignore-case-sync", "", fs.Config.IgnoreCaseSync, "Ignore case when synchronizing")
+	flags.BoolVarP(flagSet, &fs.Config.Force, "force", "", fs.Config.Force, "Allow --delete-excluded to delete files rather than just reporting them")
+	flags.BoolVarP(flagSet, &fs.Config.FixCase, "fix-case", "", fs.Config.FixCase, "Rename destination files and directories to match the case of the source")
+	flags.BoolVarP(flagSet, &fs.Config.RenameCaseCollisions, "rename-case-collisions", "", fs.Config.RenameCaseCollisions, "Rename source files whose names differ only in case instead of skipping them")
+	flags.StringVarP(flagSet, &fs.Config.Duplicates, "duplicates", "", fs.Config.Duplicates, "How to sync objects with the same name: refuse|newest|rename")
 	flags.BoolVarP(flagSet, &fs.Config.DryRun, "dry-run", "n", fs.Config.DryRun, "Do a trial run with no permanent changes")
+	flags.StringVarP(flagSet, &fs.Config.DryRunPlan, "dry-run-plan", "", fs.Config.DryRunPlan, "Write the changes a sync would make to this file as JSON, - for stdout. Implies --dry-run")
+	flags.BoolVarP(flagSet, &fs.Config.Interactive, "interactive", "i", fs.Config.Interactive, "Enable interactive mode")
 	flags.DurationVarP(flagSet, &fs.Config.ConnectTimeout, "contimeout", "", fs.Config.ConnectTimeout, "Connect timeout")
 	flags.DurationVarP(flagSet, &fs.Config.Timeout, "timeout", "", fs.Config.Timeout, "IO idle timeout")
+	flags.DurationVarP(flagSet, &fs.Config.IdleTimeout, "idle-timeout", "", fs.Config.IdleTimeout, "Close connections unused for this long (0 to keep them open - the default)")
 	flags.BoolVarP(flagSet, &dumpHeaders, "dump-headers", "", false, "Dump HTTP bodies - may contain sensitive info")
 	flags.BoolVarP(flagSet, &dumpBodies, "dump-bodies", "", false, "Dump HTTP headers and bodies - may contain sensitive info")
 	flags.BoolVarP(flagSet, &fs.Config.InsecureSkipVerify, "no-check-certificate", "", fs.Config.InsecureSkipVerify, "Do not verify the server SSL certificate. Insecure.")
@@ -56,9 +73,16 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &deleteDuring, "delete-during", "", false, "When synchronizing, delete files during transfer")
 	flags.BoolVarP(flagSet, &deleteAfter, "delete-after", "", false, "When synchronizing, delete files on destination after transferring (default)")
 	flags.IntVar64P(flagSet, &fs.Config.MaxDelete, "max-delete", "", -1, "When synchronizing, limit the number of deletes")
+	flags.FVarP(flagSet, &fs.Config.MaxDeleteSize, "max-delete-size", "", "When synchronizing, limit the total size of deletes")
+	flags.IntVarP(flagSet, &fs.Config.MaxDeletePercent, "max-delete-percent", "", fs.Config.MaxDeletePercent, "When synchronizing, don't delete anything if more than this percentage of the destination files would be deleted")
+	flags.BoolVarP(flagSet, &fs.Config.RefuseEmptySource, "refuse-empty-source", "", fs.Config.RefuseEmptySource, "When synchronizing, don't delete anything if the source is empty")
+	flags.BoolVarP(flagSet, &fs.Config.ServerSideAcrossConfigs, "server-side-across-configs", "", fs.Config.ServerSideAcrossConfigs, "Allow server side copies and moves between different remotes of the same type.")
 	flags.BoolVarP(flagSet, &fs.Config.TrackRenames, "track-renames", "", fs.Config.TrackRenames, "When synchronizing, track file renames and do a server side move if possible")
+	flags.BoolVarP(flagSet, &fs.Config.HardLinks, "hard-links", "", fs.Config.HardLinks, "Recreate files hard linked together on the source as hard links on the destination.")
+	flags.StringVarP(flagSet, &fs.Config.HardLinksManifest, "hard-links-manifest", "", fs.Config.HardLinksManifest, "File to record hard link groups in if the destination can't hard link, or to read them from if the source can't.")
 	flags.IntVarP(flagSet, &fs.Config.LowLevelRetries, "low-level-retries", "", fs.Config.LowLevelRetries, "Number of low level retries to do.")
 	flags.BoolVarP(flagSet, &fs.Config.UpdateOlder, "update", "u", fs.Config.UpdateOlder, "Skip files that are newer on the destination.")
+	flags.StringVarP(flagSet, &fs.Config.ConflictResolve, "conflict-resolve", "", fs.Config.ConflictResolve, "How to resolve files whose sizes differ when the mod times don't decide: newer|larger|source|dest.")
 	flags.BoolVarP(flagSet, &fs.Config.UseServerModTime, "use-server-modtime", "", fs.Config.UseServerModTime, "Use server modified time instead of object metadata")
 	flags.BoolVarP(flagSet, &fs.Config.NoGzip, "no-gzip-encoding", "", fs.Config.NoGzip, "Don't set Accept-Encoding: gzip.")
 	flags.IntVarP(flagSet, &fs.Config.MaxDepth, "max-depth", "", fs.Config.MaxDepth, "If set limits the recursion depth to this.")
@@ -66,26 +90,54 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &fs.Config.IgnoreChecksum, "ignore-checksum", "", fs.Config.IgnoreChecksum, "Skip post copy check of checksums.")
 	flags.BoolVarP(flagSet, &fs.Config.NoTraverse, "no-traverse", "", fs.Config.NoTraverse, "Don't traverse destination file system on copy.")
 	flags.BoolVarP(flagSet, &fs.Config.NoUpdateModTime, "no-update-modtime", "", fs.Config.NoUpdateModTime, "Don't update destination mod-time if files identical.")
+	flags.BoolVarP(flagSet, &fs.Config.CheckFirst, "check-first", "", fs.Config.CheckFirst, "Do all the checks before starting transfers.")
+	flags.StringVarP(flagSet, &fs.Config.OrderBy, "order-by", "", fs.Config.OrderBy, "Instructions on how to order the transfers, eg 'size,descending'")
+	flags.BoolVarP(flagSet, &fs.Config.VerifyTransfers, "verify-transfers", "", fs.Config.VerifyTransfers, "Retry transfers which fail the size or hash check after transfer.")
+	flags.BoolVarP(flagSet, &fs.Config.RefreshTimes, "refresh-times", "", fs.Config.RefreshTimes, "Re-upload identical files if their mod-time can't be updated in place.")
 	flags.StringVarP(flagSet, &fs.Config.BackupDir, "backup-dir", "", fs.Config.BackupDir, "Make backups into hierarchy based in DIR.")
 	flags.StringVarP(flagSet, &fs.Config.Suffix, "suffix", "", fs.Config.Suffix, "Suffix for use with --backup-dir.")
-	flags.BoolVarP(flagSet, &fs.Config.UseListR, "fast-list", "", fs.Config.UseListR, "Use recursive list if available. Uses more memory but fewer transactions.")
+	flags.StringVarP(flagSet, &fastList, "fast-list", "", fastList, "Use recursive list if available. Uses more memory but fewer transactions. Set to auto to decide for each directory.")
+	flagSet.Lookup("fast-list").NoOptDefVal = "true"
 	flags.Float64VarP(flagSet, &fs.Config.TPSLimit, "tpslimit", "", fs.Config.TPSLimit, "Limit HTTP transactions per second to this.")
 	flags.IntVarP(flagSet, &fs.Config.TPSLimitBurst, "tpslimit-burst", "", fs.Config.TPSLimitBurst, "Max burst of transactions for --tpslimit.")
+	flags.Float64VarP(flagSet, &fs.Config.TPSLimitList, "tpslimit-list", "", fs.Config.TPSLimitList, "Limit directory listings per second on each remote to this.")
 	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6 or name.")
 	flags.StringVarP(flagSet, &disableFeatures, "disable", "", "", "Disable a comma separated list of features.  Use help to see a list.")
 	flags.StringVarP(flagSet, &fs.Config.UserAgent, "user-agent", "", fs.Config.UserAgent, "Set the user-agent to a specified string. The default is rclone/ version")
+	flags.StringVarP(flagSet, &fs.Config.MimeType, "mime-type", "", fs.Config.MimeType, "Set the mime type of all uploaded files to this.")
+	flags.StringArrayVarP(flagSet, &fs.Config.MimeTypeMap, "mime-type-map", "", fs.Config.MimeTypeMap, "Mime type for a file extension as ext=type, eg .js=text/javascript. May be repeated.")
+	flags.BoolVarP(flagSet, &fs.Config.NoMimeDetection, "no-mime-detection", "", fs.Config.NoMimeDetection, "Don't guess mime types from file extensions, use application/octet-stream.")
 	flags.BoolVarP(flagSet, &fs.Config.Immutable, "immutable", "", fs.Config.Immutable, "Do not modify files. Fail if existing files have been modified.")
 	flags.BoolVarP(flagSet, &fs.Config.AutoConfirm, "auto-confirm", "", fs.Config.AutoConfirm, "If enabled, do not request console confirmation.")
 	flags.IntVarP(flagSet, &fs.Config.StatsFileNameLength, "stats-file-name-length", "", fs.Config.StatsFileNameLength, "Max file name length in stats. 0 for no limit")
 	flags.FVarP(flagSet, &fs.Config.LogLevel, "log-level", "", "Log level DEBUG|INFO|NOTICE|ERROR")
 	flags.FVarP(flagSet, &fs.Config.StatsLogLevel, "stats-log-level", "", "Log level to show --stats output DEBUG|INFO|NOTICE|ERROR")
 	flags.FVarP(flagSet, &fs.Config.BwLimit, "bwlimit", "", "Bandwidth limit in kBytes/s, or use suffix b|k|M|G or a full timetable.")
+	flags.FVarP(flagSet, &fs.Config.BwLimitRemote, "bwlimit-remote", "", "Bandwidth limit for transfers to or from a remote as remote=BANDWIDTH_SPEC. May be repeated.")
 	flags.FVarP(flagSet, &fs.Config.BufferSize, "buffer-size", "", "In memory buffer size when reading files for each --transfer.")
+	flags.BoolVarP(flagSet, &fs.Config.Partial, "partial", "", fs.Config.Partial, "Upload to a partial file renamed when complete on remotes which support it.")
+	flags.StringVarP(flagSet, &fs.Config.PartialDir, "partial-dir", "", fs.Config.PartialDir, "Directory relative to the file's to make partial uploads in instead of the remote's default.")
+	flags.StringVarP(flagSet, &fs.Config.PartialPrefix, "partial-prefix", "", fs.Config.PartialPrefix, "Prefix for the names of partial uploads instead of the remote's default, eg . to hide them.")
+	flags.StringVarP(flagSet, &fs.Config.PartialSuffix, "partial-suffix", "", fs.Config.PartialSuffix, "Suffix for the names of partial uploads instead of the remote's default.")
 	flags.FVarP(flagSet, &fs.Config.StreamingUploadCutoff, "streaming-upload-cutoff", "", "Cutoff for switching to chunked upload if file size is unknown. Upload starts after reaching cutoff or when file ends.")
 	flags.FVarP(flagSet, &fs.Config.Dump, "dump", "", "List of items to dump from: "+fs.DumpFlagsList)
 	flags.FVarP(flagSet, &fs.Config.MaxTransfer, "max-transfer", "", "Maximum size of data to transfer.")
+	flags.StringVarP(flagSet, &fs.Config.CutoffMode, "cutoff-mode", "", fs.Config.CutoffMode, "Stop the transfers in progress on SIGINT or SIGTERM if hard, or let them finish if soft.")
+	flags.StringVarP(flagSet, &fs.Config.ErrorReport, "error-report", "", fs.Config.ErrorReport, "Write the files which failed to this file as JSON lines, .csv or .txt for --files-from.")
+	flags.StringVarP(flagSet, &fs.Config.FailoverDest, "failover-dest", "", fs.Config.FailoverDest, "Copy files which fail to upload to the destination to this remote:path instead.")
+	flags.StringVarP(flagSet, &fs.Config.Manifest, "manifest", "", fs.Config.Manifest, "Write a list of the files checked and transferred to this file as JSON lines or .csv.")
+	flags.StringVarP(flagSet, &fs.Config.UploadCache, "upload-cache", "", fs.Config.UploadCache, "Database of uploaded files used to skip unchanged files without checking the destination.")
+	flags.DurationVarP(flagSet, &fs.Config.UploadCacheTTL, "upload-cache-ttl", "", fs.Config.UploadCacheTTL, "Ignore --upload-cache entries older than this (0 for never).")
+	flags.BoolVarP(flagSet, &fs.Config.UploadCacheVerify, "upload-cache-verify", "", fs.Config.UploadCacheVerify, "Check the destination exists before skipping files found in --upload-cache.")
+	flags.DurationVarP(flagSet, &fs.Config.UseListCache, "use-list-cache", "", fs.Config.UseListCache, "Cache directory listings on disk for this long so later commands can use them (0 to disable).")
+	flags.FVarP(flagSet, &fs.Config.MinFreeSpace, "min-free-space", "", "Stop starting transfers when the destination has less free space than this.")
 	flags.IntVarP(flagSet, &fs.Config.MaxBacklog, "max-backlog", "", fs.Config.MaxBacklog, "Maximum number of objects in sync or check backlog.")
 	flags.BoolVarP(flagSet, &fs.Config.StatsOneLine, "stats-one-line", "", fs.Config.StatsOneLine, "Make the stats fit on one line.")
+	flags.BoolVarP(flagSet, &fs.Config.StatsOneLineDate, "stats-one-line-date", "", fs.Config.StatsOneLineDate, "Enables --stats-one-line and add current date/time prefix.")
+	flags.StringVarP(flagSet, &fs.Config.StatsOneLineDateFormat, "stats-one-line-date-format", "", fs.Config.StatsOneLineDateFormat, "Enables --stats-one-line-date and uses custom formatted date. Enclose date string in double quotes (\"). See https://golang.org/pkg/time/#Time.Format")
+	flags.StringVarP(flagSet, &fs.Config.StatsOneLineTemplate, "stats-one-line-template", "", fs.Config.StatsOneLineTemplate, "Enables --stats-one-line and formats the line with this Go template, eg \"{{.Bytes}} at {{.Speed}}/s\".")
+	flags.BoolVarP(flagSet, &fs.Config.StatsRemotes, "stats-remotes", "", fs.Config.StatsRemotes, "Show the data transferred and requests made for each remote at the end.")
+	flags.FVarP(flagSet, &fs.Config.Costs, "cost", "", "Prices for a remote as remote=download:PRICE,upload:PRICE,requests:PRICE to estimate the cost of the run. May be repeated.")
 	flags.BoolVarP(flagSet, &fs.Config.Progress, "progress", "P", fs.Config.Progress, "Show progress during transfer.")
 	flags.BoolVarP(flagSet, &fs.Config.Cookie, "use-cookies", "", fs.Config.Cookie, "Enable session cookiejar.")
 	flags.BoolVarP(flagSet, &fs.Config.UseMmap, "use-mmap", "", fs.Config.UseMmap, "Use mmap allocator (see docs).")
@@ -96,6 +148,11 @@ func AddFlags(flagSet *pflag.FlagSet) {
 
 // SetFlags converts any flags into config which weren't straight foward
 func SetFlags() {
+	if config.Preset != "" {
+		// Load the config file now to set the flags from the
+		// presets before they are used
+		config.LoadConfig()
+	}
 	if verbose >= 2 {
 		fs.Config.LogLevel = fs.LogLevelDebug
 	} else if verbose >= 1 {
@@ -117,6 +174,24 @@ func SetFlags() {
 		}
 	}
 
+	dateFormatFlag := pflag.Lookup("stats-one-line-date-format")
+	if dateFormatFlag != nil && dateFormatFlag.Changed {
+		fs.Config.StatsOneLineDate = true
+	}
+	if fs.Config.StatsOneLineDate {
+		fs.Config.StatsOneLine = true
+	}
+	if fs.Config.StatsOneLineTemplate != "" {
+		if _, err := accounting.ParseStatsTemplate(fs.Config.StatsOneLineTemplate); err != nil {
+			log.Fatalf("Invalid --stats-one-line-template: %v", err)
+		}
+		fs.Config.StatsOneLine = true
+	}
+
+	if fs.Config.DryRunPlan != "" {
+		fs.Config.DryRun = true
+	}
+
 	if dumpHeaders {
 		fs.Config.Dump |= fs.DumpHeaders
 		fs.Logf(nil, "--dump-headers is obsolete - please use --dump headers instead")
@@ -126,6 +201,42 @@ func SetFlags() {
 		fs.Logf(nil, "--dump-bodies is obsolete - please use --dump bodies instead")
 	}
 
+	switch fs.Config.Compare {
+	case "":
+	case "etag", "provider-hash", "wrapped-hash":
+		fs.Config.CheckSum = true
+	default:
+		log.Fatalf(`--compare must be etag, provider-hash or wrapped-hash not %q`, fs.Config.Compare)
+	}
+
+	if fs.Config.MaxDeletePercent > 100 {
+		log.Fatalf("--max-delete-percent must be 100 or less")
+	}
+
+	switch fs.Config.CutoffMode {
+	case "hard", "soft":
+	default:
+		log.Fatalf(`--cutoff-mode must be hard or soft not %q`, fs.Config.CutoffMode)
+	}
+
+	switch fs.Config.ConflictResolve {
+	case "", "newer", "larger", "source", "dest":
+	default:
+		log.Fatalf(`--conflict-resolve must be newer, larger, source or dest not %q`, fs.Config.ConflictResolve)
+	}
+
+	if fs.Config.RefreshTimes && fs.Config.NoUpdateModTime {
+		log.Fatalf(`Can't use --refresh-times with --no-update-modtime.`)
+	}
+
+	if strings.ToLower(fastList) == "auto" {
+		fs.Config.FastListAuto = true
+	} else if useListR, err := strconv.ParseBool(fastList); err != nil {
+		log.Fatalf(`--fast-list must be true, false or auto not %q`, fastList)
+	} else if useListR {
+		fs.Config.UseListR = true
+	}
+
 	switch {
 	case deleteBefore && (deleteDuring || deleteAfter),
 		deleteDuring && deleteAfter:
@@ -140,6 +251,12 @@ func SetFlags() {
 		fs.Config.DeleteMode = fs.DeleteModeDefault
 	}
 
+	switch fs.Config.Duplicates {
+	case "refuse", "newest", "rename":
+	default:
+		log.Fatalf(`--duplicates must be one of refuse, newest or rename, not %q`, fs.Config.Duplicates)
+	}
+
 	if fs.Config.IgnoreSize && fs.Config.SizeOnly {
 		log.Fatalf(`Can't use --size-only and --ignore-size together.`)
 	}
@@ -159,6 +276,21 @@ func SetFlags() {
 		fs.Config.BindAddr = addrs[0]
 	}
 
+	if strings.ContainsRune(fs.Config.PartialPrefix, '/') || strings.ContainsRune(fs.Config.PartialSuffix, '/') {
+		log.Fatalf("--partial-prefix and --partial-suffix can't contain /")
+	}
+	if partialDir := path.Clean(fs.Config.PartialDir); path.IsAbs(partialDir) || partialDir == ".." || strings.HasPrefix(partialDir, "../") {
+		log.Fatalf("--partial-dir must be a relative path within the destination")
+	}
+
+	if fs.Config.MimeType != "" && !strings.ContainsRune(fs.Config.MimeType, '/') {
+		log.Fatalf("--mime-type: %q isn't a valid mime type", fs.Config.MimeType)
+	}
+	err := fs.AddMimeTypeMap(fs.Config.MimeTypeMap)
+	if err != nil {
+		log.Fatalf("--mime-type-map: %v", err)
+	}
+
 	if disableFeatures != "" {
 		if disableFeatures == "help" {
 			log.Fatalf("Possible backend features are: %s\n", strings.Join(new(fs.Features).List(), ", "))
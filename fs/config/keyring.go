@@ -0,0 +1,151 @@
+// Keep config values in the system keyring
+
+package config
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// KeyringValue is stored in the config file in place of a value
+// which is kept in the system keyring
+const KeyringValue = "@keyring"
+
+// keyringService is the name the values are stored under in the
+// keyring
+const keyringService = "rclone"
+
+var (
+	keyringMu    sync.Mutex
+	keyringCache = map[string]string{} // values read from the keyring by account
+)
+
+// keyringAccount returns the account in the keyring for key in the
+// remote section
+func keyringAccount(section, key string) string {
+	return section + "/" + key
+}
+
+// runKeyringCommand runs the command in args with stdin as its input
+// returning the output without the trailing newline
+var runKeyringCommand = func(stdin string, args ...string) (string, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// keyringGet reads the value of key in the remote section from the
+// keyring
+func keyringGet(section, key string) (value string, err error) {
+	account := keyringAccount(section, key)
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	if value, ok := keyringCache[account]; ok {
+		return value, nil
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		value, err = runKeyringCommand("", "security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	case "windows":
+		err = errors.New("keyring not supported on windows")
+	default:
+		value, err = runKeyringCommand("", "secret-tool", "lookup", "service", keyringService, "account", account)
+	}
+	if err != nil {
+		return "", err
+	}
+	keyringCache[account] = value
+	return value, nil
+}
+
+// keyringSet stores value for key in the remote section in the
+// keyring
+func keyringSet(section, key, value string) (err error) {
+	account := keyringAccount(section, key)
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	switch runtime.GOOS {
+	case "darwin":
+		// -w without a value prompts for it twice on stdin so it
+		// isn't visible on the command line
+		_, err = runKeyringCommand(value+"\n"+value+"\n", "security", "add-generic-password", "-U", "-s", keyringService, "-a", account, "-w")
+	case "windows":
+		err = errors.New("keyring not supported on windows")
+	default:
+		_, err = runKeyringCommand(value, "secret-tool", "store", "--label", "rclone "+account, "service", keyringService, "account", account)
+	}
+	if err != nil {
+		return err
+	}
+	keyringCache[account] = value
+	return nil
+}
+
+// fileSetKeyring stores value in the keyring if key in the remote
+// section is kept there, returning true if it was stored.
+//
+// This is so values which are updated, eg tokens when they are
+// refreshed, stay in the keyring rather than being written to the
+// config file.
+func fileSetKeyring(section, key, value string) bool {
+	if value == "" || value == KeyringValue {
+		return false
+	}
+	if current, err := getConfigData().GetValue(section, key); err != nil || current != KeyringValue {
+		return false
+	}
+	err := keyringSet(section, key, value)
+	if err != nil {
+		fs.Errorf(nil, "Failed to store %q for remote %q in the keyring - storing it in the config file: %v", key, section, err)
+		return false
+	}
+	return true
+}
+
+// fileGetKeyring returns value, or the value from the keyring if it
+// is KeyringValue
+func fileGetKeyring(section, key, value string) (string, bool) {
+	if value != KeyringValue {
+		return value, true
+	}
+	value, err := keyringGet(section, key)
+	if err != nil {
+		fs.Errorf(nil, "Failed to read %q for remote %q from the keyring: %v", key, section, err)
+		return "", false
+	}
+	return value, true
+}
+
+// KeyringRemote moves the values of keys in the remote name from the
+// config file into the system keyring, leaving KeyringValue in their
+// place.
+func KeyringRemote(name string, keys []string) error {
+	for _, key := range keys {
+		value, err := getConfigData().GetValue(name, key)
+		if err != nil {
+			return errors.Errorf("%q not set in remote %q", key, name)
+		}
+		if value == KeyringValue {
+			continue
+		}
+		err = keyringSet(name, key, value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to store %q in the keyring", key)
+		}
+		getConfigData().SetValue(name, key, KeyringValue)
+	}
+	SaveConfig()
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("keyring not supported on windows")
+	}
+	configKey = nil // reset password
+	tempFile, err := ioutil.TempFile("", "keyring.conf")
+	require.NoError(t, err)
+	path := tempFile.Name()
+	defer func() {
+		_ = os.Remove(path)
+	}()
+	_, err = tempFile.WriteString("[remote]\ntype = local\nsecret = potato\n")
+	require.NoError(t, err)
+	require.NoError(t, tempFile.Close())
+
+	// Fake the keyring
+	keyring := map[string]string{}
+	oldConfigPath, oldConfigFile, oldRun := ConfigPath, configFile, runKeyringCommand
+	ConfigPath = path
+	configFile = nil
+	runKeyringCommand = func(stdin string, args ...string) (string, error) {
+		account := args[len(args)-1]
+		switch args[1] {
+		case "store":
+			keyring[account] = stdin
+		case "add-generic-password":
+			assert.Equal(t, "-w", args[len(args)-1])
+			keyring[args[len(args)-2]] = strings.SplitN(stdin, "\n", 2)[0]
+		case "lookup":
+			return keyring[account], nil
+		case "find-generic-password":
+			return keyring[args[len(args)-2]], nil
+		}
+		return "", nil
+	}
+	defer func() {
+		ConfigPath, configFile, runKeyringCommand = oldConfigPath, oldConfigFile, oldRun
+		keyringCache = map[string]string{}
+	}()
+	LoadConfig()
+
+	require.Error(t, KeyringRemote("remote", []string{"missing"}))
+	require.NoError(t, KeyringRemote("remote", []string{"secret"}))
+	assert.Equal(t, KeyringValue, FileGet("remote", "secret"))
+	assert.Equal(t, map[string]string{"remote/secret": "potato"}, keyring)
+
+	// The value is read from the keyring, not the cache
+	keyringCache = map[string]string{}
+	value, ok := FileGetFlag("remote", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, "potato", value)
+	value, ok = fs.ConfigFileGet("remote", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, "potato", value)
+
+	// Moving it again does nothing
+	require.NoError(t, KeyringRemote("remote", []string{"secret"}))
+	assert.Equal(t, KeyringValue, FileGet("remote", "secret"))
+
+	// Updating it updates the keyring not the config file
+	FileSet("remote", "secret", "carrot")
+	assert.Equal(t, KeyringValue, FileGet("remote", "secret"))
+	assert.Equal(t, map[string]string{"remote/secret": "carrot"}, keyring)
+	value, ok = FileGetFlag("remote", "secret")
+	assert.True(t, ok)
+	assert.Equal(t, "carrot", value)
+
+	// Other keys are set in the config file
+	FileSet("remote", "other", "turnip")
+	assert.Equal(t, "turnip", FileGet("remote", "other"))
+	assert.Equal(t, map[string]string{"remote/secret": "carrot"}, keyring)
+}
@@ -0,0 +1,80 @@
+// Apply named flag presets from the config file
+
+package config
+
+import (
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// PresetPrefix starts the name of the config file sections which
+// hold presets rather than remotes, eg "[preset:slow-link]"
+const PresetPrefix = "preset:"
+
+// Preset is the comma separated list of presets to apply as set by
+// --preset
+var Preset = ""
+
+// isPreset returns true if the config file section is a preset
+func isPreset(section string) bool {
+	return strings.HasPrefix(section, PresetPrefix)
+}
+
+// remoteSections returns the sections of the config file which are
+// remotes
+func remoteSections() []string {
+	var remotes []string
+	for _, section := range getConfigData().GetSectionList() {
+		if !isPreset(section) {
+			remotes = append(remotes, section)
+		}
+	}
+	return remotes
+}
+
+// applyPresets sets the flags in flagSet from the presets named in
+// Preset.
+//
+// Each key in a preset is the name of a flag and its value the value
+// to set it to.  Flags given on the command line aren't changed and
+// later presets override earlier ones.
+func applyPresets(flagSet *pflag.FlagSet) error {
+	for _, name := range strings.Split(Preset, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		section := PresetPrefix + name
+		keys := configFile.GetKeyList(section)
+		if keys == nil {
+			return errors.Errorf("preset %q not found - add a [%s] section to the config file", name, section)
+		}
+		for _, key := range keys {
+			flagName := strings.Replace(strings.TrimLeft(key, "-"), "_", "-", -1)
+			if flagName == "preset" {
+				return errors.Errorf("preset %q: can't use preset in a preset", name)
+			}
+			flag := flagSet.Lookup(flagName)
+			if flag == nil {
+				return errors.Errorf("preset %q: unknown flag %q", name, flagName)
+			}
+			if flag.Changed {
+				fs.Debugf(nil, "Not setting --%s from preset %q as it is set on the command line", flagName, name)
+				continue
+			}
+			value, err := configFile.GetValue(section, key)
+			if err != nil {
+				return errors.Wrapf(err, "preset %q", name)
+			}
+			err = flag.Value.Set(value)
+			if err != nil {
+				return errors.Wrapf(err, "preset %q: invalid value for --%s", name, flagName)
+			}
+			fs.Debugf(nil, "Set --%s to %q from preset %q", flagName, value, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPresets(t *testing.T) {
+	oldConfigFile, oldPreset := configFile, Preset
+	defer func() {
+		configFile, Preset = oldConfigFile, oldPreset
+	}()
+	var err error
+	configFile, err = goconfig.LoadFromReader(bytes.NewBufferString(`
+[remote]
+type = local
+
+[preset:slow]
+transfers = 2
+bw_limit = 1M
+
+[preset:fast]
+transfers = 16
+
+[preset:bad]
+potato = 1
+
+[preset:nested]
+preset = slow
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remote"}, remoteSections())
+
+	var transfers int
+	var bwLimit string
+	newFlagSet := func(args ...string) *pflag.FlagSet {
+		transfers, bwLimit = 4, ""
+		flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flagSet.IntVar(&transfers, "transfers", transfers, "")
+		flagSet.StringVar(&bwLimit, "bw-limit", bwLimit, "")
+		flagSet.StringVar(&Preset, "preset", "", "")
+		require.NoError(t, flagSet.Parse(args))
+		return flagSet
+	}
+
+	require.NoError(t, applyPresets(newFlagSet()))
+	assert.Equal(t, 4, transfers)
+
+	require.NoError(t, applyPresets(newFlagSet("--preset", "slow")))
+	assert.Equal(t, 2, transfers)
+	assert.Equal(t, "1M", bwLimit)
+
+	// later presets override earlier ones
+	require.NoError(t, applyPresets(newFlagSet("--preset", "slow,fast")))
+	assert.Equal(t, 16, transfers)
+	assert.Equal(t, "1M", bwLimit)
+
+	// the command line overrides the presets
+	require.NoError(t, applyPresets(newFlagSet("--preset", "slow", "--transfers", "8")))
+	assert.Equal(t, 8, transfers)
+	assert.Equal(t, "1M", bwLimit)
+
+	assert.EqualError(t, applyPresets(newFlagSet("--preset", "missing")), `preset "missing" not found - add a [preset:missing] section to the config file`)
+	assert.EqualError(t, applyPresets(newFlagSet("--preset", "bad")), `preset "bad": unknown flag "potato"`)
+	assert.EqualError(t, applyPresets(newFlagSet("--preset", "nested")), `preset "nested": can't use preset in a preset`)
+}
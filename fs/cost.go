@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Cost is the price of using a remote, used to estimate the cost of
+// a run with --cost.
+type Cost struct {
+	Download float64 // price per GiB downloaded from the remote
+	Upload   float64 // price per GiB uploaded to the remote
+	Requests float64 // price per 1000 requests made to the remote
+}
+
+// String returns a printable representation of Cost.
+func (x Cost) String() string {
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return "download:" + format(x.Download) + ",upload:" + format(x.Upload) + ",requests:" + format(x.Requests)
+}
+
+// Set the Cost from a comma separated list of name:price where the
+// names are download, upload and requests.  Any prices not set are 0.
+func (x *Cost) Set(s string) error {
+	var cost Cost
+	for _, tok := range strings.Split(s, ",") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			continue
+		}
+		i := strings.IndexRune(tok, ':')
+		if i < 0 {
+			return errors.Errorf("need name:price, got %q", tok)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(tok[i+1:]), 64)
+		if err != nil || price < 0 {
+			return errors.Errorf("bad price in %q", tok)
+		}
+		switch strings.ToLower(strings.TrimSpace(tok[:i])) {
+		case "download":
+			cost.Download = price
+		case "upload":
+			cost.Upload = price
+		case "requests":
+			cost.Requests = price
+		default:
+			return errors.Errorf("unknown price %q - must be download, upload or requests", tok[:i])
+		}
+	}
+	*x = cost
+	return nil
+}
+
+// Type of the value
+func (x Cost) Type() string {
+	return "Cost"
+}
+
+// RemoteCosts contains the prices set for individual remotes with
+// --cost keyed by remote name.
+type RemoteCosts map[string]Cost
+
+// String returns a printable representation of RemoteCosts.
+func (x RemoteCosts) String() string {
+	var ret []string
+	for name, cost := range x {
+		ret = append(ret, name+"="+cost.String())
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, "; ")
+}
+
+// Set adds a remote's prices from "remote=prices".
+func (x *RemoteCosts) Set(s string) error {
+	i := strings.IndexRune(s, '=')
+	if i < 0 {
+		return errors.Errorf("need remote=prices, got %q", s)
+	}
+	name := strings.TrimSuffix(strings.TrimSpace(s[:i]), ":")
+	if name == "" {
+		return errors.Errorf("empty remote name in %q", s)
+	}
+	var cost Cost
+	if err := cost.Set(s[i+1:]); err != nil {
+		return errors.Wrapf(err, "bad cost for remote %q", name)
+	}
+	if *x == nil {
+		*x = make(RemoteCosts)
+	}
+	(*x)[name] = cost
+	return nil
+}
+
+// Type of the value
+func (x RemoteCosts) Type() string {
+	return "RemoteCosts"
+}
@@ -0,0 +1,56 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check it satisfies the interface
+var (
+	_ pflag.Value = (*Cost)(nil)
+	_ pflag.Value = (*RemoteCosts)(nil)
+)
+
+func TestCostSet(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Cost
+		err  bool
+	}{
+		{"", Cost{}, false},
+		{"download:0.09", Cost{Download: 0.09}, false},
+		{"download:0.09, upload:0.01,requests:0.005", Cost{Download: 0.09, Upload: 0.01, Requests: 0.005}, false},
+		{"Requests:1", Cost{Requests: 1}, false},
+		{"download", Cost{}, true},
+		{"download:bad", Cost{}, true},
+		{"download:-1", Cost{}, true},
+		{"potato:1", Cost{}, true},
+	} {
+		var cost Cost
+		err := cost.Set(test.in)
+		if test.err {
+			assert.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.Equal(t, test.want, cost, test.in)
+		}
+	}
+	assert.Equal(t, "download:0.09,upload:0,requests:0.005", Cost{Download: 0.09, Requests: 0.005}.String())
+}
+
+func TestRemoteCostsSet(t *testing.T) {
+	var costs RemoteCosts
+	assert.Error(t, costs.Set("download:1"))
+	assert.Error(t, costs.Set("=download:1"))
+	assert.Error(t, costs.Set("remote=bad"))
+	require.NoError(t, costs.Set("remote=download:0.09"))
+	require.NoError(t, costs.Set("other:=upload:1,requests:0.4"))
+	assert.Equal(t, RemoteCosts{
+		"remote": Cost{Download: 0.09},
+		"other":  Cost{Upload: 1, Requests: 0.4},
+	}, costs)
+	assert.Equal(t, "other=download:0,upload:1,requests:0.4; remote=download:0.09,upload:0,requests:0", costs.String())
+}
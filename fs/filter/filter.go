@@ -3,12 +3,16 @@ package filter
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
-	"log"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ncw/rclone/fs"
@@ -17,8 +21,32 @@ import (
 )
 
 // Active is the globally active filter
+//
+// Use GetActive and SetActive to read and change it once operations
+// may be running, eg from the rc.
 var Active = mustNewFilter(nil)
 
+// activeMu protects Active
+var activeMu sync.RWMutex
+
+// GetActive returns the globally active filter.
+//
+// Operations should call this once when they start and use the
+// result throughout so they see a consistent filter even if it is
+// changed while they are running.
+func GetActive() *Filter {
+	activeMu.RLock()
+	defer activeMu.RUnlock()
+	return Active
+}
+
+// SetActive replaces the globally active filter with f
+func SetActive(f *Filter) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	Active = f
+}
+
 // rule is one filter rule
 type rule struct {
 	Include bool
@@ -87,19 +115,24 @@ type Opt struct {
 	IncludeRule    []string
 	IncludeFrom    []string
 	FilesFrom      []string
+	From0          bool
 	MinAge         fs.Duration
 	MaxAge         fs.Duration
 	MinSize        fs.SizeSuffix
 	MaxSize        fs.SizeSuffix
+	DeleteMinSize  fs.SizeSuffix
+	DeleteMaxSize  fs.SizeSuffix
 	IgnoreCase     bool
 }
 
 // DefaultOpt is the default config for the filter
 var DefaultOpt = Opt{
-	MinAge:  fs.DurationOff,
-	MaxAge:  fs.DurationOff,
-	MinSize: fs.SizeSuffix(-1),
-	MaxSize: fs.SizeSuffix(-1),
+	MinAge:        fs.DurationOff,
+	MaxAge:        fs.DurationOff,
+	MinSize:       fs.SizeSuffix(-1),
+	MaxSize:       fs.SizeSuffix(-1),
+	DeleteMinSize: fs.SizeSuffix(-1),
+	DeleteMaxSize: fs.SizeSuffix(-1),
 }
 
 // Filter describes any filtering in operation
@@ -111,6 +144,8 @@ type Filter struct {
 	dirRules    rules
 	files       FilesMap // files if filesFrom
 	dirs        FilesMap // dirs from filesFrom
+	stdinMu     sync.Mutex
+	stdin       int32 // set if --files-from - hasn't been read yet - use atomic
 }
 
 // NewFilter parses the command line options and creates a Filter
@@ -133,7 +168,7 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 	if f.Opt.MaxAge.IsSet() {
 		f.ModTimeFrom = time.Now().Add(-time.Duration(f.Opt.MaxAge))
 		if !f.ModTimeTo.IsZero() && f.ModTimeTo.Before(f.ModTimeFrom) {
-			log.Fatal("filter: --min-age can't be larger than --max-age")
+			return nil, errors.New("filter: --min-age can't be larger than --max-age")
 		}
 		fs.Debugf(nil, "--max-age %v to %v", f.Opt.MaxAge, f.ModTimeFrom)
 	}
@@ -192,9 +227,13 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 	}
 	for _, rule := range f.Opt.FilesFrom {
 		f.initAddFile() // init to show --files-from set even if no files within
-		err := forEachLine(rule, func(line string) error {
-			return f.AddFile(line)
-		})
+		if rule == "-" && len(f.Opt.FilesFrom) == 1 {
+			// read stdin when it is first needed as it may be
+			// streamed instead
+			f.stdin = 1
+			continue
+		}
+		err := forEachFileFrom(rule, f.Opt.From0, f.AddFile)
 		if err != nil {
 			return nil, err
 		}
@@ -322,9 +361,51 @@ func (f *Filter) AddFile(file string) error {
 //
 // It may be nil if the list is empty
 func (f *Filter) Files() FilesMap {
+	f.readStdin()
 	return f.files
 }
 
+// readStdin reads the `--files-from -` list from stdin if it hasn't
+// been read or streamed already.
+//
+// This is called for every file filtered so it only takes the lock if
+// stdin is still to be read.
+func (f *Filter) readStdin() {
+	if atomic.LoadInt32(&f.stdin) == 0 {
+		return
+	}
+	f.stdinMu.Lock()
+	defer f.stdinMu.Unlock()
+	if atomic.LoadInt32(&f.stdin) == 0 {
+		return
+	}
+	err := forEachFileFrom("-", f.Opt.From0, f.AddFile)
+	if err != nil {
+		fs.Errorf(nil, "Failed to read --files-from from stdin: %v", err)
+	}
+	atomic.StoreInt32(&f.stdin, 0)
+}
+
+// StreamFilesFrom calls fn for each file in the `--files-from -` list
+// as it is read from stdin, without keeping the list in memory.
+//
+// It returns false without calling fn if the list can't be streamed
+// because stdin isn't the only --files-from list or it has been read
+// already.  The files streamed aren't included by the filter
+// afterwards.
+func (f *Filter) StreamFilesFrom(fn func(remote string) error) (streamed bool, err error) {
+	f.stdinMu.Lock()
+	if atomic.LoadInt32(&f.stdin) == 0 {
+		f.stdinMu.Unlock()
+		return false, nil
+	}
+	atomic.StoreInt32(&f.stdin, 0)
+	f.stdinMu.Unlock()
+	return true, forEachFileFrom("-", f.Opt.From0, func(file string) error {
+		return fn(strings.Trim(file, "/"))
+	})
+}
+
 // Clear clears all the filter rules
 func (f *Filter) Clear() {
 	f.fileRules.clear()
@@ -386,6 +467,7 @@ func (f *Filter) IncludeDirectory(fs fs.Fs) func(string) (bool, error) {
 		}
 
 		// filesFrom takes precedence
+		f.readStdin()
 		if f.files != nil {
 			_, include := f.dirs[remote]
 			return include, nil
@@ -421,6 +503,7 @@ func (f *Filter) DirContainsExcludeFile(fremote fs.Fs, remote string) (bool, err
 // sync or not
 func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
 	// filesFrom takes precedence
+	f.readStdin()
 	if f.files != nil {
 		_, include := f.files[remote]
 		return include
@@ -440,6 +523,19 @@ func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
 	return f.includeRemote(remote)
 }
 
+// IncludeDeleteSize returns whether a file of this size on the
+// destination may be deleted by a sync according to --delete-min-size
+// and --delete-max-size.
+func (f *Filter) IncludeDeleteSize(size int64) bool {
+	if f.Opt.DeleteMinSize >= 0 && size < int64(f.Opt.DeleteMinSize) {
+		return false
+	}
+	if f.Opt.DeleteMaxSize >= 0 && size > int64(f.Opt.DeleteMaxSize) {
+		return false
+	}
+	return true
+}
+
 // IncludeObject returns whether this object should be included into
 // the sync or not. This is a convenience function to avoid calling
 // o.ModTime(), which is an expensive operation.
@@ -455,11 +551,19 @@ func (f *Filter) IncludeObject(o fs.Object) bool {
 	return f.Include(o.Remote(), o.Size(), modTime)
 }
 
+// openFile opens the file pointed to by path, or stdin if it is "-"
+func openFile(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
 // forEachLine calls fn on every line in the file pointed to by path
 //
 // It ignores empty lines and lines starting with '#' or ';'
 func forEachLine(path string, fn func(string) error) (err error) {
-	in, err := os.Open(path)
+	in, err := openFile(path)
 	if err != nil {
 		return err
 	}
@@ -479,6 +583,50 @@ func forEachLine(path string, fn func(string) error) (err error) {
 	return scanner.Err()
 }
 
+// scanNul is a bufio.SplitFunc which splits the input at NUL
+// characters
+func scanNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// forEachFileFrom calls fn on every file name in the --files-from
+// list pointed to by path, which is stdin if path is "-".
+//
+// If from0 is set the names are separated by NUL characters and only
+// empty names are ignored, otherwise they are read by forEachLine.
+func forEachFileFrom(path string, from0 bool, fn func(string) error) (err error) {
+	if !from0 {
+		return forEachLine(path, fn)
+	}
+	in, err := openFile(path)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	scanner := bufio.NewScanner(in)
+	scanner.Split(scanNul)
+	for scanner.Scan() {
+		file := scanner.Text()
+		if len(file) == 0 {
+			continue
+		}
+		err := fn(file)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
 // DumpFilters dumps the filters in textual form, 1 per line
 func (f *Filter) DumpFilters() string {
 	rules := []string{}
@@ -512,6 +660,7 @@ func (f *Filter) MakeListR(NewObject func(remote string) (fs.Object, error)) fs.
 		if !f.HaveFilesFrom() {
 			return errFilesFromNotSet
 		}
+		f.readStdin()
 		var (
 			remotes = make(chan string, fs.Config.Checkers)
 			g       errgroup.Group
@@ -292,6 +292,28 @@ func TestNewFilterMaxSize(t *testing.T) {
 	assert.False(t, f.InActive())
 }
 
+func TestIncludeDeleteSize(t *testing.T) {
+	f, err := NewFilter(nil)
+	require.NoError(t, err)
+	assert.True(t, f.IncludeDeleteSize(0))
+	assert.True(t, f.IncludeDeleteSize(1e9))
+	f.Opt.DeleteMinSize = 100
+	f.Opt.DeleteMaxSize = 200
+	assert.False(t, f.IncludeDeleteSize(99))
+	assert.True(t, f.IncludeDeleteSize(100))
+	assert.True(t, f.IncludeDeleteSize(200))
+	assert.False(t, f.IncludeDeleteSize(201))
+	assert.True(t, f.InActive())
+}
+
+func TestNewFilterBadAges(t *testing.T) {
+	opt := DefaultOpt
+	opt.MinAge = fs.Duration(2 * time.Hour)
+	opt.MaxAge = fs.Duration(time.Hour)
+	_, err := NewFilter(&opt)
+	assert.Error(t, err)
+}
+
 func TestNewFilterMinAndMaxAge(t *testing.T) {
 	f, err := NewFilter(nil)
 	require.NoError(t, err)
@@ -499,6 +521,77 @@ five
 	assert.Equal(t, "one,two,three,four,five,six", strings.Join(lines, ","))
 }
 
+func TestFilterForEachFileFrom0(t *testing.T) {
+	file := testFile(t, "one\x00 two \x00\x00#three\nfour\x00five")
+	defer func() {
+		err := os.Remove(file)
+		require.NoError(t, err)
+	}()
+	files := []string{}
+	err := forEachFileFrom(file, true, func(s string) error {
+		files = append(files, s)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", " two ", "#three\nfour", "five"}, files)
+}
+
+// setStdin replaces os.Stdin with a file with the contents given
+// returning a function to restore it
+func setStdin(t *testing.T, contents string) func() {
+	file := testFile(t, contents)
+	in, err := os.Open(file)
+	require.NoError(t, err)
+	oldStdin := os.Stdin
+	os.Stdin = in
+	return func() {
+		os.Stdin = oldStdin
+		require.NoError(t, in.Close())
+		require.NoError(t, os.Remove(file))
+	}
+}
+
+func TestNewFilterFilesFromStdin(t *testing.T) {
+	defer setStdin(t, "file1\n/dir/file2/\n")()
+	Opt := DefaultOpt
+	Opt.FilesFrom = []string{"-"}
+	f, err := NewFilter(&Opt)
+	require.NoError(t, err)
+	assert.True(t, f.HaveFilesFrom())
+	assert.Equal(t, FilesMap{
+		"file1":     {},
+		"dir/file2": {},
+	}, f.Files())
+	assert.True(t, f.Include("dir/file2", 0, time.Now()))
+
+	// can't stream once read
+	streamed, err := f.StreamFilesFrom(func(remote string) error {
+		t.Errorf("unexpected file %q", remote)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.False(t, streamed)
+}
+
+func TestNewFilterStreamFilesFrom(t *testing.T) {
+	defer setStdin(t, "file1\x00/dir/file2/\x00")()
+	Opt := DefaultOpt
+	Opt.FilesFrom = []string{"-"}
+	Opt.From0 = true
+	f, err := NewFilter(&Opt)
+	require.NoError(t, err)
+	assert.True(t, f.HaveFilesFrom())
+	var files []string
+	streamed, err := f.StreamFilesFrom(func(remote string) error {
+		files = append(files, remote)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, streamed)
+	assert.Equal(t, []string{"file1", "dir/file2"}, files)
+	assert.Equal(t, FilesMap{}, f.Files())
+}
+
 func TestFilterMatchesFromDocs(t *testing.T) {
 	for _, test := range []struct {
 		glob       string
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "filter/get",
+		Fn:    rcGet,
+		Title: "Get the active filter options",
+		Help: `Returns an object with the options of the filter in use, eg
+
+    {
+        "IncludeRule": ["*.jpg"],
+        "MaxSize": 1048576,
+        ...
+    }
+
+See filter/set for how to change them.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:         "filter/set",
+		AuthRequired: true,
+		Fn:           rcSet,
+		Title:        "Change the active filter",
+		Help: `This changes the filter used by operations started after it, without
+restarting rclone.  It takes the same parameters as returned by
+filter/get, eg
+
+    rclone rc filter/set --json '{"MaxSize": 1048576, "ExcludeRule": ["*.tmp"]}'
+
+Only supply the options you wish to change - the others keep their
+current values.  Pass "reset": true to start from the defaults
+instead, ie no filtering.  Sizes are in bytes with -1 for off and ages
+are in nanoseconds.
+
+Operations which are already running carry on with the filter they
+started with.  If the options are invalid an error is returned and the
+filter isn't changed.
+
+Returns the new options as filter/get does.
+`,
+	})
+}
+
+// Get the active filter options
+func rcGet(in rc.Params) (out rc.Params, err error) {
+	err = rc.Reshape(&out, GetActive().Opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "filter/get Reshape failed")
+	}
+	return out, nil
+}
+
+// Change the active filter
+func rcSet(in rc.Params) (out rc.Params, err error) {
+	reset, err := in.GetBool("reset")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	delete(in, "reset")
+	base := GetActive().Opt
+	if reset {
+		base = DefaultOpt
+	}
+	// copy the options so the slices in the active ones aren't
+	// overwritten
+	var opt Opt
+	err = rc.Reshape(&opt, base)
+	if err != nil {
+		return nil, errors.Wrap(err, "filter/set failed to copy options")
+	}
+	err = rc.Reshape(&opt, in)
+	if err != nil {
+		return nil, errors.Wrap(err, "filter/set failed to read options")
+	}
+	f, err := NewFilter(&opt)
+	if err != nil {
+		return nil, errors.Wrap(err, "filter/set failed to make filter")
+	}
+	SetActive(f)
+	return rcGet(nil)
+}
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRcSet(t *testing.T) {
+	oldActive := Active
+	defer func() { Active = oldActive }()
+	Active = mustNewFilter(nil)
+
+	set := rc.Calls.Get("filter/set")
+	require.NotNil(t, set)
+	get := rc.Calls.Get("filter/get")
+	require.NotNil(t, get)
+
+	out, err := set.Fn(rc.Params{
+		"MaxSize":     1000,
+		"ExcludeRule": []string{"*.tmp"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, float64(1000), out["MaxSize"])
+	assert.Equal(t, fs.SizeSuffix(1000), Active.Opt.MaxSize)
+	assert.False(t, Active.Include("file.tmp", 10, time.Time{}))
+	assert.True(t, Active.Include("file.txt", 10, time.Time{}))
+	assert.False(t, Active.Include("file.txt", 1001, time.Time{}))
+
+	// the other options are kept
+	_, err = set.Fn(rc.Params{"DeleteMaxSize": 500})
+	require.NoError(t, err)
+	assert.Equal(t, fs.SizeSuffix(1000), Active.Opt.MaxSize)
+	assert.Equal(t, fs.SizeSuffix(500), Active.Opt.DeleteMaxSize)
+	assert.Equal(t, []string{"*.tmp"}, Active.Opt.ExcludeRule)
+
+	// bad options leave the filter alone
+	_, err = set.Fn(rc.Params{"MinAge": 2e9, "MaxAge": 1e9})
+	assert.Error(t, err)
+	assert.Equal(t, fs.SizeSuffix(1000), Active.Opt.MaxSize)
+
+	out, err = set.Fn(rc.Params{"reset": true})
+	require.NoError(t, err)
+	assert.True(t, Active.InActive())
+	out2, err := get.Fn(nil)
+	require.NoError(t, err)
+	assert.Equal(t, out, out2)
+}
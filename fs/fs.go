@@ -18,6 +18,7 @@ import (
 	"github.com/ncw/rclone/fs/config/configstruct"
 	"github.com/ncw/rclone/fs/fspath"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/lib/encoder"
 	"github.com/pkg/errors"
 )
 
@@ -47,6 +48,7 @@ var (
 	ErrorCantCopy                    = errors.New("can't copy object - incompatible remotes")
 	ErrorCantMove                    = errors.New("can't move object - incompatible remotes")
 	ErrorCantDirMove                 = errors.New("can't move directory - incompatible remotes")
+	ErrorCantHardLink                = errors.New("can't hard link object - incompatible remotes")
 	ErrorDirExists                   = errors.New("can't copy directory - destination already exists")
 	ErrorCantSetModTime              = errors.New("can't set modified time")
 	ErrorCantSetModTimeWithoutDelete = errors.New("can't set modified time without deleting existing object")
@@ -63,6 +65,10 @@ var (
 	ErrorDirectoryNotEmpty           = errors.New("directory not empty")
 	ErrorImmutableModified           = errors.New("immutable file modified")
 	ErrorPermissionDenied            = errors.New("permission denied")
+	ErrorCommandNotFound             = errors.New("command not found")
+	ErrorCantExpireLink              = errors.New("can't make public links which expire")
+	ErrorCantUnlink                  = errors.New("can't remove public links")
+	ErrorCantShareDirectories        = errors.New("this backend can't share directories with link")
 )
 
 // RegInfo provides information about a filesystem
@@ -81,6 +87,18 @@ type RegInfo struct {
 	Config func(name string, config configmap.Mapper) `json:"-"`
 	// Options for the Fs configuration
 	Options Options
+	// The command help, if any
+	CommandHelp []CommandHelp
+}
+
+// CommandHelp describes a single backend Command
+//
+// These are shown by "rclone backend help remote:"
+type CommandHelp struct {
+	Name  string            // Name of the command, eg "link"
+	Short string            // Single line description
+	Long  string            // Long multi-line description
+	Opts  map[string]string // maps option name to a single line help
 }
 
 // FileName returns the on disk file name for this backend
@@ -348,6 +366,26 @@ type IDer interface {
 	ID() string
 }
 
+// ETager is an optional interface for Object
+type ETager interface {
+	// ETag returns the ETag the provider stores for the Object or
+	// "" if not known
+	ETag() string
+
+	// MatchETag reads the data of the Object from in and returns
+	// whether it gives the ETag.  It returns hash.ErrUnsupported
+	// if the ETag can't be worked out from the data.
+	MatchETag(in io.Reader) (bool, error)
+}
+
+// HardLinkIDer is an optional interface for Object
+type HardLinkIDer interface {
+	// HardLinkID returns an ID which is the same for all the hard
+	// links to the same file and the number of links to it, or
+	// "", 0 if not known
+	HardLinkID() (id string, links int)
+}
+
 // ObjectUnWrapper is an optional interface for Object
 type ObjectUnWrapper interface {
 	// UnWrap returns the Object that this Object is wrapping or
@@ -355,6 +393,61 @@ type ObjectUnWrapper interface {
 	UnWrap() Object
 }
 
+// UnWrapObject returns the innermost Object that o is wrapping, or o
+// itself if it isn't wrapping anything.
+func UnWrapObject(o Object) Object {
+	for {
+		u, ok := o.(ObjectUnWrapper)
+		if !ok {
+			return o
+		}
+		next := u.UnWrap()
+		if next == nil {
+			return o
+		}
+		o = next
+	}
+}
+
+// WrappedHashes returns the wrapped hash types f supports, which are
+// the hash types of the remotes it wraps if it is an overlay, such as
+// crypt.  See hash.Wrapped.
+//
+// These aren't included in f.Hashes() as they are only the same for
+// two objects if the wrapped objects hold the same data, which they
+// won't if the overlay transforms the data differently each time, as
+// crypt does.
+func WrappedHashes(f Info) hash.Set {
+	unWrap := f.Features().UnWrap
+	if unWrap == nil {
+		return hash.Set(hash.None)
+	}
+	inner := unWrap()
+	if inner == nil {
+		return hash.Set(hash.None)
+	}
+	set := inner.Hashes()
+	set.Add(WrappedHashes(inner).Array()...)
+	return set.Wrapped()
+}
+
+// WrappedHash returns the hash of type ht of o, reading the wrapped
+// hash types from the objects o wraps.
+func WrappedHash(o Object, ht hash.Type) (string, error) {
+	if !ht.IsWrapped() {
+		return o.Hash(ht)
+	}
+	u, ok := o.(ObjectUnWrapper)
+	if !ok {
+		return "", hash.ErrUnsupported
+	}
+	inner := u.UnWrap()
+	if inner == nil {
+		return "", hash.ErrUnsupported
+	}
+	return WrappedHash(inner, ht.Unwrap())
+}
+
 // SetTierer is an optional interface for Object
 type SetTierer interface {
 	// SetTier performs changing storage tier of the Object if
@@ -407,6 +500,9 @@ type Features struct {
 	BucketBased             bool // is bucket based (like s3, swift etc)
 	SetTier                 bool // allows set tier functionality on objects
 	GetTier                 bool // allows to retrieve storage tier of objects
+	PartialUploads          bool // partial uploads are visible so can be made to a temporary name with --partial
+	ServerSideAcrossConfigs bool // can server side copy and move from other remotes of the same type
+	SlowHash                bool // hashes are calculated from the data so are slow to read
 
 	// Purge all files in the root and the root directory
 	//
@@ -422,7 +518,10 @@ type Features struct {
 	//
 	// It returns the destination Object and a possible error
 	//
-	// Will only be called if src.Fs().Name() == f.Name()
+	// Will only be called if src.Fs().Name() == f.Name(), or if
+	// src.Fs() is the same type of remote, f has the
+	// ServerSideAcrossConfigs feature and --server-side-across-configs
+	// is set
 	//
 	// If it isn't possible then return fs.ErrorCantCopy
 	Copy func(src Object, remote string) (Object, error)
@@ -433,11 +532,24 @@ type Features struct {
 	//
 	// It returns the destination Object and a possible error
 	//
-	// Will only be called if src.Fs().Name() == f.Name()
+	// Will only be called if src.Fs().Name() == f.Name(), or if
+	// src.Fs() is the same type of remote, f has the
+	// ServerSideAcrossConfigs feature and --server-side-across-configs
+	// is set
 	//
 	// If it isn't possible then return fs.ErrorCantMove
 	Move func(src Object, remote string) (Object, error)
 
+	// HardLink makes remote a hard link to the existing object
+	// src, replacing remote if it exists.
+	//
+	// It returns the destination Object and a possible error
+	//
+	// Will only be called if src.Fs().Name() == f.Name()
+	//
+	// If it isn't possible then return fs.ErrorCantHardLink
+	HardLink func(src Object, remote string) (Object, error)
+
 	// DirMove moves src, srcRemote to this remote at dstRemote
 	// using server side move operations.
 	//
@@ -467,7 +579,7 @@ type Features struct {
 	DirCacheFlush func()
 
 	// PublicLink generates a public link to the remote path (usually readable by anyone)
-	PublicLink func(remote string) (string, error)
+	PublicLink func(remote string, expire Duration, unlink bool) (string, error)
 
 	// Put in to the remote path with the modTime given of the given size
 	//
@@ -490,6 +602,13 @@ type Features struct {
 	// in into the first one and rmdirs the other directories.
 	MergeDirs func([]Directory) error
 
+	// DirSetModTime sets the modification time of the directory
+	// dir which should already exist.
+	//
+	// This should return ErrorDirNotFound if the directory isn't
+	// found.
+	DirSetModTime func(dir string, modTime time.Time) error
+
 	// CleanUp the trash in the Fs
 	//
 	// Implement this if you have a way of emptying the trash or
@@ -516,6 +635,21 @@ type Features struct {
 
 	// About gets quota information from the Fs
 	About func() (*Usage, error)
+
+	// PartialNaming returns how partial uploads are named if the
+	// Fs has the PartialUploads feature
+	PartialNaming func() PartialNaming
+
+	// Command the backend to run a named command
+	//
+	// The command run is name
+	// args may be used to read arguments from
+	// opts may be used to read optional arguments from
+	//
+	// The result should be capable of being JSON encoded
+	// If it is a string or a []string it will be shown to the user
+	// otherwise it will be JSON encoded and shown to the user like that
+	Command func(name string, arg []string, opt map[string]string) (interface{}, error)
 }
 
 // Disable nil's out the named feature.  If it isn't found then it
@@ -549,6 +683,24 @@ func (ft *Features) List() (out []string) {
 	return out
 }
 
+// Enabled returns a map of all the feature names to whether they are
+// set, that is the flag is true or the function is not nil.
+func (ft *Features) Enabled() (features map[string]bool) {
+	v := reflect.ValueOf(ft).Elem()
+	vType := v.Type()
+	features = make(map[string]bool, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		vName := vType.Field(i).Name
+		field := v.Field(i)
+		if field.Kind() == reflect.Func {
+			features[vName] = !field.IsNil()
+		} else {
+			features[vName] = field.Bool()
+		}
+	}
+	return features
+}
+
 // DisableList nil's out the comma separated list of named features.
 // If it isn't found then it will log a message.
 func (ft *Features) DisableList(list []string) *Features {
@@ -571,6 +723,9 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(Mover); ok {
 		ft.Move = do.Move
 	}
+	if do, ok := f.(HardLinker); ok {
+		ft.HardLink = do.HardLink
+	}
 	if do, ok := f.(DirMover); ok {
 		ft.DirMove = do.DirMove
 	}
@@ -599,6 +754,9 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(MergeDirser); ok {
 		ft.MergeDirs = do.MergeDirs
 	}
+	if do, ok := f.(DirSetModTimer); ok {
+		ft.DirSetModTime = do.DirSetModTime
+	}
 	if do, ok := f.(CleanUpper); ok {
 		ft.CleanUp = do.CleanUp
 	}
@@ -608,6 +766,12 @@ func (ft *Features) Fill(f Fs) *Features {
 	if do, ok := f.(Abouter); ok {
 		ft.About = do.About
 	}
+	if do, ok := f.(PartialNamer); ok {
+		ft.PartialNaming = do.PartialNaming
+	}
+	if do, ok := f.(Commander); ok {
+		ft.Command = do.Command
+	}
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -627,6 +791,9 @@ func (ft *Features) Mask(f Fs) *Features {
 	ft.BucketBased = ft.BucketBased && mask.BucketBased
 	ft.SetTier = ft.SetTier && mask.SetTier
 	ft.GetTier = ft.GetTier && mask.GetTier
+	ft.PartialUploads = ft.PartialUploads && mask.PartialUploads
+	ft.ServerSideAcrossConfigs = ft.ServerSideAcrossConfigs && mask.ServerSideAcrossConfigs
+	ft.SlowHash = ft.SlowHash && mask.SlowHash
 
 	if mask.Purge == nil {
 		ft.Purge = nil
@@ -637,6 +804,9 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.Move == nil {
 		ft.Move = nil
 	}
+	if mask.HardLink == nil {
+		ft.HardLink = nil
+	}
 	if mask.DirMove == nil {
 		ft.DirMove = nil
 	}
@@ -658,6 +828,9 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.MergeDirs == nil {
 		ft.MergeDirs = nil
 	}
+	if mask.DirSetModTime == nil {
+		ft.DirSetModTime = nil
+	}
 	if mask.CleanUp == nil {
 		ft.CleanUp = nil
 	}
@@ -667,6 +840,10 @@ func (ft *Features) Mask(f Fs) *Features {
 	if mask.About == nil {
 		ft.About = nil
 	}
+	if mask.PartialNaming == nil {
+		ft.PartialNaming = nil
+	}
+	// Command is specific to each backend so isn't masked
 	return ft.DisableList(Config.DisableFeatures)
 }
 
@@ -713,7 +890,10 @@ type Copier interface {
 	//
 	// It returns the destination Object and a possible error
 	//
-	// Will only be called if src.Fs().Name() == f.Name()
+	// Will only be called if src.Fs().Name() == f.Name(), or if
+	// src.Fs() is the same type of remote, f has the
+	// ServerSideAcrossConfigs feature and --server-side-across-configs
+	// is set
 	//
 	// If it isn't possible then return fs.ErrorCantCopy
 	Copy(src Object, remote string) (Object, error)
@@ -727,12 +907,28 @@ type Mover interface {
 	//
 	// It returns the destination Object and a possible error
 	//
-	// Will only be called if src.Fs().Name() == f.Name()
+	// Will only be called if src.Fs().Name() == f.Name(), or if
+	// src.Fs() is the same type of remote, f has the
+	// ServerSideAcrossConfigs feature and --server-side-across-configs
+	// is set
 	//
 	// If it isn't possible then return fs.ErrorCantMove
 	Move(src Object, remote string) (Object, error)
 }
 
+// HardLinker is an optional interface for Fs
+type HardLinker interface {
+	// HardLink makes remote a hard link to the existing object
+	// src, replacing remote if it exists.
+	//
+	// It returns the destination Object and a possible error
+	//
+	// Will only be called if src.Fs().Name() == f.Name()
+	//
+	// If it isn't possible then return fs.ErrorCantHardLink
+	HardLink(src Object, remote string) (Object, error)
+}
+
 // DirMover is an optional interface for Fs
 type DirMover interface {
 	// DirMove moves src, srcRemote to this remote at dstRemote
@@ -774,6 +970,25 @@ type Wrapper interface {
 	SetWrapper(f Fs)
 }
 
+// NameEncoder is an optional interface for Fs
+type NameEncoder interface {
+	// Encoding returns the Encoder used to translate the names of
+	// the files and directories stored on the remote
+	Encoding() encoder.Encoder
+}
+
+// Encoding returns the Encoder f uses to translate the names of the
+// files and directories stored on it, or encoder.Identity() if it
+// stores them unchanged.
+func Encoding(f Info) encoder.Encoder {
+	if do, ok := f.(NameEncoder); ok {
+		if enc := do.Encoding(); enc != nil {
+			return enc
+		}
+	}
+	return encoder.Identity()
+}
+
 // DirCacheFlusher is an optional interface for Fs
 type DirCacheFlusher interface {
 	// DirCacheFlush resets the directory cache - used in testing
@@ -807,7 +1022,11 @@ type PutStreamer interface {
 // PublicLinker is an optional interface for Fs
 type PublicLinker interface {
 	// PublicLink generates a public link to the remote path (usually readable by anyone)
-	PublicLink(remote string) (string, error)
+	//
+	// If expire is set the link should stop working after that
+	// long, and if unlink is set the existing public links to the
+	// remote should be removed instead.
+	PublicLink(remote string, expire Duration, unlink bool) (string, error)
 }
 
 // MergeDirser is an option interface for Fs
@@ -817,6 +1036,16 @@ type MergeDirser interface {
 	MergeDirs([]Directory) error
 }
 
+// DirSetModTimer is an optional interface for Fs
+type DirSetModTimer interface {
+	// DirSetModTime sets the modification time of the directory
+	// dir which should already exist.
+	//
+	// This should return ErrorDirNotFound if the directory isn't
+	// found.
+	DirSetModTime(dir string, modTime time.Time) error
+}
+
 // CleanUpper is an optional interfaces for Fs
 type CleanUpper interface {
 	// CleanUp the trash in the Fs
@@ -866,6 +1095,42 @@ type Abouter interface {
 	About() (*Usage, error)
 }
 
+// PartialNaming describes how partial uploads to a remote with the
+// PartialUploads feature are named.
+type PartialNaming struct {
+	Dir           string // directory relative to the file's to upload to, "" for beside it
+	Prefix        string // prefix for the leaf name
+	Suffix        string // suffix for the leaf name
+	MaxLeafLength int    // longest leaf name allowed in bytes, 0 for no limit
+}
+
+// DefaultPartialNaming is used for remotes which have the
+// PartialUploads feature but don't say how to name them.
+var DefaultPartialNaming = PartialNaming{
+	Suffix: ".partial",
+}
+
+// PartialNamer is an optional interface for Fs
+type PartialNamer interface {
+	// PartialNaming returns how partial uploads are named unless
+	// overridden by the user
+	PartialNaming() PartialNaming
+}
+
+// Commander is an optional interface for Fs
+type Commander interface {
+	// Command the backend to run a named command
+	//
+	// The command run is name
+	// args may be used to read arguments from
+	// opts may be used to read optional arguments from
+	//
+	// The result should be capable of being JSON encoded
+	// If it is a string or a []string it will be shown to the user
+	// otherwise it will be JSON encoded and shown to the user like that
+	Command(name string, arg []string, opt map[string]string) (interface{}, error)
+}
+
 // ObjectsChan is a channel of Objects
 type ObjectsChan chan Object
 
@@ -975,17 +1240,36 @@ func (section setConfigFile) Set(key, value string) {
 	ConfigFileSet(string(section), key, value)
 }
 
+// ConfigTemplate is the config key naming a remote which a remote
+// inherits any config items it doesn't set from.
+const ConfigTemplate = "template"
+
+// maxTemplateDepth is the maximum number of templates followed, to
+// stop loops
+const maxTemplateDepth = 10
+
 // A configmap.Getter to read from the config file
 type getConfigFile string
 
-// Get a config item from the config file
+// Get a config item from the config file, looking in the template of
+// the section, and its template, etc if it isn't set.
 func (section getConfigFile) Get(key string) (value string, ok bool) {
-	value, ok = ConfigFileGet(string(section), key)
-	// Ignore empty lines in the config file
-	if value == "" {
-		ok = false
+	name := string(section)
+	for i := 0; i < maxTemplateDepth; i++ {
+		value, ok = ConfigFileGet(name, key)
+		// Ignore empty lines in the config file
+		if ok && value != "" {
+			return value, true
+		}
+		if key == ConfigTemplate {
+			break
+		}
+		name, ok = ConfigFileGet(name, ConfigTemplate)
+		if !ok || name == "" {
+			break
+		}
 	}
-	return value, ok
+	return "", false
 }
 
 // ConfigMap creates a configmap.Map from the *RegInfo and the
@@ -34,6 +34,18 @@ func TestFeaturesList(t *testing.T) {
 	assert.True(t, strings.Contains(names, ",Copy,"))
 }
 
+func TestFeaturesEnabled(t *testing.T) {
+	ft := new(Features)
+	ft.CaseInsensitive = true
+	ft.Purge = func() error { return nil }
+	features := ft.Enabled()
+	assert.Equal(t, len(ft.List()), len(features))
+	assert.True(t, features["CaseInsensitive"])
+	assert.False(t, features["DuplicateFiles"])
+	assert.True(t, features["Purge"])
+	assert.False(t, features["Copy"])
+}
+
 func TestFeaturesDisableList(t *testing.T) {
 	ft := new(Features)
 	ft.Copy = func(src Object, remote string) (Object, error) {
@@ -70,3 +82,42 @@ func TestOption(t *testing.T) {
 	err = d.Set("sdfsdf")
 	assert.Error(t, err)
 }
+
+func TestGetConfigFileTemplate(t *testing.T) {
+	configFile := map[string]map[string]string{
+		"base":  {"type": "s3", "key": "base-key", "region": "base-region"},
+		"mid":   {"template": "base", "region": "mid-region", "empty": "x"},
+		"child": {"template": "mid", "empty": ""},
+		"loop1": {"template": "loop2"},
+		"loop2": {"template": "loop1"},
+	}
+	oldConfigFileGet := ConfigFileGet
+	ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := configFile[section][key]
+		return value, ok
+	}
+	defer func() {
+		ConfigFileGet = oldConfigFileGet
+	}()
+
+	for _, test := range []struct {
+		section string
+		key     string
+		want    string
+		wantOK  bool
+	}{
+		{"child", "type", "s3", true},
+		{"child", "key", "base-key", true},
+		{"child", "region", "mid-region", true},
+		{"child", "empty", "x", true},
+		{"child", "template", "mid", true},
+		{"child", "missing", "", false},
+		{"base", "region", "base-region", true},
+		{"loop1", "type", "", false},
+		{"notfound", "type", "", false},
+	} {
+		value, ok := getConfigFile(test.section).Get(test.key)
+		assert.Equal(t, test.want, value, test.section+"."+test.key)
+		assert.Equal(t, test.wantOK, ok, test.section+"."+test.key)
+	}
+}
@@ -170,7 +170,7 @@ func NewTransport(ci *fs.ConfigInfo) http.RoundTripper {
 		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
 			return dialContextTimeout(ctx, network, addr, ci)
 		}
-		t.IdleConnTimeout = 60 * time.Second
+		t.IdleConnTimeout = ci.IdleTimeout
 		t.ExpectContinueTimeout = ci.ConnectTimeout
 		// Wrap that http.Transport in our own transport
 		transport = newTransport(ci, t)
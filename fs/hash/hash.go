@@ -40,6 +40,15 @@ const (
 	None Type = 0
 )
 
+// The wrapped hash types are the standard types shifted left by
+// wrappedShift bits for each level of wrapping, up to maxWrapped
+// levels.  The standard types must fit into wrappedShift bits.
+const (
+	wrappedShift  = 8
+	maxWrapped    = 3
+	wrappedPrefix = "wrapped-"
+)
+
 // Supported returns a set of all the supported hashes by
 // HashStream and MultiHasher.
 var Supported = NewHashSet(MD5, SHA1, Dropbox, QuickXorHash)
@@ -52,6 +61,43 @@ var Width = map[Type]int{
 	QuickXorHash: 40,
 }
 
+func init() {
+	// The wrapped hashes are the same width as the hashes they wrap
+	for _, t := range Supported.Array() {
+		for w := Wrapped(t); w != None; w = Wrapped(w) {
+			Width[w] = Width[t]
+		}
+	}
+}
+
+// Wrapped returns the type of the hash t of the data as stored by the
+// remote an overlay remote, such as crypt, wraps.  Overlays which
+// transform the data can't work out the hashes of the data they
+// return from the hashes their remote stores, but they can pass on
+// the wrapped hashes to compare the data as it is stored.
+//
+// It returns None if t is wrapped too many times already.
+func Wrapped(t Type) Type {
+	if t == None || t >= 1<<(wrappedShift*maxWrapped) {
+		return None
+	}
+	return t << wrappedShift
+}
+
+// IsWrapped returns true if h is a wrapped hash type
+func (h Type) IsWrapped() bool {
+	return h >= 1<<wrappedShift
+}
+
+// Unwrap returns the type of hash the wrapped hash type h is made
+// from, which is h itself if h isn't wrapped.
+func (h Type) Unwrap() Type {
+	if !h.IsWrapped() {
+		return h
+	}
+	return h >> wrappedShift
+}
+
 // Stream will calculate hashes of all supported hash types.
 func Stream(r io.Reader) (map[Type]string, error) {
 	return StreamTypes(r, Supported)
@@ -90,6 +136,9 @@ func (h Type) String() string {
 	case QuickXorHash:
 		return "QuickXorHash"
 	default:
+		if h.IsWrapped() && h.Unwrap() != None {
+			return wrappedPrefix + h.Unwrap().String()
+		}
 		err := fmt.Sprintf("internal error: unknown hash type: 0x%x", int(h))
 		panic(err)
 	}
@@ -109,6 +158,13 @@ func (h *Type) Set(s string) error {
 	case "QuickXorHash":
 		*h = QuickXorHash
 	default:
+		if strings.HasPrefix(s, wrappedPrefix) {
+			var t Type
+			if err := t.Set(s[len(wrappedPrefix):]); err == nil && Wrapped(t) != None {
+				*h = Wrapped(t)
+				return nil
+			}
+		}
 		return errors.Errorf("Unknown hash type %q", s)
 	}
 	return nil
@@ -236,6 +292,16 @@ func (h Set) Overlap(t Set) Set {
 	return Set(int(h) & int(t))
 }
 
+// Wrapped returns the set of the wrapped types of the hash types in
+// h as returned by Wrapped.
+func (h Set) Wrapped() Set {
+	w := Set(None)
+	for _, t := range h.Array() {
+		w.Add(Wrapped(t))
+	}
+	return w
+}
+
 // SubsetOf will return true if all types of h
 // is present in the set c
 func (h Set) SubsetOf(c Set) bool {
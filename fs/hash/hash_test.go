@@ -167,3 +167,32 @@ func TestHashStringer(t *testing.T) {
 	h = hash.None
 	assert.Equal(t, h.String(), "None")
 }
+
+func TestWrapped(t *testing.T) {
+	w := hash.Wrapped(hash.MD5)
+	assert.True(t, w.IsWrapped())
+	assert.False(t, hash.MD5.IsWrapped())
+	assert.Equal(t, hash.MD5, w.Unwrap())
+	assert.Equal(t, hash.MD5, hash.MD5.Unwrap())
+	assert.Equal(t, "wrapped-MD5", w.String())
+	assert.Equal(t, hash.Width[hash.MD5], hash.Width[w])
+	assert.False(t, hash.Supported.Contains(w))
+
+	ww := hash.Wrapped(w)
+	assert.Equal(t, "wrapped-wrapped-MD5", ww.String())
+	assert.Equal(t, w, ww.Unwrap())
+	www := hash.Wrapped(ww)
+	assert.Equal(t, ww, www.Unwrap())
+	assert.Equal(t, hash.None, hash.Wrapped(www))
+	assert.Equal(t, hash.None, hash.Wrapped(hash.None))
+
+	var h hash.Type
+	require.NoError(t, h.Set("wrapped-wrapped-SHA-1"))
+	assert.Equal(t, hash.Wrapped(hash.Wrapped(hash.SHA1)), h)
+	assert.Error(t, h.Set("wrapped-potato"))
+	assert.Error(t, h.Set("wrapped-wrapped-wrapped-wrapped-MD5"))
+
+	s := hash.NewHashSet(hash.MD5, hash.SHA1, w).Wrapped()
+	assert.Equal(t, "[wrapped-MD5, wrapped-SHA-1, wrapped-wrapped-MD5]", s.String())
+	assert.Equal(t, hash.Set(hash.None), hash.Set(hash.None).Wrapped())
+}
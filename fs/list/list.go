@@ -7,6 +7,7 @@ import (
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/listcache"
 	"github.com/pkg/errors"
 )
 
@@ -19,19 +20,25 @@ import (
 //
 // Files will be returned in sorted order
 func DirSorted(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+	return DirSortedWithFilter(f, filter.GetActive(), includeAll, dir)
+}
+
+// DirSortedWithFilter is like DirSorted but uses fi rather than the
+// active filter.
+func DirSortedWithFilter(f fs.Fs, fi *filter.Filter, includeAll bool, dir string) (entries fs.DirEntries, err error) {
 	// Get unfiltered entries from the fs
-	entries, err = f.List(dir)
+	entries, err = listcache.List(f, dir)
 	if err != nil {
 		return nil, err
 	}
 	// This should happen only if exclude files lives in the
 	// starting directory, otherwise ListDirSorted should not be
 	// called.
-	if !includeAll && filter.Active.ListContainsExcludeFile(entries) {
+	if !includeAll && fi.ListContainsExcludeFile(entries) {
 		fs.Debugf(dir, "Excluded")
 		return nil, nil
 	}
-	return filterAndSortDir(entries, includeAll, dir, filter.Active.IncludeObject, filter.Active.IncludeDirectory(f))
+	return filterAndSortDir(entries, includeAll, dir, fi.IncludeObject, fi.IncludeDirectory(f))
 }
 
 // filter (if required) and check the entries, then sort them
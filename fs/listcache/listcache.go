@@ -0,0 +1,426 @@
+// Package listcache implements --use-list-cache which keeps directory
+// listings on disk so they can be shared between runs of rclone.
+package listcache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// entry is a directory entry as stored in the cache
+type entry struct {
+	Leaf    string            `json:"leaf"`
+	Dir     bool              `json:"dir,omitempty"`
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"modTime"`
+	Hashes  map[string]string `json:"hashes,omitempty"` // hashes by name if known
+}
+
+// listing is a directory listing as stored in the cache
+type listing struct {
+	Dir     string    `json:"dir"`    // name:path of the directory listed
+	Listed  time.Time `json:"listed"` // when the listing was made
+	Entries []entry   `json:"entries"`
+}
+
+// hashName returns a name suitable for a file for s
+func hashName(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// remoteName returns the name of the remote f is on with a hash of
+// the options it was made with, eg "remote{0a1b2c3d}".
+//
+// This is so remotes with the same name but different options, eg
+// on the fly remotes made with different flags or environment
+// variables, don't share listings.
+func remoteName(f fs.Info) string {
+	fsInfo, configName, _, err := fs.ParseRemote(f.Name() + ":")
+	if err != nil {
+		return f.Name()
+	}
+	m := fs.ConfigMap(fsInfo, configName)
+	var options strings.Builder
+	for _, opt := range fsInfo.Options {
+		value, _ := m.Get(opt.Name)
+		options.WriteString(opt.Name + "=" + value + "\n")
+	}
+	return f.Name() + "{" + hashName(options.String())[:8] + "}"
+}
+
+// absDir returns the name:path of dir in f
+//
+// This is used to identify the listing so an Fs with a different
+// root will share the listings with f.
+func absDir(f fs.Info, dir string) string {
+	return remoteName(f) + ":" + path.Join(f.Root(), dir)
+}
+
+// remoteDir returns the directory the listings for f are kept in
+func remoteDir(f fs.Info) string {
+	return filepath.Join(config.CacheDir, "listcache", hashName(remoteName(f)))
+}
+
+// cachePath returns the file the listing of dir in f is kept in
+func cachePath(f fs.Info, dir string) string {
+	return filepath.Join(remoteDir(f), hashName(absDir(f, dir))+".json")
+}
+
+// List returns the entries in dir in f, using the cached listing if
+// --use-list-cache is set and it is new enough.  Otherwise it lists
+// the directory and saves the listing in the cache.
+//
+// Listings which aren't read from the cache are limited by
+// --tpslimit-list.
+func List(f fs.Fs, dir string) (entries fs.DirEntries, err error) {
+	ttl := fs.Config.UseListCache
+	if ttl <= 0 {
+		limitList(f)
+		accounting.CountRequest(f)
+		return f.List(dir)
+	}
+	entries, ok := get(f, dir, ttl)
+	if ok {
+		fs.Debugf(fs.LogDirName(f, dir), "Using listing from --use-list-cache")
+		return entries, nil
+	}
+	limitList(f)
+	accounting.CountRequest(f)
+	entries, err = f.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	put(f, dir, entries)
+	return entries, nil
+}
+
+var (
+	listLimitersMu sync.Mutex
+	listLimiters   = make(map[string]*rate.Limiter) // --tpslimit-list limiters by remote name
+)
+
+// limitList waits until a listing of f is allowed by --tpslimit-list
+// which applies to each remote separately.
+func limitList(f fs.Info) {
+	if fs.Config.TPSLimitList <= 0 {
+		return
+	}
+	listLimitersMu.Lock()
+	limiter := listLimiters[f.Name()]
+	if limiter == nil {
+		limiter = rate.NewLimiter(rate.Limit(fs.Config.TPSLimitList), 1)
+		listLimiters[f.Name()] = limiter
+	}
+	listLimitersMu.Unlock()
+	err := limiter.Wait(context.Background())
+	if err != nil {
+		fs.Errorf(f, "List limiter error: %v", err)
+	}
+}
+
+// get reads the listing of dir from the cache returning false if it
+// isn't there or is older than ttl
+func get(f fs.Fs, dir string, ttl time.Duration) (entries fs.DirEntries, ok bool) {
+	data, err := ioutil.ReadFile(cachePath(f, dir))
+	if err != nil {
+		return nil, false
+	}
+	var l listing
+	err = json.Unmarshal(data, &l)
+	if err != nil {
+		fs.Debugf(fs.LogDirName(f, dir), "Ignoring corrupted --use-list-cache entry: %v", err)
+		return nil, false
+	}
+	if l.Dir != absDir(f, dir) || time.Since(l.Listed) > ttl {
+		return nil, false
+	}
+	entries = make(fs.DirEntries, 0, len(l.Entries))
+	for _, e := range l.Entries {
+		remote := path.Join(dir, e.Leaf)
+		if e.Dir {
+			entries = append(entries, fs.NewDir(remote, e.ModTime).SetSize(e.Size))
+		} else {
+			entries = append(entries, &Object{
+				f:       f,
+				remote:  remote,
+				size:    e.Size,
+				modTime: e.ModTime,
+				hashes:  e.Hashes,
+			})
+		}
+	}
+	return entries, true
+}
+
+// put saves the listing of dir in the cache
+//
+// The hashes of the objects are saved too unless the remote has to
+// read the data to find them.
+func put(f fs.Fs, dir string, entries fs.DirEntries) {
+	l := listing{
+		Dir:     absDir(f, dir),
+		Listed:  time.Now(),
+		Entries: make([]entry, 0, len(entries)),
+	}
+	hashes := f.Hashes().Array()
+	if f.Features().SlowHash {
+		hashes = nil
+	}
+	for _, e := range entries {
+		_, isDir := e.(fs.Directory)
+		item := entry{
+			Leaf:    path.Base(e.Remote()),
+			Dir:     isDir,
+			Size:    e.Size(),
+			ModTime: e.ModTime(),
+		}
+		if o, ok := e.(fs.Object); ok {
+			for _, ht := range hashes {
+				sum, err := o.Hash(ht)
+				if err != nil {
+					continue
+				}
+				if item.Hashes == nil {
+					item.Hashes = make(map[string]string, len(hashes))
+				}
+				item.Hashes[ht.String()] = sum
+			}
+		}
+		l.Entries = append(l.Entries, item)
+	}
+	err := write(cachePath(f, dir), l)
+	if err != nil {
+		fs.Errorf(fs.LogDirName(f, dir), "Failed to save listing to --use-list-cache: %v", err)
+	}
+}
+
+// write l to the file at name atomically so other rclones reading
+// the cache never see a partial listing
+func write(name string, l listing) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(name), 0700)
+	if err != nil {
+		return err
+	}
+	// Use a unique temporary file so rclones writing the same
+	// listing at the same time don't overwrite each other's
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Invalidate removes the listing of dir in f from the cache as it has
+// been changed.
+func Invalidate(f fs.Info, dir string) {
+	if fs.Config.UseListCache <= 0 {
+		return
+	}
+	err := os.Remove(cachePath(f, dir))
+	if err != nil && !os.IsNotExist(err) {
+		fs.Errorf(absDir(f, dir), "Failed to remove listing from --use-list-cache: %v", err)
+	}
+}
+
+// InvalidateParent removes the listing of the directory containing
+// remote in f from the cache as it has been changed.
+func InvalidateParent(f fs.Info, remote string) {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	Invalidate(f, dir)
+}
+
+// InvalidateAll removes all the cached listings for the remote f is
+// on.
+func InvalidateAll(f fs.Info) {
+	if fs.Config.UseListCache <= 0 {
+		return
+	}
+	err := os.RemoveAll(remoteDir(f))
+	if err != nil {
+		fs.Errorf(f, "Failed to remove listings from --use-list-cache: %v", err)
+	}
+}
+
+// Object is an fs.Object read from the list cache.
+//
+// The size, modification time and hashes come from the cache.
+// Anything else looks up the object on the remote first.
+type Object struct {
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime time.Time
+	hashes  map[string]string // hashes by name from the cache
+	mu      sync.Mutex
+	o       fs.Object // the object on the remote once looked up
+}
+
+// object returns the object on the remote, looking it up if necessary
+func (o *Object) object() (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o == nil {
+		obj, err := o.f.NewObject(o.remote)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find object from --use-list-cache")
+		}
+		o.o = obj
+	}
+	return o.o, nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification date of the file from the cache
+func (o *Object) ModTime() time.Time {
+	return o.modTime
+}
+
+// Size returns the size of the file from the cache
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// Storable says whether this object can be stored
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file from the cache,
+// reading it from the object on the remote if it wasn't cached.
+func (o *Object) Hash(ht hash.Type) (string, error) {
+	if sum, ok := o.hashes[ht.String()]; ok {
+		return sum, nil
+	}
+	obj, err := o.object()
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ht)
+}
+
+// SetModTime sets the modification time of the object on the remote
+func (o *Object) SetModTime(modTime time.Time) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	InvalidateParent(o.f, o.remote)
+	err = obj.SetModTime(modTime)
+	if err == nil {
+		o.modTime = modTime
+	}
+	return err
+}
+
+// Open opens the object on the remote for read
+func (o *Object) Open(options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.object()
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(options...)
+}
+
+// Update the object on the remote with the contents of in
+func (o *Object) Update(in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	InvalidateParent(o.f, o.remote)
+	err = obj.Update(in, src, options...)
+	if err == nil {
+		o.size, o.modTime, o.hashes = obj.Size(), obj.ModTime(), nil
+	}
+	return err
+}
+
+// Remove the object from the remote
+func (o *Object) Remove() error {
+	obj, err := o.object()
+	if err != nil {
+		return err
+	}
+	InvalidateParent(o.f, o.remote)
+	return obj.Remove()
+}
+
+// UnWrap returns the object on the remote or nil if it can't be found
+func (o *Object) UnWrap() fs.Object {
+	obj, _ := o.object()
+	return obj
+}
+
+// UnWrap returns the object on the remote if o was read from the
+// cache, or o otherwise.
+//
+// Use this before passing o to the Copy or Move of a backend as they
+// need the backend's own objects.  Only the cache's wrapping is
+// removed as other wrappers, eg crypt, need their own objects too.
+func UnWrap(o fs.Object) fs.Object {
+	if co, ok := o.(*Object); ok {
+		if obj := co.UnWrap(); obj != nil {
+			return obj
+		}
+	}
+	return o
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
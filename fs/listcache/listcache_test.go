@@ -0,0 +1,184 @@
+package listcache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/ncw/rclone/backend/local"
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-listcache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	oldCacheDir, oldTTL := config.CacheDir, fs.Config.UseListCache
+	defer func() {
+		config.CacheDir, fs.Config.UseListCache = oldCacheDir, oldTTL
+	}()
+	config.CacheDir = filepath.Join(dir, "cache")
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "one"), []byte("one"), 0666))
+	f, err := fs.NewFs(root)
+	require.NoError(t, err)
+
+	// Nothing is cached when disabled
+	fs.Config.UseListCache = 0
+	entries, err := List(f, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	_, err = os.Stat(cachePath(f, ""))
+	assert.True(t, os.IsNotExist(err))
+
+	// The listing is read from the cache the second time
+	fs.Config.UseListCache = time.Hour
+	_, err = List(f, "")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "two"), []byte("two"), 0666))
+	entries, err = List(f, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	o, ok := entries[0].(*Object)
+	require.True(t, ok)
+	assert.Equal(t, "one", o.Remote())
+	assert.Equal(t, int64(3), o.Size())
+	sum, err := o.Hash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "f97c5d29941bfb1b2fdab0874906ab82", sum)
+	d, ok := entries[1].(fs.Directory)
+	require.True(t, ok)
+	assert.Equal(t, "sub", d.Remote())
+
+	// Changing the directory removes it from the cache
+	Invalidate(f, "")
+	entries, err = List(f, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// A sub Fs shares the listings
+	subF, err := fs.NewFs(filepath.Join(root, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, cachePath(f, "sub"), cachePath(subF, ""))
+
+	// Old listings are ignored
+	fs.Config.UseListCache = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	require.NoError(t, os.Remove(filepath.Join(root, "two")))
+	entries, err = List(f, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	InvalidateAll(f)
+	_, err = os.Stat(remoteDir(f))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-listcache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	name := filepath.Join(dir, "sub", "listing.json")
+	require.NoError(t, write(name, listing{Dir: "remote:one"}))
+	require.NoError(t, write(name, listing{Dir: "remote:two"}))
+
+	// Only the listing is left behind
+	files, err := ioutil.ReadDir(filepath.Dir(name))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "listing.json", files[0].Name())
+	data, err := ioutil.ReadFile(name)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "remote:two")
+}
+
+func TestUnWrap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-listcache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "one"), []byte("one"), 0666))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+	obj, err := f.NewObject("one")
+	require.NoError(t, err)
+
+	// Objects from the remote are returned as is
+	assert.Equal(t, obj, UnWrap(obj))
+
+	// Objects from the cache are looked up on the remote
+	o := &Object{f: f, remote: "one", size: 3}
+	unwrapped := UnWrap(o)
+	_, isCached := unwrapped.(*Object)
+	assert.False(t, isCached)
+	assert.Equal(t, "one", unwrapped.Remote())
+
+	// Unless they can't be found
+	missing := &Object{f: f, remote: "missing"}
+	assert.Equal(t, fs.Object(missing), UnWrap(missing))
+}
+
+func TestRemoteName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-listcache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	f, err := fs.NewFs(":local:" + dir)
+	require.NoError(t, err)
+	name := remoteName(f)
+	assert.Regexp(t, `^:local\{[0-9a-f]{8}\}$`, name)
+
+	// Different options give a different name
+	require.NoError(t, os.Setenv("RCLONE_LOCAL_NO_CHECK_UPDATED", "true"))
+	defer func() {
+		_ = os.Unsetenv("RCLONE_LOCAL_NO_CHECK_UPDATED")
+	}()
+	assert.NotEqual(t, name, remoteName(f))
+	assert.NotEqual(t, remoteDir(f), filepath.Join(config.CacheDir, "listcache", hashName(name)))
+}
+
+func TestHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-listcache")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "one"), []byte("one"), 0666))
+	f, err := fs.NewFs(dir)
+	require.NoError(t, err)
+
+	// Cached hashes are used without looking up the object
+	o := &Object{f: f, remote: "missing", hashes: map[string]string{"MD5": "cached"}}
+	sum, err := o.Hash(hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", sum)
+	_, err = o.Hash(hash.SHA1)
+	assert.Error(t, err)
+
+	// Hashes aren't saved for remotes which read the data to find them
+	entries, err := f.List("")
+	require.NoError(t, err)
+	oldCacheDir := config.CacheDir
+	defer func() {
+		config.CacheDir = oldCacheDir
+	}()
+	config.CacheDir = filepath.Join(dir, "cache")
+	put(f, "", entries)
+	got, ok := get(f, "", time.Hour)
+	require.True(t, ok)
+	require.Len(t, got, 1)
+	assert.Nil(t, got[0].(*Object).hashes)
+}
@@ -0,0 +1,126 @@
+package march
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Values for --duplicates
+const (
+	DuplicatesRefuse = "refuse" // don't sync duplicated names
+	DuplicatesNewest = "newest" // use the newest of the duplicates
+	DuplicatesRename = "rename" // add the ID to the names of source duplicates
+)
+
+// sortDuplicates sorts objects with the same name so the newest is
+// first, using the ID then the size to break ties so the order
+// doesn't depend on the order of the listing.
+func sortDuplicates(objs []fs.Object) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		a, b := objs[i], objs[j]
+		aTime, bTime := a.ModTime(), b.ModTime()
+		if !aTime.Equal(bTime) {
+			return aTime.After(bTime)
+		}
+		if aID, bID := objectID(a), objectID(b); aID != bID {
+			return aID < bID
+		}
+		return a.Size() < b.Size()
+	})
+}
+
+// objectID returns the ID of o or "" if it doesn't have one
+func objectID(o fs.Object) string {
+	if do, ok := o.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// resolveDuplicates deals with objects in entries with the same
+// name, which some backends (eg Drive) allow, according to
+// --duplicates.
+//
+// It returns the entries to use and the names which should be
+// ignored in the other listing so the duplicates aren't added to or
+// deleted.
+func resolveDuplicates(entries fs.DirEntries, isSrc bool) (kept fs.DirEntries, refused map[string]struct{}) {
+	byLeaf := make(map[string][]fs.Object)
+	found := false
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			leaf := path.Base(o.Remote())
+			byLeaf[leaf] = append(byLeaf[leaf], o)
+			found = found || len(byLeaf[leaf]) > 1
+		}
+	}
+	if !found {
+		return entries, nil
+	}
+	mode := fs.Config.Duplicates
+	if mode == DuplicatesRename && !isSrc {
+		mode = DuplicatesNewest
+	}
+	kept = make(fs.DirEntries, 0, len(entries))
+	done := make(map[string]struct{})
+	for _, entry := range entries {
+		o, ok := entry.(fs.Object)
+		if !ok {
+			kept = append(kept, entry)
+			continue
+		}
+		leaf := path.Base(o.Remote())
+		objs := byLeaf[leaf]
+		if len(objs) == 1 {
+			kept = append(kept, entry)
+			continue
+		}
+		if _, ok := done[leaf]; ok {
+			continue
+		}
+		done[leaf] = struct{}{}
+		sortDuplicates(objs)
+		switch mode {
+		case DuplicatesNewest:
+			fs.Logf(objs[0], "Using newest of %d objects with the same name", len(objs))
+			kept = append(kept, objs[0])
+		case DuplicatesRename:
+			for i, dupe := range objs {
+				suffix := objectID(dupe)
+				if suffix == "" {
+					suffix = fmt.Sprint(i + 1)
+				}
+				newLeaf := addLeafSuffix(leaf, " {"+suffix+"}")
+				fs.Logf(dupe, "Renaming duplicate to %q", newLeaf)
+				kept = append(kept, renameObject(dupe, newLeaf))
+			}
+		default:
+			if refused == nil {
+				refused = make(map[string]struct{})
+			}
+			refused[leaf] = struct{}{}
+			err := errors.Errorf("%d objects with the same name", len(objs))
+			fs.Errorf(o, "Ignoring as found %v - use --duplicates or rclone dedupe to fix", err)
+			fs.CountError(err)
+		}
+	}
+	return kept, refused
+}
+
+// dropRefused returns entries without the names in refused
+func dropRefused(entries fs.DirEntries, refused map[string]struct{}) fs.DirEntries {
+	if len(refused) == 0 {
+		return entries
+	}
+	kept := make(fs.DirEntries, 0, len(entries))
+	for _, entry := range entries {
+		if _, ok := refused[path.Base(entry.Remote())]; !ok {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
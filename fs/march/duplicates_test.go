@@ -0,0 +1,66 @@
+package march
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+)
+
+// dupeObject is an object with a modification time and an ID
+type dupeObject struct {
+	mockobject.Object
+	modTime time.Time
+	id      string
+}
+
+func (o dupeObject) ModTime() time.Time { return o.modTime }
+func (o dupeObject) ID() string         { return o.id }
+
+func remotes(entries fs.DirEntries) (out []string) {
+	for _, entry := range entries {
+		out = append(out, entry.Remote())
+	}
+	return out
+}
+
+func TestResolveDuplicates(t *testing.T) {
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	var (
+		old    = dupeObject{mockobject.Object("dir/a.txt"), t0, "id2"}
+		newer  = dupeObject{mockobject.Object("dir/a.txt"), t0.Add(time.Hour), "id3"}
+		sameID = dupeObject{mockobject.Object("dir/a.txt"), t0, "id1"}
+		single = dupeObject{mockobject.Object("dir/b.txt"), t0, "id4"}
+	)
+	entries := fs.DirEntries{old, single, newer, sameID}
+	defer func() { fs.Config.Duplicates = DuplicatesRefuse }()
+
+	// No duplicates returns the input
+	kept, refused := resolveDuplicates(fs.DirEntries{old, single}, true)
+	assert.Equal(t, fs.DirEntries{old, single}, kept)
+	assert.Nil(t, refused)
+
+	fs.Config.Duplicates = DuplicatesRefuse
+	oldErrors := accounting.Stats.GetErrors()
+	kept, refused = resolveDuplicates(entries, true)
+	assert.Equal(t, fs.DirEntries{single}, kept)
+	assert.Equal(t, map[string]struct{}{"a.txt": {}}, refused)
+	assert.Equal(t, oldErrors+1, accounting.Stats.GetErrors())
+	assert.Equal(t, fs.DirEntries{single}, dropRefused(fs.DirEntries{old, single}, refused))
+
+	fs.Config.Duplicates = DuplicatesNewest
+	kept, refused = resolveDuplicates(entries, true)
+	assert.Equal(t, fs.DirEntries{newer, single}, kept)
+	assert.Nil(t, refused)
+
+	fs.Config.Duplicates = DuplicatesRename
+	kept, _ = resolveDuplicates(entries, true)
+	assert.Equal(t, []string{"dir/a {id3}.txt", "dir/a {id1}.txt", "dir/a {id2}.txt", "dir/b.txt"}, remotes(kept))
+
+	// The destination uses the newest when renaming
+	kept, _ = resolveDuplicates(entries, false)
+	assert.Equal(t, fs.DirEntries{newer, single}, kept)
+}
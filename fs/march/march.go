@@ -3,6 +3,7 @@ package march
 
 import (
 	"context"
+	"fmt"
 	"path"
 	"sort"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -19,18 +21,21 @@ import (
 // calling Callback for each match
 type March struct {
 	// parameters
-	Ctx           context.Context // context for background goroutines
-	Fdst          fs.Fs           // source Fs
-	Fsrc          fs.Fs           // dest Fs
-	Dir           string          // directory
-	NoTraverse    bool            // don't traverse the destination
-	SrcIncludeAll bool            // don't include all files in the src
-	DstIncludeAll bool            // don't include all files in the destination
-	Callback      Marcher         // object to call with results
+	Ctx           context.Context          // context for background goroutines
+	Fdst          fs.Fs                    // source Fs
+	Fsrc          fs.Fs                    // dest Fs
+	Dir           string                   // directory
+	NoTraverse    bool                     // don't traverse the destination
+	SrcIncludeAll bool                     // don't include all files in the src
+	DstIncludeAll bool                     // don't include all files in the destination
+	Callback      Marcher                  // object to call with results
+	SkipSrc       func(src fs.Object) bool // if set, src objects it returns true for are ignored
+	Filter        *filter.Filter           // filter to use, the active one if nil
 	// internal state
 	srcListDir listDirFn // function to call to list a directory in the src
 	dstListDir listDirFn // function to call to list a directory in the dst
 	transforms []matchTransformFn
+	ignoreCase bool // set if names are compared case insensitively
 }
 
 // Marcher is called on each match
@@ -45,6 +50,9 @@ type Marcher interface {
 
 // init sets up a march over opt.Fsrc, and opt.Fdst calling back callback for each match
 func (m *March) init() {
+	if m.Filter == nil {
+		m.Filter = filter.GetActive()
+	}
 	m.srcListDir = m.makeListDir(m.Fsrc, m.SrcIncludeAll)
 	if !m.NoTraverse {
 		m.dstListDir = m.makeListDir(m.Fdst, m.DstIncludeAll)
@@ -58,8 +66,11 @@ func (m *March) init() {
 	//                  | Yes | No  | No                 |
 	//                  | No  | Yes | Yes                |
 	//                  | Yes | Yes | Yes                |
-	if m.Fdst.Features().CaseInsensitive {
+	//
+	// --ignore-case-sync forces a lower case compare
+	if m.Fdst.Features().CaseInsensitive || fs.Config.IgnoreCaseSync {
 		m.transforms = append(m.transforms, strings.ToLower)
+		m.ignoreCase = true
 	}
 }
 
@@ -68,9 +79,12 @@ type listDirFn func(dir string) (entries fs.DirEntries, err error)
 
 // makeListDir makes a listing function for the given fs and includeAll flags
 func (m *March) makeListDir(f fs.Fs, includeAll bool) listDirFn {
-	if (!fs.Config.UseListR || f.Features().ListR == nil) && !filter.Active.HaveFilesFrom() {
+	if !fs.Config.UseListR && fs.Config.FastListAuto && fs.Config.MaxDepth < 0 && f.Features().ListR != nil && !m.Filter.HaveFilesFrom() {
+		return m.makeListDirAuto(f, includeAll)
+	}
+	if (!fs.Config.UseListR || f.Features().ListR == nil) && !m.Filter.HaveFilesFrom() {
 		return func(dir string) (entries fs.DirEntries, err error) {
-			return list.DirSorted(f, includeAll, dir)
+			return list.DirSortedWithFilter(f, m.Filter, includeAll, dir)
 		}
 	}
 	var (
@@ -83,7 +97,7 @@ func (m *March) makeListDir(f fs.Fs, includeAll bool) listDirFn {
 		mu.Lock()
 		defer mu.Unlock()
 		if !started {
-			dirs, dirsErr = walk.NewDirTree(f, m.Dir, includeAll, fs.Config.MaxDepth)
+			dirs, dirsErr = walk.NewDirTreeWithFilter(f, m.Filter, m.Dir, includeAll, fs.Config.MaxDepth)
 			started = true
 		}
 		if dirsErr != nil {
@@ -99,6 +113,46 @@ func (m *March) makeListDir(f fs.Fs, includeAll bool) listDirFn {
 	}
 }
 
+// makeListDirAuto makes a listing function for --fast-list auto.
+//
+// This lists each directory with List unless walk.UseListR decides
+// the tree below it should be listed with ListR, in which case the
+// listings of its subdirectories are read from that.
+func (m *March) makeListDirAuto(f fs.Fs, includeAll bool) listDirFn {
+	var (
+		mu   sync.Mutex
+		dirs = make(walk.DirTree) // listings read with ListR
+	)
+	return func(dir string) (entries fs.DirEntries, err error) {
+		mu.Lock()
+		entries, ok := dirs[dir]
+		if ok {
+			delete(dirs, dir)
+		}
+		mu.Unlock()
+		if ok {
+			return entries, nil
+		}
+		entries, err = list.DirSortedWithFilter(f, m.Filter, includeAll, dir)
+		if err != nil || !walk.UseListR(f, entries) {
+			return entries, err
+		}
+		fs.Debugf(dir, "Listing directory tree with ListR as --fast-list is auto")
+		tree, err := walk.NewDirTreeWithFilter(f, m.Filter, dir, includeAll, -1)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		for dirPath, dirEntries := range tree {
+			if dirPath != dir {
+				dirs[dirPath] = dirEntries
+			}
+		}
+		mu.Unlock()
+		return entries, nil
+	}
+}
+
 // listDirJob describe a directory listing that needs to be done
 type listDirJob struct {
 	srcRemote string
@@ -118,10 +172,28 @@ func (m *March) Run() {
 		srcDepth = fs.MaxLevel
 	}
 	dstDepth := srcDepth
-	if filter.Active.Opt.DeleteExcluded {
+	if m.Filter.Opt.DeleteExcluded {
 		dstDepth = fs.MaxLevel
 	}
 
+	// Start some go routines to call the Callback for the objects
+	// in directories which have been compared. These run
+	// separately from the listing so the objects can be checked
+	// and transferred while other directories are still being
+	// listed. The queue is bounded so the listing can't get too
+	// far ahead of the transfers.
+	var callbacks sync.WaitGroup
+	compared := make(chan comparedDir, fs.Config.Checkers)
+	for i := 0; i < fs.Config.Checkers; i++ {
+		callbacks.Add(1)
+		go func() {
+			defer callbacks.Done()
+			for dir := range compared {
+				m.callObjects(dir)
+			}
+		}()
+	}
+
 	// Start some directory listing go routines
 	var wg sync.WaitGroup         // sync closing of go routines
 	var traversing sync.WaitGroup // running directory traversals
@@ -138,7 +210,7 @@ func (m *March) Run() {
 					if !ok {
 						return
 					}
-					jobs := m.processJob(job)
+					jobs, dir := m.processJob(job)
 					if len(jobs) > 0 {
 						traversing.Add(len(jobs))
 						go func() {
@@ -154,6 +226,15 @@ func (m *March) Run() {
 							}
 						}()
 					}
+					// Queue the objects once the
+					// subdirectories have been sent off
+					// to be listed
+					if !dir.empty() {
+						select {
+						case <-m.Ctx.Done():
+						case compared <- dir:
+						}
+					}
 					traversing.Done()
 				}
 			}
@@ -178,6 +259,8 @@ func (m *March) Run() {
 	traversing.Wait()
 	close(in)
 	wg.Wait()
+	close(compared)
+	callbacks.Wait()
 }
 
 // Check to see if the context has been cancelled
@@ -236,12 +319,9 @@ func newMatchEntries(entries fs.DirEntries, transforms []matchTransformFn) match
 	es := make(matchEntries, len(entries))
 	for i := range es {
 		es[i].entry = entries[i]
-		name := path.Base(entries[i].Remote())
-		es[i].leaf = name
-		for _, transform := range transforms {
-			name = transform(name)
-		}
-		es[i].name = name
+		leaf := path.Base(entries[i].Remote())
+		es[i].leaf = leaf
+		es[i].name = transformName(leaf, transforms)
 	}
 	es.sort()
 	return es
@@ -256,6 +336,14 @@ type matchPair struct {
 // comparison in matchListings.
 type matchTransformFn func(name string) string
 
+// transformName applies all the transforms to name
+func transformName(name string, transforms []matchTransformFn) string {
+	for _, transform := range transforms {
+		name = transform(name)
+	}
+	return name
+}
+
 // Process the two listings, matching up the items in the two slices
 // using the transform function on each name first.
 //
@@ -327,12 +415,171 @@ func matchListings(srcListEntries, dstListEntries fs.DirEntries, transforms []ma
 	return
 }
 
+// renamedObject is a source object renamed by resolveCaseCollisions
+// or resolveDuplicates so it doesn't overwrite another
+type renamedObject struct {
+	fs.Object
+	remote string
+}
+
+// renameObject returns o renamed to leaf in the same directory
+func renameObject(o fs.Object, leaf string) fs.Object {
+	return &renamedObject{
+		Object: o,
+		remote: path.Join(path.Dir(o.Remote()), leaf),
+	}
+}
+
+// Remote returns the new name of the object
+func (o *renamedObject) Remote() string {
+	return o.remote
+}
+
+// String returns the new name of the object for logging
+func (o *renamedObject) String() string {
+	return o.remote
+}
+
+// renameCaseCollision returns the object in entry renamed to leaf
+func renameCaseCollision(entry fs.DirEntry, leaf string) fs.DirEntry {
+	return renameObject(entry.(fs.Object), leaf)
+}
+
+// addLeafSuffix adds suffix to leaf before the extension, if any
+func addLeafSuffix(leaf, suffix string) string {
+	ext := path.Ext(leaf)
+	if ext == leaf {
+		ext = ""
+	}
+	return leaf[:len(leaf)-len(ext)] + suffix + ext
+}
+
+// caseCollisionLeaf returns the name that leaf is renamed to when it
+// is the nth case collision, eg "README (case 1).md"
+func caseCollisionLeaf(leaf string, n int) string {
+	return addLeafSuffix(leaf, fmt.Sprintf(" (case %d)", n))
+}
+
+// resolveCaseCollisions finds the entries in srcList whose names
+// differ only in case, so would overwrite each other in the
+// destination.
+//
+// The first entry (in sorted order) is kept. With
+// --rename-case-collisions the following objects are renamed to a
+// name which isn't in use already, otherwise they are reported as
+// errors and dropped.
+func resolveCaseCollisions(srcList fs.DirEntries, transforms []matchTransformFn) fs.DirEntries {
+	var (
+		entries  = newMatchEntries(srcList, transforms)
+		first    = make(map[string]string, len(srcList)) // first leaf for each name
+		counts   map[string]int                          // number of collisions for each name
+		taken    map[string]struct{}                     // names in use, made on the first rename
+		kept     = make(fs.DirEntries, 0, len(srcList))
+		prevLeaf string
+		prevNew  string // new leaf for prevLeaf or "" if not renamed
+		prevKept bool
+	)
+	for _, entry := range entries {
+		firstLeaf, found := first[entry.name]
+		if !found {
+			first[entry.name] = entry.leaf
+		}
+		// exact duplicates are treated the same as the previous
+		// entry and are dealt with by matchListings
+		if entry.leaf == prevLeaf {
+			if prevNew != "" {
+				kept = append(kept, renameCaseCollision(entry.entry, prevNew))
+			} else if prevKept {
+				kept = append(kept, entry.entry)
+			}
+			continue
+		}
+		prevLeaf, prevNew, prevKept = entry.leaf, "", true
+		if !found {
+			kept = append(kept, entry.entry)
+			continue
+		}
+		if _, isObject := entry.entry.(fs.Object); isObject && fs.Config.RenameCaseCollisions {
+			if counts == nil {
+				counts = make(map[string]int)
+				taken = make(map[string]struct{}, len(entries))
+				for _, e := range entries {
+					taken[e.name] = struct{}{}
+				}
+			}
+			// find the next name which isn't in use
+			for {
+				counts[entry.name]++
+				prevNew = caseCollisionLeaf(entry.leaf, counts[entry.name])
+				newName := transformName(prevNew, transforms)
+				if _, found := taken[newName]; !found {
+					taken[newName] = struct{}{}
+					break
+				}
+			}
+			fs.Logf(entry.entry, "Renaming to %q as it differs only in case from %q", prevNew, firstLeaf)
+			kept = append(kept, renameCaseCollision(entry.entry, prevNew))
+			continue
+		}
+		prevKept = false
+		err := errors.Errorf("%s differs only in case from %q", fs.DirEntryType(entry.entry), firstLeaf)
+		fs.Errorf(entry.entry, "Ignoring as %v", err)
+		fs.CountError(err)
+	}
+	return kept
+}
+
+// comparedDir holds the objects from a directory which has been
+// compared which are waiting for the Callback to be called
+type comparedDir struct {
+	srcOnly fs.DirEntries
+	dstOnly fs.DirEntries
+	matches []matchPair
+}
+
+// empty returns true if there are no objects in dir
+func (dir *comparedDir) empty() bool {
+	return len(dir.srcOnly) == 0 && len(dir.dstOnly) == 0 && len(dir.matches) == 0
+}
+
+// isDir returns true if entry is a directory
+func isDir(entry fs.DirEntry) bool {
+	_, ok := entry.(fs.Directory)
+	return ok
+}
+
+// callObjects calls the Callback for each of the objects in dir
+func (m *March) callObjects(dir comparedDir) {
+	for _, src := range dir.srcOnly {
+		if m.aborting() {
+			return
+		}
+		m.Callback.SrcOnly(src)
+	}
+	for _, dst := range dir.dstOnly {
+		if m.aborting() {
+			return
+		}
+		m.Callback.DstOnly(dst)
+	}
+	for _, match := range dir.matches {
+		if m.aborting() {
+			return
+		}
+		m.Callback.Match(match.dst, match.src)
+	}
+}
+
 // processJob processes a listDirJob listing the source and
-// destination directories, comparing them and returning a slice of
-// more jobs
+// destination directories and comparing them.
+//
+// The Callback is called for the directories straight away and a
+// slice of more jobs is returned for the ones to recurse into.  The
+// objects are returned in dir so the Callback can be called for them
+// without holding up the listing.
 //
 // returns errors using processError
-func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
+func (m *March) processJob(job listDirJob) (jobs []listDirJob, dir comparedDir) {
 	var (
 		srcList, dstList       fs.DirEntries
 		srcListErr, dstListErr error
@@ -360,14 +607,41 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	if srcListErr != nil {
 		fs.Errorf(job.srcRemote, "error reading source directory: %v", srcListErr)
 		fs.CountError(srcListErr)
-		return nil
+		return nil, dir
 	}
 	if dstListErr == fs.ErrorDirNotFound {
 		// Copy the stuff anyway
 	} else if dstListErr != nil {
 		fs.Errorf(job.dstRemote, "error reading destination directory: %v", dstListErr)
 		fs.CountError(dstListErr)
-		return nil
+		return nil, dir
+	}
+
+	// Drop any src objects which should be skipped
+	if m.SkipSrc != nil {
+		kept := srcList[:0]
+		for _, src := range srcList {
+			if srcObj, ok := src.(fs.Object); ok && m.SkipSrc(srcObj) {
+				continue
+			}
+			kept = append(kept, src)
+		}
+		srcList = kept
+	}
+
+	// Deal with objects with the same name on backends which
+	// allow them
+	if m.Fsrc.Features().DuplicateFiles || (m.Fdst.Features().DuplicateFiles && !m.NoTraverse) {
+		var srcRefused, dstRefused map[string]struct{}
+		srcList, srcRefused = resolveDuplicates(srcList, true)
+		dstList, dstRefused = resolveDuplicates(dstList, false)
+		srcList = dropRefused(srcList, dstRefused)
+		dstList = dropRefused(dstList, srcRefused)
+	}
+
+	// Deal with source names which differ only in case
+	if m.ignoreCase {
+		srcList = resolveCaseCollisions(srcList, m.transforms)
 	}
 
 	// If NoTraverse is set, then try to find a matching object
@@ -388,7 +662,11 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	srcOnly, dstOnly, matches := matchListings(srcList, dstList, m.transforms)
 	for _, src := range srcOnly {
 		if m.aborting() {
-			return nil
+			return nil, dir
+		}
+		if !isDir(src) {
+			dir.srcOnly = append(dir.srcOnly, src)
+			continue
 		}
 		recurse := m.Callback.SrcOnly(src)
 		if recurse && job.srcDepth > 0 {
@@ -402,7 +680,11 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	}
 	for _, dst := range dstOnly {
 		if m.aborting() {
-			return nil
+			return nil, dir
+		}
+		if !isDir(dst) {
+			dir.dstOnly = append(dir.dstOnly, dst)
+			continue
 		}
 		recurse := m.Callback.DstOnly(dst)
 		if recurse && job.dstDepth > 0 {
@@ -415,7 +697,11 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 	}
 	for _, match := range matches {
 		if m.aborting() {
-			return nil
+			return nil, dir
+		}
+		if !isDir(match.src) {
+			dir.matches = append(dir.matches, match)
+			continue
 		}
 		recurse := m.Callback.Match(match.dst, match.src)
 		if recurse && job.srcDepth > 0 && job.dstDepth > 0 {
@@ -427,5 +713,5 @@ func (m *March) processJob(job listDirJob) (jobs []listDirJob) {
 			})
 		}
 	}
-	return jobs
+	return jobs, dir
 }
@@ -3,10 +3,13 @@
 package march
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 )
@@ -169,3 +172,101 @@ func TestMatchListings(t *testing.T) {
 		assert.Equal(t, test.matches, matches, test.what)
 	}
 }
+
+func TestResolveCaseCollisions(t *testing.T) {
+	var (
+		readme = mockobject.Object("dir/README.md")
+		Readme = mockobject.Object("dir/Readme.md")
+		readMe = mockobject.Object("dir/readme.md")
+		other  = mockobject.Object("dir/OTHER")
+		dupe   = mockobject.Object("dir/OTHER")
+		dir    = fs.NewDir("dir/other", time.Now())
+	)
+	transforms := []matchTransformFn{strings.ToLower}
+	input := fs.DirEntries{readMe, other, Readme, readme, dupe, dir}
+
+	// Collisions are dropped and counted as errors
+	oldErrors := accounting.Stats.GetErrors()
+	kept := resolveCaseCollisions(input, transforms)
+	assert.Equal(t, fs.DirEntries{other, dupe, readme}, kept)
+	assert.Equal(t, oldErrors+3, accounting.Stats.GetErrors())
+
+	// Objects are renamed with --rename-case-collisions
+	fs.Config.RenameCaseCollisions = true
+	defer func() { fs.Config.RenameCaseCollisions = false }()
+	oldErrors = accounting.Stats.GetErrors()
+	kept = resolveCaseCollisions(input, transforms)
+	assert.Equal(t, []string{"dir/OTHER", "dir/OTHER", "dir/README.md", "dir/Readme (case 1).md", "dir/readme (case 2).md"}, remotes(kept))
+	assert.Equal(t, oldErrors+1, accounting.Stats.GetErrors(), "directories can't be renamed")
+}
+
+func TestResolveCaseCollisionsNameInUse(t *testing.T) {
+	var (
+		readme      = mockobject.Object("dir/readme.md")
+		README      = mockobject.Object("dir/README.md")
+		Readme      = mockobject.Object("dir/Readme.md")
+		readmeCase1 = mockobject.Object("dir/readme (CASE 1).md")
+		readmeCase2 = mockobject.Object("dir/readme (case 2).md")
+	)
+	transforms := []matchTransformFn{strings.ToLower}
+	input := fs.DirEntries{readme, README, Readme, readmeCase1, readmeCase2}
+
+	fs.Config.RenameCaseCollisions = true
+	defer func() { fs.Config.RenameCaseCollisions = false }()
+	kept := resolveCaseCollisions(input, transforms)
+	assert.Equal(t, []string{"dir/readme (CASE 1).md", "dir/readme (case 2).md", "dir/README.md", "dir/Readme (case 3).md", "dir/readme (case 4).md"}, remotes(kept))
+}
+
+func TestCaseCollisionLeaf(t *testing.T) {
+	assert.Equal(t, "README (case 1).md", caseCollisionLeaf("README.md", 1))
+	assert.Equal(t, "Makefile (case 2)", caseCollisionLeaf("Makefile", 2))
+	assert.Equal(t, ".Hidden (case 1)", caseCollisionLeaf(".Hidden", 1))
+}
+
+// recorder is a Marcher which records the calls made to it
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) SrcOnly(src fs.DirEntry) bool {
+	r.calls = append(r.calls, "srcOnly "+src.Remote())
+	return false
+}
+
+func (r *recorder) DstOnly(dst fs.DirEntry) bool {
+	r.calls = append(r.calls, "dstOnly "+dst.Remote())
+	return false
+}
+
+func (r *recorder) Match(dst, src fs.DirEntry) bool {
+	r.calls = append(r.calls, "match "+src.Remote())
+	return false
+}
+
+func TestCallObjects(t *testing.T) {
+	var (
+		a = mockobject.Object("a")
+		b = mockobject.Object("b")
+		c = mockobject.Object("c")
+	)
+	assert.True(t, (&comparedDir{}).empty())
+	dir := comparedDir{
+		srcOnly: fs.DirEntries{a},
+		dstOnly: fs.DirEntries{b},
+		matches: []matchPair{{src: c, dst: c}},
+	}
+	assert.False(t, dir.empty())
+
+	r := &recorder{}
+	m := &March{Ctx: context.Background(), Callback: r}
+	m.callObjects(dir)
+	assert.Equal(t, []string{"srcOnly a", "dstOnly b", "match c"}, r.calls)
+
+	// Nothing is called once the march is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = &recorder{}
+	m = &March{Ctx: ctx, Callback: r}
+	m.callObjects(dir)
+	assert.Nil(t, r.calls)
+}
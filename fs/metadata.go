@@ -0,0 +1,76 @@
+package fs
+
+import (
+	"os"
+	"strconv"
+)
+
+// Metadata is the POSIX metadata of an Object as read by the
+// Metadataer interface.
+//
+// The keys used are
+//
+//     mode - the permission bits in octal, eg "644"
+//     uid  - the numeric user ID of the owner
+//     gid  - the numeric group ID of the owner
+//
+// Any of them may be missing if the backend doesn't know them.
+type Metadata map[string]string
+
+// Metadataer is an optional interface for Object
+type Metadataer interface {
+	// Metadata returns the POSIX metadata of the Object
+	Metadata() (Metadata, error)
+}
+
+// GetMetadata returns the Metadata of o, looking through any wrapping
+// Objects, or nil if it has none.
+func GetMetadata(o Object) (Metadata, error) {
+	for o != nil {
+		if do, ok := o.(Metadataer); ok {
+			return do.Metadata()
+		}
+		unwrap, ok := o.(ObjectUnWrapper)
+		if !ok {
+			break
+		}
+		o = unwrap.UnWrap()
+	}
+	return nil, nil
+}
+
+// Mode returns the permission bits from the metadata
+func (m Metadata) Mode() (mode os.FileMode, ok bool) {
+	value, ok := m["mode"]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(value, 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return os.FileMode(n).Perm(), true
+}
+
+// UID returns the user ID of the owner from the metadata
+func (m Metadata) UID() (uid uint32, ok bool) {
+	return m.id("uid")
+}
+
+// GID returns the group ID of the owner from the metadata
+func (m Metadata) GID() (gid uint32, ok bool) {
+	return m.id("gid")
+}
+
+// id parses the numeric id in key
+func (m Metadata) id(key string) (id uint32, ok bool) {
+	value, ok := m[key]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
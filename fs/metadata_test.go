@@ -0,0 +1,30 @@
+package fs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadata(t *testing.T) {
+	m := Metadata{
+		"mode": "754",
+		"uid":  "1000",
+		"gid":  "potato",
+	}
+	mode, ok := m.Mode()
+	assert.True(t, ok)
+	assert.Equal(t, os.FileMode(0754), mode)
+	uid, ok := m.UID()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(1000), uid)
+	_, ok = m.GID()
+	assert.False(t, ok)
+
+	var empty Metadata
+	_, ok = empty.Mode()
+	assert.False(t, ok)
+	_, ok = empty.UID()
+	assert.False(t, ok)
+}
@@ -4,11 +4,48 @@ import (
 	"mime"
 	"path"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
+// mimeTypeMap is the extra extension to mime type mappings set with
+// --mime-type-map keyed by lower case extension
+var mimeTypeMap = map[string]string{}
+
+// AddMimeTypeMap adds the extension to mime type mappings in
+// mappings, which should be of the form "ext=type", to those used to
+// guess mime types from file names.
+func AddMimeTypeMap(mappings []string) error {
+	for _, mapping := range mappings {
+		equals := strings.IndexRune(mapping, '=')
+		if equals < 0 {
+			return errors.Errorf("mapping %q must be of the form ext=type", mapping)
+		}
+		ext, mimeType := mapping[:equals], mapping[equals+1:]
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if len(ext) < 2 || !strings.ContainsRune(mimeType, '/') {
+			return errors.Errorf("mapping %q must be of the form ext=type", mapping)
+		}
+		mimeTypeMap[strings.ToLower(ext)] = mimeType
+	}
+	return nil
+}
+
 // MimeTypeFromName returns a guess at the mime type from the name
+//
+// The mappings from --mime-type-map are used first, then if
+// --no-mime-detection is set it is always application/octet-stream.
 func MimeTypeFromName(remote string) (mimeType string) {
-	mimeType = mime.TypeByExtension(path.Ext(remote))
+	ext := path.Ext(remote)
+	if mapped, ok := mimeTypeMap[strings.ToLower(ext)]; ok {
+		return mapped
+	}
+	if Config.NoMimeDetection {
+		return "application/octet-stream"
+	}
+	mimeType = mime.TypeByExtension(ext)
 	if !strings.ContainsRune(mimeType, '/') {
 		mimeType = "application/octet-stream"
 	}
@@ -29,6 +66,16 @@ func MimeType(o ObjectInfo) (mimeType string) {
 	return MimeTypeFromName(o.Remote())
 }
 
+// UploadMimeType returns the MimeType to upload o with.
+//
+// This is the --mime-type if it is set, otherwise MimeType(o).
+func UploadMimeType(o ObjectInfo) string {
+	if Config.MimeType != "" {
+		return Config.MimeType
+	}
+	return MimeType(o)
+}
+
 // MimeTypeDirEntry returns the MimeType of a DirEntry
 //
 // It returns "inode/directory" for directories, or uses
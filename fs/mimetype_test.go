@@ -0,0 +1,31 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMimeTypeFromName(t *testing.T) {
+	oldMap, oldNoMimeDetection := mimeTypeMap, Config.NoMimeDetection
+	defer func() {
+		mimeTypeMap, Config.NoMimeDetection = oldMap, oldNoMimeDetection
+	}()
+	mimeTypeMap = map[string]string{}
+
+	assert.Equal(t, "image/jpeg", MimeTypeFromName("dir/file.jpg"))
+	assert.Equal(t, "application/octet-stream", MimeTypeFromName("file.potato"))
+	assert.Equal(t, "application/octet-stream", MimeTypeFromName("file"))
+
+	assert.Error(t, AddMimeTypeMap([]string{"potato"}))
+	assert.Error(t, AddMimeTypeMap([]string{".=text/plain"}))
+	assert.Error(t, AddMimeTypeMap([]string{"potato=text"}))
+	assert.NoError(t, AddMimeTypeMap([]string{".potato=text/x-potato", "JS=text/javascript"}))
+	assert.Equal(t, "text/x-potato", MimeTypeFromName("file.potato"))
+	assert.Equal(t, "text/x-potato", MimeTypeFromName("file.POTATO"))
+	assert.Equal(t, "text/javascript", MimeTypeFromName("file.js"))
+
+	Config.NoMimeDetection = true
+	assert.Equal(t, "application/octet-stream", MimeTypeFromName("dir/file.jpg"))
+	assert.Equal(t, "text/x-potato", MimeTypeFromName("file.potato"))
+}
@@ -0,0 +1,143 @@
+package operations
+
+import (
+	"io"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+)
+
+// checkCompare compares the hashes of src and dst in the way set by
+// --compare for CheckHashes.  It returns ok false if they can't be
+// compared that way, in which case they should be compared as usual.
+//
+// With --compare provider-hash a hash the remote of dst stores is
+// compared, reading the data of src to work it out if the remote of
+// src doesn't have it.
+//
+// With --compare etag the data of src is compared with the ETag the
+// provider stores for dst, working out the ETag of objects uploaded
+// in parts.  The hash type returned is MD5 as the ETags are made from
+// MD5s.
+//
+// With --compare wrapped-hash the hashes of the data stored by the
+// remotes src and dst wrap are compared, if they are overlays such as
+// crypt.  This only matches if the wrapped data was copied as is, as
+// crypt encrypts each upload with a random nonce.
+func checkCompare(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, ok bool, err error) {
+	switch fs.Config.Compare {
+	case "provider-hash":
+		return compareProviderHash(src, dst)
+	case "etag":
+		return compareETag(src, dst)
+	case "wrapped-hash":
+		return compareWrappedHash(src, dst)
+	}
+	return false, hash.None, false, nil
+}
+
+// compareProviderHash does --compare provider-hash for checkCompare
+func compareProviderHash(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, ok bool, err error) {
+	dstHashes := dst.Fs().Hashes()
+	if dstHashes.Count() == 0 {
+		return false, hash.None, false, nil
+	}
+	var srcHash string
+	if common := src.Fs().Hashes().Overlap(dstHashes); common.Count() > 0 {
+		ht = common.GetOne()
+		srcHash, err = src.Hash(ht)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(src, "Failed to calculate src hash: %v", err)
+			return false, ht, true, err
+		}
+	} else {
+		ht = dstHashes.GetOne()
+	}
+	if srcHash == "" {
+		srcObj, isObject := src.(fs.Object)
+		if !isObject {
+			return false, hash.None, false, nil
+		}
+		srcHash, err = readHash(srcObj, ht)
+		if err != nil {
+			fs.CountError(err)
+			fs.Errorf(src, "Failed to calculate src hash: %v", err)
+			return false, ht, true, err
+		}
+	}
+	equal, ht, err = checkDstHash(src, dst, ht, srcHash)
+	return equal, ht, true, err
+}
+
+// readHash works out the hash of type ht of o by reading its data
+func readHash(o fs.Object, ht hash.Type) (sum string, err error) {
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(ht))
+	if err != nil {
+		return "", err
+	}
+	in, err := o.Open()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open")
+	}
+	defer fs.CheckClose(in, &err)
+	_, err = io.Copy(hasher, in)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read")
+	}
+	return hasher.Sums()[ht], nil
+}
+
+// compareWrappedHash does --compare wrapped-hash for checkCompare
+func compareWrappedHash(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, ok bool, err error) {
+	srcObj, isObject := src.(fs.Object)
+	if !isObject {
+		return false, hash.None, false, nil
+	}
+	common := fs.WrappedHashes(src.Fs()).Overlap(fs.WrappedHashes(dst.Fs()))
+	if common.Count() == 0 {
+		return false, hash.None, false, nil
+	}
+	ht = common.GetOne()
+	srcHash, err := fs.WrappedHash(srcObj, ht)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to read src hash: %v", err)
+		return false, ht, true, err
+	}
+	if srcHash == "" {
+		return false, hash.None, false, nil
+	}
+	equal, ht, err = checkDstHash(src, dst, ht, srcHash)
+	return equal, ht, true, err
+}
+
+// compareETag does --compare etag for checkCompare
+func compareETag(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, ok bool, err error) {
+	etager, isETager := dst.(fs.ETager)
+	srcObj, isObject := src.(fs.Object)
+	if !isETager || !isObject || etager.ETag() == "" {
+		return false, hash.None, false, nil
+	}
+	in, err := srcObj.Open()
+	if err != nil {
+		err = errors.Wrap(err, "failed to open")
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to compare with ETag: %v", err)
+		return false, hash.MD5, true, err
+	}
+	defer fs.CheckClose(in, &err)
+	equal, err = etager.MatchETag(in)
+	if err == hash.ErrUnsupported {
+		return false, hash.None, false, nil
+	} else if err != nil {
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to compare with ETag: %v", err)
+		return false, hash.MD5, true, err
+	}
+	if !equal {
+		fs.Debugf(dst, "ETag %s doesn't match the data of %v", etager.ETag(), src)
+	}
+	return equal, hash.MD5, true, nil
+}
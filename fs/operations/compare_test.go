@@ -0,0 +1,178 @@
+package operations
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fstest/mockfs"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashFs is a mock Fs which supports the hashes given
+type hashFs struct {
+	*mockfs.Fs
+	hashes hash.Set
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *hashFs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// compareTestObject is a mock Object with content, an MD5 and an ETag
+type compareTestObject struct {
+	fs.Object
+	f    fs.Info
+	md5  string
+	etag string
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *compareTestObject) Fs() fs.Info {
+	return o.f
+}
+
+// Hash returns the MD5 of the object
+func (o *compareTestObject) Hash(ht hash.Type) (string, error) {
+	if ht != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	return o.md5, nil
+}
+
+// ETag returns the ETag of the object
+func (o *compareTestObject) ETag() string {
+	return o.etag
+}
+
+// MatchETag returns whether the ETag is the MD5 of the data in in
+func (o *compareTestObject) MatchETag(in io.Reader) (bool, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return false, err
+	}
+	return md5hex(data) == o.etag, nil
+}
+
+func md5hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCheckHashesCompare(t *testing.T) {
+	oldCompare := fs.Config.Compare
+	defer func() { fs.Config.Compare = oldCompare }()
+
+	data := []byte("hello world")
+	fsrc := &hashFs{Fs: mockfs.NewFs("src", "src"), hashes: hash.NewHashSet()}
+	fdst := &hashFs{Fs: mockfs.NewFs("dst", "dst"), hashes: hash.NewHashSet(hash.MD5)}
+	src := &compareTestObject{Object: mockobject.New("file").WithContent(data, mockobject.SeekModeNone), f: fsrc}
+	same := &compareTestObject{Object: mockobject.New("file"), f: fdst, md5: md5hex(data), etag: md5hex(data)}
+	differ := &compareTestObject{Object: mockobject.New("file"), f: fdst, md5: md5hex(data[1:]), etag: md5hex(data[1:])}
+
+	for _, test := range []struct {
+		compare string
+		dst     fs.Object
+		equal   bool
+		ht      hash.Type
+	}{
+		// no hashes in common so can't compare
+		{"", same, true, hash.None},
+		{"", differ, true, hash.None},
+		{"provider-hash", same, true, hash.MD5},
+		{"provider-hash", differ, false, hash.MD5},
+		{"etag", same, true, hash.MD5},
+		{"etag", differ, false, hash.MD5},
+		// no ETag so compared as usual
+		{"etag", &compareTestObject{Object: mockobject.New("file"), f: fdst, md5: md5hex(data)}, true, hash.None},
+	} {
+		fs.Config.Compare = test.compare
+		equal, ht, err := CheckHashes(src, test.dst)
+		require.NoError(t, err)
+		assert.Equal(t, test.equal, equal, test.compare)
+		assert.Equal(t, test.ht, ht, test.compare)
+	}
+}
+
+// wrapFs is a mock overlay Fs without hashes which wraps an Fs
+type wrapFs struct {
+	*mockfs.Fs
+	inner fs.Fs
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *wrapFs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// Features returns the optional features of this Fs
+func (f *wrapFs) Features() *fs.Features {
+	return &fs.Features{UnWrap: func() fs.Fs { return f.inner }}
+}
+
+// wrapObject is a mock Object in a wrapFs
+type wrapObject struct {
+	fs.Object
+	f     fs.Info
+	inner fs.Object
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *wrapObject) Fs() fs.Info {
+	return o.f
+}
+
+// Hash returns no hashes
+func (o *wrapObject) Hash(ht hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// UnWrap returns the wrapped Object
+func (o *wrapObject) UnWrap() fs.Object {
+	return o.inner
+}
+
+func TestCheckHashesCompareWrapped(t *testing.T) {
+	oldCompare := fs.Config.Compare
+	defer func() { fs.Config.Compare = oldCompare }()
+
+	data := []byte("hello world")
+	fsrc := &wrapFs{Fs: mockfs.NewFs("src", "src"), inner: &hashFs{Fs: mockfs.NewFs("isrc", "isrc"), hashes: hash.NewHashSet(hash.MD5)}}
+	fdst := &wrapFs{Fs: mockfs.NewFs("dst", "dst"), inner: &hashFs{Fs: mockfs.NewFs("idst", "idst"), hashes: hash.NewHashSet(hash.MD5)}}
+	assert.Equal(t, hash.NewHashSet(hash.Wrapped(hash.MD5)), fs.WrappedHashes(fsrc))
+	assert.Equal(t, hash.Set(hash.None), fs.WrappedHashes(fsrc.inner))
+
+	wrap := func(f *wrapFs, md5 string) fs.Object {
+		inner := &compareTestObject{Object: mockobject.New("file"), f: f.inner, md5: md5}
+		return &wrapObject{Object: mockobject.New("file"), f: f, inner: inner}
+	}
+	src := wrap(fsrc, md5hex(data))
+	same := wrap(fdst, md5hex(data))
+	differ := wrap(fdst, md5hex(data[1:]))
+
+	for _, test := range []struct {
+		compare string
+		dst     fs.Object
+		equal   bool
+		ht      hash.Type
+	}{
+		// wrapped hashes aren't compared without --compare
+		{"", same, true, hash.None},
+		{"", differ, true, hash.None},
+		{"wrapped-hash", same, true, hash.Wrapped(hash.MD5)},
+		{"wrapped-hash", differ, false, hash.Wrapped(hash.MD5)},
+	} {
+		fs.Config.Compare = test.compare
+		equal, ht, err := CheckHashes(src, test.dst)
+		require.NoError(t, err)
+		assert.Equal(t, test.equal, equal, test.compare)
+		assert.Equal(t, test.ht, ht, test.compare)
+	}
+}
@@ -0,0 +1,84 @@
+package operations
+
+import (
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Values for --conflict-resolve
+const (
+	ConflictResolveNewer  = "newer"  // transfer if the source is newer
+	ConflictResolveLarger = "larger" // transfer if the source is larger
+	ConflictResolveSource = "source" // always transfer
+	ConflictResolveDest   = "dest"   // never transfer
+)
+
+// CheckConflictResolve returns an error if --conflict-resolve isn't
+// one of the allowed values.
+func CheckConflictResolve() error {
+	switch fs.Config.ConflictResolve {
+	case "", ConflictResolveNewer, ConflictResolveLarger, ConflictResolveSource, ConflictResolveDest:
+		return nil
+	}
+	return errors.Errorf("unknown --conflict-resolve %q - must be newer, larger, source or dest", fs.Config.ConflictResolve)
+}
+
+// conflictWindow returns the modify window to compare the times of
+// src and dst with or false if they can't be compared.
+func conflictWindow(src, dst fs.Object) (modifyWindow time.Duration, ok bool) {
+	modifyWindow = fs.GetModifyWindow(src.Fs(), dst.Fs())
+	return modifyWindow, modifyWindow != fs.ModTimeNotSupported
+}
+
+// conflictReason returns why it is ambiguous whether src should
+// replace dst, or "" if it isn't.
+//
+// This is the case if the sizes differ but the modification times
+// are the same or aren't being compared so there is no way of
+// telling which is the most recent version.  It is only checked if
+// --conflict-resolve is set.
+func conflictReason(src, dst fs.Object) string {
+	if fs.Config.ConflictResolve == "" || fs.Config.CheckSum || !sizeDiffers(src, dst) {
+		return ""
+	}
+	if fs.Config.SizeOnly {
+		return "--size-only is in use"
+	}
+	modifyWindow, ok := conflictWindow(src, dst)
+	if !ok {
+		return "modification times aren't supported"
+	}
+	dt := dst.ModTime().Sub(src.ModTime())
+	if dt < modifyWindow && dt > -modifyWindow {
+		return "modification times are the same"
+	}
+	return ""
+}
+
+// resolveConflict returns whether src should replace dst according
+// to --conflict-resolve.
+func resolveConflict(src, dst fs.Object) (transfer bool, why string) {
+	switch fs.Config.ConflictResolve {
+	case ConflictResolveNewer:
+		if modifyWindow, ok := conflictWindow(src, dst); ok {
+			dt := src.ModTime().Sub(dst.ModTime())
+			if dt >= modifyWindow {
+				return true, "copying as the source is newer"
+			} else if dt <= -modifyWindow {
+				return false, "skipping as the destination is newer"
+			}
+		}
+		// fall back to the size if the times don't decide it
+		fallthrough
+	case ConflictResolveLarger:
+		if src.Size() > dst.Size() {
+			return true, "copying as the source is larger"
+		}
+		return false, "skipping as the destination is larger"
+	case ConflictResolveDest:
+		return false, "skipping to keep the destination"
+	}
+	return true, "copying to keep the source"
+}
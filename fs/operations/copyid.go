@@ -0,0 +1,99 @@
+package operations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// CopyIDHelp returns the help for the copyid and moveid backend
+// commands of the backend called name which runs them with
+// CopyOrMoveIDs.
+func CopyIDHelp(name string) []fs.CommandHelp {
+	return []fs.CommandHelp{{
+		Name:  "copyid",
+		Short: "Copy files by ID.",
+		Long: fmt.Sprintf(`This copies files by ID to a path in the remote, which is useful
+for automation which keeps track of files by their ID, as these don't
+change when the files are renamed or moved.
+
+    rclone backend copyid %s: ID path
+
+If path ends with "/" (or is empty) then the file will be copied into
+that directory with its current name, otherwise it is copied to path.
+More than one ID and path pair can be given.
+
+IDs can be found with "rclone lsjson" or "rclone lsf --format i".
+`, name),
+	}, {
+		Name:  "moveid",
+		Short: "Move files by ID.",
+		Long: fmt.Sprintf(`This moves files by ID to a path in the remote, in the same way as
+copyid copies them.
+
+    rclone backend moveid %s: ID path
+`, name),
+	}}
+}
+
+// IDFinder looks up the file with ID for CopyOrMoveIDs.
+//
+// It returns the file and a function to move it to dest, or nil if
+// it can be moved with the Move of the Fs.
+type IDFinder func(ID string) (o fs.Object, move func(dest string) (fs.Object, error), err error)
+
+// CopyOrMoveIDs runs the copyid backend command on f, or moveid if
+// move is set, with arg which are pairs of file IDs and the paths to
+// copy them to.
+//
+// The files are looked up with find and copied or moved server side.
+func CopyOrMoveIDs(f fs.Fs, arg []string, move bool, find IDFinder) error {
+	if len(arg) == 0 || len(arg)%2 != 0 {
+		return errors.New("need pairs of ID and path")
+	}
+	for i := 0; i < len(arg); i += 2 {
+		err := copyOrMoveID(f, arg[i], arg[i+1], move, find)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyOrMoveID copies or moves the file with ID to dest
+func copyOrMoveID(f fs.Fs, ID, dest string, move bool, find IDFinder) error {
+	o, doMove, err := find(ID)
+	if err != nil {
+		return err
+	}
+	if dest == "" || strings.HasSuffix(dest, "/") {
+		dest += o.Remote()
+	}
+	action := "copy"
+	if move {
+		action = "move"
+	}
+	if SkipDestructive(o, action+" to "+dest) {
+		return nil
+	}
+	fs.Debugf(o, "ID %q: %s to %q", ID, action, dest)
+	switch {
+	case move && doMove != nil:
+		_, err = doMove(dest)
+	case move:
+		fsMove := f.Features().Move
+		if fsMove == nil {
+			return fs.ErrorCantMove
+		}
+		_, err = fsMove(o, dest)
+	default:
+		fsCopy := f.Features().Copy
+		if fsCopy == nil {
+			return fs.ErrorCantCopy
+		}
+		_, err = fsCopy(o, dest)
+	}
+	return err
+}
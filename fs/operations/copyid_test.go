@@ -0,0 +1,45 @@
+package operations_test
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyOrMoveIDs(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().Copy == nil || r.Fremote.Features().Move == nil {
+		t.Skip("remote can't copy and move server side")
+	}
+	file1 := r.WriteObject("sub/file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// use the path of the files as their IDs
+	find := func(ID string) (fs.Object, func(dest string) (fs.Object, error), error) {
+		o, err := r.Fremote.NewObject(ID)
+		return o, nil, err
+	}
+
+	err := operations.CopyOrMoveIDs(r.Fremote, []string{"sub/file1"}, false, find)
+	assert.Error(t, err)
+	err = operations.CopyOrMoveIDs(r.Fremote, []string{"potato", "dir/"}, false, find)
+	assert.Error(t, err)
+
+	// copy into a directory keeping the name
+	err = operations.CopyOrMoveIDs(r.Fremote, []string{"sub/file1", "dir/"}, false, find)
+	require.NoError(t, err)
+	file2 := file1
+	file2.Path = "dir/sub/file1"
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// move to a new name
+	err = operations.CopyOrMoveIDs(r.Fremote, []string{"sub/file1", "moved"}, true, find)
+	require.NoError(t, err)
+	file1.Path = "moved"
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
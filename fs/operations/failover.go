@@ -0,0 +1,70 @@
+package operations
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/fserrors"
+)
+
+// ErrorCopiedToFailover is returned when a file couldn't be copied to
+// the destination but was copied to the --failover-dest instead.
+var ErrorCopiedToFailover = errors.New("copied to --failover-dest instead of the destination")
+
+// failover caches the Fs made from --failover-dest
+var failover struct {
+	mu   sync.Mutex
+	dest string // the --failover-dest f was made from
+	f    fs.Fs  // nil if it isn't set or couldn't be made
+}
+
+// failoverFs returns the Fs for --failover-dest or nil if it isn't set
+// or can't be made.
+func failoverFs() fs.Fs {
+	failover.mu.Lock()
+	defer failover.mu.Unlock()
+	if failover.dest == fs.Config.FailoverDest {
+		return failover.f
+	}
+	failover.dest = fs.Config.FailoverDest
+	failover.f = nil
+	if failover.dest == "" {
+		return nil
+	}
+	f, err := fs.NewFs(failover.dest)
+	if err != nil {
+		fs.Errorf(nil, "Failed to make --failover-dest %q: %v", failover.dest, err)
+		return nil
+	}
+	failover.f = f
+	return f
+}
+
+// copyToFailover copies src to remote on the --failover-dest after
+// copying it to f failed with err after retries retries.
+//
+// It returns true if src was copied, in which case the failure is
+// recorded in the --error-report and the caller should return
+// ErrorCopiedToFailover.  Fatal
+// errors, failures copying to the --failover-dest itself and failures
+// while shutting down aren't failed over.
+func copyToFailover(group *accounting.StatsInfo, f fs.Fs, remote string, src fs.Object, retries int, err error) bool {
+	if fserrors.IsFatalError(err) || accounting.ShuttingDown() {
+		return false
+	}
+	failoverF := failoverFs()
+	if failoverF == nil || Same(f, failoverF) {
+		return false
+	}
+	fs.Logf(src, "Failed to copy to %v - copying to --failover-dest %v instead: %v", f, failoverF, err)
+	dst, _ := failoverF.NewObject(remote)
+	_, failoverErr := copyWithStats(group, failoverF, dst, remote, src)
+	if failoverErr != nil {
+		return false
+	}
+	accounting.RecordFailover(src, "copy", retries, err, failoverF.Name()+":"+failoverF.Root())
+	return true
+}
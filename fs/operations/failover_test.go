@@ -0,0 +1,54 @@
+package operations_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyFailover(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sub/file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	dir, err := ioutil.TempDir("", "rclone-failover")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	// a destination which can't be written to as it is below a file
+	blocker := filepath.Join(dir, "blocker")
+	require.NoError(t, ioutil.WriteFile(blocker, []byte("blocker"), 0600))
+	fdst, err := fs.NewFs(filepath.Join(blocker, "dst"))
+	require.NoError(t, err)
+
+	oldFailoverDest, oldLowLevelRetries := fs.Config.FailoverDest, fs.Config.LowLevelRetries
+	defer func() {
+		fs.Config.FailoverDest, fs.Config.LowLevelRetries = oldFailoverDest, oldLowLevelRetries
+	}()
+	fs.Config.LowLevelRetries = 1
+	src, err := r.Flocal.NewObject(file1.Path)
+	require.NoError(t, err)
+
+	// fails without a --failover-dest
+	_, err = operations.Copy(fdst, nil, file1.Path, src)
+	assert.Error(t, err)
+
+	// copied to the --failover-dest instead
+	fs.Config.FailoverDest = filepath.Join(dir, "failover")
+	newDst, err := operations.Copy(fdst, nil, file1.Path, src)
+	assert.Equal(t, operations.ErrorCopiedToFailover, err)
+	assert.Nil(t, newDst)
+	ffailover, err := fs.NewFs(fs.Config.FailoverDest)
+	require.NoError(t, err)
+	fstest.CheckItems(t, ffailover, file1)
+}
@@ -0,0 +1,60 @@
+package operations
+
+import (
+	"time"
+
+	"github.com/ncw/rclone/fs"
+)
+
+// FsInfo describes the capabilities of a remote as shown by
+// "rclone backend features" and the operations/fsinfo rc call
+type FsInfo struct {
+	// Name of the remote (as passed into NewFs)
+	Name string
+
+	// Root of the remote (as passed into NewFs)
+	Root string
+
+	// String returns a description of the FS
+	String string
+
+	// Precision of the ModTimes in this Fs in Nanoseconds
+	Precision time.Duration
+
+	// Returns the supported hash types of the filesystem
+	Hashes []string
+
+	// Returns the wrapped hash types if the filesystem is an overlay
+	WrappedHashes []string
+
+	// Encoding describes the characters replaced in the names of
+	// the files stored on the remote
+	Encoding []string
+
+	// Features returns the optional features of this Fs
+	Features map[string]bool
+}
+
+// GetFsInfo gets the information (FsInfo) about a given Fs
+func GetFsInfo(f fs.Fs) *FsInfo {
+	info := &FsInfo{
+		Name:          f.Name(),
+		Root:          f.Root(),
+		String:        f.String(),
+		Precision:     f.Precision(),
+		Hashes:        make([]string, 0, 4),
+		WrappedHashes: make([]string, 0, 4),
+		Encoding:      []string{},
+		Features:      f.Features().Enabled(),
+	}
+	for _, hashType := range f.Hashes().Array() {
+		info.Hashes = append(info.Hashes, hashType.String())
+	}
+	for _, hashType := range fs.WrappedHashes(f).Array() {
+		info.WrappedHashes = append(info.WrappedHashes, hashType.String())
+	}
+	for _, pair := range fs.Encoding(f).Mapping() {
+		info.Encoding = append(info.Encoding, pair.String())
+	}
+	return info
+}
@@ -0,0 +1,81 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/lib/atexit"
+)
+
+var (
+	interactiveMu sync.Mutex
+	skipped       = map[string]bool{} // answers given for all operations of a kind
+)
+
+// SkipDestructive should be called whenever rclone is about to do a
+// destructive operation.
+//
+// It will check the --dry-run flag and it will ask the user if the
+// --interactive flag is set.
+//
+// subject should be the object or directory in use and action should
+// be a short phrase such that "rclone is about to action subject"
+// makes sense.
+func SkipDestructive(subject interface{}, action string) (skip bool) {
+	var flag string
+	switch {
+	case fs.Config.DryRun:
+		flag = "--dry-run"
+		skip = true
+	case fs.Config.Interactive:
+		flag = "--interactive"
+		interactiveMu.Lock()
+		defer interactiveMu.Unlock()
+		var found bool
+		skip, found = skipped[action]
+		if !found {
+			skip = skipDestructiveChoose(subject, action)
+		}
+	default:
+		return false
+	}
+	if skip {
+		fs.Logf(subject, "Skipped %s as %s is set", action, flag)
+	}
+	return skip
+}
+
+// skipDestructiveChoose asks the user whether to do action on subject
+//
+// Call with interactiveMu held
+func skipDestructiveChoose(subject interface{}, action string) (skip bool) {
+	fmt.Printf("rclone: %s \"%v\"?\n", action, subject)
+	switch config.Command([]string{
+		"yYes, this is OK",
+		"nNo, skip this",
+		fmt.Sprintf("sSkip all %s operations with no more questions", action),
+		fmt.Sprintf("!Do all %s operations with no more questions", action),
+		"qExit rclone now.",
+	}) {
+	case 'y':
+		skip = false
+	case 'n':
+		skip = true
+	case 's':
+		skip = true
+		skipped[action] = true
+		fs.Logf(nil, "Skipping all %s operations from now on without asking", action)
+	case '!':
+		skip = false
+		skipped[action] = false
+		fs.Logf(nil, "Doing all %s operations from now on without asking", action)
+	case 'q':
+		fs.Logf(nil, "Quitting rclone now")
+		atexit.Run()
+		os.Exit(0)
+	}
+	return skip
+}
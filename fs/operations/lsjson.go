@@ -12,16 +12,18 @@ import (
 
 // ListJSONItem in the struct which gets marshalled for each line
 type ListJSONItem struct {
-	Path      string
-	Name      string
-	Encrypted string `json:",omitempty"`
-	Size      int64
-	MimeType  string    `json:",omitempty"`
-	ModTime   Timestamp //`json:",omitempty"`
-	IsDir     bool
-	Hashes    map[string]string `json:",omitempty"`
-	ID        string            `json:",omitempty"`
-	OrigID    string            `json:",omitempty"`
+	Path           string
+	Name           string
+	Encrypted      string `json:",omitempty"`
+	Size           int64
+	UnderlyingPath string    `json:",omitempty"`
+	UnderlyingSize *int64    `json:",omitempty"`
+	MimeType       string    `json:",omitempty"`
+	ModTime        Timestamp //`json:",omitempty"`
+	IsDir          bool
+	Hashes         map[string]string `json:",omitempty"`
+	ID             string            `json:",omitempty"`
+	OrigID         string            `json:",omitempty"`
 }
 
 // Timestamp a time in the provided format
@@ -65,11 +67,12 @@ func formatForPrecision(precision time.Duration) string {
 
 // ListJSONOpt describes the options for ListJSON
 type ListJSONOpt struct {
-	Recurse       bool `json:"recurse"`
-	NoModTime     bool `json:"noModTime"`
-	ShowEncrypted bool `json:"showEncrypted"`
-	ShowOrigIDs   bool `json:"showOrigIDs"`
-	ShowHash      bool `json:"showHash"`
+	Recurse        bool `json:"recurse"`
+	NoModTime      bool `json:"noModTime"`
+	ShowEncrypted  bool `json:"showEncrypted"`
+	ShowOrigIDs    bool `json:"showOrigIDs"`
+	ShowUnderlying bool `json:"showUnderlying"`
+	ShowHash       bool `json:"showHash"`
 }
 
 // ListJSON lists fsrc using the options in opt calling callback for each item
@@ -135,6 +138,22 @@ func ListJSON(fsrc fs.Fs, remote string, opt *ListJSONOpt, callback func(*ListJS
 					item.OrigID = do.ID()
 				}
 			}
+			if opt.ShowUnderlying {
+				switch x := entry.(type) {
+				case fs.Directory:
+					// directories aren't wrapped so this is only
+					// known for crypt with --encrypted
+					if cipher != nil {
+						item.UnderlyingPath = cipher.EncryptDirName(x.Remote())
+					}
+				case fs.Object:
+					if u := fs.UnWrapObject(x); u != x {
+						size := u.Size()
+						item.UnderlyingPath = u.Remote()
+						item.UnderlyingSize = &size
+					}
+				}
+			}
 			switch x := entry.(type) {
 			case fs.Directory:
 				item.IsDir = true
@@ -3,11 +3,11 @@ package operations
 
 import (
 	"bytes"
-	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"path"
 	"sort"
@@ -21,6 +21,7 @@ import (
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/listcache"
 	"github.com/ncw/rclone/fs/march"
 	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/walk"
@@ -42,7 +43,15 @@ import (
 // err - may return an error which will already have been logged
 //
 // If an error is returned it will return equal as false
+//
+// If --compare is set the hashes are compared as described in
+// checkCompare.
 func CheckHashes(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, err error) {
+	if fs.Config.Compare != "" {
+		if equal, ht, ok, err := checkCompare(src, dst); ok {
+			return equal, ht, err
+		}
+	}
 	common := src.Fs().Hashes().Overlap(dst.Fs().Hashes())
 	// fs.Debugf(nil, "Shared hashes: %v", common)
 	if common.Count() == 0 {
@@ -58,7 +67,13 @@ func CheckHashes(src fs.ObjectInfo, dst fs.Object) (equal bool, ht hash.Type, er
 	if srcHash == "" {
 		return true, hash.None, nil
 	}
-	dstHash, err := dst.Hash(ht)
+	return checkDstHash(src, dst, ht, srcHash)
+}
+
+// checkDstHash compares srcHash of type ht with the hash of dst as
+// CheckHashes does.  ht may be a wrapped hash type.
+func checkDstHash(src fs.ObjectInfo, dst fs.Object, ht hash.Type, srcHash string) (bool, hash.Type, error) {
+	dstHash, err := fs.WrappedHash(dst, ht)
 	if err != nil {
 		fs.CountError(err)
 		fs.Errorf(dst, "Failed to calculate dst hash: %v", err)
@@ -167,9 +182,7 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 
 	// mod time differs but hash is the same to reset mod time if required
 	if !fs.Config.NoUpdateModTime {
-		if fs.Config.DryRun {
-			fs.Logf(src, "Not updating modification time as --dry-run")
-		} else {
+		if !SkipDestructive(src, "update modification time") {
 			// Size and hash the same but mtime different
 			// Error if objects are treated as immutable
 			if fs.Config.Immutable {
@@ -178,6 +191,13 @@ func equal(src fs.ObjectInfo, dst fs.Object, sizeOnly, checkSum bool) bool {
 			}
 			// Update the mtime of the dst object here
 			err := dst.SetModTime(srcModTime)
+			listcache.InvalidateParent(dst.Fs(), dst.Remote())
+			if (err == fs.ErrorCantSetModTime || err == fs.ErrorCantSetModTimeWithoutDelete) && !fs.Config.RefreshTimes {
+				// Treat the files as identical otherwise they
+				// would be re-uploaded on every run
+				fs.Debugf(dst, "src and dst identical but can't set mod time without re-uploading - use --refresh-times to re-upload")
+				return true
+			}
 			if err == fs.ErrorCantSetModTime {
 				fs.Debugf(dst, "src and dst identical but can't set mod time without re-uploading")
 				return false
@@ -248,13 +268,32 @@ var _ fs.MimeTyper = (*overrideRemoteObject)(nil)
 // It returns the destination object if possible.  Note that this may
 // be nil.
 func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
-	newDst = dst
-	if fs.Config.DryRun {
+	return CopyWithStats(nil, f, dst, remote, src)
+}
+
+// CopyWithStats is like Copy but also accounts the transfer to the
+// stats group passed in if it isn't nil.
+func CopyWithStats(group *accounting.StatsInfo, f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+	if dst != nil {
+		if SkipDestructive(dst, "overwrite") {
+			return dst, nil
+		}
+	} else if fs.Config.DryRun {
 		fs.Logf(src, "Not copying as --dry-run")
-		return newDst, nil
+		return nil, nil
 	}
+	return copyWithStats(group, f, dst, remote, src)
+}
+
+// copyWithStats does the work of CopyWithStats without checking
+// --dry-run or --interactive
+func copyWithStats(group *accounting.StatsInfo, f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+	if err := accounting.WaitIfPaused(accounting.ShutdownContext()); err != nil {
+		return dst, err
+	}
+	defer listcache.InvalidateParent(f, remote)
+	newDst = dst
 	maxTries := fs.Config.LowLevelRetries
-	tries := 0
 	doUpdate := dst != nil
 	// work out which hash to use - limit to 1 hash in common
 	var common hash.Set
@@ -268,110 +307,194 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 	}
 	hashOption := &fs.HashesOption{Hashes: common}
 	var actionTaken string
+	verifyTries := 0
 	for {
-		// Try server side copy first - if has optional interface and
-		// is same underlying remote
-		actionTaken = "Copied (server side copy)"
-		if doCopy := f.Features().Copy; doCopy != nil && SameConfig(src.Fs(), f) {
-			newDst, err = doCopy(src, remote)
-			if err == nil {
-				dst = newDst
-			}
-		} else {
-			err = fs.ErrorCantCopy
-		}
-		// If can't server side copy, do it manually
-		if err == fs.ErrorCantCopy {
-			var in0 io.ReadCloser
-			in0, err = newReOpen(src, hashOption, fs.Config.LowLevelRetries)
-			if err != nil {
-				err = errors.Wrap(err, "failed to open source object")
-			} else {
-				if src.Size() == -1 {
-					// -1 indicates unknown size. Use Rcat to handle both remotes supporting and not supporting PutStream.
-					if doUpdate {
-						actionTaken = "Copied (Rcat, replaced existing)"
-					} else {
-						actionTaken = "Copied (Rcat, new)"
+		tries := 0
+		for {
+			// Try server side copy first - if has optional interface and
+			// is same underlying remote
+			actionTaken = "Copied (server side copy)"
+			if doCopy := f.Features().Copy; doCopy != nil && CanServerSide(f, src.Fs()) {
+				accounting.CountRequest(f)
+				newDst, err = doCopy(listcache.UnWrap(src), remote)
+				if err == nil {
+					dst = newDst
+					accounting.Stats.ServerSideCopy(src.Size())
+					if group != nil {
+						group.ServerSideCopy(src.Size())
 					}
-					dst, err = Rcat(f, remote, in0, src.ModTime())
-					newDst = dst
+				}
+			} else {
+				err = fs.ErrorCantCopy
+			}
+			// If can't server side copy, do it manually
+			if err == fs.ErrorCantCopy {
+				var in0 io.ReadCloser
+				in0, err = newReOpen(src, hashOption, fs.Config.LowLevelRetries)
+				if err != nil {
+					err = errors.Wrap(err, "failed to open source object")
 				} else {
-					in := accounting.NewAccount(in0, src).WithBuffer() // account and buffer the transfer
-					var wrappedSrc fs.ObjectInfo = src
-					// We try to pass the original object if possible
-					if src.Remote() != remote {
-						wrappedSrc = &overrideRemoteObject{Object: src, remote: remote}
-					}
-					if doUpdate {
-						actionTaken = "Copied (replaced existing)"
-						err = dst.Update(in, wrappedSrc, hashOption)
-					} else {
-						actionTaken = "Copied (new)"
-						dst, err = f.Put(in, wrappedSrc, hashOption)
-					}
-					closeErr := in.Close()
-					if err == nil {
+					if src.Size() == -1 {
+						// -1 indicates unknown size. Use Rcat to handle both remotes supporting and not supporting PutStream.
+						if doUpdate {
+							actionTaken = "Copied (Rcat, replaced existing)"
+						} else {
+							actionTaken = "Copied (Rcat, new)"
+						}
+						dst, err = Rcat(f, remote, in0, src.ModTime())
 						newDst = dst
-						err = closeErr
+					} else {
+						in := accounting.NewAccount(in0, src).WithRemoteLimit(f).WithUpload(f).WithBuffer().WithGroup(group) // account and buffer the transfer
+						partial := usePartial(f)
+						uploadRemote, finalRemote := remote, remote
+						if doUpdate {
+							// keep the name of dst which may differ in case or normalization
+							finalRemote = dst.Remote()
+						}
+						if partial {
+							uploadRemote = PartialName(f, finalRemote)
+						}
+						var wrappedSrc fs.ObjectInfo = src
+						// We try to pass the original object if possible
+						if src.Remote() != uploadRemote {
+							wrappedSrc = &overrideRemoteObject{Object: src, remote: uploadRemote}
+						}
+						var partialDst fs.Object
+						switch {
+						case partial:
+							if doUpdate {
+								actionTaken = "Copied (replaced existing)"
+							} else {
+								actionTaken = "Copied (new)"
+							}
+							partialDst, err = f.Put(in, wrappedSrc, hashOption)
+						case doUpdate:
+							actionTaken = "Copied (replaced existing)"
+							err = dst.Update(in, wrappedSrc, hashOption)
+						default:
+							actionTaken = "Copied (new)"
+							dst, err = f.Put(in, wrappedSrc, hashOption)
+						}
+						closeErr := in.Close()
+						if err == nil {
+							err = closeErr
+						}
+						if partial {
+							switch {
+							case err == nil && partialDst != nil:
+								dst, err = finishPartial(f, partialDst, dst, finalRemote)
+							case partialDst != nil:
+								removeFailedCopy(partialDst)
+								fallthrough
+							default:
+								removePartialDir(f, uploadRemote, finalRemote)
+							}
+						}
+						if err == nil {
+							newDst = dst
+						}
 					}
 				}
 			}
+			tries++
+			if tries >= maxTries || accounting.ShuttingDown() {
+				break
+			}
+			// Retry if err returned a retry error
+			if fserrors.IsRetryError(err) || fserrors.ShouldRetry(err) {
+				fs.Debugf(src, "Received error: %v - low level retry %d/%d", err, tries, maxTries)
+				continue
+			}
+			// otherwise finish
+			break
+		}
+		if err != nil {
+			if copyToFailover(group, f, remote, src, tries-1, err) {
+				fs.CountError(ErrorCopiedToFailover)
+				return nil, ErrorCopiedToFailover
+			}
+			fs.CountError(err)
+			fs.Errorf(src, "Failed to copy: %v", err)
+			accounting.RecordError(src, "copy", tries-1, err)
+			return newDst, err
 		}
-		tries++
-		if tries >= maxTries {
+
+		var verified, corrupted bool
+		verified, corrupted, err = checkTransfer(src, dst, hashType)
+		if !corrupted {
+			if verified {
+				accounting.Stats.Verified()
+				if group != nil {
+					group.Verified()
+				}
+			}
 			break
 		}
-		// Retry if err returned a retry error
-		if fserrors.IsRetryError(err) || fserrors.ShouldRetry(err) {
-			fs.Debugf(src, "Received error: %v - low level retry %d/%d", err, tries, maxTries)
-			continue
+		removeFailedCopy(dst)
+		verifyTries++
+		if !fs.Config.VerifyTransfers || verifyTries >= maxTries {
+			if copyToFailover(group, f, remote, src, verifyTries-1, err) {
+				fs.CountError(ErrorCopiedToFailover)
+				return nil, ErrorCopiedToFailover
+			}
+			fs.CountError(err)
+			accounting.RecordError(src, "copy", verifyTries-1, err)
+			return newDst, err
 		}
-		// otherwise finish
-		break
-	}
-	if err != nil {
-		fs.CountError(err)
-		fs.Errorf(src, "Failed to copy: %v", err)
-		return newDst, err
+		fs.Logf(src, "Retrying corrupted transfer %d/%d", verifyTries, maxTries)
+		doUpdate = false
+		dst, newDst = nil, nil
 	}
 
+	fs.Infof(src, actionTaken)
+	return newDst, err
+}
+
+// checkTransfer checks that dst matches src after a transfer by
+// comparing their sizes and hashes of hashType, ignoring blank hashes.
+//
+// verified is set if the hashes were compared and matched.
+// corrupted is set if dst differs from src, in which case err will
+// describe the problem.  Errors reading the hashes are counted and
+// returned in err but don't mark the transfer as corrupted.
+func checkTransfer(src fs.ObjectInfo, dst fs.Object, hashType hash.Type) (verified, corrupted bool, err error) {
 	// Verify sizes are the same after transfer
 	if sizeDiffers(src, dst) {
 		err = errors.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), dst.Size())
 		fs.Errorf(dst, "%v", err)
-		fs.CountError(err)
-		removeFailedCopy(dst)
-		return newDst, err
+		return false, true, err
 	}
 
 	// Verify hashes are the same after transfer - ignoring blank hashes
 	// TODO(klauspost): This could be extended, so we always create a hash type matching
 	// the destination, and calculate it while sending.
-	if hashType != hash.None {
-		var srcSum string
-		srcSum, err = src.Hash(hashType)
-		if err != nil {
-			fs.CountError(err)
-			fs.Errorf(src, "Failed to read src hash: %v", err)
-		} else if srcSum != "" {
-			var dstSum string
-			dstSum, err = dst.Hash(hashType)
-			if err != nil {
-				fs.CountError(err)
-				fs.Errorf(dst, "Failed to read hash: %v", err)
-			} else if !fs.Config.IgnoreChecksum && !hash.Equals(srcSum, dstSum) {
-				err = errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", hashType, srcSum, dstSum)
-				fs.Errorf(dst, "%v", err)
-				fs.CountError(err)
-				removeFailedCopy(dst)
-				return newDst, err
-			}
-		}
+	if hashType == hash.None {
+		return false, false, nil
 	}
-
-	fs.Infof(src, actionTaken)
-	return newDst, err
+	srcSum, err := src.Hash(hashType)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(src, "Failed to read src hash: %v", err)
+		return false, false, err
+	}
+	if srcSum == "" {
+		return false, false, nil
+	}
+	dstSum, err := dst.Hash(hashType)
+	if err != nil {
+		fs.CountError(err)
+		fs.Errorf(dst, "Failed to read hash: %v", err)
+		return false, false, err
+	}
+	if dstSum == "" || fs.Config.IgnoreChecksum {
+		return false, false, nil
+	}
+	if !hash.Equals(srcSum, dstSum) {
+		err = errors.Errorf("corrupted on transfer: %v hash differ %q vs %q", hashType, srcSum, dstSum)
+		fs.Errorf(dst, "%v", err)
+		return false, true, err
+	}
+	return true, false, nil
 }
 
 // Move src object to dst or fdst if nil.  If dst is nil then it uses
@@ -385,24 +508,45 @@ func Copy(f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Objec
 // It returns the destination object if possible.  Note that this may
 // be nil.
 func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
-	newDst = dst
-	if fs.Config.DryRun {
-		fs.Logf(src, "Not moving as --dry-run")
-		return newDst, nil
+	return MoveWithStats(nil, fdst, dst, remote, src)
+}
+
+// MoveWithStats is like Move but also accounts the transfer to the
+// stats group passed in if it isn't nil.
+func MoveWithStats(group *accounting.StatsInfo, fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+	if SkipDestructive(src, "move") {
+		return dst, nil
 	}
+	return moveWithStats(group, fdst, dst, remote, src)
+}
+
+// moveWithStats does the work of MoveWithStats without checking
+// --dry-run or --interactive
+func moveWithStats(group *accounting.StatsInfo, fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
+	if err := accounting.WaitIfPaused(accounting.ShutdownContext()); err != nil {
+		return dst, err
+	}
+	defer listcache.InvalidateParent(fdst, remote)
+	defer listcache.InvalidateParent(src.Fs(), src.Remote())
+	newDst = dst
 	// See if we have Move available
-	if doMove := fdst.Features().Move; doMove != nil && SameConfig(src.Fs(), fdst) {
+	if doMove := fdst.Features().Move; doMove != nil && CanServerSide(fdst, src.Fs()) {
 		// Delete destination if it exists
 		if dst != nil {
-			err = DeleteFile(dst)
+			err = deleteFileWithBackupDir(dst, nil, false)
 			if err != nil {
 				return newDst, err
 			}
 		}
 		// Move dst <- src
-		newDst, err = doMove(src, remote)
+		accounting.CountRequest(fdst)
+		newDst, err = doMove(listcache.UnWrap(src), remote)
 		switch err {
 		case nil:
+			accounting.Stats.ServerSideMove(src.Size())
+			if group != nil {
+				group.ServerSideMove(src.Size())
+			}
 			fs.Infof(src, "Moved (server side)")
 			return newDst, nil
 		case fs.ErrorCantMove:
@@ -410,17 +554,18 @@ func Move(fdst fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Ob
 		default:
 			fs.CountError(err)
 			fs.Errorf(src, "Couldn't move: %v", err)
+			accounting.RecordError(src, "move", 0, err)
 			return newDst, err
 		}
 	}
 	// Move not found or didn't work so copy dst <- src
-	newDst, err = Copy(fdst, dst, remote, src)
+	newDst, err = copyWithStats(group, fdst, dst, remote, src)
 	if err != nil {
 		fs.Errorf(src, "Not deleting source as copy failed: %v", err)
 		return newDst, err
 	}
 	// Delete src if no error on copy
-	return newDst, DeleteFile(src)
+	return newDst, deleteFileWithBackupDir(src, nil, false)
 }
 
 // CanServerSideMove returns true if fdst support server side moves or
@@ -440,32 +585,46 @@ func CanServerSideMove(fdst fs.Fs) bool {
 // If backupDir is set then it moves the file to there instead of
 // deleting
 func DeleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs) (err error) {
+	return deleteFileWithBackupDir(dst, backupDir, true)
+}
+
+// deleteFileWithBackupDir does the work of DeleteFileWithBackupDir
+// only checking --dry-run and --interactive if check is set
+func deleteFileWithBackupDir(dst fs.Object, backupDir fs.Fs, check bool) (err error) {
+	defer listcache.InvalidateParent(dst.Fs(), dst.Remote())
 	accounting.Stats.Checking(dst.Remote())
 	numDeletes := accounting.Stats.Deletes(1)
 	if fs.Config.MaxDelete != -1 && numDeletes > fs.Config.MaxDelete {
 		return fserrors.FatalError(errors.New("--max-delete threshold reached"))
 	}
-	action, actioned, actioning := "delete", "Deleted", "deleting"
+	deletesSize := accounting.Stats.DeletesSize(dst.Size())
+	if fs.Config.MaxDeleteSize >= 0 && deletesSize > int64(fs.Config.MaxDeleteSize) {
+		return fserrors.FatalError(errors.New("--max-delete-size threshold reached"))
+	}
+	action, actioned := "delete", "Deleted"
 	if backupDir != nil {
-		action, actioned, actioning = "move into backup dir", "Moved into backup dir", "moving into backup dir"
+		action, actioned = "move into backup dir", "Moved into backup dir"
 	}
-	if fs.Config.DryRun {
-		fs.Logf(dst, "Not %s as --dry-run", actioning)
+	skip := check && SkipDestructive(dst, action)
+	if skip {
+		// do nothing
 	} else if backupDir != nil {
 		if !SameConfig(dst.Fs(), backupDir) {
 			err = errors.New("parameter to --backup-dir has to be on the same remote as destination")
 		} else {
 			remoteWithSuffix := dst.Remote() + fs.Config.Suffix
 			overwritten, _ := backupDir.NewObject(remoteWithSuffix)
-			_, err = Move(backupDir, overwritten, remoteWithSuffix, dst)
+			_, err = moveWithStats(nil, backupDir, overwritten, remoteWithSuffix, dst)
 		}
 	} else {
+		accounting.CountRequest(dst.Fs())
 		err = dst.Remove()
 	}
 	if err != nil {
 		fs.CountError(err)
 		fs.Errorf(dst, "Couldn't %s: %v", action, err)
-	} else if !fs.Config.DryRun {
+		accounting.RecordError(dst, action, 0, err)
+	} else if !skip {
 		fs.Infof(dst, actioned)
 	}
 	accounting.Stats.DoneChecking(dst.Remote())
@@ -486,6 +645,13 @@ func DeleteFile(dst fs.Object) (err error) {
 // If backupDir is set the files will be placed into that directory
 // instead of being deleted.
 func DeleteFilesWithBackupDir(toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error {
+	return DeleteFilesWithCallback(toBeDeleted, backupDir, nil)
+}
+
+// DeleteFilesWithCallback is like DeleteFilesWithBackupDir but also
+// calls deleted, if it isn't nil, with each file and the error
+// deleting it.  deleted may be called concurrently.
+func DeleteFilesWithCallback(toBeDeleted fs.ObjectsChan, backupDir fs.Fs, deleted func(dst fs.Object, err error)) error {
 	var wg sync.WaitGroup
 	wg.Add(fs.Config.Transfers)
 	var errorCount int32
@@ -496,6 +662,9 @@ func DeleteFilesWithBackupDir(toBeDeleted fs.ObjectsChan, backupDir fs.Fs) error
 			defer wg.Done()
 			for dst := range toBeDeleted {
 				err := DeleteFileWithBackupDir(dst, backupDir)
+				if deleted != nil {
+					deleted(dst, err)
+				}
 				if err != nil {
 					atomic.AddInt32(&errorCount, 1)
 					if fserrors.IsFatalError(err) {
@@ -530,6 +699,25 @@ func SameConfig(fdst, fsrc fs.Info) bool {
 	return fdst.Name() == fsrc.Name()
 }
 
+// SameRemoteType returns true if fdst and fsrc are the same type of
+// remote
+func SameRemoteType(fdst, fsrc fs.Info) bool {
+	return fmt.Sprintf("%T", fdst) == fmt.Sprintf("%T", fsrc)
+}
+
+// CanServerSide returns true if objects on fsrc may be copied or
+// moved to fdst with server side operations.
+//
+// They must use the same config file entry unless
+// --server-side-across-configs is set and they are the same type of
+// remote which supports it.
+func CanServerSide(fdst, fsrc fs.Info) bool {
+	if SameConfig(fdst, fsrc) {
+		return true
+	}
+	return fs.Config.ServerSideAcrossConfigs && fdst.Features().ServerSideAcrossConfigs && SameRemoteType(fdst, fsrc)
+}
+
 // Same returns true if fdst and fsrc point to the same underlying Fs
 func Same(fdst, fsrc fs.Info) bool {
 	return SameConfig(fdst, fsrc) && fdst.Root() == fsrc.Root()
@@ -590,6 +778,8 @@ type checkMarch struct {
 	srcFilesMissing int32
 	dstFilesMissing int32
 	matches         int32
+	tokens          chan struct{}  // limits the number of checks running at once
+	wg              sync.WaitGroup // for the checks running in the background
 }
 
 // DstOnly have an object which is in the destination only
@@ -647,22 +837,37 @@ func (c *checkMarch) checkIdentical(dst, src fs.Object) (differ bool, noHash boo
 	return c.check(dst, src)
 }
 
+// checkPair checks dst and src are identical and records the result
+func (c *checkMarch) checkPair(dst, src fs.Object) {
+	differ, noHash := c.checkIdentical(dst, src)
+	if differ {
+		atomic.AddInt32(&c.differences, 1)
+	} else {
+		atomic.AddInt32(&c.matches, 1)
+		fs.Debugf(dst, "OK")
+	}
+	if noHash {
+		atomic.AddInt32(&c.noHashes, 1)
+	}
+}
+
 // Match is called when src and dst are present, so sync src to dst
 func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 	switch srcX := src.(type) {
 	case fs.Object:
 		dstX, ok := dst.(fs.Object)
 		if ok {
-			differ, noHash := c.checkIdentical(dstX, srcX)
-			if differ {
-				atomic.AddInt32(&c.differences, 1)
-			} else {
-				atomic.AddInt32(&c.matches, 1)
-				fs.Debugf(dstX, "OK")
-			}
-			if noHash {
-				atomic.AddInt32(&c.noHashes, 1)
-			}
+			// check the pair in the background so the files in a
+			// directory are checked in parallel
+			c.tokens <- struct{}{}
+			c.wg.Add(1)
+			go func() {
+				defer func() {
+					<-c.tokens
+					c.wg.Done()
+				}()
+				c.checkPair(dstX, srcX)
+			}()
 		} else {
 			err := errors.Errorf("is file on %v but directory on %v", c.fsrc, c.fdst)
 			fs.Errorf(src, "%v", err)
@@ -695,17 +900,28 @@ func (c *checkMarch) Match(dst, src fs.DirEntry) (recurse bool) {
 //
 // it returns true if differences were found
 // it also returns whether it couldn't be hashed
+//
+// Up to --checkers pairs of files are checked at once.
 func CheckFn(fdst, fsrc fs.Fs, check checkFn, oneway bool) error {
+	return checkWithCheckers(fdst, fsrc, check, oneway, fs.Config.Checkers)
+}
+
+// checkWithCheckers is CheckFn running at most checkers checks at once
+func checkWithCheckers(fdst, fsrc fs.Fs, check checkFn, oneway bool, checkers int) error {
+	if checkers < 1 {
+		checkers = 1
+	}
 	c := &checkMarch{
 		fdst:   fdst,
 		fsrc:   fsrc,
 		check:  check,
 		oneway: oneway,
+		tokens: make(chan struct{}, checkers),
 	}
 
 	// set up a march over fdst and fsrc
 	m := &march.March{
-		Ctx:      context.Background(),
+		Ctx:      accounting.ShutdownContext(),
 		Fdst:     fdst,
 		Fsrc:     fsrc,
 		Dir:      "",
@@ -713,6 +929,7 @@ func CheckFn(fdst, fsrc fs.Fs, check checkFn, oneway bool) error {
 	}
 	fs.Infof(fdst, "Waiting for checks to finish")
 	m.Run()
+	c.wg.Wait()
 
 	if c.dstFilesMissing > 0 {
 		fs.Logf(fdst, "%d files missing", c.dstFilesMissing)
@@ -774,28 +991,58 @@ func CheckEqualReaders(in1, in2 io.Reader) (differ bool, err error) {
 //
 // it returns true if differences were found
 func CheckIdentical(dst, src fs.Object) (differ bool, err error) {
+	return checkIdenticalBuffered(dst, src, 2*int64(fs.Config.BufferSize))
+}
+
+// checkIdenticalBuffered is CheckIdentical using at most bufSize
+// bytes of memory to buffer both files.
+func checkIdenticalBuffered(dst, src fs.Object, bufSize int64) (differ bool, err error) {
 	in1, err := dst.Open()
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", dst)
 	}
-	in1 = accounting.NewAccount(in1, dst).WithBuffer() // account and buffer the transfer
+	in1 = accounting.NewAccount(in1, dst).WithBufferSize(bufSize / 2) // account and buffer the transfer
 	defer fs.CheckClose(in1, &err)
 
 	in2, err := src.Open()
 	if err != nil {
 		return true, errors.Wrapf(err, "failed to open %q", src)
 	}
-	in2 = accounting.NewAccount(in2, src).WithBuffer() // account and buffer the transfer
+	in2 = accounting.NewAccount(in2, src).WithBufferSize(bufSize / 2) // account and buffer the transfer
 	defer fs.CheckClose(in2, &err)
 
 	return CheckEqualReaders(in1, in2)
 }
 
+// CheckDownloadOpt controls how CheckDownloadWithOpt reads the files
+type CheckDownloadOpt struct {
+	Checkers   int           // number of pairs of files to download at once - 0 for --checkers
+	BufferSize fs.SizeSuffix // memory to buffer each pair of files in - 0 for twice --buffer-size
+}
+
 // CheckDownload checks the files in fsrc and fdst according to Size
 // and the actual contents of the files.
 func CheckDownload(fdst, fsrc fs.Fs, oneway bool) error {
+	return CheckDownloadWithOpt(fdst, fsrc, oneway, CheckDownloadOpt{})
+}
+
+// CheckDownloadWithOpt checks the files in fsrc and fdst according to
+// Size and the actual contents of the files.
+//
+// Both files of each pair are streamed and compared as they are
+// read, so at most opt.Checkers pairs are read at once each using
+// opt.BufferSize of memory for buffering.
+func CheckDownloadWithOpt(fdst, fsrc fs.Fs, oneway bool, opt CheckDownloadOpt) error {
+	checkers := opt.Checkers
+	if checkers <= 0 {
+		checkers = fs.Config.Checkers
+	}
+	bufSize := int64(opt.BufferSize)
+	if bufSize <= 0 {
+		bufSize = 2 * int64(fs.Config.BufferSize)
+	}
 	check := func(a, b fs.Object) (differ bool, noHash bool) {
-		differ, err := CheckIdentical(a, b)
+		differ, err := checkIdenticalBuffered(a, b, bufSize)
 		if err != nil {
 			fs.CountError(err)
 			fs.Errorf(a, "Failed to download: %v", err)
@@ -803,7 +1050,7 @@ func CheckDownload(fdst, fsrc fs.Fs, oneway bool) error {
 		}
 		return differ, false
 	}
-	return CheckFn(fdst, fsrc, check, oneway)
+	return checkWithCheckers(fdst, fsrc, check, oneway, checkers)
 }
 
 // ListFn lists the Fs to the supplied function
@@ -952,6 +1199,33 @@ func Mkdir(f fs.Fs, dir string) error {
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Making directory")
 	err := f.Mkdir(dir)
+	listcache.InvalidateParent(f, dir)
+	if err != nil {
+		fs.CountError(err)
+		return err
+	}
+	return nil
+}
+
+// SetDirModTime sets the modification time of the directory dir in f
+// to modTime if f supports it.
+//
+// It returns fs.ErrorDirNotFound if the directory doesn't exist
+// which isn't counted as an error.
+func SetDirModTime(f fs.Fs, dir string, modTime time.Time) error {
+	do := f.Features().DirSetModTime
+	if do == nil || fs.Config.NoUpdateModTime {
+		return nil
+	}
+	if fs.Config.DryRun {
+		fs.Logf(fs.LogDirName(f, dir), "Not setting directory modification time as dry run is set")
+		return nil
+	}
+	fs.Debugf(fs.LogDirName(f, dir), "Setting directory modification time to %v", modTime)
+	err := do(dir, modTime)
+	if err == fs.ErrorDirNotFound {
+		return err
+	}
 	if err != nil {
 		fs.CountError(err)
 		return err
@@ -962,11 +1236,12 @@ func Mkdir(f fs.Fs, dir string) error {
 // TryRmdir removes a container but not if not empty.  It doesn't
 // count errors but may return one.
 func TryRmdir(f fs.Fs, dir string) error {
-	if fs.Config.DryRun {
-		fs.Logf(fs.LogDirName(f, dir), "Not deleting as dry run is set")
+	if SkipDestructive(fs.LogDirName(f, dir), "remove directory") {
 		return nil
 	}
 	fs.Debugf(fs.LogDirName(f, dir), "Removing directory")
+	defer listcache.InvalidateParent(f, dir)
+	defer listcache.Invalidate(f, dir)
 	return f.Rmdir(dir)
 }
 
@@ -982,15 +1257,14 @@ func Rmdir(f fs.Fs, dir string) error {
 
 // Purge removes a directory and all of its contents
 func Purge(f fs.Fs, dir string) error {
+	defer listcache.InvalidateAll(f)
 	doFallbackPurge := true
 	var err error
 	if dir == "" {
 		// FIXME change the Purge interface so it takes a dir - see #1891
 		if doPurge := f.Features().Purge; doPurge != nil {
 			doFallbackPurge = false
-			if fs.Config.DryRun {
-				fs.Logf(f, "Not purging as --dry-run set")
-			} else {
+			if !SkipDestructive(f, "purge") {
 				err = doPurge()
 				if err == fs.ErrorCantPurge {
 					doFallbackPurge = true
@@ -1067,8 +1341,7 @@ func CleanUp(f fs.Fs) error {
 	if doCleanUp == nil {
 		return errors.Errorf("%v doesn't support cleanup", f)
 	}
-	if fs.Config.DryRun {
-		fs.Logf(f, "Not running cleanup as --dry-run set")
+	if SkipDestructive(f, "clean up old files") {
 		return nil
 	}
 	return doCleanUp()
@@ -1088,18 +1361,24 @@ type readCloser struct {
 //
 // if count < 0 then it will be ignored
 // if count >= 0 then only that many characters will be output
-func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
+//
+// If sep is not empty it is written between the contents of each file.
+func Cat(f fs.Fs, w io.Writer, offset, count int64, sep []byte) error {
 	var mu sync.Mutex
+	first := true
 	return ListFn(f, func(o fs.Object) {
 		var err error
 		accounting.Stats.Transferring(o.Remote())
 		defer func() {
-			accounting.Stats.DoneTransferring(o.Remote(), err == nil)
+			accounting.Stats.DoneTransferringError(o.Remote(), o.Size(), err)
 		}()
 		opt := fs.RangeOption{Start: offset, End: -1}
 		size := o.Size()
 		if opt.Start < 0 {
 			opt.Start += size
+			if opt.Start < 0 {
+				opt.Start = 0
+			}
 		}
 		if count >= 0 {
 			opt.End = opt.Start + count - 1
@@ -1121,7 +1400,7 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 				size = count
 			}
 		}
-		in = accounting.NewAccountSizeName(in, size, o.Remote()).WithBuffer() // account and buffer the transfer
+		in = accounting.NewAccountSizeName(in, size, o.Remote()).WithDownload(o.Fs()).WithBuffer() // account and buffer the transfer
 		defer func() {
 			err = in.Close()
 			if err != nil {
@@ -1132,6 +1411,15 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 		// take the lock just before we output stuff, so at the last possible moment
 		mu.Lock()
 		defer mu.Unlock()
+		if !first && len(sep) > 0 {
+			_, err = w.Write(sep)
+			if err != nil {
+				fs.CountError(err)
+				fs.Errorf(o, "Failed to send separator to output: %v", err)
+				return
+			}
+		}
+		first = false
 		_, err = io.Copy(w, in)
 		if err != nil {
 			fs.CountError(err)
@@ -1143,9 +1431,9 @@ func Cat(f fs.Fs, w io.Writer, offset, count int64) error {
 // Rcat reads data from the Reader until EOF and uploads it to a file on remote
 func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (dst fs.Object, err error) {
 	accounting.Stats.Transferring(dstFileName)
-	in = accounting.NewAccountSizeName(in, -1, dstFileName).WithBuffer()
+	in = accounting.NewAccountSizeName(in, -1, dstFileName).WithRemoteLimit(fdst).WithUpload(fdst).WithBuffer()
 	defer func() {
-		accounting.Stats.DoneTransferring(dstFileName, err == nil)
+		accounting.Stats.DoneTransferringError(dstFileName, -1, err)
 		if otherErr := in.Close(); otherErr != nil {
 			fs.Debugf(fdst, "Rcat: failed to close source: %v", err)
 		}
@@ -1223,12 +1511,15 @@ func Rcat(fdst fs.Fs, dstFileName string, in io.ReadCloser, modTime time.Time) (
 }
 
 // PublicLink adds a "readable by anyone with link" permission on the given file or folder.
-func PublicLink(f fs.Fs, remote string) (string, error) {
+//
+// If expire is set the link will stop working after that long. If
+// unlink is set the public links are removed instead.
+func PublicLink(f fs.Fs, remote string, expire fs.Duration, unlink bool) (string, error) {
 	doPublicLink := f.Features().PublicLink
 	if doPublicLink == nil {
 		return "", errors.Errorf("%v doesn't support public links", f)
 	}
-	return doPublicLink(remote)
+	return doPublicLink(remote, expire, unlink)
 }
 
 // Rmdirs removes any empty directories (or directories only
@@ -1299,19 +1590,33 @@ func Rmdirs(f fs.Fs, dir string, leaveRoot bool) error {
 // Returns a flag which indicates whether the file needs to be
 // transferred or not.
 func NeedTransfer(dst, src fs.Object) bool {
+	transfer, _ := NeedTransferConflict(dst, src)
+	return transfer
+}
+
+// NeedTransferConflict is like NeedTransfer but also returns whether
+// it was ambiguous if src or dst is the version to keep, in which case
+// --conflict-resolve decided.
+func NeedTransferConflict(dst, src fs.Object) (transfer bool, conflict bool) {
 	if dst == nil {
 		fs.Debugf(src, "Couldn't find file - need to transfer")
-		return true
+		return true, false
 	}
 	// If we should ignore existing files, don't transfer
 	if fs.Config.IgnoreExisting {
 		fs.Debugf(src, "Destination exists, skipping")
-		return false
+		return false, false
 	}
 	// If we should upload unconditionally
 	if fs.Config.IgnoreTimes {
 		fs.Debugf(src, "Transferring unconditionally as --ignore-times is in use")
-		return true
+		return true, false
+	}
+	// If it isn't clear which to keep then follow --conflict-resolve
+	if reason := conflictReason(src, dst); reason != "" {
+		transfer, why := resolveConflict(src, dst)
+		fs.Logf(src, "Conflict: sizes differ (src %d vs dst %d) but %s - %s (--conflict-resolve %s)", src.Size(), dst.Size(), reason, why, fs.Config.ConflictResolve)
+		return transfer, true
 	}
 	// If UpdateOlder is in effect, skip if dst is newer than src
 	if fs.Config.UpdateOlder {
@@ -1329,13 +1634,13 @@ func NeedTransfer(dst, src fs.Object) bool {
 		switch {
 		case dt >= modifyWindow:
 			fs.Debugf(src, "Destination is newer than source, skipping")
-			return false
+			return false, false
 		case dt <= -modifyWindow:
 			fs.Debugf(src, "Destination is older than source, transferring")
 		default:
 			if src.Size() == dst.Size() {
 				fs.Debugf(src, "Destination mod time is within %v of source and sizes identical, skipping", modifyWindow)
-				return false
+				return false, false
 			}
 			fs.Debugf(src, "Destination mod time is within %v of source but sizes differ, transferring", modifyWindow)
 		}
@@ -1343,10 +1648,10 @@ func NeedTransfer(dst, src fs.Object) bool {
 		// Check to see if changed or not
 		if Equal(src, dst) {
 			fs.Debugf(src, "Unchanged skipping")
-			return false
+			return false, false
 		}
 	}
-	return true
+	return true, false
 }
 
 // RcatSize reads data from the Reader until EOF and uploads it to a file on remote.
@@ -1357,8 +1662,8 @@ func RcatSize(fdst fs.Fs, dstFileName string, in io.ReadCloser, size int64, modT
 	if size >= 0 {
 		// Size known use Put
 		accounting.Stats.Transferring(dstFileName)
-		body := ioutil.NopCloser(in)                                 // we let the server close the body
-		in := accounting.NewAccountSizeName(body, size, dstFileName) // account the transfer (no buffering)
+		body := ioutil.NopCloser(in)                                                                        // we let the server close the body
+		in := accounting.NewAccountSizeName(body, size, dstFileName).WithRemoteLimit(fdst).WithUpload(fdst) // account the transfer (no buffering)
 
 		if fs.Config.DryRun {
 			fs.Logf("stdin", "Not uploading as --dry-run")
@@ -1374,7 +1679,7 @@ func RcatSize(fdst fs.Fs, dstFileName string, in io.ReadCloser, size int64, modT
 				accounting.Stats.Error(closeErr)
 				fs.Errorf(dstFileName, "Post request: close failed: %v", closeErr)
 			}
-			accounting.Stats.DoneTransferring(dstFileName, err == nil)
+			accounting.Stats.DoneTransferringError(dstFileName, size, err)
 		}()
 		info := object.NewStaticObjectInfo(dstFileName, modTime, size, true, nil, fdst)
 		obj, err = fdst.Put(in, info)
@@ -1438,7 +1743,7 @@ func moveOrCopyFile(fdst fs.Fs, fsrc fs.Fs, dstFileName string, srcFileName stri
 	if NeedTransfer(dstObj, srcObj) {
 		accounting.Stats.Transferring(srcFileName)
 		_, err = Op(fdst, dstObj, dstFileName, srcObj)
-		accounting.Stats.DoneTransferring(srcFileName, err == nil)
+		accounting.Stats.DoneTransferringError(srcFileName, srcObj.Size(), err)
 	} else {
 		accounting.Stats.Checking(srcFileName)
 		if !cp {
@@ -1568,6 +1873,38 @@ func (l *ListFormat) AddID() {
 	})
 }
 
+// AddUnderlyingPath adds the path of the Object that the file is
+// stored as on the wrapped remote of an overlay remote such as crypt,
+// or "" if it isn't wrapping anything
+func (l *ListFormat) AddUnderlyingPath() {
+	l.AppendOutput(func() string {
+		o, ok := l.entry.(fs.Object)
+		if !ok {
+			return ""
+		}
+		if u := fs.UnWrapObject(o); u != o {
+			return u.Remote()
+		}
+		return ""
+	})
+}
+
+// AddUnderlyingSize adds the size of the Object that the file is
+// stored as on the wrapped remote of an overlay remote such as crypt,
+// or "" if it isn't wrapping anything
+func (l *ListFormat) AddUnderlyingSize() {
+	l.AppendOutput(func() string {
+		o, ok := l.entry.(fs.Object)
+		if !ok {
+			return ""
+		}
+		if u := fs.UnWrapObject(o); u != o {
+			return strconv.FormatInt(u.Size(), 10)
+		}
+		return ""
+	})
+}
+
 // AddMimeType adds file's MimeType to the output if known
 func (l *ListFormat) AddMimeType() {
 	l.AppendOutput(func() string {
@@ -1632,7 +1969,7 @@ func DirMove(f fs.Fs, srcRemote, dstRemote string) (err error) {
 		newPath string
 	}
 	renames := make(chan rename, fs.Config.Transfers)
-	g, ctx := errgroup.WithContext(context.Background())
+	g, ctx := errgroup.WithContext(accounting.ShutdownContext())
 	for i := 0; i < fs.Config.Transfers; i++ {
 		g.Go(func() error {
 			for job := range renames {
@@ -1675,3 +2012,64 @@ func DirMove(f fs.Fs, srcRemote, dstRemote string) (err error) {
 
 	return nil
 }
+
+// fixCaseTmpName returns a temporary name to rename remote to on the
+// way to a name which differs from it only in case
+func fixCaseTmpName(remote string) string {
+	return fmt.Sprintf("%s.rclone-fix-case-%08x", remote, rand.Uint32())
+}
+
+// MoveCaseInsensitive renames dst to remote which differs from
+// dst.Remote() only in case.
+//
+// It renames via a temporary name as renaming directly may do nothing
+// or fail on a case insensitive backend.  If fdst can't move files
+// server side the case isn't fixed and dst is returned as that would
+// mean transferring the file twice.
+func MoveCaseInsensitive(fdst fs.Fs, dst fs.Object, remote string) (newDst fs.Object, err error) {
+	if fdst.Features().Move == nil {
+		fs.Logf(dst, "Can't fix case as remote can't move files server side")
+		return dst, nil
+	}
+	if SkipDestructive(dst, "fix case") {
+		return dst, nil
+	}
+	tmp, err := moveWithStats(nil, fdst, nil, fixCaseTmpName(remote), dst)
+	if err != nil {
+		return nil, err
+	}
+	newDst, err = moveWithStats(nil, fdst, nil, remote, tmp)
+	if err != nil {
+		return nil, err
+	}
+	fs.Infof(newDst, "Fixed case from %q", dst.Remote())
+	return newDst, nil
+}
+
+// DirMoveCaseInsensitive renames the directory srcRemote to
+// dstRemote which differs from it only in case.
+//
+// It renames via a temporary name as renaming directly may do nothing
+// or fail on a case insensitive backend.  If f can't move directories
+// server side the case isn't fixed as that would mean transferring
+// the whole directory twice.
+func DirMoveCaseInsensitive(f fs.Fs, srcRemote, dstRemote string) error {
+	if f.Features().DirMove == nil {
+		fs.Logf(fs.LogDirName(f, srcRemote), "Can't fix case as remote can't move directories server side")
+		return nil
+	}
+	if SkipDestructive(fs.LogDirName(f, srcRemote), "fix case") {
+		return nil
+	}
+	tmp := fixCaseTmpName(dstRemote)
+	err := DirMove(f, srcRemote, tmp)
+	if err != nil {
+		return err
+	}
+	err = DirMove(f, tmp, dstRemote)
+	if err != nil {
+		return err
+	}
+	fs.Infof(fs.LogDirName(f, dstRemote), "Fixed case from %q", srcRemote)
+	return nil
+}
@@ -8,8 +8,11 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/object"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSizeDiffers(t *testing.T) {
@@ -38,3 +41,108 @@ func TestSizeDiffers(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("ignoreSize=%v, srcSize=%v, dstSize=%v", test.ignoreSize, test.srcSize, test.dstSize))
 	}
 }
+
+func TestCheckTransfer(t *testing.T) {
+	when := time.Now()
+	dst := object.NewMemoryObject("a", when, []byte("potato"))
+	md5sum, err := dst.Hash(hash.MD5)
+	require.NoError(t, err)
+	for _, test := range []struct {
+		size          int64
+		hashes        map[hash.Type]string
+		hashType      hash.Type
+		wantVerified  bool
+		wantCorrupted bool
+	}{
+		{6, nil, hash.None, false, false},
+		{6, map[hash.Type]string{hash.MD5: md5sum}, hash.MD5, true, false},
+		{6, map[hash.Type]string{hash.MD5: ""}, hash.MD5, false, false},
+		{6, map[hash.Type]string{hash.MD5: "0123456789abcdef0123456789abcdef"}, hash.MD5, false, true},
+		{5, map[hash.Type]string{hash.MD5: md5sum}, hash.MD5, false, true},
+	} {
+		src := object.NewStaticObjectInfo("a", when, test.size, true, test.hashes, nil)
+		verified, corrupted, err := checkTransfer(src, dst, test.hashType)
+		what := fmt.Sprintf("size=%d, hashes=%v", test.size, test.hashes)
+		assert.Equal(t, test.wantVerified, verified, what)
+		assert.Equal(t, test.wantCorrupted, corrupted, what)
+		assert.Equal(t, test.wantCorrupted, err != nil, what)
+	}
+}
+
+func TestSkipDestructive(t *testing.T) {
+	oldDryRun, oldInteractive, oldReadLine := fs.Config.DryRun, fs.Config.Interactive, config.ReadLine
+	defer func() {
+		fs.Config.DryRun, fs.Config.Interactive, config.ReadLine = oldDryRun, oldInteractive, oldReadLine
+		skipped = map[string]bool{}
+	}()
+	var answers []string
+	config.ReadLine = func() string {
+		answer := answers[0]
+		answers = answers[1:]
+		return answer
+	}
+
+	fs.Config.DryRun, fs.Config.Interactive = false, false
+	assert.False(t, SkipDestructive("file", "delete"))
+
+	fs.Config.DryRun = true
+	assert.True(t, SkipDestructive("file", "delete"))
+
+	fs.Config.DryRun, fs.Config.Interactive = false, true
+	answers = []string{"y", "n", "s", "!"}
+	assert.False(t, SkipDestructive("file", "delete"))
+	assert.True(t, SkipDestructive("file", "delete"))
+	assert.True(t, SkipDestructive("file", "delete"))
+	assert.True(t, SkipDestructive("file2", "delete"), "should remember skip all")
+	assert.False(t, SkipDestructive("file", "move"))
+	assert.False(t, SkipDestructive("file2", "move"), "should remember do all")
+	assert.Len(t, answers, 0)
+}
+
+// cantSetModTimeObject is a MemoryObject whose SetModTime returns err
+type cantSetModTimeObject struct {
+	*object.MemoryObject
+	err     error
+	removed bool
+}
+
+func (o *cantSetModTimeObject) SetModTime(modTime time.Time) error {
+	return o.err
+}
+
+func (o *cantSetModTimeObject) Remove() error {
+	o.removed = true
+	return nil
+}
+
+func TestEqualCantSetModTime(t *testing.T) {
+	oldRefreshTimes := fs.Config.RefreshTimes
+	defer func() {
+		fs.Config.RefreshTimes = oldRefreshTimes
+	}()
+	t1 := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	src := object.NewMemoryObject("a", t1, []byte("potato"))
+	for _, test := range []struct {
+		err          error
+		refreshTimes bool
+		want         bool
+		wantRemoved  bool
+	}{
+		{nil, false, true, false},
+		{nil, true, true, false},
+		{fs.ErrorCantSetModTime, false, true, false},
+		{fs.ErrorCantSetModTime, true, false, false},
+		{fs.ErrorCantSetModTimeWithoutDelete, false, true, false},
+		{fs.ErrorCantSetModTimeWithoutDelete, true, false, true},
+	} {
+		fs.Config.RefreshTimes = test.refreshTimes
+		dst := &cantSetModTimeObject{
+			MemoryObject: object.NewMemoryObject("a", t2, []byte("potato")),
+			err:          test.err,
+		}
+		what := fmt.Sprintf("err=%v, refreshTimes=%v", test.err, test.refreshTimes)
+		assert.Equal(t, test.want, equal(src, dst, false, false), what)
+		assert.Equal(t, test.wantRemoved, dst.removed, what)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -37,11 +38,14 @@ import (
 	_ "github.com/ncw/rclone/backend/all" // import all backends
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/list"
+	"github.com/ncw/rclone/fs/object"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -342,6 +346,15 @@ func TestCheckDownload(t *testing.T) {
 	testCheck(t, operations.CheckDownload)
 }
 
+func TestCheckDownloadWithOpt(t *testing.T) {
+	testCheck(t, func(fdst, fsrc fs.Fs, oneway bool) error {
+		return operations.CheckDownloadWithOpt(fdst, fsrc, oneway, operations.CheckDownloadOpt{
+			Checkers:   1,
+			BufferSize: 64 * 1024,
+		})
+	})
+}
+
 func TestCheckSizeOnly(t *testing.T) {
 	fs.Config.SizeOnly = true
 	defer func() { fs.Config.SizeOnly = false }()
@@ -366,9 +379,10 @@ func TestCat(t *testing.T) {
 		{0, 5, "ABCDE", "01234"},
 		{-3, -1, "HIJ", "678"},
 		{1, 3, "BCD", "123"},
+		{-20, -1, "ABCDEFGHIJ", "012345678"},
 	} {
 		var buf bytes.Buffer
-		err := operations.Cat(r.Fremote, &buf, test.offset, test.count)
+		err := operations.Cat(r.Fremote, &buf, test.offset, test.count, nil)
 		require.NoError(t, err)
 		res := buf.String()
 
@@ -376,6 +390,14 @@ func TestCat(t *testing.T) {
 			t.Errorf("Incorrect output from Cat(%d,%d): %q", test.offset, test.count, res)
 		}
 	}
+
+	var buf bytes.Buffer
+	err := operations.Cat(r.Fremote, &buf, 0, 3, []byte("\n--\n"))
+	require.NoError(t, err)
+	res := buf.String()
+	if res != "ABC\n--\n012" && res != "012\n--\nABC" {
+		t.Errorf("Incorrect output from Cat with separator: %q", res)
+	}
 }
 
 func TestRcat(t *testing.T) {
@@ -756,6 +778,38 @@ func TestSameConfig(t *testing.T) {
 	}
 }
 
+// otherFsInfo is a different type of remote to testFsInfo
+type otherFsInfo struct {
+	testFsInfo
+}
+
+func TestCanServerSide(t *testing.T) {
+	defer func() {
+		fs.Config.ServerSideAcrossConfigs = false
+	}()
+	a := &testFsInfo{name: "name", root: "root"}
+	b := &testFsInfo{name: "namey", root: "root"}
+	c := &otherFsInfo{testFsInfo{name: "namez", root: "root"}}
+	aFeature := &testFsInfo{name: "name", root: "rooty", features: fs.Features{ServerSideAcrossConfigs: true}}
+	bFeature := &testFsInfo{name: "namey", root: "rooty", features: fs.Features{ServerSideAcrossConfigs: true}}
+	cFeature := &otherFsInfo{testFsInfo{name: "namez", root: "rooty", features: fs.Features{ServerSideAcrossConfigs: true}}}
+
+	assert.True(t, operations.SameRemoteType(a, b))
+	assert.False(t, operations.SameRemoteType(a, c))
+
+	for _, across := range []bool{false, true} {
+		fs.Config.ServerSideAcrossConfigs = across
+		assert.True(t, operations.CanServerSide(a, aFeature))
+		assert.True(t, operations.CanServerSide(a, a))
+		assert.False(t, operations.CanServerSide(a, b))
+		assert.False(t, operations.CanServerSide(b, aFeature))
+		assert.Equal(t, across, operations.CanServerSide(bFeature, a))
+		assert.Equal(t, across, operations.CanServerSide(aFeature, bFeature))
+		assert.False(t, operations.CanServerSide(cFeature, aFeature))
+		assert.False(t, operations.CanServerSide(aFeature, cFeature))
+	}
+}
+
 func TestSame(t *testing.T) {
 	a := &testFsInfo{name: "name", root: "root"}
 	for _, test := range []struct {
@@ -872,6 +926,56 @@ func TestCheckEqualReaders(t *testing.T) {
 	assert.Equal(t, differ, true)
 }
 
+// TestListJSONShowUnderlying checks the underlying names and sizes
+// are shown for the objects of a crypt remote
+func TestListJSONShowUnderlying(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-lsjson-crypt")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for key, value := range map[string]string{
+		"type":                "crypt",
+		"remote":              dir,
+		"password":            obscure.MustObscure("potato"),
+		"filename_encryption": "off",
+	} {
+		envVar := fs.ConfigToEnv("lsjson-crypt", key)
+		require.NoError(t, os.Setenv(envVar, value))
+		defer func() { _ = os.Unsetenv(envVar) }()
+	}
+	f, err := fs.NewFs("lsjson-crypt:")
+	require.NoError(t, err)
+	contents := "hello world"
+	src := object.NewStaticObjectInfo("file", t1, int64(len(contents)), true, nil, nil)
+	_, err = f.Put(strings.NewReader(contents), src)
+	require.NoError(t, err)
+
+	var items []*operations.ListJSONItem
+	err = operations.ListJSON(f, "", &operations.ListJSONOpt{ShowUnderlying: true}, func(item *operations.ListJSONItem) error {
+		items = append(items, item)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "file", items[0].Path)
+	assert.Equal(t, int64(len(contents)), items[0].Size)
+	assert.Equal(t, "file.bin", items[0].UnderlyingPath)
+	require.NotNil(t, items[0].UnderlyingSize)
+	fi, err := os.Stat(filepath.Join(dir, "file.bin"))
+	require.NoError(t, err)
+	assert.Equal(t, fi.Size(), *items[0].UnderlyingSize)
+}
+
+// wrappedObject is an Object for an overlay remote which wraps another
+type wrappedObject struct {
+	fs.Object
+	wrapped fs.Object
+}
+
+// UnWrap returns the Object that this Object is wrapping
+func (o wrappedObject) UnWrap() fs.Object { return o.wrapped }
+
 func TestListFormat(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
@@ -911,6 +1015,17 @@ func TestListFormat(t *testing.T) {
 	assert.Contains(t, list.Format(items[0]), "/")
 	assert.Equal(t, "inode/directory", list.Format(items[1]))
 
+	list.SetOutput(nil)
+	list.AddUnderlyingPath()
+	list.AddUnderlyingSize()
+	assert.Equal(t, ":::", list.Format(items[0])) // not wrapped
+	assert.Equal(t, ":::", list.Format(items[1]))
+	underlying := mockobject.New("a.bin").WithContent([]byte("abcdef"), mockobject.SeekModeNone)
+	wrapped := wrappedObject{Object: items[0].(fs.Object), wrapped: underlying}
+	assert.Equal(t, "a.bin:::6", list.Format(wrapped))
+	wrapped = wrappedObject{Object: items[0].(fs.Object), wrapped: wrappedObject{Object: mockobject.New("a.mid"), wrapped: underlying}}
+	assert.Equal(t, "a.bin:::6", list.Format(wrapped), "unwraps all the layers")
+
 	list.SetOutput(nil)
 	list.AddPath()
 	list.SetAbsolute(true)
@@ -1043,3 +1158,65 @@ func TestDirMove(t *testing.T) {
 	)
 
 }
+
+// TestCheckHashesCompareWrappedCrypt checks --compare wrapped-hash
+// with a pair of crypt remotes
+func TestCheckHashesCompareWrappedCrypt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-compare-crypt")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	oldCompare := fs.Config.Compare
+	defer func() { fs.Config.Compare = oldCompare }()
+
+	// Make two crypt remotes with the same password over two local
+	// directories, configured with environment variables which are
+	// only needed while the Fs is made
+	newCrypt := func(name string) fs.Fs {
+		for key, value := range map[string]string{
+			"type":                "crypt",
+			"remote":              filepath.Join(dir, name),
+			"password":            obscure.MustObscure("potato"),
+			"filename_encryption": "off",
+		} {
+			envVar := fs.ConfigToEnv(name, key)
+			require.NoError(t, os.Setenv(envVar, value))
+			defer func() { _ = os.Unsetenv(envVar) }()
+		}
+		f, err := fs.NewFs(name + ":")
+		require.NoError(t, err)
+		return f
+	}
+	fsrc, fdst := newCrypt("compare-crypt-src"), newCrypt("compare-crypt-dst")
+	put := func(f fs.Fs, remote string) fs.Object {
+		contents := "hello world"
+		src := object.NewStaticObjectInfo(remote, t1, int64(len(contents)), true, nil, nil)
+		o, err := f.Put(strings.NewReader(contents), src)
+		require.NoError(t, err)
+		return o
+	}
+	fs.Config.Compare = "wrapped-hash"
+
+	// A copy of the encrypted data has the same wrapped hashes
+	src := put(fsrc, "copied")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "compare-crypt-src", "copied.bin"))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "compare-crypt-dst"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "compare-crypt-dst", "copied.bin"), data, 0666))
+	dst, err := fdst.NewObject("copied")
+	require.NoError(t, err)
+	equal, ht, err := operations.CheckHashes(src, dst)
+	require.NoError(t, err)
+	assert.True(t, equal)
+	assert.True(t, ht.IsWrapped(), ht.String())
+
+	// The same file uploaded through crypt is encrypted with a
+	// different nonce so the wrapped hashes don't match
+	src = put(fsrc, "uploaded")
+	dst = put(fdst, "uploaded")
+	equal, ht, err = operations.CheckHashes(src, dst)
+	require.NoError(t, err)
+	assert.False(t, equal)
+	assert.True(t, ht.IsWrapped(), ht.String())
+}
@@ -0,0 +1,124 @@
+package operations
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+	"unicode/utf8"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// usePartial returns whether uploads to f should be made to a partial
+// name and renamed into place when complete.
+//
+// This is done if --partial is set, the partial uploads of f are
+// visible and it can rename them into place.
+func usePartial(f fs.Fs) bool {
+	return fs.Config.Partial && f.Features().PartialUploads && f.Features().Move != nil
+}
+
+// partialNaming returns how partial uploads to f are named.
+//
+// This is the remote's naming unless any of --partial-dir,
+// --partial-prefix or --partial-suffix are set in which case they
+// are used instead.
+func partialNaming(f fs.Fs) fs.PartialNaming {
+	naming := fs.DefaultPartialNaming
+	if do := f.Features().PartialNaming; do != nil {
+		naming = do()
+	}
+	if fs.Config.PartialDir != "" || fs.Config.PartialPrefix != "" || fs.Config.PartialSuffix != "" {
+		naming.Dir = fs.Config.PartialDir
+		naming.Prefix = fs.Config.PartialPrefix
+		naming.Suffix = fs.Config.PartialSuffix
+	}
+	return naming
+}
+
+// PartialName returns the name a partial upload of remote to f is
+// made to before being renamed to remote.
+//
+// This is made from the partial naming of f with a random part so
+// simultaneous uploads of the same file don't collide, eg
+// "dir/file.txt.1234abcd.partial".  The leaf of remote is shortened
+// if necessary so the name isn't longer than the remote allows - the
+// random part keeps it unique.
+func PartialName(f fs.Fs, remote string) string {
+	naming := partialNaming(f)
+	dir, leaf := path.Split(remote)
+	if naming.Dir != "" {
+		dir = path.Join(dir, naming.Dir) + "/"
+	}
+	random := fmt.Sprintf(".%08x", rand.Uint32())
+	if naming.MaxLeafLength > 0 {
+		leaf = truncateLeaf(leaf, naming.MaxLeafLength-len(naming.Prefix)-len(random)-len(naming.Suffix))
+	}
+	return dir + naming.Prefix + leaf + random + naming.Suffix
+}
+
+// truncateLeaf shortens leaf to at most n bytes without splitting a
+// UTF-8 character
+func truncateLeaf(leaf string, n int) string {
+	if len(leaf) <= n {
+		return leaf
+	}
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && !utf8.RuneStart(leaf[n]) {
+		n--
+	}
+	return leaf[:n]
+}
+
+// removePartialDir removes the --partial-dir partial was made in if
+// it is empty.
+//
+// This is only done if the partial upload was made in a directory of
+// its own - it isn't an error if it isn't empty as other uploads may
+// still be using it.
+func removePartialDir(f fs.Fs, partial, remote string) {
+	dir := path.Dir(partial)
+	if dir == path.Dir(remote) {
+		return
+	}
+	if dir == "." {
+		dir = ""
+	}
+	if err := f.Rmdir(dir); err != nil {
+		fs.Debugf(fs.LogDirName(f, dir), "Not removing partial upload directory: %v", err)
+	}
+}
+
+// finishPartial renames the completed partial upload to remote,
+// replacing dst if it isn't nil.
+//
+// The partial upload is removed if it can't be renamed unless dst has
+// been removed already.  The --partial-dir it was made in is removed
+// if it is empty.
+func finishPartial(f fs.Fs, partial fs.Object, dst fs.Object, remote string) (newDst fs.Object, err error) {
+	defer removePartialDir(f, partial.Remote(), remote)
+	doMove := f.Features().Move
+	newDst, err = doMove(partial, remote)
+	if err == nil {
+		return newDst, nil
+	}
+	if dst != nil {
+		// Some remotes can't rename over an existing file so
+		// remove it and try again
+		fs.Debugf(partial, "Failed to rename partial upload over existing file - removing it and retrying: %v", err)
+		if removeErr := dst.Remove(); removeErr == nil {
+			newDst, err = doMove(partial, remote)
+			if err == nil {
+				return newDst, nil
+			}
+			return nil, errors.Wrapf(err, "failed to rename partial upload %q", partial.Remote())
+		}
+	}
+	if removeErr := partial.Remove(); removeErr != nil {
+		fs.Errorf(partial, "Failed to remove partial upload: %v", removeErr)
+	}
+	return nil, errors.Wrap(err, "failed to rename partial upload")
+}
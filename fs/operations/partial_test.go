@@ -0,0 +1,112 @@
+package operations_test
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/fstest/mockfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// partialFs is an Fs with the given partial naming
+type partialFs struct {
+	*mockfs.Fs
+	naming   fs.PartialNaming
+	features *fs.Features
+}
+
+func newPartialFs(naming fs.PartialNaming) *partialFs {
+	f := &partialFs{
+		Fs:     mockfs.NewFs("partial", ""),
+		naming: naming,
+	}
+	f.features = (&fs.Features{PartialUploads: true}).Fill(f)
+	return f
+}
+
+func (f *partialFs) Features() *fs.Features {
+	return f.features
+}
+
+func (f *partialFs) PartialNaming() fs.PartialNaming {
+	return f.naming
+}
+
+func TestPartialName(t *testing.T) {
+	oldDir, oldPrefix, oldSuffix := fs.Config.PartialDir, fs.Config.PartialPrefix, fs.Config.PartialSuffix
+	defer func() {
+		fs.Config.PartialDir, fs.Config.PartialPrefix, fs.Config.PartialSuffix = oldDir, oldPrefix, oldSuffix
+	}()
+	hidden := fs.PartialNaming{Prefix: ".", Suffix: ".partial"}
+	for _, test := range []struct {
+		naming              fs.PartialNaming
+		dir, prefix, suffix string
+		remote              string
+		want                string
+	}{
+		// the remote's naming
+		{fs.PartialNaming{Suffix: ".partial"}, "", "", "", "file.txt", `^file\.txt\.[0-9a-f]{8}\.partial$`},
+		{hidden, "", "", "", "dir/file.txt", `^dir/\.file\.txt\.[0-9a-f]{8}\.partial$`},
+		{fs.PartialNaming{Dir: ".tmp"}, "", "", "", "dir/file.txt", `^dir/\.tmp/file\.txt\.[0-9a-f]{8}$`},
+		// overridden by the flags
+		{hidden, "", "", ".part", "dir/file.txt", `^dir/file\.txt\.[0-9a-f]{8}\.part$`},
+		{hidden, "", "~", "", "dir/file.txt", `^dir/~file\.txt\.[0-9a-f]{8}$`},
+		{hidden, ".tmp", "", ".partial", "file.txt", `^\.tmp/file\.txt\.[0-9a-f]{8}\.partial$`},
+		// too long leaves are shortened
+		{fs.PartialNaming{Suffix: ".partial", MaxLeafLength: 21}, "", "", "", "dir/file.txt", `^dir/file\.[0-9a-f]{8}\.partial$`},
+		{fs.PartialNaming{Prefix: ".", MaxLeafLength: 13}, "", "", "", "£££", `^\.£\.[0-9a-f]{8}$`},
+		{fs.PartialNaming{Suffix: ".partial", MaxLeafLength: 10}, "", "", "", "file.txt", `^\.[0-9a-f]{8}\.partial$`},
+	} {
+		fs.Config.PartialDir, fs.Config.PartialPrefix, fs.Config.PartialSuffix = test.dir, test.prefix, test.suffix
+		f := newPartialFs(test.naming)
+		got := operations.PartialName(f, test.remote)
+		assert.Regexp(t, regexp.MustCompile(test.want), got, test.remote)
+	}
+
+	// Remotes which don't say use the default naming
+	fs.Config.PartialDir, fs.Config.PartialPrefix, fs.Config.PartialSuffix = "", "", ""
+	f := mockfs.NewFs("mock", "")
+	assert.Regexp(t, regexp.MustCompile(`^file\.txt\.[0-9a-f]{8}\.partial$`), operations.PartialName(f, "file.txt"))
+	assert.NotEqual(t, operations.PartialName(f, "file.txt"), operations.PartialName(f, "file.txt"))
+
+	// Leaves as long as the remote allows get partial names it allows
+	local := newPartialFs(fs.PartialNaming{Suffix: ".partial", MaxLeafLength: 255})
+	leaf := operations.PartialName(local, "dir/"+strings.Repeat("a", 255))
+	assert.Equal(t, 255, len(path.Base(leaf)))
+}
+
+func TestCopyFilePartial(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if !r.Fremote.Features().PartialUploads {
+		t.Skip("remote doesn't use partial uploads")
+	}
+	oldPartial, oldDir := fs.Config.Partial, fs.Config.PartialDir
+	defer func() { fs.Config.Partial, fs.Config.PartialDir = oldPartial, oldDir }()
+	fs.Config.Partial = true
+	fs.Config.PartialDir = ".partial"
+
+	file1 := r.WriteFile("sub/file1", "file1 contents", t1)
+	file2 := r.WriteObject("sub/file1", "old contents", t2)
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	// replace an existing file
+	err := operations.CopyFile(r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+
+	// a new file
+	err = operations.CopyFile(r.Fremote, r.Flocal, "sub/file2", file1.Path)
+	require.NoError(t, err)
+	file3 := file1
+	file3.Path = "sub/file2"
+
+	// the partial directory is removed
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file3}, []string{"sub"}, fs.GetModifyWindow(r.Fremote))
+}
@@ -23,6 +23,7 @@ func init() {
     - noModTime - If set return modification time
     - showEncrypted -  If set show decrypted names
     - showOrigIDs - If set show the IDs for each item if known
+    - showUnderlying - If set show the paths and sizes of the underlying objects for overlay remotes
     - showHash - If set return a dictionary of hashes
 
 The result is
@@ -96,6 +97,86 @@ func rcAbout(in rc.Params) (out rc.Params, err error) {
 	return out, nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:         "operations/fsinfo",
+		AuthRequired: true,
+		Fn:           rcFsInfo,
+		Title:        "Return information about the remote",
+		Help: `This takes the following parameters
+
+- fs - a remote name string eg "drive:"
+
+This returns info about the remote passed in;
+
+` + "```" + `
+{
+	// optional features and whether they are available or not
+	"Features": {
+		"About": true,
+		"BucketBased": false,
+		"CanHaveEmptyDirectories": true,
+		"CaseInsensitive": false,
+		"ChangeNotify": false,
+		"CleanUp": false,
+		"Command": false,
+		"Copy": false,
+		"DirCacheFlush": false,
+		"DirMove": true,
+		"DirSetModTime": true,
+		"DuplicateFiles": false,
+		"GetTier": false,
+		"HardLink": true,
+		"ListR": false,
+		"MergeDirs": false,
+		"Move": true,
+		"PartialNaming": true,
+		"PartialUploads": true,
+		"PublicLink": false,
+		"Purge": true,
+		"PutStream": true,
+		"PutUnchecked": false,
+		"ReadMimeType": false,
+		"SetTier": false,
+		"SlowHash": true,
+		"SetWrapper": false,
+		"UnWrap": false,
+		"WrapFs": false,
+		"WriteMimeType": false
+	},
+	// Names of hashes available
+	"Hashes": [
+		"MD5",
+		"SHA-1",
+		"DropboxHash",
+		"QuickXorHash"
+	],
+	"Name": "local",	// Name as created
+	"Precision": 1,		// Precision of timestamps in ns
+	"Root": "/",		// Path as created
+	"String": "Local file system at /" // how the remote will appear in logs
+}
+` + "```" + `
+
+This is the same as "rclone backend features remote:".
+`,
+	})
+}
+
+// Fsinfo the remote
+func rcFsInfo(in rc.Params) (out rc.Params, err error) {
+	f, err := rc.GetFs(in)
+	if err != nil {
+		return nil, err
+	}
+	info := GetFsInfo(f)
+	err = rc.Reshape(&out, info)
+	if err != nil {
+		return nil, errors.Wrap(err, "fsinfo Reshape failed")
+	}
+	return out, nil
+}
+
 func init() {
 	for _, copy := range []bool{false, true} {
 		copy := copy
@@ -255,3 +336,67 @@ func rcSize(in rc.Params) (out rc.Params, err error) {
 	out["bytes"] = bytes
 	return out, nil
 }
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "backend/command",
+		AuthRequired: true,
+		Fn:           rcBackend,
+		Title:        "Runs a backend command.",
+		Help: `This takes the following parameters
+
+- command - a string with the command name
+- fs - a remote name string eg "drive:"
+- arg - a list of arguments for the backend command
+- opt - a map of string to string of options
+
+Returns
+
+- result - result from the backend command
+
+For example
+
+    rclone rc backend/command --json '{"command":"rekey","fs":"crypt:","opt":{"password":"newpassword"}}'
+
+Note that this is the direct equivalent of using this "backend"
+command:
+
+    rclone backend rekey crypt: -o password=newpassword
+
+See the [backend](/commands/rclone_backend/) command for more information.
+`,
+	})
+}
+
+// Run a backend command
+func rcBackend(in rc.Params) (out rc.Params, err error) {
+	f, err := rc.GetFs(in)
+	if err != nil {
+		return nil, err
+	}
+	doCommand := f.Features().Command
+	if doCommand == nil {
+		return nil, errors.Errorf("%v: doesn't support backend commands", f)
+	}
+	command, err := in.GetString("command")
+	if err != nil {
+		return nil, err
+	}
+	var opt = map[string]string{}
+	err = in.GetStruct("opt", &opt)
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	var arg = []string{}
+	err = in.GetStruct("arg", &arg)
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	result, err := doCommand(command, arg, opt)
+	if err != nil {
+		return nil, errors.Wrapf(err, "command %q failed", command)
+	}
+	out = make(rc.Params)
+	out["result"] = result
+	return out, nil
+}
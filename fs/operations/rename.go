@@ -0,0 +1,225 @@
+package operations
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// RenameRegex is a sed style s/regexp/replacement/flags substitution
+// used by Rename to make the new names of files.
+type RenameRegex struct {
+	re          *regexp.Regexp
+	replacement string
+	global      bool // replace all the matches, not just the first
+	fullPath    bool // apply to the path rather than the leaf name
+}
+
+// sedGroup matches \1 style references in sed replacements
+var sedGroup = regexp.MustCompile(`\\([0-9])`)
+
+// NewRenameRegex parses a substitution of the form
+// s/regexp/replacement/flags.
+//
+// Any character may be used instead of / and it may be escaped with
+// a backslash in the regexp and replacement.  The regexp is in Go
+// syntax and the replacement may refer to groups as $1 or \1.  The
+// flags are g to replace all matches rather than the first and i to
+// match case insensitively.
+//
+// If fullPath is set it is applied to the path of the file relative
+// to the root rather than just the leaf name.
+func NewRenameRegex(s string, fullPath bool) (*RenameRegex, error) {
+	if len(s) < 2 || s[0] != 's' {
+		return nil, errors.Errorf("rename regex %q must be of the form s/regexp/replacement/", s)
+	}
+	sep := rune(s[1])
+	var parts []string
+	var part []rune
+	escaped := false
+	for _, c := range s[2:] {
+		switch {
+		case escaped && c == sep:
+			part = append(part, c)
+			escaped = false
+		case escaped:
+			part = append(part, '\\', c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == sep:
+			parts = append(parts, string(part))
+			part = nil
+		default:
+			part = append(part, c)
+		}
+	}
+	if escaped {
+		part = append(part, '\\')
+	}
+	parts = append(parts, string(part))
+	if len(parts) != 3 {
+		return nil, errors.Errorf("rename regex %q must be of the form s/regexp/replacement/", s)
+	}
+	pattern, replacement, flags := parts[0], parts[1], parts[2]
+	r := &RenameRegex{
+		replacement: sedGroup.ReplaceAllString(replacement, "$${$1}"),
+		fullPath:    fullPath,
+	}
+	for _, flag := range flags {
+		switch flag {
+		case 'g':
+			r.global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, errors.Errorf("unknown flag %q in rename regex %q", flag, s)
+		}
+	}
+	var err error
+	r.re, err = regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad rename regex")
+	}
+	return r, nil
+}
+
+// replace does the substitution on s
+func (r *RenameRegex) replace(s string) string {
+	if r.global {
+		return r.re.ReplaceAllString(s, r.replacement)
+	}
+	loc := r.re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return s
+	}
+	replaced := r.re.ExpandString(nil, r.replacement, s, loc)
+	return s[:loc[0]] + string(replaced) + s[loc[1]:]
+}
+
+// NewRemote returns the new name for remote
+func (r *RenameRegex) NewRemote(remote string) string {
+	if r.fullPath {
+		return path.Clean(r.replace(remote))
+	}
+	dir, leaf := path.Split(remote)
+	return path.Clean(dir + r.replace(leaf))
+}
+
+// rename is a file to be renamed by Rename
+type rename struct {
+	o         fs.Object
+	newRemote string
+}
+
+// checkTarget returns an error if something exists at x.newRemote.
+//
+// The listing Rename uses to find collisions obeys the filters and
+// --max-depth so this looks the target up directly.
+func (x *rename) checkTarget(f fs.Fs) error {
+	_, err := f.NewObject(x.newRemote)
+	switch err {
+	case nil, fs.ErrorNotAFile:
+		return errors.Errorf("%q exists already", x.newRemote)
+	case fs.ErrorObjectNotFound:
+		return nil
+	}
+	return errors.Wrapf(err, "failed to check %q", x.newRemote)
+}
+
+// Rename renames the files in f using r, moving them server side if
+// f supports it.
+//
+// A file isn't renamed if its new name is empty or outside f, or a
+// file of that name exists already, even if it is excluded by the
+// filters, or another file is being renamed to it.  Use --dry-run to
+// see what would be renamed.
+func Rename(f fs.Fs, r *RenameRegex) error {
+	if f.Features().Move == nil {
+		fs.Logf(f, "Remote can't move files server side so renaming will copy them")
+	}
+	existing := make(map[string]struct{}) // files and directories found
+	var renames []rename
+	err := walk.Walk(f, "", false, fs.Config.MaxDepth, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			existing[entry.Remote()] = struct{}{}
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			newRemote := r.NewRemote(o.Remote())
+			if newRemote != o.Remote() {
+				renames = append(renames, rename{o: o, newRemote: newRemote})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list files to rename")
+	}
+
+	// Find the renames which can't be done
+	targets := make(map[string]int, len(renames))
+	for _, x := range renames {
+		targets[x.newRemote]++
+	}
+	var errorCount int32
+	toRename := make(chan rename, len(renames))
+	for _, x := range renames {
+		var err error
+		switch _, found := existing[x.newRemote]; {
+		case x.newRemote == "." || x.newRemote == ".." || strings.HasPrefix(x.newRemote, "../") || strings.HasPrefix(x.newRemote, "/"):
+			err = errors.Errorf("new name %q is invalid", x.newRemote)
+		case found:
+			err = errors.Errorf("%q exists already", x.newRemote)
+		case targets[x.newRemote] > 1:
+			err = errors.Errorf("%d files would be renamed to %q", targets[x.newRemote], x.newRemote)
+		default:
+			toRename <- x
+			continue
+		}
+		fs.CountError(err)
+		fs.Errorf(x.o, "Not renaming: %v", err)
+		errorCount++
+	}
+	close(toRename)
+
+	var wg sync.WaitGroup
+	wg.Add(fs.Config.Transfers)
+	for i := 0; i < fs.Config.Transfers; i++ {
+		go func() {
+			defer wg.Done()
+			for x := range toRename {
+				if err := x.checkTarget(f); err != nil {
+					fs.CountError(err)
+					fs.Errorf(x.o, "Not renaming: %v", err)
+					atomic.AddInt32(&errorCount, 1)
+					continue
+				}
+				if SkipDestructive(x.o, "rename to "+x.newRemote) {
+					continue
+				}
+				_, err := moveWithStats(nil, f, nil, x.newRemote, x.o)
+				if err != nil {
+					atomic.AddInt32(&errorCount, 1)
+					continue
+				}
+				fs.Infof(x.o, "Renamed to %q", x.newRemote)
+			}
+		}()
+	}
+	wg.Wait()
+	if errorCount > 0 {
+		return errors.Errorf("failed to rename %d files", errorCount)
+	}
+	return nil
+}
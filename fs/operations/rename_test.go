@@ -0,0 +1,88 @@
+package operations_test
+
+import (
+	"testing"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/operations"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRenameRegex(t *testing.T) {
+	for _, test := range []struct {
+		regex    string
+		fullPath bool
+		in       string
+		want     string
+		err      bool
+	}{
+		{regex: "", err: true},
+		{regex: "s/a/b", err: true},
+		{regex: "s/a/b/c/", err: true},
+		{regex: "s/a/b/x", err: true},
+		{regex: "s/(/b/", err: true},
+		{regex: "s/a/b/", in: "dir/aaa", want: "dir/baa"},
+		{regex: "s/a/b/g", in: "dir/aaa", want: "dir/bbb"},
+		{regex: "s/A/b/gi", in: "dir/aaa", want: "dir/bbb"},
+		{regex: "s/dir/sub/", in: "dir/dir", want: "dir/sub"},
+		{regex: "s/dir/sub/", fullPath: true, in: "dir/dir", want: "sub/dir"},
+		{regex: `s/^(.*)\.jpeg$/$1.jpg/`, in: "a.jpeg.jpeg", want: "a.jpeg.jpg"},
+		{regex: `s/(\w+) (\w+)/\2 \1/`, in: "hello world", want: "world hello"},
+		{regex: `s|/|-|g`, fullPath: true, in: "a/b/c", want: "a-b-c"},
+		{regex: `s/\//-/g`, fullPath: true, in: "a/b/c", want: "a-b-c"},
+		{regex: `s/.*//`, in: "dir/file", want: "dir"},
+	} {
+		what := test.regex + " on " + test.in
+		r, err := operations.NewRenameRegex(test.regex, test.fullPath)
+		if test.err {
+			assert.Error(t, err, what)
+			continue
+		}
+		require.NoError(t, err, what)
+		assert.Equal(t, test.want, r.NewRemote(test.in), what)
+	}
+}
+
+func TestRename(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("one.jpeg", "one", t1)
+	file2 := r.WriteObject("sub/two.JPEG", "two", t2)
+	file3 := r.WriteObject("three.jpeg", "three", t1)
+	file4 := r.WriteObject("three.jpg", "four", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	// renaming onto a directory isn't allowed
+	re, err := operations.NewRenameRegex(`s/.*//`, false)
+	require.NoError(t, err)
+	assert.Equal(t, "sub", re.NewRemote("sub/two.JPEG"))
+	err = operations.Rename(r.Fremote, re)
+	assert.Error(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	re, err = operations.NewRenameRegex(`s/\.jpeg$/.jpg/i`, false)
+	require.NoError(t, err)
+
+	fs.Config.DryRun = true
+	err = operations.Rename(r.Fremote, re)
+	fs.Config.DryRun = false
+	assert.Error(t, err) // three.jpg exists
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	err = operations.Rename(r.Fremote, re)
+	assert.Error(t, err)
+	file1.Path = "one.jpg"
+	file2.Path = "sub/two.jpg"
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+
+	// a target hidden by --max-depth isn't overwritten
+	re, err = operations.NewRenameRegex(`s/^one\.jpg$/sub\/two.jpg/`, true)
+	require.NoError(t, err)
+	fs.Config.MaxDepth = 1
+	err = operations.Rename(r.Fremote, re)
+	fs.Config.MaxDepth = -1
+	assert.Error(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+}
@@ -3,16 +3,23 @@
 package rc
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/obscure"
+	"github.com/ncw/rclone/fs/fspath"
+	"github.com/pkg/errors"
 )
 
 var (
 	fsCacheMu           sync.Mutex
 	fsCache             = map[string]*cacheEntry{}
-	fsNewFs             = fs.NewFs // for tests
+	createdRemotes      = map[string]*remoteDef{} // remotes made by fscache/create
+	fsNewFs             = fs.NewFs                // for tests
 	expireRunning       = false
 	cacheExpireDuration = 300 * time.Second // expire the cache entry when it is older than this
 	cacheExpireInterval = 60 * time.Second  // interval to run the cache expire
@@ -24,13 +31,53 @@ type cacheEntry struct {
 	lastUsed time.Time
 }
 
+// remoteDef is a remote made by fscache/create which isn't in the
+// config file
+type remoteDef struct {
+	fsInfo  *fs.RegInfo
+	expires time.Time // forget the remote at this time if set
+	mu      sync.Mutex
+	config  configmap.Simple // the parameters of the remote
+}
+
+// Get a config item for the remote
+func (def *remoteDef) Get(key string) (value string, ok bool) {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	return def.config.Get(key)
+}
+
+// Set a config item for the remote, eg when a token is refreshed
+func (def *remoteDef) Set(key, value string) {
+	def.mu.Lock()
+	defer def.mu.Unlock()
+	def.config.Set(key, value)
+}
+
+// newFs makes the Fs for fsString, using the remotes made by
+// fscache/create if necessary
+//
+// Call with fsCacheMu held
+func newFs(fsString string) (fs.Fs, error) {
+	configName, fsPath := fspath.Parse(fsString)
+	def := createdRemotes[configName]
+	if configName == "" || def == nil {
+		return fsNewFs(fsString)
+	}
+	m := configmap.New()
+	m.AddGetter(def)
+	m.AddGetter(fs.ConfigMap(def.fsInfo, configName))
+	m.AddSetter(def)
+	return def.fsInfo.NewFs(configName, fsPath, m)
+}
+
 // GetCachedFs gets a fs.Fs named fsString either from the cache or creates it afresh
 func GetCachedFs(fsString string) (f fs.Fs, err error) {
 	fsCacheMu.Lock()
 	defer fsCacheMu.Unlock()
 	entry, ok := fsCache[fsString]
 	if !ok {
-		f, err = fsNewFs(fsString)
+		f, err = newFs(fsString)
 		if err != nil {
 			return nil, err
 		}
@@ -63,17 +110,37 @@ func PutCachedFs(fsString string, f fs.Fs) {
 	}
 }
 
+// forgetRemote removes the remote made by fscache/create called name
+// and any Fs made from it from the cache
+//
+// Call with fsCacheMu held
+func forgetRemote(name string) {
+	delete(createdRemotes, name)
+	for fsString := range fsCache {
+		if configName, _ := fspath.Parse(fsString); configName == name {
+			delete(fsCache, fsString)
+		}
+	}
+}
+
 // cacheExpire expires any entries that haven't been used recently
+// and remotes made by fscache/create which have expired
 func cacheExpire() {
 	fsCacheMu.Lock()
 	defer fsCacheMu.Unlock()
 	now := time.Now()
+	for name, def := range createdRemotes {
+		if !def.expires.IsZero() && now.After(def.expires) {
+			fs.Debugf(nil, "Forgetting expired remote %q", name)
+			forgetRemote(name)
+		}
+	}
 	for fsString, entry := range fsCache {
 		if now.Sub(entry.lastUsed) > cacheExpireDuration {
 			delete(fsCache, fsString)
 		}
 	}
-	if len(fsCache) != 0 {
+	if len(fsCache) != 0 || len(createdRemotes) != 0 {
 		time.AfterFunc(cacheExpireInterval, cacheExpire)
 		expireRunning = true
 	} else {
@@ -115,3 +182,223 @@ func GetFsAndRemoteNamed(in Params, fsName, remoteName string) (f fs.Fs, remote
 func GetFsAndRemote(in Params) (f fs.Fs, remote string, err error) {
 	return GetFsAndRemoteNamed(in, "fs", "remote")
 }
+
+func init() {
+	Add(Call{
+		Path:  "fscache/list",
+		Fn:    rcFscacheList,
+		Title: "List the remotes in the cache",
+		Help: `
+Remotes made by rc commands are kept in a cache so they can be used
+again quickly.  Remotes which haven't been used for 5 minutes are
+removed from the cache.
+
+This returns
+
+- fses - a list of the cached remotes each with
+  - fs - the name of the remote as used in the "fs" parameter
+  - lastUsed - the time it was last used
+- remotes - a list of the remotes made with fscache/create each with
+  - name - the name of the remote
+  - type - the type of the remote
+  - expires - when the remote will be forgotten if set
+`,
+	})
+}
+
+// List the cached remotes
+func rcFscacheList(in Params) (out Params, err error) {
+	fsCacheMu.Lock()
+	defer fsCacheMu.Unlock()
+	fses := []Params{}
+	for fsString, entry := range fsCache {
+		fses = append(fses, Params{
+			"fs":       fsString,
+			"lastUsed": entry.lastUsed,
+		})
+	}
+	sort.Slice(fses, func(i, j int) bool {
+		return fses[i]["fs"].(string) < fses[j]["fs"].(string)
+	})
+	remotes := []Params{}
+	for name, def := range createdRemotes {
+		remote := Params{
+			"name": name,
+			"type": def.fsInfo.Name,
+		}
+		if !def.expires.IsZero() {
+			remote["expires"] = def.expires
+		}
+		remotes = append(remotes, remote)
+	}
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i]["name"].(string) < remotes[j]["name"].(string)
+	})
+	out = Params{
+		"fses":    fses,
+		"remotes": remotes,
+	}
+	return out, nil
+}
+
+func init() {
+	Add(Call{
+		Path:         "fscache/clear",
+		AuthRequired: true,
+		Fn:           rcFscacheClear,
+		Title:        "Clear the cache of remotes",
+		Help: `
+This takes the following parameters
+
+- fs - a remote name string eg "drive:" to remove from the cache (optional)
+- name - the name of a remote made with fscache/create to forget (optional)
+
+If neither is supplied then all the remotes are removed from the
+cache.  Remotes made with fscache/create will be made again when next
+used unless they are forgotten by passing their name.
+`,
+	})
+}
+
+// Clear the cache of remotes
+func rcFscacheClear(in Params) (out Params, err error) {
+	fsString, err := in.GetString("fs")
+	if NotErrParamNotFound(err) {
+		return nil, err
+	}
+	name, err := in.GetString("name")
+	if NotErrParamNotFound(err) {
+		return nil, err
+	}
+	fsCacheMu.Lock()
+	defer fsCacheMu.Unlock()
+	switch {
+	case name != "":
+		if createdRemotes[name] == nil {
+			return nil, errors.Errorf("no remote %q made with fscache/create", name)
+		}
+		forgetRemote(name)
+	case fsString != "":
+		delete(fsCache, fsString)
+	default:
+		fsCache = map[string]*cacheEntry{}
+	}
+	return nil, nil
+}
+
+func init() {
+	Add(Call{
+		Path:         "fscache/create",
+		AuthRequired: true,
+		Fn:           rcFscacheCreate,
+		Title:        "Make a remote without putting it in the config file",
+		Help: `
+This makes a remote which can be used in the "fs" parameter of other
+rc commands but which isn't saved in the config file, so credentials
+can be supplied by the caller and only kept in memory.
+
+This takes the following parameters
+
+- name - name of remote to make
+- type - type of the new remote
+- parameters - a map of { "key": "value" } pairs as used in the config file
+- passwords - a map of { "key": "value" } pairs of passwords in plain text (optional)
+- fs - path within the remote to check it works with (optional)
+- expire - forget the remote after this duration eg "1h" (optional)
+
+The remote is checked by making it with the path given in fs.  If this
+fails then the error is returned and the remote isn't made.  Making a
+remote with the same name replaces it.
+
+This returns
+
+- fs - the name of the remote to use in the "fs" parameter
+
+See the [config create command](/commands/rclone_config_create/) for
+more information on the parameters.
+`,
+	})
+}
+
+// Make a remote without putting it in the config file
+func rcFscacheCreate(in Params) (out Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	if !fspath.Matcher.MatchString(name+":") || name[0] == ':' {
+		return nil, errors.Errorf("invalid remote name %q", name)
+	}
+	remoteType, err := in.GetString("type")
+	if err != nil {
+		return nil, err
+	}
+	fsInfo, err := fs.Find(remoteType)
+	if err != nil {
+		return nil, err
+	}
+	def := &remoteDef{
+		fsInfo: fsInfo,
+		config: configmap.Simple{},
+	}
+	for _, key := range []string{"parameters", "passwords"} {
+		var values Params
+		err = in.GetStruct(key, &values)
+		if IsErrParamNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			value := fmt.Sprint(v)
+			if key == "passwords" {
+				value, err = obscure.Obscure(value)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to obscure %q", k)
+				}
+			}
+			def.config[k] = value
+		}
+	}
+	expire, err := in.GetString("expire")
+	if err == nil {
+		duration, err := fs.ParseDuration(expire)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad expire")
+		}
+		def.expires = time.Now().Add(duration)
+	} else if NotErrParamNotFound(err) {
+		return nil, err
+	}
+	fsPath, err := in.GetString("fs")
+	if NotErrParamNotFound(err) {
+		return nil, err
+	}
+	fsString := name + ":" + fsPath
+
+	fsCacheMu.Lock()
+	defer fsCacheMu.Unlock()
+	old := createdRemotes[name]
+	createdRemotes[name] = def
+	f, err := newFs(fsString)
+	if err != nil && err != fs.ErrorIsFile {
+		delete(createdRemotes, name)
+		if old != nil {
+			createdRemotes[name] = old
+		}
+		return nil, errors.Wrapf(err, "failed to make remote %q", name)
+	}
+	// Remove anything made with the old version of the remote
+	forgetRemote(name)
+	createdRemotes[name] = def
+	fsCache[fsString] = &cacheEntry{
+		f:        f,
+		fsString: fsString,
+		lastUsed: time.Now(),
+	}
+	if !expireRunning {
+		time.AfterFunc(cacheExpireInterval, cacheExpire)
+		expireRunning = true
+	}
+	return Params{"fs": fsString}, nil
+}
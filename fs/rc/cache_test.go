@@ -1,10 +1,13 @@
 package rc
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config/configmap"
+	"github.com/ncw/rclone/fs/config/obscure"
 	"github.com/ncw/rclone/fstest/mockfs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,6 +28,7 @@ func mockNewFs(t *testing.T) func() {
 		fsNewFs = oldFsNewFs
 		fsCacheMu.Lock()
 		fsCache = map[string]*cacheEntry{}
+		createdRemotes = map[string]*remoteDef{}
 		expireRunning = false
 		fsCacheMu.Unlock()
 	}
@@ -136,3 +140,102 @@ func TestGetFsAndRemote(t *testing.T) {
 	assert.NotNil(t, f)
 	assert.Equal(t, "hello", remote)
 }
+
+func TestFscache(t *testing.T) {
+	defer mockNewFs(t)()
+
+	// A backend which records how it was configured
+	var gotConfig map[string]string
+	fs.Register(&fs.RegInfo{
+		Name: "rcmock",
+		NewFs: func(name, root string, m configmap.Mapper) (fs.Fs, error) {
+			gotConfig = map[string]string{}
+			for _, key := range []string{"user", "pass", "region"} {
+				gotConfig[key], _ = m.Get(key)
+			}
+			if gotConfig["user"] == "bad" {
+				return nil, errors.New("bad user")
+			}
+			return mockfs.NewFs(name, root), nil
+		},
+		Options: []fs.Option{{
+			Name: "user",
+		}, {
+			Name:       "pass",
+			IsPassword: true,
+		}, {
+			Name:    "region",
+			Default: "eu",
+		}},
+	})
+
+	create := Calls.Get("fscache/create")
+	require.NotNil(t, create)
+	list := Calls.Get("fscache/list")
+	require.NotNil(t, list)
+	clear := Calls.Get("fscache/clear")
+	require.NotNil(t, clear)
+
+	out, err := create.Fn(Params{
+		"name":       "tmp",
+		"type":       "rcmock",
+		"parameters": Params{"user": "potato"},
+		"passwords":  Params{"pass": "secret"},
+		"fs":         "dir",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Params{"fs": "tmp:dir"}, out)
+	assert.Equal(t, "potato", gotConfig["user"])
+	assert.Equal(t, "eu", gotConfig["region"])
+	assert.Equal(t, "secret", obscure.MustReveal(gotConfig["pass"]))
+
+	// A failed create leaves the old remote
+	_, err = create.Fn(Params{
+		"name":       "tmp",
+		"type":       "rcmock",
+		"parameters": Params{"user": "bad"},
+	})
+	assert.Error(t, err)
+
+	// Other paths on the remote can be made
+	f, err := GetCachedFs("tmp:other")
+	require.NoError(t, err)
+	assert.Equal(t, "other", f.Root())
+	assert.Equal(t, "potato", gotConfig["user"])
+
+	out, err = list.Fn(nil)
+	require.NoError(t, err)
+	fses := out["fses"].([]Params)
+	require.Len(t, fses, 2)
+	assert.Equal(t, "tmp:dir", fses[0]["fs"])
+	assert.Equal(t, "tmp:other", fses[1]["fs"])
+	remotes := out["remotes"].([]Params)
+	require.Len(t, remotes, 1)
+	assert.Equal(t, Params{"name": "tmp", "type": "rcmock"}, remotes[0])
+
+	_, err = clear.Fn(Params{"fs": "tmp:other"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(fsCache))
+	_, err = clear.Fn(Params{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(fsCache))
+	assert.Equal(t, 1, len(createdRemotes))
+
+	_, err = clear.Fn(Params{"name": "tmp"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(createdRemotes))
+	_, err = clear.Fn(Params{"name": "tmp"})
+	assert.Error(t, err)
+
+	// Check expiry
+	_, err = create.Fn(Params{
+		"name":   "tmp",
+		"type":   "rcmock",
+		"expire": "1ms",
+	})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	cacheExpire()
+	assert.Equal(t, 0, len(createdRemotes))
+	assert.Equal(t, 0, len(fsCache))
+}
@@ -3,6 +3,7 @@
 package rc
 
 import (
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,8 +29,15 @@ type Job struct {
 	Success   bool      `json:"success"`
 	Duration  float64   `json:"duration"`
 	Output    Params    `json:"output"`
+	Group     string    `json:"group"`
+	ownGroup  bool      // set if Group was made for this job
 }
 
+// DeleteJobGroup is called to delete the stats group made for a job
+// when the job expires.  It is set up by the accounting package to
+// avoid an import loop.
+var DeleteJobGroup = func(group string) {}
+
 // Jobs describes a collection of running tasks
 type Jobs struct {
 	mu             sync.RWMutex
@@ -70,6 +78,9 @@ func (jobs *Jobs) Expire() {
 		job.mu.Lock()
 		if job.Finished && now.Sub(job.EndTime) > expireDuration {
 			delete(jobs.jobs, ID)
+			if job.ownGroup {
+				DeleteJobGroup(job.Group)
+			}
 		}
 		job.mu.Unlock()
 	}
@@ -99,6 +110,11 @@ func (jobs *Jobs) Get(ID int64) *Job {
 	return jobs.jobs[ID]
 }
 
+// GetJob returns the job with the given ID or nil if it doesn't exist
+func GetJob(ID int64) *Job {
+	return running.Get(ID)
+}
+
 // mark the job as finished
 func (job *Job) finish(out Params, err error) {
 	job.mu.Lock()
@@ -136,6 +152,14 @@ func (jobs *Jobs) NewJob(fn Func, in Params) *Job {
 		ID:        atomic.AddInt64(&jobID, 1),
 		StartTime: time.Now(),
 	}
+	// Account the job to its own stats group unless one was given
+	if group, err := in.GetString("_group"); err == nil {
+		job.Group = group
+	} else {
+		job.Group = fmt.Sprintf("job/%d", job.ID)
+		job.ownGroup = true
+		in["_group"] = job.Group
+	}
 	go job.run(fn, in)
 	jobs.mu.Lock()
 	jobs.jobs[job.ID] = job
@@ -166,6 +190,7 @@ Results
 - endTime - time the job finished (eg "2018-10-26T18:50:20.528746884+01:00")
 - error - error from the job or empty string for no error
 - finished - boolean whether the job has finished or not
+- group - name of the stats group the job is accounted to (eg "job/1")
 - id - as passed in above
 - startTime - time the job started (eg "2018-10-26T18:50:20.528336039+01:00")
 - success - boolean - true for success false otherwise
@@ -1,6 +1,7 @@
 package rc
 
 import (
+	"fmt"
 	"runtime"
 	"testing"
 	"time"
@@ -53,6 +54,22 @@ func TestJobsExpire(t *testing.T) {
 	jobs.mu.Unlock()
 }
 
+func TestJobsExpireGroup(t *testing.T) {
+	oldDeleteJobGroup := DeleteJobGroup
+	defer func() { DeleteJobGroup = oldDeleteJobGroup }()
+	var deleted []string
+	DeleteJobGroup = func(group string) {
+		deleted = append(deleted, group)
+	}
+	jobs := newJobs()
+	old := time.Now().Add(-expireDuration - 60*time.Second)
+	jobs.jobs[1] = &Job{ID: 1, Finished: true, EndTime: old, Group: "job/1", ownGroup: true}
+	jobs.jobs[2] = &Job{ID: 2, Finished: true, EndTime: old, Group: "mygroup"}
+	jobs.Expire()
+	assert.Equal(t, 0, len(jobs.jobs))
+	assert.Equal(t, []string{"job/1"}, deleted)
+}
+
 var noopFn = func(in Params) (Params, error) {
 	return nil, nil
 }
@@ -77,6 +94,19 @@ func TestJobsGet(t *testing.T) {
 	assert.Nil(t, jobs.Get(123123123123))
 }
 
+func TestJobsGroup(t *testing.T) {
+	jobs := newJobs()
+	in := Params{}
+	job := jobs.NewJob(noopFn, in)
+	assert.Equal(t, fmt.Sprintf("job/%d", job.ID), job.Group)
+	assert.Equal(t, job.Group, in["_group"])
+
+	in = Params{"_group": "mygroup"}
+	job = jobs.NewJob(noopFn, in)
+	assert.Equal(t, "mygroup", job.Group)
+	assert.Equal(t, "mygroup", in["_group"])
+}
+
 var longFn = func(in Params) (Params, error) {
 	time.Sleep(1 * time.Hour)
 	return nil, nil
@@ -0,0 +1,189 @@
+package rcschedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronSpec is a parsed cron expression
+//
+// Each field is a bitmask with bit n set if value n matches.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	every                         time.Duration // if set run at this interval instead
+}
+
+// cronField describes the allowed range of a cron field
+type cronField struct {
+	name     string
+	min, max uint
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// descriptors are the shorthand schedules which can be used instead
+// of the 5 fields
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// bit 63 is set on the dom and dow masks if the field was "*" so
+// that the standard cron rule of matching either if both are
+// restricted can be applied
+const starBit = 1 << 63
+
+// parseCron parses a cron expression with 5 space separated fields
+// (minute, hour, day of month, month, day of week), one of the
+// @descriptors, or "@every <duration>".
+func parseCron(spec string) (*cronSpec, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad schedule %q", spec)
+		}
+		if every < time.Second {
+			return nil, errors.Errorf("bad schedule %q: interval must be at least 1s", spec)
+		}
+		return &cronSpec{every: every}, nil
+	}
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("bad schedule %q: expecting %d fields but got %d", spec, len(cronFields), len(fields))
+	}
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad schedule %q", spec)
+		}
+		masks[i] = mask
+	}
+	// Sunday can be 0 or 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+	return &cronSpec{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+	}, nil
+}
+
+// parseCronField parses a comma separated list of "*", "n", "a-b"
+// each optionally followed by "/step" into a bitmask
+func parseCronField(field string, f cronField) (mask uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, uint(1)
+		if i := strings.IndexRune(part, '/'); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, errors.Errorf("bad step in %s %q", f.name, part)
+			}
+			step = uint(n)
+		}
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+			mask |= starBit
+		case strings.ContainsRune(rangePart, '-'):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			if lo, err = parseCronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, errors.Errorf("bad range in %s %q", f.name, part)
+			}
+		default:
+			if lo, err = parseCronValue(rangePart, f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if step != 1 {
+				// "n/step" means from n to the max
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// parseCronValue parses a single number checking it is in range
+func parseCronValue(s string, f cronField) (uint, error) {
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(n) < f.min || uint(n) > f.max {
+		return 0, errors.Errorf("bad %s %q: must be a number from %d to %d", f.name, s, f.min, f.max)
+	}
+	return uint(n), nil
+}
+
+// dayMatches returns true if the day of t matches the spec
+//
+// As with cron, if both day of month and day of week are restricted
+// then a day matches if either matches.
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.dom&starBit != 0 || c.dow&starBit != 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next returns the first time after t which matches the spec, or the
+// zero time if there isn't one in the next 5 years
+func (c *cronSpec) next(t time.Time) time.Time {
+	if c.every > 0 {
+		return t.Add(c.every)
+	}
+	// Start at the next whole minute
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+	for t.Year() <= yearLimit {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
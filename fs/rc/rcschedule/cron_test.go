@@ -0,0 +1,57 @@
+package rcschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"x * * * *",
+		"@every potato",
+		"@every 1ms",
+		"@fortnightly",
+	} {
+		_, err := parseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Friday
+	start := time.Date(2019, 3, 15, 10, 30, 15, 0, time.UTC)
+	for _, test := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2019, 3, 15, 10, 31, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2019, 3, 16, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"10,20 11-12 * * *", time.Date(2019, 3, 15, 11, 10, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2019, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2019, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 1", time.Date(2019, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{"@hourly", time.Date(2019, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"@every 90s", start.Add(90 * time.Second)},
+	} {
+		c, err := parseCron(test.spec)
+		require.NoError(t, err, test.spec)
+		assert.Equal(t, test.want, c.next(start), test.spec)
+	}
+}
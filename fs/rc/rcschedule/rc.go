@@ -0,0 +1,102 @@
+package rcschedule
+
+import (
+	"github.com/ncw/rclone/fs/rc"
+)
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "schedule/list",
+		Fn:    rcList,
+		Title: "Lists the schedules and their status.",
+		Help: `This lists the schedules loaded with --rc-schedule-file.
+
+Results
+- schedules - array of schedules each with
+  - name - name of the schedule
+  - schedule - the cron expression
+  - command - rc command run, eg "sync/sync"
+  - params - parameters passed to the command
+  - enabled - boolean whether the schedule will run
+  - running - boolean whether a run is in progress
+  - skipped - number of runs skipped as the previous run was still in progress
+  - nextRun - time the schedule is next due (only if enabled)
+  - lastRun - the log entry for the last run (if any) - see schedule/log
+`,
+	})
+	rc.Add(rc.Call{
+		Path:         "schedule/enable",
+		AuthRequired: true,
+		Fn: func(in rc.Params) (rc.Params, error) {
+			return rcSetDisabled(in, false)
+		},
+		Title: "Enables a schedule.",
+		Help: `Parameters
+- name - name of the schedule
+
+The schedule will next run at its next due time.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:         "schedule/disable",
+		AuthRequired: true,
+		Fn: func(in rc.Params) (rc.Params, error) {
+			return rcSetDisabled(in, true)
+		},
+		Title: "Disables a schedule.",
+		Help: `Parameters
+- name - name of the schedule
+
+Any run in progress is not stopped.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:  "schedule/log",
+		Fn:    rcLog,
+		Title: "Shows the recent runs of a schedule.",
+		Help: `Parameters
+- name - name of the schedule
+
+Results
+- log - array of the last 20 runs, oldest first, each with
+  - jobid - id of the job which did the run
+  - startTime - time the run started
+  - endTime - time the run finished
+  - duration - time in seconds the run took
+  - success - boolean - true for success false otherwise
+  - error - error from the run or empty string for no error
+  - stats - the stats for the run as returned by core/stats
+`,
+	})
+}
+
+// List the schedules
+func rcList(in rc.Params) (out rc.Params, err error) {
+	out = make(rc.Params)
+	out["schedules"] = scheduler.list()
+	return out, nil
+}
+
+// Enable or disable a schedule
+func rcSetDisabled(in rc.Params, disabled bool) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	return nil, scheduler.setDisabled(name, disabled)
+}
+
+// Show the log of a schedule
+func rcLog(in rc.Params) (out rc.Params, err error) {
+	name, err := in.GetString("name")
+	if err != nil {
+		return nil, err
+	}
+	log, err := scheduler.runLog(name)
+	if err != nil {
+		return nil, err
+	}
+	out = make(rc.Params)
+	out["log"] = log
+	return out, nil
+}
@@ -0,0 +1,309 @@
+// Package rcschedule runs rc commands on a schedule in the rc daemon
+package rcschedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+// maximum number of runs to keep in the log of each schedule
+const maxLogEntries = 20
+
+// Schedule describes an rc command to run on a cron like schedule
+type Schedule struct {
+	Name     string    `json:"name"`     // unique name of the schedule
+	Spec     string    `json:"schedule"` // cron expression, eg "0 2 * * *"
+	Command  string    `json:"command"`  // rc command to run, eg "sync/sync"
+	Params   rc.Params `json:"params"`   // parameters for the command
+	Disabled bool      `json:"disabled"` // set to not run the schedule
+
+	cron    *cronSpec
+	next    time.Time  // when the schedule is next due
+	running bool       // set if a run is in progress
+	skipped int64      // number of runs skipped as the previous one was running
+	log     []LogEntry // the most recent runs
+}
+
+// LogEntry records a single run of a Schedule
+type LogEntry struct {
+	JobID     int64     `json:"jobid"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Duration  float64   `json:"duration"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error"`
+	Stats     rc.Params `json:"stats"`
+}
+
+// Scheduler runs the Schedules it contains
+type Scheduler struct {
+	mu        sync.Mutex
+	schedules map[string]*Schedule
+	kick      chan struct{}
+	started   bool
+}
+
+// the global scheduler used by the rc calls
+var scheduler = newScheduler()
+
+// newScheduler makes a new, empty Scheduler
+func newScheduler() *Scheduler {
+	return &Scheduler{
+		schedules: make(map[string]*Schedule),
+		kick:      make(chan struct{}, 1),
+	}
+}
+
+// Load reads the schedules from the JSON file at path and starts
+// running them in the background
+func Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read schedule file")
+	}
+	var schedules []*Schedule
+	err = json.Unmarshal(data, &schedules)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse schedule file")
+	}
+	for _, sch := range schedules {
+		err = scheduler.add(sch)
+		if err != nil {
+			return err
+		}
+	}
+	scheduler.start()
+	return nil
+}
+
+// add checks sch and adds it to the scheduler
+func (s *Scheduler) add(sch *Schedule) (err error) {
+	if sch.Name == "" {
+		return errors.New("schedule must have a name")
+	}
+	sch.cron, err = parseCron(sch.Spec)
+	if err != nil {
+		return errors.Wrapf(err, "schedule %q", sch.Name)
+	}
+	if rc.Calls.Get(sch.Command) == nil {
+		return errors.Errorf("schedule %q: unknown rc command %q", sch.Name, sch.Command)
+	}
+	if sch.Params == nil {
+		sch.Params = rc.Params{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.schedules[sch.Name]; found {
+		return errors.Errorf("duplicate schedule %q", sch.Name)
+	}
+	sch.next = sch.cron.next(time.Now())
+	s.schedules[sch.Name] = sch
+	fs.Debugf(nil, "Schedule %q: next run at %v", sch.Name, sch.next)
+	return nil
+}
+
+// start runs the scheduler in the background if not already running
+func (s *Scheduler) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		s.started = true
+		go s.loop()
+	}
+}
+
+// loop starts the schedules as they become due
+func (s *Scheduler) loop() {
+	for {
+		next := s.runDue(time.Now())
+		wait := time.Hour
+		if !next.IsZero() && next.Sub(time.Now()) < wait {
+			wait = next.Sub(time.Now())
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.kick:
+			timer.Stop()
+		}
+	}
+}
+
+// kickLoop makes the loop recalculate when the schedules are next due
+func (s *Scheduler) kickLoop() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// runDue starts all the enabled schedules which are due at now and
+// returns the time the next one is due, or the zero time if none are
+func (s *Scheduler) runDue(now time.Time) (next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sch := range s.schedules {
+		if sch.Disabled || sch.next.IsZero() {
+			continue
+		}
+		if !now.Before(sch.next) {
+			if sch.running {
+				sch.skipped++
+				fs.Logf(nil, "Schedule %q: skipping run as previous run is still in progress", sch.Name)
+			} else {
+				s.startRun(sch)
+			}
+			sch.next = sch.cron.next(now)
+		}
+		if next.IsZero() || (!sch.next.IsZero() && sch.next.Before(next)) {
+			next = sch.next
+		}
+	}
+	return next
+}
+
+// startRun starts sch running as an rc job - call with s.mu held
+func (s *Scheduler) startRun(sch *Schedule) {
+	call := rc.Calls.Get(sch.Command)
+	if call == nil {
+		fs.Errorf(nil, "Schedule %q: unknown rc command %q", sch.Name, sch.Command)
+		return
+	}
+	// Copy the params as the job adds to them
+	in := make(rc.Params, len(sch.Params))
+	for k, v := range sch.Params {
+		in[k] = v
+	}
+	sch.running = true
+	entry := LogEntry{StartTime: time.Now()}
+	out, err := rc.StartJob(func(in rc.Params) (rc.Params, error) {
+		out, err := call.Fn(in)
+		s.finishRun(sch, entry, in, err)
+		return out, err
+	}, in)
+	if err != nil {
+		sch.running = false
+		fs.Errorf(nil, "Schedule %q: failed to start: %v", sch.Name, err)
+		return
+	}
+	fs.Infof(nil, "Schedule %q: started %s as job %v", sch.Name, sch.Command, out["jobid"])
+}
+
+// finishRun records the result of a run of sch in its log
+func (s *Scheduler) finishRun(sch *Schedule, entry LogEntry, in rc.Params, err error) {
+	entry.EndTime = time.Now()
+	entry.Duration = entry.EndTime.Sub(entry.StartTime).Seconds()
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+		fs.Errorf(nil, "Schedule %q: run failed: %v", sch.Name, err)
+	} else {
+		fs.Infof(nil, "Schedule %q: run finished successfully", sch.Name)
+	}
+	// Record the stats for the run, discarding the stats group if
+	// it was made for this job
+	if group, _ := in.GetString("_group"); group != "" {
+		entry.Stats, _ = accounting.StatsGroup(group).RemoteStats(nil)
+		entry.JobID = jobIDFromGroup(group)
+		if entry.JobID != 0 {
+			accounting.DeleteStatsGroup(group)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sch.running = false
+	if len(sch.log) >= maxLogEntries {
+		sch.log = sch.log[1:]
+	}
+	sch.log = append(sch.log, entry)
+}
+
+// jobIDFromGroup returns the job ID from a "job/<id>" group name or 0
+func jobIDFromGroup(group string) (ID int64) {
+	var n int64
+	if _, err := fmt.Sscanf(group, "job/%d", &n); err == nil {
+		ID = n
+	}
+	return ID
+}
+
+// get returns the schedule called name
+func (s *Scheduler) get(name string) (*Schedule, error) {
+	sch := s.schedules[name]
+	if sch == nil {
+		return nil, errors.Errorf("schedule %q not found", name)
+	}
+	return sch, nil
+}
+
+// setDisabled enables or disables the schedule called name
+func (s *Scheduler) setDisabled(name string, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sch, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	sch.Disabled = disabled
+	if !disabled {
+		sch.next = sch.cron.next(time.Now())
+	}
+	s.kickLoop()
+	return nil
+}
+
+// status returns the current state of sch - call with s.mu held
+func (sch *Schedule) status() rc.Params {
+	out := rc.Params{
+		"name":     sch.Name,
+		"schedule": sch.Spec,
+		"command":  sch.Command,
+		"params":   sch.Params,
+		"enabled":  !sch.Disabled,
+		"running":  sch.running,
+		"skipped":  sch.skipped,
+	}
+	if !sch.Disabled && !sch.next.IsZero() {
+		out["nextRun"] = sch.next
+	}
+	if len(sch.log) > 0 {
+		out["lastRun"] = sch.log[len(sch.log)-1]
+	}
+	return out
+}
+
+// list returns the status of all the schedules sorted by name
+func (s *Scheduler) list() []rc.Params {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.schedules))
+	for name := range s.schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]rc.Params, 0, len(names))
+	for _, name := range names {
+		out = append(out, s.schedules[name].status())
+	}
+	return out
+}
+
+// runLog returns a copy of the log of the schedule called name
+func (s *Scheduler) runLog(name string) ([]LogEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sch, err := s.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return append([]LogEntry(nil), sch.log...), nil
+}
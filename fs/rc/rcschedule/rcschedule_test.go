@@ -0,0 +1,99 @@
+package rcschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRelease = make(chan error)
+
+func init() {
+	rc.Add(rc.Call{
+		Path: "schedule/test",
+		Fn: func(in rc.Params) (rc.Params, error) {
+			return nil, <-testRelease
+		},
+	})
+}
+
+// wait for the schedule to stop running
+func waitFinished(t *testing.T, s *Scheduler, sch *Schedule) {
+	for i := 0; i < 100; i++ {
+		s.mu.Lock()
+		running := sch.running
+		s.mu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("schedule didn't finish")
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	s := newScheduler()
+	assert.Error(t, s.add(&Schedule{Spec: "* * * * *", Command: "schedule/test"}))
+	assert.Error(t, s.add(&Schedule{Name: "a", Spec: "potato", Command: "schedule/test"}))
+	assert.Error(t, s.add(&Schedule{Name: "a", Spec: "* * * * *", Command: "schedule/potato"}))
+	require.NoError(t, s.add(&Schedule{Name: "a", Spec: "* * * * *", Command: "schedule/test"}))
+	assert.Error(t, s.add(&Schedule{Name: "a", Spec: "* * * * *", Command: "schedule/test"}))
+}
+
+func TestSchedulerRun(t *testing.T) {
+	s := newScheduler()
+	sch := &Schedule{Name: "test", Spec: "@every 1m", Command: "schedule/test"}
+	require.NoError(t, s.add(sch))
+
+	// Not due yet
+	now := time.Now()
+	next := s.runDue(now)
+	assert.Equal(t, sch.next, next)
+	assert.False(t, sch.running)
+
+	// Due so starts running
+	now = now.Add(time.Minute)
+	s.runDue(now)
+	assert.True(t, sch.running)
+
+	// Due again but still running so is skipped
+	now = now.Add(time.Minute)
+	s.runDue(now)
+	assert.Equal(t, int64(1), sch.skipped)
+
+	testRelease <- errors.New("potato")
+	waitFinished(t, s, sch)
+	log, err := s.runLog("test")
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.False(t, log[0].Success)
+	assert.Equal(t, "potato", log[0].Error)
+	assert.NotEqual(t, int64(0), log[0].JobID)
+
+	// Disabled so doesn't run
+	require.NoError(t, s.setDisabled("test", true))
+	s.runDue(now.Add(time.Hour))
+	assert.False(t, sch.running)
+	assert.Error(t, s.setDisabled("potato", true))
+
+	// Enabled again
+	require.NoError(t, s.setDisabled("test", false))
+	s.runDue(now.Add(time.Hour))
+	assert.True(t, sch.running)
+	testRelease <- nil
+	waitFinished(t, s, sch)
+	log, err = s.runLog("test")
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	assert.True(t, log[1].Success)
+
+	list := s.list()
+	require.Len(t, list, 1)
+	assert.Equal(t, "test", list[0]["name"])
+	assert.Equal(t, true, list[0]["enabled"])
+	assert.Equal(t, int64(1), list[0]["skipped"])
+}
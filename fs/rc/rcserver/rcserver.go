@@ -111,7 +111,11 @@ func writeError(path string, in rc.Params, w http.ResponseWriter, err error, sta
 func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimLeft(r.URL.Path, "/")
 
-	w.Header().Add("Access-Control-Allow-Origin", "*")
+	allowOrigin := s.opt.HTTPOptions.AllowOrigin
+	if allowOrigin == "" {
+		allowOrigin = "*"
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
 
 	// echo back access control headers client needs
 	reqAccessHeaders := r.Header.Get("Access-Control-Request-Headers")
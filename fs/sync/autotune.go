@@ -0,0 +1,224 @@
+package sync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/lib/pacer"
+)
+
+// How often --auto-tune looks at what has happened - a var so the
+// tests can change it
+var autoTuneInterval = 10 * time.Second
+
+// autoTuner implements --auto-tune for the checkers or the transfers.
+//
+// The maximum number of workers are started and each must take a
+// slot with Acquire before doing anything and give it back with
+// Release.  The number of slots is adjusted every autoTuneInterval
+// depending on the low level retries, the errors, the throughput and
+// the time each item took.
+type autoTuner struct {
+	mu           sync.Mutex
+	cond         *sync.Cond
+	name         string       // "checkers" or "transfers" for the logs
+	progress     func() int64 // total progress, eg bytes transferred
+	limit        int          // number of slots
+	max          int          // maximum number of slots
+	active       int          // number of slots in use
+	stopped      bool         // set when the workers are finishing
+	stop         chan struct{}
+	done         int64         // items done since the last tune
+	took         time.Duration // total time taken by the items done since the last tune
+	busy         bool          // set if all the slots were in use since the last tune
+	grew         bool          // set if the last tune increased the limit
+	lastTune     time.Time     // when the last tune was done
+	lastProgress int64         // progress at the last tune
+	lastRate     float64       // progress per second in the last interval
+	lastRetries  int64         // low level retries at the last tune
+	lastErrors   int64         // errors at the last tune
+	bestLatency  time.Duration // the smallest average time per item seen
+}
+
+// newAutoTuner makes an autoTuner for the workers called name which
+// starts with start slots and can grow to max, measuring its progress
+// with progress, or returns nil if --auto-tune isn't in use.
+func newAutoTuner(name string, start, max int, progress func() int64) *autoTuner {
+	if !fs.Config.AutoTune {
+		return nil
+	}
+	if max < 1 {
+		max = 1
+	}
+	if start > max {
+		start = max
+	}
+	if start < 1 {
+		start = 1
+	}
+	t := &autoTuner{
+		name:     name,
+		progress: progress,
+		limit:    start,
+		max:      max,
+	}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// Workers returns the number of workers to start given that n would
+// be started without --auto-tune.
+//
+// It is safe to call on a nil *autoTuner.
+func (t *autoTuner) Workers(n int) int {
+	if t == nil {
+		return n
+	}
+	return t.max
+}
+
+// Start starts the tuning in the background.
+//
+// It is safe to call on a nil *autoTuner.
+func (t *autoTuner) Start() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.stopped = false
+	t.stop = make(chan struct{})
+	t.lastTune = time.Now()
+	t.lastProgress = t.progress()
+	t.lastRetries = pacer.LowLevelRetries()
+	t.lastErrors = accounting.Stats.GetErrors()
+	stop := t.stop
+	t.mu.Unlock()
+	ticker := time.NewTicker(autoTuneInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.tune(time.Now(), pacer.LowLevelRetries(), accounting.Stats.GetErrors())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the tuning and lets all the workers run so they can
+// see their input has finished.
+//
+// It is safe to call on a nil *autoTuner.
+func (t *autoTuner) Stop() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	if t.stop != nil {
+		close(t.stop)
+		t.stop = nil
+	}
+	t.cond.Broadcast()
+}
+
+// Acquire waits for a free slot and takes it.
+//
+// It is safe to call on a nil *autoTuner.
+func (t *autoTuner) Acquire() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	for !t.stopped && t.active >= t.limit {
+		t.cond.Wait()
+	}
+	t.active++
+	if t.active >= t.limit {
+		t.busy = true
+	}
+	t.mu.Unlock()
+}
+
+// Release gives back a slot taken with Acquire.  If start isn't zero
+// it is when the work done in the slot started.
+//
+// It is safe to call on a nil *autoTuner.
+func (t *autoTuner) Release(start time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.active--
+	if !start.IsZero() {
+		t.done++
+		t.took += time.Since(start)
+	}
+	t.cond.Signal()
+	t.mu.Unlock()
+}
+
+// tune adjusts the number of slots given the total low level retries
+// and errors at now.
+func (t *autoTuner) tune(now time.Time, retries, errors int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := t.progress()
+	var rate float64
+	if dt := now.Sub(t.lastTune).Seconds(); dt > 0 {
+		rate = float64(progress-t.lastProgress) / dt
+	}
+	var latency time.Duration
+	if t.done > 0 {
+		latency = t.took / time.Duration(t.done)
+		if t.bestLatency == 0 || latency < t.bestLatency {
+			t.bestLatency = latency
+		}
+	}
+	oldLimit := t.limit
+	reason := ""
+	switch {
+	case retries > t.lastRetries:
+		t.limit /= 2
+		reason = "remote is rate limiting"
+	case errors > t.lastErrors:
+		t.limit /= 2
+		reason = "errors occurred"
+	case t.grew && rate < t.lastRate*1.05:
+		t.limit--
+		reason = "the last increase didn't help"
+	case latency > 4*t.bestLatency:
+		t.limit--
+		reason = "remote is slowing down"
+	case t.busy:
+		t.limit++
+		reason = "all " + t.name + " busy"
+	}
+	if t.limit < 1 {
+		t.limit = 1
+	}
+	if t.limit > t.max {
+		t.limit = t.max
+	}
+	if t.limit != oldLimit {
+		fs.Infof(nil, "Auto tune: %s %d -> %d as %s", t.name, oldLimit, t.limit, reason)
+		t.cond.Broadcast()
+	}
+	t.grew = t.limit > oldLimit
+	t.busy = t.active >= t.limit
+	t.done = 0
+	t.took = 0
+	t.lastTune = now
+	t.lastProgress = progress
+	t.lastRate = rate
+	t.lastRetries = retries
+	t.lastErrors = errors
+}
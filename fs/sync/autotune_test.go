@@ -0,0 +1,123 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoTunerOff(t *testing.T) {
+	assert.Nil(t, newAutoTuner("checkers", 8, 64, func() int64 { return 0 }))
+	var tuner *autoTuner
+	assert.Equal(t, 8, tuner.Workers(8))
+	tuner.Start()
+	tuner.Acquire()
+	tuner.Release(time.Now())
+	tuner.Stop()
+}
+
+func TestAutoTunerTune(t *testing.T) {
+	fs.Config.AutoTune = true
+	defer func() { fs.Config.AutoTune = false }()
+
+	var progress int64
+	tuner := newAutoTuner("transfers", 4, 6, func() int64 { return progress })
+	require.NotNil(t, tuner)
+	assert.Equal(t, 6, tuner.Workers(4))
+	now := time.Now()
+	tuner.lastTune = now
+
+	// tune with all the slots busy and the given progress,
+	// retries and errors
+	tune := func(newProgress, retries, errors int64) int {
+		for i := 0; i < tuner.limit; i++ {
+			tuner.Acquire()
+		}
+		for i := 0; i < tuner.limit; i++ {
+			tuner.Release(time.Time{})
+		}
+		now = now.Add(time.Second)
+		progress = newProgress
+		tuner.tune(now, retries, errors)
+		return tuner.limit
+	}
+
+	// all busy and going faster so grow
+	assert.Equal(t, 5, tune(100, 0, 0))
+	assert.Equal(t, 6, tune(300, 0, 0))
+	// limited to max
+	assert.Equal(t, 6, tune(600, 0, 0))
+	// rate limited so halve
+	assert.Equal(t, 3, tune(900, 1, 0))
+	// errors so halve
+	assert.Equal(t, 1, tune(1200, 1, 1))
+	// never below 1
+	assert.Equal(t, 1, tune(1500, 2, 1))
+	// grow again
+	assert.Equal(t, 2, tune(1800, 2, 1))
+	// the increase didn't help so undo it
+	assert.Equal(t, 1, tune(2000, 2, 1))
+
+	// items taking much longer than the best seen so shrink
+	tuner.limit = 4
+	tuner.grew = false
+	tuner.bestLatency = time.Second
+	tuner.done = 1
+	tuner.took = 5 * time.Second
+	tuner.tune(now.Add(time.Second), 2, 1)
+	assert.Equal(t, 3, tuner.limit)
+}
+
+func TestAutoTunerStop(t *testing.T) {
+	fs.Config.AutoTune = true
+	defer func() { fs.Config.AutoTune = false }()
+
+	tuner := newAutoTuner("checkers", 1, 1, func() int64 { return 0 })
+	tuner.Start()
+	tuner.Acquire()
+	done := make(chan struct{})
+	go func() {
+		tuner.Acquire()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Acquire didn't wait for a free slot")
+	case <-time.After(10 * time.Millisecond):
+	}
+	tuner.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop didn't release the waiting Acquire")
+	}
+}
+
+// Test a sync with --auto-tune
+func TestSyncAutoTune(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fs.Config.AutoTune = true
+	oldInterval := autoTuneInterval
+	autoTuneInterval = time.Millisecond
+	defer func() {
+		fs.Config.AutoTune = false
+		autoTuneInterval = oldInterval
+	}()
+
+	var files []fstest.Item
+	for _, name := range []string{"one", "two", "three", "sub/four", "sub/five"} {
+		files = append(files, r.WriteFile(name, "contents of "+name, t1))
+	}
+
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal, files...)
+	fstest.CheckItems(t, r.Fremote, files...)
+}
@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"sync/atomic"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+)
+
+// deleteGuard stops sync deleting the files on the destination if
+// the source is empty and --refuse-empty-source is set, or if more
+// than --max-delete-percent of the destination files would be
+// deleted.  This protects the destination when the source is
+// unexpectedly empty, eg because a disk wasn't mounted.
+//
+// The files to delete are only known once both sides have been
+// listed so the deletes are done after the transfers when it is in
+// use.
+type deleteGuard struct {
+	srcObjects int64 // number of objects seen on the source - use atomic
+	dstObjects int64 // number of objects seen on the destination - use atomic
+}
+
+// newDeleteGuard makes a guard for the deletes or returns nil if it
+// isn't needed
+func newDeleteGuard(deleteMode fs.DeleteMode) *deleteGuard {
+	if deleteMode == fs.DeleteModeOff || (!fs.Config.RefuseEmptySource && fs.Config.MaxDeletePercent < 0) {
+		return nil
+	}
+	return &deleteGuard{}
+}
+
+// AddSrc records an object seen on the source
+//
+// It is safe to call on a nil *deleteGuard.
+func (g *deleteGuard) AddSrc() {
+	if g == nil {
+		return
+	}
+	atomic.AddInt64(&g.srcObjects, 1)
+}
+
+// AddDst records an object seen on the destination
+//
+// It is safe to call on a nil *deleteGuard.
+func (g *deleteGuard) AddDst() {
+	if g == nil {
+		return
+	}
+	atomic.AddInt64(&g.dstObjects, 1)
+}
+
+// Check returns a fatal error if toDelete files on the destination
+// shouldn't be deleted.
+//
+// It is safe to call on a nil *deleteGuard.
+func (g *deleteGuard) Check(toDelete int) error {
+	if g == nil || toDelete == 0 {
+		return nil
+	}
+	srcObjects, dstObjects := atomic.LoadInt64(&g.srcObjects), atomic.LoadInt64(&g.dstObjects)
+	if fs.Config.RefuseEmptySource && srcObjects == 0 {
+		return fserrors.FatalError(errors.Errorf("not deleting %d files as the source is empty and --refuse-empty-source is set", toDelete))
+	}
+	if fs.Config.MaxDeletePercent >= 0 && dstObjects > 0 {
+		percent := 100 * float64(toDelete) / float64(dstObjects)
+		if percent > float64(fs.Config.MaxDeletePercent) {
+			return fserrors.FatalError(errors.Errorf("not deleting %d of the %d files on the destination (%.1f%%) as it is more than --max-delete-percent %d%%", toDelete, dstObjects, percent, fs.Config.MaxDeletePercent))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package sync
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/operations"
+)
+
+// dirRenames finds the directories which have been renamed on the
+// source for --track-renames so they can be renamed on the
+// destination with a single server side DirMove instead of renaming
+// each of the files in them.
+//
+// A directory which is only on the source is a rename of a directory
+// which is only on the destination if they contain the same files
+// and directories with the same sizes and hashes.
+type dirRenames struct {
+	mu  sync.Mutex
+	src map[string]struct{} // directories only on the source
+	dst map[string]struct{} // directories only on the destination
+}
+
+// dirTree is the contents of a directory which is only on one side
+type dirTree struct {
+	dir     string               // the directory
+	objects map[string]fs.Object // the objects in it by path relative to dir
+	dirs    []string             // the directories in it relative to dir
+}
+
+// newDirRenames returns a dirRenames for syncing to fdst or nil if
+// directories can't be renamed.
+//
+// Directories are only renamed if the destination supports DirMove
+// and no filters are in use in fi, as any files excluded by the
+// filters would be moved too.
+func newDirRenames(fdst fs.Fs, trackRenames bool, fi *filter.Filter) *dirRenames {
+	if !trackRenames || fdst.Features().DirMove == nil || !fi.InActive() {
+		return nil
+	}
+	return &dirRenames{
+		src: make(map[string]struct{}),
+		dst: make(map[string]struct{}),
+	}
+}
+
+// AddSrc records a directory which is only on the source
+//
+// It is safe to call on a nil *dirRenames.
+func (d *dirRenames) AddSrc(dir string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.src[dir] = struct{}{}
+	d.mu.Unlock()
+}
+
+// AddDst records a directory which is only on the destination
+//
+// It is safe to call on a nil *dirRenames.
+func (d *dirRenames) AddDst(dir string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.dst[dir] = struct{}{}
+	d.mu.Unlock()
+}
+
+// parentDir returns the directory containing remote, "" for the root
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// isIn returns true if remote is dir or is inside it
+func isIn(remote, dir string) bool {
+	return remote == dir || strings.HasPrefix(remote, dir+"/")
+}
+
+// trees returns the topmost directories in dirs, which are the ones
+// whose parent isn't in dirs, with the objects passed in sorted into
+// them.
+func trees(dirs map[string]struct{}, objects []fs.Object) map[string]*dirTree {
+	tops := make(map[string]*dirTree)
+	for dir := range dirs {
+		if _, ok := dirs[parentDir(dir)]; !ok {
+			tops[dir] = &dirTree{
+				dir:     dir,
+				objects: make(map[string]fs.Object),
+			}
+		}
+	}
+	// top finds the topmost directory containing remote if any
+	top := func(remote string) *dirTree {
+		for dir := parentDir(remote); dir != ""; dir = parentDir(dir) {
+			if t, ok := tops[dir]; ok {
+				return t
+			}
+		}
+		return nil
+	}
+	for dir := range dirs {
+		if t := top(dir); t != nil {
+			t.dirs = append(t.dirs, dir[len(t.dir)+1:])
+		}
+	}
+	for _, o := range objects {
+		if t := top(o.Remote()); t != nil {
+			t.objects[o.Remote()[len(t.dir)+1:]] = o
+		}
+	}
+	return tops
+}
+
+// key makes a string from the names and sizes of the contents of the
+// tree which is the same for trees which might be renames of each
+// other, or "" if the tree has no files.
+func (t *dirTree) key() string {
+	if len(t.objects) == 0 {
+		return ""
+	}
+	var entries []string
+	for _, dir := range t.dirs {
+		entries = append(entries, dir+"/")
+	}
+	for remote, o := range t.objects {
+		entries = append(entries, remote+"\x00"+strconv.FormatInt(o.Size(), 10))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\x00")
+}
+
+// sameHashes returns true if all the objects in src have the same
+// hash as the object with the same name in dst.
+func (s *syncCopyMove) sameHashes(src, dst *dirTree) bool {
+	for remote, srcObj := range src.objects {
+		dstObj := dst.objects[remote]
+		s.checking(srcObj.Remote())
+		srcHash := s.renameHash(srcObj)
+		dstHash := ""
+		if srcHash != "" {
+			dstHash = s.renameHash(dstObj)
+		}
+		s.doneChecking(srcObj.Remote())
+		if srcHash == "" || srcHash != dstHash {
+			return false
+		}
+	}
+	return true
+}
+
+// renameDirs renames the directories on the destination which have
+// been renamed on the source for --track-renames.
+//
+// The files in the renamed directories are removed from
+// s.renameCheck and s.dstFiles so they aren't renamed or deleted
+// again.
+func (s *syncCopyMove) renameDirs() {
+	d := s.dirRenames
+	if d == nil || len(d.src) == 0 || len(d.dst) == 0 {
+		return
+	}
+	fs.Infof(s.fdst, "Looking for renamed directories for --track-renames")
+	var dstObjects []fs.Object
+	for _, o := range s.dstFiles {
+		dstObjects = append(dstObjects, o)
+	}
+	srcTrees := trees(d.src, s.renameCheck)
+	dstTrees := trees(d.dst, dstObjects)
+
+	// Index the destination trees by key
+	dstByKey := make(map[string][]*dirTree)
+	for _, t := range dstTrees {
+		if key := t.key(); key != "" {
+			dstByKey[key] = append(dstByKey[key], t)
+		}
+	}
+
+	// Rename the destination trees which match a source tree
+	renamed := make(map[fs.Object]struct{})
+	for _, srcTree := range srcTrees {
+		if s.aborting() {
+			break
+		}
+		key := srcTree.key()
+		if key == "" {
+			continue
+		}
+		candidates := dstByKey[key]
+		for i, dstTree := range candidates {
+			if !s.sameHashes(srcTree, dstTree) {
+				continue
+			}
+			if !s.renameDir(srcTree, dstTree) {
+				break
+			}
+			dstByKey[key] = append(candidates[:i:i], candidates[i+1:]...)
+			for _, o := range srcTree.objects {
+				renamed[o] = struct{}{}
+			}
+			break
+		}
+	}
+	if len(renamed) == 0 {
+		return
+	}
+
+	// Remove the objects which have been renamed
+	renameCheck := s.renameCheck[:0]
+	for _, o := range s.renameCheck {
+		if _, ok := renamed[o]; !ok {
+			renameCheck = append(renameCheck, o)
+		}
+	}
+	s.renameCheck = renameCheck
+}
+
+// renameDir renames the dst directory to the name of the src
+// directory, returning true if it was renamed.
+func (s *syncCopyMove) renameDir(src, dst *dirTree) bool {
+	var size int64
+	for _, o := range src.objects {
+		size += o.Size()
+	}
+	if fs.Config.DryRun {
+		fs.Logf(fs.LogDirName(s.fdst, dst.dir), "Not renaming directory to %q as --dry-run", src.dir)
+	} else {
+		err := operations.DirMove(s.fdst, dst.dir, src.dir)
+		if err != nil {
+			fs.Debugf(fs.LogDirName(s.fdst, dst.dir), "Failed to rename directory to %q: %v", src.dir, err)
+			return false
+		}
+		accounting.Stats.ServerSideMove(size)
+		if s.group != nil {
+			s.group.ServerSideMove(size)
+		}
+	}
+	for remote, o := range src.objects {
+		s.plan.Record(planRename, o, path.Join(dst.dir, remote))
+	}
+
+	// remove the files and directories from dstFiles and dstEmptyDirs
+	s.dstFilesMu.Lock()
+	for remote := range s.dstFiles {
+		if isIn(remote, dst.dir) {
+			delete(s.dstFiles, remote)
+		}
+	}
+	s.dstFilesMu.Unlock()
+	s.dstEmptyDirsMu.Lock()
+	for remote := range s.dstEmptyDirs {
+		if isIn(remote, dst.dir) {
+			delete(s.dstEmptyDirs, remote)
+		}
+	}
+	s.dstEmptyDirsMu.Unlock()
+
+	fs.Infof(fs.LogDirName(s.fdst, src.dir), "Renamed directory from %q", dst.dir)
+	return true
+}
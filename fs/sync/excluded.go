@@ -0,0 +1,85 @@
+package sync
+
+import (
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/filter"
+)
+
+// excludedGuard stops --delete-excluded deleting the files on the
+// destination which are excluded by the filters unless --force is
+// set.  Instead they are logged with a summary at the end of the run
+// so the user can check what would be deleted.
+type excludedGuard struct {
+	mu         sync.Mutex
+	f          fs.Fs
+	fi         *filter.Filter
+	includeDir func(string) (bool, error)
+	files      int   // number of excluded files not deleted
+	bytes      int64 // total size of the excluded files not deleted
+}
+
+// newExcludedGuard makes a guard for --delete-excluded with fi on f
+// or returns nil if it isn't needed
+func newExcludedGuard(f fs.Fs, fi *filter.Filter) *excludedGuard {
+	if !fi.Opt.DeleteExcluded || fs.Config.Force || fs.Config.DryRun {
+		return nil
+	}
+	return &excludedGuard{
+		f:          f,
+		fi:         fi,
+		includeDir: fi.IncludeDirectory(f),
+	}
+}
+
+// KeepObject returns true if o is excluded by the filters and so
+// must be kept, recording it for the summary.
+//
+// It is safe to call on a nil *excludedGuard.
+func (g *excludedGuard) KeepObject(o fs.Object) bool {
+	if g == nil || g.fi.IncludeObject(o) {
+		return false
+	}
+	fs.Logf(o, "Not deleting excluded file as --force not set")
+	g.mu.Lock()
+	g.files++
+	if size := o.Size(); size > 0 {
+		g.bytes += size
+	}
+	g.mu.Unlock()
+	return true
+}
+
+// KeepDir returns true if the directory remote is excluded by the
+// filters and so must be kept.
+//
+// It is safe to call on a nil *excludedGuard.
+func (g *excludedGuard) KeepDir(remote string) bool {
+	if g == nil {
+		return false
+	}
+	include, err := g.includeDir(remote)
+	if err != nil {
+		// keep the directory if we can't tell
+		fs.Debugf(remote, "Failed to read directory filter: %v", err)
+		return true
+	}
+	return !include
+}
+
+// Report logs the summary of the excluded files which weren't
+// deleted.
+//
+// It is safe to call on a nil *excludedGuard.
+func (g *excludedGuard) Report() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.files == 0 {
+		return
+	}
+	fs.Logf(g.f, "--delete-excluded would delete %d files (%v) excluded by filters - use --force to delete them", g.files, fs.SizeSuffix(g.bytes))
+}
@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/fserrors"
+	"github.com/pkg/errors"
+)
+
+// ErrorMinFreeSpace is returned for each file not transferred
+// because it would take the free space on the destination below the
+// limit set by --min-free-space
+var ErrorMinFreeSpace = fserrors.NoRetryError(errors.New("Destination free space below limit set by --min-free-space"))
+
+// How often to read the free space from the destination
+const freeSpaceCheckInterval = 10 * time.Second
+
+// freeSpace guards against filling up the destination by checking
+// its free space before each transfer.
+//
+// The free space is only read from the backend every
+// freeSpaceCheckInterval and in between the size of the transfers
+// started since is deducted from it.
+type freeSpace struct {
+	mu       sync.Mutex
+	f        fs.Fs
+	min      int64     // minimum free space to leave
+	free     int64     // free space at last check less the size of transfers since
+	unknown  bool      // set if the free space couldn't be read at the last check
+	lastRead time.Time // when free was last read from the backend
+	disabled bool      // set if the backend can't report its free space
+}
+
+// newFreeSpace makes a free space guard for f or returns nil if
+// --min-free-space isn't in use
+func newFreeSpace(f fs.Fs) *freeSpace {
+	if fs.Config.MinFreeSpace < 0 {
+		return nil
+	}
+	return &freeSpace{
+		f:   f,
+		min: int64(fs.Config.MinFreeSpace),
+	}
+}
+
+// read the free space from the backend - call with the lock held
+func (s *freeSpace) read() {
+	do := s.f.Features().About
+	if do == nil {
+		fs.Errorf(s.f, "Ignoring --min-free-space as the destination can't report its free space")
+		s.disabled = true
+		return
+	}
+	s.lastRead = time.Now()
+	usage, err := do()
+	if err != nil {
+		// This may be temporary, eg if the destination
+		// doesn't exist yet, so try again next time
+		fs.Debugf(s.f, "Failed to read free space for --min-free-space: %v", err)
+		s.unknown = true
+		return
+	}
+	if usage.Free == nil {
+		fs.Errorf(s.f, "Ignoring --min-free-space as the destination doesn't report its free space")
+		s.disabled = true
+		return
+	}
+	s.free = *usage.Free
+	s.unknown = false
+	fs.Debugf(s.f, "Free space is %v", fs.SizeSuffix(s.free))
+}
+
+// Check returns ErrorMinFreeSpace if transferring size bytes would
+// leave less than the minimum free space on the destination.
+//
+// It is safe to call on a nil *freeSpace.
+func (s *freeSpace) Check(size int64) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.disabled && time.Since(s.lastRead) >= freeSpaceCheckInterval {
+		s.read()
+	}
+	if s.disabled || s.unknown {
+		return nil
+	}
+	if size < 0 {
+		size = 0
+	}
+	if s.free-size < s.min {
+		return ErrorMinFreeSpace
+	}
+	s.free -= size
+	return nil
+}
@@ -0,0 +1,272 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// hardLinks recreates the files which are hard linked together on
+// the source as hard links on the destination for --hard-links.
+//
+// If the destination can't make hard links the groups of linked
+// files are written to --hard-links-manifest instead, and if the
+// source can't read them they are read from it.
+type hardLinks struct {
+	link     func(src fs.Object, remote string) (fs.Object, error) // make a hard link on the destination, nil if not supported
+	manifest string                                                // --hard-links-manifest to write, "" if not writing
+	ids      map[string]string                                     // group IDs by remote read from --hard-links-manifest
+	mu       sync.Mutex                                            // protect the below
+	groups   map[string]*hardLinkGroup                             // the groups of linked files by ID
+	order    []string                                              // IDs of the groups in the order found
+}
+
+// hardLinkGroup is a group of source files which are hard linked
+// together
+type hardLinkGroup struct {
+	leader  string        // remote of the file being transferred for the group
+	done    chan struct{} // closed when target is set
+	target  fs.Object     // destination file to link to, nil if none
+	remotes []string      // remotes of the source files in the group
+}
+
+// newHardLinks makes a hardLinks for copying to fdst or returns nil
+// if --hard-links isn't in use.
+func newHardLinks(fdst fs.Fs, DoMove bool) (*hardLinks, error) {
+	if !fs.Config.HardLinks {
+		return nil, nil
+	}
+	if DoMove {
+		fs.Errorf(fdst, "Ignoring --hard-links as it doesn't work with move, only copy or sync")
+		return nil, nil
+	}
+	h := &hardLinks{
+		link:   fdst.Features().HardLink,
+		groups: make(map[string]*hardLinkGroup),
+	}
+	switch {
+	case h.link == nil && fs.Config.HardLinksManifest == "":
+		fs.Errorf(fdst, "Ignoring --hard-links as the destination can't make hard links and --hard-links-manifest isn't set")
+		return nil, nil
+	case h.link == nil:
+		fs.Infof(fdst, "Destination can't make hard links so recording them in --hard-links-manifest")
+		h.manifest = fs.Config.HardLinksManifest
+	case fs.Config.HardLinksManifest != "":
+		var err error
+		h.ids, err = readHardLinksManifest(fs.Config.HardLinksManifest)
+		if os.IsNotExist(errors.Cause(err)) {
+			fs.Debugf(nil, "Not reading --hard-links-manifest: %v", err)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// readHardLinksManifest reads the groups of linked files from path,
+// returning a group ID for each remote in a group.
+//
+// Each line of the file is a JSON list of the remotes in a group.
+func readHardLinksManifest(path string) (ids map[string]string, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read --hard-links-manifest")
+	}
+	ids = make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var remotes []string
+		err = json.Unmarshal(scanner.Bytes(), &remotes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse --hard-links-manifest line %d", line)
+		}
+		id := "manifest:" + strconv.Itoa(line)
+		for _, remote := range remotes {
+			ids[remote] = id
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read --hard-links-manifest")
+	}
+	return ids, nil
+}
+
+// id returns the ID of the group src is in or "" if it isn't hard
+// linked to anything
+func (h *hardLinks) id(src fs.Object) string {
+	o := src
+	for {
+		if do, ok := o.(fs.HardLinkIDer); ok {
+			id, links := do.HardLinkID()
+			if id != "" && links > 1 {
+				return id
+			}
+			break
+		}
+		do, ok := o.(fs.ObjectUnWrapper)
+		if !ok || do.UnWrap() == nil {
+			break
+		}
+		o = do.UnWrap()
+	}
+	return h.ids[src.Remote()]
+}
+
+// join adds src to the group with id, making the group if necessary.
+// It returns the group and whether it was made.
+//
+// Call with h.mu held.
+func (h *hardLinks) join(id string, src fs.Object) (g *hardLinkGroup, made bool) {
+	g = h.groups[id]
+	if g == nil {
+		g = &hardLinkGroup{
+			done: make(chan struct{}),
+		}
+		h.groups[id] = g
+		h.order = append(h.order, id)
+		made = true
+	}
+	g.remotes = append(g.remotes, src.Remote())
+	return g, made
+}
+
+// Unchanged notes that src didn't need transferring as it is the
+// same as dst so dst may be linked to by the rest of its group.
+//
+// It is safe to call on a nil *hardLinks.
+func (h *hardLinks) Unchanged(src, dst fs.Object) {
+	if h == nil {
+		return
+	}
+	id := h.id(src)
+	if id == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g, made := h.join(id, src)
+	if made {
+		g.target = dst
+		close(g.done)
+	}
+}
+
+// Start is called before src is transferred.
+//
+// If src is the first of its group to be transferred or isn't hard
+// linked then it returns linked false and src should be transferred
+// then Done called.
+//
+// Otherwise it waits for the first of the group to be transferred,
+// and makes src a hard link to it on the destination returning the
+// new object and linked true.  If that isn't possible it returns
+// linked false and src should be transferred.
+//
+// It is safe to call on a nil *hardLinks.
+func (h *hardLinks) Start(ctx context.Context, src fs.Object) (dst fs.Object, linked bool) {
+	if h == nil {
+		return nil, false
+	}
+	id := h.id(src)
+	if id == "" {
+		return nil, false
+	}
+	h.mu.Lock()
+	g, made := h.join(id, src)
+	if made {
+		g.leader = src.Remote()
+	}
+	h.mu.Unlock()
+	if made || h.link == nil {
+		return nil, false
+	}
+	select {
+	case <-g.done:
+	case <-ctx.Done():
+		return nil, false
+	}
+	if fs.Config.DryRun {
+		fs.Logf(src, "Not hard linking as --dry-run")
+		return nil, true
+	}
+	if g.target == nil {
+		return nil, false
+	}
+	dst, err := h.link(g.target, src.Remote())
+	if err != nil {
+		fs.Debugf(src, "Failed to hard link to %q so copying: %v", g.target.Remote(), err)
+		return nil, false
+	}
+	fs.Infof(dst, "Hard linked to %q", g.target.Remote())
+	return dst, true
+}
+
+// Done is called when src has been transferred to dst, which is nil
+// if the transfer failed.
+//
+// It is safe to call on a nil *hardLinks.
+func (h *hardLinks) Done(src, dst fs.Object) {
+	if h == nil {
+		return
+	}
+	id := h.id(src)
+	if id == "" {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	g := h.groups[id]
+	if g == nil || g.leader != src.Remote() {
+		return
+	}
+	g.leader = ""
+	g.target = dst
+	close(g.done)
+}
+
+// Close writes --hard-links-manifest if the destination can't make
+// hard links.
+//
+// It is safe to call on a nil *hardLinks.
+func (h *hardLinks) Close() error {
+	if h == nil || h.manifest == "" {
+		return nil
+	}
+	if fs.Config.DryRun {
+		fs.Logf(nil, "Not writing --hard-links-manifest as --dry-run")
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, id := range h.order {
+		remotes := h.groups[id].remotes
+		if len(remotes) < 2 {
+			continue
+		}
+		sort.Strings(remotes)
+		err := encoder.Encode(remotes)
+		if err != nil {
+			return errors.Wrap(err, "failed to write --hard-links-manifest")
+		}
+	}
+	err := ioutil.WriteFile(h.manifest, buf.Bytes(), 0666)
+	if err != nil {
+		return errors.Wrap(err, "failed to write --hard-links-manifest")
+	}
+	return nil
+}
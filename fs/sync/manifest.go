@@ -0,0 +1,182 @@
+package sync
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/ncw/rclone/fs/walk"
+	"github.com/pkg/errors"
+)
+
+// Actions recorded in the manifest
+const (
+	manifestUnchanged = "unchanged"
+	manifestCopied    = "copied"
+	manifestMoved     = "moved"
+	manifestDeleted   = "deleted"
+	manifestError     = "error"
+	manifestConflict  = "conflict" // recorded as well as the action taken
+)
+
+// manifestItem is a line in the manifest
+type manifestItem struct {
+	Action   string
+	Path     string
+	Size     int64
+	ModTime  string
+	HashType string `json:",omitempty"`
+	Hash     string `json:",omitempty"`
+}
+
+// manifest records the files examined and transferred by a sync into
+// a JSONL or CSV file set with --manifest
+type manifest struct {
+	mu       sync.Mutex
+	out      *os.File
+	csv      *csv.Writer   // set if writing CSV
+	json     *json.Encoder // set if writing JSONL
+	hashType hash.Type     // hash to record
+	err      error         // first error writing the manifest
+}
+
+// newManifest creates the manifest file set by --manifest recording
+// hashes of hashType, or returns nil if it isn't in use
+//
+// The manifest is written as CSV if the file name ends in .csv and
+// as JSON lines otherwise.
+func newManifest(hashType hash.Type) (*manifest, error) {
+	if fs.Config.Manifest == "" {
+		return nil, nil
+	}
+	if fs.Config.DryRun {
+		fs.Logf(nil, "Not writing --manifest as --dry-run")
+		return nil, nil
+	}
+	out, err := os.Create(fs.Config.Manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create --manifest file")
+	}
+	m := &manifest{
+		out:      out,
+		hashType: hashType,
+	}
+	if strings.ToLower(filepath.Ext(fs.Config.Manifest)) == ".csv" {
+		m.csv = csv.NewWriter(out)
+		m.err = m.csv.Write([]string{"Action", "Path", "Size", "ModTime", "HashType", "Hash"})
+	} else {
+		m.json = json.NewEncoder(out)
+	}
+	return m, nil
+}
+
+// manifestHashType returns the hash to record in the manifest of a
+// sync from fsrc to fdst - the hash in common if possible, otherwise
+// the dst hash
+func manifestHashType(fdst, fsrc fs.Info) hash.Type {
+	hashType := fsrc.Hashes().Overlap(fdst.Hashes()).GetOne()
+	if hashType == hash.None {
+		hashType = fdst.Hashes().GetOne()
+	}
+	return hashType
+}
+
+// recordDirMove writes a manifest of the files moved by a server
+// side directory move of fsrc to fdst.  These aren't examined by the
+// move so they are listed from fdst afterwards.
+func recordDirMove(fdst, fsrc fs.Fs) (err error) {
+	m, err := newManifest(manifestHashType(fdst, fsrc))
+	if err != nil || m == nil {
+		return err
+	}
+	defer func() {
+		closeErr := m.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	err = walk.Walk(fdst, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+		if err != nil {
+			return err
+		}
+		entries.ForObject(func(o fs.Object) {
+			m.Record(manifestMoved, o, true)
+		})
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list moved files for --manifest")
+	}
+	return nil
+}
+
+// Record adds o to the manifest with the action given.
+//
+// The hash is only read if readHash is set as it may be expensive.
+//
+// It is safe to call on a nil *manifest.
+func (m *manifest) Record(action string, o fs.ObjectInfo, readHash bool) {
+	if m == nil {
+		return
+	}
+	item := manifestItem{
+		Action:  action,
+		Path:    o.Remote(),
+		Size:    o.Size(),
+		ModTime: o.ModTime().Format(time.RFC3339Nano),
+	}
+	if readHash && m.hashType != hash.None {
+		sum, err := o.Hash(m.hashType)
+		if err != nil {
+			fs.Debugf(o, "Failed to read hash for --manifest: %v", err)
+		} else if sum != "" {
+			item.HashType = m.hashType.String()
+			item.Hash = sum
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return
+	}
+	if m.csv != nil {
+		m.err = m.csv.Write([]string{item.Action, item.Path, strconv.FormatInt(item.Size, 10), item.ModTime, item.HashType, item.Hash})
+	} else {
+		m.err = m.json.Encode(&item)
+	}
+	if m.err != nil {
+		fs.Errorf(nil, "Failed to write --manifest: %v", m.err)
+	}
+}
+
+// Close the manifest returning the first error writing it if any
+//
+// It is safe to call on a nil *manifest.
+func (m *manifest) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.csv != nil {
+		m.csv.Flush()
+		if m.err == nil {
+			m.err = m.csv.Error()
+		}
+	}
+	err := m.out.Close()
+	if m.err == nil {
+		m.err = err
+	}
+	if m.err != nil {
+		return errors.Wrap(m.err, "failed to write --manifest")
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// Actions recorded in the plan
+const (
+	planAdd    = "add"
+	planUpdate = "update"
+	planDelete = "delete"
+	planRename = "rename"
+)
+
+// planItem is a change to the destination in the plan
+type planItem struct {
+	Action  string
+	Path    string
+	OldPath string `json:",omitempty"` // the path renamed from
+	Size    int64
+}
+
+// planReport is the JSON document written by --dry-run-plan
+type planReport struct {
+	Adds    int
+	Updates int
+	Deletes int
+	Renames int
+	Bytes   int64 // bytes which would be transferred
+	Items   []planItem
+}
+
+// plan records the changes a sync run with --dry-run would make to
+// the destination so they can be written as JSON to the file set
+// with --dry-run-plan
+type plan struct {
+	mu    sync.Mutex
+	items []planItem
+}
+
+// newPlan returns a plan if --dry-run-plan is in use or nil if not
+func newPlan() *plan {
+	if fs.Config.DryRunPlan == "" || !fs.Config.DryRun {
+		return nil
+	}
+	return &plan{}
+}
+
+// Record adds a change to the plan
+//
+// It is safe to call on a nil *plan.
+func (p *plan) Record(action string, o fs.ObjectInfo, oldPath string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.items = append(p.items, planItem{
+		Action:  action,
+		Path:    o.Remote(),
+		OldPath: oldPath,
+		Size:    o.Size(),
+	})
+	p.mu.Unlock()
+}
+
+// report makes the planReport from the items recorded
+func (p *plan) report() *planReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := &planReport{
+		Items: append([]planItem{}, p.items...),
+	}
+	sort.Slice(r.Items, func(i, j int) bool {
+		a, b := r.Items[i], r.Items[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Action < b.Action
+	})
+	for _, item := range r.Items {
+		switch item.Action {
+		case planAdd:
+			r.Adds++
+		case planUpdate:
+			r.Updates++
+		case planDelete:
+			r.Deletes++
+		case planRename:
+			r.Renames++
+		}
+		if (item.Action == planAdd || item.Action == planUpdate) && item.Size > 0 {
+			r.Bytes += item.Size
+		}
+	}
+	return r
+}
+
+// Write the plan to the file set by --dry-run-plan, or to stdout if
+// it is "-"
+//
+// It is safe to call on a nil *plan.
+func (p *plan) Write() (err error) {
+	if p == nil {
+		return nil
+	}
+	var out io.Writer = os.Stdout
+	if fs.Config.DryRunPlan != "-" {
+		f, err := os.Create(fs.Config.DryRunPlan)
+		if err != nil {
+			return errors.Wrap(err, "failed to create --dry-run-plan file")
+		}
+		defer func() {
+			closeErr := f.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	err = enc.Encode(p.report())
+	if err != nil {
+		return errors.Wrap(err, "failed to write --dry-run-plan")
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package sync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/rc"
+	"github.com/pkg/errors"
+)
+
+// queueExport is the pending work of a sync, copy or move started
+// via the rc as written by job/export and read by job/import
+type queueExport struct {
+	Method   string   `json:"method"`   // rc method which started the job, eg "sync/copy"
+	SrcFs    string   `json:"srcFs"`    // source remote as passed to the rc
+	DstFs    string   `json:"dstFs"`    // destination remote as passed to the rc
+	Complete bool     `json:"complete"` // set if the source had been listed completely
+	Pending  []string `json:"pending"`  // source files still to be checked or transferred
+}
+
+// queue tracks a sync, copy or move started via the rc so its
+// pending work can be exported
+type queue struct {
+	mu     sync.Mutex
+	method string
+	srcFs  string
+	dstFs  string
+	s      *syncCopyMove // the pass currently running, nil if none
+}
+
+// queues are the queues of the running jobs by stats group
+var queues = struct {
+	mu     sync.Mutex
+	queues map[*accounting.StatsInfo]*queue
+}{
+	queues: make(map[*accounting.StatsInfo]*queue),
+}
+
+// addQueue starts tracking the job accounted to group and returns a
+// function to stop tracking it.
+func addQueue(group *accounting.StatsInfo, method, srcFs, dstFs string) func() {
+	if group == nil {
+		return func() {}
+	}
+	q := &queue{
+		method: method,
+		srcFs:  srcFs,
+		dstFs:  dstFs,
+	}
+	queues.mu.Lock()
+	queues.queues[group] = q
+	queues.mu.Unlock()
+	return func() {
+		queues.mu.Lock()
+		if queues.queues[group] == q {
+			delete(queues.queues, group)
+		}
+		queues.mu.Unlock()
+	}
+}
+
+// getQueue returns the queue of the job accounted to group or nil
+func getQueue(group *accounting.StatsInfo) *queue {
+	if group == nil {
+		return nil
+	}
+	queues.mu.Lock()
+	defer queues.mu.Unlock()
+	return queues.queues[group]
+}
+
+// setRunning records s as the pass of the sync running for the queue
+// of its stats group, if any.
+func setRunning(s *syncCopyMove) {
+	q := getQueue(s.group)
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	q.s = s
+	q.mu.Unlock()
+}
+
+// export returns the pending work of the queue
+func (q *queue) export() *queueExport {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := &queueExport{
+		Method:  q.method,
+		SrcFs:   q.srcFs,
+		DstFs:   q.dstFs,
+		Pending: []string{},
+	}
+	if q.s != nil {
+		out.Pending, out.Complete = q.s.pending()
+	}
+	return out
+}
+
+// pending returns the sorted remotes of the source files which are
+// queued or being checked or transferred and whether the source has
+// been listed completely.
+func (s *syncCopyMove) pending() (remotes []string, complete bool) {
+	seen := map[string]struct{}{}
+	s.inFlightMu.Lock()
+	for remote := range s.inFlight {
+		seen[remote] = struct{}{}
+	}
+	complete = s.listed
+	s.inFlightMu.Unlock()
+	for _, p := range []*pipe{s.toBeChecked, s.toBeRenamed, s.toBeUploaded} {
+		for _, remote := range p.remotes() {
+			seen[remote] = struct{}{}
+		}
+	}
+	remotes = make([]string, 0, len(seen))
+	for remote := range seen {
+		remotes = append(remotes, remote)
+	}
+	sort.Strings(remotes)
+	return remotes, complete
+}
+
+// startWork records that remote has been taken from a pipe to work on
+func (s *syncCopyMove) startWork(remote string) {
+	s.inFlightMu.Lock()
+	s.inFlight[remote]++
+	s.inFlightMu.Unlock()
+}
+
+// endWork records that the work on remote started with startWork has
+// finished
+func (s *syncCopyMove) endWork(remote string) {
+	s.inFlightMu.Lock()
+	s.inFlight[remote]--
+	if s.inFlight[remote] <= 0 {
+		delete(s.inFlight, remote)
+	}
+	s.inFlightMu.Unlock()
+}
+
+// setListed records that the source has been listed completely
+func (s *syncCopyMove) setListed() {
+	s.inFlightMu.Lock()
+	s.listed = true
+	s.inFlightMu.Unlock()
+}
+
+// runPending checks and transfers the source files in remotes from
+// fsrc to fdst, moving them if DoMove is set, without listing either
+// remote.
+func runPending(group *accounting.StatsInfo, fdst, fsrc fs.Fs, DoMove bool, remotes []string) error {
+	s, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, DoMove, false, false)
+	if err != nil {
+		return err
+	}
+	s.group = group
+	setRunning(s)
+	s.startCheckers()
+	s.startTransfers()
+	for _, remote := range remotes {
+		src, err := fsrc.NewObject(remote)
+		if err == fs.ErrorObjectNotFound {
+			fs.Debugf(remote, "Skipping pending file as it is no longer in the source")
+			continue
+		} else if err != nil {
+			fs.CountError(err)
+			fs.Errorf(remote, "Failed to find pending file in the source: %v", err)
+			s.processError(err)
+			continue
+		}
+		dst, err := fdst.NewObject(remote)
+		if err != nil {
+			dst = nil
+		}
+		if !s.toBeChecked.Put(s.ctx, fs.ObjectPair{Src: src, Dst: dst}) {
+			break
+		}
+	}
+	s.setListed()
+	s.stopCheckers()
+	s.stopTransfers()
+	s.cancel()
+	return s.currentError()
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "job/export",
+		AuthRequired: true,
+		Fn:           rcJobExport,
+		Title:        "Export the pending work of a sync, copy or move job",
+		Help: `This takes the following parameters
+
+- jobid - id of the job (integer) which must be a sync/sync, sync/copy or sync/move
+- file - optional local file to write the export to as JSON
+
+Results
+- method - the rc method which started the job, eg "sync/copy"
+- srcFs - the source remote
+- dstFs - the destination remote
+- complete - true if the source had been listed completely
+- pending - array of the source files still to be checked or transferred
+
+If file is given the results are written to it rather than returned.
+The file is replaced atomically so it can be exported to regularly.
+
+Pass the export to job/import in a new rclone to carry on with the
+work without listing the source and destination again, eg after a
+reboot.  If complete is false then files not listed yet aren't in the
+export, so run the sync, copy or move again after the import.
+`,
+	})
+	rc.Add(rc.Call{
+		Path:         "job/import",
+		AuthRequired: true,
+		Fn:           rcJobImport,
+		Title:        "Carry on with the pending work exported by job/export",
+		Help: `This takes the following parameters
+
+- file - local file written by job/export
+- queue - the results of job/export (use instead of file)
+
+This checks and transfers each pending file, copying or moving it as
+the exported job would have done, without listing the source or
+destination.  Files no longer in the source are skipped.
+
+Note that the deletions of a sync/sync aren't exported so run the
+sync again afterwards to do them.
+
+Use _async to run this as a job which can itself be exported.
+`,
+	})
+}
+
+// Export the pending work of a job
+func rcJobExport(in rc.Params) (out rc.Params, err error) {
+	jobID, err := in.GetInt64("jobid")
+	if err != nil {
+		return nil, err
+	}
+	file, err := in.GetString("file")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	job := rc.GetJob(jobID)
+	if job == nil {
+		return nil, errors.New("job not found")
+	}
+	q := getQueue(accounting.StatsGroup(job.Group))
+	if q == nil {
+		return nil, errors.New("job is not a running sync, copy or move")
+	}
+	export := q.export()
+	if file != "" {
+		return nil, writeQueueExport(file, export)
+	}
+	out = make(rc.Params)
+	err = rc.Reshape(&out, export)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// writeQueueExport writes export to file atomically
+func writeQueueExport(file string, export *queueExport) error {
+	b, err := json.MarshalIndent(export, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".partial"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	err = os.Rename(tmp, file)
+	if err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	return nil
+}
+
+// Import the pending work of a job
+func rcJobImport(in rc.Params) (out rc.Params, err error) {
+	var export queueExport
+	file, err := in.GetString("file")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read export")
+		}
+		err = json.Unmarshal(b, &export)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse export")
+		}
+	} else {
+		err = in.GetStruct("queue", &export)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var DoMove bool
+	switch export.Method {
+	case "sync/sync", "sync/copy":
+	case "sync/move":
+		DoMove = true
+	default:
+		return nil, errors.Errorf("can't import a job with method %q", export.Method)
+	}
+	fsrc, err := rc.GetCachedFs(export.SrcFs)
+	if err != nil {
+		return nil, err
+	}
+	fdst, err := rc.GetCachedFs(export.DstFs)
+	if err != nil {
+		return nil, err
+	}
+	if !export.Complete {
+		fs.Logf(fdst, "Importing an export made before the source was listed completely - run %s again afterwards", export.Method)
+	}
+	groupName, err := in.GetString("_group")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	var group *accounting.StatsInfo
+	if groupName != "" {
+		group = accounting.StatsGroup(groupName)
+	}
+	defer addQueue(group, export.Method, export.SrcFs, export.DstFs)()
+	return nil, runPending(group, fdst, fsrc, DoMove, export.Pending)
+}
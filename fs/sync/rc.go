@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/rc"
 )
 
@@ -22,6 +23,7 @@ func init() {
 
 - srcFs - a remote name string eg "drive:src" for the source
 - dstFs - a remote name string eg "drive:dst" for the destination
+- createEmptySrcDirs - create empty src directories on destination if set
 ` + moveHelp + `
 
 See the [` + name + ` command](/commands/rclone_` + name + `/) command for more information on the above.`,
@@ -39,17 +41,32 @@ func rcSyncCopyMove(in rc.Params, name string) (out rc.Params, err error) {
 	if err != nil {
 		return nil, err
 	}
+	createEmptySrcDirs, err := in.GetBool("createEmptySrcDirs")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	groupName, err := in.GetString("_group")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	var group *accounting.StatsInfo
+	if groupName != "" {
+		group = accounting.StatsGroup(groupName)
+	}
+	srcName, _ := in.GetString("srcFs")
+	dstName, _ := in.GetString("dstFs")
+	defer addQueue(group, "sync/"+name, srcName, dstName)()
 	switch name {
 	case "sync":
-		return nil, Sync(dstFs, srcFs)
+		return nil, SyncWithStats(group, dstFs, srcFs, createEmptySrcDirs)
 	case "copy":
-		return nil, CopyDir(dstFs, srcFs)
+		return nil, CopyDirWithStats(group, dstFs, srcFs, createEmptySrcDirs)
 	case "move":
 		deleteEmptySrcDirs, err := in.GetBool("deleteEmptySrcDirs")
 		if rc.NotErrParamNotFound(err) {
 			return nil, err
 		}
-		return nil, MoveDir(dstFs, srcFs, deleteEmptySrcDirs)
+		return nil, MoveDirWithStats(group, dstFs, srcFs, deleteEmptySrcDirs, createEmptySrcDirs)
 	}
 	panic("unknown rcSyncCopyMove type")
 }
@@ -1,10 +1,16 @@
 package sync
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/rc"
 	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -95,3 +101,90 @@ func TestRcSync(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file1, file2)
 	fstest.CheckItems(t, r.Fremote, file1, file2)
 }
+
+// job/export: export the pending work of a sync
+func TestRcJobExport(t *testing.T) {
+	r, call := rcNewRun(t, "job/export")
+	defer r.Finalise()
+
+	_, err := call.Fn(rc.Params{"jobid": int64(123456789)})
+	assert.EqualError(t, err, "job not found")
+
+	group := accounting.StatsGroup("test-job-export")
+	defer accounting.DeleteStatsGroup("test-job-export")
+	defer addQueue(group, "sync/copy", r.LocalName, r.FremoteName)()
+	q := getQueue(group)
+	require.NotNil(t, q)
+	assert.Equal(t, &queueExport{
+		Method:  "sync/copy",
+		SrcFs:   r.LocalName,
+		DstFs:   r.FremoteName,
+		Pending: []string{},
+	}, q.export())
+
+	s, err := newSyncCopyMove(r.Fremote, r.Flocal, fs.DeleteModeOff, false, false, false)
+	require.NoError(t, err)
+	defer s.cancel()
+	s.group = group
+	setRunning(s)
+	for _, remote := range []string{"b", "a"} {
+		require.True(t, s.toBeChecked.Put(s.ctx, fs.ObjectPair{Src: mockobject.Object(remote)}))
+	}
+	s.startWork("c")
+	assert.Equal(t, &queueExport{
+		Method:  "sync/copy",
+		SrcFs:   r.LocalName,
+		DstFs:   r.FremoteName,
+		Pending: []string{"a", "b", "c"},
+	}, q.export())
+	s.endWork("c")
+	s.setListed()
+	export := q.export()
+	assert.True(t, export.Complete)
+	assert.Equal(t, []string{"a", "b"}, export.Pending)
+}
+
+// job/import: carry on with the pending work of a copy
+func TestRcJobImport(t *testing.T) {
+	r, call := rcNewRun(t, "job/import")
+	defer r.Finalise()
+	r.Mkdir(r.Fremote)
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := r.WriteFile("subdir/file2", "file2 contents", t2)
+	file3 := r.WriteFile("file3", "file3 contents", t3)
+
+	export := &queueExport{
+		Method:   "sync/copy",
+		SrcFs:    r.LocalName,
+		DstFs:    r.FremoteName,
+		Complete: true,
+		Pending:  []string{"file1", "subdir/file2", "missing"},
+	}
+	dir, err := ioutil.TempDir("", "rclone-job-import")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	file := filepath.Join(dir, "export.json")
+	require.NoError(t, writeQueueExport(file, export))
+
+	out, err := call.Fn(rc.Params{"file": file})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params(nil), out)
+
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	export.Method = "sync/move"
+	export.Pending = []string{"file3"}
+	_, err = call.Fn(rc.Params{"queue": export})
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	export.Method = "operations/purge"
+	_, err = call.Fn(rc.Params{"queue": export})
+	assert.EqualError(t, err, `can't import a job with method "operations/purge"`)
+}
@@ -4,9 +4,11 @@ package sync
 import (
 	"context"
 	"fmt"
+	"math"
 	"path"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
@@ -25,6 +27,7 @@ type syncCopyMove struct {
 	deleteMode         fs.DeleteMode // how we are doing deletions
 	DoMove             bool
 	deleteEmptySrcDirs bool
+	copyEmptySrcDirs   bool
 	dir                string
 	// internal state
 	ctx            context.Context        // internal context for controlling go-routines
@@ -43,6 +46,9 @@ type syncCopyMove struct {
 	dstEmptyDirs   map[string]fs.DirEntry // potentially empty directories
 	srcEmptyDirsMu sync.Mutex             // protect srcEmptyDirs
 	srcEmptyDirs   map[string]fs.DirEntry // potentially empty directories
+	setDirModTime  bool                   // set if we should set the modtime of dst directories
+	srcDirsMu      sync.Mutex             // protect srcDirs
+	srcDirs        []fs.Directory         // src directories, only used if setDirModTime
 	checkerWg      sync.WaitGroup         // wait for checkers
 	toBeChecked    *pipe                  // checkers channel
 	transfersWg    sync.WaitGroup         // wait for transfers
@@ -59,17 +65,34 @@ type syncCopyMove struct {
 	trackRenamesWg sync.WaitGroup         // wg for background track renames
 	trackRenamesCh chan fs.Object         // objects are pumped in here
 	renameCheck    []fs.Object            // accumulate files to check for rename here
+	dirRenames     *dirRenames            // directories to check for rename, nil if not in use
 	backupDir      fs.Fs                  // place to store overwrites/deletes
 	suffix         string                 // suffix to add to files placed in backupDir
+	freeSpace      *freeSpace             // guard for --min-free-space, nil if not in use
+	excluded       *excludedGuard         // guard for --delete-excluded without --force, nil if not in use
+	deletes        *deleteGuard           // guard for --refuse-empty-source and --max-delete-percent, nil if not in use
+	manifest       *manifest              // --manifest being written, nil if not in use
+	plan           *plan                  // --dry-run-plan being recorded, nil if not in use
+	uploadCache    *uploadCache           // --upload-cache in use, nil if not in use
+	hardLinks      *hardLinks             // --hard-links in use, nil if not in use
+	group          *accounting.StatsInfo  // stats group to account to as well as the global stats, may be nil
+	checkTuner     *autoTuner             // --auto-tune for the checkers, nil if not in use
+	transferTuner  *autoTuner             // --auto-tune for the transfers, nil if not in use
+	filter         *filter.Filter         // the filter in use, read once so it can't change during the sync
+	inFlightMu     sync.Mutex             // protect inFlight and listed
+	inFlight       map[string]int         // remotes of the files being worked on
+	listed         bool                   // set when the source has been listed completely
 }
 
-func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) (*syncCopyMove, error) {
+func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) (*syncCopyMove, error) {
+	fi := filter.GetActive()
 	s := &syncCopyMove{
 		fdst:               fdst,
 		fsrc:               fsrc,
 		deleteMode:         deleteMode,
 		DoMove:             DoMove,
 		deleteEmptySrcDirs: deleteEmptySrcDirs,
+		copyEmptySrcDirs:   copyEmptySrcDirs,
 		dir:                "",
 		srcFilesChan:       make(chan fs.Object, fs.Config.Checkers+fs.Config.Transfers),
 		srcFilesResult:     make(chan error, 1),
@@ -77,15 +100,41 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		dstEmptyDirs:       make(map[string]fs.DirEntry),
 		srcEmptyDirs:       make(map[string]fs.DirEntry),
 		noTraverse:         fs.Config.NoTraverse,
-		toBeChecked:        newPipe(accounting.Stats.SetCheckQueue, fs.Config.MaxBacklog),
-		toBeUploaded:       newPipe(accounting.Stats.SetTransferQueue, fs.Config.MaxBacklog),
 		deleteFilesCh:      make(chan fs.Object, fs.Config.Checkers),
 		trackRenames:       fs.Config.TrackRenames,
 		commonHash:         fsrc.Hashes().Overlap(fdst.Hashes()).GetOne(),
-		toBeRenamed:        newPipe(accounting.Stats.SetRenameQueue, fs.Config.MaxBacklog),
 		trackRenamesCh:     make(chan fs.Object, fs.Config.Checkers),
+		setDirModTime:      fdst.Features().DirSetModTime != nil && !fs.Config.NoUpdateModTime && deleteMode != fs.DeleteModeOnly,
+		freeSpace:          newFreeSpace(fdst),
+		excluded:           newExcludedGuard(fdst, fi),
+		checkTuner:         newAutoTuner("checkers", fs.Config.Checkers, fs.Config.AutoTuneMaxCheckers, accounting.Stats.GetChecks),
+		transferTuner:      newAutoTuner("transfers", fs.Config.Transfers, fs.Config.AutoTuneMaxTransfers, accounting.Stats.GetBytes),
+		inFlight:           make(map[string]int),
+		filter:             fi,
 	}
-	s.ctx, s.cancel = context.WithCancel(context.Background())
+	backlog := fs.Config.MaxBacklog
+	if fs.Config.CheckFirst {
+		// The transfers aren't started until all the checks
+		// have been done so they need to be queued without limit
+		backlog = math.MaxInt32
+	}
+	err := operations.CheckConflictResolve()
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.toBeChecked, err = newPipe("", accounting.Stats.SetCheckQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.toBeUploaded, err = newPipe(fs.Config.OrderBy, accounting.Stats.SetTransferQueue, backlog)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.toBeRenamed, err = newPipe("", accounting.Stats.SetRenameQueue, fs.Config.MaxBacklog)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.ctx, s.cancel = context.WithCancel(accounting.ShutdownContext())
 	if s.noTraverse && s.deleteMode != fs.DeleteModeOff {
 		fs.Errorf(nil, "Ignoring --no-traverse with sync")
 		s.noTraverse = false
@@ -115,9 +164,14 @@ func newSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 			s.noTraverse = false
 		}
 	}
+	s.dirRenames = newDirRenames(fdst, s.trackRenames, fi)
+	s.deletes = newDeleteGuard(s.deleteMode)
+	if s.deletes != nil && s.deleteMode == fs.DeleteModeDuring {
+		// the guard needs both sides listed before deleting
+		s.deleteMode = fs.DeleteModeAfter
+	}
 	// Make Fs for --backup-dir if required
 	if fs.Config.BackupDir != "" {
-		var err error
 		s.backupDir, err = fs.NewFs(fs.Config.BackupDir)
 		if err != nil {
 			return nil, fserrors.FatalError(errors.Errorf("Failed to make fs for --backup-dir %q: %v", fs.Config.BackupDir, err))
@@ -167,6 +221,9 @@ func (s *syncCopyMove) processError(err error) {
 	if err == nil {
 		return
 	}
+	if s.group != nil {
+		s.group.Error(err)
+	}
 	s.errorMu.Lock()
 	defer s.errorMu.Unlock()
 	switch {
@@ -199,21 +256,62 @@ func (s *syncCopyMove) currentError() error {
 	return s.noRetryErr
 }
 
+// checking accounts remote as being checked
+func (s *syncCopyMove) checking(remote string) {
+	accounting.Stats.Checking(remote)
+	if s.group != nil {
+		s.group.Checking(remote)
+	}
+}
+
+// doneChecking accounts remote as having been checked
+func (s *syncCopyMove) doneChecking(remote string) {
+	accounting.Stats.DoneChecking(remote)
+	if s.group != nil {
+		s.group.DoneChecking(remote)
+	}
+}
+
+// transferring accounts remote as being transferred
+func (s *syncCopyMove) transferring(remote string) {
+	accounting.Stats.Transferring(remote)
+	if s.group != nil {
+		s.group.Transferring(remote)
+	}
+}
+
+// doneTransferring accounts remote of size bytes as having been
+// transferred with err
+func (s *syncCopyMove) doneTransferring(remote string, size int64, err error) {
+	accounting.Stats.DoneTransferringError(remote, size, err)
+	if s.group != nil {
+		s.group.DoneTransferringError(remote, size, err)
+	}
+}
+
 // pairChecker reads Objects~s on in send to out if they need transferring.
 //
 // FIXME potentially doing lots of hashes at once
 func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
+		s.checkTuner.Acquire()
 		pair, ok := in.Get(s.ctx)
 		if !ok {
+			s.checkTuner.Release(time.Time{})
 			return
 		}
+		start := time.Now()
 		src := pair.Src
-		accounting.Stats.Checking(src.Remote())
+		s.startWork(src.Remote())
+		s.checking(src.Remote())
 		// Check to see if can store this
 		if src.Storable() {
-			if operations.NeedTransfer(pair.Dst, pair.Src) {
+			needTransfer, conflict := operations.NeedTransferConflict(pair.Dst, pair.Src)
+			if conflict {
+				s.manifest.Record(manifestConflict, src, false)
+			}
+			if needTransfer {
 				// If files are treated as immutable, fail if destination exists and does not match
 				if fs.Config.Immutable && pair.Dst != nil {
 					fs.Errorf(pair.Dst, "Source and destination exist but do not match: immutable file modified")
@@ -230,18 +328,15 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 							// If successful zero out the dst as it is no longer there and copy the file
 							pair.Dst = nil
 							ok = out.Put(s.ctx, pair)
-							if !ok {
-								return
-							}
 						}
 					} else {
 						ok = out.Put(s.ctx, pair)
-						if !ok {
-							return
-						}
 					}
 				}
 			} else {
+				s.manifest.Record(manifestUnchanged, src, fs.Config.CheckSum)
+				s.uploadCache.Record(src)
+				s.hardLinks.Unchanged(src, pair.Dst)
 				// If moving need to delete the files we don't need to copy
 				if s.DoMove {
 					// Delete src if no error on copy
@@ -249,7 +344,13 @@ func (s *syncCopyMove) pairChecker(in *pipe, out *pipe, wg *sync.WaitGroup) {
 				}
 			}
 		}
-		accounting.Stats.DoneChecking(src.Remote())
+		s.doneChecking(src.Remote())
+		s.endWork(src.Remote())
+		s.checkTuner.Release(start)
+		// Stop if the pair couldn't be passed on
+		if !ok {
+			return
+		}
 	}
 }
 
@@ -263,6 +364,7 @@ func (s *syncCopyMove) pairRenamer(in *pipe, out *pipe, wg *sync.WaitGroup) {
 			return
 		}
 		src := pair.Src
+		s.startWork(src.Remote())
 		if !s.tryRename(src) {
 			// pass on if not renamed
 			ok = out.Put(s.ctx, pair)
@@ -270,6 +372,7 @@ func (s *syncCopyMove) pairRenamer(in *pipe, out *pipe, wg *sync.WaitGroup) {
 				return
 			}
 		}
+		s.endWork(src.Remote())
 	}
 }
 
@@ -278,49 +381,104 @@ func (s *syncCopyMove) pairCopyOrMove(in *pipe, fdst fs.Fs, wg *sync.WaitGroup)
 	defer wg.Done()
 	var err error
 	for {
+		s.transferTuner.Acquire()
 		pair, ok := in.Get(s.ctx)
 		if !ok {
+			s.transferTuner.Release(time.Time{})
+			return
+		}
+		// Don't wait for a pause to end if the sync is stopped
+		if accounting.WaitIfPaused(s.ctx) != nil {
+			s.transferTuner.Release(time.Time{})
 			return
 		}
+		start := time.Now()
 		src := pair.Src
-		accounting.Stats.Transferring(src.Remote())
-		if s.DoMove {
-			_, err = operations.Move(fdst, pair.Dst, src.Remote(), src)
-		} else {
-			_, err = operations.Copy(fdst, pair.Dst, src.Remote(), src)
+		s.startWork(src.Remote())
+		// Skip the transfers which don't fit on the destination
+		err = s.freeSpace.Check(src.Size())
+		if err != nil {
+			fs.Errorf(src, "Not transferring: %v", err)
+			s.processError(err)
+			s.doneTransferring(src.Remote(), src.Size(), err)
+			s.endWork(src.Remote())
+			s.transferTuner.Release(time.Time{})
+			continue
+		}
+		s.transferring(src.Remote())
+		action := manifestCopied
+		newDst, linked := s.hardLinks.Start(s.ctx, src)
+		switch {
+		case linked:
+			err = nil
+		case s.DoMove:
+			action = manifestMoved
+			newDst, err = operations.MoveWithStats(s.group, fdst, pair.Dst, src.Remote(), src)
+		default:
+			newDst, err = operations.CopyWithStats(s.group, fdst, pair.Dst, src.Remote(), src)
+			if err != nil {
+				s.hardLinks.Done(src, nil)
+			} else {
+				s.hardLinks.Done(src, newDst)
+			}
+		}
+		if err == nil {
+			if pair.Dst == nil {
+				s.plan.Record(planAdd, src, "")
+			} else {
+				s.plan.Record(planUpdate, src, "")
+			}
+		}
+		switch {
+		case err != nil:
+			s.manifest.Record(manifestError, src, false)
+		case newDst != nil:
+			s.manifest.Record(action, newDst, true)
+			s.uploadCache.Record(src)
+		default:
+			s.manifest.Record(action, src, false)
+			s.uploadCache.Record(src)
 		}
 		s.processError(err)
-		accounting.Stats.DoneTransferring(src.Remote(), err == nil)
+		s.doneTransferring(src.Remote(), src.Size(), err)
+		s.endWork(src.Remote())
+		s.transferTuner.Release(start)
 	}
 }
 
 // This starts the background checkers.
 func (s *syncCopyMove) startCheckers() {
-	s.checkerWg.Add(fs.Config.Checkers)
-	for i := 0; i < fs.Config.Checkers; i++ {
+	checkers := s.checkTuner.Workers(fs.Config.Checkers)
+	s.checkerWg.Add(checkers)
+	for i := 0; i < checkers; i++ {
 		go s.pairChecker(s.toBeChecked, s.toBeUploaded, &s.checkerWg)
 	}
+	s.checkTuner.Start()
 }
 
 // This stops the background checkers
 func (s *syncCopyMove) stopCheckers() {
 	s.toBeChecked.Close()
 	fs.Infof(s.fdst, "Waiting for checks to finish")
+	s.checkTuner.Stop()
 	s.checkerWg.Wait()
 }
 
 // This starts the background transfers
 func (s *syncCopyMove) startTransfers() {
-	s.transfersWg.Add(fs.Config.Transfers)
-	for i := 0; i < fs.Config.Transfers; i++ {
+	transfers := s.transferTuner.Workers(fs.Config.Transfers)
+	s.transfersWg.Add(transfers)
+	for i := 0; i < transfers; i++ {
 		go s.pairCopyOrMove(s.toBeUploaded, s.fdst, &s.transfersWg)
 	}
+	s.transferTuner.Start()
 }
 
 // This stops the background transfers
 func (s *syncCopyMove) stopTransfers() {
 	s.toBeUploaded.Close()
 	fs.Infof(s.fdst, "Waiting for transfers to finish")
+	s.transferTuner.Stop()
 	s.transfersWg.Wait()
 }
 
@@ -376,7 +534,7 @@ func (s *syncCopyMove) startDeleters() {
 	s.deletersWg.Add(1)
 	go func() {
 		defer s.deletersWg.Done()
-		err := operations.DeleteFilesWithBackupDir(s.deleteFilesCh, s.backupDir)
+		err := operations.DeleteFilesWithCallback(s.deleteFilesCh, s.backupDir, s.recordDelete)
 		s.processError(err)
 	}()
 }
@@ -396,6 +554,20 @@ func (s *syncCopyMove) stopDeleters() {
 // checkSrcMap is clear then it assumes that the any source files that
 // have been found have been removed from dstFiles already.
 func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
+	toDeleteCount := len(s.dstFiles)
+	if checkSrcMap {
+		for remote := range s.dstFiles {
+			if _, exists := s.srcFiles[remote]; exists {
+				toDeleteCount--
+			}
+		}
+	}
+	// Check the guards first so their fatal errors are returned
+	// even if there were IO errors
+	if err := s.deletes.Check(toDeleteCount); err != nil {
+		fs.Errorf(s.fdst, "%v", err)
+		return err
+	}
 	if accounting.Stats.Errored() && !fs.Config.IgnoreErrors {
 		fs.Errorf(s.fdst, "%v", fs.ErrorNotDeleting)
 		return fs.ErrorNotDeleting
@@ -415,6 +587,7 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 			if s.aborting() {
 				break
 			}
+			s.plan.Record(planDelete, o, "")
 			select {
 			case <-s.ctx.Done():
 				break outer
@@ -423,7 +596,16 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 		}
 		close(toDelete)
 	}()
-	return operations.DeleteFilesWithBackupDir(toDelete, s.backupDir)
+	return operations.DeleteFilesWithCallback(toDelete, s.backupDir, s.recordDelete)
+}
+
+// recordDelete records the deletion of dst in the manifest
+func (s *syncCopyMove) recordDelete(dst fs.Object, err error) {
+	if err != nil {
+		s.manifest.Record(manifestError, dst, false)
+	} else {
+		s.manifest.Record(manifestDeleted, dst, false)
+	}
 }
 
 // This deletes the empty directories in the slice passed in.  It
@@ -502,6 +684,41 @@ func copyEmptyDirectories(f fs.Fs, entries map[string]fs.DirEntry) error {
 	return nil
 }
 
+// setDirModTimes sets the modification times of the directories in
+// fdst to those of the corresponding directories in fsrc.
+//
+// Directories which don't exist in fdst are skipped.
+func (s *syncCopyMove) setDirModTimes() error {
+	var err error
+	okCount := 0
+	for _, dir := range s.srcDirs {
+		setErr := operations.SetDirModTime(s.fdst, dir.Remote(), dir.ModTime())
+		switch setErr {
+		case nil:
+			okCount++
+		case fs.ErrorDirNotFound:
+		default:
+			fs.Errorf(fs.LogDirName(s.fdst, dir.Remote()), "Failed to set directory modification time: %v", setErr)
+			err = setErr
+		}
+	}
+	if okCount > 0 {
+		fs.Debugf(s.fdst, "set modification time of %d directories", okCount)
+	}
+	return err
+}
+
+// recordSrcDir records dir so its modification time can be set at
+// the end of the sync if required
+func (s *syncCopyMove) recordSrcDir(dir fs.Directory) {
+	if !s.setDirModTime {
+		return
+	}
+	s.srcDirsMu.Lock()
+	s.srcDirs = append(s.srcDirs, dir)
+	s.srcDirsMu.Unlock()
+}
+
 func (s *syncCopyMove) srcParentDirCheck(entry fs.DirEntry) {
 	// If we are moving files then we don't want to remove directories with files in them
 	// from the srcEmptyDirs as we are about to move them making the directory empty.
@@ -582,12 +799,12 @@ func (s *syncCopyMove) makeRenameMap() {
 			for obj := range in {
 				// only create hash for dst fs.Object if its size could match
 				if _, found := possibleSizes[obj.Size()]; found {
-					accounting.Stats.Checking(obj.Remote())
+					s.checking(obj.Remote())
 					hash := s.renameHash(obj)
 					if hash != "" {
 						s.pushRenameMap(hash, obj)
 					}
-					accounting.Stats.DoneChecking(obj.Remote())
+					s.doneChecking(obj.Remote())
 				}
 			}
 		}()
@@ -599,8 +816,8 @@ func (s *syncCopyMove) makeRenameMap() {
 // tryRename renames a src object when doing track renames if
 // possible, it returns true if the object was renamed.
 func (s *syncCopyMove) tryRename(src fs.Object) bool {
-	accounting.Stats.Checking(src.Remote())
-	defer accounting.Stats.DoneChecking(src.Remote())
+	s.checking(src.Remote())
+	defer s.doneChecking(src.Remote())
 
 	// Calculate the hash of the src object
 	hash := s.renameHash(src)
@@ -618,7 +835,7 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	dstOverwritten, _ := s.fdst.NewObject(src.Remote())
 
 	// Rename dst to have name src.Remote()
-	_, err := operations.Move(s.fdst, dstOverwritten, src.Remote(), dst)
+	_, err := operations.MoveWithStats(s.group, s.fdst, dstOverwritten, src.Remote(), dst)
 	if err != nil {
 		fs.Debugf(src, "Failed to rename to %q: %v", dst.Remote(), err)
 		return false
@@ -629,6 +846,7 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 	delete(s.dstFiles, dst.Remote())
 	s.dstFilesMu.Unlock()
 
+	s.plan.Record(planRename, src, dst.Remote())
 	fs.Infof(src, "Renamed from %q", dst.Remote())
 	return true
 }
@@ -645,11 +863,14 @@ func (s *syncCopyMove) run() error {
 		fs.Errorf(s.fdst, "Nothing to do as source and destination are the same")
 		return nil
 	}
+	setRunning(s)
 
 	// Start background checking and transferring pipeline
 	s.startCheckers()
 	s.startRenamers()
-	s.startTransfers()
+	if !fs.Config.CheckFirst {
+		s.startTransfers()
+	}
 	s.startDeleters()
 	s.dstFiles = make(map[string]fs.Object)
 
@@ -663,12 +884,22 @@ func (s *syncCopyMove) run() error {
 		Dir:           s.dir,
 		NoTraverse:    s.noTraverse,
 		Callback:      s,
-		DstIncludeAll: filter.Active.Opt.DeleteExcluded,
+		DstIncludeAll: s.filter.Opt.DeleteExcluded,
+		Filter:        s.filter,
+	}
+	if s.uploadCache != nil {
+		m.SkipSrc = s.uploadCache.Skip
+	}
+	streamed, err := s.streamFilesFrom()
+	s.processError(err)
+	if !streamed {
+		m.Run()
 	}
-	m.Run()
 
 	s.stopTrackRenames()
 	if s.trackRenames {
+		// Rename the directories which have been renamed
+		s.renameDirs()
 		// Build the map of the remaining dstFiles by hash
 		s.makeRenameMap()
 		// Attempt renames for all the files which don't have a matching dst
@@ -679,17 +910,24 @@ func (s *syncCopyMove) run() error {
 			}
 		}
 	}
+	s.setListed()
 
 	// Stop background checking and transferring pipeline
 	s.stopCheckers()
 	s.stopRenamers()
+	if fs.Config.CheckFirst {
+		fs.Infof(s.fdst, "Checks finished, now starting transfers")
+		s.startTransfers()
+	}
 	s.stopTransfers()
 	s.stopDeleters()
 
-	s.processError(copyEmptyDirectories(s.fdst, s.srcEmptyDirs))
+	if s.copyEmptySrcDirs {
+		s.processError(copyEmptyDirectories(s.fdst, s.srcEmptyDirs))
+	}
 
 	// Delete files after
-	if s.deleteMode == fs.DeleteModeAfter {
+	if s.deleteMode == fs.DeleteModeAfter || (s.deleteMode == fs.DeleteModeOnly && s.deletes != nil) {
 		if s.currentError() != nil && !fs.Config.IgnoreErrors {
 			fs.Errorf(s.fdst, "%v", fs.ErrorNotDeleting)
 		} else {
@@ -706,6 +944,14 @@ func (s *syncCopyMove) run() error {
 		}
 	}
 
+	s.excluded.Report()
+
+	// Set the modification times of the directories now their
+	// contents won't change any more
+	if s.setDirModTime {
+		s.processError(s.setDirModTimes())
+	}
+
 	// Delete empty fsrc subdirectories
 	// if DoMove and --delete-empty-src-dirs flag is set
 	if s.DoMove && s.deleteEmptySrcDirs {
@@ -718,6 +964,73 @@ func (s *syncCopyMove) run() error {
 	return s.currentError()
 }
 
+// streamFilesFrom copies or moves the files in the `--files-from -`
+// list as they are read from stdin instead of marching over the source
+// and the destination, so the list doesn't have to be kept in memory.
+//
+// It returns false if the list can't be streamed, which it can't be
+// when syncing as the whole list is needed to find the files to
+// delete.
+func (s *syncCopyMove) streamFilesFrom() (streamed bool, err error) {
+	if s.deleteMode != fs.DeleteModeOff || s.dir != "" {
+		return false, nil
+	}
+	return s.filter.StreamFilesFrom(func(remote string) error {
+		if s.aborting() {
+			return s.ctx.Err()
+		}
+		src, err := s.fsrc.NewObject(remote)
+		if err == fs.ErrorObjectNotFound {
+			// Skip files that are not found
+			return nil
+		} else if err != nil {
+			fs.CountError(err)
+			fs.Errorf(remote, "Failed to read from source: %v", err)
+			s.processError(err)
+			return nil
+		}
+		if s.uploadCache.Skip(src) {
+			return nil
+		}
+		dst, err := s.fdst.NewObject(remote)
+		switch err {
+		case nil:
+			s.Match(dst, src)
+		case fs.ErrorObjectNotFound:
+			s.SrcOnly(src)
+		default:
+			fs.CountError(err)
+			fs.Errorf(remote, "Failed to read from destination: %v", err)
+			s.processError(err)
+		}
+		return nil
+	})
+}
+
+// needsCaseFix returns true if --fix-case is in use and the names
+// of dst and src differ in case
+//
+// Only the leaf names are compared as the parent directories will
+// have been fixed already.
+func (s *syncCopyMove) needsCaseFix(dst, src fs.DirEntry) bool {
+	return fs.Config.FixCase && !fs.Config.Immutable && path.Base(dst.Remote()) != path.Base(src.Remote())
+}
+
+// fixDirCase renames the directory dst to have the same case as src
+func (s *syncCopyMove) fixDirCase(dst, src fs.DirEntry) {
+	// The directory will still be listed with its old name so
+	// this only works on case insensitive backends
+	if !s.fdst.Features().CaseInsensitive {
+		fs.Logf(dst, "Can't fix case of directory on case sensitive backend")
+		return
+	}
+	err := operations.DirMoveCaseInsensitive(s.fdst, dst.Remote(), src.Remote())
+	if err != nil {
+		fs.Errorf(dst, "Failed to fix case: %v", err)
+		s.processError(err)
+	}
+}
+
 // DstOnly have an object which is in the destination only
 func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	if s.deleteMode == fs.DeleteModeOff {
@@ -725,13 +1038,27 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	}
 	switch x := dst.(type) {
 	case fs.Object:
-		switch s.deleteMode {
+		s.deletes.AddDst()
+		if s.excluded.KeepObject(x) {
+			return false
+		}
+		if !s.filter.IncludeDeleteSize(x.Size()) {
+			fs.Infof(x, "Not deleting as size is outside --delete-min-size/--delete-max-size")
+			return false
+		}
+		deleteMode := s.deleteMode
+		if s.deletes != nil {
+			// the guard needs both sides listed before deleting
+			deleteMode = fs.DeleteModeAfter
+		}
+		switch deleteMode {
 		case fs.DeleteModeAfter:
 			// record object as needs deleting
 			s.dstFilesMu.Lock()
 			s.dstFiles[x.Remote()] = x
 			s.dstFilesMu.Unlock()
 		case fs.DeleteModeDuring, fs.DeleteModeOnly:
+			s.plan.Record(planDelete, x, "")
 			select {
 			case <-s.ctx.Done():
 				return
@@ -743,11 +1070,12 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 	case fs.Directory:
 		// Do the same thing to the entire contents of the directory
 		// Record directory as it is potentially empty and needs deleting
-		if s.fdst.Features().CanHaveEmptyDirectories {
+		if s.fdst.Features().CanHaveEmptyDirectories && !s.excluded.KeepDir(dst.Remote()) {
 			s.dstEmptyDirsMu.Lock()
 			s.dstEmptyDirs[dst.Remote()] = dst
 			s.dstEmptyDirsMu.Unlock()
 		}
+		s.dirRenames.AddDst(dst.Remote())
 		return true
 	default:
 		panic("Bad object in DirEntries")
@@ -758,6 +1086,9 @@ func (s *syncCopyMove) DstOnly(dst fs.DirEntry) (recurse bool) {
 
 // SrcOnly have an object which is in the source only
 func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
+	if _, ok := src.(fs.Object); ok {
+		s.deletes.AddSrc()
+	}
 	if s.deleteMode == fs.DeleteModeOnly {
 		return false
 	}
@@ -791,6 +1122,8 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 		s.srcParentDirCheck(src)
 		s.srcEmptyDirs[src.Remote()] = src
 		s.srcEmptyDirsMu.Unlock()
+		s.recordSrcDir(x)
+		s.dirRenames.AddSrc(src.Remote())
 		return true
 	default:
 		panic("Bad object in DirEntries")
@@ -802,6 +1135,8 @@ func (s *syncCopyMove) SrcOnly(src fs.DirEntry) (recurse bool) {
 func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 	switch srcX := src.(type) {
 	case fs.Object:
+		s.deletes.AddSrc()
+		s.deletes.AddDst()
 		s.srcEmptyDirsMu.Lock()
 		s.srcParentDirCheck(src)
 		s.srcEmptyDirsMu.Unlock()
@@ -811,6 +1146,15 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 		}
 		dstX, ok := dst.(fs.Object)
 		if ok {
+			if s.needsCaseFix(dst, src) {
+				newDst, err := operations.MoveCaseInsensitive(s.fdst, dstX, src.Remote())
+				if err != nil {
+					fs.Errorf(dst, "Failed to fix case: %v", err)
+					s.processError(err)
+					return false
+				}
+				dstX = newDst
+			}
 			ok = s.toBeChecked.Put(s.ctx, fs.ObjectPair{Src: srcX, Dst: dstX})
 			if !ok {
 				return false
@@ -825,11 +1169,15 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 		// Do the same thing to the entire contents of the directory
 		_, ok := dst.(fs.Directory)
 		if ok {
+			if s.needsCaseFix(dst, src) {
+				s.fixDirCase(dst, src)
+			}
 			// Record the src directory for deletion
 			s.srcEmptyDirsMu.Lock()
 			s.srcParentDirCheck(src)
 			s.srcEmptyDirs[src.Remote()] = src
 			s.srcEmptyDirsMu.Unlock()
+			s.recordSrcDir(srcX)
 			return true
 		}
 		// FIXME src is dir, dst is file
@@ -849,20 +1197,47 @@ func (s *syncCopyMove) Match(dst, src fs.DirEntry) (recurse bool) {
 // If DoMove is true then files will be moved instead of copied
 //
 // dir is the start directory, "" for root
-func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool) error {
+func runSyncCopyMove(group *accounting.StatsInfo, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) (err error) {
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
+	m, err := newManifest(manifestHashType(fdst, fsrc))
+	if err != nil {
+		return fserrors.FatalError(err)
+	}
+	defer func() {
+		closeErr := m.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	p := newPlan()
+	defer func() {
+		writeErr := p.Write()
+		if err == nil {
+			err = writeErr
+		}
+	}()
+	// Skipping files in the upload cache is only safe if they
+	// won't be deleted from the destination or need removing from
+	// the source
+	skipping := deleteMode == fs.DeleteModeOff && !DoMove
+	if fs.Config.UploadCache != "" && !skipping {
+		fs.Errorf(fdst, "Ignoring --upload-cache as it can only be used with copy")
+	}
 	// Run an extra pass to delete only
 	if deleteMode == fs.DeleteModeBefore {
 		if fs.Config.TrackRenames {
 			return fserrors.FatalError(errors.New("can't use --delete-before with --track-renames"))
 		}
 		// only delete stuff during in this pass
-		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs)
+		do, err := newSyncCopyMove(fdst, fsrc, fs.DeleteModeOnly, false, deleteEmptySrcDirs, copyEmptySrcDirs)
 		if err != nil {
 			return err
 		}
+		do.manifest = m
+		do.plan = p
+		do.group = group
 		err = do.run()
 		if err != nil {
 			return err
@@ -870,39 +1245,90 @@ func runSyncCopyMove(fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, de
 		// Next pass does a copy only
 		deleteMode = fs.DeleteModeOff
 	}
-	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs)
+	do, err := newSyncCopyMove(fdst, fsrc, deleteMode, DoMove, deleteEmptySrcDirs, copyEmptySrcDirs)
 	if err != nil {
 		return err
 	}
+	if skipping {
+		do.uploadCache, err = newUploadCache(fdst, fsrc)
+		if err != nil {
+			return fserrors.FatalError(err)
+		}
+		defer func() {
+			closeErr := do.uploadCache.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}()
+	}
+	do.hardLinks, err = newHardLinks(fdst, DoMove)
+	if err != nil {
+		return fserrors.FatalError(err)
+	}
+	defer func() {
+		closeErr := do.hardLinks.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	do.manifest = m
+	do.plan = p
+	do.group = group
 	return do.run()
 }
 
 // Sync fsrc into fdst
-func Sync(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.Config.DeleteMode, false, false)
+//
+// If copyEmptySrcDirs is set then empty directories in fsrc are
+// created in fdst.
+func Sync(fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
+	return SyncWithStats(nil, fdst, fsrc, copyEmptySrcDirs)
+}
+
+// SyncWithStats is like Sync but also accounts to the stats group
+// passed in if it isn't nil.
+func SyncWithStats(group *accounting.StatsInfo, fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
+	return runSyncCopyMove(group, fdst, fsrc, fs.Config.DeleteMode, false, false, copyEmptySrcDirs)
 }
 
 // CopyDir copies fsrc into fdst
-func CopyDir(fdst, fsrc fs.Fs) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, false, false)
+//
+// If copyEmptySrcDirs is set then empty directories in fsrc are
+// created in fdst.
+func CopyDir(fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
+	return CopyDirWithStats(nil, fdst, fsrc, copyEmptySrcDirs)
+}
+
+// CopyDirWithStats is like CopyDir but also accounts to the stats
+// group passed in if it isn't nil.
+func CopyDirWithStats(group *accounting.StatsInfo, fdst, fsrc fs.Fs, copyEmptySrcDirs bool) error {
+	return runSyncCopyMove(group, fdst, fsrc, fs.DeleteModeOff, false, false, copyEmptySrcDirs)
 }
 
 // moveDir moves fsrc into fdst
-func moveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
-	return runSyncCopyMove(fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs)
+func moveDir(group *accounting.StatsInfo, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	return runSyncCopyMove(group, fdst, fsrc, fs.DeleteModeOff, true, deleteEmptySrcDirs, copyEmptySrcDirs)
 }
 
 // MoveDir moves fsrc into fdst
-func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
+//
+// If copyEmptySrcDirs is set then empty directories in fsrc are
+// created in fdst.
+func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
+	return MoveDirWithStats(nil, fdst, fsrc, deleteEmptySrcDirs, copyEmptySrcDirs)
+}
+
+// MoveDirWithStats is like MoveDir but also accounts to the stats
+// group passed in if it isn't nil.
+func MoveDirWithStats(group *accounting.StatsInfo, fdst, fsrc fs.Fs, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) error {
 	if operations.Same(fdst, fsrc) {
 		fs.Errorf(fdst, "Nothing to do as source and destination are the same")
 		return nil
 	}
 
 	// First attempt to use DirMover if exists, same Fs and no filters are active
-	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && filter.Active.InActive() {
-		if fs.Config.DryRun {
-			fs.Logf(fdst, "Not doing server side directory move as --dry-run")
+	if fdstDirMove := fdst.Features().DirMove; fdstDirMove != nil && operations.SameConfig(fsrc, fdst) && filter.GetActive().InActive() {
+		if operations.SkipDestructive(fdst, "server side directory move") {
 			return nil
 		}
 		fs.Debugf(fdst, "Using server side directory move")
@@ -912,7 +1338,7 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 			fs.Infof(fdst, "Server side directory move failed - fallback to file moves: %v", err)
 		case nil:
 			fs.Infof(fdst, "Server side directory move succeeded")
-			return nil
+			return recordDirMove(fdst, fsrc)
 		default:
 			fs.CountError(err)
 			fs.Errorf(fdst, "Server side directory move failed: %v", err)
@@ -928,5 +1354,5 @@ func MoveDir(fdst, fsrc fs.Fs, deleteEmptySrcDirs bool) error {
 	}
 
 	// Otherwise move the files one by one
-	return moveDir(fdst, fsrc, deleteEmptySrcDirs)
+	return moveDir(group, fdst, fsrc, deleteEmptySrcDirs, copyEmptySrcDirs)
 }
@@ -3,7 +3,13 @@
 package sync
 
 import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,9 +17,12 @@ import (
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fs/fserrors"
 	"github.com/ncw/rclone/fs/hash"
 	"github.com/ncw/rclone/fs/operations"
 	"github.com/ncw/rclone/fstest"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/text/unicode/norm"
@@ -39,7 +48,7 @@ func TestCopyWithDryRun(t *testing.T) {
 	r.Mkdir(r.Fremote)
 
 	fs.Config.DryRun = true
-	err := CopyDir(r.Fremote, r.Flocal)
+	err := CopyDir(r.Fremote, r.Flocal, false)
 	fs.Config.DryRun = false
 	require.NoError(t, err)
 
@@ -54,7 +63,7 @@ func TestCopy(t *testing.T) {
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 	r.Mkdir(r.Fremote)
 
-	err := CopyDir(r.Fremote, r.Flocal)
+	err := CopyDir(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -71,7 +80,7 @@ func TestCopyNoTraverse(t *testing.T) {
 
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 
-	err := CopyDir(r.Fremote, r.Flocal)
+	err := CopyDir(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -89,7 +98,7 @@ func TestSyncNoTraverse(t *testing.T) {
 	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -107,13 +116,422 @@ func TestCopyWithDepth(t *testing.T) {
 	fs.Config.MaxDepth = 1
 	defer func() { fs.Config.MaxDepth = -1 }()
 
-	err := CopyDir(r.Fremote, r.Flocal)
+	err := CopyDir(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1, file2)
 	fstest.CheckItems(t, r.Fremote, file2)
 }
 
+// Test copy with --check-first and --order-by
+func TestCopyCheckFirst(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
+	file2 := r.WriteFile("hello world2", "hello world2", t2)
+
+	fs.Config.CheckFirst = true
+	fs.Config.OrderBy = "size,descending"
+	defer func() {
+		fs.Config.CheckFirst = false
+		fs.Config.OrderBy = ""
+	}()
+
+	err := CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+// Test copy skips the files which don't fit in the free space
+func TestCopyMinFreeSpace(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().About == nil {
+		t.Skip("Can't read free space")
+	}
+	file1 := r.WriteFile("hello world", "hello world", t1)
+	r.Mkdir(r.Fremote)
+
+	fs.Config.MinFreeSpace = fs.SizeSuffix(1 << 62)
+	defer func() { fs.Config.MinFreeSpace = -1 }()
+
+	accounting.Stats.ResetCounters()
+	err := CopyDir(r.Fremote, r.Flocal, false)
+	assert.Equal(t, ErrorMinFreeSpace, err)
+
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote)
+
+	// Only the big file doesn't fit
+	file2 := r.WriteFile("big", strings.Repeat("x", 4<<20), t1)
+	usage, err := r.Fremote.Features().About()
+	require.NoError(t, err)
+	if usage.Free == nil {
+		t.Skip("Can't read free space")
+	}
+	fs.Config.MinFreeSpace = fs.SizeSuffix(*usage.Free - 1<<20)
+	accounting.Stats.ResetCounters()
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	assert.Equal(t, ErrorMinFreeSpace, err)
+
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	fs.Config.MinFreeSpace = 0
+	accounting.Stats.ResetCounters()
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+}
+
+// Test copy writing a manifest
+func TestCopyManifest(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
+	file2 := r.WriteBoth("unchanged", "unchanged", t2)
+
+	dir, err := ioutil.TempDir("", "rclone-manifest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	defer func() { fs.Config.Manifest = "" }()
+	for _, name := range []string{"manifest.jsonl", "manifest.csv"} {
+		fs.Config.Manifest = filepath.Join(dir, name)
+		err = CopyDir(r.Fremote, r.Flocal, false)
+		require.NoError(t, err)
+		fstest.CheckItems(t, r.Fremote, file1, file2)
+
+		data, err := ioutil.ReadFile(fs.Config.Manifest)
+		require.NoError(t, err)
+		manifest := string(data)
+		lines := strings.Split(strings.TrimSpace(manifest), "\n")
+		if name == "manifest.csv" {
+			assert.Equal(t, "Action,Path,Size,ModTime,HashType,Hash", lines[0])
+			lines = lines[1:]
+		}
+		// file1 will have been copied first time round only
+		assert.Equal(t, 2, len(lines), manifest)
+		assert.Contains(t, manifest, "unchanged")
+		if name == "manifest.jsonl" {
+			assert.Contains(t, manifest, `{"Action":"copied","Path":"sub dir/hello world","Size":11,"ModTime":"2001-02-03T04:05:06.499999999Z","HashType":"MD5","Hash":"5eb63bbbe01eeed093cb22bb8f5acdc3"}`)
+		}
+	}
+}
+
+// Test sync records the deleted files in the manifest
+func TestSyncManifestDeletes(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	dir, err := ioutil.TempDir("", "rclone-manifest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	oldDeleteMode := fs.Config.DeleteMode
+	defer func() {
+		fs.Config.Manifest = ""
+		fs.Config.DeleteMode = oldDeleteMode
+	}()
+	fs.Config.Manifest = filepath.Join(dir, "manifest.jsonl")
+	for _, deleteMode := range []fs.DeleteMode{fs.DeleteModeBefore, fs.DeleteModeDuring, fs.DeleteModeAfter} {
+		fs.Config.DeleteMode = deleteMode
+		file1 := r.WriteBoth("kept", "kept", t1)
+		r.WriteObject("deleted", "deleted", t2)
+
+		accounting.Stats.ResetCounters()
+		err = Sync(r.Fremote, r.Flocal, false)
+		require.NoError(t, err)
+		fstest.CheckItems(t, r.Fremote, file1)
+
+		data, err := ioutil.ReadFile(fs.Config.Manifest)
+		require.NoError(t, err)
+		manifest := string(data)
+		assert.Contains(t, manifest, `{"Action":"deleted","Path":"deleted","Size":7,`, "deleteMode=%v", deleteMode)
+	}
+}
+
+// Test the manifest records the files moved by a server side
+// directory move
+func TestMoveDirManifest(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().DirMove == nil {
+		t.Skip("Skipping test as remote can't move directories")
+	}
+	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	FremoteMove, _, finaliseMove, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
+	require.NoError(t, err)
+	defer finaliseMove()
+
+	dir, err := ioutil.TempDir("", "rclone-manifest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	fs.Config.Manifest = filepath.Join(dir, "manifest.jsonl")
+	defer func() { fs.Config.Manifest = "" }()
+
+	err = MoveDir(FremoteMove, r.Fremote, false, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, FremoteMove, file1)
+
+	data, err := ioutil.ReadFile(fs.Config.Manifest)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `{"Action":"moved","Path":"sub dir/hello world","Size":11,`)
+}
+
+// Test copy with --hard-links
+func TestCopyHardLinks(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().HardLink == nil {
+		t.Skip("Skipping test as remote can't make hard links")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test as hard links aren't read on windows")
+	}
+	file1 := r.WriteFile("one", "linked", t1)
+	file2 := file1
+	file2.Path = "sub dir/two"
+	require.NoError(t, os.MkdirAll(filepath.Join(r.LocalName, "sub dir"), 0777))
+	require.NoError(t, os.Link(filepath.Join(r.LocalName, "one"), filepath.Join(r.LocalName, "sub dir/two")))
+	file3 := r.WriteFile("three", "not linked", t2)
+
+	fs.Config.HardLinks = true
+	defer func() { fs.Config.HardLinks = false }()
+	err := CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	remoteName := r.Fremote.Root()
+	stat := func(remote string) os.FileInfo {
+		fi, err := os.Stat(filepath.Join(remoteName, remote))
+		require.NoError(t, err)
+		return fi
+	}
+	assert.True(t, os.SameFile(stat("one"), stat("sub dir/two")))
+	assert.False(t, os.SameFile(stat("one"), stat("three")))
+}
+
+// Test --hard-links-manifest round trip
+func TestHardLinksManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-hard-links")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+	manifest := filepath.Join(dir, "links.jsonl")
+
+	h := &hardLinks{manifest: manifest, groups: make(map[string]*hardLinkGroup)}
+	for _, remote := range []string{"b", "a", "c"} {
+		h.mu.Lock()
+		h.join("1:2", mockobject.New(remote))
+		h.mu.Unlock()
+	}
+	h.mu.Lock()
+	h.join("1:3", mockobject.New("alone"))
+	h.mu.Unlock()
+	require.NoError(t, h.Close())
+
+	data, err := ioutil.ReadFile(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, "[\"a\",\"b\",\"c\"]\n", string(data))
+
+	ids, err := readHardLinksManifest(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "manifest:1", "b": "manifest:1", "c": "manifest:1"}, ids)
+}
+
+// Test sync with --fix-case
+func TestSyncFixCase(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().CaseInsensitive {
+		t.Skip("Skipping test as remote is case insensitive")
+	}
+	file1 := r.WriteFile("sub/Hello", "hello", t1)
+	file2 := r.WriteObject("sub/hello", "hello", t1)
+
+	fs.Config.IgnoreCaseSync = true
+	fs.Config.FixCase = true
+	defer func() {
+		fs.Config.IgnoreCaseSync = false
+		fs.Config.FixCase = false
+	}()
+
+	// Check --dry-run doesn't fix the case
+	fs.Config.DryRun = true
+	err := Sync(r.Fremote, r.Flocal, false)
+	fs.Config.DryRun = false
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file2)
+
+	err = Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file1)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// Test sync with --dry-run-plan
+func TestSyncDryRunPlan(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("new", "new file", t1)
+	file2 := r.WriteFile("changed", "changed file", t2)
+	file3 := r.WriteObject("changed", "old", t1)
+	file4 := r.WriteObject("deleted", "deleted file", t1)
+	file5 := r.WriteBoth("unchanged", "unchanged", t2)
+
+	dir, err := ioutil.TempDir("", "rclone-plan")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	fs.Config.DryRun = true
+	fs.Config.DryRunPlan = filepath.Join(dir, "plan.json")
+	defer func() {
+		fs.Config.DryRun = false
+		fs.Config.DryRunPlan = ""
+	}()
+	err = Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Flocal, file1, file2, file5)
+	fstest.CheckItems(t, r.Fremote, file3, file4, file5)
+
+	data, err := ioutil.ReadFile(fs.Config.DryRunPlan)
+	require.NoError(t, err)
+	var got planReport
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, planReport{
+		Adds:    1,
+		Updates: 1,
+		Deletes: 1,
+		Bytes:   20,
+		Items: []planItem{
+			{Action: planUpdate, Path: "changed", Size: 12},
+			{Action: planDelete, Path: "deleted", Size: 12},
+			{Action: planAdd, Path: "new", Size: 8},
+		},
+	}, got)
+}
+
+// Test copy with --upload-cache
+func TestCopyUploadCache(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
+
+	dir, err := ioutil.TempDir("", "rclone-upload-cache")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	fs.Config.UploadCache = filepath.Join(dir, "uploaded.db")
+	defer func() {
+		fs.Config.UploadCache = ""
+		fs.Config.UploadCacheVerify = false
+	}()
+
+	// First copy uploads the file and records it
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// Remove it from the destination behind rclone's back - the
+	// next copy should skip it without looking
+	obj, err := r.Fremote.NewObject(file1.Path)
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove())
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote)
+
+	// With verify the missing file is noticed and uploaded again
+	fs.Config.UploadCacheVerify = true
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// Changing the source invalidates the entry
+	fs.Config.UploadCacheVerify = false
+	file1 = r.WriteFile("sub dir/hello world", "hello world again", t2)
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// Test copy with --upload-cache and files from streamed from stdin
+func TestCopyUploadCacheWithFilesFromStdin(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("potato2", "hello world", t1)
+
+	dir, err := ioutil.TempDir("", "rclone-upload-cache")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	fs.Config.UploadCache = filepath.Join(dir, "uploaded.db")
+	defer func() {
+		fs.Config.UploadCache = ""
+	}()
+
+	// First copy uploads the file and records it
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	// Remove it from the destination behind rclone's back
+	obj, err := r.Fremote.NewObject(file1.Path)
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove())
+
+	// Read the --files-from list from stdin
+	in, err := ioutil.TempFile("", "rclone-files-from")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, in.Close())
+		require.NoError(t, os.Remove(in.Name()))
+	}()
+	_, err = in.WriteString("potato2\n")
+	require.NoError(t, err)
+	_, err = in.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	oldStdin := os.Stdin
+	os.Stdin = in
+	defer func() { os.Stdin = oldStdin }()
+
+	opt := filter.DefaultOpt
+	opt.FilesFrom = []string{"-"}
+	f, err := filter.NewFilter(&opt)
+	require.NoError(t, err)
+
+	// Monkey patch the active filter
+	oldFilter := filter.Active
+	filter.Active = f
+	defer func() {
+		filter.Active = oldFilter
+	}()
+
+	// The streamed copy should skip the file without looking
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote)
+}
+
 // Test copy with files from
 func TestCopyWithFilesFrom(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -135,7 +553,7 @@ func TestCopyWithFilesFrom(t *testing.T) {
 	}
 	defer unpatch()
 
-	err = CopyDir(r.Fremote, r.Flocal)
+	err = CopyDir(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	unpatch()
 
@@ -143,6 +561,53 @@ func TestCopyWithFilesFrom(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1)
 }
 
+// Test copy with files from streamed from stdin
+func TestCopyWithFilesFromStdin(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("potato2", "hello world", t1)
+	file2 := r.WriteFile("hello world2", "hello world2", t2)
+	file3 := r.WriteFile("sub dir/potato3", "hello world3", t2)
+	file4 := r.WriteObject("sub dir/potato3", "hello", t1)
+	fstest.CheckItems(t, r.Fremote, file4)
+
+	// Read the --files-from list from stdin
+	in, err := ioutil.TempFile("", "rclone-files-from")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, in.Close())
+		require.NoError(t, os.Remove(in.Name()))
+	}()
+	_, err = in.WriteString("potato2\x00notfound\x00sub dir/potato3\x00")
+	require.NoError(t, err)
+	_, err = in.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	oldStdin := os.Stdin
+	os.Stdin = in
+	defer func() { os.Stdin = oldStdin }()
+
+	opt := filter.DefaultOpt
+	opt.FilesFrom = []string{"-"}
+	opt.From0 = true
+	f, err := filter.NewFilter(&opt)
+	require.NoError(t, err)
+
+	// Monkey patch the active filter
+	oldFilter := filter.Active
+	filter.Active = f
+	unpatch := func() {
+		filter.Active = oldFilter
+	}
+	defer unpatch()
+
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	unpatch()
+
+	fstest.CheckItems(t, r.Flocal, file1, file2, file3)
+	fstest.CheckItems(t, r.Fremote, file1, file3)
+}
+
 // Test copy empty directories
 func TestCopyEmptyDirectories(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -152,7 +617,7 @@ func TestCopyEmptyDirectories(t *testing.T) {
 	require.NoError(t, err)
 	r.Mkdir(r.Fremote)
 
-	err = CopyDir(r.Fremote, r.Flocal)
+	err = CopyDir(r.Fremote, r.Flocal, true)
 	require.NoError(t, err)
 
 	fstest.CheckListingWithPrecision(
@@ -169,6 +634,63 @@ func TestCopyEmptyDirectories(t *testing.T) {
 	)
 }
 
+// Test copy without empty directories
+func TestCopyWithoutEmptyDirectories(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteFile("sub dir/hello world", "hello world", t1)
+	err := operations.Mkdir(r.Flocal, "sub dir2")
+	require.NoError(t, err)
+	r.Mkdir(r.Fremote)
+
+	err = CopyDir(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+
+	fstest.CheckListingWithPrecision(
+		t,
+		r.Fremote,
+		[]fstest.Item{
+			file1,
+		},
+		[]string{
+			"sub dir",
+		},
+		fs.GetModifyWindow(r.Fremote),
+	)
+}
+
+// Test copy preserves directory modification times
+func TestCopyDirModTime(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().DirSetModTime == nil {
+		t.Skip("Can't set directory modification times")
+	}
+	r.WriteFile("sub dir/hello world", "hello world", t1)
+	require.NoError(t, operations.Mkdir(r.Flocal, "sub dir2"))
+	require.NoError(t, r.Flocal.Features().DirSetModTime("sub dir", t2))
+	require.NoError(t, r.Flocal.Features().DirSetModTime("sub dir2", t3))
+	r.Mkdir(r.Fremote)
+
+	err := CopyDir(r.Fremote, r.Flocal, true)
+	require.NoError(t, err)
+
+	entries, err := r.Fremote.List("")
+	require.NoError(t, err)
+	want := map[string]time.Time{
+		"sub dir":  t2,
+		"sub dir2": t3,
+	}
+	for _, entry := range entries {
+		if dir, ok := entry.(fs.Directory); ok {
+			dt, ok := fstest.CheckTimeEqualWithPrecision(want[dir.Remote()], dir.ModTime(), fs.GetModifyWindow(r.Fremote))
+			assert.True(t, ok, "%s: modification time out by %v", dir.Remote(), dt)
+			delete(want, dir.Remote())
+		}
+	}
+	assert.Empty(t, want)
+}
+
 // Test a server side copy if possible, or the backup path if not
 func TestServerSideCopy(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -181,7 +703,7 @@ func TestServerSideCopy(t *testing.T) {
 	defer finaliseCopy()
 	t.Logf("Server side copy (if possible) %v -> %v", r.Fremote, FremoteCopy)
 
-	err = CopyDir(FremoteCopy, r.Fremote)
+	err = CopyDir(FremoteCopy, r.Fremote, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, FremoteCopy, file1)
@@ -199,7 +721,7 @@ func TestCopyAfterDelete(t *testing.T) {
 	err := operations.Mkdir(r.Flocal, "")
 	require.NoError(t, err)
 
-	err = CopyDir(r.Fremote, r.Flocal)
+	err = CopyDir(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal)
@@ -213,7 +735,7 @@ func TestCopyRedownload(t *testing.T) {
 	file1 := r.WriteObject("sub dir/hello world", "hello world", t1)
 	fstest.CheckItems(t, r.Fremote, file1)
 
-	err := CopyDir(r.Flocal, r.Fremote)
+	err := CopyDir(r.Flocal, r.Fremote, false)
 	require.NoError(t, err)
 
 	// Test with combined precision of local and remote as we copied it there and back
@@ -233,7 +755,7 @@ func TestSyncBasedOnCheckSum(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file1)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file.
@@ -245,7 +767,7 @@ func TestSyncBasedOnCheckSum(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred no files
@@ -267,7 +789,7 @@ func TestSyncSizeOnly(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file1)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file.
@@ -279,7 +801,7 @@ func TestSyncSizeOnly(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred no files
@@ -301,7 +823,7 @@ func TestSyncIgnoreSize(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file1)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file.
@@ -313,7 +835,7 @@ func TestSyncIgnoreSize(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred no files
@@ -329,7 +851,7 @@ func TestSyncIgnoreTimes(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred exactly 0 files because the
@@ -340,7 +862,7 @@ func TestSyncIgnoreTimes(t *testing.T) {
 	defer func() { fs.Config.IgnoreTimes = false }()
 
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file even though the
@@ -360,7 +882,7 @@ func TestSyncIgnoreExisting(t *testing.T) {
 	defer func() { fs.Config.IgnoreExisting = false }()
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1)
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -368,7 +890,7 @@ func TestSyncIgnoreExisting(t *testing.T) {
 	// Change everything
 	r.WriteFile("existing", "newpotatoes", t2)
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	// Items should not change
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -416,7 +938,7 @@ func TestSyncIgnoreErrors(t *testing.T) {
 
 	accounting.Stats.ResetCounters()
 	fs.CountError(nil)
-	assert.NoError(t, Sync(r.Fremote, r.Flocal))
+	assert.NoError(t, Sync(r.Fremote, r.Flocal, false))
 
 	fstest.CheckListingWithPrecision(
 		t,
@@ -459,7 +981,7 @@ func TestSyncAfterChangingModtimeOnly(t *testing.T) {
 	defer func() { fs.Config.DryRun = false }()
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -468,7 +990,7 @@ func TestSyncAfterChangingModtimeOnly(t *testing.T) {
 	fs.Config.DryRun = false
 
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -496,7 +1018,7 @@ func TestSyncAfterChangingModtimeOnlyWithNoUpdateModTime(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -517,7 +1039,7 @@ func TestSyncDoesntUpdateModtime(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Flocal, file1)
@@ -537,7 +1059,7 @@ func TestSyncAfterAddingAFile(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1, file2)
 	fstest.CheckItems(t, r.Fremote, file1, file2)
@@ -552,7 +1074,7 @@ func TestSyncAfterChangingFilesSizeOnly(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file2)
 	fstest.CheckItems(t, r.Fremote, file2)
@@ -575,7 +1097,7 @@ func TestSyncAfterChangingContentsOnly(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file2)
 	fstest.CheckItems(t, r.Fremote, file2)
@@ -591,7 +1113,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileDryRun(t *testing.T) {
 
 	fs.Config.DryRun = true
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	fs.Config.DryRun = false
 	require.NoError(t, err)
 
@@ -610,12 +1132,107 @@ func TestSyncAfterRemovingAFileAndAddingAFile(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file1, file3)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1, file3)
 	fstest.CheckItems(t, r.Fremote, file1, file3)
 }
 
+// Sync refusing to delete everything as the source is empty
+func TestSyncRefuseEmptySource(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("potato", "potato", t1)
+	file2 := r.WriteObject("sub dir/potato2", "potato2", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+	// the source must exist as a missing one is an IO error
+	r.ForceMkdir(r.Flocal)
+
+	fs.Config.RefuseEmptySource = true
+	defer func() {
+		fs.Config.RefuseEmptySource = false
+		fs.Config.DeleteMode = fs.DeleteModeDefault
+		accounting.Stats.ResetCounters()
+	}()
+	accounting.Stats.ResetCounters()
+	for _, deleteMode := range []fs.DeleteMode{fs.DeleteModeAfter, fs.DeleteModeDuring, fs.DeleteModeBefore} {
+		fs.Config.DeleteMode = deleteMode
+		accounting.Stats.ResetCounters()
+		err := Sync(r.Fremote, r.Flocal, false)
+		require.Error(t, err)
+		assert.True(t, fserrors.IsFatalError(err), err)
+		assert.Contains(t, err.Error(), "--refuse-empty-source")
+		fstest.CheckItems(t, r.Fremote, file1, file2)
+	}
+
+	// The guard is reported even if there were IO errors
+	fs.Config.DeleteMode = fs.DeleteModeAfter
+	accounting.Stats.ResetCounters()
+	accounting.Stats.Error(errors.New("IO error"))
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--refuse-empty-source")
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	// Sync works when the source isn't empty
+	file3 := r.WriteFile("potato", "potato", t1)
+	fs.Config.DeleteMode = fs.DeleteModeDefault
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
+	fstest.CheckItems(t, r.Flocal, file3)
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// Sync refusing to delete too large a percentage of the destination
+func TestSyncMaxDeletePercent(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato", "potato", t1)
+	file2 := r.WriteObject("potato2", "potato2", t2)
+	file3 := r.WriteObject("potato3", "potato3", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	defer func() {
+		fs.Config.MaxDeletePercent = -1
+	}()
+	fs.Config.MaxDeletePercent = 50
+	accounting.Stats.ResetCounters()
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err), err)
+	assert.Contains(t, err.Error(), "--max-delete-percent")
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	fs.Config.MaxDeletePercent = 67
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
+// Sync stopping when --max-delete-size is reached
+func TestSyncMaxDeleteSize(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato", "potato", t1)
+	file2 := r.WriteObject("potato2", "potato2", t2)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	defer func() {
+		fs.Config.MaxDeleteSize = -1
+	}()
+	fs.Config.MaxDeleteSize = 6
+	accounting.Stats.ResetCounters()
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsFatalError(err), err)
+	fstest.CheckItems(t, r.Fremote, file1, file2)
+
+	fs.Config.MaxDeleteSize = 7
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
+	fstest.CheckItems(t, r.Fremote, file1)
+}
+
 // Sync after removing a file and adding a file
 func TestSyncAfterRemovingAFileAndAddingAFileSubDir(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -656,7 +1273,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileSubDir(t *testing.T) {
 	)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckListingWithPrecision(
@@ -726,7 +1343,7 @@ func TestSyncAfterRemovingAFileAndAddingAFileSubDirWithErrors(t *testing.T) {
 
 	accounting.Stats.ResetCounters()
 	fs.CountError(nil)
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	assert.Equal(t, fs.ErrorNotDeleting, err)
 
 	fstest.CheckListingWithPrecision(
@@ -803,7 +1420,7 @@ func TestCopyDeleteBefore(t *testing.T) {
 	fstest.CheckItems(t, r.Flocal, file2)
 
 	accounting.Stats.ResetCounters()
-	err := CopyDir(r.Fremote, r.Flocal)
+	err := CopyDir(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	fstest.CheckItems(t, r.Fremote, file1, file2)
@@ -826,18 +1443,39 @@ func TestSyncWithExclude(t *testing.T) {
 	}()
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Fremote, file2, file1)
 
 	// Now sync the other way round and check enormous doesn't get
 	// deleted as it is excluded from the sync
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Flocal, r.Fremote)
+	err = Sync(r.Flocal, r.Fremote, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file2, file1, file3)
 }
 
+// Test with --delete-max-size
+func TestSyncWithDeleteMaxSize(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1) // 60 bytes
+	file2 := r.WriteObject("small", "small", t1)
+	file3 := r.WriteObject("enormous", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", t1) // 100 bytes
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+	fstest.CheckItems(t, r.Flocal, file1)
+
+	filter.Active.Opt.DeleteMaxSize = 40
+	defer func() {
+		filter.Active.Opt.DeleteMaxSize = -1
+	}()
+
+	accounting.Stats.ResetCounters()
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file3)
+}
+
 // Test with exclude and delete excluded
 func TestSyncWithExcludeAndDeleteExcluded(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -850,24 +1488,52 @@ func TestSyncWithExcludeAndDeleteExcluded(t *testing.T) {
 
 	filter.Active.Opt.MaxSize = 40
 	filter.Active.Opt.DeleteExcluded = true
+	fs.Config.Force = true
 	defer func() {
 		filter.Active.Opt.MaxSize = -1
 		filter.Active.Opt.DeleteExcluded = false
+		fs.Config.Force = false
 	}()
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Fremote, file2)
 
 	// Check sync the other way round to make sure enormous gets
 	// deleted even though it is excluded
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Flocal, r.Fremote)
+	err = Sync(r.Flocal, r.Fremote, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file2)
 }
 
+// Test with exclude and delete excluded but without --force
+func TestSyncWithExcludeAndDeleteExcludedNoForce(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteBoth("potato2", "------------------------------------------------------------", t1) // 60 bytes
+	file2 := r.WriteBoth("empty space", "", t2)
+	file3 := r.WriteObject("enormous", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", t1) // 100 bytes
+	file4 := r.WriteObject("small", "small", t1)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3, file4)
+	fstest.CheckItems(t, r.Flocal, file1, file2)
+
+	filter.Active.Opt.MaxSize = 40
+	filter.Active.Opt.DeleteExcluded = true
+	defer func() {
+		filter.Active.Opt.MaxSize = -1
+		filter.Active.Opt.DeleteExcluded = false
+	}()
+
+	// The excluded files are kept but the file only on the
+	// destination which isn't excluded is deleted
+	accounting.Stats.ResetCounters()
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+}
+
 // Test with UpdateOlder set
 func TestSyncWithUpdateOlder(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -898,11 +1564,50 @@ func TestSyncWithUpdateOlder(t *testing.T) {
 	}()
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Fremote, oneO, twoF, threeO, fourF, fiveF)
 }
 
+// Test with --conflict-resolve
+func TestSyncWithConflictResolve(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if fs.GetModifyWindow(r.Fremote) == fs.ModTimeNotSupported {
+		t.Skip("Can't run this test on fs which doesn't support mod time")
+	}
+	defer func() { fs.Config.ConflictResolve = "" }()
+
+	fs.Config.ConflictResolve = "potato"
+	err := Sync(r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+
+	// the same mod times and different sizes are a conflict
+	small := r.WriteFile("small", "small", t2)
+	large := r.WriteFile("large", "large file", t2)
+	newer := r.WriteFile("newer", "newer", t3)
+	smallO := r.WriteObject("small", "small file", t2)
+	largeO := r.WriteObject("large", "large", t2)
+	newerO := r.WriteObject("newer", "newer file", t1)
+	fstest.CheckItems(t, r.Fremote, smallO, largeO, newerO)
+
+	// newer isn't a conflict as the times differ
+	fs.Config.ConflictResolve = operations.ConflictResolveDest
+	err = Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, smallO, largeO, newer)
+
+	fs.Config.ConflictResolve = operations.ConflictResolveLarger
+	err = Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, smallO, large, newer)
+
+	fs.Config.ConflictResolve = operations.ConflictResolveSource
+	err = Sync(r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	fstest.CheckItems(t, r.Fremote, small, large, newer)
+}
+
 // Test with TrackRenames set
 func TestSyncWithTrackRenames(t *testing.T) {
 	r := fstest.NewRun(t)
@@ -922,7 +1627,7 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	f2 := r.WriteFile("yam", "Yam Content", t2)
 
 	accounting.Stats.ResetCounters()
-	require.NoError(t, Sync(r.Fremote, r.Flocal))
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
 
 	fstest.CheckItems(t, r.Fremote, f1, f2)
 	fstest.CheckItems(t, r.Flocal, f1, f2)
@@ -931,7 +1636,7 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	f2 = r.RenameFile(f2, "yaml")
 
 	accounting.Stats.ResetCounters()
-	require.NoError(t, Sync(r.Fremote, r.Flocal))
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
 
 	fstest.CheckItems(t, r.Fremote, f1, f2)
 
@@ -942,6 +1647,54 @@ func TestSyncWithTrackRenames(t *testing.T) {
 	}
 }
 
+// Test renaming a directory with TrackRenames set
+func TestSyncWithTrackRenamesDir(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	fs.Config.TrackRenames = true
+	defer func() {
+		fs.Config.TrackRenames = false
+	}()
+
+	haveHash := r.Fremote.Hashes().Overlap(r.Flocal.Hashes()).GetOne() != hash.None
+	canTrackRenames := haveHash && operations.CanServerSideMove(r.Fremote)
+	canRenameDirs := canTrackRenames && r.Fremote.Features().DirMove != nil
+	t.Logf("Can track renames: %v, can rename directories: %v", canTrackRenames, canRenameDirs)
+
+	f1 := r.WriteFile("potato", "Potato Content", t1)
+	f2 := r.WriteFile("dir/yam", "Yam Content", t2)
+	f3 := r.WriteFile("dir/sub/sweet", "Sweet Potato Content", t2)
+
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
+
+	fstest.CheckItems(t, r.Fremote, f1, f2, f3)
+
+	// Now rename the directory locally
+	require.NoError(t, os.Rename(filepath.Join(r.LocalName, "dir"), filepath.Join(r.LocalName, "renamed")))
+	f2.Path = "renamed/yam"
+	f3.Path = "renamed/sub/sweet"
+
+	accounting.Stats.ResetCounters()
+	require.NoError(t, Sync(r.Fremote, r.Flocal, false))
+
+	fstest.CheckItems(t, r.Fremote, f1, f2, f3)
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{f1, f2, f3}, []string{"renamed", "renamed/sub"}, fs.GetModifyWindow(r.Fremote))
+
+	moves, _ := accounting.Stats.GetServerSideMoves()
+	switch {
+	case canRenameDirs:
+		assert.Equal(t, int64(0), accounting.Stats.GetTransfers())
+		assert.Equal(t, int64(1), moves)
+	case canTrackRenames:
+		assert.Equal(t, int64(0), accounting.Stats.GetTransfers())
+		assert.Equal(t, int64(2), moves)
+	default:
+		assert.Equal(t, int64(2), accounting.Stats.GetTransfers())
+	}
+}
+
 // Test a server side move if possible, or the backup path if not
 func testServerSideMove(t *testing.T, r *fstest.Run, withFilter, testDeleteEmptyDirs bool) {
 	FremoteMove, _, finaliseMove, err := fstest.RandomRemote(*fstest.RemoteName, *fstest.SubDir)
@@ -968,7 +1721,7 @@ func testServerSideMove(t *testing.T, r *fstest.Run, withFilter, testDeleteEmpty
 
 	// Do server side move
 	accounting.Stats.ResetCounters()
-	err = MoveDir(FremoteMove, r.Fremote, testDeleteEmptyDirs)
+	err = MoveDir(FremoteMove, r.Fremote, testDeleteEmptyDirs, false)
 	require.NoError(t, err)
 
 	if withFilter {
@@ -995,7 +1748,7 @@ func testServerSideMove(t *testing.T, r *fstest.Run, withFilter, testDeleteEmpty
 
 	// Move it back to a new empty remote, dst does not exist this time
 	accounting.Stats.ResetCounters()
-	err = MoveDir(FremoteMove2, FremoteMove, testDeleteEmptyDirs)
+	err = MoveDir(FremoteMove2, FremoteMove, testDeleteEmptyDirs, false)
 	require.NoError(t, err)
 
 	if withFilter {
@@ -1020,7 +1773,7 @@ func TestMoveWithDeleteEmptySrcDirs(t *testing.T) {
 	r.Mkdir(r.Fremote)
 
 	// run move with --delete-empty-src-dirs
-	err := MoveDir(r.Fremote, r.Flocal, true)
+	err := MoveDir(r.Fremote, r.Flocal, true, false)
 	require.NoError(t, err)
 
 	fstest.CheckListingWithPrecision(
@@ -1040,7 +1793,7 @@ func TestMoveWithoutDeleteEmptySrcDirs(t *testing.T) {
 	file2 := r.WriteFile("nested/sub dir/file", "nested", t1)
 	r.Mkdir(r.Fremote)
 
-	err := MoveDir(r.Fremote, r.Flocal, false)
+	err := MoveDir(r.Fremote, r.Flocal, false, false)
 	require.NoError(t, err)
 
 	fstest.CheckListingWithPrecision(
@@ -1101,7 +1854,7 @@ func TestServerSideMoveOverlap(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file1)
 
 	// Subdir move with no filters should return ErrorCantMoveOverlapping
-	err = MoveDir(FremoteMove, r.Fremote, false)
+	err = MoveDir(FremoteMove, r.Fremote, false, false)
 	assert.EqualError(t, err, fs.ErrorCantMoveOverlapping.Error())
 
 	// Now try with a filter which should also fail with ErrorCantMoveOverlapping
@@ -1109,7 +1862,7 @@ func TestServerSideMoveOverlap(t *testing.T) {
 	defer func() {
 		filter.Active.Opt.MinSize = -1
 	}()
-	err = MoveDir(FremoteMove, r.Fremote, false)
+	err = MoveDir(FremoteMove, r.Fremote, false, false)
 	assert.EqualError(t, err, fs.ErrorCantMoveOverlapping.Error())
 }
 
@@ -1145,7 +1898,7 @@ func testSyncBackupDir(t *testing.T, suffix string) {
 	require.NoError(t, err)
 
 	accounting.Stats.ResetCounters()
-	err = Sync(fdst, r.Flocal)
+	err = Sync(fdst, r.Flocal, false)
 	require.NoError(t, err)
 
 	// one should be moved to the backup dir and the new one installed
@@ -1166,7 +1919,7 @@ func testSyncBackupDir(t *testing.T, suffix string) {
 	// This should delete three and overwrite one again, checking
 	// the files got overwritten correctly in backup-dir
 	accounting.Stats.ResetCounters()
-	err = Sync(fdst, r.Flocal)
+	err = Sync(fdst, r.Flocal, false)
 	require.NoError(t, err)
 
 	// one should be moved to the backup dir and the new one installed
@@ -1203,7 +1956,7 @@ func TestSyncUTFNorm(t *testing.T) {
 	fstest.CheckItems(t, r.Fremote, file2)
 
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 
 	// We should have transferred exactly one file, but kept the
@@ -1229,7 +1982,7 @@ func TestSyncImmutable(t *testing.T) {
 
 	// Should succeed
 	accounting.Stats.ResetCounters()
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	require.NoError(t, err)
 	fstest.CheckItems(t, r.Flocal, file1)
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -1241,7 +1994,7 @@ func TestSyncImmutable(t *testing.T) {
 
 	// Should fail with ErrorImmutableModified and not modify local or remote files
 	accounting.Stats.ResetCounters()
-	err = Sync(r.Fremote, r.Flocal)
+	err = Sync(r.Fremote, r.Flocal, false)
 	assert.EqualError(t, err, fs.ErrorImmutableModified.Error())
 	fstest.CheckItems(t, r.Flocal, file2)
 	fstest.CheckItems(t, r.Fremote, file1)
@@ -1277,6 +2030,6 @@ func TestAbort(t *testing.T) {
 
 	accounting.Stats.ResetCounters()
 
-	err := Sync(r.Fremote, r.Flocal)
+	err := Sync(r.Fremote, r.Flocal, false)
 	assert.Equal(t, accounting.ErrorMaxTransferLimitReached, err)
 }
@@ -0,0 +1,173 @@
+// +build !plan9
+
+package sync
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
+	"github.com/ncw/rclone/fs/hash"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// name of the bolt bucket the uploaded files are stored in
+var uploadCacheBucket = []byte("uploaded")
+
+// uploadCache is a persistent record of the files which have been
+// uploaded to a destination, used by --upload-cache to skip
+// unchanged files without looking at the destination.
+type uploadCache struct {
+	db       *bolt.DB
+	fdst     fs.Fs
+	prefix   string        // prefix for the keys for fdst
+	hashType hash.Type     // type of the source hash recorded
+	ttl      time.Duration // entries older than this are ignored, 0 for never
+	verify   bool          // check the destination exists before skipping
+}
+
+// uploadCacheEntry is what is recorded for each file uploaded
+type uploadCacheEntry struct {
+	Size     int64     `json:"size"`
+	Hash     string    `json:"hash"`
+	HashType string    `json:"hashType"`
+	Uploaded time.Time `json:"uploaded"`
+}
+
+// newUploadCache opens the --upload-cache database for copying fsrc
+// to fdst or returns nil if --upload-cache isn't in use.
+func newUploadCache(fdst, fsrc fs.Fs) (*uploadCache, error) {
+	if fs.Config.UploadCache == "" {
+		return nil, nil
+	}
+	hashType := fsrc.Hashes().GetOne()
+	if hashType == hash.None {
+		fs.Errorf(fsrc, "Ignoring --upload-cache as the source doesn't support any hashes")
+		return nil, nil
+	}
+	db, err := bolt.Open(fs.Config.UploadCache, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open --upload-cache")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(uploadCacheBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to initialise --upload-cache")
+	}
+	return &uploadCache{
+		db:       db,
+		fdst:     fdst,
+		prefix:   fdst.Name() + ":" + fdst.Root() + "\x00",
+		hashType: hashType,
+		ttl:      fs.Config.UploadCacheTTL,
+		verify:   fs.Config.UploadCacheVerify,
+	}, nil
+}
+
+// key returns the database key for remote in the destination
+func (c *uploadCache) key(remote string) []byte {
+	return []byte(c.prefix + remote)
+}
+
+// get reads the entry for remote returning nil if not found
+func (c *uploadCache) get(remote string) (entry *uploadCacheEntry) {
+	_ = c.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(uploadCacheBucket).Get(c.key(remote))
+		if data == nil {
+			return nil
+		}
+		entry = new(uploadCacheEntry)
+		if err := json.Unmarshal(data, entry); err != nil {
+			fs.Debugf(remote, "Ignoring corrupted --upload-cache entry: %v", err)
+			entry = nil
+		}
+		return nil
+	})
+	return entry
+}
+
+// Skip returns true if src is recorded as already uploaded with the
+// same size and hash so doesn't need to be checked or transferred.
+//
+// It is safe to call on a nil *uploadCache.
+func (c *uploadCache) Skip(src fs.Object) bool {
+	if c == nil {
+		return false
+	}
+	entry := c.get(src.Remote())
+	if entry == nil || entry.Size != src.Size() || entry.HashType != c.hashType.String() {
+		return false
+	}
+	if c.ttl > 0 && time.Since(entry.Uploaded) > c.ttl {
+		return false
+	}
+	accounting.Stats.Checking(src.Remote())
+	defer accounting.Stats.DoneChecking(src.Remote())
+	srcHash, err := src.Hash(c.hashType)
+	if err != nil || srcHash == "" || srcHash != entry.Hash {
+		return false
+	}
+	if c.verify && !c.verifyDst(src) {
+		return false
+	}
+	fs.Debugf(src, "Unchanged according to --upload-cache")
+	return true
+}
+
+// verifyDst checks the destination of src still exists with the
+// right size, forgetting it if not
+func (c *uploadCache) verifyDst(src fs.Object) bool {
+	dst, err := c.fdst.NewObject(src.Remote())
+	if err == nil && dst.Size() == src.Size() {
+		return true
+	}
+	fs.Debugf(src, "Destination missing or changed so ignoring --upload-cache entry")
+	_ = c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadCacheBucket).Delete(c.key(src.Remote()))
+	})
+	return false
+}
+
+// Record notes that src has been uploaded or found to be identical
+// to the destination.
+//
+// It is safe to call on a nil *uploadCache.
+func (c *uploadCache) Record(src fs.Object) {
+	if c == nil || fs.Config.DryRun {
+		return
+	}
+	srcHash, err := src.Hash(c.hashType)
+	if err != nil || srcHash == "" {
+		return
+	}
+	data, err := json.Marshal(uploadCacheEntry{
+		Size:     src.Size(),
+		Hash:     srcHash,
+		HashType: c.hashType.String(),
+		Uploaded: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	err = c.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(uploadCacheBucket).Put(c.key(src.Remote()), data)
+	})
+	if err != nil {
+		fs.Errorf(src, "Failed to write --upload-cache entry: %v", err)
+	}
+}
+
+// Close the upload cache
+//
+// It is safe to call on a nil *uploadCache.
+func (c *uploadCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.db.Close()
+}
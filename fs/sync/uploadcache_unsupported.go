@@ -0,0 +1,35 @@
+// Stub --upload-cache for the platforms bolt doesn't support
+
+// +build plan9
+
+package sync
+
+import (
+	"github.com/ncw/rclone/fs"
+)
+
+// uploadCache isn't supported on this platform
+type uploadCache struct{}
+
+// newUploadCache returns nil as --upload-cache isn't supported on
+// this platform.
+func newUploadCache(fdst, fsrc fs.Fs) (*uploadCache, error) {
+	if fs.Config.UploadCache != "" {
+		fs.Errorf(fdst, "Ignoring --upload-cache as it isn't supported on this platform")
+	}
+	return nil, nil
+}
+
+// Skip returns false as no uploads are recorded
+func (c *uploadCache) Skip(src fs.Object) bool {
+	return false
+}
+
+// Record does nothing as no uploads are recorded
+func (c *uploadCache) Record(src fs.Object) {
+}
+
+// Close the upload cache
+func (c *uploadCache) Close() error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package walk
+
+import (
+	"github.com/ncw/rclone/fs"
+)
+
+// Thresholds for --fast-list auto
+const (
+	// Listing the tree under a directory with at least this many
+	// subdirectories with ListR should take fewer transactions than
+	// listing each directory.
+	autoListRMinDirs = 8
+	// Remotes which aren't bucket based make ListR out of directory
+	// listings so it needs a bigger tree to be worth it.
+	autoListRMinDirsNotBucketBased = 32
+	// ListR keeps the whole tree in memory so don't use it if the
+	// tree is estimated to have more entries than this.
+	autoListRMaxEntries = 1000000
+)
+
+// UseListR returns whether the tree under the directory with the
+// entries given should be listed with ListR when --fast-list is auto.
+//
+// The number of entries in the tree is estimated from the number of
+// items in each subdirectory, if the remote knows it, or by assuming
+// each subdirectory has as many entries as the directory otherwise.
+// ListR is used if the directory has enough subdirectories for it to
+// save transactions, but not so many entries that it would use too
+// much memory.
+func UseListR(f fs.Fs, entries fs.DirEntries) bool {
+	if f.Features().ListR == nil {
+		return false
+	}
+	dirs := 0
+	estimate := int64(len(entries))
+	entries.ForDir(func(dir fs.Directory) {
+		dirs++
+		if items := dir.Items(); items >= 0 {
+			estimate += items
+		} else {
+			estimate += int64(len(entries))
+		}
+	})
+	if estimate > autoListRMaxEntries {
+		return false
+	}
+	if f.Features().BucketBased {
+		return dirs >= autoListRMinDirs
+	}
+	return dirs >= autoListRMinDirsNotBucketBased
+}
@@ -0,0 +1,94 @@
+package walk
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/filter"
+	"github.com/ncw/rclone/fstest/mockdir"
+	"github.com/ncw/rclone/fstest/mockfs"
+	"github.com/ncw/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeDirs makes n directories in the root
+func makeDirs(n int) (entries fs.DirEntries) {
+	for i := 0; i < n; i++ {
+		entries = append(entries, mockdir.New(fmt.Sprintf("dir%d", i)))
+	}
+	return entries
+}
+
+func TestUseListR(t *testing.T) {
+	f := mockfs.NewFs("mock", "/")
+
+	// no ListR
+	assert.False(t, UseListR(f, makeDirs(100)))
+
+	f.Features().ListR = func(dir string, callback fs.ListRCallback) error {
+		return nil
+	}
+	assert.False(t, UseListR(f, makeDirs(autoListRMinDirsNotBucketBased-1)))
+	assert.True(t, UseListR(f, makeDirs(autoListRMinDirsNotBucketBased)))
+
+	f.Features().BucketBased = true
+	assert.False(t, UseListR(f, fs.DirEntries{mockobject.Object("potato")}))
+	assert.False(t, UseListR(f, makeDirs(autoListRMinDirs-1)))
+	assert.True(t, UseListR(f, makeDirs(autoListRMinDirs)))
+
+	// too many entries
+	entries := makeDirs(autoListRMinDirs)
+	entries[0] = fs.NewDir("dir0", time.Time{}).SetItems(autoListRMaxEntries)
+	assert.False(t, UseListR(f, entries))
+}
+
+func TestWalkFastListAuto(t *testing.T) {
+	oldFastListAuto := fs.Config.FastListAuto
+	defer func() { fs.Config.FastListAuto = oldFastListAuto }()
+	fs.Config.FastListAuto = true
+
+	f := mockfs.NewFs("mock", "/")
+	f.Features().BucketBased = true
+	root := makeDirs(autoListRMinDirs)
+	tree := fs.DirEntries{}
+	for _, dir := range root {
+		tree = append(tree, dir, mockobject.Object(dir.Remote()+"/file"))
+	}
+	f.Features().ListR = func(dir string, callback fs.ListRCallback) error {
+		assert.Equal(t, "", dir)
+		return callback(tree)
+	}
+	listDir := func(gotF fs.Fs, includeAll bool, dir string) (fs.DirEntries, error) {
+		assert.Equal(t, "", dir, "only the root should be listed with List")
+		return root, nil
+	}
+
+	var (
+		mu   sync.Mutex
+		dirs []string
+	)
+	err := walk(f, filter.Active, "", true, -1, func(dir string, entries fs.DirEntries, err error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, err)
+		dirs = append(dirs, dir)
+		if dir != "" {
+			assert.Equal(t, fs.DirEntries{mockobject.Object(dir + "/file")}, entries)
+		}
+		return nil
+	}, listDir)
+	require.NoError(t, err)
+	assert.Equal(t, "", dirs[0])
+	sort.Strings(dirs)
+	want := []string{""}
+	for _, dir := range root {
+		want = append(want, dir.Remote())
+	}
+	sort.Strings(want)
+	assert.Equal(t, want, dirs)
+}
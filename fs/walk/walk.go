@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/accounting"
 	"github.com/ncw/rclone/fs/filter"
 	"github.com/ncw/rclone/fs/list"
 	"github.com/pkg/errors"
@@ -52,44 +53,60 @@ type Func func(path string, entries fs.DirEntries, err error) error
 // Parent directories are always listed before their children
 //
 // This is implemented by WalkR if Config.UseRecursiveListing is true
-// and f supports it and level > 1, or WalkN otherwise.
+// and f supports it and level > 1, or WalkN otherwise.  If
+// Config.FastListAuto is set then WalkN may use WalkR for the
+// directory trees UseListR chooses.
 //
 // If --files-from is set then a DirTree will be constructed with just
 // those files in and then walked with WalkR
 //
 // NB (f, path) to be replaced by fs.Dir at some point
 func Walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
-	if filter.Active.HaveFilesFrom() {
-		return walkR(f, path, includeAll, maxLevel, fn, filter.Active.MakeListR(f.NewObject))
+	return WalkWithFilter(f, filter.GetActive(), path, includeAll, maxLevel, fn)
+}
+
+// WalkWithFilter is like Walk but uses fi rather than the active
+// filter.
+func WalkWithFilter(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int, fn Func) error {
+	if fi.HaveFilesFrom() {
+		return walkR(f, fi, path, includeAll, maxLevel, fn, fi.MakeListR(f.NewObject))
 	}
 	if (maxLevel < 0 || maxLevel > 1) && fs.Config.UseListR && f.Features().ListR != nil {
-		return walkListR(f, path, includeAll, maxLevel, fn)
+		return walkListR(f, fi, path, includeAll, maxLevel, fn)
 	}
-	return walkListDirSorted(f, path, includeAll, maxLevel, fn)
+	return walkListDirSorted(f, fi, path, includeAll, maxLevel, fn)
 }
 
 // walkListDirSorted lists the directory.
 //
 // It implements Walk using non recursive directory listing.
-func walkListDirSorted(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
-	return walk(f, path, includeAll, maxLevel, fn, list.DirSorted)
+func walkListDirSorted(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int, fn Func) error {
+	return walk(f, fi, path, includeAll, maxLevel, fn, listDirSorted(fi))
 }
 
 // walkListR lists the directory.
 //
 // It implements Walk using recursive directory listing if
 // available, or returns ErrorCantListR if not.
-func walkListR(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func) error {
+func walkListR(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int, fn Func) error {
 	listR := f.Features().ListR
 	if listR == nil {
 		return ErrorCantListR
 	}
-	return walkR(f, path, includeAll, maxLevel, fn, listR)
+	return walkR(f, fi, path, includeAll, maxLevel, fn, listR)
 }
 
 type listDirFunc func(fs fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error)
 
-func walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir listDirFunc) error {
+// listDirSorted returns a listDirFunc which lists with list.DirSorted
+// using fi
+func listDirSorted(fi *filter.Filter) listDirFunc {
+	return func(f fs.Fs, includeAll bool, dir string) (entries fs.DirEntries, err error) {
+		return list.DirSortedWithFilter(f, fi, includeAll, dir)
+	}
+}
+
+func walk(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int, fn Func, listDir listDirFunc) error {
 	var (
 		wg         sync.WaitGroup // sync closing of go routines
 		traversing sync.WaitGroup // running directory traversals
@@ -127,7 +144,11 @@ func walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir
 					}
 					entries, err := listDir(f, includeAll, job.remote)
 					var jobs []listJob
-					if err == nil && job.depth != 0 {
+					useListR := false
+					if err == nil && job.depth < 0 && fs.Config.FastListAuto && UseListR(f, entries) {
+						// List the tree below with ListR instead
+						useListR = true
+					} else if err == nil && job.depth != 0 {
 						entries.ForDir(func(dir fs.Directory) {
 							// Recurse for the directory
 							jobs = append(jobs, listJob{
@@ -140,6 +161,18 @@ func walk(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listDir
 					err = fn(job.remote, entries, err)
 					mu.Unlock()
 					// NB once we have passed entries to fn we mustn't touch it again
+					if err == nil && useListR {
+						fs.Debugf(job.remote, "Listing directory tree with ListR as --fast-list is auto")
+						err = walkR(f, fi, job.remote, includeAll, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+							if dirPath == job.remote {
+								// already done
+								return nil
+							}
+							mu.Lock()
+							defer mu.Unlock()
+							return fn(dirPath, entries, err)
+						}, f.Features().ListR)
+					}
 					if err != nil && err != ErrorSkipDir {
 						traversing.Done()
 						fs.CountError(err)
@@ -355,13 +388,14 @@ func (dt DirTree) String() string {
 	return out.String()
 }
 
-func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, listR fs.ListRFn) (DirTree, error) {
+func walkRDirTree(f fs.Fs, fi *filter.Filter, startPath string, includeAll bool, maxLevel int, listR fs.ListRFn) (DirTree, error) {
 	dirs := make(DirTree)
 	// Entries can come in arbitrary order. We use toPrune to keep
 	// all directories to exclude later.
 	toPrune := make(map[string]bool)
-	includeDirectory := filter.Active.IncludeDirectory(f)
+	includeDirectory := fi.IncludeDirectory(f)
 	var mu sync.Mutex
+	accounting.CountRequest(f)
 	err := listR(startPath, func(entries fs.DirEntries) error {
 		mu.Lock()
 		defer mu.Unlock()
@@ -370,7 +404,7 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 			switch x := entry.(type) {
 			case fs.Object:
 				// Make sure we don't delete excluded files if not required
-				if includeAll || filter.Active.IncludeObject(x) {
+				if includeAll || fi.IncludeObject(x) {
 					if maxLevel < 0 || slashes <= maxLevel-1 {
 						dirs.add(x)
 					} else {
@@ -385,9 +419,9 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 					fs.Debugf(x, "Excluded from sync (and deletion)")
 				}
 				// Check if we need to prune a directory later.
-				if !includeAll && len(filter.Active.Opt.ExcludeFile) > 0 {
+				if !includeAll && len(fi.Opt.ExcludeFile) > 0 {
 					basename := path.Base(x.Remote())
-					if basename == filter.Active.Opt.ExcludeFile {
+					if basename == fi.Opt.ExcludeFile {
 						excludeDir := parentDir(x.Remote())
 						toPrune[excludeDir] = true
 						fs.Debugf(basename, "Excluded from sync (and deletion) based on exclude file")
@@ -432,7 +466,7 @@ func walkRDirTree(f fs.Fs, startPath string, includeAll bool, maxLevel int, list
 }
 
 // Create a DirTree using List
-func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir listDirFunc) (DirTree, error) {
+func walkNDirTree(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int, listDir listDirFunc) (DirTree, error) {
 	dirs := make(DirTree)
 	fn := func(dirPath string, entries fs.DirEntries, err error) error {
 		if err == nil {
@@ -440,7 +474,7 @@ func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir l
 		}
 		return err
 	}
-	err := walk(f, path, includeAll, maxLevel, fn, listDir)
+	err := walk(f, fi, path, includeAll, maxLevel, fn, listDir)
 	if err != nil {
 		return nil, err
 	}
@@ -463,17 +497,23 @@ func walkNDirTree(f fs.Fs, path string, includeAll bool, maxLevel int, listDir l
 //
 // NB (f, path) to be replaced by fs.Dir at some point
 func NewDirTree(f fs.Fs, path string, includeAll bool, maxLevel int) (DirTree, error) {
-	if filter.Active.HaveFilesFrom() {
-		return walkRDirTree(f, path, includeAll, maxLevel, filter.Active.MakeListR(f.NewObject))
+	return NewDirTreeWithFilter(f, filter.GetActive(), path, includeAll, maxLevel)
+}
+
+// NewDirTreeWithFilter is like NewDirTree but uses fi rather than the
+// active filter.
+func NewDirTreeWithFilter(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int) (DirTree, error) {
+	if fi.HaveFilesFrom() {
+		return walkRDirTree(f, fi, path, includeAll, maxLevel, fi.MakeListR(f.NewObject))
 	}
 	if ListR := f.Features().ListR; (maxLevel < 0 || maxLevel > 1) && ListR != nil {
-		return walkRDirTree(f, path, includeAll, maxLevel, ListR)
+		return walkRDirTree(f, fi, path, includeAll, maxLevel, ListR)
 	}
-	return walkNDirTree(f, path, includeAll, maxLevel, list.DirSorted)
+	return walkNDirTree(f, fi, path, includeAll, maxLevel, listDirSorted(fi))
 }
 
-func walkR(f fs.Fs, path string, includeAll bool, maxLevel int, fn Func, listR fs.ListRFn) error {
-	dirs, err := walkRDirTree(f, path, includeAll, maxLevel, listR)
+func walkR(f fs.Fs, fi *filter.Filter, path string, includeAll bool, maxLevel int, fn Func, listR fs.ListRFn) error {
+	dirs, err := walkRDirTree(f, fi, path, includeAll, maxLevel, listR)
 	if err != nil {
 		return err
 	}
@@ -8,34 +8,89 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
 
 	"github.com/ncw/rclone/fs"
 )
 
 var (
-	fns          []func()
+	fnsMu        sync.Mutex
+	fns          []FnHandle
 	exitChan     chan os.Signal
 	exitOnce     sync.Once
 	registerOnce sync.Once
+	onSignalMu   sync.Mutex
+	onSignal     func(os.Signal) // called on the first signal if set
+	signalCode   int             // exit code if signalled again after onSignal
 )
 
+// FnHandle is the handle returned by Register to pass to Unregister
+type FnHandle *func()
+
 // Register a function to be called on exit
-func Register(fn func()) {
-	fns = append(fns, fn)
-	// Run AtExit handlers on SIGINT or SIGTERM so everything gets
-	// tidied up properly
+func Register(fn func()) FnHandle {
+	fnsMu.Lock()
+	handle := FnHandle(&fn)
+	fns = append(fns, handle)
+	fnsMu.Unlock()
+	startSignalHandler()
+	return handle
+}
+
+// Unregister a function registered with Register so it isn't called
+// on exit
+func Unregister(handle FnHandle) {
+	fnsMu.Lock()
+	defer fnsMu.Unlock()
+	for i, fn := range fns {
+		if fn == handle {
+			fns = append(fns[:i], fns[i+1:]...)
+			return
+		}
+	}
+}
+
+// OnSignal sets fn to be called the first time SIGINT or SIGTERM is
+// received instead of running the at exit functions and exiting, so
+// the program can shut down gracefully.  If a signal is received
+// again the at exit functions are run and the program exits with
+// exitCode.
+//
+// Call OnSignal(nil, exitCode) to run the at exit functions and exit
+// with exitCode on the first signal, and OnSignal(nil, 0) to restore
+// the default.
+func OnSignal(fn func(sig os.Signal), exitCode int) {
+	onSignalMu.Lock()
+	onSignal, signalCode = fn, exitCode
+	onSignalMu.Unlock()
+	startSignalHandler()
+}
+
+// startSignalHandler runs the at exit handlers on SIGINT or SIGTERM
+// so everything gets tidied up properly
+func startSignalHandler() {
 	registerOnce.Do(func() {
 		exitChan = make(chan os.Signal, 1)
-		signal.Notify(exitChan, os.Interrupt) // syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT
+		signal.Notify(exitChan, os.Interrupt, syscall.SIGTERM)
 		go func() {
-			sig, closed := <-exitChan
-			if closed || sig == nil {
-				return
+			for sig := range exitChan {
+				if sig == nil {
+					return
+				}
+				onSignalMu.Lock()
+				fn := onSignal
+				onSignal = nil
+				exitCode := signalCode
+				onSignalMu.Unlock()
+				if fn != nil {
+					go fn(sig)
+					continue
+				}
+				fs.Infof(nil, "Signal received: %s", sig)
+				Run()
+				fs.Infof(nil, "Exiting...")
+				os.Exit(exitCode)
 			}
-			fs.Infof(nil, "Signal received: %s", sig)
-			Run()
-			fs.Infof(nil, "Exiting...")
-			os.Exit(0)
 		}()
 	})
 }
@@ -53,8 +108,11 @@ func IgnoreSignals() {
 // Run all the at exit functions if they haven't been run already
 func Run() {
 	exitOnce.Do(func() {
-		for _, fn := range fns {
-			fn()
+		fnsMu.Lock()
+		toRun := append([]FnHandle(nil), fns...)
+		fnsMu.Unlock()
+		for _, fn := range toRun {
+			(*fn)()
 		}
 	})
 }
@@ -0,0 +1,23 @@
+package atexit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterUnregister(t *testing.T) {
+	var called []string
+	one := Register(func() { called = append(called, "one") })
+	two := Register(func() { called = append(called, "two") })
+	Register(func() { called = append(called, "three") })
+	Unregister(two)
+	Unregister(two) // twice does nothing
+	assert.NotNil(t, one)
+	Run()
+	assert.Equal(t, []string{"one", "three"}, called)
+
+	// Only runs once
+	Run()
+	assert.Equal(t, []string{"one", "three"}, called)
+}
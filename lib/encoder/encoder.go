@@ -18,6 +18,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode/utf8"
 )
 
@@ -62,6 +63,12 @@ type Encoder interface {
 	Encode(string) string
 	// Decode takes a name and undoes any substitutions made by Encode
 	Decode(string) string
+	// EncodeWithInfo is like Encode but also returns true if the
+	// name was changed
+	EncodeWithInfo(string) (string, bool)
+	// DecodeWithInfo is like Decode but also returns true if the
+	// name was changed
+	DecodeWithInfo(string) (string, bool)
 
 	// FromStandardPath takes a / separated path in Standard encoding
 	// and converts it to a / separated path in this encoding.
@@ -75,6 +82,9 @@ type Encoder interface {
 	// ToStandardName takes name in this encoding and converts
 	// it in Standard encoding.
 	ToStandardName(string) string
+	// Mapping returns the characters replaced by the encoding and
+	// their replacements
+	Mapping() []Pair
 }
 
 // MultiEncoder is a configurable Encoder. The Encode* constants in this
@@ -82,16 +92,99 @@ type Encoder interface {
 // character classes
 type MultiEncoder uint
 
+// tableFlags are the flags which affect the character tables
+var tableFlags = [...]uint{EncodeSlash, EncodeWin, EncodeBackSlash, EncodeHashPercent, EncodeDel, EncodeCtl}
+
+// charTable is a lookup table of the character replacements made by
+// a MultiEncoder.
+type charTable struct {
+	encode      [utf8.RuneSelf]rune // replacement for each ASCII character or 0 if not replaced
+	encodeExtra map[rune]rune       // replacements of non ASCII characters, if any
+	decodeExtra map[rune]rune       // originals of replacements not covered by encode, if any
+}
+
+// charTables holds the charTable for each combination of tableFlags,
+// each built the first time it is used
+var charTables [1 << uint(len(tableFlags))]struct {
+	once  sync.Once
+	table charTable
+}
+
+// init fills in the table for mask
+func (t *charTable) init(mask MultiEncoder) {
+	full := func(chars string) {
+		for _, c := range chars {
+			t.encode[c] = c + fullOffset
+		}
+	}
+	t.encode[0] = symbolOffset // always encoded
+	if uint(mask)&EncodeWin != 0 {
+		full(`:?"*<>|`)
+	}
+	if uint(mask)&EncodeSlash != 0 {
+		full(`/`)
+	}
+	if uint(mask)&EncodeBackSlash != 0 {
+		full(`\`)
+	}
+	if uint(mask)&EncodeHashPercent != 0 {
+		full(`#%`)
+	}
+	if uint(mask)&EncodeDel != 0 {
+		t.encode[0x7F] = '␡' // SYMBOL FOR DELETE
+	}
+	if uint(mask)&EncodeCtl != 0 {
+		for c := rune(1); c <= 0x1F; c++ {
+			t.encode[c] = symbolOffset + c
+		}
+	}
+}
+
+// original returns the ASCII character r is the replacement of and
+// true, or false if r isn't a replacement character in this table.
+func (t *charTable) original(r rune) (c rune, replaced bool) {
+	switch {
+	case r >= '!'+fullOffset && r <= '~'+fullOffset:
+		c = r - fullOffset
+	case r >= symbolOffset && r <= symbolOffset+0x1F:
+		c = r - symbolOffset
+	case r == '␡': // SYMBOL FOR DELETE
+		c = 0x7F
+	default:
+		c, replaced = t.decodeExtra[r]
+		return c, replaced
+	}
+	if t.encode[c] == r {
+		return c, true
+	}
+	c, replaced = t.decodeExtra[r]
+	return c, replaced
+}
+
+// table returns the charTable for mask, building it if necessary
+func (mask MultiEncoder) table() *charTable {
+	i := 0
+	for bit, flag := range tableFlags {
+		if uint(mask)&flag != 0 {
+			i |= 1 << uint(bit)
+		}
+	}
+	entry := &charTables[i]
+	entry.once.Do(func() {
+		entry.table.init(mask)
+	})
+	return &entry.table
+}
+
 // Encode takes a raw name and substitutes any reserved characters and
 // patterns in it
 func (mask MultiEncoder) Encode(in string) string {
+	return mask.encode(mask.table(), in)
+}
+
+// encode does Encode replacing the characters in t
+func (mask MultiEncoder) encode(t *charTable, in string) string {
 	var (
-		encodeWin            = uint(mask)&EncodeWin != 0
-		encodeSlash          = uint(mask)&EncodeSlash != 0
-		encodeBackSlash      = uint(mask)&EncodeBackSlash != 0
-		encodeHashPercent    = uint(mask)&EncodeHashPercent != 0
-		encodeDel            = uint(mask)&EncodeDel != 0
-		encodeCtl            = uint(mask)&EncodeCtl != 0
 		encodeLeftSpace      = uint(mask)&EncodeLeftSpace != 0
 		encodeLeftTilde      = uint(mask)&EncodeLeftTilde != 0
 		encodeRightSpace     = uint(mask)&EncodeRightSpace != 0
@@ -108,7 +201,7 @@ func (mask MultiEncoder) Encode(in string) string {
 			prefix, in = string(QuoteRune)+"␠", in[l:] // SYMBOL FOR SPACE
 		}
 	}
-	if encodeLeftTilde && len(in) > 0 { // Leading ~
+	if encodeLeftTilde && prefix == "" && len(in) > 0 { // Leading ~
 		if in[0] == '~' {
 			prefix, in = string('~'+fullOffset), in[1:] // FULLWIDTH TILDE
 		} else if r, l := utf8.DecodeRuneInString(in); r == '~'+fullOffset {
@@ -124,7 +217,7 @@ func (mask MultiEncoder) Encode(in string) string {
 			suffix, in = string(QuoteRune)+"␠", in[:len(in)-l] // SYMBOL FOR SPACE
 		}
 	}
-	if encodeRightPeriod && len(in) > 0 { // Trailing .
+	if encodeRightPeriod && suffix == "" && len(in) > 0 { // Trailing .
 		if in[len(in)-1] == '.' {
 			suffix, in = "．", in[:len(in)-1] // FULLWIDTH FULL STOP
 		} else if r, l := utf8.DecodeLastRuneInString(in); r == '．' {
@@ -135,52 +228,17 @@ func (mask MultiEncoder) Encode(in string) string {
 	if prefix == "" && suffix == "" {
 		// find the first rune which (most likely) needs to be replaced
 		index = strings.IndexFunc(in, func(r rune) bool {
-			switch r {
-			case 0, '␀', QuoteRune, utf8.RuneError:
-				return true
-			}
-			if encodeWin { // :?"*<>|
-				switch r {
-				case '*', '<', '>', '?', ':', '|', '"',
-					'＊', '＜', '＞', '？', '：', '｜', '＂':
-					return true
-				}
-			}
-			if encodeSlash { // /
-				switch r {
-				case '/',
-					'／':
-					return true
-				}
-			}
-			if encodeBackSlash { // \
-				switch r {
-				case '\\',
-					'＼':
-					return true
-				}
-			}
-			if encodeHashPercent { // #%
-				switch r {
-				case '#', '%',
-					'＃', '％':
-					return true
-				}
+			if r < utf8.RuneSelf {
+				return t.encode[r] != 0
 			}
-			if encodeDel { // DEL(0x7F)
-				switch r {
-				case rune(0x7F), '␡':
-					return true
-				}
+			if r == QuoteRune || r == utf8.RuneError {
+				return true
 			}
-			if encodeCtl { // CTRL(0x01-0x1F)
-				if r >= 1 && r <= 0x1F {
-					return true
-				} else if r > symbolOffset && r <= symbolOffset+0x1F {
-					return true
-				}
+			if _, found := t.encodeExtra[r]; found {
+				return true
 			}
-			return false
+			_, replaced := t.original(r)
+			return replaced
 		})
 	}
 	// nothing to replace, return input
@@ -196,11 +254,16 @@ func (mask MultiEncoder) Encode(in string) string {
 	in = in[index:]
 
 	for i, r := range in {
-		switch r {
-		case 0:
-			out.WriteRune(symbolOffset)
+		if r < utf8.RuneSelf {
+			if replacement := t.encode[r]; replacement != 0 {
+				out.WriteRune(replacement)
+			} else {
+				out.WriteByte(byte(r))
+			}
 			continue
-		case '␀', QuoteRune:
+		}
+		switch r {
+		case QuoteRune:
 			out.WriteRune(QuoteRune)
 			out.WriteRune(r)
 			continue
@@ -219,70 +282,13 @@ func (mask MultiEncoder) Encode(in string) string {
 				continue
 			}
 		}
-		if encodeWin { // :?"*<>|
-			switch r {
-			case '*', '<', '>', '?', ':', '|', '"':
-				out.WriteRune(r + fullOffset)
-				continue
-			case '＊', '＜', '＞', '？', '：', '｜', '＂':
-				out.WriteRune(QuoteRune)
-				out.WriteRune(r)
-				continue
-			}
-		}
-		if encodeSlash { // /
-			switch r {
-			case '/':
-				out.WriteRune(r + fullOffset)
-				continue
-			case '／':
-				out.WriteRune(QuoteRune)
-				out.WriteRune(r)
-				continue
-			}
-		}
-		if encodeBackSlash { // \
-			switch r {
-			case '\\':
-				out.WriteRune(r + fullOffset)
-				continue
-			case '＼':
-				out.WriteRune(QuoteRune)
-				out.WriteRune(r)
-				continue
-			}
-		}
-		if encodeHashPercent { // #%
-			switch r {
-			case '#', '%':
-				out.WriteRune(r + fullOffset)
-				continue
-			case '＃', '％':
-				out.WriteRune(QuoteRune)
-				out.WriteRune(r)
-				continue
-			}
-		}
-		if encodeDel { // DEL(0x7F)
-			switch r {
-			case rune(0x7F):
-				out.WriteRune('␡') // SYMBOL FOR DELETE
-				continue
-			case '␡':
-				out.WriteRune(QuoteRune)
-				out.WriteRune(r)
-				continue
-			}
+		if replacement, found := t.encodeExtra[r]; found {
+			out.WriteRune(replacement)
+			continue
 		}
-		if encodeCtl { // CTRL(0x01-0x1F)
-			if r >= 1 && r <= 0x1F {
-				out.WriteRune('␀' + r) // SYMBOL FOR NULL
-				continue
-			} else if r > symbolOffset && r <= symbolOffset+0x1F {
-				out.WriteRune(QuoteRune)
-				out.WriteRune(r)
-				continue
-			}
+		// quote the replacement characters so they decode to themselves
+		if _, replaced := t.original(r); replaced {
+			out.WriteRune(QuoteRune)
 		}
 		out.WriteRune(r)
 	}
@@ -292,13 +298,12 @@ func (mask MultiEncoder) Encode(in string) string {
 
 // Decode takes a name and undoes any substitutions made by Encode
 func (mask MultiEncoder) Decode(in string) string {
+	return mask.decode(mask.table(), in)
+}
+
+// decode does Decode restoring the characters replaced in t
+func (mask MultiEncoder) decode(t *charTable, in string) string {
 	var (
-		encodeWin            = uint(mask)&EncodeWin != 0
-		encodeSlash          = uint(mask)&EncodeSlash != 0
-		encodeBackSlash      = uint(mask)&EncodeBackSlash != 0
-		encodeHashPercent    = uint(mask)&EncodeHashPercent != 0
-		encodeDel            = uint(mask)&EncodeDel != 0
-		encodeCtl            = uint(mask)&EncodeCtl != 0
 		encodeLeftSpace      = uint(mask)&EncodeLeftSpace != 0
 		encodeLeftTilde      = uint(mask)&EncodeLeftTilde != 0
 		encodeRightSpace     = uint(mask)&EncodeRightSpace != 0
@@ -324,15 +329,15 @@ func (mask MultiEncoder) Decode(in string) string {
 	suffix := ""
 	if r, l := utf8.DecodeLastRuneInString(in); encodeRightSpace && r == '␠' { // SYMBOL FOR SPACE
 		in = in[:len(in)-l]
-		if r, l2 := utf8.DecodeLastRuneInString(in); r == QuoteRune {
-			suffix, in = "␠", in[:len(in)-l2]
+		if endsInQuote(in) {
+			suffix, in = "␠", in[:len(in)-quoteLen]
 		} else {
 			suffix = " "
 		}
 	} else if encodeRightPeriod && r == '．' { // FULLWIDTH FULL STOP
 		in = in[:len(in)-l]
-		if r, l2 := utf8.DecodeLastRuneInString(in); r == QuoteRune {
-			suffix, in = "．", in[:len(in)-l2]
+		if endsInQuote(in) {
+			suffix, in = "．", in[:len(in)-quoteLen]
 		} else {
 			suffix = "."
 		}
@@ -341,47 +346,11 @@ func (mask MultiEncoder) Decode(in string) string {
 	if prefix == "" && suffix == "" {
 		// find the first rune which (most likely) needs to be replaced
 		index = strings.IndexFunc(in, func(r rune) bool {
-			switch r {
-			case '␀', QuoteRune:
+			if r == QuoteRune {
 				return true
 			}
-			if encodeWin { // :?"*<>|
-				switch r {
-				case '＊', '＜', '＞', '？', '：', '｜', '＂':
-					return true
-				}
-			}
-			if encodeSlash { // /
-				switch r {
-				case '／':
-					return true
-				}
-			}
-			if encodeBackSlash { // \
-				switch r {
-				case '＼':
-					return true
-				}
-			}
-			if encodeHashPercent { // #%
-				switch r {
-				case '＃', '％':
-					return true
-				}
-			}
-			if encodeDel { // DEL(0x7F)
-				switch r {
-				case '␡':
-					return true
-				}
-			}
-			if encodeCtl { // CTRL(0x01-0x1F)
-				if r > symbolOffset && r <= symbolOffset+0x1F {
-					return true
-				}
-			}
-
-			return false
+			_, replaced := t.original(r)
+			return replaced
 		})
 	}
 	// nothing to replace, return input
@@ -403,87 +372,22 @@ func (mask MultiEncoder) Decode(in string) string {
 			continue
 		}
 		unquote, unquoteNext = unquoteNext, false
-		switch r {
-		case '␀': // SYMBOL FOR NULL
+		if r == QuoteRune {
 			if unquote {
 				out.WriteRune(r)
 			} else {
-				out.WriteRune(0)
+				unquoteNext = true
 			}
 			continue
-		case QuoteRune:
+		}
+		if c, replaced := t.original(r); replaced {
 			if unquote {
 				out.WriteRune(r)
 			} else {
-				unquoteNext = true
+				out.WriteRune(c)
 			}
 			continue
 		}
-		if encodeWin { // :?"*<>|
-			switch r {
-			case '＊', '＜', '＞', '？', '：', '｜', '＂':
-				if unquote {
-					out.WriteRune(r)
-				} else {
-					out.WriteRune(r - fullOffset)
-				}
-				continue
-			}
-		}
-		if encodeSlash { // /
-			switch r {
-			case '／': // FULLWIDTH SOLIDUS
-				if unquote {
-					out.WriteRune(r)
-				} else {
-					out.WriteRune(r - fullOffset)
-				}
-				continue
-			}
-		}
-		if encodeBackSlash { // \
-			switch r {
-			case '＼': // FULLWIDTH REVERSE SOLIDUS
-				if unquote {
-					out.WriteRune(r)
-				} else {
-					out.WriteRune(r - fullOffset)
-				}
-				continue
-			}
-		}
-		if encodeHashPercent { // #%
-			switch r {
-			case '＃', '％':
-				if unquote {
-					out.WriteRune(r)
-				} else {
-					out.WriteRune(r - fullOffset)
-				}
-				continue
-			}
-		}
-		if encodeDel { // DEL(0x7F)
-			switch r {
-			case '␡': // SYMBOL FOR DELETE
-				if unquote {
-					out.WriteRune(r)
-				} else {
-					out.WriteRune(0x7F)
-				}
-				continue
-			}
-		}
-		if encodeCtl { // CTRL(0x01-0x1F)
-			if r > symbolOffset && r <= symbolOffset+0x1F {
-				if unquote {
-					out.WriteRune(r)
-				} else {
-					out.WriteRune(r - symbolOffset)
-				}
-				continue
-			}
-		}
 		if unquote {
 			if encodeInvalidUnicode {
 				skipNext = appendUnquotedByte(&out, in[i:])
@@ -510,6 +414,21 @@ func (mask MultiEncoder) Decode(in string) string {
 	return out.String()
 }
 
+// EncodeWithInfo is like Encode but also returns true if the name
+// was changed, so callers can cheaply tell when a name needed
+// encoding.
+func (mask MultiEncoder) EncodeWithInfo(in string) (string, bool) {
+	out := mask.Encode(in)
+	return out, out != in
+}
+
+// DecodeWithInfo is like Decode but also returns true if the name
+// was changed.
+func (mask MultiEncoder) DecodeWithInfo(in string) (string, bool) {
+	out := mask.Decode(in)
+	return out, out != in
+}
+
 // FromStandardPath takes a / separated path in Standard encoding
 // and converts it to a / separated path in this encoding.
 func (mask MultiEncoder) FromStandardPath(s string) string {
@@ -534,6 +453,21 @@ func (mask MultiEncoder) ToStandardName(s string) string {
 	return ToStandardName(mask, s)
 }
 
+// quoteLen is the length of QuoteRune in bytes
+var quoteLen = utf8.RuneLen(QuoteRune)
+
+// endsInQuote returns true if s ends with a QuoteRune which quotes
+// whatever follows s rather than being one half of a quoted
+// QuoteRune, ie if s ends with an odd number of QuoteRunes.
+func endsInQuote(s string) bool {
+	n := 0
+	for strings.HasSuffix(s, string(QuoteRune)) {
+		s = s[:len(s)-quoteLen]
+		n++
+	}
+	return n%2 == 1
+}
+
 func appendQuotedBytes(w io.Writer, s string) {
 	for _, b := range []byte(s) {
 		_, _ = fmt.Fprintf(w, string(QuoteRune)+"%02X", b)
@@ -556,6 +490,9 @@ type identity struct{}
 func (identity) Encode(in string) string { return in }
 func (identity) Decode(in string) string { return in }
 
+func (identity) EncodeWithInfo(in string) (string, bool) { return in, false }
+func (identity) DecodeWithInfo(in string) (string, bool) { return in, false }
+
 func (i identity) FromStandardPath(s string) string {
 	return FromStandardPath(i, s)
 }
@@ -140,6 +140,63 @@ func TestDecodeHalf(t *testing.T) {
 	}
 }
 
+func TestEncodeWithInfo(t *testing.T) {
+	e := MultiEncoder(EncodeWin | EncodeRightSpace)
+	for _, tc := range []struct {
+		in      string
+		out     string
+		changed bool
+	}{
+		{"", "", false},
+		{"plain", "plain", false},
+		{"a:b", "a：b", true},
+		{"a ", "a␠", true},
+		{"a：b", "a‛：b", true},
+	} {
+		got, changed := e.EncodeWithInfo(tc.in)
+		if got != tc.out || changed != tc.changed {
+			t.Errorf("EncodeWithInfo(%q) want %q, %v got %q, %v", tc.in, tc.out, tc.changed, got, changed)
+		}
+		got, changed = e.DecodeWithInfo(tc.out)
+		if got != tc.in || changed != tc.changed {
+			t.Errorf("DecodeWithInfo(%q) want %q, %v got %q, %v", tc.out, tc.in, tc.changed, got, changed)
+		}
+	}
+	got, changed := Identity().EncodeWithInfo("a:b")
+	if got != "a:b" || changed {
+		t.Errorf("Identity().EncodeWithInfo changed the name")
+	}
+}
+
+func TestCharTable(t *testing.T) {
+	// flags which don't affect the tables share them
+	a := MultiEncoder(EncodeWin | EncodeCtl).table()
+	b := MultiEncoder(EncodeWin | EncodeCtl | EncodeLeftSpace | EncodeInvalidUtf8).table()
+	if a != b {
+		t.Errorf("tables not shared")
+	}
+	for _, tc := range []struct {
+		mask     uint
+		r        rune
+		c        rune
+		replaced bool
+	}{
+		{0, '␀', 0, true},
+		{0, '＊', '*', false},
+		{EncodeWin, '＊', '*', true},
+		{EncodeWin, '～', '~', false},
+		{EncodeCtl, '␁', 1, true},
+		{EncodeDel, '␡', 0x7F, true},
+		{EncodeCtl, '␡', 0x7F, false},
+		{EncodeStandard, 'a', 0, false},
+	} {
+		c, replaced := MultiEncoder(tc.mask).table().original(tc.r)
+		if replaced != tc.replaced || (replaced && c != tc.c) {
+			t.Errorf("mask %d original(%q) want %q, %v got %q, %v", tc.mask, tc.r, tc.c, tc.replaced, c, replaced)
+		}
+	}
+}
+
 const oneDrive = MultiEncoder(
 	EncodeStandard |
 		EncodeWin |
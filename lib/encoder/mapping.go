@@ -0,0 +1,178 @@
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is where in a name the replacement of a Pair is made
+type Position int
+
+// Positions of the replacements
+const (
+	Anywhere Position = iota // the character is replaced wherever it is
+	Leading                  // only the first character of a name is replaced
+	Trailing                 // only the last character of a name is replaced
+)
+
+// String turns a Position into a string
+func (p Position) String() string {
+	switch p {
+	case Anywhere:
+		return "anywhere"
+	case Leading:
+		return "leading"
+	case Trailing:
+		return "trailing"
+	}
+	return fmt.Sprintf("Position(%d)", int(p))
+}
+
+// Pair is a character replaced by an Encoder and its replacement.
+//
+// A Pair with From set to utf8.RuneError stands for the bytes of
+// invalid UTF-8 sequences, which are each replaced with To followed
+// by the value of the byte as 2 hex digits.
+type Pair struct {
+	From     rune     // the character in the original name
+	To       rune     // the character it is replaced with
+	Position Position // where in the name it is replaced
+}
+
+// String returns a description of the Pair, eg `"/" -> "／"`
+func (p Pair) String() string {
+	from, ok := charName(p.From)
+	if !ok {
+		from = strconv.QuoteRune(p.From)
+	}
+	s := fmt.Sprintf("%s -> %q", from, p.To)
+	if p.Position != Anywhere {
+		s += " (" + p.Position.String() + ")"
+	}
+	return s
+}
+
+// controlNames are the names of the ASCII control characters
+var controlNames = [...]string{
+	"NUL", "SOH", "STX", "ETX", "EOT", "ENQ", "ACK", "BEL",
+	"BS", "HT", "LF", "VT", "FF", "CR", "SO", "SI",
+	"DLE", "DC1", "DC2", "DC3", "DC4", "NAK", "SYN", "ETB",
+	"CAN", "EM", "SUB", "ESC", "FS", "GS", "RS", "US",
+}
+
+// charName returns the name of c and true if c isn't printable on
+// its own, or false if it is
+func charName(c rune) (string, bool) {
+	switch {
+	case c < rune(len(controlNames)):
+		return controlNames[c], true
+	case c == ' ':
+		return "SP", true
+	case c == 0x7F:
+		return "DEL", true
+	case c == utf8.RuneError:
+		return "invalid UTF-8", true
+	}
+	return "", false
+}
+
+// Mapping returns the characters replaced by the encoder and their
+// replacements, in the order of their positions then characters.
+func (mask MultiEncoder) Mapping() []Pair {
+	return mask.mapping(mask.table())
+}
+
+// mapping returns the Mapping of mask replacing the characters in t
+func (mask MultiEncoder) mapping(t *charTable) []Pair {
+	var pairs []Pair
+	for c, r := range t.encode {
+		if r != 0 {
+			pairs = append(pairs, Pair{From: rune(c), To: r})
+		}
+	}
+	for c, r := range t.encodeExtra {
+		pairs = append(pairs, Pair{From: c, To: r})
+	}
+	if uint(mask)&EncodeInvalidUtf8 != 0 {
+		pairs = append(pairs, Pair{From: utf8.RuneError, To: QuoteRune})
+	}
+	if uint(mask)&EncodeLeftSpace != 0 {
+		pairs = append(pairs, Pair{From: ' ', To: '␠', Position: Leading}) // SYMBOL FOR SPACE
+	}
+	if uint(mask)&EncodeLeftTilde != 0 {
+		pairs = append(pairs, Pair{From: '~', To: '～', Position: Leading}) // FULLWIDTH TILDE
+	}
+	if uint(mask)&EncodeRightSpace != 0 {
+		pairs = append(pairs, Pair{From: ' ', To: '␠', Position: Trailing}) // SYMBOL FOR SPACE
+	}
+	if uint(mask)&EncodeRightPeriod != 0 {
+		pairs = append(pairs, Pair{From: '.', To: '．', Position: Trailing}) // FULLWIDTH FULL STOP
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Position != pairs[j].Position {
+			return pairs[i].Position < pairs[j].Position
+		}
+		return pairs[i].From < pairs[j].From
+	})
+	return pairs
+}
+
+// Mapping returns the characters replaced by the encoder, including
+// the substitutions, and their replacements, in the order of their
+// positions then characters.
+func (e *SubstitutionEncoder) Mapping() []Pair {
+	return e.mask.mapping(&e.table)
+}
+
+// Mapping returns nil as no characters are replaced
+func (identity) Mapping() []Pair { return nil }
+
+// Flags are the names of the flags for MultiEncoder, in the order of
+// their values
+var Flags = []struct {
+	Name string
+	Flag uint
+}{
+	{"Zero", EncodeZero},
+	{"Slash", EncodeSlash},
+	{"Win", EncodeWin},
+	{"BackSlash", EncodeBackSlash},
+	{"HashPercent", EncodeHashPercent},
+	{"Del", EncodeDel},
+	{"Ctl", EncodeCtl},
+	{"LeftSpace", EncodeLeftSpace},
+	{"LeftTilde", EncodeLeftTilde},
+	{"RightSpace", EncodeRightSpace},
+	{"RightPeriod", EncodeRightPeriod},
+	{"InvalidUtf8", EncodeInvalidUtf8},
+}
+
+// WriteTable writes pairs to w as a markdown table for the
+// documentation.
+func WriteTable(w io.Writer, pairs []Pair) error {
+	_, err := fmt.Fprintf(w, "| Character | Value | Replacement | Position |\n| --------- |:-----:|:-----------:| -------- |\n")
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		from, ok := charName(p.From)
+		if !ok {
+			from = strings.Replace(string(p.From), "|", `\|`, -1)
+		}
+		value := fmt.Sprintf("0x%02X", p.From)
+		to := string(p.To)
+		if p.From == utf8.RuneError {
+			value = "0x80-0xFF"
+			to += "XX"
+		}
+		_, err = fmt.Fprintf(w, "| %s | %s | %s | %s |\n", from, value, to, p.Position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package encoder
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMapping(t *testing.T) {
+	substitution, err := NewSubstitutionEncoder(MultiEncoder(EncodeSlash|EncodeRightPeriod), map[rune]rune{
+		',': '，', // FULLWIDTH COMMA
+		'é': 'ê',
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		e    Encoder
+		want []Pair
+	}{
+		{"Identity", Identity(), nil},
+		{"Zero", MultiEncoder(EncodeZero), []Pair{{0, '␀', Anywhere}}},
+		{"Standard", Standard, func() []Pair {
+			pairs := []Pair{{0, '␀', Anywhere}}
+			for c := rune(1); c <= 0x1F; c++ {
+				pairs = append(pairs, Pair{c, symbolOffset + c, Anywhere})
+			}
+			return append(pairs, Pair{'/', '／', Anywhere}, Pair{0x7F, '␡', Anywhere})
+		}()},
+		{"Positional", MultiEncoder(EncodeLeftSpace | EncodeLeftTilde | EncodeRightSpace | EncodeRightPeriod | EncodeInvalidUtf8 | EncodeHashPercent), []Pair{
+			{0, '␀', Anywhere},
+			{'#', '＃', Anywhere},
+			{'%', '％', Anywhere},
+			{utf8.RuneError, QuoteRune, Anywhere},
+			{' ', '␠', Leading},
+			{'~', '～', Leading},
+			{' ', '␠', Trailing},
+			{'.', '．', Trailing},
+		}},
+		{"Substitution", substitution, []Pair{
+			{0, '␀', Anywhere},
+			{',', '，', Anywhere},
+			{'/', '／', Anywhere},
+			{'é', 'ê', Anywhere},
+			{'.', '．', Trailing},
+		}},
+	} {
+		got := tc.e.Mapping()
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPairString(t *testing.T) {
+	for _, tc := range []struct {
+		in   Pair
+		want string
+	}{
+		{Pair{0, '␀', Anywhere}, `NUL -> '␀'`},
+		{Pair{'/', '／', Anywhere}, `'/' -> '／'`},
+		{Pair{' ', '␠', Leading}, `SP -> '␠' (leading)`},
+		{Pair{'.', '．', Trailing}, `'.' -> '．' (trailing)`},
+		{Pair{utf8.RuneError, QuoteRune, Anywhere}, `invalid UTF-8 -> '‛'`},
+	} {
+		if got := tc.in.String(); got != tc.want {
+			t.Errorf("String(%#v) = %q want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteTable(&buf, MultiEncoder(EncodeWin|EncodeInvalidUtf8|EncodeRightSpace).Mapping()[6:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `| Character | Value | Replacement | Position |
+| --------- |:-----:|:-----------:| -------- |
+| ? | 0x3F | ？ | anywhere |
+| \| | 0x7C | ｜ | anywhere |
+| invalid UTF-8 | 0x80-0xFF | ‛XX | anywhere |
+| SP | 0x20 | ␠ | trailing |
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
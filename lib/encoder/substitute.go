@@ -0,0 +1,185 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// SubstitutionEncoder is an Encoder which replaces the characters of
+// a MultiEncoder and substitutes the characters in a map too.
+//
+// This is for remotes which reject characters none of the Encode*
+// flags cover, eg "," or "&".  The substituted characters are quoted
+// with QuoteRune where they appear in a name in the same way as the
+// replacements made by the flags.
+type SubstitutionEncoder struct {
+	mask          MultiEncoder
+	table         charTable
+	substitutions map[rune]rune
+}
+
+// NewSubstitutionEncoder makes an Encoder which replaces the
+// characters selected by mask and substitutes each character which is
+// a key of substitutions with its value.
+//
+// It returns an error if the substitutions couldn't be reversed, eg
+// if two characters are substituted with the same one, a replacement
+// is ASCII or is already used by mask, or a character substituted is
+// already replaced by mask.
+func NewSubstitutionEncoder(mask MultiEncoder, substitutions map[rune]rune) (*SubstitutionEncoder, error) {
+	builtin := mask.table()
+	e := &SubstitutionEncoder{
+		mask:          mask,
+		table:         *builtin,
+		substitutions: make(map[rune]rune, len(substitutions)),
+	}
+	// the replacements made for leading and trailing characters
+	positional := map[rune]uint{
+		'␠': EncodeLeftSpace | EncodeRightSpace, // SYMBOL FOR SPACE
+		'～': EncodeLeftTilde,                    // FULLWIDTH TILDE
+		'．': EncodeRightPeriod,                  // FULLWIDTH FULL STOP
+	}
+	from := make([]rune, 0, len(substitutions))
+	for c := range substitutions {
+		from = append(from, c)
+	}
+	sort.Slice(from, func(i, j int) bool { return from[i] < from[j] })
+	originals := make(map[rune]rune, len(substitutions))
+	for _, c := range from {
+		r := substitutions[c]
+		for _, x := range []rune{c, r} {
+			if x == QuoteRune || x == utf8.RuneError || !utf8.ValidRune(x) {
+				return nil, fmt.Errorf("can't substitute %q with %q: %q can't be used", c, r, x)
+			}
+		}
+		if c == r {
+			return nil, fmt.Errorf("can't substitute %q with itself", c)
+		}
+		if r < utf8.RuneSelf {
+			return nil, fmt.Errorf("can't substitute %q with %q: the replacement mustn't be ASCII", c, r)
+		}
+		if c < utf8.RuneSelf && builtin.encode[c] != 0 {
+			return nil, fmt.Errorf("can't substitute %q: it is already replaced with %q", c, builtin.encode[c])
+		}
+		if orig, replaced := builtin.original(c); replaced {
+			return nil, fmt.Errorf("can't substitute %q: it is the replacement for %q", c, orig)
+		}
+		if orig, replaced := builtin.original(r); replaced {
+			return nil, fmt.Errorf("can't substitute %q with %q: it is already the replacement for %q", c, r, orig)
+		}
+		if uint(mask)&positional[r] != 0 {
+			return nil, fmt.Errorf("can't substitute %q with %q: it is already used for leading or trailing characters", c, r)
+		}
+		if _, found := substitutions[r]; found {
+			return nil, fmt.Errorf("can't substitute %q with %q: it is substituted itself", c, r)
+		}
+		if orig, found := originals[r]; found {
+			return nil, fmt.Errorf("can't substitute both %q and %q with %q", orig, c, r)
+		}
+		originals[r] = c
+		e.substitutions[c] = r
+	}
+	e.table.encodeExtra = make(map[rune]rune)
+	e.table.decodeExtra = originals
+	for c, r := range e.substitutions {
+		if c < utf8.RuneSelf {
+			e.table.encode[c] = r
+		} else {
+			e.table.encodeExtra[c] = r
+		}
+	}
+	return e, nil
+}
+
+// ParseSubstitutions parses substitutions for NewSubstitutionEncoder
+// written as pairs of characters, the character to substitute
+// followed by its replacement, eg ",，&＆" to substitute "," with
+// FULLWIDTH COMMA and "&" with FULLWIDTH AMPERSAND.
+func ParseSubstitutions(s string) (map[rune]rune, error) {
+	if !utf8.ValidString(s) {
+		return nil, fmt.Errorf("invalid UTF-8 in substitutions %q", s)
+	}
+	runes := []rune(s)
+	if len(runes)%2 != 0 {
+		return nil, fmt.Errorf("substitutions %q must be pairs of characters", s)
+	}
+	substitutions := make(map[rune]rune, len(runes)/2)
+	for i := 0; i < len(runes); i += 2 {
+		c, r := runes[i], runes[i+1]
+		if _, found := substitutions[c]; found {
+			return nil, fmt.Errorf("%q is substituted more than once in %q", c, s)
+		}
+		substitutions[c] = r
+	}
+	return substitutions, nil
+}
+
+// String returns the substitutions in the format read by
+// ParseSubstitutions
+func (e *SubstitutionEncoder) String() string {
+	from := make([]rune, 0, len(e.substitutions))
+	for c := range e.substitutions {
+		from = append(from, c)
+	}
+	sort.Slice(from, func(i, j int) bool { return from[i] < from[j] })
+	var out bytes.Buffer
+	for _, c := range from {
+		out.WriteRune(c)
+		out.WriteRune(e.substitutions[c])
+	}
+	return out.String()
+}
+
+// Encode takes a raw name and substitutes any reserved characters and
+// patterns in it
+func (e *SubstitutionEncoder) Encode(in string) string {
+	return e.mask.encode(&e.table, in)
+}
+
+// Decode takes a name and undoes any substitutions made by Encode
+func (e *SubstitutionEncoder) Decode(in string) string {
+	return e.mask.decode(&e.table, in)
+}
+
+// EncodeWithInfo is like Encode but also returns true if the name
+// was changed.
+func (e *SubstitutionEncoder) EncodeWithInfo(in string) (string, bool) {
+	out := e.Encode(in)
+	return out, out != in
+}
+
+// DecodeWithInfo is like Decode but also returns true if the name
+// was changed.
+func (e *SubstitutionEncoder) DecodeWithInfo(in string) (string, bool) {
+	out := e.Decode(in)
+	return out, out != in
+}
+
+// FromStandardPath takes a / separated path in Standard encoding
+// and converts it to a / separated path in this encoding.
+func (e *SubstitutionEncoder) FromStandardPath(s string) string {
+	return FromStandardPath(e, s)
+}
+
+// FromStandardName takes name in Standard encoding and converts
+// it in this encoding.
+func (e *SubstitutionEncoder) FromStandardName(s string) string {
+	return FromStandardName(e, s)
+}
+
+// ToStandardPath takes a / separated path in this encoding
+// and converts it to a / separated path in Standard encoding.
+func (e *SubstitutionEncoder) ToStandardPath(s string) string {
+	return ToStandardPath(e, s)
+}
+
+// ToStandardName takes name in this encoding and converts
+// it in Standard encoding.
+func (e *SubstitutionEncoder) ToStandardName(s string) string {
+	return ToStandardName(e, s)
+}
+
+// check interface
+var _ Encoder = (*SubstitutionEncoder)(nil)
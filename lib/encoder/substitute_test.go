@@ -0,0 +1,135 @@
+package encoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubstitutionEncoder(t *testing.T) {
+	e, err := NewSubstitutionEncoder(MultiEncoder(EncodeStandard|EncodeLeftSpace), map[rune]rune{
+		',': '，', // FULLWIDTH COMMA
+		'&': '＆', // FULLWIDTH AMPERSAND
+		'é': 'ê',
+		' ': '⎵', // BOTTOM SQUARE BRACKET
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		in  string
+		out string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"a,b", "a，b"},
+		{"a&b,c", "a＆b，c"},
+		{"café", "cafê"},
+		{"a/b", "a／b"},
+		{"a b", "a⎵b"},
+		{" a b", "␠a⎵b"},
+		{"a，b", "a‛，b"},
+		{"a＆,b", "a‛＆，b"},
+		{"cafê", "caf‛ê"},
+		{"a⎵", "a‛⎵"},
+		{"a／,", "a‛／，"},
+		{"‛,", "‛‛，"},
+		{"\x00,", "␀，"},
+	} {
+		got := e.Encode(tc.in)
+		if got != tc.out {
+			t.Errorf("Encode(%q) want %q got %q", tc.in, tc.out, got)
+		}
+		got2 := e.Decode(got)
+		if got2 != tc.in {
+			t.Errorf("Decode(%q) want %q got %q", got, tc.in, got2)
+		}
+	}
+	if got, want := e.String(), " ⎵&＆,，éê"; got != want {
+		t.Errorf("String() want %q got %q", want, got)
+	}
+	if got, want := e.FromStandardPath("a,b/c／d"), "a，b/c／d"; got != want {
+		t.Errorf("FromStandardPath want %q got %q", want, got)
+	}
+}
+
+func TestSubstitutionEncoderVerify(t *testing.T) {
+	names := AdversarialNames()
+	for _, s := range []string{"，", "‛，‛", "&＆é⎵ ⎶", "~＿.∙"} {
+		names = append(names, s, "a"+s, s+"b", s+s)
+	}
+	for _, tc := range []struct {
+		mask          uint
+		substitutions map[rune]rune
+	}{
+		{0, map[rune]rune{',': '，'}},
+		{EncodeStandard, map[rune]rune{',': '，', '&': '＆', 'é': 'ê'}},
+		{EncodeStandard | EncodeInvalidUtf8, map[rune]rune{'B': 'β', 'F': 'φ'}},
+		{EncodeLeftSpace | EncodeRightSpace, map[rune]rune{' ': '⎵', '_': '⎶'}},
+		{EncodeLeftTilde | EncodeRightPeriod, map[rune]rune{'~': '＿', '.': '∙'}},
+		{EncodeWin | EncodeCtl, map[rune]rune{'_': '‗', 'a': '␡'}},
+	} {
+		e, err := NewSubstitutionEncoder(MultiEncoder(tc.mask), tc.substitutions)
+		if err != nil {
+			t.Errorf("mask %#x %q: %v", tc.mask, tc.substitutions, err)
+			continue
+		}
+		if err := Verify(e, names); err != nil {
+			t.Errorf("mask %#x %q: %v", tc.mask, tc.substitutions, err)
+		}
+	}
+}
+
+func TestSubstitutionEncoderErrors(t *testing.T) {
+	for _, tc := range []struct {
+		mask          uint
+		substitutions map[rune]rune
+		want          string
+	}{
+		{0, map[rune]rune{',': ','}, "with itself"},
+		{0, map[rune]rune{',': ';'}, "mustn't be ASCII"},
+		{0, map[rune]rune{',': QuoteRune}, "can't be used"},
+		{0, map[rune]rune{QuoteRune: 'x' + fullOffset}, "can't be used"},
+		{0, map[rune]rune{'�': 'x' + fullOffset}, "can't be used"},
+		{EncodeSlash, map[rune]rune{'/': '∕'}, "already replaced"},
+		{EncodeSlash, map[rune]rune{'／': '∕'}, "is the replacement for"},
+		{EncodeSlash, map[rune]rune{',': '／'}, "already the replacement for"},
+		{EncodeCtl, map[rune]rune{',': '␁'}, "already the replacement for"},
+		{EncodeLeftSpace, map[rune]rune{',': '␠'}, "leading or trailing"},
+		{EncodeRightPeriod, map[rune]rune{',': '．'}, "leading or trailing"},
+		{0, map[rune]rune{',': '，', '，': '⸴'}, "substituted itself"},
+		{0, map[rune]rune{',': '，', '&': '，'}, "can't substitute both"},
+	} {
+		_, err := NewSubstitutionEncoder(MultiEncoder(tc.mask), tc.substitutions)
+		if err == nil {
+			t.Errorf("mask %#x %q: expected error", tc.mask, tc.substitutions)
+		} else if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("mask %#x %q: want error containing %q got %v", tc.mask, tc.substitutions, tc.want, err)
+		}
+	}
+	// the replacement for leading and trailing characters can be used
+	// if they aren't being encoded
+	_, err := NewSubstitutionEncoder(0, map[rune]rune{'~': '～', '.': '．'})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseSubstitutions(t *testing.T) {
+	got, err := ParseSubstitutions(",，&＆")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[','] != '，' || got['&'] != '＆' {
+		t.Errorf("got %q", got)
+	}
+	got, err = ParseSubstitutions("")
+	if err != nil || len(got) != 0 {
+		t.Errorf("got %q, %v", got, err)
+	}
+	for _, in := range []string{",", ",，&", ",，,、", "\xFF，"} {
+		_, err := ParseSubstitutions(in)
+		if err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
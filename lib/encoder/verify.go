@@ -0,0 +1,115 @@
+package encoder
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// maxVerifyErrors is the number of failures Verify reports
+const maxVerifyErrors = 10
+
+// Verify checks that each of samples comes back unchanged after it has
+// been encoded then decoded by e, and that EncodeWithInfo and
+// DecodeWithInfo agree with Encode and Decode.
+//
+// Pass AdversarialNames() as samples to check e against names which
+// are likely to catch encodings which don't round trip, eg
+//
+//	err := encoder.Verify(enc, encoder.AdversarialNames())
+func Verify(e Encoder, samples []string) error {
+	var failures []string
+	fail := func(format string, a ...interface{}) {
+		if len(failures) < maxVerifyErrors {
+			failures = append(failures, fmt.Sprintf(format, a...))
+		} else if len(failures) == maxVerifyErrors {
+			failures = append(failures, "...")
+		}
+	}
+	for _, in := range samples {
+		enc := e.Encode(in)
+		dec := e.Decode(enc)
+		if dec != in {
+			fail("Decode(Encode(%q)) = Decode(%q) = %q", in, enc, dec)
+		}
+		enc2, changed := e.EncodeWithInfo(in)
+		if enc2 != enc || changed != (enc != in) {
+			fail("EncodeWithInfo(%q) = %q, %v but Encode gives %q", in, enc2, changed, enc)
+		}
+		dec2, changed := e.DecodeWithInfo(enc)
+		if dec2 != dec || changed != (dec != enc) {
+			fail("DecodeWithInfo(%q) = %q, %v but Decode gives %q", enc, dec2, changed, dec)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("encoding doesn't round trip:\n  %s", strings.Join(failures, "\n  "))
+	}
+	return nil
+}
+
+// adversarialPieces are the parts the names from AdversarialNames are
+// made from - the runes which are replaced and their replacements
+func adversarialPieces() (pieces []string) {
+	// every ASCII character
+	for c := rune(0); c < 0x80; c++ {
+		pieces = append(pieces, string(c))
+	}
+	// the FULLWIDTH replacements of printable ASCII
+	for c := rune('!'); c <= '~'; c++ {
+		pieces = append(pieces, string(c+fullOffset))
+	}
+	// the SYMBOL FOR replacements of the control characters
+	for c := rune(0); c <= 0x20; c++ {
+		pieces = append(pieces, string(symbolOffset+c))
+	}
+	pieces = append(pieces,
+		"␡",                            // SYMBOL FOR DELETE
+		string(QuoteRune),              // the quote itself
+		string(QuoteRune)+"␀",          // a quoted replacement
+		"\xBF", "\xFE", "\xFF", "\xC0", // invalid UTF-8
+		"\xE2\x80",          // a truncated QuoteRune
+		"‛BF", "‛bf", "‛XY", // look like quoted invalid UTF-8
+		"..", "  ", "~~",
+		"ξ", "日本",
+	)
+	return pieces
+}
+
+// trickyPieces are the pieces which are most likely to confuse an
+// encoder when combined
+var trickyPieces = []string{
+	"\x00", "/", "\\", ":", "*", "#", "%", ".", " ", "~", "\x7F", "\x01",
+	"␀", "␠", "␡", "／", "＼", "：", "＊", "．", "～",
+	string(QuoteRune), "\xBF", "\xFE", "‛BF", "a",
+}
+
+// AdversarialNames returns names designed to find encodings which
+// don't round trip.
+//
+// These include each character which may be replaced and each
+// replacement on its own and at the start, middle and end of a name,
+// the quote rune, invalid UTF-8, pairs of the trickiest of these and
+// a fixed set of random mixtures of them all.  The same names are
+// returned each time.
+func AdversarialNames() []string {
+	pieces := adversarialPieces()
+	var names []string
+	for _, p := range pieces {
+		names = append(names, p, p+"a", "a"+p, "a"+p+"b", p+p, p+"a"+p)
+	}
+	for _, p := range trickyPieces {
+		for _, q := range trickyPieces {
+			names = append(names, p+q, p+"a"+q, p+q+p)
+		}
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		var name bytes.Buffer
+		for n := 1 + rnd.Intn(8); n > 0; n-- {
+			name.WriteString(pieces[rnd.Intn(len(pieces))])
+		}
+		names = append(names, name.String())
+	}
+	return names
+}
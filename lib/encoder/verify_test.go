@@ -0,0 +1,91 @@
+package encoder
+
+import (
+	"strings"
+	"testing"
+)
+
+// allFlags are all the flags of a MultiEncoder
+var allFlags = []uint{
+	EncodeZero, EncodeSlash, EncodeWin, EncodeBackSlash, EncodeHashPercent, EncodeDel, EncodeCtl,
+	EncodeLeftSpace, EncodeLeftTilde, EncodeRightSpace, EncodeRightPeriod, EncodeInvalidUtf8,
+}
+
+func TestVerify(t *testing.T) {
+	names := AdversarialNames()
+	// check every pair of flags
+	for _, flag1 := range allFlags {
+		for _, flag2 := range allFlags {
+			mask := flag1 | flag2
+			if err := Verify(MultiEncoder(mask), names); err != nil {
+				t.Errorf("mask %#x: %v", mask, err)
+			}
+		}
+	}
+	for _, e := range []Encoder{Standard, Identity(), MultiEncoder(EncodeStandard | EncodeWin | EncodeBackSlash | EncodeLeftSpace | EncodeLeftTilde | EncodeRightSpace | EncodeRightPeriod | EncodeInvalidUtf8)} {
+		if err := Verify(e, names); err != nil {
+			t.Errorf("%v: %v", e, err)
+		}
+	}
+}
+
+// lossy is an Encoder which doesn't round trip as it doesn't quote
+// the replacement characters
+type lossy struct{ identity }
+
+func (lossy) Encode(in string) string { return strings.Replace(in, "/", "／", -1) }
+func (lossy) Decode(in string) string { return strings.Replace(in, "／", "/", -1) }
+func (l lossy) EncodeWithInfo(in string) (string, bool) {
+	out := l.Encode(in)
+	return out, out != in
+}
+func (l lossy) DecodeWithInfo(in string) (string, bool) {
+	out := l.Decode(in)
+	return out, out != in
+}
+
+func TestVerifyFails(t *testing.T) {
+	err := Verify(lossy{}, []string{"a/b", "ab"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = Verify(lossy{}, []string{"a/b", "a／b"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), `Decode(Encode("a／b"))`) {
+		t.Errorf("error doesn't name the sample: %v", err)
+	}
+	err = Verify(lossy{}, AdversarialNames())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if got := strings.Count(err.Error(), "\n"); got != maxVerifyErrors+1 {
+		t.Errorf("want %d lines of errors got %d", maxVerifyErrors+1, got)
+	}
+}
+
+func TestAdversarialNames(t *testing.T) {
+	names := AdversarialNames()
+	again := AdversarialNames()
+	if len(names) != len(again) {
+		t.Fatalf("got %d names then %d", len(names), len(again))
+	}
+	for i := range names {
+		if names[i] != again[i] {
+			t.Fatalf("name %d changed from %q to %q", i, names[i], again[i])
+		}
+	}
+	for _, want := range []string{"\x00", "／", string(QuoteRune), "a\xBFb", "‛BF"} {
+		found := false
+		for _, name := range names {
+			if name == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%q not found", want)
+		}
+	}
+}
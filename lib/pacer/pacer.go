@@ -5,6 +5,7 @@ import (
 	"context"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ncw/rclone/fs"
@@ -71,6 +72,17 @@ const (
 	S3Pacer
 )
 
+// lowLevelRetries counts the retries done by all the pacers
+var lowLevelRetries int64
+
+// LowLevelRetries returns the total number of low level retries done
+// by all the pacers so far.  These are mostly caused by rate limiting
+// by the remote so this can be used to see whether it is being
+// throttled.
+func LowLevelRetries() int64 {
+	return atomic.LoadInt64(&lowLevelRetries)
+}
+
 // Paced is a function which is called by the Call and CallNoRetry
 // methods.  It should return a boolean, true if it would like to be
 // retried, and an error.  This error may be returned or returned
@@ -407,6 +419,7 @@ func (p *Pacer) call(fn Paced, retries int) (err error) {
 		if !retry {
 			break
 		}
+		atomic.AddInt64(&lowLevelRetries, 1)
 		fs.Debugf("pacer", "low level retry %d/%d (error %v)", i, retries, err)
 	}
 	if retry {
@@ -64,12 +64,14 @@ func (l *CacheMode) Type() string {
 
 // cache opened files
 type cache struct {
-	f      fs.Fs                 // fs for the cache directory
-	opt    *Options              // vfs Options
-	root   string                // root of the cache directory
-	itemMu sync.Mutex            // protects the following variables
-	item   map[string]*cacheItem // files/directories in the cache
-	used   int64                 // total size of files in the cache
+	f          fs.Fs                    // fs for the cache directory
+	opt        *Options                 // vfs Options
+	root       string                   // root of the cache directory
+	failedPath string                   // file to keep the failed uploads in
+	itemMu     sync.Mutex               // protects the following variables
+	item       map[string]*cacheItem    // files/directories in the cache
+	used       int64                    // total size of files in the cache
+	failed     map[string]*failedUpload // files which failed to upload by name
 }
 
 // cacheItem is stored in the item map
@@ -85,11 +87,9 @@ func newCacheItem(isFile bool) *cacheItem {
 	return &cacheItem{atime: time.Now(), isFile: isFile}
 }
 
-// newCache creates a new cache heirachy for f
-//
-// This starts background goroutines which can be cancelled with the
-// context passed in.
-func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
+// cacheRoot returns the root of f as a relative path suitable for
+// making paths in the cache directory with
+func cacheRoot(f fs.Fs) string {
 	fRoot := filepath.FromSlash(f.Root())
 	if runtime.GOOS == "windows" {
 		if strings.HasPrefix(fRoot, `\\?`) {
@@ -97,7 +97,17 @@ func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
 		}
 		fRoot = strings.Replace(fRoot, ":", "", -1)
 	}
+	return fRoot
+}
+
+// newCache creates a new cache heirachy for f
+//
+// This starts background goroutines which can be cancelled with the
+// context passed in.
+func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
+	fRoot := cacheRoot(f)
 	root := filepath.Join(config.CacheDir, "vfs", f.Name(), fRoot)
+	failedPath := filepath.Join(config.CacheDir, "vfsfailed", f.Name(), fRoot) + ".json"
 	fs.Debugf(nil, "vfs cache root is %q", root)
 
 	f, err := fs.NewFs(root)
@@ -106,10 +116,15 @@ func newCache(ctx context.Context, f fs.Fs, opt *Options) (*cache, error) {
 	}
 
 	c := &cache{
-		f:    f,
-		opt:  opt,
-		root: root,
-		item: make(map[string]*cacheItem),
+		f:          f,
+		opt:        opt,
+		root:       root,
+		failedPath: failedPath,
+		item:       make(map[string]*cacheItem),
+	}
+	err = c.loadFailed()
+	if err != nil {
+		return nil, err
 	}
 
 	go c.cleaner(ctx)
@@ -381,7 +396,7 @@ func (c *cache) _purgeOld(maxAge time.Duration, remove func(name string)) {
 	defer c.itemMu.Unlock()
 	cutoff := time.Now().Add(-maxAge)
 	for name, item := range c.item {
-		if item.isFile && item.opens == 0 {
+		if item.isFile && item.opens == 0 && !c._isFailed(name) {
 			// If not locked and access time too long ago - delete the file
 			dt := item.atime.Sub(cutoff)
 			// fs.Debugf(name, "atime=%v cutoff=%v, dt=%v", item.atime, cutoff, dt)
@@ -446,9 +461,9 @@ func (c *cache) _purgeOverQuota(quota int64, remove func(name string)) {
 
 	var items cacheNamedItems
 
-	// Make a slice of unused files
+	// Make a slice of unused files which don't need uploading
 	for name, item := range c.item {
-		if item.isFile && item.opens == 0 {
+		if item.isFile && item.opens == 0 && !c._isFailed(name) {
 			items = append(items, cacheNamedItem{
 				name: name,
 				item: item,
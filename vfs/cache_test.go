@@ -12,6 +12,7 @@ import (
 
 	"github.com/djherbis/times"
 	"github.com/ncw/rclone/fstest"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -622,3 +623,86 @@ func TestCachePurgeOverQuota(t *testing.T) {
 	assert.Equal(t, int64(0), c.used)
 	assert.Equal(t, []string(nil), itemAsString(c))
 }
+
+func TestCacheFailedUploads(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := DefaultOpt
+	opt.CachePollInterval = 0
+	c, err := newCache(ctx, r.Fremote, &opt)
+	require.NoError(t, err)
+	assert.Equal(t, []failedUpload{}, c.failedUploads())
+
+	c.open("sub/potato")
+	c.close("sub/potato")
+	c.setFailed("/sub/potato", errors.New("boom"))
+	c.setFailed("sub/potato", errors.New("bang"))
+	failed := c.failedUploads()
+	require.Equal(t, 1, len(failed))
+	assert.Equal(t, "sub/potato", failed[0].Name)
+	assert.Equal(t, 2, failed[0].Tries)
+	assert.Equal(t, "bang", failed[0].Error)
+
+	// failed uploads aren't purged
+	var removed []string
+	removeFile := func(name string) {
+		removed = append(removed, name)
+	}
+	c._purgeOld(-10*time.Second, removeFile)
+	c.used = 100
+	c._purgeOverQuota(1, removeFile)
+	assert.Equal(t, []string(nil), removed)
+
+	// and are remembered by the next cache
+	c2, err := newCache(ctx, r.Fremote, &opt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub/potato"}, []string{c2.failedUploads()[0].Name})
+
+	c2.setFailed("sub/potato", nil)
+	assert.Equal(t, []failedUpload{}, c2.failedUploads())
+	_, err = os.Stat(c2.failedPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCacheDueUploads(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := DefaultOpt
+	opt.CachePollInterval = 0
+	opt.WriteRetries = 2
+	opt.WriteRetryDelay = time.Second
+	c, err := newCache(ctx, r.Fremote, &opt)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, c.retryDelay(1))
+	assert.Equal(t, 4*time.Second, c.retryDelay(3))
+
+	// Retried by the uploader once the delay has passed
+	c.setFailed("potato", errors.New("boom"))
+	assert.True(t, c.willRetry("potato"))
+	now := time.Now()
+	assert.Equal(t, []string(nil), c.dueUploads(now))
+	assert.Equal(t, []string{"potato"}, c.dueUploads(now.Add(time.Second)))
+
+	// The delay doubles each time
+	c.setFailed("potato", errors.New("boom"))
+	assert.True(t, c.willRetry("potato"))
+	now = time.Now()
+	assert.Equal(t, []string(nil), c.dueUploads(now.Add(time.Second)))
+	assert.Equal(t, []string{"potato"}, c.dueUploads(now.Add(2*time.Second)))
+
+	// Until --vfs-write-retries have been done
+	c.setFailed("potato", errors.New("boom"))
+	assert.False(t, c.willRetry("potato"))
+	assert.Equal(t, []string(nil), c.dueUploads(time.Now().Add(time.Hour)))
+
+	c.setFailed("potato", nil)
+	assert.False(t, c.willRetry("potato"))
+}
@@ -0,0 +1,259 @@
+// This deals with uploading files from the cache and keeping track
+// of the ones which failed
+
+package vfs
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/pkg/errors"
+)
+
+// failedUpload records a file in the cache which couldn't be
+// uploaded to the remote
+type failedUpload struct {
+	Name  string    `json:"name"`  // remote path of the file
+	Time  time.Time `json:"time"`  // time of the last attempt
+	Tries int       `json:"tries"` // number of attempts so far
+	Error string    `json:"error"` // error from the last attempt
+}
+
+// loadFailed reads the list of failed uploads from disk
+//
+// Call before the cache is in use
+func (c *cache) loadFailed() error {
+	c.failed = make(map[string]*failedUpload)
+	data, err := ioutil.ReadFile(c.failedPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read failed uploads")
+	}
+	var failed []*failedUpload
+	err = json.Unmarshal(data, &failed)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse failed uploads")
+	}
+	for _, upload := range failed {
+		c.failed[upload.Name] = upload
+	}
+	if len(failed) > 0 {
+		fs.Errorf(nil, "vfs cache: %d files failed to upload last time - retry them with rclone rc vfs/retry", len(failed))
+	}
+	return nil
+}
+
+// _saveFailed writes the list of failed uploads to disk, removing
+// the file if there aren't any
+//
+// Call with itemMu held
+func (c *cache) _saveFailed() {
+	if len(c.failed) == 0 {
+		err := os.Remove(c.failedPath)
+		if err != nil && !os.IsNotExist(err) {
+			fs.Errorf(nil, "vfs cache: failed to remove failed uploads list: %v", err)
+		}
+		return
+	}
+	data, err := json.MarshalIndent(c._failedUploads(), "", "\t")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(c.failedPath), 0700)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(c.failedPath, data, 0600)
+	}
+	if err != nil {
+		fs.Errorf(nil, "vfs cache: failed to save failed uploads list: %v", err)
+	}
+}
+
+// _failedUploads returns a copy of the failed uploads sorted by name
+//
+// Call with itemMu held
+func (c *cache) _failedUploads() []failedUpload {
+	failed := make([]failedUpload, 0, len(c.failed))
+	for _, upload := range c.failed {
+		failed = append(failed, *upload)
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Name < failed[j].Name
+	})
+	return failed
+}
+
+// failedUploads returns a copy of the failed uploads sorted by name
+func (c *cache) failedUploads() []failedUpload {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	return c._failedUploads()
+}
+
+// _isFailed returns true if name failed to upload - call with itemMu held
+func (c *cache) _isFailed(name string) bool {
+	_, found := c.failed[name]
+	return found
+}
+
+// setFailed records the result of uploading name
+func (c *cache) setFailed(name string, uploadErr error) {
+	name = clean(name)
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	upload := c.failed[name]
+	if uploadErr == nil {
+		if upload == nil {
+			return
+		}
+		delete(c.failed, name)
+	} else {
+		if upload == nil {
+			upload = &failedUpload{Name: name}
+			c.failed[name] = upload
+		}
+		upload.Time = time.Now()
+		upload.Tries++
+		upload.Error = uploadErr.Error()
+	}
+	c._saveFailed()
+}
+
+// retryDelay returns how long to wait before retrying an upload
+// which has failed tries times - this doubles for each try.
+func (c *cache) retryDelay(tries int) time.Duration {
+	if tries < 1 {
+		tries = 1
+	}
+	return c.opt.WriteRetryDelay << uint(tries-1)
+}
+
+// dueUploads returns the names of the failed uploads which the
+// uploader should retry now.  These have been tried no more than
+// --vfs-write-retries times and their retry delay has passed.
+func (c *cache) dueUploads(now time.Time) (names []string) {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	for _, upload := range c._failedUploads() {
+		if upload.Tries <= c.opt.WriteRetries && now.Sub(upload.Time) >= c.retryDelay(upload.Tries) {
+			names = append(names, upload.Name)
+		}
+	}
+	return names
+}
+
+// willRetry returns true if the uploader will retry the failed upload
+// of name
+func (c *cache) willRetry(name string) bool {
+	c.itemMu.Lock()
+	defer c.itemMu.Unlock()
+	upload := c.failed[clean(name)]
+	return upload != nil && upload.Tries <= c.opt.WriteRetries
+}
+
+// upload transfers the file name from the cache to the remote f,
+// replacing dst if it isn't nil.
+//
+// If the file can't be uploaded then it is added to the failed
+// uploads so it stays in the cache.  The uploader retries it in the
+// background --vfs-write-retries times, doubling the delay between
+// attempts each time, then it stays there until it is retried with
+// vfs/retry or deleted.
+func (c *cache) upload(f fs.Fs, dst fs.Object, name string) (o fs.Object, err error) {
+	cacheObj, err := c.f.NewObject(name)
+	if err != nil {
+		err = errors.Wrap(err, "failed to find cache file")
+	} else {
+		o, err = copyObj(f, dst, name, cacheObj)
+	}
+	c.setFailed(name, err)
+	if err != nil {
+		err = errors.Wrap(err, "failed to transfer file from cache to remote")
+		if c.willRetry(name) {
+			fs.Errorf(name, "%v - retrying in the background", err)
+		} else {
+			fs.Errorf(name, "%v - keeping in the cache to retry with rclone rc vfs/retry", err)
+		}
+	}
+	return o, err
+}
+
+// uploader retries the failed uploads in c in the background when
+// they are due.
+//
+// doesn't return until context is cancelled
+func (vfs *VFS) uploader(ctx context.Context, c *cache) {
+	interval := c.opt.WriteRetryDelay
+	if interval <= 0 {
+		interval = time.Second
+	}
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if names := c.dueUploads(time.Now()); len(names) > 0 {
+				_, err := vfs.retry(c, names)
+				if err != nil {
+					fs.Errorf(nil, "vfs cache: failed to retry uploads: %v", err)
+				}
+			}
+		case <-ctx.Done():
+			fs.Debugf(nil, "cache uploader exiting")
+			return
+		}
+	}
+}
+
+// retryUploads tries to upload the files in names from the cache
+// again, or all the failed uploads if names is empty.
+//
+// It returns the names which were uploaded.
+func (vfs *VFS) retryUploads(names []string) (uploaded []string, err error) {
+	c := vfs.cache
+	if c == nil {
+		return nil, errors.New("vfs cache not in use")
+	}
+	if len(names) == 0 {
+		for _, upload := range c.failedUploads() {
+			names = append(names, upload.Name)
+		}
+	}
+	return vfs.retry(c, names)
+}
+
+// retry tries to upload the files in names from the cache c again
+//
+// It returns the names which were uploaded.
+func (vfs *VFS) retry(c *cache, names []string) (uploaded []string, err error) {
+	root, err := vfs.Root()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		name = clean(name)
+		if c.opens(name) > 0 {
+			fs.Infof(name, "vfs cache: not retrying upload as file is open - it will be uploaded when closed")
+			continue
+		}
+		dst, err := vfs.f.NewObject(name)
+		if err == fs.ErrorObjectNotFound {
+			dst = nil
+		} else if err != nil {
+			c.setFailed(name, err)
+			continue
+		}
+		_, err = c.upload(vfs.f, dst, name)
+		if err == nil {
+			uploaded = append(uploaded, name)
+			root.ForgetPath(name, fs.EntryObject)
+		}
+	}
+	return uploaded, nil
+}
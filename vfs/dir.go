@@ -37,7 +37,7 @@ func newDir(vfs *VFS, f fs.Fs, parent *Dir, fsDir fs.Directory) *Dir {
 		entry:   fsDir,
 		path:    fsDir.Remote(),
 		modTime: fsDir.ModTime(),
-		inode:   newInode(),
+		inode:   vfs.inodes.Inode(fsDir.Remote()),
 		items:   make(map[string]Node),
 	}
 }
@@ -65,6 +65,12 @@ func (d *Dir) Mode() (mode os.FileMode) {
 	return d.vfs.Opt.DirPerms
 }
 
+// Owner returns the user and group IDs of the directory - satisfies
+// Node interface
+func (d *Dir) Owner() (uid, gid uint32) {
+	return d.vfs.Opt.UID, d.vfs.Opt.GID
+}
+
 // Name (base) of the directory - satisfies Node interface
 func (d *Dir) Name() (name string) {
 	name = path.Base(d.path)
@@ -89,6 +95,27 @@ func (d *Dir) Inode() uint64 {
 	return d.inode
 }
 
+// Nlink returns the number of links to the directory - satisfies
+// Node interface
+//
+// This is 2 plus the number of subdirectories if the directory
+// listing is cached, otherwise 1 which tells tools like find that the
+// number of subdirectories isn't known.
+func (d *Dir) Nlink() uint32 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stale(time.Now()) {
+		return 1
+	}
+	nlink := uint32(2)
+	for _, node := range d.items {
+		if node.IsDir() {
+			nlink++
+		}
+	}
+	return nlink
+}
+
 // Node returns the Node assocuated with this - satisfies Noder interface
 func (d *Dir) Node() Node {
 	return d
@@ -496,6 +523,7 @@ func (d *Dir) Remove() error {
 	if d.parent != nil {
 		d.parent.delObject(d.Name())
 	}
+	d.vfs.inodes.Remove(d.path)
 	return nil
 }
 
@@ -590,6 +618,7 @@ func (d *Dir) Rename(oldName, newName string, destDir *Dir) error {
 			fs.Errorf(oldPath, "Dir.Rename error: %v", err)
 			return err
 		}
+		d.vfs.inodes.Rename(srcRemote, dstRemote)
 		newDir := fs.NewDirCopy(x).SetRemote(newPath)
 		// Update the node with the new details
 		if oldNode != nil {
@@ -20,6 +20,9 @@ const (
 	EBADF
 	EROFS
 	ENOSYS
+	EDQUOT
+	EACCES
+	EBUSY
 )
 
 // Errors which have exact counterparts in os
@@ -38,6 +41,9 @@ var errorNames = []string{
 	EBADF:     "Bad file descriptor",
 	EROFS:     "Read only file system",
 	ENOSYS:    "Function not implemented",
+	EDQUOT:    "Disk quota exceeded",
+	EACCES:    "Permission denied",
+	EBUSY:     "Device or resource busy",
 }
 
 // Error renders the error as a string
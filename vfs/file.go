@@ -40,7 +40,7 @@ func newFile(d *Dir, o fs.Object, leaf string) *File {
 		d:     d,
 		o:     o,
 		leaf:  leaf,
-		inode: newInode(),
+		inode: d.vfs.inodes.Inode(path.Join(d.path, leaf)),
 	}
 }
 
@@ -63,8 +63,51 @@ func (f *File) IsDir() bool {
 }
 
 // Mode bits of the file or directory - satisfies Node interface
+//
+// With --vfs-metadata-perms these come from the metadata of the
+// object, masked with the umask, if the backend supplies them.
 func (f *File) Mode() (mode os.FileMode) {
-	return f.d.vfs.Opt.FilePerms
+	mode = f.d.vfs.Opt.FilePerms
+	if perm, ok := f.metadata().Mode(); ok {
+		mode = mode&^os.ModePerm | perm&^os.FileMode(f.d.vfs.Opt.Umask)
+	}
+	return mode
+}
+
+// Owner returns the user and group IDs of the file - satisfies Node
+// interface
+//
+// With --vfs-metadata-perms these come from the metadata of the
+// object if the backend supplies them, otherwise from --uid and
+// --gid.
+func (f *File) Owner() (uid, gid uint32) {
+	uid, gid = f.d.vfs.Opt.UID, f.d.vfs.Opt.GID
+	m := f.metadata()
+	if id, ok := m.UID(); ok {
+		uid = id
+	}
+	if id, ok := m.GID(); ok {
+		gid = id
+	}
+	return uid, gid
+}
+
+// metadata returns the metadata of the object if --vfs-metadata-perms
+// is set, or nil if it isn't or there is none
+func (f *File) metadata() fs.Metadata {
+	if !f.d.vfs.Opt.MetadataPerms {
+		return nil
+	}
+	o := f.getObject()
+	if o == nil {
+		return nil
+	}
+	m, err := fs.GetMetadata(o)
+	if err != nil {
+		fs.Debugf(f, "Failed to read metadata: %v", err)
+		return nil
+	}
+	return m
 }
 
 // Name (base) of the directory - satisfies Node interface
@@ -87,6 +130,19 @@ func (f *File) Inode() uint64 {
 	return f.inode
 }
 
+// Nlink returns the number of hard links to the file - satisfies
+// Node interface
+//
+// This is 1 unless the backend says the object is hard linked.
+func (f *File) Nlink() uint32 {
+	if do, ok := f.getObject().(fs.HardLinkIDer); ok {
+		if _, links := do.HardLinkID(); links > 1 {
+			return uint32(links)
+		}
+	}
+	return 1
+}
+
 // Node returns the Node assocuated with this - satisfies Noder interface
 func (f *File) Node() Node {
 	return f
@@ -129,6 +185,7 @@ func (f *File) rename(destDir *Dir, newName string) error {
 			fs.Errorf(f.Path(), "File.Rename %v", err)
 			return err
 		}
+		f.d.vfs.inodes.Rename(f.o.Remote(), newObject.Remote())
 		// Update the node with the new details
 		fs.Debugf(f.o, "Updating file with %v %p", newObject, f)
 		// f.rename(destDir, newObject)
@@ -466,9 +523,11 @@ func (f *File) Remove() error {
 
 	// Remove the item from the directory listing
 	f.d.delObject(f.Name())
+	f.d.vfs.inodes.Remove(f.Path())
 	// Remove the object from the cache
 	if f.d.vfs.Opt.CacheMode >= CacheModeMinimal {
 		f.d.vfs.cache.remove(f.Path())
+		f.d.vfs.cache.setFailed(f.Path(), nil)
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fstest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -75,6 +76,48 @@ func TestFileMethods(t *testing.T) {
 	assert.Equal(t, vfs, file.VFS())
 }
 
+func TestFileMetadataPerms(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject("file1", "file1 contents", t1)
+	fstest.CheckItems(t, r.Fremote, file1)
+
+	o, err := r.Fremote.NewObject("file1")
+	require.NoError(t, err)
+	m, err := fs.GetMetadata(o)
+	require.NoError(t, err)
+	perm, ok := m.Mode()
+	if !ok {
+		t.Skip("backend doesn't supply permissions")
+	}
+
+	opt := DefaultOpt
+	opt.MetadataPerms = true
+	opt.Umask = 0077
+	vfs := New(r.Fremote, &opt)
+	node, err := vfs.Stat("file1")
+	require.NoError(t, err)
+	file := node.(*File)
+
+	assert.Equal(t, perm&^0077, file.Mode())
+	uid, gid := file.Owner()
+	if wantUID, ok := m.UID(); ok {
+		assert.Equal(t, wantUID, uid)
+	}
+	if wantGID, ok := m.GID(); ok {
+		assert.Equal(t, wantGID, gid)
+	}
+
+	// Without the option the defaults are used
+	vfs = New(r.Fremote, nil)
+	node, err = vfs.Stat("file1")
+	require.NoError(t, err)
+	assert.Equal(t, vfs.Opt.FilePerms, node.Mode())
+	uid, gid = node.(*File).Owner()
+	assert.Equal(t, vfs.Opt.UID, uid)
+	assert.Equal(t, vfs.Opt.GID, gid)
+}
+
 func TestFileSetModTime(t *testing.T) {
 	r := fstest.NewRun(t)
 	defer r.Finalise()
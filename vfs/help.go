@@ -61,6 +61,8 @@ may find that you need one or the other or both.
     --vfs-cache-mode string              Cache mode off|minimal|writes|full (default "off")
     --vfs-cache-poll-interval duration   Interval to poll the cache for stale objects. (default 1m0s)
     --vfs-cache-max-size int             Max total size of objects in the cache. (default off)
+    --vfs-write-retries int              Number of times to retry uploading a file from the cache. (default 3)
+    --vfs-write-retry-delay duration     Delay before retrying an upload from the cache, doubled for each retry. (default 1s)
 
 If run with ` + "`-vv`" + ` rclone will print the location of the file cache.  The
 files are stored in the user cache file area which is OS dependent but
@@ -81,6 +83,16 @@ for two reasons.  Firstly because it is only checked every
 --vfs-cache-poll-interval.  Secondly because open files cannot be
 evicted from the cache.
 
+If a file can't be uploaded when it is closed, eg because the remote
+is full or the network is down, the close returns an error and rclone
+retries the upload in the background --vfs-write-retries times,
+waiting --vfs-write-retry-delay before the first retry and twice as
+long before each one after that.  The file is kept in the cache, and
+isn't removed by --vfs-cache-max-age or --vfs-cache-max-size, until it
+is uploaded or deleted.  This is remembered when the mount is stopped.  List these
+files with ` + "`rclone rc vfs/failed`" + ` and upload them again with
+` + "`rclone rc vfs/retry`" + `.
+
 #### --vfs-cache-mode off
 
 In this mode the cache will read directly from the remote and write
@@ -136,4 +148,13 @@ This mode should support all normal file system operations.
 
 If an upload or download fails it will be retried up to
 --low-level-retries times.
+
+### File Locking
+
+rclone doesn't store advisory locks (flock and POSIX byte range
+locks) on the remote.  When mounting with FUSE on Linux and OS X the
+kernel keeps track of the locks itself, so programs which insist on
+locking their files, such as office suites and SQLite, can use them,
+but the locks aren't seen by other rclone processes or other users of
+the remote.
 `
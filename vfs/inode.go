@@ -0,0 +1,12 @@
+package vfs
+
+import (
+	"sync/atomic"
+)
+
+var inodeCount uint64
+
+// newInode creates a new unique inode number
+func newInode() (inode uint64) {
+	return atomic.AddUint64(&inodeCount, 1)
+}
@@ -0,0 +1,231 @@
+// +build !plan9
+
+package vfs
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ncw/rclone/fs"
+	"github.com/ncw/rclone/fs/config"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// how long new inode numbers are kept before writing them to disk
+const inodeFlushDelay = time.Second
+
+// names of the bolt buckets used
+var (
+	inodeBucket = []byte("inodes") // inode numbers by path
+	metaBucket  = []byte("meta")   // the next inode number
+	nextKey     = []byte("next")
+)
+
+// inodeTable hands out inode numbers which are kept the same across
+// remounts for --vfs-persistent-inodes by storing them in the cache
+// directory.
+type inodeTable struct {
+	db      *bolt.DB
+	mu      sync.Mutex        // protect the below
+	next    uint64            // next inode number to hand out
+	pending map[string]uint64 // inode numbers not written to the database yet
+	flusher *time.Timer       // set if a flush is scheduled
+}
+
+var (
+	inodeTablesMu sync.Mutex
+	inodeTables   = make(map[string]*inodeTable) // open inode tables by path
+)
+
+// newInodeTable opens the inode table for f or returns nil if
+// --vfs-persistent-inodes isn't in use or it can't be opened.
+//
+// The tables are shared between VFSes on the same Fs and are never
+// closed as the database can only be opened once.
+func newInodeTable(f fs.Fs, opt *Options) *inodeTable {
+	if !opt.PersistentInodes {
+		return nil
+	}
+	dbPath := filepath.Join(config.CacheDir, "vfsinodes", f.Name(), cacheRoot(f)) + ".db"
+	inodeTablesMu.Lock()
+	defer inodeTablesMu.Unlock()
+	if t := inodeTables[dbPath]; t != nil {
+		return t
+	}
+	t, err := openInodeTable(dbPath)
+	if err != nil {
+		fs.Errorf(f, "Failed to open inode table - inode numbers won't persist: %v", err)
+		return nil
+	}
+	fs.Debugf(f, "Inode numbers are kept in %q", dbPath)
+	inodeTables[dbPath] = t
+	return t
+}
+
+// openInodeTable opens the inode table in the database at dbPath
+func openInodeTable(dbPath string) (*inodeTable, error) {
+	err := os.MkdirAll(filepath.Dir(dbPath), 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make directory for inode table")
+	}
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open inode table")
+	}
+	t := &inodeTable{
+		db:      db,
+		next:    1,
+		pending: make(map[string]uint64),
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(inodeBucket); err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		if v := meta.Get(nextKey); len(v) == 8 {
+			t.next = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to initialise inode table")
+	}
+	return t, nil
+}
+
+// encodeInode encodes inode for the database
+func encodeInode(inode uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], inode)
+	return buf[:]
+}
+
+// Inode returns the inode number for path, handing out a new one if
+// path hasn't been seen before.
+//
+// It is safe to call on a nil *inodeTable in which case it returns a
+// new inode number each time.
+func (t *inodeTable) Inode(path string) (inode uint64) {
+	if t == nil {
+		return newInode()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if inode, ok := t.pending[path]; ok {
+		return inode
+	}
+	err := t.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(inodeBucket).Get([]byte(path)); len(v) == 8 {
+			inode = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+	if err != nil {
+		fs.Errorf(path, "Failed to read inode number: %v", err)
+	}
+	if inode != 0 {
+		return inode
+	}
+	inode = t.next
+	t.next++
+	t.pending[path] = inode
+	if t.flusher == nil {
+		t.flusher = time.AfterFunc(inodeFlushDelay, t.flush)
+	}
+	return inode
+}
+
+// _flush writes the pending inode numbers to the database, calling
+// fn in the same transaction if set - call with the lock held
+func (t *inodeTable) _flush(fn func(inodes *bolt.Bucket) error) {
+	if t.flusher != nil {
+		t.flusher.Stop()
+		t.flusher = nil
+	}
+	err := t.db.Update(func(tx *bolt.Tx) error {
+		inodes := tx.Bucket(inodeBucket)
+		for path, inode := range t.pending {
+			if err := inodes.Put([]byte(path), encodeInode(inode)); err != nil {
+				return err
+			}
+		}
+		if fn != nil {
+			if err := fn(inodes); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(metaBucket).Put(nextKey, encodeInode(t.next))
+	})
+	if err != nil {
+		fs.Errorf(nil, "Failed to write inode table: %v", err)
+	}
+	t.pending = make(map[string]uint64)
+}
+
+// flush writes the pending inode numbers to the database
+func (t *inodeTable) flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t._flush(nil)
+}
+
+// Rename moves the inode number of oldPath and the paths below it to
+// newPath.
+//
+// It is safe to call on a nil *inodeTable.
+func (t *inodeTable) Rename(oldPath, newPath string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t._flush(func(inodes *bolt.Bucket) error {
+		// find the keys to move first as the bucket can't be
+		// changed while iterating over it
+		type move struct{ from, to, inode []byte }
+		var moves []move
+		if v := inodes.Get([]byte(oldPath)); v != nil {
+			moves = append(moves, move{[]byte(oldPath), []byte(newPath), append([]byte(nil), v...)})
+		}
+		prefix := oldPath + "/"
+		c := inodes.Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, v = c.Next() {
+			moves = append(moves, move{append([]byte(nil), k...), []byte(newPath + "/" + string(k[len(prefix):])), append([]byte(nil), v...)})
+		}
+		for _, m := range moves {
+			if err := inodes.Delete(m.from); err != nil {
+				return err
+			}
+		}
+		for _, m := range moves {
+			if err := inodes.Put(m.to, m.inode); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Remove forgets the inode number of path.
+//
+// It is safe to call on a nil *inodeTable.
+func (t *inodeTable) Remove(path string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, path)
+	t._flush(func(inodes *bolt.Bucket) error {
+		return inodes.Delete([]byte(path))
+	})
+}
@@ -0,0 +1,55 @@
+// +build !plan9
+
+package vfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInodeTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rclone-vfs-inodes")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	dbPath := filepath.Join(dir, "sub", "inodes.db")
+
+	table, err := openInodeTable(dbPath)
+	require.NoError(t, err)
+
+	// the same path gets the same number before and after a flush
+	dirInode := table.Inode("dir")
+	fileInode := table.Inode("dir/file")
+	otherInode := table.Inode("other")
+	assert.Equal(t, dirInode, table.Inode("dir"))
+	assert.NotEqual(t, dirInode, fileInode)
+	table.flush()
+	assert.Equal(t, dirInode, table.Inode("dir"))
+	assert.Equal(t, fileInode, table.Inode("dir/file"))
+
+	// renaming a directory moves the paths below it too
+	table.Rename("dir", "newdir")
+	assert.Equal(t, dirInode, table.Inode("newdir"))
+	assert.Equal(t, fileInode, table.Inode("newdir/file"))
+	assert.NotEqual(t, dirInode, table.Inode("dir"))
+
+	// removing forgets the number
+	table.Remove("other")
+	newOtherInode := table.Inode("other")
+	assert.NotEqual(t, otherInode, newOtherInode)
+
+	// the numbers are the same after reopening
+	table.flush()
+	require.NoError(t, table.db.Close())
+	table, err = openInodeTable(dbPath)
+	require.NoError(t, err)
+	defer func() { _ = table.db.Close() }()
+	assert.Equal(t, dirInode, table.Inode("newdir"))
+	assert.Equal(t, fileInode, table.Inode("newdir/file"))
+	assert.Equal(t, newOtherInode, table.Inode("other"))
+	assert.True(t, table.Inode("new") > newOtherInode)
+}
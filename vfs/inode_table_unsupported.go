@@ -0,0 +1,34 @@
+// Stub --vfs-persistent-inodes for the platforms bolt doesn't support
+
+// +build plan9
+
+package vfs
+
+import (
+	"github.com/ncw/rclone/fs"
+)
+
+// inodeTable isn't supported on this platform
+type inodeTable struct{}
+
+// newInodeTable returns nil as --vfs-persistent-inodes isn't
+// supported on this platform.
+func newInodeTable(f fs.Fs, opt *Options) *inodeTable {
+	if opt.PersistentInodes {
+		fs.Errorf(f, "Ignoring --vfs-persistent-inodes as it isn't supported on this platform")
+	}
+	return nil
+}
+
+// Inode returns a new inode number each time
+func (t *inodeTable) Inode(path string) (inode uint64) {
+	return newInode()
+}
+
+// Rename does nothing as no inode numbers are kept
+func (t *inodeTable) Rename(oldPath, newPath string) {
+}
+
+// Remove does nothing as no inode numbers are kept
+func (t *inodeTable) Remove(path string) {
+}
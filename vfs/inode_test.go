@@ -0,0 +1,16 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInodeTableNil(t *testing.T) {
+	var table *inodeTable
+	a := table.Inode("a")
+	b := table.Inode("a")
+	assert.NotEqual(t, a, b)
+	table.Rename("a", "b")
+	table.Remove("b")
+}
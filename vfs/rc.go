@@ -193,6 +193,70 @@ not reached.
 If poll-interval is updated or disabled temporarily, some changes
 might not get picked up by the polling function, depending on the
 used remote.
+`,
+	})
+	rc.Add(rc.Call{
+		Path: "vfs/failed",
+		Fn: func(in rc.Params) (out rc.Params, err error) {
+			if vfs.cache == nil {
+				return nil, errors.New("vfs cache not in use")
+			}
+			return rc.Params{
+				"failed": vfs.cache.failedUploads(),
+			}, nil
+		},
+		Title: "List the files which failed to upload from the cache.",
+		Help: `
+This lists the files which couldn't be uploaded from the VFS cache
+after --vfs-write-retries attempts.  These are kept in the cache,
+even when the mount is stopped, until they are uploaded or deleted.
+
+    rclone rc vfs/failed
+
+Each entry has the name of the file, the time of the last attempt,
+the number of attempts and the last error.
+`,
+	})
+	rc.Add(rc.Call{
+		Path: "vfs/retry",
+		Fn: func(in rc.Params) (out rc.Params, err error) {
+			var names []string
+			for k, v := range in {
+				path, ok := v.(string)
+				if !ok {
+					return out, errors.Errorf("value must be string %q=%v", k, v)
+				}
+				if !strings.HasPrefix(k, "file") {
+					return out, errors.Errorf("unknown key %q", k)
+				}
+				names = append(names, path)
+			}
+			uploaded, err := vfs.retryUploads(names)
+			if err != nil {
+				return nil, err
+			}
+			if uploaded == nil {
+				uploaded = []string{}
+			}
+			return rc.Params{
+				"uploaded": uploaded,
+				"failed":   vfs.cache.failedUploads(),
+			}, nil
+		},
+		Title:        "Retry uploading the files which failed to upload from the cache.",
+		AuthRequired: true,
+		Help: `
+This tries to upload the files which failed to upload from the VFS
+cache again.
+
+    rclone rc vfs/retry
+
+Pass files in as file=path to retry just those, eg
+
+    rclone rc vfs/retry file=hello file2=home/junk/goodbye
+
+It returns the files which were uploaded and the ones which still
+failed.
 `,
 	})
 }
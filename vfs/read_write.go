@@ -296,17 +296,8 @@ func (fh *RWFileHandle) close() (err error) {
 
 	if isCopied {
 		// Transfer the temp file to the remote
-		cacheObj, err := fh.d.vfs.cache.f.NewObject(fh.remote)
+		o, err := fh.d.vfs.cache.upload(fh.d.vfs.f, fh.file.getObject(), fh.remote)
 		if err != nil {
-			err = errors.Wrap(err, "failed to find cache file")
-			fs.Errorf(fh.logPrefix(), "%v", err)
-			return err
-		}
-
-		o, err := copyObj(fh.d.vfs.f, fh.file.getObject(), fh.remote, cacheObj)
-		if err != nil {
-			err = errors.Wrap(err, "failed to transfer file from cache to remote")
-			fs.Errorf(fh.logPrefix(), "%v", err)
 			return err
 		}
 		fh.file.setObject(o)
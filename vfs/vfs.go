@@ -25,11 +25,11 @@ import (
 	"path"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/ncw/rclone/fs"
 	"github.com/ncw/rclone/fs/log"
+	"github.com/ncw/rclone/lib/atexit"
 )
 
 // DefaultOpt is the default values uses for Opt
@@ -51,6 +51,8 @@ var DefaultOpt = Options{
 	ChunkSize:         128 * fs.MebiByte,
 	ChunkSizeLimit:    -1,
 	CacheMaxSize:      -1,
+	WriteRetries:      3,
+	WriteRetryDelay:   time.Second,
 }
 
 // Node represents either a directory (*Dir) or a file (*File)
@@ -58,6 +60,7 @@ type Node interface {
 	os.FileInfo
 	IsFile() bool
 	Inode() uint64
+	Nlink() uint32
 	SetModTime(modTime time.Time) error
 	Sync() error
 	Remove() error
@@ -67,6 +70,7 @@ type Node interface {
 	Open(flags int) (Handle, error)
 	Truncate(size int64) error
 	Path() string
+	Owner() (uid, gid uint32)
 }
 
 // Check interfaces
@@ -178,6 +182,8 @@ type VFS struct {
 	usageTime time.Time
 	usage     *fs.Usage
 	pollChan  chan time.Duration
+	inodes    *inodeTable // persistent inode numbers, nil if not in use
+	exitFn    atexit.FnHandle
 }
 
 // Options is options for creating the vfs
@@ -193,12 +199,16 @@ type Options struct {
 	GID               uint32
 	DirPerms          os.FileMode
 	FilePerms         os.FileMode
+	MetadataPerms     bool          // use the permissions and owner from the backend metadata if available
 	ChunkSize         fs.SizeSuffix // if > 0 read files in chunks
 	ChunkSizeLimit    fs.SizeSuffix // if > ChunkSize double the chunk size after each chunk until reached
 	CacheMode         CacheMode
 	CacheMaxAge       time.Duration
 	CacheMaxSize      fs.SizeSuffix
 	CachePollInterval time.Duration
+	WriteRetries      int           // number of times to retry uploads from the cache
+	WriteRetryDelay   time.Duration // delay before the first retry, doubled for each one
+	PersistentInodes  bool          // keep inode numbers the same across remounts
 }
 
 // New creates a new VFS and root directory.  If opt is nil, then
@@ -224,6 +234,7 @@ func New(f fs.Fs, opt *Options) *VFS {
 	vfs.Opt.DirPerms |= os.ModeDir
 
 	// Create root directory
+	vfs.inodes = newInodeTable(f, &vfs.Opt)
 	vfs.root = newDir(vfs, f, nil, fsDir)
 
 	// Start polling function
@@ -237,14 +248,30 @@ func New(f fs.Fs, opt *Options) *VFS {
 
 	vfs.SetCacheMode(vfs.Opt.CacheMode)
 
+	// Finish the writes in progress on exit
+	vfs.exitFn = atexit.Register(vfs.exit)
+
 	// add the remote control
 	vfs.addRC()
 	return vfs
 }
 
+// exitWritersTimeout is how long exit waits for the writes in
+// progress to finish with --cutoff-mode soft
+const exitWritersTimeout = 30 * time.Second
+
+// exit is called when rclone exits.  With --cutoff-mode soft it waits
+// for the files being written to be closed and uploaded first.
+func (vfs *VFS) exit() {
+	if fs.Config.CutoffMode == "soft" {
+		vfs.WaitForWriters(exitWritersTimeout)
+	}
+	vfs.Shutdown()
+}
+
 // SetCacheMode change the cache mode
 func (vfs *VFS) SetCacheMode(cacheMode CacheMode) {
-	vfs.Shutdown()
+	vfs.stopCache()
 	vfs.cache = nil
 	if vfs.Opt.CacheMode > CacheModeOff {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -257,11 +284,26 @@ func (vfs *VFS) SetCacheMode(cacheMode CacheMode) {
 		}
 		vfs.cancel = cancel
 		vfs.cache = cache
+		go vfs.uploader(ctx, cache)
 	}
 }
 
 // Shutdown stops any background go-routines
 func (vfs *VFS) Shutdown() {
+	vfs.stopCache()
+	if vfs.exitFn != nil {
+		atexit.Unregister(vfs.exitFn)
+		vfs.exitFn = nil
+	}
+}
+
+// stopCache stops the background go-routines of the cache
+func (vfs *VFS) stopCache() {
+	if vfs.cache != nil {
+		if n := len(vfs.cache.failedUploads()); n > 0 {
+			fs.Errorf(nil, "vfs cache: %d files failed to upload and are kept in %q - retry them with rclone rc vfs/retry next time", n, vfs.cache.root)
+		}
+	}
 	if vfs.cancel != nil {
 		vfs.cancel()
 		vfs.cancel = nil
@@ -328,13 +370,6 @@ func (vfs *VFS) Root() (*Dir, error) {
 	return vfs.root, nil
 }
 
-var inodeCount uint64
-
-// newInode creates a new unique inode number
-func newInode() (inode uint64) {
-	return atomic.AddUint64(&inodeCount, 1)
-}
-
 // Stat finds the Node by path starting from the root
 //
 // It is the equivalent of os.Stat - Node contains the os.FileInfo
@@ -28,9 +28,13 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.DurationVarP(flagSet, &Opt.CachePollInterval, "vfs-cache-poll-interval", "", Opt.CachePollInterval, "Interval to poll the cache for stale objects.")
 	flags.DurationVarP(flagSet, &Opt.CacheMaxAge, "vfs-cache-max-age", "", Opt.CacheMaxAge, "Max age of objects in the cache.")
 	flags.FVarP(flagSet, &Opt.CacheMaxSize, "vfs-cache-max-size", "", "Max total size of objects in the cache.")
+	flags.IntVarP(flagSet, &Opt.WriteRetries, "vfs-write-retries", "", Opt.WriteRetries, "Number of times to retry uploading a file from the cache.")
+	flags.DurationVarP(flagSet, &Opt.WriteRetryDelay, "vfs-write-retry-delay", "", Opt.WriteRetryDelay, "Delay before retrying an upload from the cache, doubled for each retry.")
 	flags.FVarP(flagSet, &Opt.ChunkSize, "vfs-read-chunk-size", "", "Read the source objects in chunks.")
 	flags.FVarP(flagSet, &Opt.ChunkSizeLimit, "vfs-read-chunk-size-limit", "", "If greater than --vfs-read-chunk-size, double the chunk size after each chunk read, until the limit is reached. 'off' is unlimited.")
 	flags.FVarP(flagSet, DirPerms, "dir-perms", "", "Directory permissions")
 	flags.FVarP(flagSet, FilePerms, "file-perms", "", "File permissions")
+	flags.BoolVarP(flagSet, &Opt.MetadataPerms, "vfs-metadata-perms", "", Opt.MetadataPerms, "Use file permissions and owner from the backend if available.")
+	flags.BoolVarP(flagSet, &Opt.PersistentInodes, "vfs-persistent-inodes", "", Opt.PersistentInodes, "Keep inode numbers the same across remounts by storing them in the cache directory.")
 	platformFlags(flagSet)
 }